@@ -0,0 +1,124 @@
+package v1
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	componentsnvidiadcgmdiag "github.com/leptonai/gpud/components/accelerator/nvidia/dcgm-diag"
+	componentsnvidianccl "github.com/leptonai/gpud/components/accelerator/nvidia/nccl"
+	"github.com/leptonai/gpud/pkg/nvidia-query/dcgm"
+	"github.com/leptonai/gpud/pkg/server"
+)
+
+// RunDiagnostics runs the NVIDIA DCGM diagnostics on the gpud server,
+// and blocks until the diagnostics complete.
+func RunDiagnostics(ctx context.Context, addr string, diagReq componentsnvidiadcgmdiag.DiagRequest) (*dcgm.DiagResult, error) {
+	b, err := json.Marshal(diagReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("%s/v1%s", addr, server.URLPathDiagnostics), bytes.NewReader(b))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set(server.RequestHeaderContentType, server.RequestHeaderJSON)
+
+	resp, err := createDefaultHTTPClient().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, readErrorResponse(resp)
+	}
+
+	var result dcgm.DiagResult
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode json: %w", err)
+	}
+	return &result, nil
+}
+
+// CancelDiagnostics cancels the running NVIDIA DCGM diagnostics on the gpud server.
+func CancelDiagnostics(ctx context.Context, addr string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, fmt.Sprintf("%s/v1%s", addr, server.URLPathDiagnostics), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := createDefaultHTTPClient().Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return readErrorResponse(resp)
+	}
+	return nil
+}
+
+// RunNCCLSelfTest runs the NCCL all-reduce bandwidth self-test on the gpud server,
+// and blocks until the self-test completes.
+func RunNCCLSelfTest(ctx context.Context, addr string, selfTestReq componentsnvidianccl.SelfTestRequest) (*componentsnvidianccl.SelfTestResult, error) {
+	b, err := json.Marshal(selfTestReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("%s/v1%s", addr, server.URLPathDiagnosticsNCCL), bytes.NewReader(b))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set(server.RequestHeaderContentType, server.RequestHeaderJSON)
+
+	resp, err := createDefaultHTTPClient().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, readErrorResponse(resp)
+	}
+
+	var result componentsnvidianccl.SelfTestResult
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode json: %w", err)
+	}
+	return &result, nil
+}
+
+// CancelNCCLSelfTest cancels the running NCCL all-reduce bandwidth self-test on the gpud server.
+func CancelNCCLSelfTest(ctx context.Context, addr string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, fmt.Sprintf("%s/v1%s", addr, server.URLPathDiagnosticsNCCL), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := createDefaultHTTPClient().Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return readErrorResponse(resp)
+	}
+	return nil
+}
+
+// readErrorResponse returns the error with the message of the server error response.
+func readErrorResponse(resp *http.Response) error {
+	b, _ := io.ReadAll(resp.Body)
+
+	var errResp struct {
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(b, &errResp); err == nil && errResp.Message != "" {
+		return fmt.Errorf("server responded %d: %s", resp.StatusCode, errResp.Message)
+	}
+	return fmt.Errorf("server responded %d: %s", resp.StatusCode, string(b))
+}
@@ -0,0 +1,92 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	componentsnvidiadcgmdiag "github.com/leptonai/gpud/components/accelerator/nvidia/dcgm-diag"
+	componentsnvidianccl "github.com/leptonai/gpud/components/accelerator/nvidia/nccl"
+	"github.com/leptonai/gpud/pkg/errdefs"
+	"github.com/leptonai/gpud/pkg/nvidia-query/dcgm"
+)
+
+func TestRunDiagnostics(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/diagnostics", r.URL.Path)
+		assert.Equal(t, http.MethodPost, r.Method)
+
+		var req componentsnvidiadcgmdiag.DiagRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+
+		if !req.Force {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			_ = json.NewEncoder(w).Encode(map[string]any{"code": errdefs.ErrFailedPrecondition, "message": "gpu processes are running"})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(dcgm.DiagResult{
+			Version: "3.3.5",
+			Tests:   []dcgm.DiagTestResult{{Category: "Integration", Test: "PCIe", GPUID: 1, Status: dcgm.DiagStatusFail}},
+		})
+	}))
+	defer srv.Close()
+
+	_, err := RunDiagnostics(context.Background(), srv.URL, componentsnvidiadcgmdiag.DiagRequest{Level: 1})
+	require.Error(t, err)
+	assert.Equal(t, "server responded 412: gpu processes are running", err.Error())
+
+	result, err := RunDiagnostics(context.Background(), srv.URL, componentsnvidiadcgmdiag.DiagRequest{Level: 1, Force: true})
+	require.NoError(t, err)
+	assert.Equal(t, "3.3.5", result.Version)
+	require.Len(t, result.Failures(), 1)
+	assert.Equal(t, "PCIe", result.Failures()[0].Test)
+}
+
+func TestCancelDiagnostics(t *testing.T) {
+	running := true
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodDelete, r.Method)
+		if !running {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message":"no diagnostics running"}`))
+			return
+		}
+		running = false
+		_, _ = w.Write([]byte(`{"message":"diagnostics canceled"}`))
+	}))
+	defer srv.Close()
+
+	require.NoError(t, CancelDiagnostics(context.Background(), srv.URL))
+
+	err := CancelDiagnostics(context.Background(), srv.URL)
+	require.Error(t, err)
+	assert.Equal(t, "server responded 404: no diagnostics running", err.Error())
+}
+
+func TestRunNCCLSelfTest(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/diagnostics/nccl", r.URL.Path)
+		if r.Method == http.MethodDelete {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message":"no self-test running"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"trigger":"api","peak_bus_bandwidth_gbps":420.89,"min_bus_bandwidth_gbps":370,"health":"Healthy"}`))
+	}))
+	defer srv.Close()
+
+	result, err := RunNCCLSelfTest(context.Background(), srv.URL, componentsnvidianccl.SelfTestRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, 420.89, result.PeakBusBandwidthGBps)
+	assert.Equal(t, 370.0, result.MinBusBandwidthGBps)
+	assert.Equal(t, componentsnvidianccl.SelfTestTriggerAPI, result.Trigger)
+
+	err = CancelNCCLSelfTest(context.Background(), srv.URL)
+	require.Error(t, err)
+	assert.Equal(t, "server responded 404: no self-test running", err.Error())
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/urfave/cli"
 
 	"github.com/leptonai/gpud/pkg/config"
+	"github.com/leptonai/gpud/pkg/nvidia-query/dcgm"
 	"github.com/leptonai/gpud/version"
 )
 
@@ -38,6 +39,13 @@ var (
 	kernelModulesToCheck         cli.StringSlice
 	dockerIgnoreConnectionErrors bool
 	ibstatCommand                string
+	eventStoreBackend            string
+	kmsgSource                   string
+	kmsgFilePath                 string
+	logRulesFile                 string
+	xidCatalogFile               string
+	sxidCatalogFile              string
+	expectedGPUCount             int
 )
 
 const (
@@ -214,6 +222,41 @@ sudo rm /etc/systemd/system/gpud.service
 					Usage:       "ignore connection errors to docker daemon, useful when docker daemon is not running (default: false)",
 					Destination: &dockerIgnoreConnectionErrors,
 				},
+				&cli.StringFlag{
+					Name:        "event-store-backend",
+					Usage:       "set the event store backend [sqlite, memory] (default: sqlite)",
+					Destination: &eventStoreBackend,
+				},
+				cli.StringFlag{
+					Name:        "kmsg-source",
+					Usage:       "set the kernel log source [kmsg, journal, file] (leave empty to auto-detect)",
+					Destination: &kmsgSource,
+				},
+				cli.StringFlag{
+					Name:        "kmsg-file-path",
+					Usage:       "set the kernel log file to tail for the file kernel log source (e.g., /var/log/kern.log)",
+					Destination: &kmsgFilePath,
+				},
+				cli.StringFlag{
+					Name:        "log-rules",
+					Usage:       "set the YAML or JSON file of the user-defined log rules that generate events from the kernel messages",
+					Destination: &logRulesFile,
+				},
+				cli.StringFlag{
+					Name:        "xid-catalog",
+					Usage:       "set the YAML or JSON file of the Xid details that override or extend the built-in Xid catalog",
+					Destination: &xidCatalogFile,
+				},
+				cli.StringFlag{
+					Name:        "sxid-catalog",
+					Usage:       "set the YAML or JSON file of the SXid details that override or extend the built-in SXid catalog",
+					Destination: &sxidCatalogFile,
+				},
+				cli.IntFlag{
+					Name:        "expected-gpu-count",
+					Usage:       "set the expected number of GPUs (leave empty to use the GPUs found on the first boot)",
+					Destination: &expectedGPUCount,
+				},
 
 				// only for testing
 				cli.StringFlag{
@@ -430,6 +473,24 @@ sudo rm /etc/systemd/system/gpud.service
 					Usage:       "set the logging level [debug, info, warn, error, fatal, panic, dpanic]",
 					Destination: &logLevel,
 				},
+				&cli.StringFlag{
+					Name:        "event-store-backend",
+					Usage:       "set the event store backend [sqlite, memory] (default: memory)",
+					Destination: &eventStoreBackend,
+				},
+				&cli.StringFlag{
+					Name:  "kmsg-file",
+					Usage: "analyze the captured kernel log file (e.g., dmesg output, /dev/kmsg dump, /var/log/kern.log) rather than the live host",
+				},
+				&cli.StringFlag{
+					Name:  "journal-export",
+					Usage: "analyze the exported journal (e.g., 'journalctl -k -o json' or '-o export' output) rather than the live host",
+				},
+				cli.IntFlag{
+					Name:        "expected-gpu-count",
+					Usage:       "set the expected number of GPUs (leave empty to use the GPUs found)",
+					Destination: &expectedGPUCount,
+				},
 
 				// only for testing
 				cli.StringFlag{
@@ -440,6 +501,59 @@ sudo rm /etc/systemd/system/gpud.service
 				},
 			},
 		},
+		{
+			Name:  "xid",
+			Usage: "looks up the NVIDIA Xid/SXid catalog",
+			Subcommands: []cli.Command{
+				{
+					Name:      "lookup",
+					Usage:     "prints the Xid (or SXid) detail, merged with the catalog file (if any)",
+					UsageText: "gpud xid lookup [--xid-catalog <file>] [--sxid [--sxid-catalog <file>]] <code>",
+					Action:    cmdXIDLookup,
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:        "xid-catalog",
+							Usage:       "set the YAML or JSON file of the Xid details that override or extend the built-in Xid catalog",
+							Destination: &xidCatalogFile,
+						},
+						cli.StringFlag{
+							Name:        "sxid-catalog",
+							Usage:       "set the YAML or JSON file of the SXid details that override or extend the built-in SXid catalog",
+							Destination: &sxidCatalogFile,
+						},
+						cli.BoolFlag{
+							Name:  "sxid",
+							Usage: "look up the NVSwitch SXid rather than the Xid",
+						},
+					},
+				},
+			},
+		},
+		{
+			Name:      "diag",
+			Usage:     "runs the NVIDIA DCGM diagnostics on the running gpud server",
+			UsageText: "gpud diag [--level <1-4>] [--force] [--timeout <duration>] | gpud diag --cancel",
+			Action:    cmdDiag,
+			Flags: []cli.Flag{
+				cli.IntFlag{
+					Name:  "level,r",
+					Usage: fmt.Sprintf("set the diagnostics level [%d-%d] (1: quick, 2: medium, 3: long, 4: extended)", dcgm.MinDiagLevel, dcgm.MaxDiagLevel),
+					Value: dcgm.MinDiagLevel,
+				},
+				cli.BoolFlag{
+					Name:  "force",
+					Usage: "run the diagnostics even if the GPU processes are running",
+				},
+				cli.DurationFlag{
+					Name:  "timeout",
+					Usage: "set the diagnostics timeout (default: per level, e.g., 5m for the level 1, 3h for the level 4)",
+				},
+				cli.BoolFlag{
+					Name:  "cancel",
+					Usage: "cancel the running diagnostics",
+				},
+			},
+		},
 		{
 			Name:  "join",
 			Usage: "join gpud machine into a lepton cluster",
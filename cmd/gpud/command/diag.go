@@ -0,0 +1,64 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/urfave/cli"
+
+	client "github.com/leptonai/gpud/client/v1"
+	componentsnvidiadcgmdiag "github.com/leptonai/gpud/components/accelerator/nvidia/dcgm-diag"
+	"github.com/leptonai/gpud/pkg/config"
+)
+
+func cmdDiag(cliContext *cli.Context) error {
+	addr := fmt.Sprintf("https://localhost:%d", config.DefaultGPUdPort)
+
+	// interrupting the command aborts the request, which cancels the diagnostics
+	rootCtx, rootCancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer rootCancel()
+
+	if cliContext.Bool("cancel") {
+		if err := client.CancelDiagnostics(rootCtx, addr); err != nil {
+			return err
+		}
+		fmt.Printf("%s successfully canceled the diagnostics\n", checkMark)
+		return nil
+	}
+
+	req := componentsnvidiadcgmdiag.DiagRequest{
+		Level:            cliContext.Int("level"),
+		Force:            cliContext.Bool("force"),
+		TimeoutInSeconds: int(cliContext.Duration("timeout").Seconds()),
+	}
+	fmt.Printf("%s running dcgm diag level %d (press ctrl+c to cancel)\n", inProgress, req.Level)
+
+	result, err := client.RunDiagnostics(rootCtx, addr, req)
+	if err != nil {
+		return err
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetAlignment(tablewriter.ALIGN_CENTER)
+	table.SetHeader([]string{"Category", "Test", "GPU", "Status", "Warnings"})
+	for _, t := range result.Tests {
+		gpuID := "N/A"
+		if t.GPUID >= 0 {
+			gpuID = fmt.Sprintf("%d", t.GPUID)
+		}
+		table.Append([]string{t.Category, t.Test, gpuID, t.Status, strings.Join(t.Warnings, "; ")})
+	}
+	table.Render()
+
+	if failures := result.Failures(); len(failures) > 0 {
+		fmt.Printf("%s dcgm diag level %d failed %d test(s)\n", warningSign, req.Level, len(failures))
+		return fmt.Errorf("%d test(s) failed", len(failures))
+	}
+	fmt.Printf("%s dcgm diag level %d passed\n", checkMark, req.Level)
+	return nil
+}
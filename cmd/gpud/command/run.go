@@ -15,9 +15,13 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/leptonai/gpud/pkg/config"
+	nvidiacommon "github.com/leptonai/gpud/pkg/config/common"
 	gpud_manager "github.com/leptonai/gpud/pkg/gpud-manager"
 	gpudstate "github.com/leptonai/gpud/pkg/gpud-state"
+	"github.com/leptonai/gpud/pkg/kmsg"
 	"github.com/leptonai/gpud/pkg/log"
+	nvidiasxid "github.com/leptonai/gpud/pkg/nvidia-query/sxid"
+	nvidiaxid "github.com/leptonai/gpud/pkg/nvidia-query/xid"
 	gpudserver "github.com/leptonai/gpud/pkg/server"
 	"github.com/leptonai/gpud/pkg/sqlite"
 	pkd_systemd "github.com/leptonai/gpud/pkg/systemd"
@@ -73,6 +77,38 @@ func cmdRun(cliContext *cli.Context) error {
 
 	cfg.CompactPeriod = config.DefaultCompactPeriod
 
+	if eventStoreBackend != "" {
+		cfg.EventStoreBackend = eventStoreBackend
+	}
+	if kmsgSource != "" {
+		cfg.KmsgSource = kmsgSource
+	}
+	if kmsgFilePath != "" {
+		cfg.KmsgFilePath = kmsgFilePath
+	}
+	if logRulesFile != "" {
+		cfg.LogRules, err = kmsg.LoadRules(logRulesFile)
+		if err != nil {
+			return err
+		}
+	}
+	if xidCatalogFile != "" {
+		cfg.XIDCatalog, err = nvidiaxid.LoadCatalog(xidCatalogFile)
+		if err != nil {
+			return err
+		}
+	}
+	if sxidCatalogFile != "" {
+		cfg.SXIDCatalog, err = nvidiasxid.LoadCatalog(sxidCatalogFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	if expectedGPUCount > 0 {
+		cfg.ExpectedGPUs = &nvidiacommon.ExpectedGPUs{Count: expectedGPUCount}
+	}
+
 	cfg.EnableAutoUpdate = enableAutoUpdate
 	cfg.AutoUpdateExitCode = autoUpdateExitCode
 
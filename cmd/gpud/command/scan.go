@@ -20,6 +20,16 @@ func cmdScan(cliContext *cli.Context) error {
 
 	opts := []scan.OpOption{
 		scan.WithIbstatCommand(ibstatCommand),
+		scan.WithEventStoreBackend(eventStoreBackend),
+	}
+	if p := cliContext.String("kmsg-file"); p != "" {
+		opts = append(opts, scan.WithKmsgFile(p))
+	}
+	if p := cliContext.String("journal-export"); p != "" {
+		opts = append(opts, scan.WithJournalExport(p))
+	}
+	if expectedGPUCount > 0 {
+		opts = append(opts, scan.WithExpectedGPUCount(expectedGPUCount))
 	}
 	if zapLvl.Level() <= zap.DebugLevel { // e.g., info, warn, error
 		opts = append(opts, scan.WithDebug(true))
@@ -0,0 +1,59 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/urfave/cli"
+	"sigs.k8s.io/yaml"
+
+	nvidiasxid "github.com/leptonai/gpud/pkg/nvidia-query/sxid"
+	nvidiaxid "github.com/leptonai/gpud/pkg/nvidia-query/xid"
+)
+
+func cmdXIDLookup(cliContext *cli.Context) error {
+	if cliContext.NArg() != 1 {
+		return errors.New("expected exactly one xid code")
+	}
+	code, err := strconv.Atoi(cliContext.Args().First())
+	if err != nil {
+		return fmt.Errorf("invalid xid code %q: %w", cliContext.Args().First(), err)
+	}
+
+	var detail any
+	if cliContext.Bool("sxid") {
+		if sxidCatalogFile != "" {
+			ds, err := nvidiasxid.LoadCatalog(sxidCatalogFile)
+			if err != nil {
+				return err
+			}
+			nvidiasxid.SetCatalog(ds)
+		}
+		d, ok := nvidiasxid.GetDetail(code)
+		if !ok {
+			return fmt.Errorf("sxid %d not found", code)
+		}
+		detail = d
+	} else {
+		if xidCatalogFile != "" {
+			ds, err := nvidiaxid.LoadCatalog(xidCatalogFile)
+			if err != nil {
+				return err
+			}
+			nvidiaxid.SetCatalog(ds)
+		}
+		d, ok := nvidiaxid.GetDetail(code)
+		if !ok {
+			return fmt.Errorf("xid %d not found", code)
+		}
+		detail = d
+	}
+
+	b, err := yaml.Marshal(detail)
+	if err != nil {
+		return err
+	}
+	fmt.Print(string(b))
+	return nil
+}
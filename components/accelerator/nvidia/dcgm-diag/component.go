@@ -0,0 +1,467 @@
+// Package dcgmdiag runs the NVIDIA DCGM diagnostics on demand
+// and reports the per-test, per-GPU results.
+package dcgmdiag
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/NVIDIA/go-nvlib/pkg/nvlib/device"
+	"github.com/olekukonko/tablewriter"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	apiv1 "github.com/leptonai/gpud/api/v1"
+	"github.com/leptonai/gpud/components"
+	"github.com/leptonai/gpud/pkg/eventstore"
+	pkgfile "github.com/leptonai/gpud/pkg/file"
+	"github.com/leptonai/gpud/pkg/log"
+	"github.com/leptonai/gpud/pkg/nvidia-query/dcgm"
+	nvidianvml "github.com/leptonai/gpud/pkg/nvidia-query/nvml"
+	"github.com/leptonai/gpud/pkg/process"
+)
+
+const Name = "accelerator-nvidia-dcgm-diag"
+
+const (
+	EventNameDiag = "dcgm_diag"
+
+	EventKeyLevel    = "level"
+	EventKeyCategory = "category"
+	EventKeyTest     = "test"
+	EventKeyGPUID    = "gpu_id"
+	EventKeyStatus   = "status"
+)
+
+var (
+	ErrDcgmiNotFound       = errors.New("dcgmi not found")
+	ErrGPUProcessesRunning = errors.New("gpu processes are running, use force to run the diagnostics anyway")
+	ErrDiagAlreadyRunning  = process.ErrProcessAlreadyRunning
+	ErrInvalidDiagTimeout  = errors.New("invalid dcgm diag timeout (expected zero for the default, or positive seconds)")
+)
+
+// defaultTimeoutsPerLevel is the default timeout for each diagnostics level.
+var defaultTimeoutsPerLevel = map[int]time.Duration{
+	1: 5 * time.Minute,
+	2: 15 * time.Minute,
+	3: time.Hour,
+	4: 3 * time.Hour,
+}
+
+// DiagRequest is the request to run the DCGM diagnostics.
+type DiagRequest struct {
+	// Level is the diagnostics level from 1 (quick) to 4 (extended).
+	Level int `json:"level"`
+	// Force runs the diagnostics even if the GPU processes are running.
+	Force bool `json:"force,omitempty"`
+	// TimeoutInSeconds is the timeout for the diagnostics.
+	// If not set, the default timeout of the level is used
+	// (e.g., 5 minutes for the level 1, 3 hours for the level 4).
+	// Negative timeout is rejected.
+	TimeoutInSeconds int `json:"timeout_in_seconds,omitempty"`
+}
+
+// Diagnoser runs the DCGM diagnostics on demand.
+type Diagnoser interface {
+	// Preflight returns an error if the diagnostics of the request
+	// cannot start (e.g., already running, GPU processes running).
+	Preflight(req DiagRequest) error
+	// RunDiag runs the diagnostics and blocks until completion.
+	// Only one diagnostics runs at a time.
+	RunDiag(ctx context.Context, req DiagRequest) (*dcgm.DiagResult, error)
+	// CancelDiag cancels the running diagnostics, and returns false
+	// if no diagnostics is running.
+	CancelDiag() bool
+}
+
+var (
+	_ components.Component      = &component{}
+	_ components.HealthSettable = &component{}
+	_ Diagnoser                 = &component{}
+)
+
+type component struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	nvmlInstance     nvidianvml.InstanceV2
+	getProcessesFunc func(uuid string, dev device.Device) (nvidianvml.Processes, error)
+	locateDcgmiFunc  func() (string, error)
+	processRunner    process.Runner
+
+	eventBucket eventstore.Bucket
+
+	runMu     sync.Mutex
+	runCancel context.CancelFunc
+
+	lastMu   sync.RWMutex
+	lastData *Data
+}
+
+func New(gpudInstance *components.GPUdInstance) (components.Component, error) {
+	cctx, ccancel := context.WithCancel(gpudInstance.RootCtx)
+	c := &component{
+		ctx:              cctx,
+		cancel:           ccancel,
+		nvmlInstance:     gpudInstance.NVMLInstance,
+		getProcessesFunc: nvidianvml.GetProcesses,
+		locateDcgmiFunc: func() (string, error) {
+			return pkgfile.LocateExecutable("dcgmi")
+		},
+		processRunner: process.NewExclusiveRunner(),
+	}
+
+	if gpudInstance.EventStore != nil && runtime.GOOS == "linux" {
+		var err error
+		c.eventBucket, err = gpudInstance.EventStore.Bucket(Name)
+		if err != nil {
+			ccancel()
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
+
+func (c *component) Name() string { return Name }
+
+// Start does not run the diagnostics periodically,
+// as the diagnostics may stress the GPUs for hours.
+func (c *component) Start() error { return nil }
+
+func (c *component) LastHealthStates() apiv1.HealthStates {
+	c.lastMu.RLock()
+	lastData := c.lastData
+	c.lastMu.RUnlock()
+	return lastData.getLastHealthStates()
+}
+
+func (c *component) Events(ctx context.Context, since time.Time) (apiv1.Events, error) {
+	if c.eventBucket == nil {
+		return nil, nil
+	}
+	return c.eventBucket.Get(ctx, since)
+}
+
+func (c *component) Close() error {
+	log.Logger.Debugw("closing component")
+
+	c.cancel()
+
+	if c.eventBucket != nil {
+		c.eventBucket.Close()
+	}
+
+	return nil
+}
+
+// Check returns the result of the last diagnostics run,
+// without running the diagnostics.
+func (c *component) Check() components.CheckResult {
+	c.lastMu.RLock()
+	defer c.lastMu.RUnlock()
+
+	if c.lastData == nil {
+		return &Data{
+			ts:     time.Now().UTC(),
+			health: apiv1.HealthStateTypeHealthy,
+			reason: "no dcgm diag run yet",
+		}
+	}
+	return c.lastData
+}
+
+// SetHealthy clears the result of the last diagnostics run.
+func (c *component) SetHealthy() error {
+	log.Logger.Infow("set healthy event received")
+
+	c.lastMu.Lock()
+	c.lastData = nil
+	c.lastMu.Unlock()
+
+	return nil
+}
+
+func (c *component) Preflight(req DiagRequest) error {
+	if _, err := dcgm.DiagCommand(req.Level); err != nil {
+		return err
+	}
+	// negative timeout would expire the diagnostics before it starts
+	if req.TimeoutInSeconds < 0 {
+		return ErrInvalidDiagTimeout
+	}
+
+	c.runMu.Lock()
+	running := c.runCancel != nil
+	c.runMu.Unlock()
+	if running {
+		return ErrDiagAlreadyRunning
+	}
+
+	if _, err := c.locateDcgmiFunc(); err != nil {
+		return ErrDcgmiNotFound
+	}
+
+	if req.Force || c.nvmlInstance == nil || !c.nvmlInstance.NVMLExists() {
+		return nil
+	}
+
+	devs := c.nvmlInstance.Devices()
+	uuids := make([]string, 0, len(devs))
+	for uuid := range devs {
+		uuids = append(uuids, uuid)
+	}
+	sort.Strings(uuids)
+
+	var busy []string
+	for _, uuid := range uuids {
+		procs, err := c.getProcessesFunc(uuid, devs[uuid])
+		if err != nil {
+			return fmt.Errorf("error getting processes for device %s: %w", uuid, err)
+		}
+		if len(procs.RunningProcesses) > 0 {
+			busy = append(busy, fmt.Sprintf("%s (%d process(es))", uuid, len(procs.RunningProcesses)))
+		}
+	}
+	if len(busy) > 0 {
+		return fmt.Errorf("%w: %s", ErrGPUProcessesRunning, strings.Join(busy, ", "))
+	}
+	return nil
+}
+
+func (c *component) RunDiag(ctx context.Context, req DiagRequest) (*dcgm.DiagResult, error) {
+	if err := c.Preflight(req); err != nil {
+		return nil, err
+	}
+	cmd, err := dcgm.DiagCommand(req.Level)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := time.Duration(req.TimeoutInSeconds) * time.Second
+	if timeout == 0 {
+		timeout = defaultTimeoutsPerLevel[req.Level]
+	}
+
+	cctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	// also aborts the diagnostics on the component close (e.g., gpud shutdown)
+	stop := context.AfterFunc(c.ctx, cancel)
+	defer stop()
+
+	c.runMu.Lock()
+	if c.runCancel != nil {
+		c.runMu.Unlock()
+		return nil, ErrDiagAlreadyRunning
+	}
+	c.runCancel = cancel
+	c.runMu.Unlock()
+	defer func() {
+		c.runMu.Lock()
+		c.runCancel = nil
+		c.runMu.Unlock()
+	}()
+
+	log.Logger.Infow("running dcgm diag", "level", req.Level, "force", req.Force, "timeout", timeout)
+	start := time.Now().UTC()
+
+	// "dcgmi diag" exits non-zero on any failed test,
+	// still with the results in the output
+	output, exitCode, err := c.processRunner.RunUntilCompletion(cctx, cmd)
+	if err != nil {
+		var exitErr *exec.ExitError
+		if cctx.Err() != nil || !errors.As(err, &exitErr) || len(output) == 0 {
+			log.Logger.Warnw("dcgm diag aborted", "level", req.Level, "exitCode", exitCode, "error", err)
+			return nil, err
+		}
+		log.Logger.Infow("dcgm diag exited with non-zero status", "level", req.Level, "exitCode", exitCode)
+	}
+
+	result, err := dcgm.ParseDiagOutput(output)
+	if err != nil {
+		log.Logger.Warnw("failed to parse dcgm diag output", "level", req.Level, "error", err)
+		return nil, err
+	}
+	log.Logger.Infow("dcgm diag completed", "level", req.Level, "tests", len(result.Tests), "failures", len(result.Failures()), "took", time.Since(start))
+
+	d := &Data{
+		Level:     req.Level,
+		StartTime: start,
+		Result:    result,
+		ts:        time.Now().UTC(),
+	}
+	d.evaluate()
+
+	c.lastMu.Lock()
+	c.lastData = d
+	c.lastMu.Unlock()
+
+	if c.eventBucket != nil {
+		for _, ev := range createEvents(d.ts, req.Level, result) {
+			if err := c.eventBucket.Insert(c.ctx, ev); err != nil {
+				log.Logger.Errorw("failed to insert dcgm diag event", "test", ev.DeprecatedExtraInfo[EventKeyTest], "error", err)
+			}
+		}
+	}
+
+	return result, nil
+}
+
+func (c *component) CancelDiag() bool {
+	c.runMu.Lock()
+	defer c.runMu.Unlock()
+
+	if c.runCancel == nil {
+		return false
+	}
+	log.Logger.Infow("canceling dcgm diag")
+	c.runCancel()
+	return true
+}
+
+// createEvents creates an event for each per-test, per-GPU result.
+func createEvents(ts time.Time, level int, result *dcgm.DiagResult) apiv1.Events {
+	events := make(apiv1.Events, 0, len(result.Tests))
+	for _, t := range result.Tests {
+		eventType := apiv1.EventTypeInfo
+		switch t.Status {
+		case dcgm.DiagStatusFail:
+			eventType = apiv1.EventTypeCritical
+		case dcgm.DiagStatusWarn:
+			eventType = apiv1.EventTypeWarning
+		}
+
+		msg := fmt.Sprintf("dcgm diag level %d %s/%s %s", level, t.Category, t.Test, t.Status)
+		if t.GPUID >= 0 {
+			msg = fmt.Sprintf("dcgm diag level %d %s/%s on GPU %d %s", level, t.Category, t.Test, t.GPUID, t.Status)
+		}
+		if len(t.Warnings) > 0 {
+			msg += ": " + strings.Join(t.Warnings, "; ")
+		}
+
+		events = append(events, apiv1.Event{
+			Time:    metav1.Time{Time: ts},
+			Name:    EventNameDiag,
+			Type:    eventType,
+			Message: msg,
+			DeprecatedExtraInfo: map[string]string{
+				EventKeyLevel:    fmt.Sprintf("%d", level),
+				EventKeyCategory: t.Category,
+				EventKeyTest:     t.Test,
+				EventKeyGPUID:    fmt.Sprintf("%d", t.GPUID),
+				EventKeyStatus:   t.Status,
+			},
+		})
+	}
+	return events
+}
+
+var _ components.CheckResult = &Data{}
+
+type Data struct {
+	// Level is the diagnostics level of the last run.
+	Level int `json:"level,omitempty"`
+	// StartTime is the time when the last run started.
+	StartTime time.Time `json:"start_time,omitempty"`
+	// Result is the result of the last run.
+	Result *dcgm.DiagResult `json:"result,omitempty"`
+
+	// timestamp of the last check
+	ts time.Time
+
+	// tracks the healthy evaluation result of the last check
+	health apiv1.HealthStateType
+	// tracks the reason of the last check
+	reason string
+}
+
+func (d *Data) evaluate() {
+	failures := d.Result.Failures()
+	if len(failures) == 0 {
+		d.health = apiv1.HealthStateTypeHealthy
+		d.reason = fmt.Sprintf("dcgm diag level %d passed (%d test result(s))", d.Level, len(d.Result.Tests))
+		return
+	}
+
+	failed := make([]string, 0, len(failures))
+	for _, f := range failures {
+		if f.GPUID >= 0 {
+			failed = append(failed, fmt.Sprintf("%s on GPU %d", f.Test, f.GPUID))
+		} else {
+			failed = append(failed, f.Test)
+		}
+	}
+	d.health = apiv1.HealthStateTypeUnhealthy
+	d.reason = fmt.Sprintf("dcgm diag level %d failed %d test(s) (%s)", d.Level, len(failures), strings.Join(failed, ", "))
+}
+
+func (d *Data) String() string {
+	if d == nil {
+		return ""
+	}
+	if d.Result == nil || len(d.Result.Tests) == 0 {
+		return "no data"
+	}
+
+	buf := bytes.NewBuffer(nil)
+	table := tablewriter.NewWriter(buf)
+	table.SetAlignment(tablewriter.ALIGN_CENTER)
+	table.SetHeader([]string{"Category", "Test", "GPU", "Status", "Warnings"})
+	for _, t := range d.Result.Tests {
+		gpuID := "N/A"
+		if t.GPUID >= 0 {
+			gpuID = fmt.Sprintf("%d", t.GPUID)
+		}
+		table.Append([]string{t.Category, t.Test, gpuID, t.Status, strings.Join(t.Warnings, "; ")})
+	}
+	table.Render()
+
+	return buf.String()
+}
+
+func (d *Data) Summary() string {
+	if d == nil {
+		return ""
+	}
+	return d.reason
+}
+
+func (d *Data) HealthState() apiv1.HealthStateType {
+	if d == nil {
+		return ""
+	}
+	return d.health
+}
+
+func (d *Data) getLastHealthStates() apiv1.HealthStates {
+	if d == nil {
+		return apiv1.HealthStates{
+			{
+				Name:   Name,
+				Health: apiv1.HealthStateTypeHealthy,
+				Reason: "no data yet",
+			},
+		}
+	}
+
+	state := apiv1.HealthState{
+		Name:   Name,
+		Reason: d.reason,
+		Health: d.health,
+	}
+
+	b, _ := json.Marshal(d)
+	state.DeprecatedExtraInfo = map[string]string{
+		"data":     string(b),
+		"encoding": "json",
+	}
+	return apiv1.HealthStates{state}
+}
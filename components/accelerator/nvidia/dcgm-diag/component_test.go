@@ -0,0 +1,358 @@
+package dcgmdiag
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/NVIDIA/go-nvlib/pkg/nvlib/device"
+	"github.com/NVIDIA/go-nvml/pkg/nvml/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	apiv1 "github.com/leptonai/gpud/api/v1"
+	"github.com/leptonai/gpud/components"
+	"github.com/leptonai/gpud/pkg/eventstore"
+	"github.com/leptonai/gpud/pkg/nvidia-query/dcgm"
+	nvidianvml "github.com/leptonai/gpud/pkg/nvidia-query/nvml"
+	nvml_lib "github.com/leptonai/gpud/pkg/nvidia-query/nvml/lib"
+	"github.com/leptonai/gpud/pkg/nvidia-query/nvml/testutil"
+	"github.com/leptonai/gpud/pkg/process"
+	"github.com/leptonai/gpud/pkg/sqlite"
+)
+
+// MockNvmlInstance implements the nvidianvml.InstanceV2 interface for testing
+type MockNvmlInstance struct {
+	devicesFunc func() map[string]device.Device
+}
+
+func (m *MockNvmlInstance) Devices() map[string]device.Device {
+	if m.devicesFunc != nil {
+		return m.devicesFunc()
+	}
+	return nil
+}
+
+func (m *MockNvmlInstance) GetMemoryErrorManagementCapabilities() nvidianvml.MemoryErrorManagementCapabilities {
+	return nvidianvml.MemoryErrorManagementCapabilities{}
+}
+
+func (m *MockNvmlInstance) ProductName() string {
+	return "NVIDIA Test GPU"
+}
+
+func (m *MockNvmlInstance) NVMLExists() bool {
+	return true
+}
+
+func (m *MockNvmlInstance) Library() nvml_lib.Library {
+	return nil
+}
+
+func (m *MockNvmlInstance) Shutdown() error {
+	return nil
+}
+
+// mockRunner implements the process.Runner interface for testing
+type mockRunner struct {
+	runFunc func(ctx context.Context, script string) ([]byte, int32, error)
+}
+
+func (r *mockRunner) RunUntilCompletion(ctx context.Context, script string) ([]byte, int32, error) {
+	return r.runFunc(ctx, script)
+}
+
+const testDiagOutput = `{
+	"DCGM GPU Diagnostic" : {
+		"test_categories" : [
+			{
+				"category" : "Deployment",
+				"tests" : [
+					{ "name" : "Denylist", "results" : [ { "status" : "Pass" } ] }
+				]
+			},
+			{
+				"category" : "Integration",
+				"tests" : [
+					{
+						"name" : "PCIe",
+						"results" : [
+							{ "gpu_ids" : "0", "status" : "Pass" },
+							{ "gpu_ids" : "1", "status" : "Fail", "warnings" : [ { "warning" : "observed 3.08 GB/s" } ] }
+						]
+					}
+				]
+			}
+		],
+		"version" : "3.3.5"
+	}
+}`
+
+// MockDiagComponent creates a component with two idle GPUs,
+// where the runner returns the output.
+func MockDiagComponent(ctx context.Context, t *testing.T, output string) (*component, func()) {
+	dbRW, dbRO, cleanup := sqlite.OpenTestDB(t)
+	store, err := eventstore.New(dbRW, dbRO, eventstore.DefaultRetention)
+	require.NoError(t, err)
+	bucket, err := store.Bucket(Name)
+	require.NoError(t, err)
+
+	cctx, cancel := context.WithCancel(ctx)
+	devs := map[string]device.Device{
+		"GPU-0": testutil.NewMockDevice(&mock.Device{}, "hopper", "Nvidia", "9.0", "0000:18:00.0"),
+		"GPU-1": testutil.NewMockDevice(&mock.Device{}, "hopper", "Nvidia", "9.0", "0000:2a:00.0"),
+	}
+
+	c := &component{
+		ctx:    cctx,
+		cancel: cancel,
+		nvmlInstance: &MockNvmlInstance{
+			devicesFunc: func() map[string]device.Device { return devs },
+		},
+		getProcessesFunc: func(uuid string, dev device.Device) (nvidianvml.Processes, error) {
+			return nvidianvml.Processes{UUID: uuid}, nil
+		},
+		locateDcgmiFunc: func() (string, error) { return "/usr/bin/dcgmi", nil },
+		processRunner: &mockRunner{
+			runFunc: func(ctx context.Context, script string) ([]byte, int32, error) {
+				return []byte(output), 0, nil
+			},
+		},
+		eventBucket: bucket,
+	}
+	return c, func() {
+		_ = c.Close()
+		cleanup()
+	}
+}
+
+func TestNew(t *testing.T) {
+	c, err := New(&components.GPUdInstance{
+		RootCtx:      context.Background(),
+		NVMLInstance: &MockNvmlInstance{},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, Name, c.Name())
+	assert.NoError(t, c.Start())
+
+	tc := c.(*component)
+	assert.NotNil(t, tc.getProcessesFunc)
+	assert.NotNil(t, tc.locateDcgmiFunc)
+	assert.NotNil(t, tc.processRunner)
+	assert.NoError(t, c.Close())
+}
+
+func TestRunDiag(t *testing.T) {
+	ctx := context.Background()
+	c, cleanup := MockDiagComponent(ctx, t, testDiagOutput)
+	defer cleanup()
+
+	var script string
+	c.processRunner = &mockRunner{
+		runFunc: func(ctx context.Context, s string) ([]byte, int32, error) {
+			script = s
+			return []byte(testDiagOutput), 0, nil
+		},
+	}
+
+	d := c.Check().(*Data)
+	assert.Equal(t, apiv1.HealthStateTypeHealthy, d.health)
+	assert.Equal(t, "no dcgm diag run yet", d.reason)
+	assert.Equal(t, "no data", d.String())
+
+	result, err := c.RunDiag(ctx, DiagRequest{Level: 2})
+	require.NoError(t, err)
+	assert.Equal(t, "dcgmi diag -r 2 -j", script)
+	require.Len(t, result.Tests, 3)
+	assert.False(t, result.Passed())
+
+	d = c.Check().(*Data)
+	assert.Equal(t, apiv1.HealthStateTypeUnhealthy, d.health)
+	assert.Equal(t, "dcgm diag level 2 failed 1 test(s) (PCIe on GPU 1)", d.reason)
+	assert.Contains(t, d.String(), "observed 3.08 GB/s")
+
+	states := c.LastHealthStates()
+	require.Len(t, states, 1)
+	assert.Equal(t, apiv1.HealthStateTypeUnhealthy, states[0].Health)
+	assert.Contains(t, states[0].DeprecatedExtraInfo["data"], `"status":"fail"`)
+
+	events, err := c.Events(ctx, time.Now().Add(-time.Minute))
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+	var critical []apiv1.Event
+	for _, ev := range events {
+		assert.Equal(t, EventNameDiag, ev.Name)
+		if ev.Type == apiv1.EventTypeCritical {
+			critical = append(critical, ev)
+		}
+	}
+	require.Len(t, critical, 1)
+	assert.Equal(t, "dcgm diag level 2 Integration/PCIe on GPU 1 fail: observed 3.08 GB/s", critical[0].Message)
+	assert.Equal(t, "1", critical[0].DeprecatedExtraInfo[EventKeyGPUID])
+	assert.Equal(t, "2", critical[0].DeprecatedExtraInfo[EventKeyLevel])
+
+	require.NoError(t, c.SetHealthy())
+	d = c.Check().(*Data)
+	assert.Equal(t, apiv1.HealthStateTypeHealthy, d.health)
+}
+
+func TestRunDiag_Passed(t *testing.T) {
+	c, cleanup := MockDiagComponent(context.Background(), t, `{"DCGM Diagnostic": {"test_categories": [
+		{"category": "Deployment", "tests": [{"name": "software", "results": [{"entity_group": "GPU", "entity_id": 0, "status": "Pass"}]}]}
+	]}}`)
+	defer cleanup()
+
+	_, err := c.RunDiag(context.Background(), DiagRequest{Level: 1})
+	require.NoError(t, err)
+
+	d := c.Check().(*Data)
+	assert.Equal(t, apiv1.HealthStateTypeHealthy, d.health)
+	assert.Equal(t, "dcgm diag level 1 passed (1 test result(s))", d.reason)
+}
+
+func TestPreflight(t *testing.T) {
+	c, cleanup := MockDiagComponent(context.Background(), t, testDiagOutput)
+	defer cleanup()
+
+	assert.NoError(t, c.Preflight(DiagRequest{Level: 1}))
+	assert.ErrorIs(t, c.Preflight(DiagRequest{Level: 0}), dcgm.ErrInvalidDiagLevel)
+	assert.ErrorIs(t, c.Preflight(DiagRequest{Level: 1, TimeoutInSeconds: -1}), ErrInvalidDiagTimeout)
+	_, err := c.RunDiag(context.Background(), DiagRequest{Level: 1, TimeoutInSeconds: -1})
+	assert.ErrorIs(t, err, ErrInvalidDiagTimeout)
+
+	c.getProcessesFunc = func(uuid string, dev device.Device) (nvidianvml.Processes, error) {
+		if uuid == "GPU-1" {
+			return nvidianvml.Processes{UUID: uuid, RunningProcesses: []nvidianvml.Process{{PID: 1234}}}, nil
+		}
+		return nvidianvml.Processes{UUID: uuid}, nil
+	}
+	err = c.Preflight(DiagRequest{Level: 1})
+	assert.ErrorIs(t, err, ErrGPUProcessesRunning)
+	assert.Contains(t, err.Error(), "GPU-1 (1 process(es))")
+
+	_, err = c.RunDiag(context.Background(), DiagRequest{Level: 1})
+	assert.ErrorIs(t, err, ErrGPUProcessesRunning)
+	assert.NoError(t, c.Preflight(DiagRequest{Level: 1, Force: true}))
+
+	c.locateDcgmiFunc = func() (string, error) { return "", errors.New("not found") }
+	assert.ErrorIs(t, c.Preflight(DiagRequest{Level: 1, Force: true}), ErrDcgmiNotFound)
+}
+
+func TestRunDiag_Cancel(t *testing.T) {
+	c, cleanup := MockDiagComponent(context.Background(), t, testDiagOutput)
+	defer cleanup()
+
+	started := make(chan struct{})
+	c.processRunner = &mockRunner{
+		runFunc: func(ctx context.Context, script string) ([]byte, int32, error) {
+			close(started)
+			<-ctx.Done()
+			return nil, 0, ctx.Err()
+		},
+	}
+	assert.False(t, c.CancelDiag())
+
+	errc := make(chan error, 1)
+	go func() {
+		_, err := c.RunDiag(context.Background(), DiagRequest{Level: 4})
+		errc <- err
+	}()
+	<-started
+
+	// only one diagnostics runs at a time
+	assert.ErrorIs(t, c.Preflight(DiagRequest{Level: 1}), ErrDiagAlreadyRunning)
+	_, err := c.RunDiag(context.Background(), DiagRequest{Level: 1})
+	assert.ErrorIs(t, err, process.ErrProcessAlreadyRunning)
+
+	assert.True(t, c.CancelDiag())
+	assert.ErrorIs(t, <-errc, context.Canceled)
+	assert.False(t, c.CancelDiag())
+
+	// the aborted run does not change the health state
+	d := c.Check().(*Data)
+	assert.Equal(t, apiv1.HealthStateTypeHealthy, d.health)
+}
+
+func TestRunDiag_ParseError(t *testing.T) {
+	c, cleanup := MockDiagComponent(context.Background(), t, "Error: Unable to connect to host engine.")
+	defer cleanup()
+
+	_, err := c.RunDiag(context.Background(), DiagRequest{Level: 1})
+	assert.ErrorIs(t, err, dcgm.ErrNoDiagResult)
+
+	events, err := c.Events(context.Background(), time.Now().Add(-time.Minute))
+	require.NoError(t, err)
+	assert.Empty(t, events)
+}
+
+func TestRunDiag_NonZeroExit(t *testing.T) {
+	c, cleanup := MockDiagComponent(context.Background(), t, testDiagOutput)
+	defer cleanup()
+
+	exitErr := exec.Command("sh", "-c", "exit 226").Run()
+	require.Error(t, exitErr)
+
+	// the failed tests are reported with the non-zero exit
+	c.processRunner = &mockRunner{
+		runFunc: func(ctx context.Context, script string) ([]byte, int32, error) {
+			return []byte(testDiagOutput), 226, exitErr
+		},
+	}
+	result, err := c.RunDiag(context.Background(), DiagRequest{Level: 2})
+	require.NoError(t, err)
+	assert.False(t, result.Passed())
+	assert.Equal(t, apiv1.HealthStateTypeUnhealthy, c.Check().HealthState())
+
+	// no output to parse
+	require.NoError(t, c.SetHealthy())
+	c.processRunner = &mockRunner{
+		runFunc: func(ctx context.Context, script string) ([]byte, int32, error) {
+			return nil, 226, exitErr
+		},
+	}
+	_, err = c.RunDiag(context.Background(), DiagRequest{Level: 2})
+	assert.ErrorIs(t, err, exitErr)
+	assert.Equal(t, apiv1.HealthStateTypeHealthy, c.Check().HealthState())
+}
+
+func TestRunDiag_Close(t *testing.T) {
+	c, cleanup := MockDiagComponent(context.Background(), t, testDiagOutput)
+	defer cleanup()
+
+	started := make(chan struct{})
+	c.processRunner = &mockRunner{
+		runFunc: func(ctx context.Context, script string) ([]byte, int32, error) {
+			close(started)
+			<-ctx.Done()
+			return nil, -1, ctx.Err()
+		},
+	}
+
+	errc := make(chan error, 1)
+	go func() {
+		_, err := c.RunDiag(context.Background(), DiagRequest{Level: 4})
+		errc <- err
+	}()
+	<-started
+
+	// closing the component aborts the running diagnostics
+	c.cancel()
+	select {
+	case err := <-errc:
+		assert.ErrorIs(t, err, context.Canceled)
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for the diagnostics to abort")
+	}
+}
+
+func TestLastHealthStates_NoData(t *testing.T) {
+	c, cleanup := MockDiagComponent(context.Background(), t, testDiagOutput)
+	defer cleanup()
+
+	states := c.LastHealthStates()
+	require.Len(t, states, 1)
+	assert.Equal(t, apiv1.HealthStateTypeHealthy, states[0].Health)
+	assert.Equal(t, "no data yet", states[0].Reason)
+}
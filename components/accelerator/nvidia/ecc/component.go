@@ -1,26 +1,84 @@
-// Package ecc tracks the NVIDIA per-GPU ECC errors and other ECC related information.
+// Package ecc tracks the NVIDIA per-GPU ECC errors and other ECC related information,
+// and evaluates the error increases between the checks.
+//
+// In the MIG mode, NVML only counts the ECC errors per physical GPU
+// (the MIG device handles do not support the ECC queries), so the errors
+// are attributed to all the MIG devices of the GPU.
 package ecc
 
 import (
 	"bytes"
 	"context"
+	"database/sql"
 	"encoding/json"
 	"fmt"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/NVIDIA/go-nvlib/pkg/nvlib/device"
 	"github.com/olekukonko/tablewriter"
 	"github.com/prometheus/client_golang/prometheus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	apiv1 "github.com/leptonai/gpud/api/v1"
 	"github.com/leptonai/gpud/components"
+	"github.com/leptonai/gpud/pkg/eventstore"
+	gpudstate "github.com/leptonai/gpud/pkg/gpud-state"
+	"github.com/leptonai/gpud/pkg/healthstate"
+	pkghost "github.com/leptonai/gpud/pkg/host"
 	"github.com/leptonai/gpud/pkg/log"
 	pkgmetrics "github.com/leptonai/gpud/pkg/metrics"
 	nvidianvml "github.com/leptonai/gpud/pkg/nvidia-query/nvml"
 )
 
-const Name = "accelerator-nvidia-ecc"
+const (
+	Name = "accelerator-nvidia-ecc"
+
+	// DefaultRetentionPeriod is the period to look back for the uncorrectable errors
+	// that have not been resolved by a reboot.
+	DefaultRetentionPeriod = eventstore.DefaultRetention
+
+	// DefaultCorrectedErrorsWindow is the window to evaluate the correctable error rate.
+	DefaultCorrectedErrorsWindow = time.Hour
+)
+
+const (
+	EventNameUncorrectedErrorsIncreased = "ecc_uncorrected_errors_increased"
+	EventNameCorrectedErrorsIncreased   = "ecc_corrected_errors_increased"
+	EventNameSetHealthy                 = healthstate.EventNameSetHealthy
+
+	EventKeyGPUUUID   = "gpu_uuid"
+	EventKeyDelta     = "delta"
+	EventKeyLocations = "locations"
+	// EventKeyMIGUUIDs is the extra info key for the comma-separated MIG device UUIDs
+	// of the GPU, only set in the MIG mode.
+	EventKeyMIGUUIDs = "mig_uuids"
+)
+
+// trackedCounter is the ECC error counter tracked between the checks.
+type trackedCounter struct {
+	// name is the counter name persisted in the state database.
+	name string
+	// location describes where the errors are counted.
+	location    string
+	uncorrected bool
+	get         func(nvidianvml.ECCErrors) uint64
+}
+
+// the volatile counters are reset on the driver reload (e.g., reboot),
+// while the aggregate counters persist across the reboots
+var trackedCounters = []trackedCounter{
+	{name: "volatile_total_uncorrected", location: "volatile total", uncorrected: true, get: func(e nvidianvml.ECCErrors) uint64 { return e.Volatile.Total.Uncorrected }},
+	{name: "aggregate_sram_uncorrected", location: "aggregate SRAM", uncorrected: true, get: func(e nvidianvml.ECCErrors) uint64 { return e.Aggregate.SRAM.Uncorrected }},
+	{name: "aggregate_dram_uncorrected", location: "aggregate DRAM", uncorrected: true, get: func(e nvidianvml.ECCErrors) uint64 { return e.Aggregate.DRAM.Uncorrected }},
+	{name: "volatile_total_corrected", location: "volatile total", get: func(e nvidianvml.ECCErrors) uint64 { return e.Volatile.Total.Corrected }},
+	{name: "aggregate_sram_corrected", location: "aggregate SRAM", get: func(e nvidianvml.ECCErrors) uint64 { return e.Aggregate.SRAM.Corrected }},
+	{name: "aggregate_dram_corrected", location: "aggregate DRAM", get: func(e nvidianvml.ECCErrors) uint64 { return e.Aggregate.DRAM.Corrected }},
+}
 
 var _ components.Component = &component{}
 
@@ -31,6 +89,20 @@ type component struct {
 	nvmlInstance          nvidianvml.InstanceV2
 	getECCModeEnabledFunc func(uuid string, dev device.Device) (nvidianvml.ECCMode, error)
 	getECCErrorsFunc      func(uuid string, dev device.Device, eccModeEnabledCurrent bool) (nvidianvml.ECCErrors, error)
+	getThresholdsFunc     func() Thresholds
+	getMIGFunc            func(uuid string, dev device.Device) (nvidianvml.MIG, error)
+
+	dbRW *sql.DB
+	dbRO *sql.DB
+
+	eventBucket      eventstore.Bucket
+	rebootEventStore pkghost.RebootEventStore
+
+	// tracks the previous counters per GPU and counter name,
+	// loaded from the state database (if any) on the first check
+	prevMu       sync.Mutex
+	prevLoaded   bool
+	prevCounters map[string]map[string]gpudstate.Counter
 
 	lastMu   sync.RWMutex
 	lastData *Data
@@ -44,7 +116,36 @@ func New(gpudInstance *components.GPUdInstance) (components.Component, error) {
 		nvmlInstance:          gpudInstance.NVMLInstance,
 		getECCModeEnabledFunc: nvidianvml.GetECCModeEnabled,
 		getECCErrorsFunc:      nvidianvml.GetECCErrors,
+		getThresholdsFunc:     GetDefaultThresholds,
+		getMIGFunc:            nvidianvml.GetMIG,
+
+		dbRW: gpudInstance.DBRW,
+		dbRO: gpudInstance.DBRO,
+
+		rebootEventStore: gpudInstance.RebootEventStore,
+
+		prevCounters: make(map[string]map[string]gpudstate.Counter),
+	}
+
+	if c.dbRW != nil {
+		if err := gpudstate.CreateTableCounters(cctx, c.dbRW); err != nil {
+			ccancel()
+			return nil, err
+		}
+		if c.dbRO == nil {
+			c.dbRO = c.dbRW
+		}
+	}
+
+	if gpudInstance.EventStore != nil && runtime.GOOS == "linux" {
+		var err error
+		c.eventBucket, err = gpudInstance.EventStore.Bucket(Name)
+		if err != nil {
+			ccancel()
+			return nil, err
+		}
 	}
+
 	return c, nil
 }
 
@@ -76,7 +177,10 @@ func (c *component) LastHealthStates() apiv1.HealthStates {
 }
 
 func (c *component) Events(ctx context.Context, since time.Time) (apiv1.Events, error) {
-	return nil, nil
+	if c.eventBucket == nil {
+		return nil, nil
+	}
+	return c.eventBucket.Get(ctx, since)
 }
 
 func (c *component) Close() error {
@@ -84,6 +188,54 @@ func (c *component) Close() error {
 
 	c.cancel()
 
+	if c.eventBucket != nil {
+		c.eventBucket.Close()
+	}
+
+	return nil
+}
+
+var _ components.HealthSettable = &component{}
+
+// SetHealthy ignores the ECC error increases of all the GPUs before now,
+// until the next increase.
+func (c *component) SetHealthy() error {
+	return c.setHealthy("")
+}
+
+var _ components.DeviceHealthSettable = &component{}
+
+// SetHealthyDevice ignores the ECC error increases of the GPU before now,
+// without affecting the other GPUs, until the next increase.
+func (c *component) SetHealthyDevice(deviceUUID string) error {
+	return c.setHealthy(deviceUUID)
+}
+
+// setHealthy records the "SetHealthy" event of the GPU (or all the GPUs if empty),
+// and re-evaluates the states.
+func (c *component) setHealthy(uuid string) error {
+	log.Logger.Debugw("set healthy event received", "uuid", uuid)
+	if c.eventBucket == nil {
+		return nil
+	}
+
+	ev := apiv1.Event{
+		Time: metav1.Time{Time: time.Now().UTC()},
+		Name: EventNameSetHealthy,
+		Type: apiv1.EventTypeInfo,
+	}
+	if uuid != "" {
+		ev.DeprecatedExtraInfo = map[string]string{EventKeyGPUUUID: uuid}
+	}
+
+	cctx, ccancel := context.WithTimeout(c.ctx, 10*time.Second)
+	err := c.eventBucket.Insert(cctx, ev)
+	ccancel()
+	if err != nil {
+		return err
+	}
+
+	_ = c.Check()
 	return nil
 }
 
@@ -110,8 +262,44 @@ func (c *component) Check() components.CheckResult {
 		return d
 	}
 
+	thresholds := c.getThresholdsFunc()
+
+	c.prevMu.Lock()
+	defer c.prevMu.Unlock()
+
+	if !c.prevLoaded && c.dbRO != nil {
+		cctx, ccancel := context.WithTimeout(c.ctx, 30*time.Second)
+		loaded, err := gpudstate.LoadCounters(cctx, c.dbRO, Name)
+		ccancel()
+		if err != nil {
+			log.Logger.Errorw("error reading ecc counters", "error", err)
+
+			d.err = err
+			d.health = apiv1.HealthStateTypeUnhealthy
+			d.reason = fmt.Sprintf("error reading ecc counters: %s", err)
+			return d
+		}
+		c.prevCounters = loaded
+	}
+	c.prevLoaded = true
+
 	devs := c.nvmlInstance.Devices()
-	for uuid, dev := range devs {
+	uuids := make([]string, 0, len(devs))
+	for uuid := range devs {
+		uuids = append(uuids, uuid)
+	}
+	sort.Strings(uuids)
+
+	events := make([]apiv1.Event, 0)
+	updates := make([]gpudstate.Counter, 0)
+
+	// the error increases from this check,
+	// only used when the events are not persisted
+	uncorrectedDeltas := make(map[string]uint64)
+	correctedDeltas := make(map[string]uint64)
+
+	for _, uuid := range uuids {
+		dev := devs[uuid]
 		eccMode, err := c.getECCModeEnabledFunc(uuid, dev)
 		if err != nil {
 			log.Logger.Errorw("error getting ECC mode for device", "uuid", uuid, "error", err)
@@ -138,20 +326,282 @@ func (c *component) Check() components.CheckResult {
 		metricAggregateTotalUncorrected.With(prometheus.Labels{pkgmetrics.MetricLabelKey: uuid}).Set(float64(eccErrors.Aggregate.Total.Uncorrected))
 		metricVolatileTotalCorrected.With(prometheus.Labels{pkgmetrics.MetricLabelKey: uuid}).Set(float64(eccErrors.Volatile.Total.Corrected))
 		metricVolatileTotalUncorrected.With(prometheus.Labels{pkgmetrics.MetricLabelKey: uuid}).Set(float64(eccErrors.Volatile.Total.Uncorrected))
+
+		if !eccErrors.Supported {
+			continue
+		}
+
+		prev := c.prevCounters[uuid]
+		next := make(map[string]gpudstate.Counter, len(trackedCounters))
+
+		var uncorrectedLocations, correctedLocations []string
+		for _, tc := range trackedCounters {
+			cur := gpudstate.Counter{Device: uuid, Name: tc.name, Value: tc.get(eccErrors), Time: d.ts}
+			next[tc.name] = cur
+			updates = append(updates, cur)
+
+			p, ok := prev[tc.name]
+			// the counter may be reset (e.g., driver reload), then only track the new baseline
+			if !ok || cur.Value <= p.Value {
+				continue
+			}
+			delta := cur.Value - p.Value
+
+			// the locations overlap (e.g., DRAM errors are also counted in the volatile total),
+			// so the largest increase is the number of the new errors
+			if tc.uncorrected {
+				uncorrectedDeltas[uuid] = max(uncorrectedDeltas[uuid], delta)
+				uncorrectedLocations = append(uncorrectedLocations, fmt.Sprintf("%s +%d", tc.location, delta))
+			} else {
+				correctedDeltas[uuid] = max(correctedDeltas[uuid], delta)
+				correctedLocations = append(correctedLocations, fmt.Sprintf("%s +%d", tc.location, delta))
+			}
+		}
+		c.prevCounters[uuid] = next
+
+		var migUUIDs []string
+		if len(uncorrectedLocations) > 0 || len(correctedLocations) > 0 {
+			migUUIDs = c.getMIGUUIDs(uuid, dev)
+		}
+
+		if len(uncorrectedLocations) > 0 {
+			events = append(events, apiv1.Event{
+				Time:    metav1.Time{Time: d.ts},
+				Name:    EventNameUncorrectedErrorsIncreased,
+				Type:    apiv1.EventTypeCritical,
+				Message: fmt.Sprintf("GPU %s uncorrectable ECC errors increased by %d (%s)", uuid, uncorrectedDeltas[uuid], strings.Join(uncorrectedLocations, ", ")),
+				DeprecatedExtraInfo: map[string]string{
+					EventKeyGPUUUID:   uuid,
+					EventKeyDelta:     fmt.Sprintf("%d", uncorrectedDeltas[uuid]),
+					EventKeyLocations: strings.Join(uncorrectedLocations, ", "),
+				},
+			})
+			setMIGUUIDs(&events[len(events)-1], migUUIDs)
+		}
+		if len(correctedLocations) > 0 {
+			events = append(events, apiv1.Event{
+				Time:    metav1.Time{Time: d.ts},
+				Name:    EventNameCorrectedErrorsIncreased,
+				Type:    apiv1.EventTypeInfo,
+				Message: fmt.Sprintf("GPU %s correctable ECC errors increased by %d (%s)", uuid, correctedDeltas[uuid], strings.Join(correctedLocations, ", ")),
+				DeprecatedExtraInfo: map[string]string{
+					EventKeyGPUUUID:   uuid,
+					EventKeyDelta:     fmt.Sprintf("%d", correctedDeltas[uuid]),
+					EventKeyLocations: strings.Join(correctedLocations, ", "),
+				},
+			})
+			setMIGUUIDs(&events[len(events)-1], migUUIDs)
+		}
+	}
+
+	if c.dbRW != nil {
+		cctx, ccancel := context.WithTimeout(c.ctx, 30*time.Second)
+		err := gpudstate.UpdateCounters(cctx, c.dbRW, Name, updates)
+		ccancel()
+		if err != nil {
+			log.Logger.Errorw("error updating ecc counters", "error", err)
+
+			d.err = err
+			d.health = apiv1.HealthStateTypeUnhealthy
+			d.reason = fmt.Sprintf("error updating ecc counters: %s", err)
+			return d
+		}
+	}
+
+	if c.eventBucket != nil {
+		for _, ev := range events {
+			if err := c.eventBucket.Insert(c.ctx, ev); err != nil {
+				log.Logger.Errorw("failed to insert event", "error", err)
+
+				d.err = err
+				d.health = apiv1.HealthStateTypeUnhealthy
+				d.reason = fmt.Sprintf("error inserting event: %s", err)
+				return d
+			}
+		}
+
+		var err error
+		uncorrectedDeltas, correctedDeltas, err = c.sumRecentDeltas(d.ts)
+		if err != nil {
+			log.Logger.Errorw("failed to get events", "error", err)
+
+			d.err = err
+			d.health = apiv1.HealthStateTypeUnhealthy
+			d.reason = fmt.Sprintf("error getting events: %s", err)
+			return d
+		}
+	}
+
+	for uuid, delta := range uncorrectedDeltas {
+		if thresholds.UnhealthyUncorrectedErrors > 0 && delta >= thresholds.UnhealthyUncorrectedErrors {
+			d.UncorrectedErrorUUIDs = append(d.UncorrectedErrorUUIDs, uuid)
+		}
+	}
+	for uuid, delta := range correctedDeltas {
+		if thresholds.DegradedCorrectedErrorsPerHour > 0 && delta >= thresholds.DegradedCorrectedErrorsPerHour {
+			d.CorrectedErrorRateUUIDs = append(d.CorrectedErrorRateUUIDs, uuid)
+		}
+	}
+	sort.Strings(d.UncorrectedErrorUUIDs)
+	sort.Strings(d.CorrectedErrorRateUUIDs)
+
+	for _, uuids := range [][]string{d.UncorrectedErrorUUIDs, d.CorrectedErrorRateUUIDs} {
+		for _, uuid := range uuids {
+			if _, ok := d.MIGUUIDs[uuid]; ok {
+				continue
+			}
+			migUUIDs := c.getMIGUUIDs(uuid, devs[uuid])
+			if len(migUUIDs) == 0 {
+				continue
+			}
+			if d.MIGUUIDs == nil {
+				d.MIGUUIDs = make(map[string][]string)
+			}
+			d.MIGUUIDs[uuid] = migUUIDs
+		}
+	}
+
+	issues := make([]string, 0)
+	if len(d.UncorrectedErrorUUIDs) > 0 {
+		issues = append(issues, fmt.Sprintf("%d GPU(s) with new uncorrectable ECC errors (%s)", len(d.UncorrectedErrorUUIDs), strings.Join(d.UncorrectedErrorUUIDs, ", ")))
+	}
+	if len(d.CorrectedErrorRateUUIDs) > 0 {
+		issues = append(issues, fmt.Sprintf("%d GPU(s) with correctable ECC errors above %d per hour (%s)", len(d.CorrectedErrorRateUUIDs), thresholds.DegradedCorrectedErrorsPerHour, strings.Join(d.CorrectedErrorRateUUIDs, ", ")))
+	}
+
+	if len(issues) == 0 {
+		d.health = apiv1.HealthStateTypeHealthy
+		d.reason = fmt.Sprintf("all %d GPU(s) were checked, no ECC issue found", len(devs))
+		return d
 	}
 
-	d.health = apiv1.HealthStateTypeHealthy
-	d.reason = fmt.Sprintf("all %d GPU(s) were checked, no ECC issue found", len(devs))
+	d.reason = strings.Join(issues, "; ")
+	if len(d.UncorrectedErrorUUIDs) == 0 {
+		d.health = apiv1.HealthStateTypeDegraded
+		return d
+	}
+
+	d.health = apiv1.HealthStateTypeUnhealthy
+	d.suggestedActions = &apiv1.SuggestedActions{
+		RepairActions: []apiv1.RepairActionType{
+			apiv1.RepairActionTypeRebootSystem,
+		},
+		DeprecatedDescriptions: []string{
+			"Uncorrectable ECC errors were detected, please reboot the system to retire the affected memory pages or remap the rows",
+		},
+	}
 
 	return d
 }
 
+// getMIGUUIDs returns the MIG device UUIDs of the GPU,
+// or nil if the MIG mode is disabled (or unknown).
+func (c *component) getMIGUUIDs(uuid string, dev device.Device) []string {
+	if c.getMIGFunc == nil || dev == nil {
+		return nil
+	}
+	mig, err := c.getMIGFunc(uuid, dev)
+	if err != nil {
+		log.Logger.Warnw("error getting mig devices", "uuid", uuid, "error", err)
+		return nil
+	}
+	if !mig.Enabled {
+		return nil
+	}
+	uuids := make([]string, 0, len(mig.Instances))
+	for _, inst := range mig.Instances {
+		uuids = append(uuids, inst.UUID)
+	}
+	return uuids
+}
+
+// setMIGUUIDs attributes the ECC event to the MIG devices of the GPU, if any.
+func setMIGUUIDs(ev *apiv1.Event, migUUIDs []string) {
+	if len(migUUIDs) == 0 {
+		return
+	}
+	ev.DeprecatedExtraInfo[EventKeyMIGUUIDs] = strings.Join(migUUIDs, ",")
+	ev.Message += fmt.Sprintf(" on MIG device(s) %s", strings.Join(migUUIDs, ", "))
+}
+
+// sumRecentDeltas returns the new uncorrectable errors per GPU since the last reboot,
+// and the new correctable errors per GPU within the last hour,
+// both ignoring the errors before the last "SetHealthy" of the GPU (or all the GPUs).
+func (c *component) sumRecentDeltas(now time.Time) (map[string]uint64, map[string]uint64, error) {
+	cctx, ccancel := context.WithTimeout(c.ctx, 15*time.Second)
+	defer ccancel()
+
+	events, err := c.eventBucket.Get(cctx, now.Add(-DefaultRetentionPeriod))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var lastReboot time.Time
+	if c.rebootEventStore != nil {
+		rebootEvents, err := c.rebootEventStore.GetRebootEvents(cctx, now.Add(-DefaultRetentionPeriod))
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, ev := range rebootEvents {
+			if ev.Time.After(lastReboot) {
+				lastReboot = ev.Time.Time
+			}
+		}
+	}
+
+	setHealthy := healthstate.LastSetHealthy(events, func(ev apiv1.Event) string {
+		return ev.DeprecatedExtraInfo[EventKeyGPUUUID]
+	})
+
+	uncorrected := make(map[string]uint64)
+	corrected := make(map[string]uint64)
+	for _, ev := range events {
+		delta, err := strconv.ParseUint(ev.DeprecatedExtraInfo[EventKeyDelta], 10, 64)
+		if err != nil {
+			continue
+		}
+		uuid := ev.DeprecatedExtraInfo[EventKeyGPUUUID]
+
+		lastSetHealthy := setHealthy.Of(uuid)
+		uncorrectedSince := lastReboot
+		if lastSetHealthy.After(uncorrectedSince) {
+			uncorrectedSince = lastSetHealthy
+		}
+		correctedSince := now.Add(-DefaultCorrectedErrorsWindow)
+		if lastSetHealthy.After(correctedSince) {
+			correctedSince = lastSetHealthy
+		}
+
+		switch ev.Name {
+		case EventNameUncorrectedErrorsIncreased:
+			if ev.Time.After(uncorrectedSince) {
+				uncorrected[uuid] += delta
+			}
+		case EventNameCorrectedErrorsIncreased:
+			if ev.Time.After(correctedSince) {
+				corrected[uuid] += delta
+			}
+		}
+	}
+	return uncorrected, corrected, nil
+}
+
 var _ components.CheckResult = &Data{}
 
 type Data struct {
 	ECCModes  []nvidianvml.ECCMode   `json:"ecc_modes,omitempty"`
 	ECCErrors []nvidianvml.ECCErrors `json:"ecc_errors,omitempty"`
 
+	// UncorrectedErrorUUIDs is the GPUs with the new uncorrectable errors
+	// that have not been resolved by a reboot.
+	UncorrectedErrorUUIDs []string `json:"uncorrected_error_uuids,omitempty"`
+	// CorrectedErrorRateUUIDs is the GPUs with the correctable errors
+	// above the configured rate within the last hour.
+	CorrectedErrorRateUUIDs []string `json:"corrected_error_rate_uuids,omitempty"`
+	// MIGUUIDs is the MIG devices of the GPUs above, in the MIG mode,
+	// where the errors of the GPU affect all of its MIG devices.
+	MIGUUIDs map[string][]string `json:"mig_uuids,omitempty"`
+
 	// timestamp of the last check
 	ts time.Time
 	// error from the last check
@@ -161,6 +611,8 @@ type Data struct {
 	health apiv1.HealthStateType
 	// tracks the reason of the last check
 	reason string
+	// tracks the suggested actions of the last check
+	suggestedActions *apiv1.SuggestedActions
 }
 
 func (d *Data) String() string {
@@ -235,10 +687,11 @@ func (d *Data) getLastHealthStates() apiv1.HealthStates {
 	}
 
 	state := apiv1.HealthState{
-		Name:   Name,
-		Reason: d.reason,
-		Error:  d.getError(),
-		Health: d.health,
+		Name:             Name,
+		Reason:           d.reason,
+		Error:            d.getError(),
+		Health:           d.health,
+		SuggestedActions: d.suggestedActions,
 	}
 
 	b, _ := json.Marshal(d)
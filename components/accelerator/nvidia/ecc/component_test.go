@@ -12,12 +12,17 @@ import (
 	"github.com/NVIDIA/go-nvml/pkg/nvml/mock"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	apiv1 "github.com/leptonai/gpud/api/v1"
 	"github.com/leptonai/gpud/components"
+	"github.com/leptonai/gpud/pkg/eventstore"
+	gpudstate "github.com/leptonai/gpud/pkg/gpud-state"
+	pkghost "github.com/leptonai/gpud/pkg/host"
 	nvidianvml "github.com/leptonai/gpud/pkg/nvidia-query/nvml"
 	"github.com/leptonai/gpud/pkg/nvidia-query/nvml/lib"
 	"github.com/leptonai/gpud/pkg/nvidia-query/nvml/testutil"
+	"github.com/leptonai/gpud/pkg/sqlite"
 )
 
 // MockNvmlInstance implements the nvml.InstanceV2 interface for testing
@@ -84,6 +89,8 @@ func MockECCComponent(
 		nvmlInstance:          mockInstance,
 		getECCModeEnabledFunc: getECCModeEnabledFunc,
 		getECCErrorsFunc:      getECCErrorsFunc,
+		getThresholdsFunc:     GetDefaultThresholds,
+		prevCounters:          make(map[string]map[string]gpudstate.Counter),
 	}
 }
 
@@ -729,3 +736,256 @@ func TestCheck_MultipleDevices(t *testing.T) {
 	assert.Contains(t, uuids, uuid1)
 	assert.Contains(t, uuids, uuid2)
 }
+
+type mockRebootEventStore struct {
+	events apiv1.Events
+}
+
+func (m *mockRebootEventStore) RecordReboot(ctx context.Context) error { return nil }
+func (m *mockRebootEventStore) GetRebootEvents(ctx context.Context, since time.Time) (apiv1.Events, error) {
+	return m.events, nil
+}
+
+var _ pkghost.RebootEventStore = &mockRebootEventStore{}
+
+func TestCheck_ErrorIncreases(t *testing.T) {
+	t.Parallel()
+
+	const uuid = "gpu-uuid-123"
+
+	type step struct {
+		// mutates the ECC errors and the reboot events before the check
+		update func(eccErrors *nvidianvml.ECCErrors, rebootStore *mockRebootEventStore)
+		// re-creates the component from the persisted baselines (e.g., gpud restart)
+		restart bool
+		// sets the component healthy before the check
+		setHealthy bool
+		// sets the GPU healthy before the check
+		setHealthyDevice string
+
+		expectedHealth apiv1.HealthStateType
+		verify         func(t *testing.T, d *Data)
+	}
+
+	tests := []struct {
+		name  string
+		mig   nvidianvml.MIG
+		steps []step
+		// expected messages of the ECC error events
+		expectedEvents []string
+	}{
+		{
+			name: "uncorrected errors unhealthy until reboot, even after restart",
+			steps: []step{
+				{
+					// the first check only records the baselines
+					update: func(e *nvidianvml.ECCErrors, _ *mockRebootEventStore) {
+						e.Aggregate.DRAM.Uncorrected = 2
+						e.Volatile.Total.Uncorrected = 2
+					},
+					expectedHealth: apiv1.HealthStateTypeHealthy,
+				},
+				{
+					update: func(e *nvidianvml.ECCErrors, _ *mockRebootEventStore) {
+						e.Aggregate.DRAM.Uncorrected = 3
+						e.Volatile.Total.Uncorrected = 3
+					},
+					expectedHealth: apiv1.HealthStateTypeUnhealthy,
+					verify: func(t *testing.T, d *Data) {
+						assert.Equal(t, []string{uuid}, d.UncorrectedErrorUUIDs)
+						require.NotNil(t, d.suggestedActions)
+						assert.Equal(t, []apiv1.RepairActionType{apiv1.RepairActionTypeRebootSystem}, d.suggestedActions.RepairActions)
+					},
+				},
+				{
+					// no new event from the persisted baselines
+					restart:        true,
+					expectedHealth: apiv1.HealthStateTypeUnhealthy,
+				},
+				{
+					// the volatile counter is reset after the reboot
+					update: func(e *nvidianvml.ECCErrors, r *mockRebootEventStore) {
+						r.events = apiv1.Events{{Time: metav1.Time{Time: time.Now().Add(time.Second)}, Name: "reboot"}}
+						e.Volatile.Total.Uncorrected = 0
+					},
+					expectedHealth: apiv1.HealthStateTypeHealthy,
+				},
+			},
+			expectedEvents: []string{
+				"GPU gpu-uuid-123 uncorrectable ECC errors increased by 1 (volatile total +1, aggregate DRAM +1)",
+			},
+		},
+		{
+			name: "uncorrected errors on MIG devices",
+			mig: nvidianvml.MIG{
+				UUID:      uuid,
+				Enabled:   true,
+				Supported: true,
+				Instances: []nvidianvml.MIGInstance{
+					{UUID: "MIG-1", ParentUUID: uuid, GPUInstanceID: 1},
+					{UUID: "MIG-2", ParentUUID: uuid, GPUInstanceID: 2},
+				},
+			},
+			steps: []step{
+				{expectedHealth: apiv1.HealthStateTypeHealthy},
+				{
+					update: func(e *nvidianvml.ECCErrors, _ *mockRebootEventStore) {
+						e.Volatile.Total.Uncorrected = 1
+					},
+					expectedHealth: apiv1.HealthStateTypeUnhealthy,
+					verify: func(t *testing.T, d *Data) {
+						assert.Equal(t, map[string][]string{uuid: {"MIG-1", "MIG-2"}}, d.MIGUUIDs)
+					},
+				},
+			},
+			expectedEvents: []string{
+				"GPU gpu-uuid-123 uncorrectable ECC errors increased by 1 (volatile total +1) on MIG device(s) MIG-1, MIG-2",
+			},
+		},
+		{
+			name: "corrected error rate accumulated within the last hour",
+			steps: []step{
+				{expectedHealth: apiv1.HealthStateTypeHealthy},
+				{
+					update: func(e *nvidianvml.ECCErrors, _ *mockRebootEventStore) {
+						e.Volatile.Total.Corrected = 60
+						e.Aggregate.SRAM.Corrected = 60
+					},
+					expectedHealth: apiv1.HealthStateTypeHealthy,
+				},
+				{
+					update: func(e *nvidianvml.ECCErrors, _ *mockRebootEventStore) {
+						e.Volatile.Total.Corrected = 120
+						e.Aggregate.SRAM.Corrected = 120
+					},
+					expectedHealth: apiv1.HealthStateTypeDegraded,
+					verify: func(t *testing.T, d *Data) {
+						assert.Equal(t, []string{uuid}, d.CorrectedErrorRateUUIDs)
+						assert.Nil(t, d.suggestedActions)
+						assert.Empty(t, d.UncorrectedErrorUUIDs)
+					},
+				},
+			},
+			expectedEvents: []string{
+				"GPU gpu-uuid-123 correctable ECC errors increased by 60 (volatile total +60, aggregate SRAM +60)",
+				"GPU gpu-uuid-123 correctable ECC errors increased by 60 (volatile total +60, aggregate SRAM +60)",
+			},
+		},
+		{
+			name: "set healthy ignores the previous increases",
+			steps: []step{
+				{expectedHealth: apiv1.HealthStateTypeHealthy},
+				{
+					update: func(e *nvidianvml.ECCErrors, _ *mockRebootEventStore) {
+						e.Volatile.Total.Uncorrected = 1
+					},
+					expectedHealth: apiv1.HealthStateTypeUnhealthy,
+				},
+				{
+					setHealthy:     true,
+					expectedHealth: apiv1.HealthStateTypeHealthy,
+				},
+			},
+			expectedEvents: []string{
+				"GPU gpu-uuid-123 uncorrectable ECC errors increased by 1 (volatile total +1)",
+			},
+		},
+		{
+			name: "set healthy device only ignores the previous increases of the GPU",
+			steps: []step{
+				{expectedHealth: apiv1.HealthStateTypeHealthy},
+				{
+					update: func(e *nvidianvml.ECCErrors, _ *mockRebootEventStore) {
+						e.Volatile.Total.Uncorrected = 1
+					},
+					expectedHealth: apiv1.HealthStateTypeUnhealthy,
+				},
+				{
+					setHealthyDevice: "gpu-uuid-other",
+					expectedHealth:   apiv1.HealthStateTypeUnhealthy,
+				},
+				{
+					setHealthyDevice: uuid,
+					expectedHealth:   apiv1.HealthStateTypeHealthy,
+				},
+			},
+			expectedEvents: []string{
+				"GPU gpu-uuid-123 uncorrectable ECC errors increased by 1 (volatile total +1)",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			dbRW, dbRO, cleanup := sqlite.OpenTestDB(t)
+			defer cleanup()
+			require.NoError(t, gpudstate.CreateTableCounters(ctx, dbRW))
+
+			eventStore, err := eventstore.New(dbRW, dbRO, 0)
+			require.NoError(t, err)
+
+			mockDev := testutil.NewMockDevice(&mock.Device{}, "test-arch", "test-brand", "test-cuda", "test-pci")
+			eccErrors := nvidianvml.ECCErrors{UUID: uuid, Supported: true}
+			rebootStore := &mockRebootEventStore{}
+
+			newComponent := func() *component {
+				c := MockECCComponent(
+					ctx,
+					func() map[string]device.Device { return map[string]device.Device{uuid: mockDev} },
+					func(uuid string, dev device.Device) (nvidianvml.ECCMode, error) {
+						return nvidianvml.ECCMode{UUID: uuid, EnabledCurrent: true, Supported: true}, nil
+					},
+					func(uuid string, dev device.Device, eccModeEnabledCurrent bool) (nvidianvml.ECCErrors, error) {
+						return eccErrors, nil
+					},
+				).(*component)
+				c.getThresholdsFunc = func() Thresholds {
+					return Thresholds{UnhealthyUncorrectedErrors: 1, DegradedCorrectedErrorsPerHour: 100}
+				}
+				c.getMIGFunc = func(uuid string, dev device.Device) (nvidianvml.MIG, error) {
+					return tt.mig, nil
+				}
+				c.dbRW, c.dbRO = dbRW, dbRO
+				c.rebootEventStore = rebootStore
+				c.eventBucket, err = eventStore.Bucket(Name)
+				require.NoError(t, err)
+				return c
+			}
+
+			c := newComponent()
+			for i, s := range tt.steps {
+				if s.update != nil {
+					s.update(&eccErrors, rebootStore)
+				}
+				if s.restart {
+					c = newComponent()
+				}
+				if s.setHealthy {
+					require.NoError(t, c.SetHealthy())
+				}
+				if s.setHealthyDevice != "" {
+					require.NoError(t, c.SetHealthyDevice(s.setHealthyDevice))
+				}
+
+				d := c.Check().(*Data)
+				assert.Equal(t, s.expectedHealth, d.HealthState(), "step %d: %s", i, d.Summary())
+				if s.verify != nil {
+					s.verify(t, d)
+				}
+			}
+
+			events, err := c.Events(ctx, time.Now().Add(-time.Minute))
+			require.NoError(t, err)
+			var msgs []string
+			for _, ev := range events {
+				if ev.Name != EventNameSetHealthy {
+					msgs = append(msgs, ev.Message)
+				}
+			}
+			assert.ElementsMatch(t, tt.expectedEvents, msgs)
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package ecc
+
+import (
+	"sync"
+
+	"github.com/leptonai/gpud/pkg/log"
+)
+
+// Thresholds defines the rules to evaluate the ECC error increases between the checks.
+// Zero disables the rule.
+type Thresholds struct {
+	// UnhealthyUncorrectedErrors is the number of the new uncorrectable errors
+	// of a GPU to mark the component unhealthy and suggest a reboot.
+	UnhealthyUncorrectedErrors uint64 `json:"unhealthy_uncorrected_errors"`
+	// DegradedCorrectedErrorsPerHour is the number of the new correctable errors
+	// of a GPU within the last hour to mark the component degraded.
+	DegradedCorrectedErrorsPerHour uint64 `json:"degraded_corrected_errors_per_hour"`
+}
+
+var (
+	defaultThresholdsMu sync.RWMutex
+	defaultThresholds   = Thresholds{
+		UnhealthyUncorrectedErrors:     1,
+		DegradedCorrectedErrorsPerHour: 100,
+	}
+)
+
+func GetDefaultThresholds() Thresholds {
+	defaultThresholdsMu.RLock()
+	defer defaultThresholdsMu.RUnlock()
+	return defaultThresholds
+}
+
+func SetDefaultThresholds(thresholds Thresholds) {
+	log.Logger.Infow("setting default ecc thresholds", "unhealthy_uncorrected_errors", thresholds.UnhealthyUncorrectedErrors, "degraded_corrected_errors_per_hour", thresholds.DegradedCorrectedErrorsPerHour)
+
+	defaultThresholdsMu.Lock()
+	defer defaultThresholdsMu.Unlock()
+	defaultThresholds = thresholds
+}
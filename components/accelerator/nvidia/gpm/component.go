@@ -83,6 +83,7 @@ func New(gpudInstance *components.GPUdInstance) (components.Component, error) {
 		getGPMMetricsFunc: func(ctx2 context.Context, dev device.Device) (map[gonvml.GpmMetricId]float64, error) {
 			return nvidianvml.GetGPMMetrics(
 				ctx2,
+				gpudInstance.NVMLInstance.Library().NVML(),
 				dev,
 				sampleDuration,
 				defaultGPMMetricIDs...,
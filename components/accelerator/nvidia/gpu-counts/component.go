@@ -0,0 +1,565 @@
+// Package gpucounts tracks the number of NVIDIA GPUs against the expected baseline,
+// to detect the GPUs that fell off the bus or failed to initialize.
+package gpucounts
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+	"github.com/olekukonko/tablewriter"
+	"github.com/prometheus/client_golang/prometheus"
+
+	apiv1 "github.com/leptonai/gpud/api/v1"
+	"github.com/leptonai/gpud/components"
+	nvidiacommon "github.com/leptonai/gpud/pkg/config/common"
+	"github.com/leptonai/gpud/pkg/eventstore"
+	gpudstate "github.com/leptonai/gpud/pkg/gpud-state"
+	pkghost "github.com/leptonai/gpud/pkg/host"
+	"github.com/leptonai/gpud/pkg/kmsg"
+	"github.com/leptonai/gpud/pkg/log"
+	nvidiaquery "github.com/leptonai/gpud/pkg/nvidia-query"
+	nvidianvml "github.com/leptonai/gpud/pkg/nvidia-query/nvml"
+	"github.com/leptonai/gpud/pkg/pci"
+)
+
+const Name = "accelerator-nvidia-gpu-counts"
+
+const (
+	// BaselineSourceConfig is the baseline from the user configuration.
+	BaselineSourceConfig = "config"
+	// BaselineSourceState is the baseline persisted in the state database.
+	BaselineSourceState = "state"
+	// BaselineSourceDiscovered is the baseline discovered from NVML
+	// (e.g., first boot, or no state database).
+	BaselineSourceDiscovered = "discovered"
+)
+
+var _ components.Component = &component{}
+
+type component struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	nvmlInstance nvidianvml.InstanceV2
+	expectedGPUs *nvidiacommon.ExpectedGPUs
+
+	dbRW *sql.DB
+	dbRO *sql.DB
+
+	eventBucket eventstore.Bucket
+	kmsgSyncer  *kmsg.Syncer
+
+	listNVMLGPUsFunc func() (accessible []string, lost []string)
+	listPCIGPUsFunc  func(ctx context.Context) (pci.Devices, error)
+	countDevGPUsFunc func() (int, error)
+	getBootTimeFunc  func() time.Time
+
+	// baseline discovered when no state database is available
+	// or reset by "SetHealthy"
+	baselineMu     sync.Mutex
+	baseline       *gpudstate.GPUBaseline
+	configRecorded bool
+	// the "fallen off the bus" events before this time are ignored
+	healthyAt time.Time
+
+	lastMu   sync.RWMutex
+	lastData *Data
+}
+
+func New(gpudInstance *components.GPUdInstance) (components.Component, error) {
+	cctx, ccancel := context.WithCancel(gpudInstance.RootCtx)
+	c := &component{
+		ctx:    cctx,
+		cancel: ccancel,
+
+		nvmlInstance: gpudInstance.NVMLInstance,
+		expectedGPUs: gpudInstance.ExpectedGPUs,
+
+		dbRW: gpudInstance.DBRW,
+		dbRO: gpudInstance.DBRO,
+
+		listPCIGPUsFunc:  pci.ListNVIDIAGPUs,
+		countDevGPUsFunc: nvidiaquery.CountAllDevicesFromDevDir,
+		getBootTimeFunc: func() time.Time {
+			return time.Unix(int64(pkghost.BootTimeUnixSeconds()), 0).UTC()
+		},
+	}
+	c.listNVMLGPUsFunc = c.listNVMLGPUs
+
+	if c.dbRW != nil {
+		if err := gpudstate.CreateTableGPUBaseline(cctx, c.dbRW); err != nil {
+			ccancel()
+			return nil, err
+		}
+		if c.dbRO == nil {
+			c.dbRO = c.dbRW
+		}
+	}
+
+	if gpudInstance.EventStore != nil && runtime.GOOS == "linux" {
+		var err error
+		c.eventBucket, err = gpudInstance.EventStore.Bucket(Name)
+		if err != nil {
+			ccancel()
+			return nil, err
+		}
+
+		if gpudInstance.KmsgHub != nil {
+			c.kmsgSyncer, err = gpudInstance.KmsgHub.NewSyncer(cctx, Name, MatchEvent, c.eventBucket)
+			if err != nil {
+				ccancel()
+				return nil, err
+			}
+		}
+	}
+
+	return c, nil
+}
+
+func (c *component) Name() string { return Name }
+
+func (c *component) Start() error {
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+
+		for {
+			_ = c.Check()
+
+			select {
+			case <-c.ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return nil
+}
+
+func (c *component) LastHealthStates() apiv1.HealthStates {
+	c.lastMu.RLock()
+	lastData := c.lastData
+	c.lastMu.RUnlock()
+	return lastData.getLastHealthStates()
+}
+
+func (c *component) Events(ctx context.Context, since time.Time) (apiv1.Events, error) {
+	if c.eventBucket == nil {
+		return nil, nil
+	}
+	return c.eventBucket.Get(ctx, since)
+}
+
+func (c *component) Close() error {
+	log.Logger.Debugw("closing component")
+	c.cancel()
+
+	if c.kmsgSyncer != nil {
+		c.kmsgSyncer.Close()
+	}
+	if c.eventBucket != nil {
+		c.eventBucket.Close()
+	}
+
+	return nil
+}
+
+var _ components.HealthSettable = &component{}
+
+// SetHealthy resets the expected GPUs to the currently accessible GPUs,
+// and ignores the "fallen off the bus" events before now.
+// The user-configured expected GPUs still take precedence, if any.
+func (c *component) SetHealthy() error {
+	log.Logger.Debugw("set healthy event received")
+
+	accessible, _ := c.listNVMLGPUsFunc()
+	b := &gpudstate.GPUBaseline{
+		Count: len(accessible),
+		UUIDs: accessible,
+		Time:  time.Now().UTC(),
+	}
+
+	if c.dbRW != nil {
+		cctx, ccancel := context.WithTimeout(c.ctx, 30*time.Second)
+		err := gpudstate.RecordGPUBaseline(cctx, c.dbRW, b.Count, b.UUIDs)
+		ccancel()
+		if err != nil {
+			return err
+		}
+	}
+
+	c.baselineMu.Lock()
+	c.baseline = b
+	c.healthyAt = b.Time
+	c.baselineMu.Unlock()
+
+	return nil
+}
+
+// listNVMLGPUs returns the UUIDs of the GPUs from NVML,
+// separating the lost GPUs (e.g., fallen off the bus) from the accessible ones.
+func (c *component) listNVMLGPUs() ([]string, []string) {
+	if c.nvmlInstance == nil || !c.nvmlInstance.NVMLExists() {
+		return nil, nil
+	}
+
+	accessible := make([]string, 0)
+	lost := make([]string, 0)
+	for uuid, dev := range c.nvmlInstance.Devices() {
+		_, ret := dev.GetTemperature(nvml.TEMPERATURE_GPU)
+		if nvidianvml.IsGPULostError(ret) {
+			lost = append(lost, uuid)
+			continue
+		}
+		accessible = append(accessible, uuid)
+	}
+	sort.Strings(accessible)
+	sort.Strings(lost)
+	return accessible, lost
+}
+
+// getBaseline returns the expected GPUs, in the order of
+// the user configuration, the state database, and the GPUs discovered.
+// The discovered count is the maximum of NVML, lspci, and /dev, so that
+// the GPUs that NVML failed to initialize on the first boot are still expected.
+// Returns nil, if no baseline can be established (e.g., no GPU discovered).
+func (c *component) getBaseline(accessible []string, lost []string, pciCount int, devCount int) (*gpudstate.GPUBaseline, string, error) {
+	c.baselineMu.Lock()
+	defer c.baselineMu.Unlock()
+
+	if c.expectedGPUs != nil {
+		b := &gpudstate.GPUBaseline{
+			Count: c.expectedGPUs.Count,
+			UUIDs: c.expectedGPUs.UUIDs,
+		}
+
+		// persist once, so the configured GPUs are still expected
+		// even after the configuration is removed
+		if !c.configRecorded && c.dbRW != nil {
+			cctx, ccancel := context.WithTimeout(c.ctx, 30*time.Second)
+			err := gpudstate.RecordGPUBaseline(cctx, c.dbRW, b.Count, b.UUIDs)
+			ccancel()
+			if err != nil {
+				return nil, "", err
+			}
+			c.configRecorded = true
+		}
+		return b, BaselineSourceConfig, nil
+	}
+
+	if c.baseline == nil && c.dbRO != nil {
+		cctx, ccancel := context.WithTimeout(c.ctx, 30*time.Second)
+		b, err := gpudstate.ReadGPUBaseline(cctx, c.dbRO)
+		ccancel()
+		if err != nil {
+			return nil, "", err
+		}
+		if b != nil {
+			c.baseline = b
+			return c.baseline, BaselineSourceState, nil
+		}
+	}
+	if c.baseline != nil {
+		if c.dbRO != nil {
+			return c.baseline, BaselineSourceState, nil
+		}
+		return c.baseline, BaselineSourceDiscovered, nil
+	}
+
+	// first boot, the lost GPUs are still expected
+	uuids := make([]string, 0, len(accessible)+len(lost))
+	uuids = append(uuids, accessible...)
+	uuids = append(uuids, lost...)
+	sort.Strings(uuids)
+
+	cnt := len(uuids)
+	if pciCount > cnt {
+		cnt = pciCount
+	}
+	if devCount > cnt {
+		cnt = devCount
+	}
+	if cnt == 0 {
+		return nil, "", nil
+	}
+
+	b := &gpudstate.GPUBaseline{
+		Count: cnt,
+		UUIDs: uuids,
+		Time:  time.Now().UTC(),
+	}
+	if c.dbRW != nil {
+		cctx, ccancel := context.WithTimeout(c.ctx, 30*time.Second)
+		err := gpudstate.RecordGPUBaseline(cctx, c.dbRW, b.Count, b.UUIDs)
+		ccancel()
+		if err != nil {
+			return nil, "", err
+		}
+		log.Logger.Infow("recorded gpu baseline", "count", b.Count)
+	}
+	c.baseline = b
+
+	return c.baseline, BaselineSourceDiscovered, nil
+}
+
+func (c *component) Check() components.CheckResult {
+	log.Logger.Infow("checking nvidia gpu counts")
+
+	d := &Data{
+		ts: time.Now().UTC(),
+	}
+	defer func() {
+		c.lastMu.Lock()
+		c.lastData = d
+		c.lastMu.Unlock()
+	}()
+
+	accessible, lost := c.listNVMLGPUsFunc()
+	d.NVMLCount = len(accessible)
+	d.LostUUIDs = lost
+
+	if c.listPCIGPUsFunc != nil {
+		cctx, ccancel := context.WithTimeout(c.ctx, 30*time.Second)
+		devs, err := c.listPCIGPUsFunc(cctx)
+		ccancel()
+		if err != nil {
+			log.Logger.Warnw("failed to list nvidia gpus from lspci", "error", err)
+		} else {
+			d.PCICount = len(devs)
+		}
+	}
+	if c.countDevGPUsFunc != nil {
+		cnt, err := c.countDevGPUsFunc()
+		if err != nil {
+			log.Logger.Warnw("failed to count nvidia gpu device files", "error", err)
+		} else {
+			d.DevCount = cnt
+		}
+	}
+
+	baseline, source, err := c.getBaseline(accessible, lost, d.PCICount, d.DevCount)
+	if err != nil {
+		d.err = err
+		d.health = apiv1.HealthStateTypeUnhealthy
+		d.reason = fmt.Sprintf("error reading gpu baseline: %s", err)
+		return d
+	}
+	if baseline == nil {
+		d.health = apiv1.HealthStateTypeHealthy
+		d.reason = "no gpu found and no expected gpu count configured"
+		return d
+	}
+	d.ExpectedCount = baseline.Count
+	d.ExpectedUUIDs = baseline.UUIDs
+	d.BaselineSource = source
+
+	metricExpected.With(prometheus.Labels{}).Set(float64(d.ExpectedCount))
+	metricNVML.With(prometheus.Labels{}).Set(float64(d.NVMLCount))
+	metricPCI.With(prometheus.Labels{}).Set(float64(d.PCICount))
+	metricDevDir.With(prometheus.Labels{}).Set(float64(d.DevCount))
+
+	// the accessible GPUs may be listed in a different order
+	// than the expected ones, thus compare by set
+	present := make(map[string]struct{}, len(accessible))
+	for _, uuid := range accessible {
+		present[uuid] = struct{}{}
+	}
+	for _, uuid := range baseline.UUIDs {
+		if _, ok := present[uuid]; !ok {
+			d.MissingUUIDs = append(d.MissingUUIDs, uuid)
+		}
+	}
+
+	since := c.getBootTimeFunc()
+	c.baselineMu.Lock()
+	if c.healthyAt.After(since) {
+		since = c.healthyAt
+	}
+	c.baselineMu.Unlock()
+	if c.eventBucket != nil {
+		events, err := c.eventBucket.Get(c.ctx, since)
+		if err != nil {
+			d.err = err
+			d.health = apiv1.HealthStateTypeUnhealthy
+			d.reason = fmt.Sprintf("error getting events: %s", err)
+			return d
+		}
+		for _, ev := range events {
+			if ev.Name == eventGPUFallenOffBus {
+				d.FallenOffBusEvents++
+			}
+		}
+	}
+
+	issues := make([]string, 0)
+	if d.NVMLCount < d.ExpectedCount {
+		issues = append(issues, fmt.Sprintf("nvml found %d gpu(s) but expected %d", d.NVMLCount, d.ExpectedCount))
+	} else {
+		// the expected count may be lower than the GPUs on the bus
+		// (e.g., misconfigured), still NVML must see all of them
+		if d.NVMLCount < d.PCICount {
+			issues = append(issues, fmt.Sprintf("nvml found %d gpu(s) but lspci found %d", d.NVMLCount, d.PCICount))
+		}
+		if d.NVMLCount < d.DevCount {
+			issues = append(issues, fmt.Sprintf("nvml found %d gpu(s) but /dev found %d", d.NVMLCount, d.DevCount))
+		}
+	}
+	// lspci and /dev are optional sources (e.g., lspci not installed, container without /dev)
+	if d.PCICount > 0 && d.PCICount < d.ExpectedCount {
+		issues = append(issues, fmt.Sprintf("lspci found %d gpu(s) but expected %d", d.PCICount, d.ExpectedCount))
+	}
+	if d.DevCount > 0 && d.DevCount < d.ExpectedCount {
+		issues = append(issues, fmt.Sprintf("/dev found %d gpu(s) but expected %d", d.DevCount, d.ExpectedCount))
+	}
+	if len(d.LostUUIDs) > 0 {
+		issues = append(issues, fmt.Sprintf("%d gpu(s) lost (%s)", len(d.LostUUIDs), strings.Join(d.LostUUIDs, ", ")))
+	}
+	if len(d.MissingUUIDs) > 0 {
+		issues = append(issues, fmt.Sprintf("%d expected gpu(s) not found (%s)", len(d.MissingUUIDs), strings.Join(d.MissingUUIDs, ", ")))
+	}
+	if d.FallenOffBusEvents > 0 {
+		issues = append(issues, fmt.Sprintf("%d gpu fallen off the bus event(s) since boot", d.FallenOffBusEvents))
+	}
+
+	if len(issues) == 0 {
+		d.health = apiv1.HealthStateTypeHealthy
+		d.reason = fmt.Sprintf("all %d expected gpu(s) found", d.ExpectedCount)
+		return d
+	}
+
+	d.health = apiv1.HealthStateTypeUnhealthy
+	d.reason = strings.Join(issues, "; ")
+	d.suggestedActions = &apiv1.SuggestedActions{
+		RepairActions: []apiv1.RepairActionType{
+			apiv1.RepairActionTypeHardwareInspection,
+		},
+		DeprecatedDescriptions: []string{
+			"GPU(s) missing from the system -- inspect the GPU, riser, and PCIe slot (or the baseboard)",
+		},
+	}
+
+	return d
+}
+
+var _ components.CheckResult = &Data{}
+
+type Data struct {
+	// ExpectedCount is the expected number of GPUs.
+	ExpectedCount int `json:"expected_count"`
+	// ExpectedUUIDs is the expected GPU UUIDs, if known.
+	ExpectedUUIDs []string `json:"expected_uuids,omitempty"`
+	// BaselineSource is where the expected GPUs come from
+	// (e.g., "config", "state", "discovered").
+	BaselineSource string `json:"baseline_source,omitempty"`
+
+	// NVMLCount is the number of accessible GPUs from NVML.
+	NVMLCount int `json:"nvml_count"`
+	// PCICount is the number of GPUs on the PCI bus, zero if unknown.
+	PCICount int `json:"pci_count"`
+	// DevCount is the number of GPU device files, zero if unknown.
+	DevCount int `json:"dev_count"`
+
+	// LostUUIDs is the GPUs that NVML reports as lost.
+	LostUUIDs []string `json:"lost_uuids,omitempty"`
+	// MissingUUIDs is the expected GPUs that are not accessible.
+	MissingUUIDs []string `json:"missing_uuids,omitempty"`
+	// FallenOffBusEvents is the number of "GPU has fallen off the bus"
+	// kernel messages since boot.
+	FallenOffBusEvents int `json:"fallen_off_bus_events"`
+
+	// timestamp of the last check
+	ts time.Time
+	// error from the last check
+	err error
+
+	// tracks the healthy evaluation result of the last check
+	health apiv1.HealthStateType
+	// tracks the reason of the last check
+	reason string
+	// tracks the suggested actions of the last check
+	suggestedActions *apiv1.SuggestedActions
+}
+
+func (d *Data) String() string {
+	if d == nil {
+		return ""
+	}
+	if d.ExpectedCount == 0 {
+		return "no data"
+	}
+
+	buf := bytes.NewBuffer(nil)
+	table := tablewriter.NewWriter(buf)
+	table.SetAlignment(tablewriter.ALIGN_CENTER)
+	table.Append([]string{"Expected", fmt.Sprintf("%d (%s)", d.ExpectedCount, d.BaselineSource)})
+	table.Append([]string{"NVML", fmt.Sprintf("%d", d.NVMLCount)})
+	table.Append([]string{"lspci", fmt.Sprintf("%d", d.PCICount)})
+	table.Append([]string{"/dev", fmt.Sprintf("%d", d.DevCount)})
+	if len(d.LostUUIDs) > 0 {
+		table.Append([]string{"Lost", strings.Join(d.LostUUIDs, "\n")})
+	}
+	if len(d.MissingUUIDs) > 0 {
+		table.Append([]string{"Missing", strings.Join(d.MissingUUIDs, "\n")})
+	}
+	table.Append([]string{"Fallen off the bus events", fmt.Sprintf("%d", d.FallenOffBusEvents)})
+	table.Render()
+
+	return buf.String()
+}
+
+func (d *Data) Summary() string {
+	if d == nil {
+		return ""
+	}
+	return d.reason
+}
+
+func (d *Data) HealthState() apiv1.HealthStateType {
+	if d == nil {
+		return ""
+	}
+	return d.health
+}
+
+func (d *Data) getError() string {
+	if d == nil || d.err == nil {
+		return ""
+	}
+	return d.err.Error()
+}
+
+func (d *Data) getLastHealthStates() apiv1.HealthStates {
+	if d == nil {
+		return apiv1.HealthStates{
+			{
+				Name:   Name,
+				Health: apiv1.HealthStateTypeHealthy,
+				Reason: "no data yet",
+			},
+		}
+	}
+
+	state := apiv1.HealthState{
+		Name:             Name,
+		Reason:           d.reason,
+		Error:            d.getError(),
+		Health:           d.health,
+		SuggestedActions: d.suggestedActions,
+	}
+
+	b, _ := json.Marshal(d)
+	state.DeprecatedExtraInfo = map[string]string{
+		"data":     string(b),
+		"encoding": "json",
+	}
+	return apiv1.HealthStates{state}
+}
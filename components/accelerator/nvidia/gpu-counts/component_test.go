@@ -0,0 +1,262 @@
+package gpucounts
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	apiv1 "github.com/leptonai/gpud/api/v1"
+	"github.com/leptonai/gpud/components"
+	nvidiacommon "github.com/leptonai/gpud/pkg/config/common"
+	"github.com/leptonai/gpud/pkg/eventstore"
+	gpudstate "github.com/leptonai/gpud/pkg/gpud-state"
+	"github.com/leptonai/gpud/pkg/pci"
+	"github.com/leptonai/gpud/pkg/sqlite"
+)
+
+func newTestComponent(t *testing.T, gpudInstance *components.GPUdInstance, accessible []string, lost []string, pciCount int, devCount int) *component {
+	c, err := New(gpudInstance)
+	require.NoError(t, err)
+
+	cc := c.(*component)
+	cc.listNVMLGPUsFunc = func() ([]string, []string) {
+		return accessible, lost
+	}
+	cc.listPCIGPUsFunc = func(ctx context.Context) (pci.Devices, error) {
+		return make(pci.Devices, pciCount), nil
+	}
+	cc.countDevGPUsFunc = func() (int, error) {
+		return devCount, nil
+	}
+	cc.getBootTimeFunc = func() time.Time {
+		return time.Now().Add(-time.Hour)
+	}
+	return cc
+}
+
+func TestCheckDiscoveredBaseline(t *testing.T) {
+	t.Parallel()
+
+	dbRW, dbRO, cleanup := sqlite.OpenTestDB(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	gpudInstance := &components.GPUdInstance{RootCtx: ctx, DBRW: dbRW, DBRO: dbRO}
+
+	// first boot, all GPUs are recorded as the baseline
+	c := newTestComponent(t, gpudInstance, []string{"GPU-1", "GPU-2"}, nil, 2, 2)
+	defer c.Close()
+
+	d := c.Check().(*Data)
+	assert.Equal(t, apiv1.HealthStateTypeHealthy, d.HealthState())
+	assert.Equal(t, 2, d.ExpectedCount)
+	assert.Equal(t, BaselineSourceDiscovered, d.BaselineSource)
+
+	b, err := gpudstate.ReadGPUBaseline(ctx, dbRO)
+	require.NoError(t, err)
+	require.NotNil(t, b)
+	assert.Equal(t, []string{"GPU-1", "GPU-2"}, b.UUIDs)
+
+	// restart, one GPU is gone from NVML, lspci, and /dev
+	c2 := newTestComponent(t, gpudInstance, []string{"GPU-1"}, nil, 1, 1)
+	defer c2.Close()
+
+	d = c2.Check().(*Data)
+	assert.Equal(t, apiv1.HealthStateTypeUnhealthy, d.HealthState())
+	assert.Equal(t, BaselineSourceState, d.BaselineSource)
+	assert.Equal(t, []string{"GPU-2"}, d.MissingUUIDs)
+	assert.Contains(t, d.Summary(), "nvml found 1 gpu(s) but expected 2")
+	assert.Contains(t, d.Summary(), "lspci found 1 gpu(s) but expected 2")
+	assert.Contains(t, d.Summary(), "/dev found 1 gpu(s) but expected 2")
+
+	states := c2.LastHealthStates()
+	require.Len(t, states, 1)
+	require.NotNil(t, states[0].SuggestedActions)
+	assert.Equal(t, []apiv1.RepairActionType{apiv1.RepairActionTypeHardwareInspection}, states[0].SuggestedActions.RepairActions)
+
+	// reset the baseline to the current GPUs
+	require.NoError(t, c2.SetHealthy())
+	d = c2.Check().(*Data)
+	assert.Equal(t, apiv1.HealthStateTypeHealthy, d.HealthState())
+	assert.Equal(t, 1, d.ExpectedCount)
+}
+
+func TestCheckDiscoveredBaselineNVMLMissingOnFirstBoot(t *testing.T) {
+	t.Parallel()
+
+	dbRW, dbRO, cleanup := sqlite.OpenTestDB(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	gpudInstance := &components.GPUdInstance{RootCtx: ctx, DBRW: dbRW, DBRO: dbRO}
+
+	// first boot, NVML only sees 7 of the 8 GPUs on the bus
+	accessible := []string{"GPU-1", "GPU-2", "GPU-3", "GPU-4", "GPU-5", "GPU-6", "GPU-7"}
+	c := newTestComponent(t, gpudInstance, accessible, nil, 8, 8)
+	defer c.Close()
+
+	d := c.Check().(*Data)
+	assert.Equal(t, apiv1.HealthStateTypeUnhealthy, d.HealthState())
+	assert.Equal(t, BaselineSourceDiscovered, d.BaselineSource)
+	assert.Equal(t, 8, d.ExpectedCount)
+	assert.Equal(t, "nvml found 7 gpu(s) but expected 8", d.Summary())
+
+	states := c.LastHealthStates()
+	require.Len(t, states, 1)
+	require.NotNil(t, states[0].SuggestedActions)
+	assert.Equal(t, []apiv1.RepairActionType{apiv1.RepairActionTypeHardwareInspection}, states[0].SuggestedActions.RepairActions)
+
+	// the 8 GPUs are still expected after restart
+	b, err := gpudstate.ReadGPUBaseline(ctx, dbRO)
+	require.NoError(t, err)
+	require.NotNil(t, b)
+	assert.Equal(t, 8, b.Count)
+
+	// /dev only (e.g., lspci not installed)
+	c2 := newTestComponent(t, &components.GPUdInstance{RootCtx: ctx}, accessible, nil, 0, 8)
+	defer c2.Close()
+
+	d = c2.Check().(*Data)
+	assert.Equal(t, apiv1.HealthStateTypeUnhealthy, d.HealthState())
+	assert.Equal(t, 8, d.ExpectedCount)
+}
+
+func TestCheckConfiguredBaselineBelowPCI(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	gpudInstance := &components.GPUdInstance{
+		RootCtx:      ctx,
+		ExpectedGPUs: &nvidiacommon.ExpectedGPUs{Count: 7},
+	}
+
+	// configured count is met, but NVML still misses one GPU on the bus
+	c := newTestComponent(t, gpudInstance, []string{"GPU-1", "GPU-2", "GPU-3", "GPU-4", "GPU-5", "GPU-6", "GPU-7"}, nil, 8, 8)
+	defer c.Close()
+
+	d := c.Check().(*Data)
+	assert.Equal(t, apiv1.HealthStateTypeUnhealthy, d.HealthState())
+	assert.Equal(t, "nvml found 7 gpu(s) but lspci found 8; nvml found 7 gpu(s) but /dev found 8", d.Summary())
+}
+
+func TestCheckConfiguredBaseline(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	gpudInstance := &components.GPUdInstance{
+		RootCtx:      ctx,
+		ExpectedGPUs: &nvidiacommon.ExpectedGPUs{Count: 8},
+	}
+
+	// NVML failed to initialize the GPUs, but lspci still sees them
+	c := newTestComponent(t, gpudInstance, nil, nil, 8, 0)
+	defer c.Close()
+
+	d := c.Check().(*Data)
+	assert.Equal(t, apiv1.HealthStateTypeUnhealthy, d.HealthState())
+	assert.Equal(t, BaselineSourceConfig, d.BaselineSource)
+	assert.Equal(t, 8, d.ExpectedCount)
+	assert.Equal(t, 0, d.NVMLCount)
+	assert.Equal(t, 8, d.PCICount)
+	assert.Equal(t, "nvml found 0 gpu(s) but expected 8", d.Summary())
+}
+
+func TestCheckLostGPU(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	gpudInstance := &components.GPUdInstance{RootCtx: ctx}
+
+	// first check discovers the lost GPU as expected, but still unhealthy
+	c := newTestComponent(t, gpudInstance, []string{"GPU-1"}, []string{"GPU-2"}, 2, 2)
+	defer c.Close()
+
+	d := c.Check().(*Data)
+	assert.Equal(t, apiv1.HealthStateTypeUnhealthy, d.HealthState())
+	assert.Equal(t, 2, d.ExpectedCount)
+	assert.Equal(t, []string{"GPU-2"}, d.LostUUIDs)
+	assert.Equal(t, []string{"GPU-2"}, d.MissingUUIDs)
+}
+
+func TestCheckNoGPU(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	c := newTestComponent(t, &components.GPUdInstance{RootCtx: ctx}, nil, nil, 0, 0)
+	defer c.Close()
+
+	d := c.Check().(*Data)
+	assert.Equal(t, apiv1.HealthStateTypeHealthy, d.HealthState())
+	assert.Equal(t, "no data", d.String())
+}
+
+func TestCheckFallenOffBusEvents(t *testing.T) {
+	t.Parallel()
+
+	dbRW, dbRO, cleanup := sqlite.OpenTestDB(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	store, err := eventstore.New(dbRW, dbRO, eventstore.DefaultRetention)
+	require.NoError(t, err)
+
+	c := newTestComponent(t, &components.GPUdInstance{RootCtx: ctx}, []string{"GPU-1"}, nil, 1, 1)
+	defer c.Close()
+
+	// the event bucket is only created on linux
+	c.eventBucket, err = store.Bucket(Name)
+	require.NoError(t, err)
+
+	// before boot, ignored
+	require.NoError(t, c.eventBucket.Insert(ctx, apiv1.Event{
+		Time:    metav1.Time{Time: time.Now().Add(-2 * time.Hour)},
+		Name:    eventGPUFallenOffBus,
+		Type:    apiv1.EventTypeWarning,
+		Message: messageGPUFallenOffBus,
+	}))
+	d := c.Check().(*Data)
+	assert.Equal(t, apiv1.HealthStateTypeHealthy, d.HealthState())
+
+	require.NoError(t, c.eventBucket.Insert(ctx, apiv1.Event{
+		Time:    metav1.Time{Time: time.Now().Add(-time.Minute)},
+		Name:    eventGPUFallenOffBus,
+		Type:    apiv1.EventTypeWarning,
+		Message: messageGPUFallenOffBus,
+	}))
+	d = c.Check().(*Data)
+	assert.Equal(t, apiv1.HealthStateTypeUnhealthy, d.HealthState())
+	assert.Equal(t, 1, d.FallenOffBusEvents)
+
+	require.NoError(t, c.SetHealthy())
+	d = c.Check().(*Data)
+	assert.Equal(t, apiv1.HealthStateTypeHealthy, d.HealthState())
+}
+
+func TestDataNil(t *testing.T) {
+	var d *Data
+	assert.Equal(t, "", d.String())
+	assert.Equal(t, "", d.Summary())
+	assert.Equal(t, apiv1.HealthStateType(""), d.HealthState())
+
+	states := d.getLastHealthStates()
+	require.Len(t, states, 1)
+	assert.Equal(t, "no data yet", states[0].Reason)
+}
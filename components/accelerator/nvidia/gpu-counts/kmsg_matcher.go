@@ -0,0 +1,63 @@
+package gpucounts
+
+import (
+	"regexp"
+
+	apiv1 "github.com/leptonai/gpud/api/v1"
+	"github.com/leptonai/gpud/pkg/kmsg"
+)
+
+const (
+	// e.g.,
+	// [Sun Mar  2 10:21:33 2025] NVRM: GPU at PCI:0000:9b:00: GPU-a1b2c3d4-e5f6-7890-abcd-ef1234567890
+	// [Sun Mar  2 10:21:33 2025] NVRM: Xid (PCI:0000:9b:00): 79, pid='<unknown>', name=<unknown>, GPU has fallen off the bus.
+	// [Sun Mar  2 10:21:33 2025] NVRM: GPU 0000:9b:00.0: GPU has fallen off the bus.
+	eventGPUFallenOffBus   = "nvidia_gpu_fallen_off_bus"
+	regexGPUFallenOffBus   = `GPU has fallen off the bus`
+	messageGPUFallenOffBus = `GPU has fallen off the bus (requires hardware inspection)`
+)
+
+var (
+	compiledGPUFallenOffBus = regexp.MustCompile(regexGPUFallenOffBus)
+)
+
+// HasGPUFallenOffBus returns true if the line indicates that the GPU has fallen off the bus.
+func HasGPUFallenOffBus(line string) bool {
+	if match := compiledGPUFallenOffBus.FindStringSubmatch(line); match != nil {
+		return true
+	}
+	return false
+}
+
+func Match(line string) (eventName string, message string) {
+	ev := MatchEvent(line)
+	if ev == nil {
+		return "", ""
+	}
+	return ev.Name, ev.Message
+}
+
+func MatchEvent(line string) *kmsg.MatchedEvent {
+	for _, m := range getMatches() {
+		if m.check(line) {
+			return kmsg.NewMatchedEvent(m.eventName, m.message, m.eventType, m.repairActions)
+		}
+	}
+	return nil
+}
+
+type match struct {
+	check     func(string) bool
+	eventName string
+	regex     string
+	message   string
+
+	eventType     apiv1.EventType
+	repairActions []apiv1.RepairActionType
+}
+
+func getMatches() []match {
+	return []match{
+		{check: HasGPUFallenOffBus, eventName: eventGPUFallenOffBus, regex: regexGPUFallenOffBus, message: messageGPUFallenOffBus, eventType: apiv1.EventTypeFatal, repairActions: []apiv1.RepairActionType{apiv1.RepairActionTypeHardwareInspection}},
+	}
+}
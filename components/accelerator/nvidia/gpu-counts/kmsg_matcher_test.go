@@ -0,0 +1,109 @@
+package gpucounts
+
+import (
+	"testing"
+
+	apiv1 "github.com/leptonai/gpud/api/v1"
+)
+
+func TestHasGPUFallenOffBus(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want bool
+	}{
+		{
+			name: "xid 79 message",
+			line: "NVRM: Xid (PCI:0000:9b:00): 79, pid='<unknown>', name=<unknown>, GPU has fallen off the bus.",
+			want: true,
+		},
+		{
+			name: "with timestamp prefix",
+			line: "[Sun Mar  2 10:21:33 2025] NVRM: GPU 0000:9b:00.0: GPU has fallen off the bus.",
+			want: true,
+		},
+		{
+			name: "with ISO timestamp and facility",
+			line: "kern  :err   : 2025-03-02T10:21:33,123456+00:00 NVRM: GPU 0000:9b:00.0: GPU has fallen off the bus.",
+			want: true,
+		},
+		{
+			name: "no match - different message",
+			line: "NVRM: GPU at PCI:0000:9b:00: GPU-a1b2c3d4-e5f6-7890-abcd-ef1234567890",
+			want: false,
+		},
+		{
+			name: "empty string",
+			line: "",
+			want: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HasGPUFallenOffBus(tt.line); got != tt.want {
+				t.Errorf("HasGPUFallenOffBus(%q) = %v, want %v", tt.line, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		name        string
+		line        string
+		wantName    string
+		wantMessage string
+	}{
+		{
+			name:        "gpu fallen off the bus",
+			line:        "[Sun Mar  2 10:21:33 2025] NVRM: GPU 0000:9b:00.0: GPU has fallen off the bus.",
+			wantName:    eventGPUFallenOffBus,
+			wantMessage: messageGPUFallenOffBus,
+		},
+		{
+			name:        "no match",
+			line:        "NVRM: loading NVIDIA UNIX x86_64 Kernel Module  535.161.08",
+			wantName:    "",
+			wantMessage: "",
+		},
+		{
+			name:        "empty string",
+			line:        "",
+			wantName:    "",
+			wantMessage: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotName, gotMessage := Match(tt.line)
+			if gotName != tt.wantName {
+				t.Errorf("Match() name = %v, want %v", gotName, tt.wantName)
+			}
+			if gotMessage != tt.wantMessage {
+				t.Errorf("Match() message = %v, want %v", gotMessage, tt.wantMessage)
+			}
+		})
+	}
+}
+
+func TestMatchEvent(t *testing.T) {
+	ev := MatchEvent("NVRM: Xid (PCI:0000:9b:00): 79, pid='<unknown>', name=<unknown>, GPU has fallen off the bus.")
+	if ev == nil {
+		t.Fatal("MatchEvent() = nil, want event")
+	}
+	if ev.Name != eventGPUFallenOffBus || ev.Message != messageGPUFallenOffBus {
+		t.Errorf("MatchEvent() = %v %v, want %v %v", ev.Name, ev.Message, eventGPUFallenOffBus, messageGPUFallenOffBus)
+	}
+	if ev.Type != apiv1.EventTypeFatal {
+		t.Errorf("MatchEvent() type = %v, want %v", ev.Type, apiv1.EventTypeFatal)
+	}
+	if ev.SuggestedActions == nil || len(ev.SuggestedActions.RepairActions) != 1 || ev.SuggestedActions.RepairActions[0] != apiv1.RepairActionTypeHardwareInspection {
+		t.Errorf("MatchEvent() suggested actions = %+v, want %v", ev.SuggestedActions, apiv1.RepairActionTypeHardwareInspection)
+	}
+
+	if ev := MatchEvent("no match"); ev != nil {
+		t.Errorf("MatchEvent() = %+v, want nil", ev)
+	}
+}
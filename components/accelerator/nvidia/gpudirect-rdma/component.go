@@ -0,0 +1,340 @@
+// Package gpudirectrdma checks the GPUDirect RDMA readiness of the host,
+// as a single pass/fail result of its prerequisites (nvidia_peermem, ACS, IOMMU passthrough,
+// memlock limit of the workloads and active infiniband ports), reporting exactly which one is missing.
+package gpudirectrdma
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+
+	apiv1 "github.com/leptonai/gpud/api/v1"
+	"github.com/leptonai/gpud/components"
+	pkghost "github.com/leptonai/gpud/pkg/host"
+	"github.com/leptonai/gpud/pkg/log"
+	"github.com/leptonai/gpud/pkg/nvidia-query/infiniband"
+	nvidianvml "github.com/leptonai/gpud/pkg/nvidia-query/nvml"
+	"github.com/leptonai/gpud/pkg/nvidia-query/peermem"
+	"github.com/leptonai/gpud/pkg/pci"
+)
+
+const Name = "accelerator-nvidia-gpudirect-rdma"
+
+// acsCheckInterval is the interval to re-list the PCI devices for the ACS check,
+// same as the "pci" component, since "lspci -vvv" is expensive and the ACS
+// setting does not change without a reboot (or a BIOS change).
+const acsCheckInterval = 24 * time.Hour
+
+var _ components.Component = &component{}
+
+type component struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	nvmlInstance      nvidianvml.InstanceV2
+	currentVirtEnv    pkghost.VirtualizationEnvironment
+	getIBPortsFunc    func() (infiniband.IBPorts, error)
+	checkPeermemFunc  func(ctx context.Context) (*peermem.LsmodPeermemModuleOutput, error)
+	getPCIDevicesFunc func(ctx context.Context) (pci.Devices, error)
+	readCmdlineFunc   func() (string, error)
+	iommuEnabledFunc  func() bool
+	// returns the source of the workload limits (e.g., "containerd (pid 1234)") and the limits
+	readLimitsFunc func() (string, string, error)
+
+	// caches the ACS check result to not run "lspci" every check
+	acsMu        sync.Mutex
+	acsResult    *Prerequisite
+	acsCheckedAt time.Time
+
+	lastMu   sync.RWMutex
+	lastData *Data
+}
+
+func New(gpudInstance *components.GPUdInstance) (components.Component, error) {
+	cctx, ccancel := context.WithCancel(gpudInstance.RootCtx)
+	c := &component{
+		ctx:            cctx,
+		cancel:         ccancel,
+		nvmlInstance:   gpudInstance.NVMLInstance,
+		currentVirtEnv: pkghost.VirtualizationEnv(),
+		getIBPortsFunc: func() (infiniband.IBPorts, error) {
+			return infiniband.GetIBPorts(infiniband.DefaultSysfsRoot)
+		},
+		checkPeermemFunc:  peermem.CheckLsmodPeermemModule,
+		getPCIDevicesFunc: pci.List,
+		readCmdlineFunc: func() (string, error) {
+			return readFile(defaultCmdlinePath)
+		},
+		iommuEnabledFunc: func() bool {
+			return isIOMMUEnabled(defaultSysfsRoot)
+		},
+		readLimitsFunc: func() (string, string, error) {
+			return readWorkloadLimits(defaultProcRoot)
+		},
+	}
+	return c, nil
+}
+
+func readFile(path string) (string, error) {
+	b, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func (c *component) Name() string { return Name }
+
+func (c *component) Start() error {
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+
+		for {
+			_ = c.Check()
+
+			select {
+			case <-c.ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return nil
+}
+
+func (c *component) LastHealthStates() apiv1.HealthStates {
+	c.lastMu.RLock()
+	lastData := c.lastData
+	c.lastMu.RUnlock()
+	return lastData.getLastHealthStates()
+}
+
+func (c *component) Events(ctx context.Context, since time.Time) (apiv1.Events, error) {
+	return nil, nil
+}
+
+func (c *component) Close() error {
+	log.Logger.Debugw("closing component")
+
+	c.cancel()
+
+	return nil
+}
+
+func (c *component) Check() components.CheckResult {
+	log.Logger.Infow("checking nvidia gpudirect rdma readiness")
+
+	d := &Data{
+		ts: time.Now().UTC(),
+	}
+	defer func() {
+		c.lastMu.Lock()
+		c.lastData = d
+		c.lastMu.Unlock()
+	}()
+
+	if c.nvmlInstance == nil {
+		d.health = apiv1.HealthStateTypeHealthy
+		d.reason = "NVIDIA NVML instance is nil"
+		return d
+	}
+	if !c.nvmlInstance.NVMLExists() {
+		d.health = apiv1.HealthStateTypeHealthy
+		d.reason = "NVIDIA NVML is not loaded"
+		return d
+	}
+
+	// GPUDirect RDMA is not applicable without the RDMA devices
+	ports, err := c.getIBPortsFunc()
+	if err != nil {
+		if errors.Is(err, infiniband.ErrNoSysfsDevice) {
+			d.health = apiv1.HealthStateTypeHealthy
+			d.reason = "no infiniband device found (gpudirect rdma not applicable)"
+			return d
+		}
+
+		d.err = err
+		d.health = apiv1.HealthStateTypeUnhealthy
+		d.reason = "error getting infiniband ports"
+
+		log.Logger.Errorw(d.reason, "error", err)
+		return d
+	}
+
+	cctx, ccancel := context.WithTimeout(c.ctx, 30*time.Second)
+	d.Prerequisites = append(d.Prerequisites, checkPeermem(c.checkPeermemFunc(cctx)))
+	ccancel()
+
+	d.Prerequisites = append(d.Prerequisites, c.checkACS(d.ts))
+
+	cmdline, err := c.readCmdlineFunc()
+	if err != nil {
+		d.Prerequisites = append(d.Prerequisites, Prerequisite{Name: PrerequisiteIOMMUPassthrough, Message: fmt.Sprintf("error reading kernel command line: %v", err)})
+	} else {
+		d.Prerequisites = append(d.Prerequisites, checkIOMMUPassthrough(cmdline, c.iommuEnabledFunc()))
+	}
+
+	source, limits, err := c.readLimitsFunc()
+	if err != nil {
+		d.Prerequisites = append(d.Prerequisites, Prerequisite{Name: PrerequisiteMemlockUnlimited, Message: fmt.Sprintf("error reading process limits of %s: %v", source, err)})
+	} else {
+		d.Prerequisites = append(d.Prerequisites, checkMemlockUnlimited(source, limits))
+	}
+
+	d.Prerequisites = append(d.Prerequisites, checkInfinibandActive(ports))
+
+	for _, p := range d.Prerequisites {
+		switch {
+		case p.Unverified:
+			d.Unverified = append(d.Unverified, p.Name)
+		case !p.Passed:
+			d.Missing = append(d.Missing, p.Name)
+		}
+	}
+	d.Ready = len(d.Missing) == 0
+
+	switch {
+	case d.Ready && len(d.Unverified) > 0:
+		d.health = apiv1.HealthStateTypeHealthy
+		d.reason = fmt.Sprintf("gpudirect rdma ready (%d prerequisites met, cannot verify %s)", len(d.Prerequisites)-len(d.Unverified), strings.Join(d.Unverified, ", "))
+	case d.Ready:
+		d.health = apiv1.HealthStateTypeHealthy
+		d.reason = fmt.Sprintf("gpudirect rdma ready (all %d prerequisites met)", len(d.Prerequisites))
+	default:
+		d.health = apiv1.HealthStateTypeUnhealthy
+		d.reason = fmt.Sprintf("gpudirect rdma not ready, missing %s", strings.Join(d.Missing, ", "))
+	}
+
+	return d
+}
+
+// checkACS returns the ACS check result, cached for the acsCheckInterval.
+// The error listing the PCI devices is not cached, to retry in the next check.
+func (c *component) checkACS(now time.Time) Prerequisite {
+	c.acsMu.Lock()
+	defer c.acsMu.Unlock()
+
+	if c.acsResult != nil && now.Sub(c.acsCheckedAt) < acsCheckInterval {
+		return *c.acsResult
+	}
+
+	cctx, ccancel := context.WithTimeout(c.ctx, 15*time.Second)
+	devs, err := c.getPCIDevicesFunc(cctx)
+	ccancel()
+	if err != nil {
+		return Prerequisite{Name: PrerequisiteACSDisabled, Message: fmt.Sprintf("error listing PCI devices: %v", err)}
+	}
+
+	p := checkACSDisabled(c.currentVirtEnv, devs)
+	c.acsResult = &p
+	c.acsCheckedAt = now
+	return p
+}
+
+var _ components.CheckResult = &Data{}
+
+type Data struct {
+	// Ready is true if all the prerequisites are met.
+	Ready bool `json:"ready"`
+	// Prerequisites is the result of each prerequisite check.
+	Prerequisites []Prerequisite `json:"prerequisites,omitempty"`
+	// Missing is the names of the prerequisites not met.
+	Missing []string `json:"missing,omitempty"`
+	// Unverified is the names of the prerequisites that cannot be verified on the host,
+	// which do not fail the readiness.
+	Unverified []string `json:"unverified,omitempty"`
+
+	// timestamp of the last check
+	ts time.Time
+	// error from the last check
+	err error
+
+	// tracks the healthy evaluation result of the last check
+	health apiv1.HealthStateType
+	// tracks the reason of the last check
+	reason string
+}
+
+func (d *Data) String() string {
+	if d == nil {
+		return ""
+	}
+	if len(d.Prerequisites) == 0 {
+		return "no data"
+	}
+
+	buf := bytes.NewBuffer(nil)
+	table := tablewriter.NewWriter(buf)
+	table.SetAlignment(tablewriter.ALIGN_CENTER)
+	table.SetHeader([]string{"Prerequisite", "Passed", "Message"})
+	for _, p := range d.Prerequisites {
+		passed := "yes"
+		switch {
+		case p.Unverified:
+			passed = "unverified"
+		case !p.Passed:
+			passed = "no"
+		}
+		table.Append([]string{p.Name, passed, p.Message})
+	}
+	table.Render()
+
+	return buf.String()
+}
+
+func (d *Data) Summary() string {
+	if d == nil {
+		return ""
+	}
+	return d.reason
+}
+
+func (d *Data) HealthState() apiv1.HealthStateType {
+	if d == nil {
+		return ""
+	}
+	return d.health
+}
+
+func (d *Data) getError() string {
+	if d == nil || d.err == nil {
+		return ""
+	}
+	return d.err.Error()
+}
+
+func (d *Data) getLastHealthStates() apiv1.HealthStates {
+	if d == nil {
+		return apiv1.HealthStates{
+			{
+				Name:   Name,
+				Health: apiv1.HealthStateTypeHealthy,
+				Reason: "no data yet",
+			},
+		}
+	}
+
+	state := apiv1.HealthState{
+		Name:   Name,
+		Reason: d.reason,
+		Error:  d.getError(),
+		Health: d.health,
+	}
+
+	b, _ := json.Marshal(d)
+	state.DeprecatedExtraInfo = map[string]string{
+		"data":     string(b),
+		"encoding": "json",
+	}
+	return apiv1.HealthStates{state}
+}
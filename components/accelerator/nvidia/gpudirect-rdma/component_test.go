@@ -0,0 +1,391 @@
+package gpudirectrdma
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/NVIDIA/go-nvlib/pkg/nvlib/device"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	apiv1 "github.com/leptonai/gpud/api/v1"
+	"github.com/leptonai/gpud/components"
+	pkghost "github.com/leptonai/gpud/pkg/host"
+	"github.com/leptonai/gpud/pkg/nvidia-query/infiniband"
+	nvidianvml "github.com/leptonai/gpud/pkg/nvidia-query/nvml"
+	nvml_lib "github.com/leptonai/gpud/pkg/nvidia-query/nvml/lib"
+	"github.com/leptonai/gpud/pkg/nvidia-query/peermem"
+	"github.com/leptonai/gpud/pkg/pci"
+)
+
+// MockNvmlInstance implements the nvidianvml.InstanceV2 interface for testing
+type MockNvmlInstance struct {
+	exists bool
+}
+
+func (m *MockNvmlInstance) Devices() map[string]device.Device {
+	return nil
+}
+
+func (m *MockNvmlInstance) GetMemoryErrorManagementCapabilities() nvidianvml.MemoryErrorManagementCapabilities {
+	return nvidianvml.MemoryErrorManagementCapabilities{}
+}
+
+func (m *MockNvmlInstance) ProductName() string {
+	return "NVIDIA H100 80GB HBM3"
+}
+
+func (m *MockNvmlInstance) NVMLExists() bool {
+	return m.exists
+}
+
+func (m *MockNvmlInstance) Library() nvml_lib.Library {
+	return nil
+}
+
+func (m *MockNvmlInstance) Shutdown() error {
+	return nil
+}
+
+func readTestData(t *testing.T, name string) string {
+	b, err := os.ReadFile(filepath.Join("testdata", name))
+	require.NoError(t, err)
+	return string(b)
+}
+
+// mockReadyComponent creates a component with all the prerequisites met.
+func mockReadyComponent(t *testing.T) *component {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	return &component{
+		ctx:            ctx,
+		cancel:         cancel,
+		nvmlInstance:   &MockNvmlInstance{exists: true},
+		currentVirtEnv: pkghost.VirtualizationEnvironment{Type: "none"},
+		getIBPortsFunc: func() (infiniband.IBPorts, error) {
+			return infiniband.IBPorts{
+				{Device: "mlx5_0", Port: 1, State: "Active", PhysicalState: "LinkUp", Rate: 400},
+				{Device: "mlx5_1", Port: 1, State: "Active", PhysicalState: "LinkUp", Rate: 400},
+			}, nil
+		},
+		checkPeermemFunc: func(ctx context.Context) (*peermem.LsmodPeermemModuleOutput, error) {
+			return &peermem.LsmodPeermemModuleOutput{IbcoreUsingPeermemModule: true}, nil
+		},
+		getPCIDevicesFunc: func(ctx context.Context) (pci.Devices, error) {
+			return pci.Devices{
+				{ID: "0000:17:01.0", AccessControlService: &pci.AccessControlService{}},
+				{ID: "0000:18:00.0"},
+			}, nil
+		},
+		readCmdlineFunc: func() (string, error) {
+			return "BOOT_IMAGE=/vmlinuz-6.8.0-51-generic root=UUID=1234 ro iommu=pt pci=realloc=off\n", nil
+		},
+		iommuEnabledFunc: func() bool { return true },
+		readLimitsFunc: func() (string, string, error) {
+			return "containerd (pid 1234)", readTestData(t, "limits.unlimited"), nil
+		},
+	}
+}
+
+func TestCheckReady(t *testing.T) {
+	c := mockReadyComponent(t)
+
+	data := c.Check().(*Data)
+	assert.Equal(t, apiv1.HealthStateTypeHealthy, data.HealthState())
+	assert.Equal(t, "gpudirect rdma ready (all 5 prerequisites met)", data.Summary())
+	assert.True(t, data.Ready)
+	assert.Empty(t, data.Missing)
+	require.Len(t, data.Prerequisites, 5)
+	for _, p := range data.Prerequisites {
+		assert.True(t, p.Passed, p.Name)
+	}
+	assert.Equal(t, "ib_core using nvidia_peermem module", data.Prerequisites[0].Message)
+	assert.Equal(t, "max locked memory of containerd (pid 1234) unlimited", data.Prerequisites[3].Message)
+
+	states := c.LastHealthStates()
+	require.Len(t, states, 1)
+	assert.Contains(t, states[0].DeprecatedExtraInfo["data"], `"ready":true`)
+}
+
+func TestCheckNotReady(t *testing.T) {
+	c := mockReadyComponent(t)
+	c.checkPeermemFunc = func(ctx context.Context) (*peermem.LsmodPeermemModuleOutput, error) {
+		return &peermem.LsmodPeermemModuleOutput{}, nil
+	}
+	c.readCmdlineFunc = func() (string, error) {
+		return "BOOT_IMAGE=/vmlinuz-6.8.0-51-generic root=UUID=1234 ro intel_iommu=on", nil
+	}
+
+	data := c.Check().(*Data)
+	assert.Equal(t, apiv1.HealthStateTypeUnhealthy, data.HealthState())
+	assert.Equal(t, "gpudirect rdma not ready, missing nvidia_peermem, iommu_passthrough", data.Summary())
+	assert.False(t, data.Ready)
+	assert.Equal(t, []string{PrerequisitePeermem, PrerequisiteIOMMUPassthrough}, data.Missing)
+
+	// errors reading the prerequisites are reported as missing
+	c.getPCIDevicesFunc = func(ctx context.Context) (pci.Devices, error) {
+		return nil, errors.New("lspci failed")
+	}
+	c.readLimitsFunc = func() (string, string, error) {
+		return "gpud", "", os.ErrNotExist
+	}
+	c.acsResult = nil // expire the cached ACS result
+	data = c.Check().(*Data)
+	assert.Equal(t, []string{PrerequisitePeermem, PrerequisiteACSDisabled, PrerequisiteIOMMUPassthrough, PrerequisiteMemlockUnlimited}, data.Missing)
+	assert.Equal(t, "error listing PCI devices: lspci failed", data.Prerequisites[1].Message)
+}
+
+func TestCheckACSCached(t *testing.T) {
+	c := mockReadyComponent(t)
+	listed := 0
+	c.getPCIDevicesFunc = func(ctx context.Context) (pci.Devices, error) {
+		listed++
+		if listed == 1 {
+			return nil, errors.New("lspci failed")
+		}
+		return pci.Devices{{ID: "0000:17:01.0", AccessControlService: &pci.AccessControlService{}}}, nil
+	}
+
+	// the error is not cached
+	data := c.Check().(*Data)
+	assert.Equal(t, []string{PrerequisiteACSDisabled}, data.Missing)
+
+	for i := 0; i < 3; i++ {
+		data = c.Check().(*Data)
+		assert.True(t, data.Ready)
+	}
+	assert.Equal(t, 2, listed)
+
+	// re-lists after the interval
+	c.acsCheckedAt = c.acsCheckedAt.Add(-acsCheckInterval)
+	data = c.Check().(*Data)
+	assert.True(t, data.Ready)
+	assert.Equal(t, 3, listed)
+}
+
+func TestCheckACSUnverified(t *testing.T) {
+	c := mockReadyComponent(t)
+	// lspci not found
+	c.getPCIDevicesFunc = func(ctx context.Context) (pci.Devices, error) {
+		return nil, nil
+	}
+
+	data := c.Check().(*Data)
+	assert.Equal(t, apiv1.HealthStateTypeHealthy, data.HealthState())
+	assert.Equal(t, "gpudirect rdma ready (4 prerequisites met, cannot verify acs_disabled)", data.Summary())
+	assert.True(t, data.Ready)
+	assert.Empty(t, data.Missing)
+	assert.Equal(t, []string{PrerequisiteACSDisabled}, data.Unverified)
+	assert.Contains(t, data.String(), "unverified")
+
+	// still not ready if the other prerequisite is missing
+	c.checkPeermemFunc = func(ctx context.Context) (*peermem.LsmodPeermemModuleOutput, error) {
+		return &peermem.LsmodPeermemModuleOutput{}, nil
+	}
+	data = c.Check().(*Data)
+	assert.Equal(t, apiv1.HealthStateTypeUnhealthy, data.HealthState())
+	assert.Equal(t, []string{PrerequisitePeermem}, data.Missing)
+	assert.Equal(t, []string{PrerequisiteACSDisabled}, data.Unverified)
+}
+
+func TestCheckNotApplicable(t *testing.T) {
+	c := mockReadyComponent(t)
+	c.getIBPortsFunc = func() (infiniband.IBPorts, error) {
+		return nil, infiniband.ErrNoSysfsDevice
+	}
+
+	data := c.Check().(*Data)
+	assert.Equal(t, apiv1.HealthStateTypeHealthy, data.HealthState())
+	assert.Equal(t, "no infiniband device found (gpudirect rdma not applicable)", data.Summary())
+	assert.Equal(t, "no data", data.String())
+
+	c.getIBPortsFunc = func() (infiniband.IBPorts, error) {
+		return nil, errors.New("permission denied")
+	}
+	data = c.Check().(*Data)
+	assert.Equal(t, apiv1.HealthStateTypeUnhealthy, data.HealthState())
+	assert.Equal(t, "permission denied", data.getError())
+
+	c.nvmlInstance = &MockNvmlInstance{exists: false}
+	data = c.Check().(*Data)
+	assert.Equal(t, "NVIDIA NVML is not loaded", data.Summary())
+
+	c.nvmlInstance = nil
+	data = c.Check().(*Data)
+	assert.Equal(t, "NVIDIA NVML instance is nil", data.Summary())
+}
+
+func TestCheckPeermem(t *testing.T) {
+	p := checkPeermem(&peermem.LsmodPeermemModuleOutput{IbcoreUsingPeermemModule: true}, nil)
+	assert.True(t, p.Passed)
+	assert.Equal(t, "ib_core using nvidia_peermem module", p.Message)
+
+	assert.False(t, checkPeermem(&peermem.LsmodPeermemModuleOutput{}, nil).Passed)
+	assert.False(t, checkPeermem(nil, nil).Passed)
+
+	p = checkPeermem(nil, errors.New("requires sudo/root access"))
+	assert.False(t, p.Passed)
+	assert.Equal(t, "error checking nvidia_peermem module: requires sudo/root access", p.Message)
+}
+
+func TestCheckACSDisabled(t *testing.T) {
+	devs := pci.Devices{
+		{ID: "0000:17:01.0", AccessControlService: &pci.AccessControlService{ACSCtl: pci.ACS{SrcValid: true}}},
+		{ID: "0000:96:01.0", AccessControlService: &pci.AccessControlService{ACSCtl: pci.ACS{SrcValid: true}}},
+		{ID: "0000:18:00.0"},
+	}
+	baremetal := pkghost.VirtualizationEnvironment{Type: "none"}
+
+	p := checkACSDisabled(baremetal, devs)
+	assert.False(t, p.Passed)
+	assert.Equal(t, "ACS enabled on 2 PCI device(s): 0000:17:01.0, 0000:96:01.0", p.Message)
+	p = checkACSDisabled(baremetal, nil)
+	assert.False(t, p.Passed)
+	assert.True(t, p.Unverified)
+
+	// same as the "pci" component
+	p = checkACSDisabled(pkghost.VirtualizationEnvironment{Type: "kvm", IsKVM: true}, devs)
+	assert.True(t, p.Passed)
+	assert.Equal(t, "host virt env is KVM (no need to check ACS)", p.Message)
+	p = checkACSDisabled(pkghost.VirtualizationEnvironment{}, devs)
+	assert.True(t, p.Passed)
+	assert.Equal(t, "unknown virtualization environment (no need to check ACS)", p.Message)
+}
+
+func TestCheckIOMMUPassthrough(t *testing.T) {
+	tests := []struct {
+		cmdline string
+		passed  bool
+	}{
+		{"ro quiet iommu=pt", true},
+		{"ro quiet iommu.passthrough=1", true},
+		{"ro quiet intel_iommu=off", true},
+		{"ro quiet amd_iommu=off", true},
+		{"ro quiet intel_iommu=on", false},
+		{"ro quiet noiommu=pt", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.cmdline, func(t *testing.T) {
+			assert.Equal(t, tt.passed, checkIOMMUPassthrough(tt.cmdline, true).Passed)
+		})
+	}
+
+	// the IOMMU is not enabled without "iommu=pt"
+	p := checkIOMMUPassthrough("ro quiet", false)
+	assert.True(t, p.Passed)
+	assert.Equal(t, "IOMMU not enabled (no IOMMU device or group in sysfs)", p.Message)
+}
+
+func TestIsIOMMUEnabled(t *testing.T) {
+	root := t.TempDir()
+
+	// missing
+	assert.False(t, isIOMMUEnabled(root))
+
+	// empty
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "class", "iommu"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "kernel", "iommu_groups"), 0755))
+	assert.False(t, isIOMMUEnabled(root))
+
+	// no IOMMU group
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "class", "iommu", "dmar0"), 0755))
+	assert.False(t, isIOMMUEnabled(root))
+
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "kernel", "iommu_groups", "0"), 0755))
+	assert.True(t, isIOMMUEnabled(root))
+}
+
+func TestCheckIOMMUNotEnabled(t *testing.T) {
+	c := mockReadyComponent(t)
+	c.readCmdlineFunc = func() (string, error) {
+		return "BOOT_IMAGE=/vmlinuz-6.8.0-51-generic root=UUID=1234 ro", nil
+	}
+
+	data := c.Check().(*Data)
+	assert.Equal(t, apiv1.HealthStateTypeUnhealthy, data.HealthState())
+	assert.Equal(t, []string{PrerequisiteIOMMUPassthrough}, data.Missing)
+
+	c.iommuEnabledFunc = func() bool { return false }
+	data = c.Check().(*Data)
+	assert.Equal(t, apiv1.HealthStateTypeHealthy, data.HealthState())
+	assert.True(t, data.Ready)
+	assert.Equal(t, "IOMMU not enabled (no IOMMU device or group in sysfs)", data.Prerequisites[2].Message)
+}
+
+func TestCheckMemlockUnlimited(t *testing.T) {
+	assert.True(t, checkMemlockUnlimited("containerd (pid 1234)", readTestData(t, "limits.unlimited")).Passed)
+
+	p := checkMemlockUnlimited("containerd (pid 1234)", readTestData(t, "limits.8m"))
+	assert.False(t, p.Passed)
+	assert.Equal(t, "max locked memory of containerd (pid 1234) limited to 8388608 bytes (expected unlimited)", p.Message)
+
+	assert.False(t, checkMemlockUnlimited("gpud", "").Passed)
+}
+
+func writeTestProc(t *testing.T, root string, pid string, comm string, limitsFile string) {
+	require.NoError(t, os.MkdirAll(filepath.Join(root, pid), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, pid, "comm"), []byte(comm+"\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, pid, "limits"), []byte(readTestData(t, limitsFile)), 0644))
+}
+
+func TestReadWorkloadLimits(t *testing.T) {
+	root := t.TempDir()
+	writeTestProc(t, root, "self", "gpud", "limits.8m")
+	writeTestProc(t, root, "1", "systemd", "limits.8m")
+	writeTestProc(t, root, "4321", "dockerd", "limits.8m")
+
+	// no containerd, falls back to dockerd
+	source, limits, err := readWorkloadLimits(root)
+	require.NoError(t, err)
+	assert.Equal(t, "dockerd (pid 4321)", source)
+	assert.False(t, checkMemlockUnlimited(source, limits).Passed)
+
+	// containerd is preferred, gpud itself is limited
+	writeTestProc(t, root, "1234", "containerd", "limits.unlimited")
+	source, limits, err = readWorkloadLimits(root)
+	require.NoError(t, err)
+	assert.Equal(t, "containerd (pid 1234)", source)
+	assert.True(t, checkMemlockUnlimited(source, limits).Passed)
+
+	// no container runtime
+	root = t.TempDir()
+	writeTestProc(t, root, "self", "gpud", "limits.unlimited")
+	source, limits, err = readWorkloadLimits(root)
+	require.NoError(t, err)
+	assert.Equal(t, "gpud", source)
+	assert.True(t, checkMemlockUnlimited(source, limits).Passed)
+}
+
+func TestCheckInfinibandActive(t *testing.T) {
+	p := checkInfinibandActive(infiniband.IBPorts{
+		{Device: "mlx5_0", Port: 1, State: "Active"},
+		{Device: "mlx5_1", Port: 1, State: "Down"},
+	})
+	assert.True(t, p.Passed)
+	assert.Equal(t, "1 of 2 infiniband port(s) active, inactive: mlx5_1:1 (Down)", p.Message)
+
+	p = checkInfinibandActive(infiniband.IBPorts{
+		{Device: "mlx5_0", Port: 1, State: "Down"},
+		{Device: "mlx5_1", Port: 1, State: "Initializing"},
+	})
+	assert.False(t, p.Passed)
+	assert.Equal(t, "no active infiniband port (mlx5_0:1 (Down), mlx5_1:1 (Initializing))", p.Message)
+}
+
+func TestNew(t *testing.T) {
+	c, err := New(&components.GPUdInstance{RootCtx: context.Background()})
+	require.NoError(t, err)
+	assert.Equal(t, Name, c.Name())
+	assert.Equal(t, "no data yet", c.LastHealthStates()[0].Reason)
+
+	events, err := c.Events(context.Background(), time.Time{})
+	assert.NoError(t, err)
+	assert.Nil(t, events)
+	require.NoError(t, c.Close())
+}
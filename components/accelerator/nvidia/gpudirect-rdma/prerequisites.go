@@ -0,0 +1,200 @@
+package gpudirectrdma
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	pkghost "github.com/leptonai/gpud/pkg/host"
+	"github.com/leptonai/gpud/pkg/nvidia-query/infiniband"
+	"github.com/leptonai/gpud/pkg/nvidia-query/peermem"
+	"github.com/leptonai/gpud/pkg/pci"
+)
+
+// GPUDirect RDMA prerequisites.
+// ref. https://docs.nvidia.com/cuda/gpudirect-rdma/
+// ref. https://docs.nvidia.com/deeplearning/nccl/user-guide/docs/troubleshooting.html#gpu-direct
+const (
+	PrerequisitePeermem          = "nvidia_peermem"
+	PrerequisiteACSDisabled      = "acs_disabled"
+	PrerequisiteIOMMUPassthrough = "iommu_passthrough"
+	PrerequisiteMemlockUnlimited = "memlock_unlimited"
+	PrerequisiteInfinibandActive = "infiniband_active"
+)
+
+// Prerequisite is the result of a GPUDirect RDMA prerequisite check.
+type Prerequisite struct {
+	// Name is the prerequisite name (e.g., "nvidia_peermem").
+	Name string `json:"name"`
+	// Passed is true if the prerequisite is met.
+	Passed bool `json:"passed"`
+	// Unverified is true if the prerequisite cannot be verified on the host
+	// (e.g., "lspci" not found), which is neither met nor missing.
+	Unverified bool `json:"unverified,omitempty"`
+	// Message describes the check result, or what is missing.
+	Message string `json:"message"`
+}
+
+const (
+	defaultProcRoot    = "/proc"
+	defaultCmdlinePath = "/proc/cmdline"
+	defaultSysfsRoot   = "/sys"
+)
+
+// checkPeermem checks that the infiniband core module (ib_core) uses the nvidia_peermem module,
+// based on the "lsmod" output (requires root).
+func checkPeermem(out *peermem.LsmodPeermemModuleOutput, err error) Prerequisite {
+	if err != nil {
+		return Prerequisite{Name: PrerequisitePeermem, Message: fmt.Sprintf("error checking nvidia_peermem module: %v", err)}
+	}
+	if out == nil || !out.IbcoreUsingPeermemModule {
+		return Prerequisite{Name: PrerequisitePeermem, Message: "ib_core not using nvidia_peermem module (run \"modprobe nvidia_peermem\")"}
+	}
+	return Prerequisite{Name: PrerequisitePeermem, Passed: true, Message: "ib_core using nvidia_peermem module"}
+}
+
+// checkACSDisabled checks that the ACS is disabled on all the PCI devices (bridges),
+// which otherwise redirects the PCI peer-to-peer traffic to the CPU root complex.
+// Passes where the "pci" component skips the ACS check (e.g., virtual machines require ACS).
+func checkACSDisabled(virtEnv pkghost.VirtualizationEnvironment, devs pci.Devices) Prerequisite {
+	if reason := pci.ACSCheckSkipReason(virtEnv); reason != "" {
+		return Prerequisite{Name: PrerequisiteACSDisabled, Passed: true, Message: reason}
+	}
+	if len(devs) == 0 {
+		return Prerequisite{Name: PrerequisiteACSDisabled, Unverified: true, Message: "no PCI device listed (lspci not found or not permitted), cannot verify ACS"}
+	}
+
+	enabled := pci.FindACSEnabledDeviceUUIDs(devs)
+	if len(enabled) > 0 {
+		return Prerequisite{Name: PrerequisiteACSDisabled, Message: fmt.Sprintf("ACS enabled on %d PCI device(s): %s", len(enabled), strings.Join(enabled, ", "))}
+	}
+	return Prerequisite{Name: PrerequisiteACSDisabled, Passed: true, Message: fmt.Sprintf("ACS disabled on all %d PCI device(s)", len(devs))}
+}
+
+// checkIOMMUPassthrough checks the kernel command line sets the IOMMU in passthrough mode ("iommu=pt"),
+// or the IOMMU is disabled (by the kernel command line, or not enabled at all),
+// so that the IOMMU does not translate the peer-to-peer DMA.
+func checkIOMMUPassthrough(cmdline string, iommuEnabled bool) Prerequisite {
+	for _, field := range strings.Fields(cmdline) {
+		switch field {
+		case "iommu=pt", "iommu.passthrough=1":
+			return Prerequisite{Name: PrerequisiteIOMMUPassthrough, Passed: true, Message: fmt.Sprintf("%q set in kernel command line", field)}
+		case "iommu=off", "intel_iommu=off", "amd_iommu=off":
+			return Prerequisite{Name: PrerequisiteIOMMUPassthrough, Passed: true, Message: fmt.Sprintf("IOMMU disabled by %q in kernel command line", field)}
+		}
+	}
+	if !iommuEnabled {
+		return Prerequisite{Name: PrerequisiteIOMMUPassthrough, Passed: true, Message: "IOMMU not enabled (no IOMMU device or group in sysfs)"}
+	}
+	return Prerequisite{Name: PrerequisiteIOMMUPassthrough, Message: "\"iommu=pt\" not set in kernel command line"}
+}
+
+// isIOMMUEnabled returns true if the kernel has registered the IOMMU devices
+// ("/sys/class/iommu") and the IOMMU groups ("/sys/kernel/iommu_groups")
+// under the sysfs root, which are empty (or missing) if the IOMMU is not enabled.
+func isIOMMUEnabled(sysfsRoot string) bool {
+	for _, dir := range []string{
+		filepath.Join(sysfsRoot, "class", "iommu"),
+		filepath.Join(sysfsRoot, "kernel", "iommu_groups"),
+	} {
+		entries, err := os.ReadDir(dir)
+		if err != nil || len(entries) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// containerRuntimeProcesses is the container runtime daemons whose resource limits
+// are inherited by the containers (e.g., "LimitMEMLOCK" in the containerd systemd unit),
+// in the order of preference.
+var containerRuntimeProcesses = []string{"containerd", "dockerd", "crio"}
+
+// findContainerRuntimePID returns the process name and ID of the container runtime daemon,
+// by the process names under the procfs root (e.g., "/proc/<pid>/comm").
+// Returns false if no container runtime is running.
+func findContainerRuntimePID(procRoot string) (string, int, bool) {
+	entries, err := os.ReadDir(procRoot)
+	if err != nil {
+		return "", 0, false
+	}
+
+	found := make(map[string]int)
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		b, err := os.ReadFile(filepath.Join(procRoot, entry.Name(), "comm"))
+		if err != nil {
+			continue
+		}
+		name := strings.TrimSpace(string(b))
+		if prev, ok := found[name]; !ok || pid < prev {
+			found[name] = pid
+		}
+	}
+	for _, name := range containerRuntimeProcesses {
+		if pid, ok := found[name]; ok {
+			return name, pid, true
+		}
+	}
+	return "", 0, false
+}
+
+// readWorkloadLimits reads the resource limits that the GPU workloads get,
+// from the container runtime daemon (e.g., "/proc/<containerd pid>/limits"),
+// or from the gpud daemon itself if no container runtime is running
+// (e.g., the jobs run as the systemd units alongside gpud).
+// Returns the source of the limits and the limits.
+func readWorkloadLimits(procRoot string) (string, string, error) {
+	source, path := "gpud", filepath.Join(procRoot, "self", "limits")
+	if name, pid, ok := findContainerRuntimePID(procRoot); ok {
+		source, path = fmt.Sprintf("%s (pid %d)", name, pid), filepath.Join(procRoot, strconv.Itoa(pid), "limits")
+	}
+	limits, err := readFile(path)
+	return source, limits, err
+}
+
+// checkMemlockUnlimited checks the max locked memory limit of the workloads is unlimited,
+// required to pin the RDMA buffers, where the source is where the limits are read from
+// (e.g., the container runtime).
+func checkMemlockUnlimited(source string, limits string) Prerequisite {
+	for _, line := range strings.Split(limits, "\n") {
+		// e.g.,
+		// Max locked memory         8388608              8388608              bytes
+		if !strings.HasPrefix(line, "Max locked memory") {
+			continue
+		}
+		fields := strings.Fields(strings.TrimPrefix(line, "Max locked memory"))
+		if len(fields) < 2 {
+			break
+		}
+		if fields[0] == "unlimited" {
+			return Prerequisite{Name: PrerequisiteMemlockUnlimited, Passed: true, Message: fmt.Sprintf("max locked memory of %s unlimited", source)}
+		}
+		return Prerequisite{Name: PrerequisiteMemlockUnlimited, Message: fmt.Sprintf("max locked memory of %s limited to %s bytes (expected unlimited)", source, fields[0])}
+	}
+	return Prerequisite{Name: PrerequisiteMemlockUnlimited, Message: fmt.Sprintf("max locked memory limit of %s not found", source)}
+}
+
+func checkInfinibandActive(ports infiniband.IBPorts) Prerequisite {
+	active := make([]string, 0)
+	inactive := make([]string, 0)
+	for _, p := range ports {
+		if p.State == "Active" {
+			active = append(active, p.Name())
+		} else {
+			inactive = append(inactive, fmt.Sprintf("%s (%s)", p.Name(), p.State))
+		}
+	}
+	if len(active) == 0 {
+		return Prerequisite{Name: PrerequisiteInfinibandActive, Message: fmt.Sprintf("no active infiniband port (%s)", strings.Join(inactive, ", "))}
+	}
+	if len(inactive) > 0 {
+		return Prerequisite{Name: PrerequisiteInfinibandActive, Passed: true, Message: fmt.Sprintf("%d of %d infiniband port(s) active, inactive: %s", len(active), len(ports), strings.Join(inactive, ", "))}
+	}
+	return Prerequisite{Name: PrerequisiteInfinibandActive, Passed: true, Message: fmt.Sprintf("all %d infiniband port(s) active", len(ports))}
+}
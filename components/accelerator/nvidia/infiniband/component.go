@@ -8,8 +8,8 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"os"
 	"runtime"
+	"strings"
 	"sync"
 	"time"
 
@@ -28,6 +28,10 @@ import (
 
 const Name = "accelerator-nvidia-infiniband"
 
+// EventNamePortErrors is the event name for the port error counters
+// increasing faster than the thresholds, or the ports flapping too often.
+const EventNamePortErrors = "infiniband_port_errors"
+
 var _ components.Component = &component{}
 
 type component struct {
@@ -43,6 +47,25 @@ type component struct {
 	getIbstatOutputFunc func(ctx context.Context, ibstatCommands []string) (*infiniband.IbstatOutput, error)
 	getThresholdsFunc   func() infiniband.ExpectedPortStates
 
+	// sysfsRoot is the sysfs directory of the infiniband devices,
+	// configurable for tests (e.g., "/sys/class/infiniband")
+	sysfsRoot                  string
+	getIBPortsFunc             func(root string) (infiniband.IBPorts, error)
+	getErrorRateThresholdsFunc func() ErrorRateThresholds
+	getLinkFlapThresholdsFunc  func() LinkFlapThresholds
+
+	// tracks the ports of the last check to compute the counter deltas
+	prevPortsMu sync.Mutex
+	prevPorts   infiniband.IBPorts
+	prevPortsTs time.Time
+
+	// tracks the last observed port states and the link flap times
+	// by the port state watcher, keyed by the port name
+	portStatesMu sync.Mutex
+	portStates   map[string]infiniband.IBPort
+	portStatesTs time.Time
+	portFlaps    map[string][]time.Time
+
 	lastMu   sync.RWMutex
 	lastData *Data
 }
@@ -56,6 +79,11 @@ func New(gpudInstance *components.GPUdInstance) (components.Component, error) {
 		toolOverwrites:      gpudInstance.NVIDIAToolOverwrites,
 		getIbstatOutputFunc: infiniband.GetIbstatOutput,
 		getThresholdsFunc:   GetDefaultExpectedPortStates,
+
+		sysfsRoot:                  infiniband.DefaultSysfsRoot,
+		getIBPortsFunc:             infiniband.GetIBPorts,
+		getErrorRateThresholdsFunc: GetDefaultErrorRateThresholds,
+		getLinkFlapThresholdsFunc:  GetDefaultLinkFlapThresholds,
 	}
 
 	if gpudInstance.EventStore != nil && runtime.GOOS == "linux" {
@@ -66,8 +94,8 @@ func New(gpudInstance *components.GPUdInstance) (components.Component, error) {
 			return nil, err
 		}
 
-		if os.Geteuid() == 0 {
-			c.kmsgSyncer, err = kmsg.NewSyncer(cctx, Match, c.eventBucket)
+		if gpudInstance.KmsgHub != nil {
+			c.kmsgSyncer, err = gpudInstance.KmsgHub.NewSyncer(cctx, Name, MatchEvent, c.eventBucket)
 			if err != nil {
 				ccancel()
 				return nil, err
@@ -95,6 +123,10 @@ func (c *component) Start() error {
 			}
 		}
 	}()
+
+	if c.getIBPortsFunc != nil && c.getLinkFlapThresholdsFunc != nil {
+		go c.watchPortStates()
+	}
 	return nil
 }
 
@@ -149,30 +181,60 @@ func (c *component) Check() components.CheckResult {
 		d.reason = "NVIDIA NVML is not loaded"
 		return d
 	}
-	if c.getIbstatOutputFunc == nil {
-		d.reason = "ibstat checker not found"
-		d.health = apiv1.HealthStateTypeHealthy
-		return d
+
+	// read the ports from sysfs first, which does not require "ibstat"
+	// and also provides the port error counters
+	if c.getIBPortsFunc != nil {
+		ports, err := c.getIBPortsFunc(c.sysfsRoot)
+		switch {
+		case err == nil:
+			d.Ports = ports
+		case errors.Is(err, infiniband.ErrNoSysfsDevice):
+			log.Logger.Debugw("no infiniband device found in sysfs, falling back to ibstat", "root", c.sysfsRoot)
+		default:
+			log.Logger.Warnw("failed to read infiniband ports from sysfs, falling back to ibstat", "root", c.sysfsRoot, "error", err)
+		}
 	}
 
-	cctx, ccancel := context.WithTimeout(c.ctx, 15*time.Second)
-	d.IbstatOutput, d.err = c.getIbstatOutputFunc(cctx, []string{c.toolOverwrites.IbstatCommand})
-	ccancel()
-	if d.err != nil {
-		if errors.Is(d.err, infiniband.ErrNoIbstatCommand) {
-			d.reason = "ibstat command not found"
+	if len(d.Ports) == 0 {
+		if c.getIbstatOutputFunc == nil {
+			d.reason = "ibstat checker not found"
 			d.health = apiv1.HealthStateTypeHealthy
-		} else {
-			d.reason = fmt.Sprintf("ibstat command failed: %v", d.err)
-			d.health = apiv1.HealthStateTypeUnhealthy
+			return d
 		}
-		return d
-	}
 
-	if d.IbstatOutput == nil {
-		d.reason = reasonMissingIbstatOutput
-		d.health = apiv1.HealthStateTypeHealthy
-		return d
+		cctx, ccancel := context.WithTimeout(c.ctx, 15*time.Second)
+		d.IbstatOutput, d.err = c.getIbstatOutputFunc(cctx, []string{c.toolOverwrites.IbstatCommand})
+		ccancel()
+		if d.err != nil {
+			if errors.Is(d.err, infiniband.ErrNoIbstatCommand) {
+				d.reason = "ibstat command not found"
+				d.health = apiv1.HealthStateTypeHealthy
+			} else {
+				d.reason = fmt.Sprintf("ibstat command failed: %v", d.err)
+				d.health = apiv1.HealthStateTypeUnhealthy
+			}
+			return d
+		}
+
+		if d.IbstatOutput == nil {
+			d.reason = reasonMissingIbstatOutput
+			d.health = apiv1.HealthStateTypeHealthy
+			return d
+		}
+	} else {
+		c.prevPortsMu.Lock()
+		prevPorts, prevPortsTs := c.prevPorts, c.prevPortsTs
+		c.prevPorts, c.prevPortsTs = d.Ports, d.ts
+		c.prevPortsMu.Unlock()
+
+		d.CounterDeltas = computeCounterDeltas(prevPorts, d.Ports, d.ts.Sub(prevPortsTs))
+		setCounterDeltaMetrics(d.CounterDeltas)
+
+		if c.getLinkFlapThresholdsFunc != nil {
+			c.observePortStates(d.ts, d.Ports)
+			d.PortFlaps = c.getPortFlaps(d.ts, c.getLinkFlapThresholdsFunc().Window.Duration)
+		}
 	}
 
 	// no event bucket, no need for timeseries data checks
@@ -184,7 +246,27 @@ func (c *component) Check() components.CheckResult {
 	}
 
 	thresholds := c.getThresholdsFunc()
-	d.reason, d.health = evaluateIbstatOutputAgainstThresholds(d.IbstatOutput, thresholds)
+	if d.IbstatOutput != nil {
+		d.reason, d.health = evaluateIbstatOutputAgainstThresholds(d.IbstatOutput, thresholds)
+	} else {
+		d.reason, d.health = evaluatePortsAgainstThresholds(d.Ports.IBStatCards(), thresholds)
+	}
+
+	eventName := "ibstat"
+	if d.health == apiv1.HealthStateTypeHealthy {
+		reasons := make([]string, 0)
+		if c.getErrorRateThresholdsFunc != nil {
+			reasons = append(reasons, evaluateCounterDeltas(d.CounterDeltas, c.getErrorRateThresholdsFunc())...)
+		}
+		if c.getLinkFlapThresholdsFunc != nil {
+			reasons = append(reasons, evaluatePortFlaps(d.PortFlaps, c.getLinkFlapThresholdsFunc())...)
+		}
+		if len(reasons) > 0 {
+			d.reason = strings.Join(reasons, "; ")
+			d.health = apiv1.HealthStateTypeDegraded
+			eventName = EventNamePortErrors
+		}
+	}
 
 	// we only care about unhealthy events, no need to persist healthy events
 	if d.health == apiv1.HealthStateTypeHealthy {
@@ -196,7 +278,7 @@ func (c *component) Check() components.CheckResult {
 	// we persist such unhealthy state event
 	ev := apiv1.Event{
 		Time:    metav1.Time{Time: d.ts},
-		Name:    "ibstat",
+		Name:    eventName,
 		Type:    apiv1.EventTypeWarning,
 		Message: d.reason,
 
@@ -211,11 +293,11 @@ func (c *component) Check() components.CheckResult {
 	}
 
 	// lookup to prevent duplicate event insertions
-	cctx, ccancel = context.WithTimeout(c.ctx, 15*time.Second)
+	cctx, ccancel := context.WithTimeout(c.ctx, 15*time.Second)
 	found, err := c.eventBucket.Find(cctx, ev)
 	ccancel()
 	if err != nil {
-		d.reason = fmt.Sprintf("failed to find ibstat event: %v", err)
+		d.reason = fmt.Sprintf("failed to find %s event: %v", eventName, err)
 		d.health = apiv1.HealthStateTypeUnhealthy
 		return d
 	}
@@ -230,7 +312,7 @@ func (c *component) Check() components.CheckResult {
 	err = c.eventBucket.Insert(cctx, ev)
 	ccancel()
 	if err != nil {
-		d.reason = fmt.Sprintf("failed to insert ibstat event: %v", err)
+		d.reason = fmt.Sprintf("failed to insert %s event: %v", eventName, err)
 		d.health = apiv1.HealthStateTypeUnhealthy
 		return d
 	}
@@ -243,6 +325,7 @@ var (
 	reasonMissingEventBucket     = "missing event storage (skipped evaluation)"
 	reasonThresholdNotSetSkipped = "ports or rate threshold not set, skipping"
 	reasonNoIbIssueFound         = "no infiniband issue found (in ibstat)"
+	reasonNoIbPortIssueFound     = "no infiniband issue found (in sysfs)"
 )
 
 // Returns the output evaluation reason and its health state.
@@ -262,11 +345,31 @@ func evaluateIbstatOutputAgainstThresholds(o *infiniband.IbstatOutput, threshold
 	return reasonNoIbIssueFound, apiv1.HealthStateTypeHealthy
 }
 
+// Returns the evaluation reason of the ports read from sysfs and its health state.
+func evaluatePortsAgainstThresholds(cards infiniband.IBStatCards, thresholds infiniband.ExpectedPortStates) (string, apiv1.HealthStateType) {
+	if thresholds.AtLeastPorts <= 0 && thresholds.AtLeastRate <= 0 {
+		return reasonThresholdNotSetSkipped, apiv1.HealthStateTypeHealthy
+	}
+
+	if err := cards.CheckPortsAndRate(thresholds.AtLeastPorts, thresholds.AtLeastRate); err != nil {
+		return err.Error(), apiv1.HealthStateTypeUnhealthy
+	}
+
+	return reasonNoIbPortIssueFound, apiv1.HealthStateTypeHealthy
+}
+
 var _ components.CheckResult = &Data{}
 
 type Data struct {
 	IbstatOutput *infiniband.IbstatOutput `json:"ibstat_output"`
 
+	// Ports is the infiniband ports read from sysfs.
+	Ports infiniband.IBPorts `json:"ports,omitempty"`
+	// CounterDeltas is the increase of the port error counters since the last check.
+	CounterDeltas []PortCounterDelta `json:"counter_deltas,omitempty"`
+	// PortFlaps is the number of link flaps of each port within the window.
+	PortFlaps []PortFlaps `json:"port_flaps,omitempty"`
+
 	// timestamp of the last check
 	ts time.Time
 	// error from the last check
@@ -282,6 +385,9 @@ func (d *Data) String() string {
 	if d == nil {
 		return ""
 	}
+	if len(d.Ports) > 0 {
+		return d.portsString()
+	}
 	if d.IbstatOutput == nil {
 		return "no data"
 	}
@@ -303,6 +409,36 @@ func (d *Data) String() string {
 	return buf.String()
 }
 
+func (d *Data) portsString() string {
+	deltas := make(map[string]uint64, len(d.CounterDeltas))
+	for _, delta := range d.CounterDeltas {
+		deltas[delta.Port+"/"+delta.Counter] = delta.Delta
+	}
+
+	buf := bytes.NewBuffer(nil)
+	table := tablewriter.NewWriter(buf)
+	table.SetAlignment(tablewriter.ALIGN_CENTER)
+	header := []string{"Port", "State", "Physical State", "Rate"}
+	header = append(header, trackedCounters...)
+	table.SetHeader(header)
+	for _, p := range d.Ports {
+		row := []string{
+			p.Name(),
+			p.State,
+			p.PhysicalState,
+			fmt.Sprintf("%d", p.Rate),
+		}
+		for _, counter := range trackedCounters {
+			// counter value with the increase since the last check (e.g., "12 (+2)")
+			row = append(row, fmt.Sprintf("%d (+%d)", p.Counters[counter], deltas[p.Name()+"/"+counter]))
+		}
+		table.Append(row)
+	}
+	table.Render()
+
+	return buf.String()
+}
+
 func (d *Data) Summary() string {
 	if d == nil {
 		return ""
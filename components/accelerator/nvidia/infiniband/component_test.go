@@ -502,8 +502,8 @@ func TestComponentClose(t *testing.T) {
 
 	// Only try to create kmsgSyncer on Linux
 	if runtime.GOOS == "linux" {
-		kmsgSyncer, err := kmsg.NewSyncer(cctx, func(line string) (string, string) {
-			return "test", "test"
+		kmsgSyncer, err := kmsg.NewSyncer(cctx, func(line string) *kmsg.MatchedEvent {
+			return kmsg.NewMatchedEvent("test", "test", apiv1.EventTypeWarning, nil)
 		}, mockBucket)
 		if err == nil {
 			c.kmsgSyncer = kmsgSyncer
@@ -1172,3 +1172,94 @@ func TestCheckNilIbstatFunc(t *testing.T) {
 	assert.Equal(t, apiv1.HealthStateTypeHealthy, data.health)
 	assert.Equal(t, "ibstat checker not found", data.reason)
 }
+
+// writeTestIBPort writes the sysfs port files under the root directory.
+func writeTestIBPort(t *testing.T, root string, dev string, state string, physState string, rate string, counters map[string]uint64) {
+	dir := filepath.Join(root, dev, "ports", "1")
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "counters"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "state"), []byte(state+"\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "phys_state"), []byte(physState+"\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "rate"), []byte(rate+"\n"), 0644))
+	for name, v := range counters {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "counters", name), []byte(fmt.Sprintf("%d\n", v)), 0644))
+	}
+}
+
+func TestCheckSysfs(t *testing.T) {
+	t.Parallel()
+
+	cctx, ccancel := context.WithCancel(context.Background())
+	defer ccancel()
+
+	root := t.TempDir()
+	writeTestIBPort(t, root, "mlx5_0", "4: ACTIVE", "5: LinkUp", "400 Gb/sec (4X NDR)", map[string]uint64{infiniband.CounterSymbolError: 0})
+	writeTestIBPort(t, root, "mlx5_1", "4: ACTIVE", "5: LinkUp", "400 Gb/sec (4X NDR)", map[string]uint64{infiniband.CounterSymbolError: 0})
+
+	mockBucket := NewMockEventBucket()
+	c := &component{
+		ctx:          cctx,
+		cancel:       ccancel,
+		eventBucket:  mockBucket,
+		nvmlInstance: &mockNVMLInstance{exists: true},
+		getIbstatOutputFunc: func(ctx context.Context, ibstatCommands []string) (*infiniband.IbstatOutput, error) {
+			return nil, errors.New("ibstat must not be called")
+		},
+		getThresholdsFunc: func() infiniband.ExpectedPortStates {
+			return infiniband.ExpectedPortStates{AtLeastPorts: 2, AtLeastRate: 400}
+		},
+		sysfsRoot:                  root,
+		getIBPortsFunc:             infiniband.GetIBPorts,
+		getErrorRateThresholdsFunc: GetDefaultErrorRateThresholds,
+	}
+
+	data := c.Check().(*Data)
+	assert.Equal(t, apiv1.HealthStateTypeHealthy, data.health)
+	assert.Equal(t, reasonNoIbPortIssueFound, data.reason)
+	assert.Nil(t, data.IbstatOutput)
+	require.Len(t, data.Ports, 2)
+	assert.Empty(t, data.CounterDeltas)
+
+	// symbol errors increase faster than the threshold
+	c.prevPortsTs = c.prevPortsTs.Add(-time.Minute)
+	writeTestIBPort(t, root, "mlx5_1", "4: ACTIVE", "5: LinkUp", "400 Gb/sec (4X NDR)", map[string]uint64{infiniband.CounterSymbolError: 500})
+
+	data = c.Check().(*Data)
+	assert.Equal(t, apiv1.HealthStateTypeDegraded, data.health)
+	assert.Contains(t, data.reason, "mlx5_1:1 symbol_error increased by 500")
+	require.Len(t, data.CounterDeltas, 2)
+	assert.Contains(t, data.String(), "500 (+500)")
+
+	events := mockBucket.GetEvents()
+	require.Len(t, events, 1)
+	assert.Equal(t, EventNamePortErrors, events[0].Name)
+
+	// a port down takes precedence over the error rates
+	c.prevPortsTs = c.prevPortsTs.Add(-time.Minute)
+	writeTestIBPort(t, root, "mlx5_1", "1: DOWN", "3: Disabled", "400 Gb/sec (4X NDR)", map[string]uint64{infiniband.CounterSymbolError: 1000})
+
+	data = c.Check().(*Data)
+	assert.Equal(t, apiv1.HealthStateTypeUnhealthy, data.health)
+	assert.Equal(t, "only 1 ports (>= 400 Gb/s) are active, expect at least 2; 1 device(s) found Disabled (mlx5_1)", data.reason)
+}
+
+func TestCheckSysfsFallbackToIbstat(t *testing.T) {
+	t.Parallel()
+
+	cctx, ccancel := context.WithCancel(context.Background())
+	defer ccancel()
+
+	c := &component{
+		ctx:                 cctx,
+		cancel:              ccancel,
+		nvmlInstance:        &mockNVMLInstance{exists: true},
+		getIbstatOutputFunc: mockGetIbstatOutput,
+		getThresholdsFunc:   mockGetThresholds,
+		sysfsRoot:           filepath.Join(t.TempDir(), "does-not-exist"),
+		getIBPortsFunc:      infiniband.GetIBPorts,
+	}
+
+	data := c.Check().(*Data)
+	assert.Equal(t, apiv1.HealthStateTypeHealthy, data.health)
+	assert.NotNil(t, data.IbstatOutput)
+	assert.Empty(t, data.Ports)
+}
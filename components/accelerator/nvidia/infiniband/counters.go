@@ -0,0 +1,88 @@
+package infiniband
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/leptonai/gpud/pkg/nvidia-query/infiniband"
+)
+
+// trackedCounters is the port error counters whose deltas are tracked between checks.
+var trackedCounters = []string{
+	infiniband.CounterSymbolError,
+	infiniband.CounterLinkDowned,
+	infiniband.CounterLinkErrorRecovery,
+	infiniband.CounterPortRcvErrors,
+	infiniband.CounterExcessiveBufferOverrunErrors,
+}
+
+// PortCounterDelta is the increase of a port error counter since the last check.
+type PortCounterDelta struct {
+	// Port is the port name (e.g., "mlx5_0:1").
+	Port string `json:"port"`
+	// Counter is the counter name (e.g., "symbol_error").
+	Counter string `json:"counter"`
+	// Delta is the increase of the counter since the last check.
+	Delta uint64 `json:"delta"`
+	// PerMinute is the increase rate of the counter per minute.
+	PerMinute float64 `json:"per_minute"`
+}
+
+// computeCounterDeltas returns the deltas of the tracked counters between the two samples.
+// The ports that are missing in the previous sample are skipped.
+// If the counter decreases (e.g., reset by the driver reload), the current value is used as the delta.
+func computeCounterDeltas(prev infiniband.IBPorts, cur infiniband.IBPorts, elapsed time.Duration) []PortCounterDelta {
+	if len(prev) == 0 || elapsed <= 0 {
+		return nil
+	}
+
+	prevPorts := make(map[string]infiniband.IBPort, len(prev))
+	for _, p := range prev {
+		prevPorts[p.Name()] = p
+	}
+
+	deltas := make([]PortCounterDelta, 0)
+	for _, p := range cur {
+		pp, ok := prevPorts[p.Name()]
+		if !ok {
+			continue
+		}
+		for _, counter := range trackedCounters {
+			v, ok := p.Counters[counter]
+			if !ok {
+				continue
+			}
+			pv, ok := pp.Counters[counter]
+			if !ok {
+				continue
+			}
+
+			delta := v
+			if v >= pv {
+				delta = v - pv
+			}
+			deltas = append(deltas, PortCounterDelta{
+				Port:      p.Name(),
+				Counter:   counter,
+				Delta:     delta,
+				PerMinute: float64(delta) / elapsed.Minutes(),
+			})
+		}
+	}
+	return deltas
+}
+
+// evaluateCounterDeltas returns the reasons for the counters whose increase rates exceed the thresholds.
+func evaluateCounterDeltas(deltas []PortCounterDelta, thresholds ErrorRateThresholds) []string {
+	reasons := make([]string, 0)
+	for _, d := range deltas {
+		limit := thresholds.MaxPerMinute[d.Counter]
+		if limit <= 0 || d.PerMinute <= limit {
+			continue
+		}
+		reasons = append(reasons, fmt.Sprintf("%s %s increased by %d (%.1f/min, expected at most %.1f/min)", d.Port, d.Counter, d.Delta, d.PerMinute, limit))
+	}
+	sort.Strings(reasons)
+	return reasons
+}
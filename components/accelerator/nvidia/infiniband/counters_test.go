@@ -0,0 +1,78 @@
+package infiniband
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/leptonai/gpud/pkg/nvidia-query/infiniband"
+)
+
+func TestComputeCounterDeltas(t *testing.T) {
+	prev := infiniband.IBPorts{
+		{Device: "mlx5_0", Port: 1, Counters: map[string]uint64{
+			infiniband.CounterSymbolError: 10,
+			infiniband.CounterLinkDowned:  1,
+			"port_xmit_data":              100,
+		}},
+		{Device: "mlx5_1", Port: 1, Counters: map[string]uint64{
+			infiniband.CounterPortRcvErrors: 50,
+		}},
+	}
+	cur := infiniband.IBPorts{
+		{Device: "mlx5_0", Port: 1, Counters: map[string]uint64{
+			infiniband.CounterSymbolError: 40,
+			infiniband.CounterLinkDowned:  1,
+			"port_xmit_data":              200,
+		}},
+		// counter reset (e.g., driver reload)
+		{Device: "mlx5_1", Port: 1, Counters: map[string]uint64{
+			infiniband.CounterPortRcvErrors: 5,
+		}},
+		// new port, no previous sample
+		{Device: "mlx5_2", Port: 1, Counters: map[string]uint64{
+			infiniband.CounterSymbolError: 1000,
+		}},
+	}
+
+	assert.Nil(t, computeCounterDeltas(nil, cur, time.Minute))
+	assert.Nil(t, computeCounterDeltas(prev, cur, 0))
+
+	deltas := computeCounterDeltas(prev, cur, 2*time.Minute)
+	assert.Equal(t, []PortCounterDelta{
+		{Port: "mlx5_0:1", Counter: infiniband.CounterSymbolError, Delta: 30, PerMinute: 15},
+		{Port: "mlx5_0:1", Counter: infiniband.CounterLinkDowned, Delta: 0, PerMinute: 0},
+		{Port: "mlx5_1:1", Counter: infiniband.CounterPortRcvErrors, Delta: 5, PerMinute: 2.5},
+	}, deltas)
+}
+
+func TestEvaluateCounterDeltas(t *testing.T) {
+	deltas := []PortCounterDelta{
+		{Port: "mlx5_0:1", Counter: infiniband.CounterSymbolError, Delta: 30, PerMinute: 15},
+		{Port: "mlx5_0:1", Counter: infiniband.CounterLinkDowned, Delta: 1, PerMinute: 1},
+		{Port: "mlx5_1:1", Counter: infiniband.CounterLinkDowned, Delta: 4, PerMinute: 2},
+		{Port: "mlx5_1:1", Counter: infiniband.CounterPortRcvErrors, Delta: 5, PerMinute: 2.5},
+	}
+
+	reasons := evaluateCounterDeltas(deltas, GetDefaultErrorRateThresholds())
+	require.Len(t, reasons, 2)
+	assert.Equal(t, "mlx5_0:1 symbol_error increased by 30 (15.0/min, expected at most 10.0/min)", reasons[0])
+	assert.Equal(t, "mlx5_1:1 link_downed increased by 4 (2.0/min, expected at most 1.0/min)", reasons[1])
+
+	// zero or missing thresholds disable the evaluation
+	assert.Empty(t, evaluateCounterDeltas(deltas, ErrorRateThresholds{}))
+	assert.Empty(t, evaluateCounterDeltas(deltas, ErrorRateThresholds{MaxPerMinute: map[string]float64{infiniband.CounterSymbolError: 0}}))
+}
+
+func TestDefaultErrorRateThresholds(t *testing.T) {
+	orig := GetDefaultErrorRateThresholds()
+	defer SetDefaultErrorRateThresholds(orig)
+
+	assert.Equal(t, 10.0, orig.MaxPerMinute[infiniband.CounterSymbolError])
+	assert.Len(t, orig.MaxPerMinute, len(trackedCounters))
+
+	SetDefaultErrorRateThresholds(ErrorRateThresholds{MaxPerMinute: map[string]float64{infiniband.CounterSymbolError: 100}})
+	assert.Equal(t, 100.0, GetDefaultErrorRateThresholds().MaxPerMinute[infiniband.CounterSymbolError])
+}
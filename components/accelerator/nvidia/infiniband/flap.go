@@ -0,0 +1,185 @@
+package infiniband
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	apiv1 "github.com/leptonai/gpud/api/v1"
+	"github.com/leptonai/gpud/pkg/log"
+	"github.com/leptonai/gpud/pkg/nvidia-query/infiniband"
+)
+
+const (
+	// EventNamePortStateChange is the event name for the port state transitions.
+	EventNamePortStateChange = "infiniband_port_state_change"
+
+	EventKeyPort             = "port"
+	EventKeyOldState         = "old_state"
+	EventKeyNewState         = "new_state"
+	EventKeyOldPhysicalState = "old_physical_state"
+	EventKeyNewPhysicalState = "new_physical_state"
+	EventKeyRate             = "rate"
+)
+
+const portStateActive = "Active"
+
+// PortFlaps is the number of link flaps of a port within the window.
+type PortFlaps struct {
+	// Port is the port name (e.g., "mlx5_0:1").
+	Port string `json:"port"`
+	// Count is the number of times the port went out of "Active" within the window.
+	Count int `json:"count"`
+}
+
+// watchPortStates samples the port states from sysfs at the configured interval,
+// which is more frequent than the regular checks to catch the short link flaps.
+func (c *component) watchPortStates() {
+	for {
+		interval := c.getLinkFlapThresholdsFunc().SampleInterval.Duration
+		if interval <= 0 {
+			interval = defaultLinkFlapSampleInterval
+		}
+
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+
+		// sampled before reading, to order with the samples of the concurrent checks
+		ts := time.Now().UTC()
+		ports, err := c.getIBPortsFunc(c.sysfsRoot)
+		if err != nil {
+			continue
+		}
+		c.observePortStates(ts, ports)
+	}
+}
+
+// observePortStates compares the ports with the previously observed states,
+// and records the transitions as events and the link flaps.
+// The first observation of a port only sets its baseline state.
+// The ports are sampled by both the watcher and the checks, so the sample
+// older than the last observed one is dropped, not to record false transitions.
+func (c *component) observePortStates(ts time.Time, ports infiniband.IBPorts) {
+	c.portStatesMu.Lock()
+	defer c.portStatesMu.Unlock()
+
+	if ts.Before(c.portStatesTs) {
+		log.Logger.Debugw("skipping stale infiniband port states", "sampledAt", ts, "lastSampledAt", c.portStatesTs)
+		return
+	}
+	c.portStatesTs = ts
+
+	if c.portStates == nil {
+		c.portStates = make(map[string]infiniband.IBPort)
+	}
+	if c.portFlaps == nil {
+		c.portFlaps = make(map[string][]time.Time)
+	}
+
+	for _, p := range ports {
+		name := p.Name()
+		prev, ok := c.portStates[name]
+		c.portStates[name] = p
+		if !ok {
+			continue
+		}
+		if prev.State == p.State && prev.PhysicalState == p.PhysicalState {
+			continue
+		}
+
+		log.Logger.Warnw("infiniband port state changed",
+			"port", name,
+			"oldState", prev.State,
+			"newState", p.State,
+			"oldPhysicalState", prev.PhysicalState,
+			"newPhysicalState", p.PhysicalState,
+			"rate", p.Rate,
+		)
+
+		// link flap is the port going out of "Active"
+		if prev.State == portStateActive && p.State != portStateActive {
+			c.portFlaps[name] = append(c.portFlaps[name], ts)
+		}
+
+		if c.eventBucket == nil {
+			continue
+		}
+
+		evType := apiv1.EventTypeInfo
+		if p.State != portStateActive {
+			evType = apiv1.EventTypeWarning
+		}
+		ev := apiv1.Event{
+			Time:    metav1.Time{Time: ts},
+			Name:    EventNamePortStateChange,
+			Type:    evType,
+			Message: fmt.Sprintf("%s state changed from %s (%s) to %s (%s) at %d Gb/sec", name, prev.State, prev.PhysicalState, p.State, p.PhysicalState, p.Rate),
+			DeprecatedExtraInfo: map[string]string{
+				EventKeyPort:             name,
+				EventKeyOldState:         prev.State,
+				EventKeyNewState:         p.State,
+				EventKeyOldPhysicalState: prev.PhysicalState,
+				EventKeyNewPhysicalState: p.PhysicalState,
+				EventKeyRate:             strconv.Itoa(p.Rate),
+			},
+		}
+
+		cctx, ccancel := context.WithTimeout(c.ctx, 15*time.Second)
+		err := c.eventBucket.Insert(cctx, ev)
+		ccancel()
+		if err != nil {
+			log.Logger.Errorw("failed to insert infiniband port state change event", "port", name, "error", err)
+		}
+	}
+}
+
+// getPortFlaps returns the number of link flaps of each port within the window,
+// pruning the ones older than the window.
+func (c *component) getPortFlaps(now time.Time, window time.Duration) []PortFlaps {
+	c.portStatesMu.Lock()
+	defer c.portStatesMu.Unlock()
+
+	flaps := make([]PortFlaps, 0)
+	for port, tss := range c.portFlaps {
+		kept := make([]time.Time, 0, len(tss))
+		for _, ts := range tss {
+			if now.Sub(ts) <= window {
+				kept = append(kept, ts)
+			}
+		}
+		if len(kept) == 0 {
+			delete(c.portFlaps, port)
+			continue
+		}
+		c.portFlaps[port] = kept
+		flaps = append(flaps, PortFlaps{Port: port, Count: len(kept)})
+	}
+
+	sort.Slice(flaps, func(i, j int) bool {
+		return flaps[i].Port < flaps[j].Port
+	})
+	return flaps
+}
+
+// evaluatePortFlaps returns the reasons for the ports whose link flaps exceed the thresholds.
+func evaluatePortFlaps(flaps []PortFlaps, thresholds LinkFlapThresholds) []string {
+	if thresholds.MaxFlaps <= 0 {
+		return nil
+	}
+
+	reasons := make([]string, 0)
+	for _, f := range flaps {
+		if f.Count <= thresholds.MaxFlaps {
+			continue
+		}
+		reasons = append(reasons, fmt.Sprintf("%s flapped %d time(s) in the last %s (expected at most %d)", f.Port, f.Count, thresholds.Window.Duration, thresholds.MaxFlaps))
+	}
+	return reasons
+}
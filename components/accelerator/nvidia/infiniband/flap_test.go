@@ -0,0 +1,198 @@
+package infiniband
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	apiv1 "github.com/leptonai/gpud/api/v1"
+	"github.com/leptonai/gpud/pkg/nvidia-query/infiniband"
+)
+
+func testPort(dev string, state string, physState string) infiniband.IBPort {
+	return infiniband.IBPort{Device: dev, Port: 1, State: state, PhysicalState: physState, Rate: 400}
+}
+
+func TestObservePortStates(t *testing.T) {
+	cctx, ccancel := context.WithCancel(context.Background())
+	defer ccancel()
+
+	mockBucket := NewMockEventBucket()
+	c := &component{
+		ctx:         cctx,
+		cancel:      ccancel,
+		eventBucket: mockBucket,
+	}
+
+	now := time.Now().UTC()
+
+	// baseline, no transition
+	c.observePortStates(now, infiniband.IBPorts{testPort("mlx5_0", "Active", "LinkUp"), testPort("mlx5_1", "Active", "LinkUp")})
+	assert.Empty(t, mockBucket.GetEvents())
+
+	// mlx5_1 flaps twice
+	c.observePortStates(now.Add(5*time.Second), infiniband.IBPorts{testPort("mlx5_0", "Active", "LinkUp"), testPort("mlx5_1", "Down", "Polling")})
+	c.observePortStates(now.Add(10*time.Second), infiniband.IBPorts{testPort("mlx5_0", "Active", "LinkUp"), testPort("mlx5_1", "Active", "LinkUp")})
+	c.observePortStates(now.Add(15*time.Second), infiniband.IBPorts{testPort("mlx5_0", "Active", "LinkUp"), testPort("mlx5_1", "Down", "Disabled")})
+
+	events := mockBucket.GetEvents()
+	require.Len(t, events, 3)
+	assert.Equal(t, EventNamePortStateChange, events[0].Name)
+	assert.Equal(t, apiv1.EventTypeWarning, events[0].Type)
+	assert.Equal(t, "mlx5_1:1 state changed from Active (LinkUp) to Down (Polling) at 400 Gb/sec", events[0].Message)
+	assert.Equal(t, map[string]string{
+		EventKeyPort:             "mlx5_1:1",
+		EventKeyOldState:         "Active",
+		EventKeyNewState:         "Down",
+		EventKeyOldPhysicalState: "LinkUp",
+		EventKeyNewPhysicalState: "Polling",
+		EventKeyRate:             "400",
+	}, events[0].DeprecatedExtraInfo)
+	assert.Equal(t, apiv1.EventTypeInfo, events[1].Type)
+	assert.Equal(t, "Down", events[2].DeprecatedExtraInfo[EventKeyNewState])
+	assert.Equal(t, "Disabled", events[2].DeprecatedExtraInfo[EventKeyNewPhysicalState])
+
+	flaps := c.getPortFlaps(now.Add(20*time.Second), time.Minute)
+	assert.Equal(t, []PortFlaps{{Port: "mlx5_1:1", Count: 2}}, flaps)
+
+	// the flaps older than the window are pruned
+	flaps = c.getPortFlaps(now.Add(70*time.Second), time.Minute)
+	assert.Equal(t, []PortFlaps{{Port: "mlx5_1:1", Count: 1}}, flaps)
+	flaps = c.getPortFlaps(now.Add(2*time.Minute), time.Minute)
+	assert.Empty(t, flaps)
+}
+
+func TestObservePortStatesStaleSample(t *testing.T) {
+	cctx, ccancel := context.WithCancel(context.Background())
+	defer ccancel()
+
+	mockBucket := NewMockEventBucket()
+	c := &component{
+		ctx:         cctx,
+		cancel:      ccancel,
+		eventBucket: mockBucket,
+	}
+
+	now := time.Now().UTC()
+	c.observePortStates(now, infiniband.IBPorts{testPort("mlx5_0", "Active", "LinkUp")})
+	c.observePortStates(now.Add(10*time.Second), infiniband.IBPorts{testPort("mlx5_0", "Down", "Polling")})
+	require.Len(t, mockBucket.GetEvents(), 1)
+
+	// e.g., the check sampled before the watcher, but observed after
+	c.observePortStates(now.Add(5*time.Second), infiniband.IBPorts{testPort("mlx5_0", "Active", "LinkUp")})
+	assert.Len(t, mockBucket.GetEvents(), 1)
+	assert.Equal(t, []PortFlaps{{Port: "mlx5_0:1", Count: 1}}, c.getPortFlaps(now.Add(20*time.Second), time.Minute))
+
+	// the newer sample is compared with the last observed state
+	c.observePortStates(now.Add(15*time.Second), infiniband.IBPorts{testPort("mlx5_0", "Down", "Polling")})
+	assert.Len(t, mockBucket.GetEvents(), 1)
+	c.observePortStates(now.Add(20*time.Second), infiniband.IBPorts{testPort("mlx5_0", "Active", "LinkUp")})
+	assert.Len(t, mockBucket.GetEvents(), 2)
+	assert.Equal(t, []PortFlaps{{Port: "mlx5_0:1", Count: 1}}, c.getPortFlaps(now.Add(20*time.Second), time.Minute))
+}
+
+func TestEvaluatePortFlaps(t *testing.T) {
+	flaps := []PortFlaps{{Port: "mlx5_0:1", Count: 1}, {Port: "mlx5_1:1", Count: 4}}
+
+	thresholds := LinkFlapThresholds{Window: metav1.Duration{Duration: 10 * time.Minute}, MaxFlaps: 3}
+	assert.Equal(t, []string{"mlx5_1:1 flapped 4 time(s) in the last 10m0s (expected at most 3)"}, evaluatePortFlaps(flaps, thresholds))
+
+	// disabled
+	assert.Empty(t, evaluatePortFlaps(flaps, LinkFlapThresholds{}))
+}
+
+func TestCheckLinkFlaps(t *testing.T) {
+	cctx, ccancel := context.WithCancel(context.Background())
+	defer ccancel()
+
+	ports := infiniband.IBPorts{testPort("mlx5_0", "Active", "LinkUp")}
+	mockBucket := NewMockEventBucket()
+	c := &component{
+		ctx:          cctx,
+		cancel:       ccancel,
+		eventBucket:  mockBucket,
+		nvmlInstance: &mockNVMLInstance{exists: true},
+		getThresholdsFunc: func() infiniband.ExpectedPortStates {
+			return infiniband.ExpectedPortStates{}
+		},
+		getIBPortsFunc: func(root string) (infiniband.IBPorts, error) {
+			return ports, nil
+		},
+		getLinkFlapThresholdsFunc: func() LinkFlapThresholds {
+			return LinkFlapThresholds{Window: metav1.Duration{Duration: time.Hour}, MaxFlaps: 1}
+		},
+	}
+
+	data := c.Check().(*Data)
+	assert.Equal(t, apiv1.HealthStateTypeHealthy, data.health)
+	assert.Empty(t, data.PortFlaps)
+
+	// the watcher observes the flaps between the checks
+	now := time.Now().UTC()
+	c.observePortStates(now, infiniband.IBPorts{testPort("mlx5_0", "Down", "Polling")})
+	c.observePortStates(now, infiniband.IBPorts{testPort("mlx5_0", "Active", "LinkUp")})
+	c.observePortStates(now, infiniband.IBPorts{testPort("mlx5_0", "Down", "Polling")})
+
+	data = c.Check().(*Data)
+	assert.Equal(t, apiv1.HealthStateTypeDegraded, data.health)
+	assert.Equal(t, "mlx5_0:1 flapped 2 time(s) in the last 1h0m0s (expected at most 1)", data.reason)
+	assert.Equal(t, []PortFlaps{{Port: "mlx5_0:1", Count: 2}}, data.PortFlaps)
+
+	events := mockBucket.GetEvents()
+	require.Len(t, events, 5)
+	assert.Equal(t, EventNamePortStateChange, events[0].Name)
+	// the check observes the port back to "Active"
+	assert.Equal(t, "Active", events[3].DeprecatedExtraInfo[EventKeyNewState])
+	assert.Equal(t, EventNamePortErrors, events[4].Name)
+}
+
+func TestWatchPortStates(t *testing.T) {
+	cctx, ccancel := context.WithCancel(context.Background())
+	defer ccancel()
+
+	samples := make(chan infiniband.IBPorts, 2)
+	samples <- infiniband.IBPorts{testPort("mlx5_0", "Active", "LinkUp")}
+	samples <- infiniband.IBPorts{testPort("mlx5_0", "Down", "Polling")}
+
+	mockBucket := NewMockEventBucket()
+	c := &component{
+		ctx:         cctx,
+		cancel:      ccancel,
+		eventBucket: mockBucket,
+		getIBPortsFunc: func(root string) (infiniband.IBPorts, error) {
+			select {
+			case ports := <-samples:
+				return ports, nil
+			default:
+				return nil, infiniband.ErrNoSysfsDevice
+			}
+		},
+		getLinkFlapThresholdsFunc: func() LinkFlapThresholds {
+			return LinkFlapThresholds{SampleInterval: metav1.Duration{Duration: 10 * time.Millisecond}}
+		},
+	}
+	go c.watchPortStates()
+
+	require.Eventually(t, func() bool {
+		return len(mockBucket.GetEvents()) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	ccancel()
+
+	assert.Equal(t, "Down", mockBucket.GetEvents()[0].DeprecatedExtraInfo[EventKeyNewState])
+}
+
+func TestDefaultLinkFlapThresholds(t *testing.T) {
+	orig := GetDefaultLinkFlapThresholds()
+	defer SetDefaultLinkFlapThresholds(orig)
+
+	assert.Equal(t, 5*time.Second, orig.SampleInterval.Duration)
+	assert.Equal(t, 10*time.Minute, orig.Window.Duration)
+	assert.Equal(t, 3, orig.MaxFlaps)
+
+	SetDefaultLinkFlapThresholds(LinkFlapThresholds{MaxFlaps: 10})
+	assert.Equal(t, 10, GetDefaultLinkFlapThresholds().MaxFlaps)
+}
@@ -2,6 +2,9 @@ package infiniband
 
 import (
 	"regexp"
+
+	apiv1 "github.com/leptonai/gpud/api/v1"
+	"github.com/leptonai/gpud/pkg/kmsg"
 )
 
 const (
@@ -50,12 +53,20 @@ func HasPortModuleHighTemperature(line string) bool {
 }
 
 func Match(line string) (eventName string, message string) {
+	ev := MatchEvent(line)
+	if ev == nil {
+		return "", ""
+	}
+	return ev.Name, ev.Message
+}
+
+func MatchEvent(line string) *kmsg.MatchedEvent {
 	for _, m := range getMatches() {
 		if m.check(line) {
-			return m.eventName, m.message
+			return kmsg.NewMatchedEvent(m.eventName, m.message, m.eventType, m.repairActions)
 		}
 	}
-	return "", ""
+	return nil
 }
 
 type match struct {
@@ -63,11 +74,14 @@ type match struct {
 	eventName string
 	regex     string
 	message   string
+
+	eventType     apiv1.EventType
+	repairActions []apiv1.RepairActionType
 }
 
 func getMatches() []match {
 	return []match{
-		{check: HasPCIPowerInsufficient, eventName: eventPCIPowerInsufficient, regex: regexPCIPowerInsufficient, message: messagePCIPowerInsufficient},
-		{check: HasPortModuleHighTemperature, eventName: eventPortModuleHighTemperature, regex: regexPortModuleHighTemperature, message: messagePortModuleHighTemperature},
+		{check: HasPCIPowerInsufficient, eventName: eventPCIPowerInsufficient, regex: regexPCIPowerInsufficient, message: messagePCIPowerInsufficient, eventType: apiv1.EventTypeWarning, repairActions: []apiv1.RepairActionType{apiv1.RepairActionTypeHardwareInspection}},
+		{check: HasPortModuleHighTemperature, eventName: eventPortModuleHighTemperature, regex: regexPortModuleHighTemperature, message: messagePortModuleHighTemperature, eventType: apiv1.EventTypeWarning, repairActions: []apiv1.RepairActionType{apiv1.RepairActionTypeHardwareInspection}},
 	}
 }
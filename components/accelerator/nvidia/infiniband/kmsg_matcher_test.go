@@ -4,6 +4,8 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+
+	apiv1 "github.com/leptonai/gpud/api/v1"
 )
 
 func TestHasPCIPowerInsufficient(t *testing.T) {
@@ -249,3 +251,14 @@ func TestLogLineProcessorWithMoreExamples(t *testing.T) {
 		})
 	}
 }
+
+func TestMatchEvent(t *testing.T) {
+	ev := MatchEvent("mlx5_port_module_event:1131:(pid 0): Port module event[error]: module 0, Cable error, High Temperature")
+	assert.NotNil(t, ev)
+	assert.Equal(t, eventPortModuleHighTemperature, ev.Name)
+	assert.Equal(t, messagePortModuleHighTemperature, ev.Message)
+	assert.Equal(t, apiv1.EventTypeWarning, ev.Type)
+	assert.Equal(t, &apiv1.SuggestedActions{RepairActions: []apiv1.RepairActionType{apiv1.RepairActionTypeHardwareInspection}}, ev.SuggestedActions)
+
+	assert.Nil(t, MatchEvent("no match"))
+}
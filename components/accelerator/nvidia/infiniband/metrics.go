@@ -0,0 +1,94 @@
+package infiniband
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	pkgmetrics "github.com/leptonai/gpud/pkg/metrics"
+	"github.com/leptonai/gpud/pkg/nvidia-query/infiniband"
+)
+
+const SubSystem = "accelerator_nvidia_infiniband"
+
+var (
+	componentLabel = prometheus.Labels{
+		pkgmetrics.MetricComponentLabelKey: Name,
+	}
+
+	metricPortSymbolErrorDelta = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "",
+			Subsystem: SubSystem,
+			Name:      "port_symbol_error_delta",
+			Help:      "tracks the increase of the port symbol errors since the last check",
+		},
+		[]string{pkgmetrics.MetricComponentLabelKey, pkgmetrics.MetricLabelKey}, // label is the port name (e.g., "mlx5_0:1")
+	).MustCurryWith(componentLabel)
+
+	metricPortLinkDownedDelta = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "",
+			Subsystem: SubSystem,
+			Name:      "port_link_downed_delta",
+			Help:      "tracks the increase of the port link downed count since the last check",
+		},
+		[]string{pkgmetrics.MetricComponentLabelKey, pkgmetrics.MetricLabelKey}, // label is the port name (e.g., "mlx5_0:1")
+	).MustCurryWith(componentLabel)
+
+	metricPortLinkErrorRecoveryDelta = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "",
+			Subsystem: SubSystem,
+			Name:      "port_link_error_recovery_delta",
+			Help:      "tracks the increase of the port link error recovery count since the last check",
+		},
+		[]string{pkgmetrics.MetricComponentLabelKey, pkgmetrics.MetricLabelKey}, // label is the port name (e.g., "mlx5_0:1")
+	).MustCurryWith(componentLabel)
+
+	metricPortRcvErrorsDelta = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "",
+			Subsystem: SubSystem,
+			Name:      "port_rcv_errors_delta",
+			Help:      "tracks the increase of the port receive errors since the last check",
+		},
+		[]string{pkgmetrics.MetricComponentLabelKey, pkgmetrics.MetricLabelKey}, // label is the port name (e.g., "mlx5_0:1")
+	).MustCurryWith(componentLabel)
+
+	metricPortExcessiveBufferOverrunErrorsDelta = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "",
+			Subsystem: SubSystem,
+			Name:      "port_excessive_buffer_overrun_errors_delta",
+			Help:      "tracks the increase of the port excessive buffer overrun errors since the last check",
+		},
+		[]string{pkgmetrics.MetricComponentLabelKey, pkgmetrics.MetricLabelKey}, // label is the port name (e.g., "mlx5_0:1")
+	).MustCurryWith(componentLabel)
+
+	counterDeltaMetrics = map[string]*prometheus.GaugeVec{
+		infiniband.CounterSymbolError:                  metricPortSymbolErrorDelta,
+		infiniband.CounterLinkDowned:                   metricPortLinkDownedDelta,
+		infiniband.CounterLinkErrorRecovery:            metricPortLinkErrorRecoveryDelta,
+		infiniband.CounterPortRcvErrors:                metricPortRcvErrorsDelta,
+		infiniband.CounterExcessiveBufferOverrunErrors: metricPortExcessiveBufferOverrunErrorsDelta,
+	}
+)
+
+func init() {
+	pkgmetrics.MustRegister(
+		metricPortSymbolErrorDelta,
+		metricPortLinkDownedDelta,
+		metricPortLinkErrorRecoveryDelta,
+		metricPortRcvErrorsDelta,
+		metricPortExcessiveBufferOverrunErrorsDelta,
+	)
+}
+
+func setCounterDeltaMetrics(deltas []PortCounterDelta) {
+	for _, d := range deltas {
+		m, ok := counterDeltaMetrics[d.Counter]
+		if !ok {
+			continue
+		}
+		m.With(prometheus.Labels{pkgmetrics.MetricLabelKey: d.Port}).Set(float64(d.Delta))
+	}
+}
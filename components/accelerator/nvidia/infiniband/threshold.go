@@ -2,6 +2,9 @@ package infiniband
 
 import (
 	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/leptonai/gpud/pkg/log"
 	"github.com/leptonai/gpud/pkg/nvidia-query/infiniband"
@@ -28,3 +31,77 @@ func SetDefaultExpectedPortStates(states infiniband.ExpectedPortStates) {
 	defer defaultExpectedPortStatesMu.Unlock()
 	defaultExpectedPortStates = states
 }
+
+// ErrorRateThresholds configures the maximum increase rate of the port error counters,
+// exceeding which marks the component degraded.
+type ErrorRateThresholds struct {
+	// MaxPerMinute is the maximum increase per minute of each port error counter,
+	// keyed by the counter name (e.g., "symbol_error").
+	// The counter is not evaluated if not set or zero.
+	MaxPerMinute map[string]float64 `json:"max_per_minute"`
+}
+
+var (
+	defaultErrorRateThresholdsMu sync.RWMutex
+	defaultErrorRateThresholds   = ErrorRateThresholds{
+		MaxPerMinute: map[string]float64{
+			infiniband.CounterSymbolError:                  10,
+			infiniband.CounterLinkDowned:                   1,
+			infiniband.CounterLinkErrorRecovery:            1,
+			infiniband.CounterPortRcvErrors:                10,
+			infiniband.CounterExcessiveBufferOverrunErrors: 1,
+		},
+	}
+)
+
+func GetDefaultErrorRateThresholds() ErrorRateThresholds {
+	defaultErrorRateThresholdsMu.RLock()
+	defer defaultErrorRateThresholdsMu.RUnlock()
+	return defaultErrorRateThresholds
+}
+
+func SetDefaultErrorRateThresholds(thresholds ErrorRateThresholds) {
+	log.Logger.Infow("setting default error rate thresholds", "max_per_minute", thresholds.MaxPerMinute)
+
+	defaultErrorRateThresholdsMu.Lock()
+	defer defaultErrorRateThresholdsMu.Unlock()
+	defaultErrorRateThresholds = thresholds
+}
+
+// LinkFlapThresholds configures the port state sampling and the link flap limit,
+// exceeding which marks the component degraded.
+type LinkFlapThresholds struct {
+	// SampleInterval is the interval to sample the port states from sysfs.
+	// If not set, it defaults to 5 seconds.
+	SampleInterval metav1.Duration `json:"sample_interval"`
+	// Window is the time window to count the link flaps in.
+	Window metav1.Duration `json:"window"`
+	// MaxFlaps is the maximum number of link flaps (port going out of "Active") per port within the window.
+	// The link flaps are not evaluated if not set or zero.
+	MaxFlaps int `json:"max_flaps"`
+}
+
+const defaultLinkFlapSampleInterval = 5 * time.Second
+
+var (
+	defaultLinkFlapThresholdsMu sync.RWMutex
+	defaultLinkFlapThresholds   = LinkFlapThresholds{
+		SampleInterval: metav1.Duration{Duration: defaultLinkFlapSampleInterval},
+		Window:         metav1.Duration{Duration: 10 * time.Minute},
+		MaxFlaps:       3,
+	}
+)
+
+func GetDefaultLinkFlapThresholds() LinkFlapThresholds {
+	defaultLinkFlapThresholdsMu.RLock()
+	defer defaultLinkFlapThresholdsMu.RUnlock()
+	return defaultLinkFlapThresholds
+}
+
+func SetDefaultLinkFlapThresholds(thresholds LinkFlapThresholds) {
+	log.Logger.Infow("setting default link flap thresholds", "sample_interval", thresholds.SampleInterval.Duration, "window", thresholds.Window.Duration, "max_flaps", thresholds.MaxFlaps)
+
+	defaultLinkFlapThresholdsMu.Lock()
+	defer defaultLinkFlapThresholdsMu.Unlock()
+	defaultLinkFlapThresholds = thresholds
+}
@@ -6,6 +6,8 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -36,6 +38,7 @@ type component struct {
 	getProductNameFunc   func(dev device.Device) (string, error)
 	getArchitectureFunc  func(dev device.Device) (string, error)
 	getBrandFunc         func(dev device.Device) (string, error)
+	getMIGFunc           func(uuid string, dev device.Device) (nvidianvml.MIG, error)
 
 	lastMu   sync.RWMutex
 	lastData *Data
@@ -54,6 +57,7 @@ func New(gpudInstance *components.GPUdInstance) (components.Component, error) {
 		getProductNameFunc:   nvidianvml.GetProductName,
 		getArchitectureFunc:  nvidianvml.GetArchitecture,
 		getBrandFunc:         nvidianvml.GetBrand,
+		getMIGFunc:           nvidianvml.GetMIG,
 	}
 	return c, nil
 }
@@ -191,6 +195,24 @@ func (c *component) Check() components.CheckResult {
 		break
 	}
 
+	// the MIG layout is informational, and its failure does not affect the health state
+	uuids := make([]string, 0, len(devs))
+	for uuid := range devs {
+		uuids = append(uuids, uuid)
+	}
+	sort.Strings(uuids)
+	for _, uuid := range uuids {
+		mig, err := c.getMIGFunc(uuid, devs[uuid])
+		if err != nil {
+			log.Logger.Warnw("error getting mig devices", "uuid", uuid, "error", err)
+			continue
+		}
+		if !mig.Supported {
+			continue
+		}
+		d.MIG = append(d.MIG, mig)
+	}
+
 	d.health = apiv1.HealthStateTypeHealthy
 	d.reason = fmt.Sprintf("all %d GPU(s) were checked", len(devs))
 
@@ -206,6 +228,10 @@ type Data struct {
 	Memory  Memory  `json:"memory"`
 	Product Product `json:"products"`
 
+	// MIG is the MIG mode and the MIG devices of each GPU,
+	// empty if no GPU supports the MIG mode.
+	MIG []nvidianvml.MIG `json:"mig,omitempty"`
+
 	// timestamp of the last check
 	ts time.Time
 	// error from the last check
@@ -268,6 +294,23 @@ func (d *Data) String() string {
 	table.Append([]string{"GPU Memory", d.Memory.TotalHumanized})
 	table.Render()
 
+	if len(d.MIG) > 0 {
+		buf.WriteString("\n")
+
+		migTable := tablewriter.NewWriter(buf)
+		migTable.SetAlignment(tablewriter.ALIGN_CENTER)
+		migTable.SetHeader([]string{"GPU UUID", "MIG Enabled", "MIG Pending", "MIG Profiles"})
+		for _, mig := range d.MIG {
+			migTable.Append([]string{
+				mig.UUID,
+				fmt.Sprintf("%v", mig.Enabled),
+				fmt.Sprintf("%v", mig.PendingEnabled),
+				strings.Join(mig.Profiles(), ", "),
+			})
+		}
+		migTable.Render()
+	}
+
 	return buf.String()
 }
 
@@ -312,6 +312,10 @@ func TestCheckOnce_WithDevices(t *testing.T) {
 		return "NVIDIA", nil
 	}
 
+	c.getMIGFunc = func(uuid string, dev device.Device) (nvidianvml.MIG, error) {
+		return nvidianvml.MIG{UUID: uuid}, nil
+	}
+
 	// Call the function
 	result := c.Check()
 	d := result.(*Data)
@@ -800,3 +804,58 @@ func TestData_StringAndUtilityMethods(t *testing.T) {
 	assert.Equal(t, apiv1.HealthStateTypeHealthy, data.HealthState())
 	assert.Equal(t, apiv1.HealthStateType(""), nilData.HealthState())
 }
+
+func TestCheckOnce_MIG(t *testing.T) {
+	ctx := context.Background()
+	mockInstance := new(MockNVMLInstanceV2)
+
+	mockDev1 := testutil.NewMockDevice(&nvmlmock.Device{}, "Hopper", "NVIDIA", "9.0", "0000:00:1E.0")
+	mockDev2 := testutil.NewMockDevice(&nvmlmock.Device{}, "Hopper", "NVIDIA", "9.0", "0000:00:1F.0")
+	mockDev3 := testutil.NewMockDevice(&nvmlmock.Device{}, "Hopper", "NVIDIA", "9.0", "0000:00:20.0")
+	mockInstance.On("Devices").Return(map[string]device.Device{
+		"GPU-1": mockDev1,
+		"GPU-2": mockDev2,
+		"GPU-3": mockDev3,
+	})
+
+	comp, err := New(createMockGPUdInstance(ctx, mockInstance))
+	assert.NoError(t, err)
+
+	c := comp.(*component)
+	c.getDriverVersionFunc = func() (string, error) { return "550.90.07", nil }
+	c.getCUDAVersionFunc = func() (string, error) { return "12.4", nil }
+	c.getDeviceCountFunc = func() (int, error) { return 3, nil }
+	c.getMemoryFunc = func(uuid string, dev device.Device) (nvidianvml.Memory, error) {
+		return nvidianvml.Memory{TotalHumanized: "80GB"}, nil
+	}
+	c.getProductNameFunc = func(dev device.Device) (string, error) { return "NVIDIA H100 80GB HBM3", nil }
+	c.getArchitectureFunc = func(dev device.Device) (string, error) { return "Hopper", nil }
+	c.getBrandFunc = func(dev device.Device) (string, error) { return "NVIDIA", nil }
+	c.getMIGFunc = func(uuid string, dev device.Device) (nvidianvml.MIG, error) {
+		switch uuid {
+		case "GPU-1":
+			return nvidianvml.MIG{
+				UUID:      uuid,
+				Enabled:   true,
+				Supported: true,
+				Instances: []nvidianvml.MIGInstance{
+					{UUID: "MIG-1", ParentUUID: uuid, GPUInstanceID: 1, Profile: "3g.40gb"},
+					{UUID: "MIG-2", ParentUUID: uuid, GPUInstanceID: 2, Profile: "3g.40gb"},
+				},
+			}, nil
+		case "GPU-2":
+			return nvidianvml.MIG{UUID: uuid}, nil
+		default:
+			return nvidianvml.MIG{}, errors.New("mig error")
+		}
+	}
+
+	d := c.Check().(*Data)
+
+	// the MIG error does not affect the health state
+	assert.Equal(t, apiv1.HealthStateTypeHealthy, d.health)
+	assert.Len(t, d.MIG, 1)
+	assert.Equal(t, "GPU-1", d.MIG[0].UUID)
+	assert.Equal(t, []string{"3g.40gb", "3g.40gb"}, d.MIG[0].Profiles())
+	assert.Contains(t, d.String(), "3g.40gb, 3g.40gb")
+}
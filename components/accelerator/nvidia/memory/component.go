@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/NVIDIA/go-nvlib/pkg/nvlib/device"
+	"github.com/dustin/go-humanize"
 	"github.com/olekukonko/tablewriter"
 	"github.com/prometheus/client_golang/prometheus"
 
@@ -30,6 +31,7 @@ type component struct {
 
 	nvmlInstance  nvidianvml.InstanceV2
 	getMemoryFunc func(uuid string, dev device.Device) (nvidianvml.Memory, error)
+	getMIGFunc    func(uuid string, dev device.Device) (nvidianvml.MIG, error)
 
 	lastMu   sync.RWMutex
 	lastData *Data
@@ -42,6 +44,7 @@ func New(gpudInstance *components.GPUdInstance) (components.Component, error) {
 		cancel:        ccancel,
 		nvmlInstance:  gpudInstance.NVMLInstance,
 		getMemoryFunc: nvidianvml.GetMemory,
+		getMIGFunc:    nvidianvml.GetMIG,
 	}
 	return c, nil
 }
@@ -136,6 +139,19 @@ func (c *component) Check() components.CheckResult {
 			return d
 		}
 		metricUsedPercent.With(prometheus.Labels{pkgmetrics.MetricLabelKey: uuid}).Set(usedPct)
+
+		// attribute the memory usage to the MIG devices, if partitioned
+		mig, err := c.getMIGFunc(uuid, dev)
+		if err != nil {
+			log.Logger.Warnw("error getting mig devices", "uuid", uuid, "error", err)
+			continue
+		}
+		for _, inst := range mig.Instances {
+			d.MIGMemories = append(d.MIGMemories, inst)
+
+			metricMIGTotalBytes.With(prometheus.Labels{pkgmetrics.MetricLabelKey: inst.UUID}).Set(float64(inst.MemoryTotalBytes))
+			metricMIGUsedBytes.With(prometheus.Labels{pkgmetrics.MetricLabelKey: inst.UUID}).Set(float64(inst.MemoryUsedBytes))
+		}
 	}
 
 	d.health = apiv1.HealthStateTypeHealthy
@@ -148,6 +164,9 @@ var _ components.CheckResult = &Data{}
 
 type Data struct {
 	Memories []nvidianvml.Memory `json:"memories,omitempty"`
+	// MIGMemories is the memory usage of each MIG device,
+	// empty if no GPU is in the MIG mode.
+	MIGMemories []nvidianvml.MIGInstance `json:"mig_memories,omitempty"`
 
 	// timestamp of the last check
 	ts time.Time
@@ -184,6 +203,23 @@ func (d *Data) String() string {
 	}
 	table.Render()
 
+	if len(d.MIGMemories) > 0 {
+		buf.WriteString("\n")
+		table = tablewriter.NewWriter(buf)
+		table.SetAlignment(tablewriter.ALIGN_CENTER)
+		table.SetHeader([]string{"GPU UUID", "MIG UUID", "Profile", "Total", "Used"})
+		for _, inst := range d.MIGMemories {
+			table.Append([]string{
+				inst.ParentUUID,
+				inst.UUID,
+				inst.Profile,
+				humanize.Bytes(inst.MemoryTotalBytes),
+				humanize.Bytes(inst.MemoryUsedBytes),
+			})
+		}
+		table.Render()
+	}
+
 	return buf.String()
 }
 
@@ -65,6 +65,9 @@ func MockMemoryComponent(
 		cancel:        cancel,
 		nvmlInstance:  nvmlInstance,
 		getMemoryFunc: getMemoryFunc,
+		getMIGFunc: func(uuid string, dev device.Device) (nvidianvml.MIG, error) {
+			return nvidianvml.MIG{UUID: uuid}, nil
+		},
 	}
 }
 
@@ -646,3 +649,47 @@ func TestCheckOnce_NvmlNotExists(t *testing.T) {
 	assert.Equal(t, apiv1.HealthStateTypeHealthy, data.health, "data should be marked healthy")
 	assert.Equal(t, "NVIDIA NVML is not loaded", data.reason)
 }
+
+func TestCheckOnce_MIG(t *testing.T) {
+	ctx := context.Background()
+
+	uuid := "gpu-uuid-123"
+	mockDev := testutil.NewMockDevice(&mock.Device{}, "test-arch", "test-brand", "test-cuda", "test-pci")
+	mockNvmlInstance := &MockNvmlInstance{
+		DevicesFunc: func() map[string]device.Device {
+			return map[string]device.Device{uuid: mockDev}
+		},
+		nvmlExists: true,
+	}
+
+	getMemoryFunc := func(uuid string, dev device.Device) (nvidianvml.Memory, error) {
+		return nvidianvml.Memory{UUID: uuid, TotalBytes: 80 << 30, UsedBytes: 1 << 30, UsedPercent: "1.25", Supported: true}, nil
+	}
+	component := MockMemoryComponent(ctx, mockNvmlInstance, getMemoryFunc).(*component)
+	component.getMIGFunc = func(uuid string, dev device.Device) (nvidianvml.MIG, error) {
+		return nvidianvml.MIG{
+			UUID:      uuid,
+			Supported: true,
+			Enabled:   true,
+			Instances: []nvidianvml.MIGInstance{
+				{UUID: "MIG-1", ParentUUID: uuid, GPUInstanceID: 1, Profile: "3g.40gb", MemoryTotalBytes: 40 << 30, MemoryUsedBytes: 1 << 30},
+				{UUID: "MIG-2", ParentUUID: uuid, GPUInstanceID: 2, Profile: "3g.40gb", MemoryTotalBytes: 40 << 30},
+			},
+		}, nil
+	}
+
+	data := component.Check().(*Data)
+	assert.Equal(t, apiv1.HealthStateTypeHealthy, data.HealthState())
+	require.Len(t, data.MIGMemories, 2)
+	assert.Equal(t, "MIG-1", data.MIGMemories[0].UUID)
+	assert.Equal(t, uint64(1<<30), data.MIGMemories[0].MemoryUsedBytes)
+	assert.Contains(t, data.String(), "MIG-2")
+
+	// the mig errors do not fail the memory check
+	component.getMIGFunc = func(uuid string, dev device.Device) (nvidianvml.MIG, error) {
+		return nvidianvml.MIG{}, errors.New("mig error")
+	}
+	data = component.Check().(*Data)
+	assert.Equal(t, apiv1.HealthStateTypeHealthy, data.HealthState())
+	assert.Empty(t, data.MIGMemories)
+}
@@ -62,6 +62,26 @@ var (
 		},
 		[]string{pkgmetrics.MetricComponentLabelKey, pkgmetrics.MetricLabelKey}, // label is GPU ID
 	).MustCurryWith(componentLabel)
+
+	metricMIGTotalBytes = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "",
+			Subsystem: SubSystem,
+			Name:      "mig_total_bytes",
+			Help:      "tracks the total memory of the MIG device in bytes",
+		},
+		[]string{pkgmetrics.MetricComponentLabelKey, pkgmetrics.MetricLabelKey}, // label is MIG device UUID
+	).MustCurryWith(componentLabel)
+
+	metricMIGUsedBytes = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "",
+			Subsystem: SubSystem,
+			Name:      "mig_used_bytes",
+			Help:      "tracks the used memory of the MIG device in bytes",
+		},
+		[]string{pkgmetrics.MetricComponentLabelKey, pkgmetrics.MetricLabelKey}, // label is MIG device UUID
+	).MustCurryWith(componentLabel)
 )
 
 func init() {
@@ -71,5 +91,7 @@ func init() {
 		metricUsedBytes,
 		metricFreeBytes,
 		metricUsedPercent,
+		metricMIGTotalBytes,
+		metricMIGUsedBytes,
 	)
 }
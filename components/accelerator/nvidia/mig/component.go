@@ -0,0 +1,300 @@
+// Package mig tracks the NVIDIA Multi-Instance GPU (MIG) mode and the MIG devices,
+// and checks the MIG layout against the expected layout.
+package mig
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/NVIDIA/go-nvlib/pkg/nvlib/device"
+	"github.com/olekukonko/tablewriter"
+
+	apiv1 "github.com/leptonai/gpud/api/v1"
+	"github.com/leptonai/gpud/components"
+	"github.com/leptonai/gpud/pkg/log"
+	nvidianvml "github.com/leptonai/gpud/pkg/nvidia-query/nvml"
+)
+
+const Name = "accelerator-nvidia-mig"
+
+var _ components.Component = &component{}
+
+type component struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	nvmlInstance          nvidianvml.InstanceV2
+	getMIGFunc            func(uuid string, dev device.Device) (nvidianvml.MIG, error)
+	getExpectedLayoutFunc func() ExpectedLayout
+
+	lastMu   sync.RWMutex
+	lastData *Data
+}
+
+func New(gpudInstance *components.GPUdInstance) (components.Component, error) {
+	cctx, ccancel := context.WithCancel(gpudInstance.RootCtx)
+	c := &component{
+		ctx:                   cctx,
+		cancel:                ccancel,
+		nvmlInstance:          gpudInstance.NVMLInstance,
+		getMIGFunc:            nvidianvml.GetMIG,
+		getExpectedLayoutFunc: GetDefaultExpectedLayout,
+	}
+	return c, nil
+}
+
+func (c *component) Name() string { return Name }
+
+func (c *component) Start() error {
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+
+		for {
+			_ = c.Check()
+
+			select {
+			case <-c.ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return nil
+}
+
+func (c *component) LastHealthStates() apiv1.HealthStates {
+	c.lastMu.RLock()
+	lastData := c.lastData
+	c.lastMu.RUnlock()
+	return lastData.getLastHealthStates()
+}
+
+func (c *component) Events(ctx context.Context, since time.Time) (apiv1.Events, error) {
+	return nil, nil
+}
+
+func (c *component) Close() error {
+	log.Logger.Debugw("closing component")
+
+	c.cancel()
+
+	return nil
+}
+
+func (c *component) Check() components.CheckResult {
+	log.Logger.Infow("checking nvidia gpu mig")
+
+	d := &Data{
+		ts: time.Now().UTC(),
+	}
+	defer func() {
+		c.lastMu.Lock()
+		c.lastData = d
+		c.lastMu.Unlock()
+	}()
+
+	if c.nvmlInstance == nil {
+		d.health = apiv1.HealthStateTypeHealthy
+		d.reason = "NVIDIA NVML instance is nil"
+		return d
+	}
+	if !c.nvmlInstance.NVMLExists() {
+		d.health = apiv1.HealthStateTypeHealthy
+		d.reason = "NVIDIA NVML is not loaded"
+		return d
+	}
+
+	devs := c.nvmlInstance.Devices()
+	uuids := make([]string, 0, len(devs))
+	for uuid := range devs {
+		uuids = append(uuids, uuid)
+	}
+	sort.Strings(uuids)
+
+	for _, uuid := range uuids {
+		mig, err := c.getMIGFunc(uuid, devs[uuid])
+		if err != nil {
+			log.Logger.Errorw("error getting mig for device", "uuid", uuid, "error", err)
+
+			d.err = err
+			d.health = apiv1.HealthStateTypeUnhealthy
+			d.reason = fmt.Sprintf("error getting mig for device %s", uuid)
+			return d
+		}
+		d.MIGs = append(d.MIGs, mig)
+	}
+
+	expected := c.getExpectedLayoutFunc()
+
+	var mismatches []string
+	for _, mig := range d.MIGs {
+		mismatch := checkLayout(mig, expected)
+		if mismatch == "" {
+			continue
+		}
+		d.MismatchedUUIDs = append(d.MismatchedUUIDs, mig.UUID)
+		mismatches = append(mismatches, fmt.Sprintf("%s %s", mig.UUID, mismatch))
+	}
+
+	if len(mismatches) > 0 {
+		d.health = apiv1.HealthStateTypeDegraded
+		d.reason = fmt.Sprintf("%d GPU(s) do not match the expected mig layout (%s)", len(mismatches), strings.Join(mismatches, "; "))
+		return d
+	}
+
+	d.health = apiv1.HealthStateTypeHealthy
+	d.reason = fmt.Sprintf("all %d GPU(s) were checked, no mig layout issue found", len(devs))
+
+	return d
+}
+
+// checkLayout returns the description of the mismatch between the MIG layout
+// of the GPU and the expected layout, or an empty string if matched.
+func checkLayout(mig nvidianvml.MIG, expected ExpectedLayout) string {
+	if expected.Enabled != nil {
+		if *expected.Enabled && !mig.Supported {
+			return "does not support mig mode"
+		}
+		if mig.Supported && mig.Enabled != *expected.Enabled {
+			desc := fmt.Sprintf("has mig mode enabled %v (expected %v)", mig.Enabled, *expected.Enabled)
+			if mig.PendingEnabled == *expected.Enabled {
+				desc += ", pending gpu reset"
+			}
+			return desc
+		}
+	}
+
+	if len(expected.Profiles) == 0 || !mig.Enabled {
+		return ""
+	}
+
+	want := append([]string(nil), expected.Profiles...)
+	sort.Strings(want)
+	got := strings.Join(mig.Profiles(), ", ")
+	if got != strings.Join(want, ", ") {
+		return fmt.Sprintf("has mig profiles [%s] (expected [%s])", got, strings.Join(want, ", "))
+	}
+	return ""
+}
+
+var _ components.CheckResult = &Data{}
+
+type Data struct {
+	MIGs []nvidianvml.MIG `json:"migs,omitempty"`
+	// MismatchedUUIDs is the list of the GPU UUIDs
+	// whose MIG layout does not match the expected layout.
+	MismatchedUUIDs []string `json:"mismatched_uuids,omitempty"`
+
+	// timestamp of the last check
+	ts time.Time
+	// error from the last check
+	err error
+
+	// tracks the healthy evaluation result of the last check
+	health apiv1.HealthStateType
+	// tracks the reason of the last check
+	reason string
+}
+
+func (d *Data) String() string {
+	if d == nil {
+		return ""
+	}
+	if len(d.MIGs) == 0 {
+		return "no data"
+	}
+
+	buf := bytes.NewBuffer(nil)
+	table := tablewriter.NewWriter(buf)
+	table.SetAlignment(tablewriter.ALIGN_CENTER)
+	table.SetHeader([]string{"GPU UUID", "MIG Supported", "MIG Enabled", "MIG Pending", "MIG Devices"})
+	for _, mig := range d.MIGs {
+		table.Append([]string{
+			mig.UUID,
+			fmt.Sprintf("%v", mig.Supported),
+			fmt.Sprintf("%v", mig.Enabled),
+			fmt.Sprintf("%v", mig.PendingEnabled),
+			fmt.Sprintf("%d", len(mig.Instances)),
+		})
+	}
+	table.Render()
+
+	var instances [][]string
+	for _, mig := range d.MIGs {
+		for _, inst := range mig.Instances {
+			instances = append(instances, []string{
+				inst.UUID,
+				inst.ParentUUID,
+				fmt.Sprintf("%d", inst.GPUInstanceID),
+				fmt.Sprintf("%d", inst.ComputeInstanceID),
+				inst.Profile,
+				fmt.Sprintf("%d", inst.MultiprocessorCount),
+			})
+		}
+	}
+	if len(instances) > 0 {
+		buf.WriteString("\n")
+
+		instTable := tablewriter.NewWriter(buf)
+		instTable.SetAlignment(tablewriter.ALIGN_CENTER)
+		instTable.SetHeader([]string{"MIG UUID", "GPU UUID", "GPU Instance", "Compute Instance", "Profile", "SMs"})
+		instTable.AppendBulk(instances)
+		instTable.Render()
+	}
+
+	return buf.String()
+}
+
+func (d *Data) Summary() string {
+	if d == nil {
+		return ""
+	}
+	return d.reason
+}
+
+func (d *Data) HealthState() apiv1.HealthStateType {
+	if d == nil {
+		return ""
+	}
+	return d.health
+}
+
+func (d *Data) getError() string {
+	if d == nil || d.err == nil {
+		return ""
+	}
+	return d.err.Error()
+}
+
+func (d *Data) getLastHealthStates() apiv1.HealthStates {
+	if d == nil {
+		return apiv1.HealthStates{
+			{
+				Name:   Name,
+				Health: apiv1.HealthStateTypeHealthy,
+				Reason: "no data yet",
+			},
+		}
+	}
+
+	state := apiv1.HealthState{
+		Name:   Name,
+		Reason: d.reason,
+		Error:  d.getError(),
+		Health: d.health,
+	}
+
+	b, _ := json.Marshal(d)
+	state.DeprecatedExtraInfo = map[string]string{
+		"data":     string(b),
+		"encoding": "json",
+	}
+	return apiv1.HealthStates{state}
+}
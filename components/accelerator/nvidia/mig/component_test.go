@@ -0,0 +1,260 @@
+package mig
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/NVIDIA/go-nvlib/pkg/nvlib/device"
+	"github.com/NVIDIA/go-nvml/pkg/nvml/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	apiv1 "github.com/leptonai/gpud/api/v1"
+	"github.com/leptonai/gpud/components"
+	nvidianvml "github.com/leptonai/gpud/pkg/nvidia-query/nvml"
+	nvml_lib "github.com/leptonai/gpud/pkg/nvidia-query/nvml/lib"
+	"github.com/leptonai/gpud/pkg/nvidia-query/nvml/testutil"
+)
+
+// MockNvmlInstance implements the nvidianvml.InstanceV2 interface for testing
+type MockNvmlInstance struct {
+	devicesFunc func() map[string]device.Device
+}
+
+func (m *MockNvmlInstance) Devices() map[string]device.Device {
+	if m.devicesFunc != nil {
+		return m.devicesFunc()
+	}
+	return nil
+}
+
+func (m *MockNvmlInstance) GetMemoryErrorManagementCapabilities() nvidianvml.MemoryErrorManagementCapabilities {
+	return nvidianvml.MemoryErrorManagementCapabilities{}
+}
+
+func (m *MockNvmlInstance) ProductName() string {
+	return "NVIDIA Test GPU"
+}
+
+func (m *MockNvmlInstance) NVMLExists() bool {
+	return true
+}
+
+func (m *MockNvmlInstance) Library() nvml_lib.Library {
+	return nil
+}
+
+func (m *MockNvmlInstance) Shutdown() error {
+	return nil
+}
+
+// MockMIGComponent creates a component with mocked functions for testing
+func MockMIGComponent(
+	ctx context.Context,
+	migs map[string]nvidianvml.MIG,
+	expected ExpectedLayout,
+) *component {
+	cctx, cancel := context.WithCancel(ctx)
+
+	devs := make(map[string]device.Device, len(migs))
+	for uuid := range migs {
+		devs[uuid] = testutil.NewMockDevice(&mock.Device{}, "test-arch", "test-brand", "test-cuda", "test-pci")
+	}
+
+	return &component{
+		ctx:    cctx,
+		cancel: cancel,
+		nvmlInstance: &MockNvmlInstance{
+			devicesFunc: func() map[string]device.Device { return devs },
+		},
+		getMIGFunc: func(uuid string, dev device.Device) (nvidianvml.MIG, error) {
+			return migs[uuid], nil
+		},
+		getExpectedLayoutFunc: func() ExpectedLayout { return expected },
+	}
+}
+
+func boolPtr(b bool) *bool { return &b }
+
+func newMIG(uuid string, profiles ...string) nvidianvml.MIG {
+	mig := nvidianvml.MIG{
+		UUID:      uuid,
+		Enabled:   true,
+		Supported: true,
+	}
+	for i, profile := range profiles {
+		mig.Instances = append(mig.Instances, nvidianvml.MIGInstance{
+			UUID:          uuid + "-MIG-" + profile,
+			ParentUUID:    uuid,
+			GPUInstanceID: i + 1,
+			Profile:       profile,
+		})
+	}
+	return mig
+}
+
+func TestNew(t *testing.T) {
+	c, err := New(&components.GPUdInstance{
+		RootCtx:      context.Background(),
+		NVMLInstance: &MockNvmlInstance{},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, Name, c.Name())
+
+	tc := c.(*component)
+	assert.NotNil(t, tc.getMIGFunc)
+	assert.NotNil(t, tc.getExpectedLayoutFunc)
+	assert.NoError(t, c.Close())
+}
+
+func TestCheckLayout(t *testing.T) {
+	tests := []struct {
+		name     string
+		mig      nvidianvml.MIG
+		expected ExpectedLayout
+		mismatch bool
+	}{
+		{
+			name:     "no expected layout",
+			mig:      newMIG("GPU-1", "3g.40gb"),
+			expected: ExpectedLayout{},
+		},
+		{
+			name:     "expected enabled",
+			mig:      newMIG("GPU-1", "3g.40gb", "3g.40gb"),
+			expected: ExpectedLayout{Enabled: boolPtr(true), Profiles: []string{"3g.40gb", "3g.40gb"}},
+		},
+		{
+			name:     "expected profiles in any order",
+			mig:      newMIG("GPU-1", "4g.40gb", "2g.20gb", "1g.10gb"),
+			expected: ExpectedLayout{Profiles: []string{"1g.10gb", "2g.20gb", "4g.40gb"}},
+		},
+		{
+			name:     "mismatched profiles",
+			mig:      newMIG("GPU-1", "3g.40gb", "1g.10gb"),
+			expected: ExpectedLayout{Profiles: []string{"3g.40gb", "3g.40gb"}},
+			mismatch: true,
+		},
+		{
+			name:     "expected enabled but disabled",
+			mig:      nvidianvml.MIG{UUID: "GPU-1", Supported: true},
+			expected: ExpectedLayout{Enabled: boolPtr(true)},
+			mismatch: true,
+		},
+		{
+			name:     "expected disabled but enabled",
+			mig:      newMIG("GPU-1", "7g.80gb"),
+			expected: ExpectedLayout{Enabled: boolPtr(false)},
+			mismatch: true,
+		},
+		{
+			name:     "expected enabled but not supported",
+			mig:      nvidianvml.MIG{UUID: "GPU-1"},
+			expected: ExpectedLayout{Enabled: boolPtr(true)},
+			mismatch: true,
+		},
+		{
+			name:     "expected disabled and not supported",
+			mig:      nvidianvml.MIG{UUID: "GPU-1"},
+			expected: ExpectedLayout{Enabled: boolPtr(false)},
+		},
+		{
+			name:     "profiles ignored when disabled",
+			mig:      nvidianvml.MIG{UUID: "GPU-1", Supported: true},
+			expected: ExpectedLayout{Profiles: []string{"3g.40gb"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mismatch := checkLayout(tt.mig, tt.expected)
+			if tt.mismatch {
+				assert.NotEmpty(t, mismatch)
+			} else {
+				assert.Empty(t, mismatch)
+			}
+		})
+	}
+
+	// pending the GPU reset to apply the mode
+	mismatch := checkLayout(nvidianvml.MIG{UUID: "GPU-1", Supported: true, PendingEnabled: true}, ExpectedLayout{Enabled: boolPtr(true)})
+	assert.Contains(t, mismatch, "pending gpu reset")
+}
+
+func TestCheck(t *testing.T) {
+	c := MockMIGComponent(context.Background(), map[string]nvidianvml.MIG{
+		"GPU-1": newMIG("GPU-1", "3g.40gb", "3g.40gb"),
+		"GPU-2": newMIG("GPU-2", "3g.40gb", "1g.10gb"),
+	}, ExpectedLayout{Enabled: boolPtr(true), Profiles: []string{"3g.40gb", "3g.40gb"}})
+	defer c.Close()
+
+	d := c.Check().(*Data)
+	assert.Equal(t, apiv1.HealthStateTypeDegraded, d.health)
+	assert.Equal(t, []string{"GPU-2"}, d.MismatchedUUIDs)
+	assert.Contains(t, d.reason, "GPU-2 has mig profiles [1g.10gb, 3g.40gb] (expected [3g.40gb, 3g.40gb])")
+	require.Len(t, d.MIGs, 2)
+	assert.Equal(t, "GPU-1", d.MIGs[0].UUID)
+	assert.Contains(t, d.String(), "GPU-2-MIG-1g.10gb")
+
+	states := c.LastHealthStates()
+	require.Len(t, states, 1)
+	assert.Equal(t, apiv1.HealthStateTypeDegraded, states[0].Health)
+	assert.Contains(t, states[0].DeprecatedExtraInfo["data"], `"mismatched_uuids":["GPU-2"]`)
+
+	// no expected layout
+	c.getExpectedLayoutFunc = GetDefaultExpectedLayout
+	d = c.Check().(*Data)
+	assert.Equal(t, apiv1.HealthStateTypeHealthy, d.health)
+	assert.Equal(t, "all 2 GPU(s) were checked, no mig layout issue found", d.reason)
+}
+
+func TestCheck_Error(t *testing.T) {
+	c := MockMIGComponent(context.Background(), map[string]nvidianvml.MIG{
+		"GPU-1": newMIG("GPU-1"),
+	}, ExpectedLayout{})
+	defer c.Close()
+
+	c.getMIGFunc = func(uuid string, dev device.Device) (nvidianvml.MIG, error) {
+		return nvidianvml.MIG{}, errors.New("mig error")
+	}
+
+	d := c.Check().(*Data)
+	assert.Equal(t, apiv1.HealthStateTypeUnhealthy, d.health)
+	assert.Equal(t, "error getting mig for device GPU-1", d.reason)
+	assert.Equal(t, "mig error", d.getError())
+}
+
+func TestCheck_NilNVML(t *testing.T) {
+	c := MockMIGComponent(context.Background(), nil, ExpectedLayout{})
+	defer c.Close()
+	c.nvmlInstance = nil
+
+	d := c.Check().(*Data)
+	assert.Equal(t, apiv1.HealthStateTypeHealthy, d.health)
+	assert.Equal(t, "NVIDIA NVML instance is nil", d.reason)
+	assert.Equal(t, "no data", d.String())
+}
+
+func TestLastHealthStates_NoData(t *testing.T) {
+	c := MockMIGComponent(context.Background(), nil, ExpectedLayout{})
+	defer c.Close()
+
+	states := c.LastHealthStates()
+	require.Len(t, states, 1)
+	assert.Equal(t, apiv1.HealthStateTypeHealthy, states[0].Health)
+	assert.Equal(t, "no data yet", states[0].Reason)
+}
+
+func TestDefaultExpectedLayout(t *testing.T) {
+	orig := GetDefaultExpectedLayout()
+	defer SetDefaultExpectedLayout(orig)
+
+	assert.Nil(t, orig.Enabled)
+	assert.Empty(t, orig.Profiles)
+
+	SetDefaultExpectedLayout(ExpectedLayout{Enabled: boolPtr(true), Profiles: []string{"7g.80gb"}})
+	layout := GetDefaultExpectedLayout()
+	require.NotNil(t, layout.Enabled)
+	assert.True(t, *layout.Enabled)
+	assert.Equal(t, []string{"7g.80gb"}, layout.Profiles)
+}
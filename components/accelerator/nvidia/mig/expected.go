@@ -0,0 +1,38 @@
+package mig
+
+import (
+	"sync"
+
+	"github.com/leptonai/gpud/pkg/log"
+)
+
+// ExpectedLayout defines the expected MIG layout of every GPU.
+// The zero value skips the layout check.
+type ExpectedLayout struct {
+	// Enabled is the expected MIG mode of every GPU.
+	// Nil to skip the MIG mode check.
+	Enabled *bool `json:"enabled,omitempty"`
+	// Profiles is the expected MIG profiles of every MIG-enabled GPU
+	// in the nvidia-smi format (e.g., ["3g.40gb", "3g.40gb"]), regardless of the order.
+	// Empty to skip the profile check.
+	Profiles []string `json:"profiles,omitempty"`
+}
+
+var (
+	defaultExpectedLayoutMu sync.RWMutex
+	defaultExpectedLayout   = ExpectedLayout{}
+)
+
+func GetDefaultExpectedLayout() ExpectedLayout {
+	defaultExpectedLayoutMu.RLock()
+	defer defaultExpectedLayoutMu.RUnlock()
+	return defaultExpectedLayout
+}
+
+func SetDefaultExpectedLayout(layout ExpectedLayout) {
+	log.Logger.Infow("setting default expected mig layout", "enabled", layout.Enabled, "profiles", layout.Profiles)
+
+	defaultExpectedLayoutMu.Lock()
+	defer defaultExpectedLayoutMu.Unlock()
+	defaultExpectedLayout = layout
+}
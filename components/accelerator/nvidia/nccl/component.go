@@ -1,26 +1,35 @@
-// Package nccl monitors the NCCL status.
+// Package nccl monitors the NCCL status, and runs the NCCL all-reduce bandwidth
+// self-test on demand or on schedule when the GPUs are idle.
 // Optional, enabled if the host has NVIDIA GPUs.
 package nccl
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"runtime"
 	"sync"
 	"time"
 
+	"github.com/NVIDIA/go-nvlib/pkg/nvlib/device"
+
 	apiv1 "github.com/leptonai/gpud/api/v1"
 	"github.com/leptonai/gpud/components"
 	"github.com/leptonai/gpud/pkg/eventstore"
+	pkgfile "github.com/leptonai/gpud/pkg/file"
 	"github.com/leptonai/gpud/pkg/kmsg"
 	"github.com/leptonai/gpud/pkg/log"
 	nvidianvml "github.com/leptonai/gpud/pkg/nvidia-query/nvml"
+	"github.com/leptonai/gpud/pkg/process"
 )
 
 const Name = "accelerator-nvidia-nccl"
 
-var _ components.Component = &component{}
+var (
+	_ components.Component      = &component{}
+	_ components.HealthSettable = &component{}
+)
 
 type component struct {
 	ctx    context.Context
@@ -33,6 +42,16 @@ type component struct {
 
 	readAllKmsg func(context.Context) ([]kmsg.Message, error)
 
+	getProcessesFunc      func(uuid string, dev device.Device) (nvidianvml.Processes, error)
+	getSelfTestConfigFunc func() SelfTestConfig
+	locateExecutableFunc  func(string) (string, error)
+	selfTestRunner        process.Runner
+
+	selfTestMu      sync.Mutex
+	selfTestCancel  context.CancelFunc
+	lastSelfTestRun time.Time
+	lastSelfTest    *SelfTestResult
+
 	lastMu   sync.RWMutex
 	lastData *Data
 }
@@ -40,9 +59,13 @@ type component struct {
 func New(gpudInstance *components.GPUdInstance) (components.Component, error) {
 	cctx, ccancel := context.WithCancel(gpudInstance.RootCtx)
 	c := &component{
-		ctx:          cctx,
-		cancel:       ccancel,
-		nvmlInstance: gpudInstance.NVMLInstance,
+		ctx:                   cctx,
+		cancel:                ccancel,
+		nvmlInstance:          gpudInstance.NVMLInstance,
+		getProcessesFunc:      nvidianvml.GetProcesses,
+		getSelfTestConfigFunc: GetDefaultSelfTestConfig,
+		locateExecutableFunc:  pkgfile.LocateExecutable,
+		selfTestRunner:        process.NewExclusiveRunner(),
 	}
 
 	if gpudInstance.EventStore != nil && runtime.GOOS == "linux" {
@@ -53,8 +76,8 @@ func New(gpudInstance *components.GPUdInstance) (components.Component, error) {
 			return nil, err
 		}
 
-		if os.Geteuid() == 0 {
-			c.kmsgSyncer, err = kmsg.NewSyncer(cctx, Match, c.eventBucket)
+		if gpudInstance.KmsgHub != nil {
+			c.kmsgSyncer, err = gpudInstance.KmsgHub.NewSyncer(cctx, Name, MatchEvent, c.eventBucket)
 			if err != nil {
 				ccancel()
 				return nil, err
@@ -72,20 +95,69 @@ func New(gpudInstance *components.GPUdInstance) (components.Component, error) {
 func (c *component) Name() string { return Name }
 
 func (c *component) Start() error {
-	// do not need periodic kmsg checks since it already has a watcher
+	// do not need periodic kmsg checks since it already has a watcher,
+	// only checks whether the scheduled self-test is due
+	if c.selfTestRunner == nil {
+		return nil
+	}
+
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-c.ctx.Done():
+				return
+			case <-ticker.C:
+				c.runIdleSelfTest()
+			}
+		}
+	}()
 	return nil
 }
 
 func (c *component) LastHealthStates() apiv1.HealthStates {
+	c.selfTestMu.Lock()
+	lastSelfTest := c.lastSelfTest
+	c.selfTestMu.Unlock()
+
+	if lastSelfTest == nil {
+		return apiv1.HealthStates{
+			{
+				Component: Name,
+				Health:    apiv1.HealthStateTypeHealthy,
+				Reason:    "no issue",
+			},
+		}
+	}
+
+	b, _ := json.Marshal(lastSelfTest)
 	return apiv1.HealthStates{
 		{
 			Component: Name,
-			Health:    apiv1.HealthStateTypeHealthy,
-			Reason:    "no issue",
+			Health:    lastSelfTest.Health,
+			Reason:    lastSelfTest.Reason,
+			Error:     lastSelfTest.Error,
+			DeprecatedExtraInfo: map[string]string{
+				"data":     string(b),
+				"encoding": "json",
+			},
 		},
 	}
 }
 
+// SetHealthy clears the result of the last self-test.
+func (c *component) SetHealthy() error {
+	log.Logger.Infow("set healthy event received")
+
+	c.selfTestMu.Lock()
+	c.lastSelfTest = nil
+	c.selfTestMu.Unlock()
+
+	return nil
+}
+
 func (c *component) Events(ctx context.Context, since time.Time) (apiv1.Events, error) {
 	if c.eventBucket == nil {
 		return nil, nil
@@ -96,6 +168,10 @@ func (c *component) Events(ctx context.Context, since time.Time) (apiv1.Events,
 func (c *component) Close() error {
 	log.Logger.Debugw("closing component")
 
+	if c.cancel != nil {
+		c.cancel()
+	}
+
 	if c.kmsgSyncer != nil {
 		c.kmsgSyncer.Close()
 	}
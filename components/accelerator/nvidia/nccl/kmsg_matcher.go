@@ -2,6 +2,9 @@ package nccl
 
 import (
 	"regexp"
+
+	apiv1 "github.com/leptonai/gpud/api/v1"
+	"github.com/leptonai/gpud/pkg/kmsg"
 )
 
 const (
@@ -25,12 +28,20 @@ func HasNCCLSegfaultInLibnccl(line string) bool {
 }
 
 func Match(line string) (eventName string, message string) {
+	ev := MatchEvent(line)
+	if ev == nil {
+		return "", ""
+	}
+	return ev.Name, ev.Message
+}
+
+func MatchEvent(line string) *kmsg.MatchedEvent {
 	for _, m := range getMatches() {
 		if m.check(line) {
-			return m.eventName, m.message
+			return kmsg.NewMatchedEvent(m.eventName, m.message, m.eventType, m.repairActions)
 		}
 	}
-	return "", ""
+	return nil
 }
 
 type match struct {
@@ -38,10 +49,13 @@ type match struct {
 	eventName string
 	regex     string
 	message   string
+
+	eventType     apiv1.EventType
+	repairActions []apiv1.RepairActionType
 }
 
 func getMatches() []match {
 	return []match{
-		{check: HasNCCLSegfaultInLibnccl, eventName: eventNCCLSegfaultInLibnccl, regex: regexNCCLSegfaultInLibnccl, message: messageNCCLSegfaultInLibnccl},
+		{check: HasNCCLSegfaultInLibnccl, eventName: eventNCCLSegfaultInLibnccl, regex: regexNCCLSegfaultInLibnccl, message: messageNCCLSegfaultInLibnccl, eventType: apiv1.EventTypeWarning, repairActions: []apiv1.RepairActionType{apiv1.RepairActionTypeCheckUserAppAndGPU}},
 	}
 }
@@ -1,6 +1,10 @@
 package nccl
 
-import "testing"
+import (
+	"testing"
+
+	apiv1 "github.com/leptonai/gpud/api/v1"
+)
 
 func TestHasNCCLSegfaultInLibnccl(t *testing.T) {
 	tests := []struct {
@@ -144,3 +148,23 @@ func TestGetMatches(t *testing.T) {
 		t.Error("check function matched invalid input")
 	}
 }
+
+func TestMatchEvent(t *testing.T) {
+	ev := MatchEvent("segfault at 7f797fe00000 ip 00007f7c7ac69996 sp 00007f7c12fd7c30 error 4 in libnccl.so")
+	if ev == nil {
+		t.Fatal("MatchEvent() = nil, want event")
+	}
+	if ev.Name != eventNCCLSegfaultInLibnccl || ev.Message != messageNCCLSegfaultInLibnccl {
+		t.Errorf("MatchEvent() = %v %v, want %v %v", ev.Name, ev.Message, eventNCCLSegfaultInLibnccl, messageNCCLSegfaultInLibnccl)
+	}
+	if ev.Type != apiv1.EventTypeWarning {
+		t.Errorf("MatchEvent() type = %v, want %v", ev.Type, apiv1.EventTypeWarning)
+	}
+	if ev.SuggestedActions == nil || len(ev.SuggestedActions.RepairActions) != 1 || ev.SuggestedActions.RepairActions[0] != apiv1.RepairActionTypeCheckUserAppAndGPU {
+		t.Errorf("MatchEvent() suggested actions = %+v, want %v", ev.SuggestedActions, apiv1.RepairActionTypeCheckUserAppAndGPU)
+	}
+
+	if ev := MatchEvent("no match"); ev != nil {
+		t.Errorf("MatchEvent() = %+v, want nil", ev)
+	}
+}
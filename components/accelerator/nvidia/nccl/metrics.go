@@ -0,0 +1,42 @@
+package nccl
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	pkgmetrics "github.com/leptonai/gpud/pkg/metrics"
+)
+
+const SubSystem = "accelerator_nvidia_nccl"
+
+var (
+	componentLabel = prometheus.Labels{
+		pkgmetrics.MetricComponentLabelKey: Name,
+	}
+
+	metricAllReduceBusBandwidthGBps = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "",
+			Subsystem: SubSystem,
+			Name:      "all_reduce_bus_bandwidth_gbps",
+			Help:      "tracks the all-reduce bus bandwidth in GB/s of the last self-test",
+		},
+		[]string{pkgmetrics.MetricComponentLabelKey, pkgmetrics.MetricLabelKey}, // label is the message size in bytes
+	).MustCurryWith(componentLabel)
+
+	metricAllReducePeakBusBandwidthGBps = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "",
+			Subsystem: SubSystem,
+			Name:      "all_reduce_peak_bus_bandwidth_gbps",
+			Help:      "tracks the highest all-reduce bus bandwidth in GB/s across all message sizes of the last self-test",
+		},
+		[]string{pkgmetrics.MetricComponentLabelKey},
+	).MustCurryWith(componentLabel)
+)
+
+func init() {
+	pkgmetrics.MustRegister(
+		metricAllReduceBusBandwidthGBps,
+		metricAllReducePeakBusBandwidthGBps,
+	)
+}
@@ -0,0 +1,371 @@
+package nccl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	apiv1 "github.com/leptonai/gpud/api/v1"
+	"github.com/leptonai/gpud/pkg/log"
+	pkgmetrics "github.com/leptonai/gpud/pkg/metrics"
+	"github.com/leptonai/gpud/pkg/nvidia-query/nccl"
+	"github.com/leptonai/gpud/pkg/process"
+)
+
+// SelfTestConfig configures the NCCL all-reduce bandwidth self-test.
+type SelfTestConfig struct {
+	// Command is the "all_reduce_perf" binary (nccl-tests),
+	// or an equivalent command with the same output format.
+	Command string `json:"command"`
+	// Args is the command arguments.
+	// If empty, sweeps the message sizes from 1 MiB to 8 GiB on all the local GPUs.
+	Args string `json:"args"`
+
+	// MinBusBandwidthGBps is the expected minimum peak bus bandwidth in GB/s,
+	// applied regardless of the number of GPUs.
+	// If zero, the per-product floor is used (if any).
+	MinBusBandwidthGBps float64 `json:"min_bus_bandwidth_gbps"`
+	// BusBandwidthFloors is the per-product expected minimum peak bus bandwidth,
+	// keyed by the lower-cased product name (e.g., "h100 80gb hbm3"),
+	// which override or extend the built-in floors.
+	// The floor is skipped if measured on a different number of GPUs.
+	BusBandwidthFloors map[string]nccl.BusBandwidthFloor `json:"bus_bandwidth_floors,omitempty"`
+
+	// Timeout is the timeout for a self-test run.
+	Timeout metav1.Duration `json:"timeout"`
+
+	// IdleInterval is the interval to run the self-test on schedule,
+	// only when no process is running on the GPUs.
+	// Zero disables the scheduled runs.
+	IdleInterval metav1.Duration `json:"idle_interval"`
+}
+
+// DefaultSelfTestTimeout is the timeout for a self-test run,
+// if not configured.
+const DefaultSelfTestTimeout = 10 * time.Minute
+
+var (
+	defaultSelfTestConfigMu sync.RWMutex
+	defaultSelfTestConfig   = SelfTestConfig{
+		Command: nccl.DefaultAllReducePerfCommand,
+		Timeout: metav1.Duration{Duration: DefaultSelfTestTimeout},
+	}
+)
+
+func GetDefaultSelfTestConfig() SelfTestConfig {
+	defaultSelfTestConfigMu.RLock()
+	defer defaultSelfTestConfigMu.RUnlock()
+	return defaultSelfTestConfig
+}
+
+func SetDefaultSelfTestConfig(cfg SelfTestConfig) {
+	log.Logger.Infow("setting default nccl self-test config", "command", cfg.Command, "args", cfg.Args, "minBusBandwidthGBps", cfg.MinBusBandwidthGBps, "idleInterval", cfg.IdleInterval.Duration)
+
+	defaultSelfTestConfigMu.Lock()
+	defer defaultSelfTestConfigMu.Unlock()
+	defaultSelfTestConfig = cfg
+}
+
+const (
+	SelfTestTriggerAPI  = "api"
+	SelfTestTriggerIdle = "idle"
+
+	EventNameSelfTest = "nccl_all_reduce_self_test"
+
+	EventKeySelfTestTrigger      = "trigger"
+	EventKeySelfTestPeakBusBW    = "peak_bus_bandwidth_gbps"
+	EventKeySelfTestMinBusBW     = "min_bus_bandwidth_gbps"
+	EventKeySelfTestWrongResults = "wrong"
+)
+
+var (
+	ErrSelfTestAlreadyRunning = process.ErrProcessAlreadyRunning
+	ErrAllReducePerfNotFound  = errors.New("all_reduce_perf not found")
+	ErrGPUProcessesRunning    = errors.New("gpu processes are running, use force to run the self-test anyway")
+	ErrNoGPU                  = errors.New("no gpu found")
+)
+
+// SelfTestRequest is the request to run the NCCL all-reduce self-test.
+type SelfTestRequest struct {
+	// Force runs the self-test even if the GPU processes are running.
+	Force bool `json:"force,omitempty"`
+}
+
+// SelfTestResult is the result of the NCCL all-reduce self-test.
+type SelfTestResult struct {
+	Time        time.Time `json:"time"`
+	Trigger     string    `json:"trigger"`
+	ProductName string    `json:"product_name,omitempty"`
+	// NumGPUs is the number of GPUs that the self-test runs on.
+	NumGPUs int `json:"num_gpus"`
+
+	Result *nccl.AllReducePerfResult `json:"result,omitempty"`
+
+	// PeakBusBandwidthGBps is the highest bus bandwidth across all message sizes.
+	PeakBusBandwidthGBps float64 `json:"peak_bus_bandwidth_gbps"`
+	// MinBusBandwidthGBps is the expected minimum peak bus bandwidth,
+	// zero if unknown for the product (or for the number of GPUs).
+	MinBusBandwidthGBps float64 `json:"min_bus_bandwidth_gbps,omitempty"`
+	// noMinBusBandwidthReason is why the peak bus bandwidth is not compared.
+	noMinBusBandwidthReason string
+
+	// Health is the health state evaluated from the result.
+	Health apiv1.HealthStateType `json:"health"`
+	// Reason is the reason of the health state.
+	Reason string `json:"reason"`
+	// Error is the error of the failed run (e.g., NCCL failure), if any.
+	Error string `json:"error,omitempty"`
+}
+
+// SelfTester runs the NCCL all-reduce self-test on demand.
+type SelfTester interface {
+	// RunSelfTest runs the self-test on all the local GPUs and blocks until completion.
+	// Only one self-test runs at a time.
+	RunSelfTest(ctx context.Context, req SelfTestRequest) (*SelfTestResult, error)
+	// CancelSelfTest cancels the running self-test, and returns false
+	// if no self-test is running.
+	CancelSelfTest() bool
+}
+
+var _ SelfTester = &component{}
+
+func (c *component) RunSelfTest(ctx context.Context, req SelfTestRequest) (*SelfTestResult, error) {
+	return c.runSelfTest(ctx, SelfTestTriggerAPI, req.Force)
+}
+
+func (c *component) CancelSelfTest() bool {
+	c.selfTestMu.Lock()
+	defer c.selfTestMu.Unlock()
+
+	if c.selfTestCancel == nil {
+		return false
+	}
+	log.Logger.Infow("canceling nccl self-test")
+	c.selfTestCancel()
+	return true
+}
+
+// checkGPUsIdle returns the number of GPUs, or an error if
+// any process is running on the GPUs (unless forced).
+func (c *component) checkGPUsIdle(force bool) (int, error) {
+	if c.nvmlInstance == nil || !c.nvmlInstance.NVMLExists() {
+		return 0, ErrNoGPU
+	}
+	devs := c.nvmlInstance.Devices()
+	if len(devs) == 0 {
+		return 0, ErrNoGPU
+	}
+	if force {
+		return len(devs), nil
+	}
+
+	uuids := make([]string, 0, len(devs))
+	for uuid := range devs {
+		uuids = append(uuids, uuid)
+	}
+	sort.Strings(uuids)
+
+	var busy []string
+	for _, uuid := range uuids {
+		procs, err := c.getProcessesFunc(uuid, devs[uuid])
+		if err != nil {
+			return 0, fmt.Errorf("error getting processes for device %s: %w", uuid, err)
+		}
+		if len(procs.RunningProcesses) > 0 {
+			busy = append(busy, fmt.Sprintf("%s (%d process(es))", uuid, len(procs.RunningProcesses)))
+		}
+	}
+	if len(busy) > 0 {
+		return 0, fmt.Errorf("%w: %s", ErrGPUProcessesRunning, strings.Join(busy, ", "))
+	}
+	return len(devs), nil
+}
+
+func (c *component) runSelfTest(ctx context.Context, trigger string, force bool) (*SelfTestResult, error) {
+	c.selfTestMu.Lock()
+	running := c.selfTestCancel != nil
+	c.selfTestMu.Unlock()
+	if running {
+		return nil, ErrSelfTestAlreadyRunning
+	}
+
+	numGPUs, err := c.checkGPUsIdle(force)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := c.getSelfTestConfigFunc()
+	command := cfg.Command
+	if command == "" {
+		command = nccl.DefaultAllReducePerfCommand
+	}
+	// not a self-test failure, the health state is not changed
+	commandPath, err := c.locateExecutableFunc(command)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrAllReducePerfNotFound, err)
+	}
+	args := cfg.Args
+	if args == "" {
+		args = nccl.AllReducePerfArgs(numGPUs)
+	}
+	timeout := cfg.Timeout.Duration
+	if timeout == 0 {
+		timeout = DefaultSelfTestTimeout
+	}
+
+	cctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	c.selfTestMu.Lock()
+	if c.selfTestCancel != nil {
+		c.selfTestMu.Unlock()
+		return nil, ErrSelfTestAlreadyRunning
+	}
+	c.selfTestCancel = cancel
+	c.lastSelfTestRun = time.Now().UTC()
+	c.selfTestMu.Unlock()
+	defer func() {
+		c.selfTestMu.Lock()
+		c.selfTestCancel = nil
+		c.selfTestMu.Unlock()
+	}()
+
+	log.Logger.Infow("running nccl self-test", "trigger", trigger, "command", commandPath, "args", args, "timeout", timeout)
+
+	// the failed run exits non-zero, and its output (with the NCCL error) is evaluated
+	output, exitCode, err := c.selfTestRunner.RunUntilCompletion(cctx, fmt.Sprintf("%s %s 2>&1", commandPath, args))
+	if err != nil {
+		var exitErr *exec.ExitError
+		if cctx.Err() != nil || !errors.As(err, &exitErr) {
+			log.Logger.Warnw("nccl self-test aborted", "trigger", trigger, "error", err)
+			return nil, err
+		}
+		log.Logger.Infow("nccl self-test exited with non-zero status", "trigger", trigger, "exitCode", exitCode)
+	}
+
+	result := &SelfTestResult{
+		Time:        time.Now().UTC(),
+		Trigger:     trigger,
+		ProductName: c.nvmlInstance.ProductName(),
+		NumGPUs:     numGPUs,
+	}
+	result.MinBusBandwidthGBps = cfg.MinBusBandwidthGBps
+	if result.MinBusBandwidthGBps == 0 {
+		result.MinBusBandwidthGBps, err = nccl.ExpectedAllReduceBusBandwidth(cfg.BusBandwidthFloors, result.ProductName, numGPUs)
+		switch {
+		case errors.Is(err, nccl.ErrBusBandwidthNumGPUs):
+			result.noMinBusBandwidthReason = err.Error()
+		case err != nil:
+			result.noMinBusBandwidthReason = fmt.Sprintf("no expected bandwidth for %q", result.ProductName)
+		}
+	}
+
+	parsed, err := nccl.ParseAllReducePerfOutput(output)
+	if err != nil {
+		result.Error = err.Error()
+	}
+	result.Result = parsed
+	result.PeakBusBandwidthGBps = parsed.PeakBusBandwidthGBps()
+	result.evaluate()
+
+	log.Logger.Infow("nccl self-test completed", "trigger", trigger, "health", result.Health, "reason", result.Reason)
+
+	c.selfTestMu.Lock()
+	c.lastSelfTest = result
+	c.selfTestMu.Unlock()
+
+	if parsed != nil {
+		for _, row := range parsed.Rows {
+			metricAllReduceBusBandwidthGBps.With(prometheus.Labels{pkgmetrics.MetricLabelKey: fmt.Sprintf("%d", row.SizeBytes)}).Set(row.BusBandwidthGBps())
+		}
+		metricAllReducePeakBusBandwidthGBps.With(prometheus.Labels{}).Set(result.PeakBusBandwidthGBps)
+	}
+
+	if c.eventBucket != nil {
+		if err := c.eventBucket.Insert(c.ctx, result.event()); err != nil {
+			log.Logger.Errorw("failed to insert nccl self-test event", "error", err)
+		}
+	}
+
+	return result, nil
+}
+
+// runIdleSelfTest runs the self-test if the scheduled run is due,
+// and no process is running on the GPUs.
+func (c *component) runIdleSelfTest() {
+	interval := c.getSelfTestConfigFunc().IdleInterval.Duration
+	if interval <= 0 {
+		return
+	}
+
+	c.selfTestMu.Lock()
+	due := time.Since(c.lastSelfTestRun) >= interval
+	c.selfTestMu.Unlock()
+	if !due {
+		return
+	}
+
+	if _, err := c.runSelfTest(c.ctx, SelfTestTriggerIdle, false); err != nil {
+		log.Logger.Debugw("skipped scheduled nccl self-test", "error", err)
+	}
+}
+
+func (r *SelfTestResult) evaluate() {
+	switch {
+	case r.Error != "":
+		r.Health = apiv1.HealthStateTypeUnhealthy
+		r.Reason = "nccl all-reduce self-test failed"
+
+	case r.Result.Wrong() > 0:
+		r.Health = apiv1.HealthStateTypeUnhealthy
+		r.Reason = fmt.Sprintf("nccl all-reduce self-test returned %d wrong result(s)", r.Result.Wrong())
+
+	case r.MinBusBandwidthGBps > 0 && r.PeakBusBandwidthGBps < r.MinBusBandwidthGBps:
+		r.Health = apiv1.HealthStateTypeDegraded
+		r.Reason = fmt.Sprintf("nccl all-reduce peak bus bandwidth %.2f GB/s is below the expected %.2f GB/s", r.PeakBusBandwidthGBps, r.MinBusBandwidthGBps)
+
+	case r.MinBusBandwidthGBps > 0:
+		r.Health = apiv1.HealthStateTypeHealthy
+		r.Reason = fmt.Sprintf("nccl all-reduce peak bus bandwidth %.2f GB/s (expected at least %.2f GB/s)", r.PeakBusBandwidthGBps, r.MinBusBandwidthGBps)
+
+	default:
+		r.Health = apiv1.HealthStateTypeHealthy
+		r.Reason = fmt.Sprintf("nccl all-reduce peak bus bandwidth %.2f GB/s (%s)", r.PeakBusBandwidthGBps, r.noMinBusBandwidthReason)
+	}
+}
+
+func (r *SelfTestResult) event() apiv1.Event {
+	eventType := apiv1.EventTypeInfo
+	switch r.Health {
+	case apiv1.HealthStateTypeUnhealthy:
+		eventType = apiv1.EventTypeCritical
+	case apiv1.HealthStateTypeDegraded:
+		eventType = apiv1.EventTypeWarning
+	}
+
+	msg := r.Reason
+	if r.Error != "" {
+		msg += ": " + r.Error
+	}
+
+	return apiv1.Event{
+		Time:    metav1.Time{Time: r.Time},
+		Name:    EventNameSelfTest,
+		Type:    eventType,
+		Message: msg,
+		DeprecatedExtraInfo: map[string]string{
+			EventKeySelfTestTrigger:      r.Trigger,
+			EventKeySelfTestPeakBusBW:    fmt.Sprintf("%.2f", r.PeakBusBandwidthGBps),
+			EventKeySelfTestMinBusBW:     fmt.Sprintf("%.2f", r.MinBusBandwidthGBps),
+			EventKeySelfTestWrongResults: fmt.Sprintf("%d", r.Result.Wrong()),
+		},
+	}
+}
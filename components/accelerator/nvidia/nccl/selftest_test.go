@@ -0,0 +1,347 @@
+package nccl
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/NVIDIA/go-nvlib/pkg/nvlib/device"
+	nvmlmock "github.com/NVIDIA/go-nvml/pkg/nvml/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	apiv1 "github.com/leptonai/gpud/api/v1"
+	"github.com/leptonai/gpud/pkg/nvidia-query/nccl"
+	nvidianvml "github.com/leptonai/gpud/pkg/nvidia-query/nvml"
+	"github.com/leptonai/gpud/pkg/nvidia-query/nvml/testutil"
+	"github.com/leptonai/gpud/pkg/process"
+)
+
+const testAllReducePerfOutput = `# nThread 1 nGpus 2 minBytes 1048576 maxBytes 2097152 step: 2(factor) warmup iters: 5 iters: 20 agg iters: 1 validation: 1 graph: 0
+#  Rank  0 Group  0 Pid 182734 on   node-1 device  0 [0x18] NVIDIA H100 80GB HBM3
+#  Rank  1 Group  0 Pid 182734 on   node-1 device  1 [0x2a] NVIDIA H100 80GB HBM3
+#       size         count      type   redop    root     time   algbw   busbw #wrong     time   algbw   busbw #wrong
+#        (B)    (elements)                               (us)  (GB/s)  (GB/s)            (us)  (GB/s)  (GB/s)
+     1048576        262144     float     sum      -1    38.50   27.24   47.67      0    37.81   27.73   48.53      0
+     2097152        524288     float     sum      -1    44.12   47.53   83.18      0    43.65   48.05   84.08      0
+# Out of bounds values : 0 OK
+# Avg bus bandwidth    : 65.865
+`
+
+// mockRunner implements the process.Runner interface for testing
+type mockRunner struct {
+	runFunc func(ctx context.Context, script string) ([]byte, int32, error)
+}
+
+func (r *mockRunner) RunUntilCompletion(ctx context.Context, script string) ([]byte, int32, error) {
+	return r.runFunc(ctx, script)
+}
+
+// mockSelfTestComponent creates a component with two idle H100 GPUs,
+// where the runner returns the output.
+func mockSelfTestComponent(t *testing.T, output string, cfg SelfTestConfig) (*component, *MockEventBucket) {
+	devs := map[string]device.Device{
+		"GPU-0": testutil.NewMockDevice(&nvmlmock.Device{}, "hopper", "Nvidia", "9.0", "0000:18:00.0"),
+		"GPU-1": testutil.NewMockDevice(&nvmlmock.Device{}, "hopper", "Nvidia", "9.0", "0000:2a:00.0"),
+	}
+	nvmlInstance := new(mockNvmlInstance)
+	nvmlInstance.On("NVMLExists").Return(true)
+	nvmlInstance.On("Devices").Return(devs)
+	nvmlInstance.On("ProductName").Return("NVIDIA H100 80GB HBM3")
+
+	bucket := new(MockEventBucket)
+	bucket.On("Insert", mock.Anything, mock.Anything).Return(nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	return &component{
+		ctx:          ctx,
+		cancel:       cancel,
+		nvmlInstance: nvmlInstance,
+		eventBucket:  bucket,
+		getProcessesFunc: func(uuid string, dev device.Device) (nvidianvml.Processes, error) {
+			return nvidianvml.Processes{UUID: uuid}, nil
+		},
+		getSelfTestConfigFunc: func() SelfTestConfig { return cfg },
+		locateExecutableFunc: func(bin string) (string, error) {
+			return bin, nil
+		},
+		selfTestRunner: &mockRunner{
+			runFunc: func(ctx context.Context, script string) ([]byte, int32, error) {
+				return []byte(output), 0, nil
+			},
+		},
+	}, bucket
+}
+
+func TestRunSelfTest(t *testing.T) {
+	c, bucket := mockSelfTestComponent(t, testAllReducePerfOutput, SelfTestConfig{Command: "/opt/nccl-tests/all_reduce_perf"})
+
+	var script string
+	c.selfTestRunner = &mockRunner{
+		runFunc: func(ctx context.Context, s string) ([]byte, int32, error) {
+			script = s
+			return []byte(testAllReducePerfOutput), 0, nil
+		},
+	}
+
+	result, err := c.RunSelfTest(context.Background(), SelfTestRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, "/opt/nccl-tests/all_reduce_perf -b 1M -e 8G -f 2 -g 2 2>&1", script)
+	assert.Equal(t, SelfTestTriggerAPI, result.Trigger)
+	assert.Equal(t, 84.08, result.PeakBusBandwidthGBps)
+	assert.Equal(t, 2, result.NumGPUs)
+	require.Len(t, result.Result.Rows, 2)
+
+	// the 8-GPU floor of the product does not apply to 2 GPUs
+	assert.Equal(t, 0.0, result.MinBusBandwidthGBps)
+	assert.Equal(t, apiv1.HealthStateTypeHealthy, result.Health)
+	assert.Equal(t, "nccl all-reduce peak bus bandwidth 84.08 GB/s (expected all-reduce bus bandwidth is for a different number of gpus (expected 8 gpu(s), running on 2 gpu(s)))", result.Reason)
+
+	// the configured 2-GPU floor of the product
+	c.getSelfTestConfigFunc = func() SelfTestConfig {
+		return SelfTestConfig{BusBandwidthFloors: map[string]nccl.BusBandwidthFloor{
+			"h100 80gb hbm3": {GBps: 370, NumGPUs: 2},
+		}}
+	}
+	result, err = c.RunSelfTest(context.Background(), SelfTestRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, 370.0, result.MinBusBandwidthGBps)
+	assert.Equal(t, apiv1.HealthStateTypeDegraded, result.Health)
+	assert.Equal(t, "nccl all-reduce peak bus bandwidth 84.08 GB/s is below the expected 370.00 GB/s", result.Reason)
+
+	states := c.LastHealthStates()
+	require.Len(t, states, 1)
+	assert.Equal(t, apiv1.HealthStateTypeDegraded, states[0].Health)
+	assert.Contains(t, states[0].DeprecatedExtraInfo["data"], `"peak_bus_bandwidth_gbps":84.08`)
+
+	bucket.AssertCalled(t, "Insert", mock.Anything, mock.MatchedBy(func(ev apiv1.Event) bool {
+		return ev.Name == EventNameSelfTest &&
+			ev.Type == apiv1.EventTypeWarning &&
+			ev.DeprecatedExtraInfo[EventKeySelfTestPeakBusBW] == "84.08" &&
+			ev.DeprecatedExtraInfo[EventKeySelfTestTrigger] == SelfTestTriggerAPI
+	}))
+
+	// the configured floor overrides the per-product default
+	c.getSelfTestConfigFunc = func() SelfTestConfig { return SelfTestConfig{MinBusBandwidthGBps: 80} }
+	result, err = c.RunSelfTest(context.Background(), SelfTestRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, apiv1.HealthStateTypeHealthy, result.Health)
+	assert.Equal(t, "nccl all-reduce peak bus bandwidth 84.08 GB/s (expected at least 80.00 GB/s)", result.Reason)
+}
+
+func TestRunSelfTest_Failure(t *testing.T) {
+	c, bucket := mockSelfTestComponent(t, "node-1: Test NCCL failure common.cu:954 'unhandled cuda error'\n", SelfTestConfig{})
+
+	result, err := c.RunSelfTest(context.Background(), SelfTestRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, apiv1.HealthStateTypeUnhealthy, result.Health)
+	assert.Equal(t, "nccl all-reduce self-test failed", result.Reason)
+	assert.Contains(t, result.Error, "Test NCCL failure")
+	assert.Nil(t, result.Result)
+
+	states := c.LastHealthStates()
+	assert.Equal(t, apiv1.HealthStateTypeUnhealthy, states[0].Health)
+	assert.Contains(t, states[0].Error, "Test NCCL failure")
+
+	bucket.AssertCalled(t, "Insert", mock.Anything, mock.MatchedBy(func(ev apiv1.Event) bool {
+		return ev.Type == apiv1.EventTypeCritical
+	}))
+
+	c.selfTestRunner = &mockRunner{
+		runFunc: func(ctx context.Context, script string) ([]byte, int32, error) {
+			return []byte(`#       size         count      type   redop    root     time   algbw   busbw #wrong     time   algbw   busbw #wrong
+     1048576        262144     float     sum      -1    38.50   27.24   47.67      2    37.81   27.73   48.53      0
+`), 0, nil
+		},
+	}
+	result, err = c.RunSelfTest(context.Background(), SelfTestRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, apiv1.HealthStateTypeUnhealthy, result.Health)
+	assert.Equal(t, "nccl all-reduce self-test returned 2 wrong result(s)", result.Reason)
+}
+
+func TestRunSelfTest_NonZeroExit(t *testing.T) {
+	c, _ := mockSelfTestComponent(t, "", SelfTestConfig{})
+
+	exitErr := exec.Command("sh", "-c", "exit 1").Run()
+	require.Error(t, exitErr)
+
+	c.selfTestRunner = &mockRunner{
+		runFunc: func(ctx context.Context, script string) ([]byte, int32, error) {
+			return []byte("node-1: Test NCCL failure common.cu:954 'unhandled cuda error'\n"), 1, exitErr
+		},
+	}
+	result, err := c.RunSelfTest(context.Background(), SelfTestRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, apiv1.HealthStateTypeUnhealthy, result.Health)
+	assert.Contains(t, result.Error, "Test NCCL failure")
+
+	// not run by the runner (e.g., failed to create the output file)
+	c.selfTestRunner = &mockRunner{
+		runFunc: func(ctx context.Context, script string) ([]byte, int32, error) {
+			return nil, 0, errors.New("runner error")
+		},
+	}
+	_, err = c.RunSelfTest(context.Background(), SelfTestRequest{})
+	assert.EqualError(t, err, "runner error")
+}
+
+func TestRunSelfTest_NotFound(t *testing.T) {
+	c, bucket := mockSelfTestComponent(t, testAllReducePerfOutput, SelfTestConfig{})
+
+	c.locateExecutableFunc = func(bin string) (string, error) {
+		return "", errors.New("executable \"all_reduce_perf\" not found in PATH")
+	}
+	runs := 0
+	c.selfTestRunner = &mockRunner{
+		runFunc: func(ctx context.Context, script string) ([]byte, int32, error) {
+			runs++
+			return nil, 0, nil
+		},
+	}
+
+	_, err := c.RunSelfTest(context.Background(), SelfTestRequest{})
+	assert.ErrorIs(t, err, ErrAllReducePerfNotFound)
+	assert.Zero(t, runs)
+
+	// the missing binary does not change the health state
+	states := c.LastHealthStates()
+	require.Len(t, states, 1)
+	assert.Equal(t, apiv1.HealthStateTypeHealthy, states[0].Health)
+	bucket.AssertNotCalled(t, "Insert", mock.Anything, mock.Anything)
+}
+
+func TestRunSelfTest_DefaultTimeout(t *testing.T) {
+	// no timeout in the config
+	c, _ := mockSelfTestComponent(t, testAllReducePerfOutput, SelfTestConfig{Command: "all_reduce_perf"})
+
+	var deadline time.Time
+	c.selfTestRunner = &mockRunner{
+		runFunc: func(ctx context.Context, script string) ([]byte, int32, error) {
+			deadline, _ = ctx.Deadline()
+			return []byte(testAllReducePerfOutput), 0, ctx.Err()
+		},
+	}
+
+	result, err := c.RunSelfTest(context.Background(), SelfTestRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, apiv1.HealthStateTypeHealthy, result.Health)
+	assert.WithinDuration(t, time.Now().Add(DefaultSelfTestTimeout), deadline, time.Minute)
+}
+
+func TestSetHealthy(t *testing.T) {
+	c, _ := mockSelfTestComponent(t, "node-1: Test NCCL failure common.cu:954 'unhandled cuda error'\n", SelfTestConfig{})
+
+	result, err := c.RunSelfTest(context.Background(), SelfTestRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, apiv1.HealthStateTypeUnhealthy, result.Health)
+	assert.Equal(t, apiv1.HealthStateTypeUnhealthy, c.LastHealthStates()[0].Health)
+
+	require.NoError(t, c.SetHealthy())
+	states := c.LastHealthStates()
+	require.Len(t, states, 1)
+	assert.Equal(t, apiv1.HealthStateTypeHealthy, states[0].Health)
+	assert.Equal(t, "no issue", states[0].Reason)
+}
+
+func TestRunSelfTest_GPUProcessesRunning(t *testing.T) {
+	c, _ := mockSelfTestComponent(t, testAllReducePerfOutput, SelfTestConfig{})
+	c.getProcessesFunc = func(uuid string, dev device.Device) (nvidianvml.Processes, error) {
+		return nvidianvml.Processes{UUID: uuid, RunningProcesses: []nvidianvml.Process{{PID: 1234}}}, nil
+	}
+
+	_, err := c.RunSelfTest(context.Background(), SelfTestRequest{})
+	assert.ErrorIs(t, err, ErrGPUProcessesRunning)
+
+	result, err := c.RunSelfTest(context.Background(), SelfTestRequest{Force: true})
+	require.NoError(t, err)
+	assert.Equal(t, 84.08, result.PeakBusBandwidthGBps)
+
+	c.nvmlInstance = nil
+	_, err = c.RunSelfTest(context.Background(), SelfTestRequest{})
+	assert.ErrorIs(t, err, ErrNoGPU)
+}
+
+func TestRunSelfTest_Cancel(t *testing.T) {
+	c, _ := mockSelfTestComponent(t, testAllReducePerfOutput, SelfTestConfig{})
+
+	started := make(chan struct{})
+	c.selfTestRunner = &mockRunner{
+		runFunc: func(ctx context.Context, script string) ([]byte, int32, error) {
+			close(started)
+			<-ctx.Done()
+			return nil, 0, ctx.Err()
+		},
+	}
+	assert.False(t, c.CancelSelfTest())
+
+	errc := make(chan error, 1)
+	go func() {
+		_, err := c.RunSelfTest(context.Background(), SelfTestRequest{})
+		errc <- err
+	}()
+	<-started
+
+	_, err := c.RunSelfTest(context.Background(), SelfTestRequest{})
+	assert.ErrorIs(t, err, process.ErrProcessAlreadyRunning)
+
+	assert.True(t, c.CancelSelfTest())
+	assert.ErrorIs(t, <-errc, context.Canceled)
+
+	// the aborted run does not change the health state
+	states := c.LastHealthStates()
+	assert.Equal(t, "no issue", states[0].Reason)
+}
+
+func TestRunIdleSelfTest(t *testing.T) {
+	c, _ := mockSelfTestComponent(t, testAllReducePerfOutput, SelfTestConfig{})
+
+	runs := 0
+	c.selfTestRunner = &mockRunner{
+		runFunc: func(ctx context.Context, script string) ([]byte, int32, error) {
+			runs++
+			return []byte(testAllReducePerfOutput), 0, nil
+		},
+	}
+
+	// disabled by default
+	c.runIdleSelfTest()
+	assert.Equal(t, 0, runs)
+
+	c.getSelfTestConfigFunc = func() SelfTestConfig {
+		return SelfTestConfig{IdleInterval: metav1.Duration{Duration: time.Hour}}
+	}
+	c.runIdleSelfTest()
+	assert.Equal(t, 1, runs)
+	assert.Equal(t, SelfTestTriggerIdle, c.lastSelfTest.Trigger)
+
+	// not due yet
+	c.runIdleSelfTest()
+	assert.Equal(t, 1, runs)
+
+	// skipped while the GPUs are in use
+	c.lastSelfTestRun = time.Now().Add(-2 * time.Hour)
+	c.getProcessesFunc = func(uuid string, dev device.Device) (nvidianvml.Processes, error) {
+		return nvidianvml.Processes{}, errors.New("nvml error")
+	}
+	c.runIdleSelfTest()
+	assert.Equal(t, 1, runs)
+}
+
+func TestDefaultSelfTestConfig(t *testing.T) {
+	orig := GetDefaultSelfTestConfig()
+	defer SetDefaultSelfTestConfig(orig)
+
+	assert.Equal(t, "all_reduce_perf", orig.Command)
+	assert.Equal(t, 10*time.Minute, orig.Timeout.Duration)
+	assert.Zero(t, orig.IdleInterval.Duration)
+
+	SetDefaultSelfTestConfig(SelfTestConfig{Command: "all_reduce_perf", IdleInterval: metav1.Duration{Duration: 24 * time.Hour}})
+	assert.Equal(t, 24*time.Hour, GetDefaultSelfTestConfig().IdleInterval.Duration)
+}
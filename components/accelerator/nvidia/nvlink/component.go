@@ -1,26 +1,60 @@
-// Package nvlink monitors the NVIDIA per-GPU nvlink devices.
+// Package nvlink monitors the NVIDIA per-GPU nvlink devices,
+// and tracks the per-link error counters to detect the links going down
+// or the errors increasing faster than the configured rates.
 package nvlink
 
 import (
 	"bytes"
 	"context"
+	"database/sql"
 	"encoding/json"
 	"fmt"
+	"runtime"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/NVIDIA/go-nvlib/pkg/nvlib/device"
 	"github.com/olekukonko/tablewriter"
 	"github.com/prometheus/client_golang/prometheus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	apiv1 "github.com/leptonai/gpud/api/v1"
 	"github.com/leptonai/gpud/components"
+	"github.com/leptonai/gpud/pkg/eventstore"
+	gpudstate "github.com/leptonai/gpud/pkg/gpud-state"
+	"github.com/leptonai/gpud/pkg/healthstate"
 	"github.com/leptonai/gpud/pkg/log"
 	pkgmetrics "github.com/leptonai/gpud/pkg/metrics"
 	nvidianvml "github.com/leptonai/gpud/pkg/nvidia-query/nvml"
 )
 
-const Name = "accelerator-nvidia-nvlink"
+const (
+	Name = "accelerator-nvidia-nvlink"
+
+	// DefaultEvaluationWindow is the window to evaluate the NVLink error rates.
+	DefaultEvaluationWindow = 10 * time.Minute
+)
+
+const (
+	EventNameLinkDown         = "nvlink_down"
+	EventNameErrorsIncreasing = "nvlink_errors_increasing"
+
+	EventKeyGPUUUID = "gpu_uuid"
+	EventKeyLink    = "link"
+)
+
+// the counter names persisted in the state database per link
+const (
+	counterReplayErrors   = "replay_errors"
+	counterRecoveryErrors = "recovery_errors"
+	counterCRCErrors      = "crc_errors"
+	counterFeatureEnabled = "feature_enabled"
+	// set to 1 once the link is observed enabled,
+	// to detect the link going down across restarts
+	counterEverEnabled = "ever_enabled"
+)
 
 var _ components.Component = &component{}
 
@@ -28,21 +62,71 @@ type component struct {
 	ctx    context.Context
 	cancel context.CancelFunc
 
-	nvmlInstance  nvidianvml.InstanceV2
-	getNVLinkFunc func(uuid string, dev device.Device) (nvidianvml.NVLink, error)
+	nvmlInstance      nvidianvml.InstanceV2
+	getNVLinkFunc     func(uuid string, dev device.Device) (nvidianvml.NVLink, error)
+	getThresholdsFunc func() Thresholds
+
+	dbRW *sql.DB
+	dbRO *sql.DB
+
+	eventBucket      eventstore.Bucket
+	evaluationWindow time.Duration
+
+	// tracks the previous counters per link (e.g., "GPU-xxx/3") and counter name,
+	// loaded from the state database (if any) on the first check
+	prevMu         sync.Mutex
+	prevLoaded     bool
+	prevCounters   map[string]map[string]gpudstate.Counter
+	prevThroughput map[string]throughputSample
 
 	lastMu   sync.RWMutex
 	lastData *Data
 }
 
+// throughputSample is the aggregated cumulative throughput of a GPU.
+type throughputSample struct {
+	txBytes uint64
+	rxBytes uint64
+	ts      time.Time
+}
+
 func New(gpudInstance *components.GPUdInstance) (components.Component, error) {
 	cctx, ccancel := context.WithCancel(gpudInstance.RootCtx)
 	c := &component{
-		ctx:           cctx,
-		cancel:        ccancel,
-		nvmlInstance:  gpudInstance.NVMLInstance,
-		getNVLinkFunc: nvidianvml.GetNVLink,
+		ctx:               cctx,
+		cancel:            ccancel,
+		nvmlInstance:      gpudInstance.NVMLInstance,
+		getNVLinkFunc:     nvidianvml.GetNVLink,
+		getThresholdsFunc: GetDefaultThresholds,
+
+		dbRW: gpudInstance.DBRW,
+		dbRO: gpudInstance.DBRO,
+
+		evaluationWindow: DefaultEvaluationWindow,
+
+		prevCounters:   make(map[string]map[string]gpudstate.Counter),
+		prevThroughput: make(map[string]throughputSample),
 	}
+
+	if c.dbRW != nil {
+		if err := gpudstate.CreateTableCounters(cctx, c.dbRW); err != nil {
+			ccancel()
+			return nil, err
+		}
+		if c.dbRO == nil {
+			c.dbRO = c.dbRW
+		}
+	}
+
+	if gpudInstance.EventStore != nil && runtime.GOOS == "linux" {
+		var err error
+		c.eventBucket, err = gpudInstance.EventStore.Bucket(Name)
+		if err != nil {
+			ccancel()
+			return nil, err
+		}
+	}
+
 	return c, nil
 }
 
@@ -74,7 +158,10 @@ func (c *component) LastHealthStates() apiv1.HealthStates {
 }
 
 func (c *component) Events(ctx context.Context, since time.Time) (apiv1.Events, error) {
-	return nil, nil
+	if c.eventBucket == nil {
+		return nil, nil
+	}
+	return c.eventBucket.Get(ctx, since)
 }
 
 func (c *component) Close() error {
@@ -82,6 +169,55 @@ func (c *component) Close() error {
 
 	c.cancel()
 
+	if c.eventBucket != nil {
+		c.eventBucket.Close()
+	}
+
+	return nil
+}
+
+var _ components.HealthSettable = &component{}
+
+// SetHealthy ignores the links of all the GPUs that are currently down
+// and the error rate events before now, until the next issue.
+func (c *component) SetHealthy() error {
+	return c.setHealthy("")
+}
+
+var _ components.DeviceHealthSettable = &component{}
+
+// SetHealthyDevice ignores the links of the GPU that are currently down
+// and the error rate events of the GPU before now,
+// without affecting the other GPUs, until the next issue.
+func (c *component) SetHealthyDevice(deviceUUID string) error {
+	return c.setHealthy(deviceUUID)
+}
+
+// setHealthy records the "SetHealthy" event of the GPU (or all the GPUs if empty),
+// and re-evaluates the states.
+func (c *component) setHealthy(uuid string) error {
+	log.Logger.Debugw("set healthy event received", "uuid", uuid)
+	if c.eventBucket == nil {
+		return nil
+	}
+
+	ev := apiv1.Event{
+		Time: metav1.Time{Time: time.Now().UTC()},
+		Name: healthstate.EventNameSetHealthy,
+		Type: apiv1.EventTypeInfo,
+	}
+	if uuid != "" {
+		ev.DeprecatedExtraInfo = map[string]string{EventKeyGPUUUID: uuid}
+	}
+
+	cctx, ccancel := context.WithTimeout(c.ctx, 10*time.Second)
+	err := c.eventBucket.Insert(cctx, ev)
+	ccancel()
+	if err != nil {
+		return err
+	}
+
+	_ = c.Check()
 	return nil
 }
 
@@ -108,9 +244,56 @@ func (c *component) Check() components.CheckResult {
 		return d
 	}
 
+	thresholds := c.getThresholdsFunc()
+
+	c.prevMu.Lock()
+	defer c.prevMu.Unlock()
+
+	if !c.prevLoaded && c.dbRO != nil {
+		cctx, ccancel := context.WithTimeout(c.ctx, 30*time.Second)
+		loaded, err := gpudstate.LoadCounters(cctx, c.dbRO, Name)
+		ccancel()
+		if err != nil {
+			log.Logger.Errorw("error reading nvlink counters", "error", err)
+
+			d.err = err
+			d.health = apiv1.HealthStateTypeUnhealthy
+			d.reason = fmt.Sprintf("error reading nvlink counters: %s", err)
+			return d
+		}
+		c.prevCounters = loaded
+	}
+	c.prevLoaded = true
+
+	// the baselines are no older than the counters retention,
+	// so are the "SetHealthy" events to compare with
+	var setHealthy healthstate.SetHealthyTimes
+	if c.eventBucket != nil {
+		cctx, ccancel := context.WithTimeout(c.ctx, 15*time.Second)
+		history, err := c.eventBucket.Get(cctx, d.ts.Add(-gpudstate.DefaultCountersRetention))
+		ccancel()
+		if err != nil {
+			d.err = err
+			d.health = apiv1.HealthStateTypeUnhealthy
+			d.reason = fmt.Sprintf("error getting events: %s", err)
+			return d
+		}
+		setHealthy = healthstate.LastSetHealthy(history, getEventGPUUUID)
+	}
+
 	devs := c.nvmlInstance.Devices()
-	for uuid, dev := range devs {
-		nvLink, err := c.getNVLinkFunc(uuid, dev)
+	uuids := make([]string, 0, len(devs))
+	for uuid := range devs {
+		uuids = append(uuids, uuid)
+	}
+	sort.Strings(uuids)
+
+	events := make([]apiv1.Event, 0)
+	updates := make([]gpudstate.Counter, 0)
+	degradedLinks := make(map[string]struct{})
+	unhealthyLinks := make(map[string]struct{})
+	for _, uuid := range uuids {
+		nvLink, err := c.getNVLinkFunc(uuid, devs[uuid])
 		if err != nil {
 			log.Logger.Errorw("error getting nvlink for device", "uuid", uuid, "error", err)
 
@@ -130,19 +313,244 @@ func (c *component) Check() components.CheckResult {
 		metricReplayErrors.With(prometheus.Labels{pkgmetrics.MetricLabelKey: uuid}).Set(float64(nvLink.States.TotalRelayErrors()))
 		metricRecoveryErrors.With(prometheus.Labels{pkgmetrics.MetricLabelKey: uuid}).Set(float64(nvLink.States.TotalRecoveryErrors()))
 		metricCRCErrors.With(prometheus.Labels{pkgmetrics.MetricLabelKey: uuid}).Set(float64(nvLink.States.TotalCRCErrors()))
+
+		linksDown := 0
+		var txBytes, rxBytes uint64
+		for _, state := range nvLink.States {
+			txBytes += state.ThroughputRawTxBytes
+			rxBytes += state.ThroughputRawRxBytes
+
+			linkDev := fmt.Sprintf("%s/%d", uuid, state.Link)
+			prev := c.prevCounters[linkDev]
+
+			// only the links that were ever enabled are tracked,
+			// since the unused links are reported as disabled,
+			// and the links down before the "SetHealthy" are no longer tracked
+			// (the events are in seconds, so the baseline of the same second is reset)
+			healthyAt := setHealthy.Of(uuid)
+			reset := !healthyAt.IsZero() && !healthyAt.Before(prev[counterFeatureEnabled].Time.Truncate(time.Second))
+			everEnabled := state.FeatureEnabled || (!reset && prev[counterEverEnabled].Value == 1)
+			if everEnabled && !state.FeatureEnabled {
+				linksDown++
+				d.DownLinks = append(d.DownLinks, linkDev)
+
+				if prev[counterFeatureEnabled].Value == 1 {
+					events = append(events, apiv1.Event{
+						Time:    metav1.Time{Time: d.ts},
+						Name:    EventNameLinkDown,
+						Type:    apiv1.EventTypeCritical,
+						Message: fmt.Sprintf("GPU %s NVLink %d is down", uuid, state.Link),
+						DeprecatedExtraInfo: map[string]string{
+							EventKeyGPUUUID: uuid,
+							EventKeyLink:    fmt.Sprintf("%d", state.Link),
+						},
+					})
+				}
+			}
+
+			cur := []gpudstate.Counter{
+				{Device: linkDev, Name: counterReplayErrors, Value: state.ReplayErrors, Time: d.ts},
+				{Device: linkDev, Name: counterRecoveryErrors, Value: state.RecoveryErrors, Time: d.ts},
+				{Device: linkDev, Name: counterCRCErrors, Value: state.CRCErrors, Time: d.ts},
+			}
+			for _, cnt := range cur {
+				p, ok := prev[cnt.Name]
+				// the counter may be reset (e.g., GPU reset), then only track the new baseline
+				if !ok || cnt.Value < p.Value {
+					continue
+				}
+				delta := cnt.Value - p.Value
+				if delta == 0 {
+					continue
+				}
+
+				// avoid overestimating the rate when checked more than once per minute
+				elapsed := d.ts.Sub(p.Time)
+				if elapsed < time.Minute {
+					elapsed = time.Minute
+				}
+				rate := float64(delta) / elapsed.Minutes()
+
+				evType := apiv1.EventType("")
+				switch {
+				case thresholds.UnhealthyErrorsPerMinute > 0 && rate >= thresholds.UnhealthyErrorsPerMinute:
+					evType = apiv1.EventTypeCritical
+					unhealthyLinks[linkDev] = struct{}{}
+				case thresholds.DegradedErrorsPerMinute > 0 && rate >= thresholds.DegradedErrorsPerMinute:
+					evType = apiv1.EventTypeWarning
+					degradedLinks[linkDev] = struct{}{}
+				}
+				if evType == "" {
+					continue
+				}
+
+				events = append(events, apiv1.Event{
+					Time:    metav1.Time{Time: d.ts},
+					Name:    EventNameErrorsIncreasing,
+					Type:    evType,
+					Message: fmt.Sprintf("GPU %s NVLink %d %s increased by %d (%.1f per minute)", uuid, state.Link, strings.ReplaceAll(cnt.Name, "_", " "), delta, rate),
+					DeprecatedExtraInfo: map[string]string{
+						EventKeyGPUUUID: uuid,
+						EventKeyLink:    fmt.Sprintf("%d", state.Link),
+					},
+				})
+			}
+
+			enabled := uint64(0)
+			if state.FeatureEnabled {
+				enabled = 1
+			}
+			ever := uint64(0)
+			if everEnabled {
+				ever = 1
+			}
+			cur = append(cur,
+				gpudstate.Counter{Device: linkDev, Name: counterFeatureEnabled, Value: enabled, Time: d.ts},
+				gpudstate.Counter{Device: linkDev, Name: counterEverEnabled, Value: ever, Time: d.ts},
+			)
+
+			next := make(map[string]gpudstate.Counter, len(cur))
+			for _, cnt := range cur {
+				next[cnt.Name] = cnt
+			}
+			c.prevCounters[linkDev] = next
+			updates = append(updates, cur...)
+		}
+
+		metricLinksDown.With(prometheus.Labels{pkgmetrics.MetricLabelKey: uuid}).Set(float64(linksDown))
+
+		// the throughput counters are cumulative, so compute the rates from the previous check
+		if prev, ok := c.prevThroughput[uuid]; ok && txBytes >= prev.txBytes && rxBytes >= prev.rxBytes {
+			if elapsed := d.ts.Sub(prev.ts).Seconds(); elapsed > 0 {
+				metricThroughputRawTxBytesPerSecond.With(prometheus.Labels{pkgmetrics.MetricLabelKey: uuid}).Set(float64(txBytes-prev.txBytes) / elapsed)
+				metricThroughputRawRxBytesPerSecond.With(prometheus.Labels{pkgmetrics.MetricLabelKey: uuid}).Set(float64(rxBytes-prev.rxBytes) / elapsed)
+			}
+		}
+		c.prevThroughput[uuid] = throughputSample{txBytes: txBytes, rxBytes: rxBytes, ts: d.ts}
+	}
+
+	if c.dbRW != nil {
+		cctx, ccancel := context.WithTimeout(c.ctx, 30*time.Second)
+		err := gpudstate.UpdateCounters(cctx, c.dbRW, Name, updates)
+		ccancel()
+		if err != nil {
+			log.Logger.Errorw("error updating nvlink counters", "error", err)
+
+			d.err = err
+			d.health = apiv1.HealthStateTypeUnhealthy
+			d.reason = fmt.Sprintf("error updating nvlink counters: %s", err)
+			return d
+		}
 	}
 
-	d.health = apiv1.HealthStateTypeHealthy
-	d.reason = fmt.Sprintf("all %d GPU(s) were checked, no nvlink issue found", len(devs))
+	if c.eventBucket != nil {
+		for _, ev := range events {
+			if err := c.eventBucket.Insert(c.ctx, ev); err != nil {
+				log.Logger.Errorw("failed to insert event", "error", err)
+
+				d.err = err
+				d.health = apiv1.HealthStateTypeUnhealthy
+				d.reason = fmt.Sprintf("error inserting event: %s", err)
+				return d
+			}
+		}
+
+		// error bursts are transient, so evaluate the recent ones
+		// rather than only the last check
+		cctx, ccancel := context.WithTimeout(c.ctx, 15*time.Second)
+		recent, err := c.eventBucket.Get(cctx, d.ts.Add(-c.evaluationWindow))
+		ccancel()
+		if err != nil {
+			d.err = err
+			d.health = apiv1.HealthStateTypeUnhealthy
+			d.reason = fmt.Sprintf("error getting events: %s", err)
+			return d
+		}
+		for _, ev := range recent {
+			if ev.Name != EventNameErrorsIncreasing {
+				continue
+			}
+			if !ev.Time.After(setHealthy.Of(getEventGPUUUID(ev))) {
+				continue
+			}
+			linkDev := ev.DeprecatedExtraInfo[EventKeyGPUUUID] + "/" + ev.DeprecatedExtraInfo[EventKeyLink]
+			if ev.Type == apiv1.EventTypeCritical {
+				unhealthyLinks[linkDev] = struct{}{}
+			} else {
+				degradedLinks[linkDev] = struct{}{}
+			}
+		}
+	}
+
+	for linkDev := range unhealthyLinks {
+		d.UnhealthyErrorLinks = append(d.UnhealthyErrorLinks, linkDev)
+		delete(degradedLinks, linkDev)
+	}
+	for linkDev := range degradedLinks {
+		d.DegradedErrorLinks = append(d.DegradedErrorLinks, linkDev)
+	}
+	sort.Strings(d.UnhealthyErrorLinks)
+	sort.Strings(d.DegradedErrorLinks)
+
+	issues := make([]string, 0)
+	if len(d.DownLinks) > 0 {
+		issues = append(issues, fmt.Sprintf("%d nvlink(s) down (%s)", len(d.DownLinks), strings.Join(d.DownLinks, ", ")))
+	}
+	if len(d.UnhealthyErrorLinks) > 0 {
+		issues = append(issues, fmt.Sprintf("%d nvlink(s) with errors above %.1f per minute (%s)", len(d.UnhealthyErrorLinks), thresholds.UnhealthyErrorsPerMinute, strings.Join(d.UnhealthyErrorLinks, ", ")))
+	}
+	if len(d.DegradedErrorLinks) > 0 {
+		issues = append(issues, fmt.Sprintf("%d nvlink(s) with errors above %.1f per minute (%s)", len(d.DegradedErrorLinks), thresholds.DegradedErrorsPerMinute, strings.Join(d.DegradedErrorLinks, ", ")))
+	}
+
+	if len(issues) == 0 {
+		d.health = apiv1.HealthStateTypeHealthy
+		d.reason = fmt.Sprintf("all %d GPU(s) were checked, no nvlink issue found", len(devs))
+		return d
+	}
+
+	d.health = apiv1.HealthStateTypeDegraded
+	if len(d.DownLinks) > 0 || len(d.UnhealthyErrorLinks) > 0 {
+		d.health = apiv1.HealthStateTypeUnhealthy
+	}
+	d.reason = strings.Join(issues, "; ")
+
+	d.suggestedActions = &apiv1.SuggestedActions{}
+	if len(d.DownLinks) > 0 {
+		d.suggestedActions.RepairActions = append(d.suggestedActions.RepairActions, apiv1.RepairActionTypeRebootSystem)
+		d.suggestedActions.DeprecatedDescriptions = append(d.suggestedActions.DeprecatedDescriptions,
+			"NVLink went down, please reboot the system to retrain the link",
+		)
+	}
+	if len(d.UnhealthyErrorLinks) > 0 || len(d.DegradedErrorLinks) > 0 {
+		d.suggestedActions.RepairActions = append(d.suggestedActions.RepairActions, apiv1.RepairActionTypeHardwareInspection)
+		d.suggestedActions.DeprecatedDescriptions = append(d.suggestedActions.DeprecatedDescriptions,
+			"NVLink errors are increasing, which is often caused by a bad NVLink bridge, cable, or NVSwitch, please do a hardware inspection to mitigate the issue",
+		)
+	}
 
 	return d
 }
 
+// getEventGPUUUID returns the GPU UUID of the event, or empty if not set.
+func getEventGPUUUID(ev apiv1.Event) string {
+	return ev.DeprecatedExtraInfo[EventKeyGPUUUID]
+}
+
 var _ components.CheckResult = &Data{}
 
 type Data struct {
 	NVLinks []nvidianvml.NVLink `json:"nvlinks,omitempty"`
 
+	// DownLinks is the links (e.g., "GPU-xxx/3") that were enabled but are now disabled.
+	DownLinks []string `json:"down_links,omitempty"`
+	// UnhealthyErrorLinks is the links with the error rate above the unhealthy threshold
+	// within the evaluation window.
+	UnhealthyErrorLinks []string `json:"unhealthy_error_links,omitempty"`
+	// DegradedErrorLinks is the links with the error rate above the degraded threshold
+	// within the evaluation window.
+	DegradedErrorLinks []string `json:"degraded_error_links,omitempty"`
+
 	// timestamp of the last check
 	ts time.Time
 	// error from the last check
@@ -152,6 +560,8 @@ type Data struct {
 	health apiv1.HealthStateType
 	// tracks the reason of the last check
 	reason string
+	// tracks the suggested actions of the last check
+	suggestedActions *apiv1.SuggestedActions
 }
 
 func (d *Data) String() string {
@@ -165,9 +575,16 @@ func (d *Data) String() string {
 	buf := bytes.NewBuffer(nil)
 	table := tablewriter.NewWriter(buf)
 	table.SetAlignment(tablewriter.ALIGN_CENTER)
-	table.SetHeader([]string{"UUID", "NVLink Enabled", "NVLink Supported"})
+	table.SetHeader([]string{"UUID", "NVLink Enabled", "NVLink Supported", "Replay Errors", "Recovery Errors", "CRC Errors"})
 	for _, nvlink := range d.NVLinks {
-		table.Append([]string{nvlink.UUID, fmt.Sprintf("%t", nvlink.States.AllFeatureEnabled()), fmt.Sprintf("%t", nvlink.Supported)})
+		table.Append([]string{
+			nvlink.UUID,
+			fmt.Sprintf("%t", nvlink.States.AllFeatureEnabled()),
+			fmt.Sprintf("%t", nvlink.Supported),
+			fmt.Sprintf("%d", nvlink.States.TotalRelayErrors()),
+			fmt.Sprintf("%d", nvlink.States.TotalRecoveryErrors()),
+			fmt.Sprintf("%d", nvlink.States.TotalCRCErrors()),
+		})
 	}
 	table.Render()
 
@@ -207,10 +624,11 @@ func (d *Data) getLastHealthStates() apiv1.HealthStates {
 	}
 
 	state := apiv1.HealthState{
-		Name:   Name,
-		Reason: d.reason,
-		Error:  d.getError(),
-		Health: d.health,
+		Name:             Name,
+		Reason:           d.reason,
+		Error:            d.getError(),
+		Health:           d.health,
+		SuggestedActions: d.suggestedActions,
 	}
 
 	b, _ := json.Marshal(d)
@@ -15,9 +15,13 @@ import (
 
 	apiv1 "github.com/leptonai/gpud/api/v1"
 	"github.com/leptonai/gpud/components"
+	"github.com/leptonai/gpud/pkg/eventstore"
+	gpudstate "github.com/leptonai/gpud/pkg/gpud-state"
+	"github.com/leptonai/gpud/pkg/healthstate"
 	nvidianvml "github.com/leptonai/gpud/pkg/nvidia-query/nvml"
 	nvmllib "github.com/leptonai/gpud/pkg/nvidia-query/nvml/lib"
 	"github.com/leptonai/gpud/pkg/nvidia-query/nvml/testutil"
+	"github.com/leptonai/gpud/pkg/sqlite"
 )
 
 // MockNvmlInstance implements the nvml.InstanceV2 interface for testing
@@ -74,10 +78,14 @@ func MockNVLinkComponent(
 	}
 
 	return &component{
-		ctx:           cctx,
-		cancel:        cancel,
-		nvmlInstance:  mockInstance,
-		getNVLinkFunc: getNVLinkFunc,
+		ctx:               cctx,
+		cancel:            cancel,
+		nvmlInstance:      mockInstance,
+		getNVLinkFunc:     getNVLinkFunc,
+		getThresholdsFunc: GetDefaultThresholds,
+		evaluationWindow:  DefaultEvaluationWindow,
+		prevCounters:      make(map[string]map[string]gpudstate.Counter),
+		prevThroughput:    make(map[string]throughputSample),
 	}
 }
 
@@ -674,3 +682,242 @@ func TestCheck_MetricsGeneration(t *testing.T) {
 	assert.Equal(t, uint64(5), lastData.NVLinks[0].States.TotalCRCErrors(),
 		"TotalCRCErrors should match the sum")
 }
+func TestCheck_LinkStatesAndErrorRates(t *testing.T) {
+	t.Parallel()
+
+	const uuid = "gpu-uuid-123"
+
+	type step struct {
+		// mutates the NVLink states before the check
+		update func(states []nvidianvml.NVLinkState)
+		// re-creates the component from the persisted baselines (e.g., gpud restart)
+		restart bool
+		// sets the component healthy before the check
+		setHealthy bool
+		// sets the GPU healthy before the check
+		setHealthyDevice string
+		// disables the evaluation window, to only evaluate the current check
+		noEvaluationWindow bool
+
+		expectedHealth apiv1.HealthStateType
+		verify         func(t *testing.T, c *component, d *Data)
+	}
+
+	tests := []struct {
+		name       string
+		states     []nvidianvml.NVLinkState
+		thresholds Thresholds
+		steps      []step
+		// expected number of the events at the end
+		expectedEvents int
+	}{
+		{
+			name: "link down until set healthy, even after restart",
+			states: []nvidianvml.NVLinkState{
+				{Link: 0, FeatureEnabled: true},
+				{Link: 1, FeatureEnabled: true},
+				// never enabled, so not tracked
+				{Link: 2, FeatureEnabled: false},
+			},
+			thresholds: Thresholds{DegradedErrorsPerMinute: 10, UnhealthyErrorsPerMinute: 100},
+			steps: []step{
+				{expectedHealth: apiv1.HealthStateTypeHealthy},
+				{
+					update:         func(s []nvidianvml.NVLinkState) { s[1].FeatureEnabled = false },
+					expectedHealth: apiv1.HealthStateTypeUnhealthy,
+					verify: func(t *testing.T, c *component, d *Data) {
+						assert.Equal(t, []string{uuid + "/1"}, d.DownLinks)
+						require.NotNil(t, d.suggestedActions)
+						assert.Equal(t, []apiv1.RepairActionType{apiv1.RepairActionTypeRebootSystem}, d.suggestedActions.RepairActions)
+					},
+				},
+				{
+					// the event is only inserted on the transition
+					expectedHealth: apiv1.HealthStateTypeUnhealthy,
+					verify: func(t *testing.T, c *component, d *Data) {
+						events, err := c.Events(context.Background(), time.Now().Add(-time.Minute))
+						require.NoError(t, err)
+						require.Len(t, events, 1)
+						assert.Equal(t, EventNameLinkDown, events[0].Name)
+						assert.Equal(t, "GPU gpu-uuid-123 NVLink 1 is down", events[0].Message)
+						assert.Equal(t, "1", events[0].DeprecatedExtraInfo[EventKeyLink])
+					},
+				},
+				{
+					restart:        true,
+					expectedHealth: apiv1.HealthStateTypeUnhealthy,
+					verify: func(t *testing.T, c *component, d *Data) {
+						assert.Equal(t, []string{uuid + "/1"}, d.DownLinks)
+					},
+				},
+				{
+					// other GPUs are not affected
+					setHealthyDevice: "gpu-uuid-other",
+					expectedHealth:   apiv1.HealthStateTypeUnhealthy,
+				},
+				{
+					setHealthy:     true,
+					expectedHealth: apiv1.HealthStateTypeHealthy,
+					verify: func(t *testing.T, c *component, d *Data) {
+						assert.Empty(t, d.DownLinks)
+					},
+				},
+			},
+			expectedEvents: 1,
+		},
+		{
+			name: "error rates over the thresholds",
+			states: []nvidianvml.NVLinkState{
+				{Link: 0, FeatureEnabled: true, ReplayErrors: 1000, CRCErrors: 1000},
+				{Link: 1, FeatureEnabled: true},
+			},
+			thresholds: Thresholds{DegradedErrorsPerMinute: 10, UnhealthyErrorsPerMinute: 100},
+			steps: []step{
+				// the first check only records the baselines
+				{expectedHealth: apiv1.HealthStateTypeHealthy},
+				{
+					// below the degraded threshold
+					update:         func(s []nvidianvml.NVLinkState) { s[0].ReplayErrors += 5 },
+					expectedHealth: apiv1.HealthStateTypeHealthy,
+				},
+				{
+					update:         func(s []nvidianvml.NVLinkState) { s[1].CRCErrors += 20 },
+					expectedHealth: apiv1.HealthStateTypeDegraded,
+					verify: func(t *testing.T, c *component, d *Data) {
+						assert.Equal(t, []string{uuid + "/1"}, d.DegradedErrorLinks)
+						require.NotNil(t, d.suggestedActions)
+						assert.Equal(t, []apiv1.RepairActionType{apiv1.RepairActionTypeHardwareInspection}, d.suggestedActions.RepairActions)
+					},
+				},
+				{
+					// still degraded within the evaluation window
+					expectedHealth: apiv1.HealthStateTypeDegraded,
+				},
+				{
+					update:         func(s []nvidianvml.NVLinkState) { s[0].ReplayErrors += 500 },
+					expectedHealth: apiv1.HealthStateTypeUnhealthy,
+					verify: func(t *testing.T, c *component, d *Data) {
+						assert.Equal(t, []string{uuid + "/0"}, d.UnhealthyErrorLinks)
+						assert.Equal(t, []string{uuid + "/1"}, d.DegradedErrorLinks)
+					},
+				},
+				{
+					// counter reset only records the new baseline
+					update:             func(s []nvidianvml.NVLinkState) { s[0].ReplayErrors = 0 },
+					noEvaluationWindow: true,
+					expectedHealth:     apiv1.HealthStateTypeHealthy,
+				},
+				{
+					// the baselines survive the restart
+					update:             func(s []nvidianvml.NVLinkState) { s[1].CRCErrors += 200 },
+					restart:            true,
+					noEvaluationWindow: true,
+					expectedHealth:     apiv1.HealthStateTypeUnhealthy,
+					verify: func(t *testing.T, c *component, d *Data) {
+						assert.Equal(t, []string{uuid + "/1"}, d.UnhealthyErrorLinks)
+					},
+				},
+			},
+			expectedEvents: 3,
+		},
+		{
+			name: "error rates until set healthy device",
+			states: []nvidianvml.NVLinkState{
+				{Link: 0, FeatureEnabled: true},
+			},
+			thresholds: Thresholds{DegradedErrorsPerMinute: 10, UnhealthyErrorsPerMinute: 100},
+			steps: []step{
+				{expectedHealth: apiv1.HealthStateTypeHealthy},
+				{
+					update:         func(s []nvidianvml.NVLinkState) { s[0].CRCErrors += 20 },
+					expectedHealth: apiv1.HealthStateTypeDegraded,
+				},
+				{
+					setHealthyDevice: uuid,
+					expectedHealth:   apiv1.HealthStateTypeHealthy,
+				},
+			},
+			expectedEvents: 1,
+		},
+		{
+			name: "error rates disabled",
+			states: []nvidianvml.NVLinkState{
+				{Link: 0, FeatureEnabled: true},
+			},
+			steps: []step{
+				{expectedHealth: apiv1.HealthStateTypeHealthy},
+				{
+					update:         func(s []nvidianvml.NVLinkState) { s[0].ReplayErrors += 100000 },
+					expectedHealth: apiv1.HealthStateTypeHealthy,
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			dbRW, dbRO, cleanup := sqlite.OpenTestDB(t)
+			defer cleanup()
+			require.NoError(t, gpudstate.CreateTableCounters(ctx, dbRW))
+
+			eventStore, err := eventstore.New(dbRW, dbRO, 0)
+			require.NoError(t, err)
+
+			mockDev := testutil.NewMockDevice(&mock.Device{}, "test-arch", "test-brand", "test-cuda", "test-pci")
+			states := append([]nvidianvml.NVLinkState(nil), tt.states...)
+
+			newComponent := func() *component {
+				c := MockNVLinkComponent(
+					ctx,
+					func() map[string]device.Device { return map[string]device.Device{uuid: mockDev} },
+					func(uuid string, dev device.Device) (nvidianvml.NVLink, error) {
+						return nvidianvml.NVLink{UUID: uuid, Supported: true, States: append([]nvidianvml.NVLinkState(nil), states...)}, nil
+					},
+				).(*component)
+				c.getThresholdsFunc = func() Thresholds { return tt.thresholds }
+				c.dbRW, c.dbRO = dbRW, dbRO
+				c.eventBucket, err = eventStore.Bucket(Name)
+				require.NoError(t, err)
+				return c
+			}
+
+			c := newComponent()
+			for i, s := range tt.steps {
+				if s.update != nil {
+					s.update(states)
+				}
+				if s.restart {
+					c = newComponent()
+				}
+				if s.setHealthy {
+					require.NoError(t, c.SetHealthy())
+				}
+				if s.setHealthyDevice != "" {
+					require.NoError(t, c.SetHealthyDevice(s.setHealthyDevice))
+				}
+				if s.noEvaluationWindow {
+					c.evaluationWindow = 0
+				}
+
+				d := c.Check().(*Data)
+				assert.Equal(t, s.expectedHealth, d.HealthState(), "step %d: %s", i, d.Summary())
+				if s.verify != nil {
+					s.verify(t, c, d)
+				}
+			}
+
+			events, err := c.Events(ctx, time.Now().Add(-time.Minute))
+			require.NoError(t, err)
+			n := 0
+			for _, ev := range events {
+				if ev.Name != healthstate.EventNameSetHealthy {
+					n++
+				}
+			}
+			assert.Equal(t, tt.expectedEvents, n)
+		})
+	}
+}
@@ -52,6 +52,36 @@ var (
 		},
 		[]string{pkgmetrics.MetricComponentLabelKey, pkgmetrics.MetricLabelKey}, // label is GPU ID
 	).MustCurryWith(componentLabel)
+
+	metricLinksDown = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "",
+			Subsystem: SubSystem,
+			Name:      "links_down",
+			Help:      "tracks the number of NVLinks that were enabled but are now disabled (per GPU)",
+		},
+		[]string{pkgmetrics.MetricComponentLabelKey, pkgmetrics.MetricLabelKey}, // label is GPU ID
+	).MustCurryWith(componentLabel)
+
+	metricThroughputRawTxBytesPerSecond = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "",
+			Subsystem: SubSystem,
+			Name:      "throughput_raw_tx_bytes_per_second",
+			Help:      "tracks the NVLink raw TX throughput in bytes per second (aggregated for all links per GPU)",
+		},
+		[]string{pkgmetrics.MetricComponentLabelKey, pkgmetrics.MetricLabelKey}, // label is GPU ID
+	).MustCurryWith(componentLabel)
+
+	metricThroughputRawRxBytesPerSecond = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "",
+			Subsystem: SubSystem,
+			Name:      "throughput_raw_rx_bytes_per_second",
+			Help:      "tracks the NVLink raw RX throughput in bytes per second (aggregated for all links per GPU)",
+		},
+		[]string{pkgmetrics.MetricComponentLabelKey, pkgmetrics.MetricLabelKey}, // label is GPU ID
+	).MustCurryWith(componentLabel)
 )
 
 func init() {
@@ -60,5 +90,8 @@ func init() {
 		metricReplayErrors,
 		metricRecoveryErrors,
 		metricCRCErrors,
+		metricLinksDown,
+		metricThroughputRawTxBytesPerSecond,
+		metricThroughputRawRxBytesPerSecond,
 	)
 }
@@ -0,0 +1,41 @@
+package nvlink
+
+import (
+	"sync"
+
+	"github.com/leptonai/gpud/pkg/log"
+)
+
+// Thresholds defines the NVLink error rates to evaluate the health state.
+// The rate is evaluated per link and per error counter (replay, recovery, or CRC).
+// Zero disables the threshold.
+type Thresholds struct {
+	// DegradedErrorsPerMinute is the error rate of a single link
+	// to mark the component degraded.
+	DegradedErrorsPerMinute float64 `json:"degraded_errors_per_minute"`
+	// UnhealthyErrorsPerMinute is the error rate of a single link
+	// to mark the component unhealthy.
+	UnhealthyErrorsPerMinute float64 `json:"unhealthy_errors_per_minute"`
+}
+
+var (
+	defaultThresholdsMu sync.RWMutex
+	defaultThresholds   = Thresholds{
+		DegradedErrorsPerMinute:  10,
+		UnhealthyErrorsPerMinute: 100,
+	}
+)
+
+func GetDefaultThresholds() Thresholds {
+	defaultThresholdsMu.RLock()
+	defer defaultThresholdsMu.RUnlock()
+	return defaultThresholds
+}
+
+func SetDefaultThresholds(thresholds Thresholds) {
+	log.Logger.Infow("setting default nvlink thresholds", "degraded_errors_per_minute", thresholds.DegradedErrorsPerMinute, "unhealthy_errors_per_minute", thresholds.UnhealthyErrorsPerMinute)
+
+	defaultThresholdsMu.Lock()
+	defer defaultThresholdsMu.Unlock()
+	defaultThresholds = thresholds
+}
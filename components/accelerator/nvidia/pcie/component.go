@@ -0,0 +1,496 @@
+// Package pcie monitors the PCIe link generation and width of the NVIDIA GPUs,
+// and the PCIe replay counters, to detect the downtrained links.
+package pcie
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/NVIDIA/go-nvlib/pkg/nvlib/device"
+	"github.com/olekukonko/tablewriter"
+	"github.com/prometheus/client_golang/prometheus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	apiv1 "github.com/leptonai/gpud/api/v1"
+	"github.com/leptonai/gpud/components"
+	"github.com/leptonai/gpud/pkg/eventstore"
+	gpudstate "github.com/leptonai/gpud/pkg/gpud-state"
+	"github.com/leptonai/gpud/pkg/log"
+	pkgmetrics "github.com/leptonai/gpud/pkg/metrics"
+	nvidianvml "github.com/leptonai/gpud/pkg/nvidia-query/nvml"
+)
+
+const (
+	Name = "accelerator-nvidia-pcie"
+
+	// DefaultReplayBurstThreshold is the default number of the PCIe replays between two checks
+	// (one minute by default) to be considered a replay burst (see "Thresholds").
+	// A few replays are expected over time, but a burst indicates
+	// bad signal integrity (e.g., riser, cable, or slot issue).
+	DefaultReplayBurstThreshold = 100
+
+	// DefaultEvaluationWindow is the default window to evaluate the replay bursts.
+	DefaultEvaluationWindow = 10 * time.Minute
+)
+
+const (
+	EventNameLinkDegraded = "pcie_link_degraded"
+	EventNameReplayBurst  = "pcie_replay_burst"
+
+	EventKeyGPUUUID = "gpu_uuid"
+
+	// counterLinkDegraded is the persisted link state (1 if degraded),
+	// so that the link degraded event is not inserted again
+	// after the restart for the link that is still downtrained
+	counterLinkDegraded = "link_degraded"
+)
+
+var _ components.Component = &component{}
+
+type component struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	nvmlInstance    nvidianvml.InstanceV2
+	getPCIeLinkFunc func(uuid string, dev device.Device) (nvidianvml.PCIeLink, error)
+
+	dbRW *sql.DB
+	dbRO *sql.DB
+
+	eventBucket eventstore.Bucket
+
+	getThresholdsFunc func() Thresholds
+
+	// tracks the previous link states and replay counters
+	// to insert the events only on changes
+	// the link states are loaded from the state database (if any) on the first check
+	prevMu       sync.Mutex
+	prevLoaded   bool
+	prevDegraded map[string]bool
+	prevReplays  map[string]uint64
+
+	lastMu   sync.RWMutex
+	lastData *Data
+}
+
+func New(gpudInstance *components.GPUdInstance) (components.Component, error) {
+	cctx, ccancel := context.WithCancel(gpudInstance.RootCtx)
+	c := &component{
+		ctx:    cctx,
+		cancel: ccancel,
+
+		nvmlInstance:    gpudInstance.NVMLInstance,
+		getPCIeLinkFunc: nvidianvml.GetPCIeLink,
+
+		dbRW: gpudInstance.DBRW,
+		dbRO: gpudInstance.DBRO,
+
+		getThresholdsFunc: GetDefaultThresholds,
+
+		prevDegraded: make(map[string]bool),
+		prevReplays:  make(map[string]uint64),
+	}
+
+	if c.dbRW != nil {
+		if err := gpudstate.CreateTableCounters(cctx, c.dbRW); err != nil {
+			ccancel()
+			return nil, err
+		}
+		if c.dbRO == nil {
+			c.dbRO = c.dbRW
+		}
+	}
+
+	if gpudInstance.EventStore != nil && runtime.GOOS == "linux" {
+		var err error
+		c.eventBucket, err = gpudInstance.EventStore.Bucket(Name)
+		if err != nil {
+			ccancel()
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
+
+func (c *component) Name() string { return Name }
+
+func (c *component) Start() error {
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+
+		for {
+			_ = c.Check()
+
+			select {
+			case <-c.ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return nil
+}
+
+func (c *component) LastHealthStates() apiv1.HealthStates {
+	c.lastMu.RLock()
+	lastData := c.lastData
+	c.lastMu.RUnlock()
+	return lastData.getLastHealthStates()
+}
+
+func (c *component) Events(ctx context.Context, since time.Time) (apiv1.Events, error) {
+	if c.eventBucket == nil {
+		return nil, nil
+	}
+	return c.eventBucket.Get(ctx, since)
+}
+
+func (c *component) Close() error {
+	log.Logger.Debugw("closing component")
+
+	c.cancel()
+
+	if c.eventBucket != nil {
+		c.eventBucket.Close()
+	}
+
+	return nil
+}
+
+func (c *component) Check() components.CheckResult {
+	log.Logger.Infow("checking nvidia gpu pcie links")
+
+	d := &Data{
+		ts: time.Now().UTC(),
+	}
+	defer func() {
+		c.lastMu.Lock()
+		c.lastData = d
+		c.lastMu.Unlock()
+	}()
+
+	if c.nvmlInstance == nil {
+		d.health = apiv1.HealthStateTypeHealthy
+		d.reason = "NVIDIA NVML instance is nil"
+		return d
+	}
+	if !c.nvmlInstance.NVMLExists() {
+		d.health = apiv1.HealthStateTypeHealthy
+		d.reason = "NVIDIA NVML is not loaded"
+		return d
+	}
+
+	if err := c.loadPrevDegraded(); err != nil {
+		log.Logger.Errorw("error reading pcie link states", "error", err)
+
+		d.err = err
+		d.health = apiv1.HealthStateTypeUnhealthy
+		d.reason = fmt.Sprintf("error reading pcie link states: %s", err)
+		return d
+	}
+
+	devs := c.nvmlInstance.Devices()
+	uuids := make([]string, 0, len(devs))
+	for uuid := range devs {
+		uuids = append(uuids, uuid)
+	}
+	sort.Strings(uuids)
+
+	thresholds := c.getThresholdsFunc()
+
+	events := make([]apiv1.Event, 0)
+	updates := make([]gpudstate.Counter, 0)
+	for _, uuid := range uuids {
+		link, err := c.getPCIeLinkFunc(uuid, devs[uuid])
+		if err != nil {
+			log.Logger.Errorw("error getting pcie link", "uuid", uuid, "error", err)
+
+			d.err = err
+			d.health = apiv1.HealthStateTypeUnhealthy
+			d.reason = fmt.Sprintf("error getting pcie link for gpu %s", uuid)
+			return d
+		}
+		if !link.Supported {
+			continue
+		}
+		d.PCIeLinks = append(d.PCIeLinks, link)
+
+		labels := prometheus.Labels{pkgmetrics.MetricLabelKey: uuid}
+		metricCurrentLinkGeneration.With(labels).Set(float64(link.CurrentGeneration))
+		metricMaxLinkGeneration.With(labels).Set(float64(link.MaxGeneration))
+		metricCurrentLinkWidth.With(labels).Set(float64(link.CurrentWidth))
+		metricMaxLinkWidth.With(labels).Set(float64(link.MaxWidth))
+		metricReplayCounter.With(labels).Set(float64(link.ReplayCounter))
+		if link.Degraded() {
+			metricLinkDegraded.With(labels).Set(1)
+		} else {
+			metricLinkDegraded.With(labels).Set(0)
+		}
+
+		c.prevMu.Lock()
+		prevDegraded := c.prevDegraded[uuid]
+		prevReplays, hasPrevReplays := c.prevReplays[uuid]
+		c.prevDegraded[uuid] = link.Degraded()
+		c.prevReplays[uuid] = link.ReplayCounter
+		c.prevMu.Unlock()
+
+		degraded := uint64(0)
+		if link.Degraded() {
+			degraded = 1
+		}
+		updates = append(updates, gpudstate.Counter{Device: uuid, Name: counterLinkDegraded, Value: degraded, Time: d.ts})
+
+		if link.Degraded() {
+			d.DegradedUUIDs = append(d.DegradedUUIDs, uuid)
+			if !prevDegraded {
+				events = append(events, apiv1.Event{
+					Time:    metav1.Time{Time: d.ts},
+					Name:    EventNameLinkDegraded,
+					Type:    apiv1.EventTypeWarning,
+					Message: fmt.Sprintf("GPU %s PCIe link trained at %s (max %s)", uuid, describeLink(link.CurrentGeneration, link.CurrentWidth), describeLink(link.MaxGeneration, link.MaxWidth)),
+					DeprecatedExtraInfo: map[string]string{
+						EventKeyGPUUUID: uuid,
+					},
+				})
+			}
+		}
+
+		// the counter may be reset (e.g., GPU reset), then only track the new baseline
+		if hasPrevReplays && link.ReplayCounter >= prevReplays {
+			delta := link.ReplayCounter - prevReplays
+			if thresholds.ReplayBurst > 0 && delta >= thresholds.ReplayBurst {
+				d.ReplayBurstUUIDs = append(d.ReplayBurstUUIDs, uuid)
+				events = append(events, apiv1.Event{
+					Time:    metav1.Time{Time: d.ts},
+					Name:    EventNameReplayBurst,
+					Type:    apiv1.EventTypeWarning,
+					Message: fmt.Sprintf("GPU %s PCIe replay counter increased by %d (threshold %d)", uuid, delta, thresholds.ReplayBurst),
+					DeprecatedExtraInfo: map[string]string{
+						EventKeyGPUUUID: uuid,
+					},
+				})
+			}
+		}
+	}
+
+	if c.eventBucket != nil {
+		for _, ev := range events {
+			if err := c.eventBucket.Insert(c.ctx, ev); err != nil {
+				log.Logger.Errorw("failed to insert event", "error", err)
+
+				d.err = err
+				d.health = apiv1.HealthStateTypeUnhealthy
+				d.reason = fmt.Sprintf("error inserting event: %s", err)
+				return d
+			}
+		}
+	}
+
+	// persisted after the events are inserted, so that the link state
+	// is never persisted without its event (e.g., insertion failed)
+	if c.dbRW != nil {
+		cctx, ccancel := context.WithTimeout(c.ctx, 30*time.Second)
+		err := gpudstate.UpdateCounters(cctx, c.dbRW, Name, updates)
+		ccancel()
+		if err != nil {
+			log.Logger.Errorw("error updating pcie link states", "error", err)
+
+			d.err = err
+			d.health = apiv1.HealthStateTypeUnhealthy
+			d.reason = fmt.Sprintf("error updating pcie link states: %s", err)
+			return d
+		}
+	}
+
+	if c.eventBucket != nil {
+
+		// replay bursts are transient, so evaluate the recent ones
+		// rather than only the last check
+		cctx, ccancel := context.WithTimeout(c.ctx, 15*time.Second)
+		recent, err := c.eventBucket.Get(cctx, d.ts.Add(-thresholds.Window.Duration))
+		ccancel()
+		if err != nil {
+			d.err = err
+			d.health = apiv1.HealthStateTypeUnhealthy
+			d.reason = fmt.Sprintf("error getting events: %s", err)
+			return d
+		}
+		burstUUIDs := make(map[string]struct{})
+		for _, ev := range recent {
+			if ev.Name == EventNameReplayBurst {
+				burstUUIDs[ev.DeprecatedExtraInfo[EventKeyGPUUUID]] = struct{}{}
+			}
+		}
+		d.ReplayBurstUUIDs = d.ReplayBurstUUIDs[:0]
+		for uuid := range burstUUIDs {
+			d.ReplayBurstUUIDs = append(d.ReplayBurstUUIDs, uuid)
+		}
+		sort.Strings(d.ReplayBurstUUIDs)
+	}
+
+	issues := make([]string, 0)
+	if len(d.DegradedUUIDs) > 0 {
+		issues = append(issues, fmt.Sprintf("%d gpu(s) with degraded pcie link (%s)", len(d.DegradedUUIDs), strings.Join(d.DegradedUUIDs, ", ")))
+	}
+	if len(d.ReplayBurstUUIDs) > 0 {
+		issues = append(issues, fmt.Sprintf("%d gpu(s) with pcie replay bursts (%s)", len(d.ReplayBurstUUIDs), strings.Join(d.ReplayBurstUUIDs, ", ")))
+	}
+
+	if len(issues) == 0 {
+		d.health = apiv1.HealthStateTypeHealthy
+		d.reason = fmt.Sprintf("all %d gpu(s) were checked, no pcie link issue found", len(d.PCIeLinks))
+		return d
+	}
+
+	d.health = apiv1.HealthStateTypeDegraded
+	d.reason = strings.Join(issues, "; ")
+	d.suggestedActions = &apiv1.SuggestedActions{
+		RepairActions: []apiv1.RepairActionType{
+			apiv1.RepairActionTypeHardwareInspection,
+		},
+		DeprecatedDescriptions: []string{
+			"Downtrained PCIe links or replay bursts are often caused by a bad riser, cable, or slot, please do a hardware inspection to mitigate the issue",
+		},
+	}
+
+	return d
+}
+
+// loadPrevDegraded loads the link states persisted before the restart, if not yet loaded.
+func (c *component) loadPrevDegraded() error {
+	c.prevMu.Lock()
+	defer c.prevMu.Unlock()
+
+	if c.prevLoaded || c.dbRO == nil {
+		c.prevLoaded = true
+		return nil
+	}
+
+	cctx, ccancel := context.WithTimeout(c.ctx, 30*time.Second)
+	loaded, err := gpudstate.LoadCounters(cctx, c.dbRO, Name)
+	ccancel()
+	if err != nil {
+		return err
+	}
+	for dev, counters := range loaded {
+		if cnt, ok := counters[counterLinkDegraded]; ok {
+			c.prevDegraded[dev] = cnt.Value > 0
+		}
+	}
+	c.prevLoaded = true
+	return nil
+}
+
+// describeLink returns the link in the form of "Gen5 x16".
+func describeLink(gen int, width int) string {
+	return fmt.Sprintf("Gen%d x%d", gen, width)
+}
+
+var _ components.CheckResult = &Data{}
+
+type Data struct {
+	PCIeLinks []nvidianvml.PCIeLink `json:"pcie_links,omitempty"`
+
+	// DegradedUUIDs is the GPUs with the link trained below its maximum.
+	DegradedUUIDs []string `json:"degraded_uuids,omitempty"`
+	// ReplayBurstUUIDs is the GPUs with the replay bursts
+	// within the evaluation window.
+	ReplayBurstUUIDs []string `json:"replay_burst_uuids,omitempty"`
+
+	// timestamp of the last check
+	ts time.Time
+	// error from the last check
+	err error
+
+	// tracks the healthy evaluation result of the last check
+	health apiv1.HealthStateType
+	// tracks the reason of the last check
+	reason string
+	// tracks the suggested actions of the last check
+	suggestedActions *apiv1.SuggestedActions
+}
+
+func (d *Data) String() string {
+	if d == nil {
+		return ""
+	}
+	if len(d.PCIeLinks) == 0 {
+		return "no data"
+	}
+
+	buf := bytes.NewBuffer(nil)
+	table := tablewriter.NewWriter(buf)
+	table.SetAlignment(tablewriter.ALIGN_CENTER)
+	table.SetHeader([]string{"GPU UUID", "Bus ID", "Current", "Max", "P-State", "Replays"})
+	for _, link := range d.PCIeLinks {
+		table.Append([]string{
+			link.UUID,
+			link.BusID,
+			describeLink(link.CurrentGeneration, link.CurrentWidth),
+			describeLink(link.MaxGeneration, link.MaxWidth),
+			fmt.Sprintf("P%d", link.PerformanceState),
+			fmt.Sprintf("%d", link.ReplayCounter),
+		})
+	}
+	table.Render()
+
+	return buf.String()
+}
+
+func (d *Data) Summary() string {
+	if d == nil {
+		return ""
+	}
+	return d.reason
+}
+
+func (d *Data) HealthState() apiv1.HealthStateType {
+	if d == nil {
+		return ""
+	}
+	return d.health
+}
+
+func (d *Data) getError() string {
+	if d == nil || d.err == nil {
+		return ""
+	}
+	return d.err.Error()
+}
+
+func (d *Data) getLastHealthStates() apiv1.HealthStates {
+	if d == nil {
+		return apiv1.HealthStates{
+			{
+				Name:   Name,
+				Health: apiv1.HealthStateTypeHealthy,
+				Reason: "no data yet",
+			},
+		}
+	}
+
+	state := apiv1.HealthState{
+		Name:             Name,
+		Reason:           d.reason,
+		Error:            d.getError(),
+		Health:           d.health,
+		SuggestedActions: d.suggestedActions,
+	}
+
+	b, _ := json.Marshal(d)
+	state.DeprecatedExtraInfo = map[string]string{
+		"data":     string(b),
+		"encoding": "json",
+	}
+	return apiv1.HealthStates{state}
+}
@@ -0,0 +1,348 @@
+package pcie
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/NVIDIA/go-nvlib/pkg/nvlib/device"
+	"github.com/NVIDIA/go-nvml/pkg/nvml/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	apiv1 "github.com/leptonai/gpud/api/v1"
+	"github.com/leptonai/gpud/components"
+	"github.com/leptonai/gpud/pkg/eventstore"
+	gpudstate "github.com/leptonai/gpud/pkg/gpud-state"
+	nvidianvml "github.com/leptonai/gpud/pkg/nvidia-query/nvml"
+	"github.com/leptonai/gpud/pkg/nvidia-query/nvml/lib"
+	"github.com/leptonai/gpud/pkg/nvidia-query/nvml/testutil"
+	"github.com/leptonai/gpud/pkg/sqlite"
+)
+
+type mockNVMLInstance struct {
+	devices map[string]device.Device
+}
+
+func (m *mockNVMLInstance) Devices() map[string]device.Device { return m.devices }
+func (m *mockNVMLInstance) ProductName() string               { return "NVIDIA Test GPU" }
+func (m *mockNVMLInstance) GetMemoryErrorManagementCapabilities() nvidianvml.MemoryErrorManagementCapabilities {
+	return nvidianvml.MemoryErrorManagementCapabilities{}
+}
+func (m *mockNVMLInstance) NVMLExists() bool     { return true }
+func (m *mockNVMLInstance) Library() lib.Library { return nil }
+func (m *mockNVMLInstance) Shutdown() error      { return nil }
+
+// MockPCIeComponent creates a component with mocked functions for testing
+func MockPCIeComponent(ctx context.Context, links map[string]*nvidianvml.PCIeLink) *component {
+	cctx, cancel := context.WithCancel(ctx)
+
+	devs := make(map[string]device.Device)
+	for uuid := range links {
+		devs[uuid] = testutil.NewMockDevice(&mock.Device{}, "hopper", "Nvidia", "9.0", "0000:19:00.0")
+	}
+
+	return &component{
+		ctx:          cctx,
+		cancel:       cancel,
+		nvmlInstance: &mockNVMLInstance{devices: devs},
+		getPCIeLinkFunc: func(uuid string, dev device.Device) (nvidianvml.PCIeLink, error) {
+			return *links[uuid], nil
+		},
+		getThresholdsFunc: GetDefaultThresholds,
+		prevDegraded:      make(map[string]bool),
+		prevReplays:       make(map[string]uint64),
+	}
+}
+
+func fullLink(uuid string) *nvidianvml.PCIeLink {
+	return &nvidianvml.PCIeLink{
+		UUID:              uuid,
+		CurrentGeneration: 5,
+		MaxGeneration:     5,
+		CurrentWidth:      16,
+		MaxWidth:          16,
+		Supported:         true,
+	}
+}
+
+func TestCheckLinkStatesAndReplays(t *testing.T) {
+	t.Parallel()
+
+	type step struct {
+		// mutates the links before the check
+		update func(links map[string]*nvidianvml.PCIeLink)
+		// re-creates the component from the persisted link states (e.g., gpud restart)
+		restart bool
+		// disables the evaluation window, to only evaluate the current check
+		noEvaluationWindow bool
+
+		expectedHealth apiv1.HealthStateType
+		verify         func(t *testing.T, c *component, d *Data)
+	}
+
+	tests := []struct {
+		name  string
+		links func() map[string]*nvidianvml.PCIeLink
+		// no event store nor state database
+		noStore bool
+		// overrides the default thresholds, if set
+		thresholds *Thresholds
+		steps      []step
+		// expected number of the events at the end
+		expectedEvents int
+	}{
+		{
+			name: "link degraded",
+			links: func() map[string]*nvidianvml.PCIeLink {
+				return map[string]*nvidianvml.PCIeLink{"GPU-1": fullLink("GPU-1"), "GPU-2": fullLink("GPU-2")}
+			},
+			steps: []step{
+				{
+					expectedHealth: apiv1.HealthStateTypeHealthy,
+					verify: func(t *testing.T, c *component, d *Data) {
+						assert.Len(t, d.PCIeLinks, 2)
+					},
+				},
+				{
+					// downtrained to x8
+					update:         func(links map[string]*nvidianvml.PCIeLink) { links["GPU-2"].CurrentWidth = 8 },
+					expectedHealth: apiv1.HealthStateTypeDegraded,
+					verify: func(t *testing.T, c *component, d *Data) {
+						assert.Equal(t, []string{"GPU-2"}, d.DegradedUUIDs)
+
+						states := c.LastHealthStates()
+						require.Len(t, states, 1)
+						require.NotNil(t, states[0].SuggestedActions)
+						assert.Equal(t, []apiv1.RepairActionType{apiv1.RepairActionTypeHardwareInspection}, states[0].SuggestedActions.RepairActions)
+					},
+				},
+				{
+					// event only inserted once
+					expectedHealth: apiv1.HealthStateTypeDegraded,
+					verify: func(t *testing.T, c *component, d *Data) {
+						events, err := c.Events(context.Background(), time.Now().Add(-time.Minute))
+						require.NoError(t, err)
+						require.Len(t, events, 1)
+						assert.Equal(t, EventNameLinkDegraded, events[0].Name)
+						assert.Equal(t, "GPU GPU-2 PCIe link trained at Gen5 x8 (max Gen5 x16)", events[0].Message)
+						assert.Equal(t, "GPU-2", events[0].DeprecatedExtraInfo[EventKeyGPUUUID])
+					},
+				},
+			},
+			expectedEvents: 1,
+		},
+		{
+			name: "link degraded after restart",
+			links: func() map[string]*nvidianvml.PCIeLink {
+				link := fullLink("GPU-1")
+				link.CurrentWidth = 8
+				return map[string]*nvidianvml.PCIeLink{"GPU-1": link}
+			},
+			steps: []step{
+				{expectedHealth: apiv1.HealthStateTypeDegraded},
+				{
+					// restarted with the link still downtrained
+					restart:        true,
+					expectedHealth: apiv1.HealthStateTypeDegraded,
+					verify: func(t *testing.T, c *component, d *Data) {
+						assert.Equal(t, []string{"GPU-1"}, d.DegradedUUIDs)
+
+						events, err := c.Events(context.Background(), time.Now().Add(-time.Minute))
+						require.NoError(t, err)
+						assert.Len(t, events, 1)
+					},
+				},
+				{
+					// recovered, then degraded again
+					update:         func(links map[string]*nvidianvml.PCIeLink) { links["GPU-1"].CurrentWidth = 16 },
+					expectedHealth: apiv1.HealthStateTypeHealthy,
+				},
+				{
+					update:         func(links map[string]*nvidianvml.PCIeLink) { links["GPU-1"].CurrentWidth = 8 },
+					expectedHealth: apiv1.HealthStateTypeDegraded,
+				},
+			},
+			expectedEvents: 2,
+		},
+		{
+			name: "replay burst",
+			links: func() map[string]*nvidianvml.PCIeLink {
+				link := fullLink("GPU-1")
+				link.ReplayCounter = 1000
+				return map[string]*nvidianvml.PCIeLink{"GPU-1": link}
+			},
+			steps: []step{
+				// the first check only records the baseline
+				{expectedHealth: apiv1.HealthStateTypeHealthy},
+				{
+					// below the threshold
+					update:         func(links map[string]*nvidianvml.PCIeLink) { links["GPU-1"].ReplayCounter = 1010 },
+					expectedHealth: apiv1.HealthStateTypeHealthy,
+				},
+				{
+					update: func(links map[string]*nvidianvml.PCIeLink) {
+						links["GPU-1"].ReplayCounter = 1010 + DefaultReplayBurstThreshold
+					},
+					expectedHealth: apiv1.HealthStateTypeDegraded,
+					verify: func(t *testing.T, c *component, d *Data) {
+						assert.Equal(t, []string{"GPU-1"}, d.ReplayBurstUUIDs)
+					},
+				},
+				{
+					// still degraded within the evaluation window
+					expectedHealth: apiv1.HealthStateTypeDegraded,
+					verify: func(t *testing.T, c *component, d *Data) {
+						assert.Equal(t, []string{"GPU-1"}, d.ReplayBurstUUIDs)
+					},
+				},
+				{
+					// counter reset
+					update:             func(links map[string]*nvidianvml.PCIeLink) { links["GPU-1"].ReplayCounter = 0 },
+					noEvaluationWindow: true,
+					expectedHealth:     apiv1.HealthStateTypeHealthy,
+				},
+			},
+			expectedEvents: 1,
+		},
+		{
+			name: "custom replay burst threshold",
+			links: func() map[string]*nvidianvml.PCIeLink {
+				return map[string]*nvidianvml.PCIeLink{"GPU-1": fullLink("GPU-1")}
+			},
+			thresholds: &Thresholds{ReplayBurst: 10, Window: metav1.Duration{Duration: time.Minute}},
+			steps: []step{
+				{expectedHealth: apiv1.HealthStateTypeHealthy},
+				{
+					update:         func(links map[string]*nvidianvml.PCIeLink) { links["GPU-1"].ReplayCounter = 10 },
+					expectedHealth: apiv1.HealthStateTypeDegraded,
+					verify: func(t *testing.T, c *component, d *Data) {
+						assert.Equal(t, []string{"GPU-1"}, d.ReplayBurstUUIDs)
+					},
+				},
+			},
+			expectedEvents: 1,
+		},
+		{
+			name: "replay burst disabled",
+			links: func() map[string]*nvidianvml.PCIeLink {
+				return map[string]*nvidianvml.PCIeLink{"GPU-1": fullLink("GPU-1")}
+			},
+			thresholds: &Thresholds{Window: metav1.Duration{Duration: time.Minute}},
+			steps: []step{
+				{expectedHealth: apiv1.HealthStateTypeHealthy},
+				{
+					update: func(links map[string]*nvidianvml.PCIeLink) {
+						links["GPU-1"].ReplayCounter = 10 * DefaultReplayBurstThreshold
+					},
+					expectedHealth: apiv1.HealthStateTypeHealthy,
+				},
+			},
+		},
+		{
+			name: "replay burst without event store",
+			links: func() map[string]*nvidianvml.PCIeLink {
+				return map[string]*nvidianvml.PCIeLink{"GPU-1": fullLink("GPU-1")}
+			},
+			noStore: true,
+			steps: []step{
+				{expectedHealth: apiv1.HealthStateTypeHealthy},
+				{
+					update: func(links map[string]*nvidianvml.PCIeLink) {
+						links["GPU-1"].ReplayCounter = DefaultReplayBurstThreshold
+					},
+					expectedHealth: apiv1.HealthStateTypeDegraded,
+					verify: func(t *testing.T, c *component, d *Data) {
+						assert.Equal(t, []string{"GPU-1"}, d.ReplayBurstUUIDs)
+					},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			dbRW, dbRO, cleanup := sqlite.OpenTestDB(t)
+			defer cleanup()
+			require.NoError(t, gpudstate.CreateTableCounters(ctx, dbRW))
+
+			eventStore, err := eventstore.New(dbRW, dbRO, 0)
+			require.NoError(t, err)
+
+			links := tt.links()
+			newComponent := func() *component {
+				c := MockPCIeComponent(ctx, links)
+				if tt.thresholds != nil {
+					thresholds := *tt.thresholds
+					c.getThresholdsFunc = func() Thresholds { return thresholds }
+				}
+				if !tt.noStore {
+					c.dbRW, c.dbRO = dbRW, dbRO
+					c.eventBucket, err = eventStore.Bucket(Name)
+					require.NoError(t, err)
+				}
+				return c
+			}
+
+			c := newComponent()
+			for i, s := range tt.steps {
+				if s.update != nil {
+					s.update(links)
+				}
+				if s.restart {
+					c = newComponent()
+				}
+				if s.noEvaluationWindow {
+					c.getThresholdsFunc = func() Thresholds {
+						return Thresholds{ReplayBurst: DefaultReplayBurstThreshold}
+					}
+				}
+
+				d := c.Check().(*Data)
+				assert.Equal(t, s.expectedHealth, d.HealthState(), "step %d: %s", i, d.Summary())
+				if s.verify != nil {
+					s.verify(t, c, d)
+				}
+			}
+
+			events, err := c.Events(ctx, time.Now().Add(-time.Minute))
+			require.NoError(t, err)
+			assert.Len(t, events, tt.expectedEvents)
+		})
+	}
+}
+
+func TestCheckError(t *testing.T) {
+	t.Parallel()
+
+	links := map[string]*nvidianvml.PCIeLink{
+		"GPU-1": fullLink("GPU-1"),
+	}
+	c := MockPCIeComponent(context.Background(), links)
+	defer c.Close()
+
+	c.getPCIeLinkFunc = func(uuid string, dev device.Device) (nvidianvml.PCIeLink, error) {
+		return nvidianvml.PCIeLink{}, errors.New("test error")
+	}
+	d := c.Check().(*Data)
+	assert.Equal(t, apiv1.HealthStateTypeUnhealthy, d.HealthState())
+	assert.Equal(t, "test error", d.getError())
+}
+
+func TestCheckNilNVML(t *testing.T) {
+	t.Parallel()
+
+	c, err := New(&components.GPUdInstance{RootCtx: context.Background()})
+	require.NoError(t, err)
+	defer c.Close()
+
+	d := c.Check().(*Data)
+	assert.Equal(t, apiv1.HealthStateTypeHealthy, d.HealthState())
+	assert.Equal(t, "NVIDIA NVML instance is nil", d.Summary())
+	assert.Equal(t, "no data", d.String())
+}
@@ -0,0 +1,86 @@
+package pcie
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	pkgmetrics "github.com/leptonai/gpud/pkg/metrics"
+)
+
+const SubSystem = "accelerator_nvidia_pcie"
+
+var (
+	componentLabel = prometheus.Labels{
+		pkgmetrics.MetricComponentLabelKey: Name,
+	}
+
+	metricCurrentLinkGeneration = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "",
+			Subsystem: SubSystem,
+			Name:      "current_link_generation",
+			Help:      "tracks the current PCIe link generation",
+		},
+		[]string{pkgmetrics.MetricComponentLabelKey, pkgmetrics.MetricLabelKey}, // label is GPU ID
+	).MustCurryWith(componentLabel)
+
+	metricMaxLinkGeneration = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "",
+			Subsystem: SubSystem,
+			Name:      "max_link_generation",
+			Help:      "tracks the maximum PCIe link generation possible with the GPU and system",
+		},
+		[]string{pkgmetrics.MetricComponentLabelKey, pkgmetrics.MetricLabelKey}, // label is GPU ID
+	).MustCurryWith(componentLabel)
+
+	metricCurrentLinkWidth = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "",
+			Subsystem: SubSystem,
+			Name:      "current_link_width",
+			Help:      "tracks the current PCIe link width",
+		},
+		[]string{pkgmetrics.MetricComponentLabelKey, pkgmetrics.MetricLabelKey}, // label is GPU ID
+	).MustCurryWith(componentLabel)
+
+	metricMaxLinkWidth = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "",
+			Subsystem: SubSystem,
+			Name:      "max_link_width",
+			Help:      "tracks the maximum PCIe link width possible with the GPU and system",
+		},
+		[]string{pkgmetrics.MetricComponentLabelKey, pkgmetrics.MetricLabelKey}, // label is GPU ID
+	).MustCurryWith(componentLabel)
+
+	metricLinkDegraded = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "",
+			Subsystem: SubSystem,
+			Name:      "link_degraded",
+			Help:      "tracks whether the PCIe link is trained below its maximum generation or width (1 if degraded)",
+		},
+		[]string{pkgmetrics.MetricComponentLabelKey, pkgmetrics.MetricLabelKey}, // label is GPU ID
+	).MustCurryWith(componentLabel)
+
+	metricReplayCounter = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "",
+			Subsystem: SubSystem,
+			Name:      "replay_counter",
+			Help:      "tracks the cumulative PCIe replay counter",
+		},
+		[]string{pkgmetrics.MetricComponentLabelKey, pkgmetrics.MetricLabelKey}, // label is GPU ID
+	).MustCurryWith(componentLabel)
+)
+
+func init() {
+	pkgmetrics.MustRegister(
+		metricCurrentLinkGeneration,
+		metricMaxLinkGeneration,
+		metricCurrentLinkWidth,
+		metricMaxLinkWidth,
+		metricLinkDegraded,
+		metricReplayCounter,
+	)
+}
@@ -0,0 +1,42 @@
+package pcie
+
+import (
+	"sync"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/leptonai/gpud/pkg/log"
+)
+
+// Thresholds defines the PCIe replay bursts to evaluate the health state.
+type Thresholds struct {
+	// ReplayBurst is the number of the PCIe replays of a GPU between two checks
+	// to be considered a replay burst, and mark the component degraded.
+	// Zero disables the replay burst detection.
+	ReplayBurst uint64 `json:"replay_burst"`
+	// Window is the time window to evaluate the replay bursts in.
+	// If zero, only the replay bursts of the last check are evaluated.
+	Window metav1.Duration `json:"window"`
+}
+
+var (
+	defaultThresholdsMu sync.RWMutex
+	defaultThresholds   = Thresholds{
+		ReplayBurst: DefaultReplayBurstThreshold,
+		Window:      metav1.Duration{Duration: DefaultEvaluationWindow},
+	}
+)
+
+func GetDefaultThresholds() Thresholds {
+	defaultThresholdsMu.RLock()
+	defer defaultThresholdsMu.RUnlock()
+	return defaultThresholds
+}
+
+func SetDefaultThresholds(thresholds Thresholds) {
+	log.Logger.Infow("setting default pcie thresholds", "replay_burst", thresholds.ReplayBurst, "window", thresholds.Window.Duration)
+
+	defaultThresholdsMu.Lock()
+	defer defaultThresholdsMu.Unlock()
+	defaultThresholds = thresholds
+}
@@ -2,6 +2,9 @@ package peermem
 
 import (
 	"regexp"
+
+	apiv1 "github.com/leptonai/gpud/api/v1"
+	"github.com/leptonai/gpud/pkg/kmsg"
 )
 
 const (
@@ -27,12 +30,20 @@ func HasPeermemInvalidContext(line string) bool {
 }
 
 func Match(line string) (eventName string, message string) {
+	ev := MatchEvent(line)
+	if ev == nil {
+		return "", ""
+	}
+	return ev.Name, ev.Message
+}
+
+func MatchEvent(line string) *kmsg.MatchedEvent {
 	for _, m := range getMatches() {
 		if m.check(line) {
-			return m.eventName, m.message
+			return kmsg.NewMatchedEvent(m.eventName, m.message, m.eventType, m.repairActions)
 		}
 	}
-	return "", ""
+	return nil
 }
 
 type match struct {
@@ -40,10 +51,13 @@ type match struct {
 	eventName string
 	regex     string
 	message   string
+
+	eventType     apiv1.EventType
+	repairActions []apiv1.RepairActionType
 }
 
 func getMatches() []match {
 	return []match{
-		{check: HasPeermemInvalidContext, eventName: eventPeermemInvalidContext, regex: regexPeermemInvalidContext, message: messagePeermemInvalidContext},
+		{check: HasPeermemInvalidContext, eventName: eventPeermemInvalidContext, regex: regexPeermemInvalidContext, message: messagePeermemInvalidContext, eventType: apiv1.EventTypeWarning, repairActions: []apiv1.RepairActionType{apiv1.RepairActionTypeCheckUserAppAndGPU}},
 	}
 }
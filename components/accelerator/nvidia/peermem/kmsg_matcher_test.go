@@ -1,6 +1,10 @@
 package peermem
 
-import "testing"
+import (
+	"testing"
+
+	apiv1 "github.com/leptonai/gpud/api/v1"
+)
 
 func TestHasPeermemInvalidContext(t *testing.T) {
 	tests := []struct {
@@ -136,3 +140,23 @@ func TestGetMatches(t *testing.T) {
 		t.Error("check function matched invalid input")
 	}
 }
+
+func TestMatchEvent(t *testing.T) {
+	ev := MatchEvent("nvidia-peermem nv_get_p2p_free_callback:127 ERROR detected invalid context, skipping further processing")
+	if ev == nil {
+		t.Fatal("MatchEvent() = nil, want event")
+	}
+	if ev.Name != eventPeermemInvalidContext || ev.Message != messagePeermemInvalidContext {
+		t.Errorf("MatchEvent() = %v %v, want %v %v", ev.Name, ev.Message, eventPeermemInvalidContext, messagePeermemInvalidContext)
+	}
+	if ev.Type != apiv1.EventTypeWarning {
+		t.Errorf("MatchEvent() type = %v, want %v", ev.Type, apiv1.EventTypeWarning)
+	}
+	if ev.SuggestedActions == nil || len(ev.SuggestedActions.RepairActions) != 1 || ev.SuggestedActions.RepairActions[0] != apiv1.RepairActionTypeCheckUserAppAndGPU {
+		t.Errorf("MatchEvent() suggested actions = %+v, want %v", ev.SuggestedActions, apiv1.RepairActionTypeCheckUserAppAndGPU)
+	}
+
+	if ev := MatchEvent("no match"); ev != nil {
+		t.Errorf("MatchEvent() = %+v, want nil", ev)
+	}
+}
@@ -1,4 +1,5 @@
-// Package power tracks the NVIDIA per-GPU power usage.
+// Package power tracks the NVIDIA per-GPU power usage and analyzes
+// how much time each GPU spends throttled by power or thermal clock events.
 package power
 
 import (
@@ -6,6 +7,8 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -20,7 +23,23 @@ import (
 	nvidianvml "github.com/leptonai/gpud/pkg/nvidia-query/nvml"
 )
 
-const Name = "accelerator-nvidia-power"
+const (
+	Name = "accelerator-nvidia-power"
+
+	// DefaultThrottleEvaluationWindow is the window to evaluate the share of time
+	// each GPU spends in each clock event reason.
+	DefaultThrottleEvaluationWindow = 10 * time.Minute
+
+	// DefaultThrottleSampleInterval is the interval to sample the clock event reasons.
+	// The clock event reasons change much faster than the check interval,
+	// so the samples are collected in between the checks.
+	DefaultThrottleSampleInterval = 5 * time.Second
+
+	// DefaultMinThrottleSamples is the minimum number of samples in the window
+	// to evaluate the throttled fraction, to avoid flagging a GPU from a single
+	// observation (e.g., "gpud scan").
+	DefaultMinThrottleSamples = 10
+)
 
 var _ components.Component = &component{}
 
@@ -28,8 +47,18 @@ type component struct {
 	ctx    context.Context
 	cancel context.CancelFunc
 
-	nvmlInstance nvidianvml.InstanceV2
-	getPowerFunc func(uuid string, dev device.Device) (nvidianvml.Power, error)
+	nvmlInstance       nvidianvml.InstanceV2
+	getPowerFunc       func(uuid string, dev device.Device) (nvidianvml.Power, error)
+	getClockEventsFunc func(uuid string, dev device.Device) (nvidianvml.ClockEvents, error)
+	getThresholdsFunc  func() Thresholds
+
+	evaluationWindow time.Duration
+	sampleInterval   time.Duration
+	minSamples       int
+
+	// tracks the clock event reason samples of each GPU within the evaluation window
+	samplesMu sync.Mutex
+	samples   map[string][]throttleSample
 
 	lastMu   sync.RWMutex
 	lastData *Data
@@ -38,10 +67,16 @@ type component struct {
 func New(gpudInstance *components.GPUdInstance) (components.Component, error) {
 	cctx, ccancel := context.WithCancel(gpudInstance.RootCtx)
 	c := &component{
-		ctx:          cctx,
-		cancel:       ccancel,
-		nvmlInstance: gpudInstance.NVMLInstance,
-		getPowerFunc: nvidianvml.GetPower,
+		ctx:                cctx,
+		cancel:             ccancel,
+		nvmlInstance:       gpudInstance.NVMLInstance,
+		getPowerFunc:       nvidianvml.GetPower,
+		getClockEventsFunc: nvidianvml.GetClockEvents,
+		getThresholdsFunc:  GetDefaultThresholds,
+		evaluationWindow:   DefaultThrottleEvaluationWindow,
+		sampleInterval:     DefaultThrottleSampleInterval,
+		minSamples:         DefaultMinThrottleSamples,
+		samples:            make(map[string][]throttleSample),
 	}
 	return c, nil
 }
@@ -63,9 +98,88 @@ func (c *component) Start() error {
 			}
 		}
 	}()
+
+	go func() {
+		ticker := time.NewTicker(c.sampleInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-c.ctx.Done():
+				return
+			case <-ticker.C:
+				c.sample()
+			}
+		}
+	}()
 	return nil
 }
 
+// sample records the current clock event reasons and power usage of all GPUs.
+func (c *component) sample() {
+	if c.nvmlInstance == nil || !c.nvmlInstance.NVMLExists() {
+		return
+	}
+
+	for uuid, dev := range c.nvmlInstance.Devices() {
+		power, err := c.getPowerFunc(uuid, dev)
+		if err != nil {
+			log.Logger.Debugw("error getting power for device", "uuid", uuid, "error", err)
+			continue
+		}
+		usedPct, err := power.GetUsedPercent()
+		if err != nil {
+			log.Logger.Debugw("error getting used percent for device", "uuid", uuid, "error", err)
+			continue
+		}
+		clockEvents, err := c.getClockEventsFunc(uuid, dev)
+		if err != nil {
+			log.Logger.Debugw("error getting clock events for device", "uuid", uuid, "error", err)
+			continue
+		}
+		c.recordSample(uuid, clockEvents, usedPct)
+	}
+}
+
+// recordSample appends the sample to the GPU and drops the samples
+// outside of the evaluation window.
+func (c *component) recordSample(uuid string, clockEvents nvidianvml.ClockEvents, usedPct float64) {
+	if !clockEvents.Supported {
+		return
+	}
+
+	now := clockEvents.Time.Time
+	if now.IsZero() {
+		now = time.Now().UTC()
+	}
+
+	c.samplesMu.Lock()
+	defer c.samplesMu.Unlock()
+
+	samples := append(c.samples[uuid], throttleSample{
+		time:        now,
+		reasons:     nvidianvml.ActiveClockEventReasons(clockEvents.ReasonsBitmask),
+		usedPercent: usedPct,
+	})
+
+	since := now.Add(-c.evaluationWindow)
+	i := 0
+	for i < len(samples) && samples[i].time.Before(since) {
+		i++
+	}
+	c.samples[uuid] = samples[i:]
+}
+
+// getSamples returns a copy of the samples of the GPU.
+func (c *component) getSamples(uuid string) []throttleSample {
+	c.samplesMu.Lock()
+	defer c.samplesMu.Unlock()
+
+	samples := make([]throttleSample, len(c.samples[uuid]))
+	copy(samples, c.samples[uuid])
+	return samples
+}
+
 func (c *component) LastHealthStates() apiv1.HealthStates {
 	c.lastMu.RLock()
 	lastData := c.lastData
@@ -134,6 +248,72 @@ func (c *component) Check() components.CheckResult {
 			return d
 		}
 		metricUsedPercent.With(prometheus.Labels{pkgmetrics.MetricLabelKey: uuid}).Set(usedPct)
+
+		clockEvents, err := c.getClockEventsFunc(uuid, dev)
+		if err != nil {
+			log.Logger.Errorw("error getting clock events for device", "uuid", uuid, "error", err)
+
+			d.err = err
+			d.health = apiv1.HealthStateTypeUnhealthy
+			d.reason = fmt.Sprintf("error getting clock events for device %s", uuid)
+			return d
+		}
+		c.recordSample(uuid, clockEvents, usedPct)
+	}
+
+	uuids := make([]string, 0, len(devs))
+	for uuid := range devs {
+		uuids = append(uuids, uuid)
+	}
+	sort.Strings(uuids)
+
+	thresholds := c.getThresholdsFunc()
+
+	var throttled []string
+	thermal := false
+	for _, uuid := range uuids {
+		summary := summarizeThrottling(uuid, c.getSamples(uuid))
+		if summary.Samples == 0 {
+			continue
+		}
+		d.Throttling = append(d.Throttling, summary)
+
+		for reason, fraction := range summary.ReasonFractions {
+			metricClockEventReasonFraction.With(prometheus.Labels{pkgmetrics.MetricLabelKey: uuid, metricReasonLabelKey: reason}).Set(fraction)
+		}
+		metricThrottledFraction.With(prometheus.Labels{pkgmetrics.MetricLabelKey: uuid}).Set(summary.ThrottledFraction)
+
+		if thresholds.DegradedThrottledFraction <= 0 || summary.Samples < c.minSamples {
+			continue
+		}
+		if summary.ThrottledFraction <= thresholds.DegradedThrottledFraction {
+			continue
+		}
+
+		d.ThrottledUUIDs = append(d.ThrottledUUIDs, uuid)
+		throttled = append(throttled, describeThrottling(summary))
+		if summary.Cause == ThrottleCauseThermal {
+			thermal = true
+		}
+	}
+
+	if len(throttled) > 0 {
+		d.health = apiv1.HealthStateTypeDegraded
+		d.reason = fmt.Sprintf("%d GPU(s) throttled for more than %.0f%% of the last %s (%s)",
+			len(throttled),
+			thresholds.DegradedThrottledFraction*100,
+			c.evaluationWindow,
+			strings.Join(throttled, "; "),
+		)
+
+		// power-capped GPUs are most likely limited by the configured power limit,
+		// whereas the thermal throttling indicates a cooling problem
+		if thermal {
+			d.suggestedActions = &apiv1.SuggestedActions{
+				RepairActions: []apiv1.RepairActionType{apiv1.RepairActionTypeHardwareInspection},
+			}
+		}
+		return d
 	}
 
 	d.health = apiv1.HealthStateTypeHealthy
@@ -142,11 +322,27 @@ func (c *component) Check() components.CheckResult {
 	return d
 }
 
+func describeThrottling(summary ThrottleSummary) string {
+	desc := fmt.Sprintf("%s throttled %.0f%% of the time", summary.UUID, summary.ThrottledFraction*100)
+	switch summary.Cause {
+	case ThrottleCausePower:
+		desc += fmt.Sprintf(" by power, drawing %.0f%% of the enforced limit while power capped", summary.PowerCappedUsedPercent)
+	case ThrottleCauseThermal:
+		desc += " by thermal slowdown"
+	}
+	return desc
+}
+
 var _ components.CheckResult = &Data{}
 
 type Data struct {
 	Powers []nvidianvml.Power `json:"powers,omitempty"`
 
+	// Throttling is the per-GPU clock event reason breakdown over the evaluation window.
+	Throttling []ThrottleSummary `json:"throttling,omitempty"`
+	// ThrottledUUIDs is the list of GPUs throttled for more than the threshold fraction.
+	ThrottledUUIDs []string `json:"throttled_uuids,omitempty"`
+
 	// timestamp of the last check
 	ts time.Time
 	// error from the last check
@@ -156,6 +352,8 @@ type Data struct {
 	health apiv1.HealthStateType
 	// tracks the reason of the last check
 	reason string
+	// tracks the suggested actions of the last check
+	suggestedActions *apiv1.SuggestedActions
 }
 
 func (d *Data) String() string {
@@ -180,6 +378,29 @@ func (d *Data) String() string {
 	}
 	table.Render()
 
+	if len(d.Throttling) > 0 {
+		buf.WriteString("\n")
+
+		header := []string{"GPU UUID", "Throttled %"}
+		for _, reason := range nvidianvml.ClockEventReasons {
+			header = append(header, reason+" %")
+		}
+		header = append(header, "Power capped used %", "Cause")
+
+		table = tablewriter.NewWriter(buf)
+		table.SetAlignment(tablewriter.ALIGN_CENTER)
+		table.SetHeader(header)
+		for _, summary := range d.Throttling {
+			row := []string{summary.UUID, fmt.Sprintf("%.1f", summary.ThrottledFraction*100)}
+			for _, reason := range nvidianvml.ClockEventReasons {
+				row = append(row, fmt.Sprintf("%.1f", summary.ReasonFractions[reason]*100))
+			}
+			row = append(row, fmt.Sprintf("%.1f", summary.PowerCappedUsedPercent), summary.Cause)
+			table.Append(row)
+		}
+		table.Render()
+	}
+
 	return buf.String()
 }
 
@@ -216,10 +437,11 @@ func (d *Data) getLastHealthStates() apiv1.HealthStates {
 	}
 
 	state := apiv1.HealthState{
-		Name:   Name,
-		Reason: d.reason,
-		Error:  d.getError(),
-		Health: d.health,
+		Name:             Name,
+		Reason:           d.reason,
+		Error:            d.getError(),
+		Health:           d.health,
+		SuggestedActions: d.suggestedActions,
 	}
 
 	b, _ := json.Marshal(d)
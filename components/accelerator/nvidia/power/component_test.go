@@ -13,6 +13,7 @@ import (
 	"github.com/NVIDIA/go-nvml/pkg/nvml/mock"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	apiv1 "github.com/leptonai/gpud/api/v1"
 	"github.com/leptonai/gpud/components"
@@ -33,6 +34,14 @@ func MockPowerComponent(
 		cancel:       cancel,
 		nvmlInstance: mockNvmlInstance,
 		getPowerFunc: getPowerFunc,
+		getClockEventsFunc: func(uuid string, dev device.Device) (nvidianvml.ClockEvents, error) {
+			return nvidianvml.ClockEvents{UUID: uuid, Supported: false}, nil
+		},
+		getThresholdsFunc: GetDefaultThresholds,
+		evaluationWindow:  DefaultThrottleEvaluationWindow,
+		sampleInterval:    DefaultThrottleSampleInterval,
+		minSamples:        DefaultMinThrottleSamples,
+		samples:           make(map[string][]throttleSample),
 	}
 }
 
@@ -408,3 +417,133 @@ func TestData_GetError(t *testing.T) {
 		})
 	}
 }
+
+func newThrottleTestComponent(t *testing.T, bitmask *uint64) *component {
+	uuid := "gpu-uuid-123"
+	mockDev := testutil.NewMockDevice(&mock.Device{}, "test-arch", "test-brand", "test-cuda", "test-pci")
+	mockNvml := &mockNvmlInstance{
+		devices: map[string]device.Device{uuid: mockDev},
+	}
+
+	getPowerFunc := func(uuid string, dev device.Device) (nvidianvml.Power, error) {
+		return nvidianvml.Power{
+			UUID:                    uuid,
+			UsageMilliWatts:         686000,
+			EnforcedLimitMilliWatts: 700000,
+			UsedPercent:             "98.00",
+		}, nil
+	}
+
+	c := MockPowerComponent(context.Background(), mockNvml, getPowerFunc).(*component)
+	c.getClockEventsFunc = func(uuid string, dev device.Device) (nvidianvml.ClockEvents, error) {
+		return nvidianvml.ClockEvents{
+			Time:           metav1.Time{Time: time.Now().UTC()},
+			UUID:           uuid,
+			ReasonsBitmask: *bitmask,
+			Supported:      true,
+		}, nil
+	}
+	c.getThresholdsFunc = func() Thresholds {
+		return Thresholds{DegradedThrottledFraction: 0.5}
+	}
+	c.minSamples = 4
+	return c
+}
+
+func TestCheck_PowerCapThrottled(t *testing.T) {
+	bitmask := uint64(0x4) // SW power cap
+	c := newThrottleTestComponent(t, &bitmask)
+	defer c.Close()
+
+	// not enough samples to evaluate yet
+	for i := 0; i < 3; i++ {
+		d := c.Check().(*Data)
+		assert.Equal(t, apiv1.HealthStateTypeHealthy, d.HealthState())
+		require.Len(t, d.Throttling, 1)
+		assert.Equal(t, i+1, d.Throttling[0].Samples)
+	}
+
+	d := c.Check().(*Data)
+	assert.Equal(t, apiv1.HealthStateTypeDegraded, d.HealthState())
+	assert.Equal(t, []string{"gpu-uuid-123"}, d.ThrottledUUIDs)
+	require.Len(t, d.Throttling, 1)
+	assert.Equal(t, ThrottleCausePower, d.Throttling[0].Cause)
+	assert.InDelta(t, 1.0, d.Throttling[0].ReasonFractions[nvidianvml.ClockEventReasonSWPowerCap], 1e-9)
+	assert.InDelta(t, 98, d.Throttling[0].PowerCappedUsedPercent, 1e-9)
+	assert.Contains(t, d.Summary(), "gpu-uuid-123 throttled 100% of the time by power, drawing 98% of the enforced limit while power capped")
+	assert.Contains(t, d.String(), "SW POWER CAP %")
+
+	// power capping is not a hardware issue
+	states := c.LastHealthStates()
+	require.Len(t, states, 1)
+	assert.Nil(t, states[0].SuggestedActions)
+
+	// the clocks recover, and the throttled fraction drops below the threshold
+	bitmask = 0
+	for i := 0; i < 5; i++ {
+		d = c.Check().(*Data)
+	}
+	assert.Equal(t, apiv1.HealthStateTypeHealthy, d.HealthState())
+	assert.InDelta(t, 4.0/9.0, d.Throttling[0].ThrottledFraction, 1e-9)
+}
+
+func TestCheck_ThermalThrottled(t *testing.T) {
+	bitmask := uint64(0x8 | 0x40) // HW slowdown + HW thermal slowdown
+	c := newThrottleTestComponent(t, &bitmask)
+	defer c.Close()
+
+	var d *Data
+	for i := 0; i < 4; i++ {
+		d = c.Check().(*Data)
+	}
+	assert.Equal(t, apiv1.HealthStateTypeDegraded, d.HealthState())
+	assert.Equal(t, ThrottleCauseThermal, d.Throttling[0].Cause)
+	assert.Contains(t, d.Summary(), "by thermal slowdown")
+
+	states := c.LastHealthStates()
+	require.Len(t, states, 1)
+	require.NotNil(t, states[0].SuggestedActions)
+	assert.Equal(t, []apiv1.RepairActionType{apiv1.RepairActionTypeHardwareInspection}, states[0].SuggestedActions.RepairActions)
+}
+
+func TestCheck_ThrottleThresholdDisabled(t *testing.T) {
+	bitmask := uint64(0x4)
+	c := newThrottleTestComponent(t, &bitmask)
+	defer c.Close()
+	c.getThresholdsFunc = func() Thresholds { return Thresholds{} }
+
+	var d *Data
+	for i := 0; i < 5; i++ {
+		d = c.Check().(*Data)
+	}
+	assert.Equal(t, apiv1.HealthStateTypeHealthy, d.HealthState())
+	assert.InDelta(t, 1.0, d.Throttling[0].ThrottledFraction, 1e-9)
+}
+
+func TestCheck_ThrottleSamplesOutsideWindow(t *testing.T) {
+	bitmask := uint64(0x4)
+	c := newThrottleTestComponent(t, &bitmask)
+	defer c.Close()
+
+	// samples older than the evaluation window are dropped
+	c.samples["gpu-uuid-123"] = []throttleSample{
+		{time: time.Now().Add(-time.Hour)},
+		{time: time.Now().Add(-time.Hour)},
+	}
+	d := c.Check().(*Data)
+	require.Len(t, d.Throttling, 1)
+	assert.Equal(t, 1, d.Throttling[0].Samples)
+}
+
+func TestCheck_ClockEventsError(t *testing.T) {
+	bitmask := uint64(0)
+	c := newThrottleTestComponent(t, &bitmask)
+	defer c.Close()
+	c.getClockEventsFunc = func(uuid string, dev device.Device) (nvidianvml.ClockEvents, error) {
+		return nvidianvml.ClockEvents{}, errors.New("clock events error")
+	}
+
+	d := c.Check().(*Data)
+	assert.Equal(t, apiv1.HealthStateTypeUnhealthy, d.HealthState())
+	assert.Equal(t, "error getting clock events for device gpu-uuid-123", d.Summary())
+}
@@ -6,7 +6,13 @@ import (
 	pkgmetrics "github.com/leptonai/gpud/pkg/metrics"
 )
 
-const SubSystem = "accelerator_nvidia_power"
+const (
+	SubSystem = "accelerator_nvidia_power"
+
+	// metricReasonLabelKey is the label key for the clock event reason
+	// (e.g., "sw_power_cap", "hw_thermal_slowdown").
+	metricReasonLabelKey = "reason"
+)
 
 var (
 	componentLabel = prometheus.Labels{
@@ -42,6 +48,26 @@ var (
 		},
 		[]string{pkgmetrics.MetricComponentLabelKey, pkgmetrics.MetricLabelKey}, // label is GPU ID
 	).MustCurryWith(componentLabel)
+
+	metricClockEventReasonFraction = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "",
+			Subsystem: SubSystem,
+			Name:      "clock_event_reason_fraction",
+			Help:      "tracks the fraction of the evaluation window that the clock event reason was active (0 to 1)",
+		},
+		[]string{pkgmetrics.MetricComponentLabelKey, pkgmetrics.MetricLabelKey, metricReasonLabelKey}, // label is GPU ID
+	).MustCurryWith(componentLabel)
+
+	metricThrottledFraction = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "",
+			Subsystem: SubSystem,
+			Name:      "throttled_fraction",
+			Help:      "tracks the fraction of the evaluation window that the GPU clocks were throttled by any power or thermal reason (0 to 1)",
+		},
+		[]string{pkgmetrics.MetricComponentLabelKey, pkgmetrics.MetricLabelKey}, // label is GPU ID
+	).MustCurryWith(componentLabel)
 )
 
 func init() {
@@ -49,5 +75,7 @@ func init() {
 		metricCurrentUsageMilliWatts,
 		metricEnforcedLimitMilliWatts,
 		metricUsedPercent,
+		metricClockEventReasonFraction,
+		metricThrottledFraction,
 	)
 }
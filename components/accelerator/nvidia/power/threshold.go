@@ -0,0 +1,37 @@
+package power
+
+import (
+	"sync"
+
+	"github.com/leptonai/gpud/pkg/log"
+)
+
+// Thresholds defines the clock throttling limits to evaluate the health state.
+// Zero disables the threshold.
+type Thresholds struct {
+	// DegradedThrottledFraction is the fraction (0 to 1) of the evaluation window
+	// that a GPU spends throttled (by any of the power or thermal clock event reasons)
+	// to mark the component degraded.
+	DegradedThrottledFraction float64 `json:"degraded_throttled_fraction"`
+}
+
+var (
+	defaultThresholdsMu sync.RWMutex
+	defaultThresholds   = Thresholds{
+		DegradedThrottledFraction: 0.5,
+	}
+)
+
+func GetDefaultThresholds() Thresholds {
+	defaultThresholdsMu.RLock()
+	defer defaultThresholdsMu.RUnlock()
+	return defaultThresholds
+}
+
+func SetDefaultThresholds(thresholds Thresholds) {
+	log.Logger.Infow("setting default power thresholds", "degraded_throttled_fraction", thresholds.DegradedThrottledFraction)
+
+	defaultThresholdsMu.Lock()
+	defer defaultThresholdsMu.Unlock()
+	defaultThresholds = thresholds
+}
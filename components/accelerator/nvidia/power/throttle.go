@@ -0,0 +1,122 @@
+package power
+
+import (
+	"time"
+
+	nvidianvml "github.com/leptonai/gpud/pkg/nvidia-query/nvml"
+)
+
+const (
+	// ThrottleCausePower is the throttle cause when the clocks are mostly
+	// reduced by the SW power cap or the HW power brake (e.g., power-capped racks).
+	ThrottleCausePower = "power"
+	// ThrottleCauseThermal is the throttle cause when the clocks are mostly
+	// reduced by the HW or SW thermal slowdown (e.g., cooling problems).
+	ThrottleCauseThermal = "thermal"
+)
+
+// throttlingReasons are the clock event reasons that count towards the throttled time.
+// Sync boost is excluded since it only aligns the clocks within a sync boost group.
+var throttlingReasons = map[string]struct{}{
+	nvidianvml.ClockEventReasonSWPowerCap:           {},
+	nvidianvml.ClockEventReasonHWSlowdown:           {},
+	nvidianvml.ClockEventReasonHWThermalSlowdown:    {},
+	nvidianvml.ClockEventReasonHWPowerBrakeSlowdown: {},
+	nvidianvml.ClockEventReasonSWThermalSlowdown:    {},
+}
+
+// throttleSample is a single observation of the active clock event reasons
+// and the power draw of a GPU.
+type throttleSample struct {
+	time time.Time
+	// active clock event reasons (see nvidianvml.ActiveClockEventReasons)
+	reasons []string
+	// power usage relative to the enforced limit in percent
+	usedPercent float64
+}
+
+// ThrottleSummary is the breakdown of the clock event reasons of a GPU
+// over the evaluation window.
+// The duration share of each reason is approximated by the share of
+// the samples (taken at a fixed interval) where the reason was active.
+type ThrottleSummary struct {
+	// UUID is the GPU UUID.
+	UUID string `json:"uuid"`
+	// Samples is the number of samples in the evaluation window.
+	Samples int `json:"samples"`
+	// ReasonFractions is the fraction of the evaluation window (0 to 1)
+	// that each clock event reason was active.
+	ReasonFractions map[string]float64 `json:"reason_fractions,omitempty"`
+	// ThrottledFraction is the fraction of the evaluation window (0 to 1)
+	// that any of the power or thermal clock event reasons was active.
+	ThrottledFraction float64 `json:"throttled_fraction"`
+	// PowerCappedUsedPercent is the average power usage relative to the enforced limit
+	// while the SW power cap was active. A value close to 100 means the GPU
+	// is capped by the configured power limit.
+	PowerCappedUsedPercent float64 `json:"power_capped_used_percent,omitempty"`
+	// Cause is the dominant throttle cause ("power" or "thermal"),
+	// empty if the GPU was not throttled by either.
+	Cause string `json:"cause,omitempty"`
+}
+
+func summarizeThrottling(uuid string, samples []throttleSample) ThrottleSummary {
+	summary := ThrottleSummary{
+		UUID:    uuid,
+		Samples: len(samples),
+	}
+	if len(samples) == 0 {
+		return summary
+	}
+
+	counts := make(map[string]int)
+	throttled, powerCapped, power, thermal := 0, 0, 0, 0
+	powerCappedUsedPercent := 0.0
+	for _, s := range samples {
+		isThrottled, isPower, isThermal := false, false, false
+		for _, reason := range s.reasons {
+			counts[reason]++
+
+			if _, ok := throttlingReasons[reason]; ok {
+				isThrottled = true
+			}
+			switch reason {
+			case nvidianvml.ClockEventReasonSWPowerCap:
+				powerCapped++
+				powerCappedUsedPercent += s.usedPercent
+				isPower = true
+			case nvidianvml.ClockEventReasonHWPowerBrakeSlowdown:
+				isPower = true
+			case nvidianvml.ClockEventReasonHWThermalSlowdown, nvidianvml.ClockEventReasonSWThermalSlowdown:
+				isThermal = true
+			}
+		}
+		if isThrottled {
+			throttled++
+		}
+		if isPower {
+			power++
+		}
+		if isThermal {
+			thermal++
+		}
+	}
+
+	total := float64(len(samples))
+	summary.ReasonFractions = make(map[string]float64, len(nvidianvml.ClockEventReasons))
+	for _, reason := range nvidianvml.ClockEventReasons {
+		summary.ReasonFractions[reason] = float64(counts[reason]) / total
+	}
+	summary.ThrottledFraction = float64(throttled) / total
+	if powerCapped > 0 {
+		summary.PowerCappedUsedPercent = powerCappedUsedPercent / float64(powerCapped)
+	}
+
+	switch {
+	case power > 0 && power >= thermal:
+		summary.Cause = ThrottleCausePower
+	case thermal > 0:
+		summary.Cause = ThrottleCauseThermal
+	}
+
+	return summary
+}
@@ -0,0 +1,53 @@
+package power
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	nvidianvml "github.com/leptonai/gpud/pkg/nvidia-query/nvml"
+)
+
+func TestSummarizeThrottling(t *testing.T) {
+	now := time.Now()
+
+	summary := summarizeThrottling("GPU-1", nil)
+	assert.Equal(t, ThrottleSummary{UUID: "GPU-1"}, summary)
+
+	// power capped 3 out of 4 samples, sync boost does not count as throttled
+	summary = summarizeThrottling("GPU-1", []throttleSample{
+		{time: now, reasons: []string{nvidianvml.ClockEventReasonSWPowerCap}, usedPercent: 98},
+		{time: now, reasons: []string{nvidianvml.ClockEventReasonSWPowerCap}, usedPercent: 100},
+		{time: now, reasons: []string{nvidianvml.ClockEventReasonSWPowerCap, nvidianvml.ClockEventReasonSyncBoost}, usedPercent: 96},
+		{time: now, reasons: []string{nvidianvml.ClockEventReasonSyncBoost}, usedPercent: 50},
+	})
+	assert.Equal(t, 4, summary.Samples)
+	assert.InDelta(t, 0.75, summary.ThrottledFraction, 1e-9)
+	assert.InDelta(t, 0.75, summary.ReasonFractions[nvidianvml.ClockEventReasonSWPowerCap], 1e-9)
+	assert.InDelta(t, 0.5, summary.ReasonFractions[nvidianvml.ClockEventReasonSyncBoost], 1e-9)
+	assert.Zero(t, summary.ReasonFractions[nvidianvml.ClockEventReasonHWThermalSlowdown])
+	assert.InDelta(t, 98, summary.PowerCappedUsedPercent, 1e-9)
+	assert.Equal(t, ThrottleCausePower, summary.Cause)
+
+	// thermal dominates
+	summary = summarizeThrottling("GPU-2", []throttleSample{
+		{time: now, reasons: []string{nvidianvml.ClockEventReasonHWSlowdown, nvidianvml.ClockEventReasonHWThermalSlowdown}, usedPercent: 60},
+		{time: now, reasons: []string{nvidianvml.ClockEventReasonSWThermalSlowdown}, usedPercent: 60},
+		{time: now, reasons: []string{nvidianvml.ClockEventReasonSWPowerCap}, usedPercent: 90},
+		{time: now, usedPercent: 40},
+	})
+	assert.InDelta(t, 0.75, summary.ThrottledFraction, 1e-9)
+	assert.InDelta(t, 0.25, summary.ReasonFractions[nvidianvml.ClockEventReasonHWThermalSlowdown], 1e-9)
+	assert.InDelta(t, 0.25, summary.ReasonFractions[nvidianvml.ClockEventReasonSWThermalSlowdown], 1e-9)
+	assert.InDelta(t, 90, summary.PowerCappedUsedPercent, 1e-9)
+	assert.Equal(t, ThrottleCauseThermal, summary.Cause)
+
+	// not throttled
+	summary = summarizeThrottling("GPU-3", []throttleSample{
+		{time: now, usedPercent: 40},
+	})
+	assert.Zero(t, summary.ThrottledFraction)
+	assert.Zero(t, summary.PowerCappedUsedPercent)
+	assert.Empty(t, summary.Cause)
+}
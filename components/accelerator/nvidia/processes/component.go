@@ -15,6 +15,7 @@ import (
 
 	apiv1 "github.com/leptonai/gpud/api/v1"
 	"github.com/leptonai/gpud/components"
+	"github.com/leptonai/gpud/pkg/container"
 	"github.com/leptonai/gpud/pkg/log"
 	pkgmetrics "github.com/leptonai/gpud/pkg/metrics"
 	nvidianvml "github.com/leptonai/gpud/pkg/nvidia-query/nvml"
@@ -30,6 +31,10 @@ type component struct {
 
 	nvmlInstance     nvidianvml.InstanceV2
 	getProcessesFunc func(uuid string, dev device.Device) (nvidianvml.Processes, error)
+	getMIGFunc       func(uuid string, dev device.Device) (nvidianvml.MIG, error)
+	// resolves the container (and the Kubernetes pod) of the process,
+	// nil to skip the container attribution
+	resolveContainerFunc func(ctx context.Context, pid uint32) *container.Identity
 
 	lastMu   sync.RWMutex
 	lastData *Data
@@ -42,6 +47,9 @@ func New(gpudInstance *components.GPUdInstance) (components.Component, error) {
 		cancel:           ccancel,
 		nvmlInstance:     gpudInstance.NVMLInstance,
 		getProcessesFunc: nvidianvml.GetProcesses,
+		getMIGFunc:       nvidianvml.GetMIG,
+
+		resolveContainerFunc: container.NewResolver(container.DefaultProcRoot).Resolve,
 	}
 	return c, nil
 }
@@ -108,6 +116,10 @@ func (c *component) Check() components.CheckResult {
 		return d
 	}
 
+	// per-container counters across all GPUs, keyed by the container name
+	containerProcs := make(map[string]int)
+	containerMemory := make(map[string]uint64)
+
 	devs := c.nvmlInstance.Devices()
 	for uuid, dev := range devs {
 		procs, err := c.getProcessesFunc(uuid, dev)
@@ -118,9 +130,37 @@ func (c *component) Check() components.CheckResult {
 			return d
 		}
 
+		c.resolveMIG(uuid, dev, &procs)
+		c.resolveContainers(&procs)
 		d.Processes = append(d.Processes, procs)
 
+		for _, proc := range procs.RunningProcesses {
+			if proc.Container == nil {
+				continue
+			}
+			containerProcs[proc.Container.Name()]++
+			containerMemory[proc.Container.Name()] += proc.GPUUsedMemoryBytes
+		}
+
 		metricRunningProcesses.With(prometheus.Labels{pkgmetrics.MetricLabelKey: uuid}).Set(float64(len(procs.RunningProcesses)))
+
+		migProcs := make(map[string]int)
+		for _, proc := range procs.RunningProcesses {
+			if proc.MIG != nil && proc.MIG.UUID != "" {
+				migProcs[proc.MIG.UUID]++
+			}
+		}
+		for migUUID, cnt := range migProcs {
+			metricMIGRunningProcesses.With(prometheus.Labels{pkgmetrics.MetricLabelKey: migUUID}).Set(float64(cnt))
+		}
+	}
+
+	// reset to drop the containers that are gone
+	metricContainerRunningProcesses.Reset()
+	metricContainerUsedMemoryBytes.Reset()
+	for name, cnt := range containerProcs {
+		metricContainerRunningProcesses.With(prometheus.Labels{pkgmetrics.MetricLabelKey: name}).Set(float64(cnt))
+		metricContainerUsedMemoryBytes.With(prometheus.Labels{pkgmetrics.MetricLabelKey: name}).Set(float64(containerMemory[name]))
 	}
 
 	d.health = apiv1.HealthStateTypeHealthy
@@ -129,6 +169,53 @@ func (c *component) Check() components.CheckResult {
 	return d
 }
 
+// resolveMIG attributes the processes to the MIG device UUIDs,
+// only when any process reports the GPU and compute instance IDs.
+func (c *component) resolveMIG(uuid string, dev device.Device, procs *nvidianvml.Processes) {
+	hasMIG := false
+	for _, proc := range procs.RunningProcesses {
+		if proc.MIG != nil {
+			hasMIG = true
+			break
+		}
+	}
+	if !hasMIG {
+		return
+	}
+
+	mig, err := c.getMIGFunc(uuid, dev)
+	if err != nil {
+		log.Logger.Warnw("error getting mig devices", "uuid", uuid, "error", err)
+		return
+	}
+	for i := range procs.RunningProcesses {
+		proc := &procs.RunningProcesses[i]
+		if proc.MIG == nil {
+			continue
+		}
+
+		// the instance IDs are only meaningful in the MIG mode
+		if !mig.Enabled {
+			proc.MIG = nil
+			continue
+		}
+		if inst, ok := mig.FindInstance(proc.MIG.GPUInstanceID, proc.MIG.ComputeInstanceID); ok {
+			proc.MIG.UUID = inst.UUID
+		}
+	}
+}
+
+// resolveContainers attributes the processes to the containers and the Kubernetes pods.
+func (c *component) resolveContainers(procs *nvidianvml.Processes) {
+	if c.resolveContainerFunc == nil {
+		return
+	}
+	for i := range procs.RunningProcesses {
+		proc := &procs.RunningProcesses[i]
+		proc.Container = c.resolveContainerFunc(c.ctx, proc.PID)
+	}
+}
+
 var _ components.CheckResult = &Data{}
 
 type Data struct {
@@ -165,6 +252,58 @@ func (d *Data) String() string {
 	}
 	table.Render()
 
+	var migRows [][]string
+	for _, procs := range d.Processes {
+		for _, proc := range procs.RunningProcesses {
+			if proc.MIG == nil {
+				continue
+			}
+			migRows = append(migRows, []string{
+				procs.UUID,
+				fmt.Sprintf("%d", proc.PID),
+				fmt.Sprintf("%d", proc.MIG.GPUInstanceID),
+				fmt.Sprintf("%d", proc.MIG.ComputeInstanceID),
+				proc.MIG.UUID,
+			})
+		}
+	}
+	if len(migRows) > 0 {
+		buf.WriteString("\n")
+		table = tablewriter.NewWriter(buf)
+		table.SetAlignment(tablewriter.ALIGN_CENTER)
+		table.SetHeader([]string{"GPU UUID", "PID", "GPU Instance", "Compute Instance", "MIG UUID"})
+		table.AppendBulk(migRows)
+		table.Render()
+	}
+
+	var containerRows [][]string
+	for _, procs := range d.Processes {
+		for _, proc := range procs.RunningProcesses {
+			if proc.Container == nil {
+				continue
+			}
+			pod := ""
+			if proc.Container.PodName != "" {
+				pod = proc.Container.PodNamespace + "/" + proc.Container.PodName
+			}
+			containerRows = append(containerRows, []string{
+				procs.UUID,
+				fmt.Sprintf("%d", proc.PID),
+				pod,
+				proc.Container.ContainerName,
+				proc.Container.ShortContainerID(),
+			})
+		}
+	}
+	if len(containerRows) > 0 {
+		buf.WriteString("\n")
+		table = tablewriter.NewWriter(buf)
+		table.SetAlignment(tablewriter.ALIGN_CENTER)
+		table.SetHeader([]string{"GPU UUID", "PID", "Pod", "Container", "Container ID"})
+		table.AppendBulk(containerRows)
+		table.Render()
+	}
+
 	return buf.String()
 }
 
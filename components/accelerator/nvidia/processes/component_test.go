@@ -15,6 +15,7 @@ import (
 
 	apiv1 "github.com/leptonai/gpud/api/v1"
 	"github.com/leptonai/gpud/components"
+	"github.com/leptonai/gpud/pkg/container"
 	nvidianvml "github.com/leptonai/gpud/pkg/nvidia-query/nvml"
 	nvmllib "github.com/leptonai/gpud/pkg/nvidia-query/nvml/lib"
 	"github.com/leptonai/gpud/pkg/nvidia-query/nvml/testutil"
@@ -481,3 +482,106 @@ func TestCheckEdgeCases(t *testing.T) {
 		assert.Equal(t, "all 2 GPU(s) were checked, no process issue found", data.reason)
 	})
 }
+
+func TestCheckMIG(t *testing.T) {
+	mockDevices := map[string]device.Device{
+		"gpu-uuid-1": createMockDevice("gpu-uuid-1", nil),
+	}
+	comp, err := New(&components.GPUdInstance{
+		RootCtx: context.Background(),
+		NVMLInstance: &mockNVMLInstance{
+			nvmlExists:  true,
+			devicesFunc: func() map[string]device.Device { return mockDevices },
+		},
+	})
+	require.NoError(t, err)
+	c := comp.(*component)
+
+	c.getProcessesFunc = func(uuid string, dev device.Device) (nvidianvml.Processes, error) {
+		return nvidianvml.Processes{
+			UUID: uuid,
+			RunningProcesses: []nvidianvml.Process{
+				{PID: 1, MIG: &nvidianvml.ProcessMIG{GPUInstanceID: 1, ComputeInstanceID: 0}},
+				{PID: 2, MIG: &nvidianvml.ProcessMIG{GPUInstanceID: 5, ComputeInstanceID: 0}},
+			},
+		}, nil
+	}
+	mig := nvidianvml.MIG{
+		UUID:      "gpu-uuid-1",
+		Supported: true,
+		Enabled:   true,
+		Instances: []nvidianvml.MIGInstance{
+			{UUID: "MIG-1", ParentUUID: "gpu-uuid-1", GPUInstanceID: 1, ComputeInstanceID: 0, Profile: "3g.40gb"},
+		},
+	}
+	c.getMIGFunc = func(uuid string, dev device.Device) (nvidianvml.MIG, error) {
+		return mig, nil
+	}
+
+	data := c.Check().(*Data)
+	require.Len(t, data.Processes, 1)
+	require.Len(t, data.Processes[0].RunningProcesses, 2)
+	assert.Equal(t, "MIG-1", data.Processes[0].RunningProcesses[0].MIG.UUID)
+	// the instance is not found
+	assert.Empty(t, data.Processes[0].RunningProcesses[1].MIG.UUID)
+	assert.Contains(t, data.String(), "MIG-1")
+
+	// the instance IDs are ignored when the MIG mode is disabled
+	mig = nvidianvml.MIG{UUID: "gpu-uuid-1", Supported: true}
+	data = c.Check().(*Data)
+	assert.Nil(t, data.Processes[0].RunningProcesses[0].MIG)
+	assert.Nil(t, data.Processes[0].RunningProcesses[1].MIG)
+}
+
+func TestCheckContainers(t *testing.T) {
+	mockDevices := map[string]device.Device{
+		"gpu-uuid-1": createMockDevice("gpu-uuid-1", nil),
+	}
+	comp, err := New(&components.GPUdInstance{
+		RootCtx: context.Background(),
+		NVMLInstance: &mockNVMLInstance{
+			nvmlExists:  true,
+			devicesFunc: func() map[string]device.Device { return mockDevices },
+		},
+	})
+	require.NoError(t, err)
+	c := comp.(*component)
+
+	c.getProcessesFunc = func(uuid string, dev device.Device) (nvidianvml.Processes, error) {
+		return nvidianvml.Processes{
+			UUID: uuid,
+			RunningProcesses: []nvidianvml.Process{
+				{PID: 100, GPUUsedMemoryBytes: 1024},
+				{PID: 101, GPUUsedMemoryBytes: 2048},
+				{PID: 200},
+			},
+		}, nil
+	}
+	c.resolveContainerFunc = func(ctx context.Context, pid uint32) *container.Identity {
+		switch pid {
+		case 100, 101:
+			return &container.Identity{
+				ContainerID:   "5f0c3e2a9b8d7c6e5f4a3b2c1d0e9f8a7b6c5d4e3f2a1b0c9d8e7f6a5b4c3d2e",
+				ContainerName: "trainer",
+				PodName:       "llama-train-0",
+				PodNamespace:  "ml",
+			}
+		}
+		return nil
+	}
+
+	data := c.Check().(*Data)
+	require.Len(t, data.Processes, 1)
+	procs := data.Processes[0].RunningProcesses
+	require.Len(t, procs, 3)
+	require.NotNil(t, procs[0].Container)
+	assert.Equal(t, "ml/llama-train-0/trainer", procs[0].Container.Name())
+	assert.Nil(t, procs[2].Container)
+
+	s := data.String()
+	assert.Contains(t, s, "ml/llama-train-0")
+	assert.Contains(t, s, "5f0c3e2a9b8d")
+
+	states := c.LastHealthStates()
+	assert.Contains(t, states[0].DeprecatedExtraInfo["data"], `"pod_name":"llama-train-0"`)
+}
@@ -22,8 +22,43 @@ var (
 		},
 		[]string{pkgmetrics.MetricComponentLabelKey, pkgmetrics.MetricLabelKey}, // label is GPU ID
 	).MustCurryWith(componentLabel)
+
+	metricMIGRunningProcesses = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "",
+			Subsystem: SubSystem,
+			Name:      "mig_running_total",
+			Help:      "tracks the current per-MIG device process counter",
+		},
+		[]string{pkgmetrics.MetricComponentLabelKey, pkgmetrics.MetricLabelKey}, // label is MIG device UUID
+	).MustCurryWith(componentLabel)
+
+	metricContainerRunningProcesses = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "",
+			Subsystem: SubSystem,
+			Name:      "container_running_total",
+			Help:      "tracks the current per-container GPU process counter across all GPUs",
+		},
+		[]string{pkgmetrics.MetricComponentLabelKey, pkgmetrics.MetricLabelKey}, // label is "<namespace>/<pod>/<container>" or the short container ID
+	).MustCurryWith(componentLabel)
+
+	metricContainerUsedMemoryBytes = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "",
+			Subsystem: SubSystem,
+			Name:      "container_gpu_used_memory_bytes",
+			Help:      "tracks the current per-container GPU memory used by the processes across all GPUs",
+		},
+		[]string{pkgmetrics.MetricComponentLabelKey, pkgmetrics.MetricLabelKey}, // label is "<namespace>/<pod>/<container>" or the short container ID
+	).MustCurryWith(componentLabel)
 )
 
 func init() {
-	pkgmetrics.MustRegister(metricRunningProcesses)
+	pkgmetrics.MustRegister(
+		metricRunningProcesses,
+		metricMIGRunningProcesses,
+		metricContainerRunningProcesses,
+		metricContainerUsedMemoryBytes,
+	)
 }
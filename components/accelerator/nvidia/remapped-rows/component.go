@@ -1,12 +1,15 @@
-// Package remappedrows tracks the NVIDIA per-GPU remapped rows.
+// Package remappedrows tracks the NVIDIA per-GPU remapped rows,
+// and evaluates the row remapping lifecycle across the reboots.
 package remappedrows
 
 import (
 	"bytes"
 	"context"
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"runtime"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -19,6 +22,9 @@ import (
 	apiv1 "github.com/leptonai/gpud/api/v1"
 	"github.com/leptonai/gpud/components"
 	"github.com/leptonai/gpud/pkg/eventstore"
+	gpudstate "github.com/leptonai/gpud/pkg/gpud-state"
+	"github.com/leptonai/gpud/pkg/healthstate"
+	pkghost "github.com/leptonai/gpud/pkg/host"
 	"github.com/leptonai/gpud/pkg/log"
 	pkgmetrics "github.com/leptonai/gpud/pkg/metrics"
 	"github.com/leptonai/gpud/pkg/nvidia-query/nvml"
@@ -28,6 +34,27 @@ import (
 // Name is the ID of the remapped rows component.
 const Name = "accelerator-nvidia-remapped-rows"
 
+// DefaultRetentionPeriod is the period to look back for the row remapping lifecycle events.
+const DefaultRetentionPeriod = eventstore.DefaultRetention
+
+const (
+	EventNameRemappingPending = "row_remapping_pending"
+	EventNameRemappingFailed  = "row_remapping_failed"
+	EventNameRemappingApplied = "row_remapping_applied"
+	EventNameRowsRemapped     = "rows_remapped"
+
+	EventKeyGPUUUID = "gpu_id"
+)
+
+// the counter names persisted in the state database per GPU,
+// to detect the changes across the gpud restarts (e.g., reboot)
+const (
+	counterRemappedDueToCorrectableErrors   = "remapped_due_to_correctable_errors"
+	counterRemappedDueToUncorrectableErrors = "remapped_due_to_uncorrectable_errors"
+	counterRemappingPending                 = "remapping_pending"
+	counterRemappingFailed                  = "remapping_failed"
+)
+
 var _ components.Component = &component{}
 
 type component struct {
@@ -37,7 +64,17 @@ type component struct {
 	nvmlInstance        nvidianvml.InstanceV2
 	getRemappedRowsFunc func(uuid string, dev device.Device) (nvidianvml.RemappedRows, error)
 
-	eventBucket eventstore.Bucket
+	dbRW *sql.DB
+	dbRO *sql.DB
+
+	eventBucket      eventstore.Bucket
+	rebootEventStore pkghost.RebootEventStore
+
+	// tracks the previous remapped rows per GPU and counter name,
+	// loaded from the state database (if any) on the first check
+	prevMu       sync.Mutex
+	prevLoaded   bool
+	prevCounters map[string]map[string]gpudstate.Counter
 
 	lastMu   sync.RWMutex
 	lastData *Data
@@ -50,6 +87,23 @@ func New(gpudInstance *components.GPUdInstance) (components.Component, error) {
 		cancel:              ccancel,
 		nvmlInstance:        gpudInstance.NVMLInstance,
 		getRemappedRowsFunc: nvml.GetRemappedRows,
+
+		dbRW: gpudInstance.DBRW,
+		dbRO: gpudInstance.DBRO,
+
+		rebootEventStore: gpudInstance.RebootEventStore,
+
+		prevCounters: make(map[string]map[string]gpudstate.Counter),
+	}
+
+	if c.dbRW != nil {
+		if err := gpudstate.CreateTableCounters(cctx, c.dbRW); err != nil {
+			ccancel()
+			return nil, err
+		}
+		if c.dbRO == nil {
+			c.dbRO = c.dbRW
+		}
 	}
 
 	if gpudInstance.EventStore != nil && runtime.GOOS == "linux" {
@@ -142,11 +196,36 @@ func (c *component) Check() components.CheckResult {
 		return d
 	}
 
-	issues := make([]string, 0)
+	c.prevMu.Lock()
+	defer c.prevMu.Unlock()
+
+	if !c.prevLoaded && c.dbRO != nil {
+		cctx, ccancel := context.WithTimeout(c.ctx, 30*time.Second)
+		loaded, err := gpudstate.LoadCounters(cctx, c.dbRO, Name)
+		ccancel()
+		if err != nil {
+			log.Logger.Errorw("error reading remapped rows counters", "error", err)
+
+			d.err = err
+			d.health = apiv1.HealthStateTypeUnhealthy
+			d.reason = fmt.Sprintf("error reading remapped rows counters: %s", err)
+			return d
+		}
+		c.prevCounters = loaded
+	}
+	c.prevLoaded = true
 
 	devs := c.nvmlInstance.Devices()
-	for uuid, dev := range devs {
-		remappedRows, err := c.getRemappedRowsFunc(uuid, dev)
+	uuids := make([]string, 0, len(devs))
+	for uuid := range devs {
+		uuids = append(uuids, uuid)
+	}
+	sort.Strings(uuids)
+
+	events := make([]apiv1.Event, 0)
+	updates := make([]gpudstate.Counter, 0)
+	for _, uuid := range uuids {
+		remappedRows, err := c.getRemappedRowsFunc(uuid, devs[uuid])
 		if err != nil {
 			log.Logger.Errorw("error getting remapped rows", "uuid", uuid, "error", err)
 
@@ -157,7 +236,8 @@ func (c *component) Check() components.CheckResult {
 		}
 		d.RemappedRows = append(d.RemappedRows, remappedRows)
 
-		metricUncorrectableErrors.With(prometheus.Labels{pkgmetrics.MetricLabelKey: uuid}).Set(float64(remappedRows.RemappedDueToCorrectableErrors))
+		metricCorrectableErrors.With(prometheus.Labels{pkgmetrics.MetricLabelKey: uuid}).Set(float64(remappedRows.RemappedDueToCorrectableErrors))
+		metricUncorrectableErrors.With(prometheus.Labels{pkgmetrics.MetricLabelKey: uuid}).Set(float64(remappedRows.RemappedDueToUncorrectableErrors))
 
 		if remappedRows.RemappingPending {
 			metricRemappingPending.With(prometheus.Labels{pkgmetrics.MetricLabelKey: uuid}).Set(float64(1.0))
@@ -171,71 +251,74 @@ func (c *component) Check() components.CheckResult {
 			metricRemappingFailed.With(prometheus.Labels{pkgmetrics.MetricLabelKey: uuid}).Set(float64(0.0))
 		}
 
-		b, _ := json.Marshal(d)
-		if c.eventBucket != nil && remappedRows.RemappingPending {
-			log.Logger.Warnw("inserting event for remapping pending", "uuid", uuid)
+		cur := []gpudstate.Counter{
+			{Device: uuid, Name: counterRemappedDueToCorrectableErrors, Value: uint64(remappedRows.RemappedDueToCorrectableErrors), Time: d.ts},
+			{Device: uuid, Name: counterRemappedDueToUncorrectableErrors, Value: uint64(remappedRows.RemappedDueToUncorrectableErrors), Time: d.ts},
+			{Device: uuid, Name: counterRemappingPending, Value: boolToCounter(remappedRows.RemappingPending), Time: d.ts},
+			{Device: uuid, Name: counterRemappingFailed, Value: boolToCounter(remappedRows.RemappingFailed), Time: d.ts},
+		}
+		events = append(events, c.lifecycleEvents(d.ts, remappedRows, c.prevCounters[uuid])...)
 
-			cctx, ccancel := context.WithTimeout(c.ctx, 10*time.Second)
-			err = c.eventBucket.Insert(
-				cctx,
-				apiv1.Event{
-					Time:    metav1.Time{Time: d.ts},
-					Name:    "row_remapping_pending",
-					Type:    apiv1.EventTypeWarning,
-					Message: fmt.Sprintf("%s detected pending row remapping", uuid),
-					DeprecatedExtraInfo: map[string]string{
-						"gpu_id":   uuid,
-						"data":     string(b),
-						"encoding": "json",
-					},
-					DeprecatedSuggestedActions: nil,
-				},
-			)
-			ccancel()
-			if err != nil {
-				log.Logger.Errorw("error inserting event for remapping pending", "uuid", uuid, "error", err)
-				d.err = err
-				d.health = apiv1.HealthStateTypeUnhealthy
-				d.reason = fmt.Sprintf("error inserting event for remapping pending for %s", uuid)
-				continue
-			}
+		next := make(map[string]gpudstate.Counter, len(cur))
+		for _, cnt := range cur {
+			next[cnt.Name] = cnt
+		}
+		c.prevCounters[uuid] = next
+		updates = append(updates, cur...)
+	}
+
+	if c.dbRW != nil {
+		cctx, ccancel := context.WithTimeout(c.ctx, 30*time.Second)
+		err := gpudstate.UpdateCounters(cctx, c.dbRW, Name, updates)
+		ccancel()
+		if err != nil {
+			log.Logger.Errorw("error updating remapped rows counters", "error", err)
+
+			d.err = err
+			d.health = apiv1.HealthStateTypeUnhealthy
+			d.reason = fmt.Sprintf("error updating remapped rows counters: %s", err)
+			return d
 		}
+	}
 
-		if c.eventBucket != nil && remappedRows.RemappingFailed {
-			log.Logger.Warnw("inserting event for remapping failed", "uuid", uuid)
+	var history apiv1.Events
+	if c.eventBucket != nil {
+		for _, ev := range events {
+			log.Logger.Warnw("inserting event", "name", ev.Name, "uuid", ev.DeprecatedExtraInfo[EventKeyGPUUUID])
 
 			cctx, ccancel := context.WithTimeout(c.ctx, 10*time.Second)
-			err = c.eventBucket.Insert(
-				cctx,
-				apiv1.Event{
-					Time:    metav1.Time{Time: d.ts},
-					Name:    "row_remapping_failed",
-					Type:    apiv1.EventTypeWarning,
-					Message: fmt.Sprintf("%s detected failed row remapping", uuid),
-					DeprecatedExtraInfo: map[string]string{
-						"gpu_id":   uuid,
-						"data":     string(b),
-						"encoding": "json",
-					},
-					DeprecatedSuggestedActions: nil,
-				},
-			)
+			err := c.eventBucket.Insert(cctx, ev)
 			ccancel()
 			if err != nil {
-				log.Logger.Errorw("error inserting event for remapping failed", "uuid", uuid, "error", err)
+				log.Logger.Errorw("error inserting event", "name", ev.Name, "error", err)
+
 				d.err = err
 				d.health = apiv1.HealthStateTypeUnhealthy
-				d.reason = fmt.Sprintf("error inserting event for remapping failed for %s", uuid)
-				continue
+				d.reason = fmt.Sprintf("error inserting event %s for %s", ev.Name, ev.DeprecatedExtraInfo[EventKeyGPUUUID])
+				return d
 			}
 		}
 
-		if remappedRows.QualifiesForRMA() {
-			issues = append(issues, fmt.Sprintf("%s qualifies for RMA (row remapping failed, remapped due to %d uncorrectable error(s))", uuid, remappedRows.RemappedDueToUncorrectableErrors))
+		var err error
+		history, err = c.getHistory(d.ts)
+		if err != nil {
+			log.Logger.Errorw("error getting events", "error", err)
+
+			d.err = err
+			d.health = apiv1.HealthStateTypeUnhealthy
+			d.reason = fmt.Sprintf("error getting events: %s", err)
+			return d
 		}
-		if remappedRows.RequiresReset() {
-			issues = append(issues, fmt.Sprintf("%s needs reset (detected pending row remapping)", uuid))
+	}
+
+	issues := make([]string, 0)
+	for _, remappedRows := range d.RemappedRows {
+		state := evaluateGPUState(remappedRows, history)
+		if state == nil {
+			continue
 		}
+		issues = append(issues, state.Reason)
+		d.gpuStates = append(d.gpuStates, *state)
 	}
 
 	if len(issues) > 0 {
@@ -249,6 +332,129 @@ func (c *component) Check() components.CheckResult {
 	return d
 }
 
+var _ components.HealthSettable = &component{}
+
+// SetHealthy sets the states of all the GPUs to healthy,
+// until the next row remapping event of the GPU.
+func (c *component) SetHealthy() error {
+	return c.setHealthy("")
+}
+
+var _ components.DeviceHealthSettable = &component{}
+
+// SetHealthyDevice sets the state of the GPU to healthy,
+// without affecting the states of the other GPUs,
+// until the next row remapping event of the GPU.
+func (c *component) SetHealthyDevice(deviceUUID string) error {
+	return c.setHealthy(deviceUUID)
+}
+
+// setHealthy records the "SetHealthy" event of the GPU (or all the GPUs if empty),
+// and re-evaluates the states.
+func (c *component) setHealthy(uuid string) error {
+	log.Logger.Debugw("set healthy event received", "uuid", uuid)
+	if c.eventBucket == nil {
+		return nil
+	}
+
+	ev := apiv1.Event{
+		Time: metav1.Time{Time: time.Now().UTC()},
+		Name: healthstate.EventNameSetHealthy,
+		Type: apiv1.EventTypeInfo,
+	}
+	if uuid != "" {
+		ev.DeprecatedExtraInfo = map[string]string{EventKeyGPUUUID: uuid}
+	}
+
+	cctx, ccancel := context.WithTimeout(c.ctx, 10*time.Second)
+	err := c.eventBucket.Insert(cctx, ev)
+	ccancel()
+	if err != nil {
+		return err
+	}
+
+	_ = c.Check()
+	return nil
+}
+
+// lifecycleEvents returns the events from the changes of the remapped rows
+// since the previous check (or the last check before the gpud restart).
+func (c *component) lifecycleEvents(now time.Time, remappedRows nvidianvml.RemappedRows, prev map[string]gpudstate.Counter) []apiv1.Event {
+	b, _ := json.Marshal(remappedRows)
+	newEvent := func(name string, eventType apiv1.EventType, msg string) apiv1.Event {
+		return apiv1.Event{
+			Time:    metav1.Time{Time: now},
+			Name:    name,
+			Type:    eventType,
+			Message: msg,
+			DeprecatedExtraInfo: map[string]string{
+				EventKeyGPUUUID: remappedRows.UUID,
+				"data":          string(b),
+				"encoding":      "json",
+			},
+		}
+	}
+
+	events := make([]apiv1.Event, 0)
+
+	prevPending, hasPrev := prev[counterRemappingPending]
+	if remappedRows.RemappingPending && prevPending.Value == 0 {
+		events = append(events, newEvent(EventNameRemappingPending, apiv1.EventTypeWarning, fmt.Sprintf("%s detected pending row remapping", remappedRows.UUID)))
+	}
+	if hasPrev && prevPending.Value == 1 && !remappedRows.RemappingPending {
+		events = append(events, newEvent(EventNameRemappingApplied, apiv1.EventTypeInfo, fmt.Sprintf("%s applied pending row remapping", remappedRows.UUID)))
+	}
+	if remappedRows.RemappingFailed && prev[counterRemappingFailed].Value == 0 {
+		events = append(events, newEvent(EventNameRemappingFailed, apiv1.EventTypeWarning, fmt.Sprintf("%s detected failed row remapping", remappedRows.UUID)))
+	}
+
+	// only compare against the baselines, if any
+	var correctable, uncorrectable uint64
+	if p, ok := prev[counterRemappedDueToCorrectableErrors]; ok && uint64(remappedRows.RemappedDueToCorrectableErrors) > p.Value {
+		correctable = uint64(remappedRows.RemappedDueToCorrectableErrors) - p.Value
+	}
+	if p, ok := prev[counterRemappedDueToUncorrectableErrors]; ok && uint64(remappedRows.RemappedDueToUncorrectableErrors) > p.Value {
+		uncorrectable = uint64(remappedRows.RemappedDueToUncorrectableErrors) - p.Value
+	}
+	if correctable > 0 || uncorrectable > 0 {
+		events = append(events, newEvent(EventNameRowsRemapped, apiv1.EventTypeWarning, fmt.Sprintf("%s remapped %d row(s) due to correctable errors and %d row(s) due to uncorrectable errors", remappedRows.UUID, correctable, uncorrectable)))
+	}
+
+	return events
+}
+
+// getHistory returns the events of this component and the reboot events
+// within the retention period, sorted by time in descending order.
+func (c *component) getHistory(now time.Time) (apiv1.Events, error) {
+	cctx, ccancel := context.WithTimeout(c.ctx, 15*time.Second)
+	defer ccancel()
+
+	since := now.Add(-DefaultRetentionPeriod)
+	events, err := c.eventBucket.Get(cctx, since)
+	if err != nil {
+		return nil, err
+	}
+	if c.rebootEventStore != nil {
+		rebootEvents, err := c.rebootEventStore.GetRebootEvents(cctx, since)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, rebootEvents...)
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Time.Time.After(events[j].Time.Time)
+	})
+	return events, nil
+}
+
+func boolToCounter(b bool) uint64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
 var _ components.CheckResult = &Data{}
 
 type Data struct {
@@ -268,6 +474,8 @@ type Data struct {
 	health apiv1.HealthStateType
 	// tracks the reason of the last check
 	reason string
+	// tracks the health states of the GPUs with the issues
+	gpuStates apiv1.HealthStates
 }
 
 func (d *Data) String() string {
@@ -328,14 +536,30 @@ func (d *Data) getLastHealthStates() apiv1.HealthStates {
 		}
 	}
 
+	b, _ := json.Marshal(d)
+
+	// one state per GPU with the issues,
+	// so that the issue of one GPU does not hide the others
+	if len(d.gpuStates) > 0 {
+		states := make(apiv1.HealthStates, 0, len(d.gpuStates))
+		for _, state := range d.gpuStates {
+			state.Error = d.getError()
+			state.DeprecatedExtraInfo = map[string]string{
+				EventKeyGPUUUID: state.DeprecatedExtraInfo[EventKeyGPUUUID],
+				"data":          string(b),
+				"encoding":      "json",
+			}
+			states = append(states, state)
+		}
+		return states
+	}
+
 	state := apiv1.HealthState{
 		Name:   Name,
 		Reason: d.reason,
 		Error:  d.getError(),
 		Health: d.health,
 	}
-
-	b, _ := json.Marshal(d)
 	state.DeprecatedExtraInfo = map[string]string{
 		"data":     string(b),
 		"encoding": "json",
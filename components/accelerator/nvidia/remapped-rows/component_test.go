@@ -1191,12 +1191,17 @@ func TestCheckOnceWithMultipleGPUs(t *testing.T) {
 	assert.Contains(t, data.reason, "GPU2 needs reset")
 	assert.Contains(t, data.reason, "GPU3 qualifies for RMA")
 
-	// Check health states API
+	// Check health states API, one state per GPU with the issue
 	states := c.LastHealthStates()
-	require.Len(t, states, 1)
+	require.Len(t, states, 2)
 	assert.Equal(t, apiv1.HealthStateTypeUnhealthy, states[0].Health)
+	assert.Equal(t, "GPU2", states[0].DeprecatedExtraInfo[EventKeyGPUUUID])
 	assert.Contains(t, states[0].Reason, "needs reset")
-	assert.Contains(t, states[0].Reason, "qualifies for RMA")
+	assert.Equal(t, []apiv1.RepairActionType{apiv1.RepairActionTypeRebootSystem}, states[0].SuggestedActions.RepairActions)
+	assert.Equal(t, apiv1.HealthStateTypeUnhealthy, states[1].Health)
+	assert.Equal(t, "GPU3", states[1].DeprecatedExtraInfo[EventKeyGPUUUID])
+	assert.Contains(t, states[1].Reason, "qualifies for RMA")
+	assert.Equal(t, []apiv1.RepairActionType{apiv1.RepairActionTypeHardwareInspection}, states[1].SuggestedActions.RepairActions)
 
 	// Check events were generated
 	events, err := eventBucket.Get(ctx, time.Time{})
@@ -1430,3 +1435,158 @@ func TestComponentWithNoNVML(t *testing.T) {
 	assert.Equal(t, apiv1.HealthStateTypeHealthy, states[0].Health)
 	assert.Equal(t, "NVIDIA NVML instance is nil", states[0].Reason)
 }
+
+type mockRebootEventStore struct {
+	events apiv1.Events
+}
+
+func (m *mockRebootEventStore) RecordReboot(ctx context.Context) error { return nil }
+func (m *mockRebootEventStore) GetRebootEvents(ctx context.Context, since time.Time) (apiv1.Events, error) {
+	return m.events, nil
+}
+
+func TestCheckLifecycleAcrossReboot(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dbRW, dbRO, cleanup := sqlite.OpenTestDB(t)
+	defer cleanup()
+
+	eventStore, err := eventstore.New(dbRW, dbRO, 0)
+	require.NoError(t, err)
+	rebootStore := &mockRebootEventStore{}
+
+	remappedRows := nvml.RemappedRows{UUID: "GPU1", Supported: true}
+	newComponent := func() *component {
+		comp, err := New(&components.GPUdInstance{
+			RootCtx: ctx,
+			NVMLInstance: &mockNVMLInstance{
+				getDevicesFunc:     func() map[string]device.Device { return map[string]device.Device{"GPU1": nil} },
+				getProductNameFunc: func() string { return "NVIDIA Test GPU" },
+				getMemoryErrorManagementCapabilitiesFunc: func() nvml.MemoryErrorManagementCapabilities {
+					return nvml.MemoryErrorManagementCapabilities{RowRemapping: true}
+				},
+			},
+			DBRW:             dbRW,
+			DBRO:             dbRO,
+			EventStore:       eventStore,
+			RebootEventStore: rebootStore,
+		})
+		require.NoError(t, err)
+
+		c := comp.(*component)
+		c.eventBucket, err = eventStore.Bucket(Name)
+		require.NoError(t, err)
+		c.getRemappedRowsFunc = func(uuid string, dev device.Device) (nvml.RemappedRows, error) {
+			return remappedRows, nil
+		}
+		return c
+	}
+
+	c := newComponent()
+	defer c.Close()
+
+	d := c.Check().(*Data)
+	assert.Equal(t, apiv1.HealthStateTypeHealthy, d.HealthState())
+
+	// new row remapped due to an uncorrectable error, pending the reset
+	remappedRows.RemappedDueToUncorrectableErrors = 1
+	remappedRows.RemappingPending = true
+	d = c.Check().(*Data)
+	assert.Equal(t, apiv1.HealthStateTypeUnhealthy, d.HealthState())
+	states := c.LastHealthStates()
+	require.Len(t, states, 1)
+	assert.Equal(t, []apiv1.RepairActionType{apiv1.RepairActionTypeRebootSystem}, states[0].SuggestedActions.RepairActions)
+
+	// no duplicate event while pending
+	_ = c.Check()
+	events, err := c.Events(ctx, time.Time{})
+	require.NoError(t, err)
+	names := make([]string, 0, len(events))
+	for _, ev := range events {
+		names = append(names, ev.Name)
+	}
+	assert.ElementsMatch(t, []string{EventNameRemappingPending, EventNameRowsRemapped}, names)
+
+	// rebooted, but the remapping is still pending after the gpud restart
+	rebootStore.events = apiv1.Events{{Time: metav1.Time{Time: time.Now().Add(time.Second)}, Name: "reboot"}}
+	c2 := newComponent()
+	defer c2.Close()
+	d = c2.Check().(*Data)
+	assert.Equal(t, apiv1.HealthStateTypeUnhealthy, d.HealthState())
+	assert.Contains(t, d.Summary(), "persisted after reboot")
+	states = c2.LastHealthStates()
+	require.Len(t, states, 1)
+	assert.Equal(t, []apiv1.RepairActionType{apiv1.RepairActionTypeHardwareInspection}, states[0].SuggestedActions.RepairActions)
+
+	// remapping applied
+	remappedRows.RemappingPending = false
+	d = c2.Check().(*Data)
+	assert.Equal(t, apiv1.HealthStateTypeHealthy, d.HealthState())
+
+	events, err = c2.Events(ctx, time.Time{})
+	require.NoError(t, err)
+	var applied int
+	for _, ev := range events {
+		if ev.Name == EventNameRemappingApplied {
+			applied++
+			assert.Equal(t, "GPU1", ev.DeprecatedExtraInfo[EventKeyGPUUUID])
+		}
+	}
+	assert.Equal(t, 1, applied)
+}
+
+func TestSetHealthyDevice(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	eventStore, err := eventstore.NewMemory(0)
+	require.NoError(t, err)
+
+	nvmlInstance := &mockNVMLInstance{
+		getDevicesFunc: func() map[string]device.Device {
+			return map[string]device.Device{"GPU1": nil, "GPU2": nil}
+		},
+		getProductNameFunc: func() string { return "NVIDIA Test GPU" },
+		getMemoryErrorManagementCapabilitiesFunc: func() nvml.MemoryErrorManagementCapabilities {
+			return nvml.MemoryErrorManagementCapabilities{RowRemapping: true}
+		},
+	}
+
+	comp, err := New(&components.GPUdInstance{
+		RootCtx:      ctx,
+		NVMLInstance: nvmlInstance,
+		EventStore:   eventStore,
+	})
+	require.NoError(t, err)
+	defer comp.Close()
+
+	c := comp.(*component)
+	c.getRemappedRowsFunc = func(uuid string, dev device.Device) (nvml.RemappedRows, error) {
+		return nvml.RemappedRows{UUID: uuid, RemappingPending: true}, nil
+	}
+
+	// one state per GPU, named after the GPU
+	c.Check()
+	states := c.LastHealthStates()
+	require.Len(t, states, 2)
+	assert.Equal(t, "accelerator-nvidia-remapped-rows/GPU1", states[0].Name)
+	assert.Equal(t, "accelerator-nvidia-remapped-rows/GPU2", states[1].Name)
+
+	// only clears the GPU set healthy
+	require.NoError(t, c.SetHealthyDevice("GPU1"))
+	states = c.LastHealthStates()
+	require.Len(t, states, 1)
+	assert.Equal(t, "accelerator-nvidia-remapped-rows/GPU2", states[0].Name)
+	assert.Equal(t, apiv1.HealthStateTypeUnhealthy, states[0].Health)
+
+	require.NoError(t, c.SetHealthy())
+	states = c.LastHealthStates()
+	require.Len(t, states, 1)
+	assert.Equal(t, Name, states[0].Name)
+	assert.Equal(t, apiv1.HealthStateTypeHealthy, states[0].Health)
+}
@@ -0,0 +1,125 @@
+package remappedrows
+
+import (
+	"fmt"
+	"time"
+
+	apiv1 "github.com/leptonai/gpud/api/v1"
+	"github.com/leptonai/gpud/pkg/healthstate"
+	"github.com/leptonai/gpud/pkg/nvidia-query/nvml"
+)
+
+// evaluateGPUState resolves the state of a GPU from its current remapped rows
+// and the event history (including the "reboot" events).
+// Returns nil if no issue is found, or if the GPU is set healthy
+// after its last row remapping event.
+// The state is named after the GPU (see "healthstate.DeviceStateName"),
+// so that each GPU is distinguishable and can be set healthy on its own.
+//
+// A pending row remapping is applied by the GPU reset (e.g., reboot),
+// so the reboot is only suggested when the pending remapping has not
+// persisted after a reboot. Otherwise, the reboot would not help,
+// and the GPU requires a hardware inspection.
+// note: assume events are sorted by time in descending order
+func evaluateGPUState(rr nvml.RemappedRows, events apiv1.Events) *apiv1.HealthState {
+	if setHealthySinceLastEvent(rr.UUID, events) {
+		return nil
+	}
+
+	name := healthstate.DeviceStateName(Name, rr.UUID)
+	if rr.QualifiesForRMA() {
+		return &apiv1.HealthState{
+			Name:                name,
+			Health:              apiv1.HealthStateTypeUnhealthy,
+			Reason:              fmt.Sprintf("%s qualifies for RMA (row remapping failed, remapped due to %d uncorrectable error(s))", rr.UUID, rr.RemappedDueToUncorrectableErrors),
+			DeprecatedExtraInfo: map[string]string{EventKeyGPUUUID: rr.UUID},
+			SuggestedActions: &apiv1.SuggestedActions{
+				RepairActions: []apiv1.RepairActionType{apiv1.RepairActionTypeHardwareInspection},
+			},
+		}
+	}
+
+	if !rr.RequiresReset() {
+		return nil
+	}
+
+	pendingSince := findPendingSince(rr.UUID, events)
+	if !pendingSince.IsZero() && rebootedAfter(pendingSince, events) {
+		return &apiv1.HealthState{
+			Name:                name,
+			Health:              apiv1.HealthStateTypeUnhealthy,
+			Reason:              fmt.Sprintf("%s needs hardware inspection (pending row remapping since %s persisted after reboot)", rr.UUID, pendingSince.UTC().Format(time.RFC3339)),
+			DeprecatedExtraInfo: map[string]string{EventKeyGPUUUID: rr.UUID},
+			SuggestedActions: &apiv1.SuggestedActions{
+				RepairActions: []apiv1.RepairActionType{apiv1.RepairActionTypeHardwareInspection},
+			},
+		}
+	}
+
+	return &apiv1.HealthState{
+		Name:                name,
+		Health:              apiv1.HealthStateTypeUnhealthy,
+		Reason:              fmt.Sprintf("%s needs reset (detected pending row remapping)", rr.UUID),
+		DeprecatedExtraInfo: map[string]string{EventKeyGPUUUID: rr.UUID},
+		SuggestedActions: &apiv1.SuggestedActions{
+			RepairActions: []apiv1.RepairActionType{apiv1.RepairActionTypeRebootSystem},
+		},
+	}
+}
+
+// setHealthySinceLastEvent returns true if the GPU (or all the GPUs) is set healthy
+// at or after the last row remapping event of the GPU.
+// The events in the same second are not ordered by the event store,
+// thus the "SetHealthy" event takes precedence.
+func setHealthySinceLastEvent(uuid string, events apiv1.Events) bool {
+	var setHealthyAt, lastEventAt time.Time
+	for _, ev := range events {
+		switch ev.Name {
+		case healthstate.EventNameSetHealthy:
+			if target := ev.DeprecatedExtraInfo[EventKeyGPUUUID]; target != "" && target != uuid {
+				continue
+			}
+			if ev.Time.After(setHealthyAt) {
+				setHealthyAt = ev.Time.Time
+			}
+		case EventNameRemappingPending, EventNameRemappingFailed, EventNameRowsRemapped:
+			if ev.DeprecatedExtraInfo[EventKeyGPUUUID] != uuid {
+				continue
+			}
+			if ev.Time.After(lastEventAt) {
+				lastEventAt = ev.Time.Time
+			}
+		}
+	}
+	return !setHealthyAt.IsZero() && !setHealthyAt.Before(lastEventAt)
+}
+
+// findPendingSince returns the time of the earliest "row_remapping_pending" event
+// of the GPU since the last "row_remapping_applied" event of the GPU.
+// Returns zero time if not found.
+// note: assume events are sorted by time in descending order
+func findPendingSince(uuid string, events apiv1.Events) time.Time {
+	var since time.Time
+	for _, ev := range events {
+		if ev.DeprecatedExtraInfo[EventKeyGPUUUID] != uuid {
+			continue
+		}
+		if ev.Name == EventNameRemappingApplied {
+			break
+		}
+		if ev.Name == EventNameRemappingPending {
+			since = ev.Time.Time
+		}
+	}
+	return since
+}
+
+// rebootedAfter returns true if any "reboot" event is found after the given time.
+func rebootedAfter(t time.Time, events apiv1.Events) bool {
+	for _, ev := range events {
+		if ev.Name == "reboot" && ev.Time.After(t) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,157 @@
+package remappedrows
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	apiv1 "github.com/leptonai/gpud/api/v1"
+	"github.com/leptonai/gpud/pkg/healthstate"
+	"github.com/leptonai/gpud/pkg/nvidia-query/nvml"
+)
+
+func TestEvaluateGPUState(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now().UTC()
+	gpuEvent := func(name string, uuid string, ago time.Duration) apiv1.Event {
+		return apiv1.Event{
+			Time:                metav1.Time{Time: now.Add(-ago)},
+			Name:                name,
+			DeprecatedExtraInfo: map[string]string{EventKeyGPUUUID: uuid},
+		}
+	}
+	rebootEvent := func(ago time.Duration) apiv1.Event {
+		return apiv1.Event{Time: metav1.Time{Time: now.Add(-ago)}, Name: "reboot"}
+	}
+
+	tests := []struct {
+		name           string
+		remappedRows   nvml.RemappedRows
+		events         apiv1.Events
+		expectedAction apiv1.RepairActionType
+		expectedReason string
+	}{
+		{
+			name:         "no issue",
+			remappedRows: nvml.RemappedRows{UUID: "GPU1"},
+			events: apiv1.Events{
+				gpuEvent(EventNameRemappingPending, "GPU1", time.Hour),
+				rebootEvent(2 * time.Hour),
+			},
+		},
+		{
+			name:           "pending without reboot",
+			remappedRows:   nvml.RemappedRows{UUID: "GPU1", RemappingPending: true},
+			events:         apiv1.Events{gpuEvent(EventNameRemappingPending, "GPU1", time.Hour)},
+			expectedAction: apiv1.RepairActionTypeRebootSystem,
+			expectedReason: "needs reset",
+		},
+		{
+			name:         "pending before the last reboot",
+			remappedRows: nvml.RemappedRows{UUID: "GPU1", RemappingPending: true},
+			events: apiv1.Events{
+				rebootEvent(time.Hour),
+				gpuEvent(EventNameRemappingPending, "GPU1", 2*time.Hour),
+			},
+			expectedAction: apiv1.RepairActionTypeHardwareInspection,
+			expectedReason: "persisted after reboot",
+		},
+		{
+			name:         "pending after the last reboot",
+			remappedRows: nvml.RemappedRows{UUID: "GPU1", RemappingPending: true},
+			events: apiv1.Events{
+				gpuEvent(EventNameRemappingPending, "GPU1", time.Hour),
+				rebootEvent(2 * time.Hour),
+			},
+			expectedAction: apiv1.RepairActionTypeRebootSystem,
+			expectedReason: "needs reset",
+		},
+		{
+			name:         "previous pending was applied by the reboot",
+			remappedRows: nvml.RemappedRows{UUID: "GPU1", RemappingPending: true},
+			events: apiv1.Events{
+				gpuEvent(EventNameRemappingPending, "GPU1", time.Hour),
+				gpuEvent(EventNameRemappingApplied, "GPU1", 2*time.Hour),
+				rebootEvent(3 * time.Hour),
+				gpuEvent(EventNameRemappingPending, "GPU1", 4*time.Hour),
+			},
+			expectedAction: apiv1.RepairActionTypeRebootSystem,
+			expectedReason: "needs reset",
+		},
+		{
+			name:         "pending on other GPU before the reboot",
+			remappedRows: nvml.RemappedRows{UUID: "GPU1", RemappingPending: true},
+			events: apiv1.Events{
+				gpuEvent(EventNameRemappingPending, "GPU1", time.Hour),
+				rebootEvent(2 * time.Hour),
+				gpuEvent(EventNameRemappingPending, "GPU2", 3*time.Hour),
+			},
+			expectedAction: apiv1.RepairActionTypeRebootSystem,
+			expectedReason: "needs reset",
+		},
+		{
+			name:         "set healthy after the pending event",
+			remappedRows: nvml.RemappedRows{UUID: "GPU1", RemappingPending: true},
+			events: apiv1.Events{
+				gpuEvent(healthstate.EventNameSetHealthy, "GPU1", time.Minute),
+				gpuEvent(EventNameRemappingPending, "GPU1", time.Hour),
+			},
+		},
+		{
+			name:         "set healthy on all GPUs",
+			remappedRows: nvml.RemappedRows{UUID: "GPU1", RemappingPending: true},
+			events: apiv1.Events{
+				{Time: metav1.Time{Time: now.Add(-time.Minute)}, Name: healthstate.EventNameSetHealthy},
+				gpuEvent(EventNameRemappingPending, "GPU1", time.Hour),
+			},
+		},
+		{
+			name:         "set healthy on other GPU",
+			remappedRows: nvml.RemappedRows{UUID: "GPU1", RemappingPending: true},
+			events: apiv1.Events{
+				gpuEvent(healthstate.EventNameSetHealthy, "GPU2", time.Minute),
+				gpuEvent(EventNameRemappingPending, "GPU1", time.Hour),
+			},
+			expectedAction: apiv1.RepairActionTypeRebootSystem,
+			expectedReason: "needs reset",
+		},
+		{
+			name:         "new event after set healthy",
+			remappedRows: nvml.RemappedRows{UUID: "GPU1", RemappingPending: true},
+			events: apiv1.Events{
+				gpuEvent(EventNameRemappingPending, "GPU1", time.Minute),
+				gpuEvent(healthstate.EventNameSetHealthy, "GPU1", time.Hour),
+			},
+			expectedAction: apiv1.RepairActionTypeRebootSystem,
+			expectedReason: "needs reset",
+		},
+		{
+			name:           "failed",
+			remappedRows:   nvml.RemappedRows{UUID: "GPU1", RemappingPending: true, RemappingFailed: true, RemappedDueToUncorrectableErrors: 8},
+			expectedAction: apiv1.RepairActionTypeHardwareInspection,
+			expectedReason: "qualifies for RMA",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := evaluateGPUState(tt.remappedRows, tt.events)
+			if tt.expectedReason == "" {
+				assert.Nil(t, state)
+				return
+			}
+
+			require.NotNil(t, state)
+			assert.Equal(t, healthstate.DeviceStateName(Name, tt.remappedRows.UUID), state.Name)
+			assert.Equal(t, apiv1.HealthStateTypeUnhealthy, state.Health)
+			assert.Contains(t, state.Reason, tt.expectedReason)
+			assert.Equal(t, tt.remappedRows.UUID, state.DeprecatedExtraInfo[EventKeyGPUUUID])
+			require.NotNil(t, state.SuggestedActions)
+			assert.Equal(t, []apiv1.RepairActionType{tt.expectedAction}, state.SuggestedActions.RepairActions)
+		})
+	}
+}
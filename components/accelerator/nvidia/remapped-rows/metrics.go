@@ -13,6 +13,16 @@ var (
 		pkgmetrics.MetricComponentLabelKey: "accelerator-nvidia-remapped-rows",
 	}
 
+	metricCorrectableErrors = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "",
+			Subsystem: SubSystem,
+			Name:      "due_to_correctable_errors",
+			Help:      "tracks the number of rows remapped due to correctable errors",
+		},
+		[]string{pkgmetrics.MetricComponentLabelKey, pkgmetrics.MetricLabelKey}, // label is GPU ID
+	).MustCurryWith(componentLabel)
+
 	metricUncorrectableErrors = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Namespace: "",
@@ -53,6 +53,9 @@ type component struct {
 	rebootEventStore pkghost.RebootEventStore
 	eventBucket      eventstore.Bucket
 	kmsgWatcher      kmsg.Watcher
+	// source of the kernel messages (e.g., "kmsg", "journal")
+	// empty if no source is available (e.g., non-root without journal)
+	kmsgSource string
 
 	readAllKmsg  func(context.Context) ([]kmsg.Message, error)
 	extraEventCh chan *apiv1.Event
@@ -60,8 +63,9 @@ type component struct {
 	lastMu   sync.RWMutex
 	lastData *Data
 
-	mu        sync.RWMutex
-	currState apiv1.HealthState
+	mu sync.RWMutex
+	// one state per NVSwitch
+	currStates apiv1.HealthStates
 }
 
 func New(gpudInstance *components.GPUdInstance) (components.Component, error) {
@@ -83,12 +87,13 @@ func New(gpudInstance *components.GPUdInstance) (components.Component, error) {
 			return nil, err
 		}
 
-		if os.Geteuid() == 0 {
-			c.kmsgWatcher, err = kmsg.NewWatcher()
+		if gpudInstance.KmsgHub != nil {
+			c.kmsgWatcher, err = gpudInstance.KmsgHub.NewWatcher(Name)
 			if err != nil {
 				ccancel()
 				return nil, err
 			}
+			c.kmsgSource = gpudInstance.KmsgHub.Source()
 		}
 	}
 
@@ -135,7 +140,27 @@ func (c *component) Start() error {
 func (c *component) LastHealthStates() apiv1.HealthStates {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
-	return apiv1.HealthStates{c.currState}
+
+	states := make(apiv1.HealthStates, 0, len(c.currStates))
+	for _, state := range c.currStates {
+		if state.Reason != "" {
+			state.Reason = c.withSourceReason(state.Reason)
+		}
+		states = append(states, state)
+	}
+	if len(states) == 0 {
+		states = append(states, apiv1.HealthState{Name: StateNameErrorSXid})
+	}
+	return states
+}
+
+// withSourceReason appends the kernel log source to the reason,
+// so that it is visible when the errors cannot be detected.
+func (c *component) withSourceReason(reason string) string {
+	if c.kmsgSource == "" {
+		return reason + " (no kernel log source)"
+	}
+	return fmt.Sprintf("%s (kernel log source: %s)", reason, c.kmsgSource)
 }
 
 func (c *component) Events(ctx context.Context, since time.Time) (apiv1.Events, error) {
@@ -340,51 +365,61 @@ func (c *component) start(kmsgCh <-chan kmsg.Message, updatePeriod time.Duration
 				continue
 			}
 
-		case message := <-kmsgCh:
-			sxidErr := Match(message.Message)
-			if sxidErr == nil {
-				log.Logger.Debugw("not sxid event, skip", "kmsg", message)
-				continue
-			}
-
-			id := uuid.New()
-			var sxidName string
-			if sxidErr.Detail != nil {
-				sxidName = sxidErr.Detail.Name
-			}
-			logger := log.Logger.With("id", id, "sxid", sxidErr.SXid, "sxidName", sxidName, "deviceUUID", sxidErr.DeviceUUID)
-			logger.Infow("got sxid event", "kmsg", message, "kmsgTimestamp", message.Timestamp.Unix())
-
-			event := apiv1.Event{
-				Time: message.Timestamp,
-				Name: EventNameErrorSXid,
-				DeprecatedExtraInfo: map[string]string{
-					EventKeyErrorSXidData: strconv.FormatInt(int64(sxidErr.SXid), 10),
-					EventKeyDeviceUUID:    sxidErr.DeviceUUID,
-				},
-			}
-			sameEvent, err := c.eventBucket.Find(c.ctx, event)
-			if err != nil {
-				logger.Errorw("failed to check event existence", "error", err)
-				continue
-			}
-			if sameEvent != nil {
-				logger.Infow("find the same event, skip inserting it")
-				continue
-			}
-			if err = c.eventBucket.Insert(c.ctx, event); err != nil {
-				logger.Errorw("failed to create event", "error", err)
-				continue
-			}
-			logger.Infow("inserted the event successfully")
-			if err = c.updateCurrentState(); err != nil {
-				logger.Errorw("failed to update current state", "error", err)
+		case message, ok := <-kmsgCh:
+			if !ok {
+				// the kmsg source is closed (e.g., hub closed)
+				kmsgCh = nil
 				continue
 			}
+			c.processKmsg(message)
+			kmsg.Ack(c.kmsgWatcher, message)
 		}
 	}
 }
 
+// processKmsg inserts the SXID event of the kernel message, if matched.
+func (c *component) processKmsg(message kmsg.Message) {
+	sxidErr := Match(message.Message)
+	if sxidErr == nil {
+		log.Logger.Debugw("not sxid event, skip", "kmsg", message)
+		return
+	}
+
+	id := uuid.New()
+	var sxidName string
+	if sxidErr.Detail != nil {
+		sxidName = sxidErr.Detail.Name
+	}
+	logger := log.Logger.With("id", id, "sxid", sxidErr.SXid, "sxidName", sxidName, "deviceUUID", sxidErr.DeviceUUID)
+	logger.Infow("got sxid event", "kmsg", message, "kmsgTimestamp", message.Timestamp.Unix())
+
+	event := apiv1.Event{
+		Time: message.Timestamp,
+		Name: EventNameErrorSXid,
+		DeprecatedExtraInfo: map[string]string{
+			EventKeyErrorSXidData: strconv.FormatInt(int64(sxidErr.SXid), 10),
+			EventKeyDeviceUUID:    normalizeDeviceID(sxidErr.DeviceUUID),
+		},
+	}
+	sameEvent, err := c.eventBucket.Find(c.ctx, event)
+	if err != nil {
+		logger.Errorw("failed to check event existence", "error", err)
+		return
+	}
+	if sameEvent != nil {
+		logger.Infow("find the same event, skip inserting it")
+		return
+	}
+	if err = c.eventBucket.Insert(c.ctx, event); err != nil {
+		logger.Errorw("failed to create event", "error", err)
+		return
+	}
+	logger.Infow("inserted the event successfully")
+	if err = c.updateCurrentState(); err != nil {
+		logger.Errorw("failed to update current state", "error", err)
+	}
+}
+
 var _ components.HealthSettable = &component{}
 
 func (c *component) SetHealthy() error {
@@ -398,6 +433,27 @@ func (c *component) SetHealthy() error {
 	return nil
 }
 
+var _ components.DeviceHealthSettable = &component{}
+
+// SetHealthyDevice sets the state of the NVSwitch to healthy,
+// without affecting the states of the other NVSwitches.
+// The device is the NVSwitch PCI device ID (e.g., "PCI:0000:05:00.0").
+func (c *component) SetHealthyDevice(deviceUUID string) error {
+	deviceUUID = normalizeDeviceID(deviceUUID)
+	log.Logger.Debugw("set healthy event received", "deviceUUID", deviceUUID)
+	newEvent := &apiv1.Event{
+		Time:                metav1.Time{Time: time.Now().UTC()},
+		Name:                "SetHealthy",
+		DeprecatedExtraInfo: map[string]string{EventKeyDeviceUUID: deviceUUID},
+	}
+	select {
+	case c.extraEventCh <- newEvent:
+	default:
+		log.Logger.Debugw("channel full, set healthy event skipped")
+	}
+	return nil
+}
+
 func (c *component) updateCurrentState() error {
 	if c.rebootEventStore == nil || c.eventBucket == nil {
 		return nil
@@ -418,9 +474,11 @@ func (c *component) updateCurrentState() error {
 	events := mergeEvents(rebootEvents, localEvents)
 
 	c.mu.Lock()
-	c.currState = evolveHealthyState(events)
+	c.currStates = evolveHealthyStates(events)
 	if rebootErr != "" {
-		c.currState.Error = fmt.Sprintf("%s\n%s", rebootErr, c.currState.Error)
+		for i := range c.currStates {
+			c.currStates[i].Error = fmt.Sprintf("%s\n%s", rebootErr, c.currStates[i].Error)
+		}
 	}
 	c.mu.Unlock()
 
@@ -3,8 +3,6 @@ package sxid
 import (
 	"context"
 	"errors"
-	"os"
-	"runtime"
 	"testing"
 	"time"
 
@@ -262,10 +260,17 @@ func TestSXIDComponent_States(t *testing.T) {
 		Health: apiv1.HealthStateTypeHealthy,
 		Reason: "SXIDComponent is healthy",
 	}
-	component.currState = s
+	component.currStates = apiv1.HealthStates{s}
 	states := component.LastHealthStates()
 	assert.Len(t, states, 1)
-	assert.Equal(t, s, states[0])
+	assert.Equal(t, "SXIDComponent is healthy (no kernel log source)", states[0].Reason)
+
+	component.kmsgSource = kmsg.SourceJournal
+	states = component.LastHealthStates()
+	assert.Len(t, states, 1)
+	want := s
+	want.Reason = "SXIDComponent is healthy (kernel log source: journal)"
+	assert.Equal(t, want, states[0])
 
 	startTime := time.Now().Add(-1 * time.Hour)
 
@@ -732,7 +737,7 @@ func TestSXIDComponent_Start(t *testing.T) {
 	err := component.Start()
 	assert.NoError(t, err)
 
-	if runtime.GOOS == "linux" && os.Geteuid() == 0 {
+	if component.kmsgWatcher != nil {
 		// Start again to ensure it doesn't cause issues
 		err = component.Start()
 		assert.Equal(t, kmsg.ErrWatcherAlreadyStarted, err)
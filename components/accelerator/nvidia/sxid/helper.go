@@ -4,10 +4,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"strconv"
+	"strings"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	apiv1 "github.com/leptonai/gpud/api/v1"
+	"github.com/leptonai/gpud/pkg/healthstate"
 	"github.com/leptonai/gpud/pkg/log"
 	"github.com/leptonai/gpud/pkg/nvidia-query/sxid"
 )
@@ -20,6 +22,42 @@ const (
 	rebootThreshold = 2
 )
 
+// evolveHealthyStates resolves the state of the SXID error component per device,
+// so that the SXID errors on one device do not affect the states of the other NVSwitches.
+// Returns one state per device (named after the device UUID), or a single healthy
+// state if no SXID error is found.
+// note: assume events are sorted by time in descending order
+func evolveHealthyStates(events apiv1.Events) apiv1.HealthStates {
+	return healthstate.EvolvePerDevice(events, EventNameErrorSXid, EventKeyDeviceUUID, getDeviceUUID, evolveHealthyState)
+}
+
+// getDeviceUUID returns the NVSwitch device UUID of the SXID event,
+// either from the extra info or from the resolved SXID data.
+func getDeviceUUID(event apiv1.Event) string {
+	if event.DeprecatedExtraInfo == nil {
+		return ""
+	}
+	if uuid := event.DeprecatedExtraInfo[EventKeyDeviceUUID]; uuid != "" {
+		return normalizeDeviceID(uuid)
+	}
+	var sxidErr sxidErrorEventDetail
+	if err := json.Unmarshal([]byte(event.DeprecatedExtraInfo[EventKeyErrorSXidData]), &sxidErr); err != nil {
+		return ""
+	}
+	return normalizeDeviceID(sxidErr.DeviceUUID)
+}
+
+// normalizeDeviceID returns the NVSwitch PCI device ID in the kernel message format
+// (e.g., "PCI:0000:05:00.0"), so that the same NVSwitch is tracked (and set healthy)
+// once, regardless of the case and the "PCI:" prefix.
+// NVSwitches have no GPU UUID, so the PCI device ID is the device UUID.
+func normalizeDeviceID(deviceID string) string {
+	if deviceID == "" {
+		return ""
+	}
+	return "PCI:" + strings.TrimPrefix(strings.ToLower(deviceID), "pci:")
+}
+
 // evolveHealthyState resolves the state of the SXID error component.
 // note: assume events are sorted by time in descending order
 func evolveHealthyState(events apiv1.Events) (ret apiv1.HealthState) {
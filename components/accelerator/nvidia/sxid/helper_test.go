@@ -102,6 +102,77 @@ func TestStateUpdateBasedOnEvents(t *testing.T) {
 	})
 }
 
+func TestEvolveHealthyStatesPerDevice(t *testing.T) {
+	withDevice := func(event apiv1.Event, uuid string) apiv1.Event {
+		event.DeprecatedExtraInfo[EventKeyDeviceUUID] = uuid
+		return event
+	}
+
+	t.Run("no event found", func(t *testing.T) {
+		states := evolveHealthyStates(apiv1.Events{{Name: "reboot"}})
+		assert.Len(t, states, 1)
+		assert.Equal(t, apiv1.HealthStateTypeHealthy, states[0].Health)
+		assert.Equal(t, "SXIDComponent is healthy", states[0].Reason)
+		assert.Nil(t, states[0].DeprecatedExtraInfo)
+		assert.Equal(t, StateNameErrorSXid, states[0].Name)
+	})
+
+	events := apiv1.Events{
+		withDevice(createSXidEvent(time.Time{}, 456, apiv1.EventTypeFatal, apiv1.RepairActionTypeRebootSystem), "PCI:0000:9b:00"),
+		withDevice(createSXidEvent(time.Time{}, 123, apiv1.EventTypeCritical, apiv1.RepairActionTypeRebootSystem), "PCI:0000:1a:00"),
+	}
+
+	t.Run("one state per device", func(t *testing.T) {
+		states := evolveHealthyStates(events)
+		assert.Len(t, states, 2)
+
+		// sorted by the device UUID
+		assert.Equal(t, "PCI:0000:1a:00", states[0].DeprecatedExtraInfo[EventKeyDeviceUUID])
+		assert.Equal(t, apiv1.HealthStateTypeDegraded, states[0].Health)
+		assert.Equal(t, "error_sxid/PCI:0000:1a:00", states[0].Name)
+		assert.Equal(t, "error_sxid/PCI:0000:9b:00", states[1].Name)
+		assert.Equal(t, "PCI:0000:9b:00", states[1].DeprecatedExtraInfo[EventKeyDeviceUUID])
+		assert.Equal(t, apiv1.HealthStateTypeUnhealthy, states[1].Health)
+	})
+
+	t.Run("SetHealthy on one device", func(t *testing.T) {
+		states := evolveHealthyStates(append(apiv1.Events{
+			{Name: "SetHealthy", DeprecatedExtraInfo: map[string]string{EventKeyDeviceUUID: "PCI:0000:9b:00"}},
+		}, events...))
+		assert.Len(t, states, 2)
+		assert.Equal(t, apiv1.HealthStateTypeDegraded, states[0].Health)
+		assert.Equal(t, apiv1.HealthStateTypeHealthy, states[1].Health)
+		assert.Equal(t, "SXIDComponent is healthy on PCI:0000:9b:00", states[1].Reason)
+		assert.Nil(t, states[1].SuggestedActions)
+	})
+
+	t.Run("SetHealthy on all devices", func(t *testing.T) {
+		states := evolveHealthyStates(append(apiv1.Events{{Name: "SetHealthy"}}, events...))
+		assert.Len(t, states, 2)
+		for _, state := range states {
+			assert.Equal(t, apiv1.HealthStateTypeHealthy, state.Health)
+		}
+	})
+
+	t.Run("same device in the different formats", func(t *testing.T) {
+		states := evolveHealthyStates(append(apiv1.Events{
+			{Name: "SetHealthy", DeprecatedExtraInfo: map[string]string{EventKeyDeviceUUID: "0000:9B:00"}},
+			withDevice(createSXidEvent(time.Time{}, 123, apiv1.EventTypeCritical, apiv1.RepairActionTypeRebootSystem), "pci:0000:1A:00"),
+		}, events...))
+		assert.Len(t, states, 2)
+		assert.Equal(t, "error_sxid/PCI:0000:1a:00", states[0].Name)
+		assert.Equal(t, apiv1.HealthStateTypeHealthy, states[1].Health)
+	})
+
+	t.Run("reboot applies to all devices", func(t *testing.T) {
+		states := evolveHealthyStates(append(apiv1.Events{{Name: "reboot"}}, events...))
+		assert.Len(t, states, 2)
+		for _, state := range states {
+			assert.Equal(t, apiv1.HealthStateTypeHealthy, state.Health)
+		}
+	})
+}
+
 func Test_sxidErrorEventDetailJSON(t *testing.T) {
 	testTime := metav1.Time{Time: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
 
@@ -0,0 +1,410 @@
+// Package topology builds the GPU/NIC topology matrix and the NUMA/CPU affinity
+// (similar to "nvidia-smi topo -m"), and checks the topology against the reference.
+package topology
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/NVIDIA/go-nvlib/pkg/nvlib/device"
+	"github.com/olekukonko/tablewriter"
+
+	apiv1 "github.com/leptonai/gpud/api/v1"
+	"github.com/leptonai/gpud/components"
+	"github.com/leptonai/gpud/pkg/log"
+	nvidianvml "github.com/leptonai/gpud/pkg/nvidia-query/nvml"
+	"github.com/leptonai/gpud/pkg/pci"
+)
+
+const Name = "accelerator-nvidia-topology"
+
+var _ components.Component = &component{}
+
+type component struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	nvmlInstance        nvidianvml.InstanceV2
+	numCPUs             int
+	getTopologyLinkFunc func(dev1 device.Device, dev2 device.Device) (string, error)
+	getCPUAffinityFunc  func(dev device.Device, numCPUs int) ([]int, error)
+	getNVLinkPeersFunc  func(dev device.Device) (nvidianvml.NVLinkPeers, error)
+	readSysfsDeviceFunc func(busID string) (pci.SysfsDevice, error)
+	listNICsFunc        func() ([]pci.ClassDevice, error)
+	getReferenceFunc    func() Reference
+
+	lastMu   sync.RWMutex
+	lastData *Data
+}
+
+func New(gpudInstance *components.GPUdInstance) (components.Component, error) {
+	cctx, ccancel := context.WithCancel(gpudInstance.RootCtx)
+	c := &component{
+		ctx:                 cctx,
+		cancel:              ccancel,
+		nvmlInstance:        gpudInstance.NVMLInstance,
+		numCPUs:             runtime.NumCPU(),
+		getTopologyLinkFunc: nvidianvml.GetTopologyLink,
+		getCPUAffinityFunc:  nvidianvml.GetCPUAffinity,
+		getNVLinkPeersFunc:  nvidianvml.GetNVLinkPeers,
+		readSysfsDeviceFunc: func(busID string) (pci.SysfsDevice, error) {
+			return pci.ReadSysfsDevice(pci.DefaultSysfsRoot, busID)
+		},
+		listNICsFunc: func() ([]pci.ClassDevice, error) {
+			return pci.ListClassDevices(pci.DefaultSysfsRoot, "infiniband")
+		},
+		getReferenceFunc: GetDefaultReference,
+	}
+	return c, nil
+}
+
+func (c *component) Name() string { return Name }
+
+func (c *component) Start() error {
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+
+		for {
+			_ = c.Check()
+
+			select {
+			case <-c.ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return nil
+}
+
+func (c *component) LastHealthStates() apiv1.HealthStates {
+	c.lastMu.RLock()
+	lastData := c.lastData
+	c.lastMu.RUnlock()
+	return lastData.getLastHealthStates()
+}
+
+func (c *component) Events(ctx context.Context, since time.Time) (apiv1.Events, error) {
+	return nil, nil
+}
+
+func (c *component) Close() error {
+	log.Logger.Debugw("closing component")
+
+	c.cancel()
+
+	return nil
+}
+
+type gpuDevice struct {
+	uuid   string
+	dev    device.Device
+	sysfs  pci.SysfsDevice
+	peers  nvidianvml.NVLinkPeers
+	device Device
+}
+
+func (c *component) Check() components.CheckResult {
+	log.Logger.Infow("checking nvidia gpu topology")
+
+	d := &Data{
+		ts: time.Now().UTC(),
+	}
+	defer func() {
+		c.lastMu.Lock()
+		c.lastData = d
+		c.lastMu.Unlock()
+	}()
+
+	if c.nvmlInstance == nil {
+		d.health = apiv1.HealthStateTypeHealthy
+		d.reason = "NVIDIA NVML instance is nil"
+		return d
+	}
+	if !c.nvmlInstance.NVMLExists() {
+		d.health = apiv1.HealthStateTypeHealthy
+		d.reason = "NVIDIA NVML is not loaded"
+		return d
+	}
+
+	var gpus []gpuDevice
+	for uuid, dev := range c.nvmlInstance.Devices() {
+		busID, err := dev.GetPCIBusID()
+		if err != nil {
+			log.Logger.Errorw("error getting pci bus id for device", "uuid", uuid, "error", err)
+
+			d.err = err
+			d.health = apiv1.HealthStateTypeUnhealthy
+			d.reason = fmt.Sprintf("error getting pci bus id for device %s", uuid)
+			return d
+		}
+
+		gpu := gpuDevice{
+			uuid:  uuid,
+			dev:   dev,
+			sysfs: c.readSysfsDevice(busID),
+		}
+		gpu.device = Device{
+			Type:        DeviceTypeGPU,
+			ID:          uuid,
+			BusID:       gpu.sysfs.BusID,
+			NUMANode:    gpu.sysfs.NUMANode,
+			CPUAffinity: gpu.sysfs.LocalCPUList,
+		}
+
+		cpus, err := c.getCPUAffinityFunc(dev, c.numCPUs)
+		if err != nil {
+			log.Logger.Errorw("error getting cpu affinity for device", "uuid", uuid, "error", err)
+
+			d.err = err
+			d.health = apiv1.HealthStateTypeUnhealthy
+			d.reason = fmt.Sprintf("error getting cpu affinity for device %s", uuid)
+			return d
+		}
+		if len(cpus) > 0 {
+			gpu.device.CPUAffinity = formatCPUList(cpus)
+		}
+
+		gpu.peers, err = c.getNVLinkPeersFunc(dev)
+		if err != nil {
+			log.Logger.Errorw("error getting nvlink peers for device", "uuid", uuid, "error", err)
+
+			d.err = err
+			d.health = apiv1.HealthStateTypeUnhealthy
+			d.reason = fmt.Sprintf("error getting nvlink peers for device %s", uuid)
+			return d
+		}
+
+		gpus = append(gpus, gpu)
+	}
+	sort.Slice(gpus, func(i, j int) bool {
+		return gpus[i].sysfs.BusID < gpus[j].sysfs.BusID
+	})
+
+	nics, err := c.listNICsFunc()
+	if err != nil {
+		// the topology between the GPUs is still useful without the NICs
+		log.Logger.Warnw("error listing nics", "error", err)
+	}
+
+	sysfsDevs := make([]pci.SysfsDevice, 0, len(gpus)+len(nics))
+	for i := range gpus {
+		gpus[i].device.Label = fmt.Sprintf("%s%d", DeviceTypeGPU, i)
+		d.Devices = append(d.Devices, gpus[i].device)
+		sysfsDevs = append(sysfsDevs, gpus[i].sysfs)
+	}
+	for i, nic := range nics {
+		sysfs := c.readSysfsDevice(nic.BusID)
+		d.Devices = append(d.Devices, Device{
+			Label:       fmt.Sprintf("%s%d", DeviceTypeNIC, i),
+			Type:        DeviceTypeNIC,
+			ID:          nic.Name,
+			BusID:       sysfs.BusID,
+			NUMANode:    sysfs.NUMANode,
+			CPUAffinity: sysfs.LocalCPUList,
+		})
+		sysfsDevs = append(sysfsDevs, sysfs)
+	}
+
+	d.Matrix = make([][]string, len(d.Devices))
+	for i := range d.Devices {
+		d.Matrix[i] = make([]string, len(d.Devices))
+		for j := range d.Devices {
+			if i == j {
+				d.Matrix[i][j] = nvidianvml.TopologyLinkSelf
+				continue
+			}
+			if i >= len(gpus) || j >= len(gpus) {
+				d.Matrix[i][j] = classifySysfsLink(sysfsDevs[i], sysfsDevs[j])
+				continue
+			}
+
+			link, err := c.getGPULink(gpus[i], gpus[j])
+			if err != nil {
+				log.Logger.Errorw("error getting topology link between devices", "uuid1", gpus[i].uuid, "uuid2", gpus[j].uuid, "error", err)
+
+				d.err = err
+				d.health = apiv1.HealthStateTypeUnhealthy
+				d.reason = fmt.Sprintf("error getting topology link between devices %s and %s", gpus[i].uuid, gpus[j].uuid)
+				return d
+			}
+			d.Matrix[i][j] = link
+		}
+	}
+
+	d.Mismatches = checkReference(d.Devices, d.Matrix, c.getReferenceFunc())
+	if len(d.Mismatches) > 0 {
+		d.health = apiv1.HealthStateTypeDegraded
+		d.reason = fmt.Sprintf("topology differs from the reference (%s)", strings.Join(d.Mismatches, "; "))
+		return d
+	}
+
+	d.health = apiv1.HealthStateTypeHealthy
+	d.reason = fmt.Sprintf("all %d GPU(s) and %d NIC(s) were checked, no topology issue found", len(gpus), len(nics))
+
+	return d
+}
+
+// readSysfsDevice reads the PCI device from the sysfs, and returns the device
+// with the unknown NUMA node if not found (e.g., the GPU passed through without the sysfs).
+func (c *component) readSysfsDevice(busID string) pci.SysfsDevice {
+	dev, err := c.readSysfsDeviceFunc(busID)
+	if err != nil {
+		log.Logger.Debugw("error reading pci device from sysfs", "busID", busID, "error", err)
+		return pci.SysfsDevice{BusID: pci.NormalizeBusID(busID), NUMANode: -1}
+	}
+	return dev
+}
+
+// getGPULink returns the link type between the two GPUs,
+// preferring the NVLink connections over the PCIe topology.
+func (c *component) getGPULink(gpu1 gpuDevice, gpu2 gpuDevice) (string, error) {
+	links := gpu1.peers.GPULinks[gpu2.sysfs.BusID]
+	if links == 0 && gpu1.peers.SwitchLinks > 0 && gpu2.peers.SwitchLinks > 0 {
+		// all the GPUs connected to the NVSwitches reach each other
+		links = gpu1.peers.SwitchLinks
+		if gpu2.peers.SwitchLinks < links {
+			links = gpu2.peers.SwitchLinks
+		}
+	}
+	if links > 0 {
+		return fmt.Sprintf("NV%d", links), nil
+	}
+
+	link, err := c.getTopologyLinkFunc(gpu1.dev, gpu2.dev)
+	if err != nil {
+		return "", err
+	}
+	if link == "" {
+		link = classifySysfsLink(gpu1.sysfs, gpu2.sysfs)
+	}
+	return link, nil
+}
+
+var _ components.CheckResult = &Data{}
+
+type Data struct {
+	// Devices is the list of the GPUs and the NICs in the matrix order.
+	Devices []Device `json:"devices,omitempty"`
+	// Matrix is the link types between the devices,
+	// where Matrix[i][j] is the link between Devices[i] and Devices[j]
+	// (e.g., "NV18", "PIX", "SYS", empty if unknown).
+	Matrix [][]string `json:"matrix,omitempty"`
+	// Mismatches is the list of the differences from the reference topology.
+	Mismatches []string `json:"mismatches,omitempty"`
+
+	// timestamp of the last check
+	ts time.Time
+	// error from the last check
+	err error
+
+	// tracks the healthy evaluation result of the last check
+	health apiv1.HealthStateType
+	// tracks the reason of the last check
+	reason string
+}
+
+func (d *Data) String() string {
+	if d == nil {
+		return ""
+	}
+	if len(d.Devices) == 0 {
+		return "no data"
+	}
+
+	buf := bytes.NewBuffer(nil)
+	table := tablewriter.NewWriter(buf)
+	table.SetAlignment(tablewriter.ALIGN_CENTER)
+
+	header := []string{""}
+	for _, dev := range d.Devices {
+		header = append(header, dev.Label)
+	}
+	header = append(header, "CPU Affinity", "NUMA Affinity")
+	table.SetHeader(header)
+
+	for i, dev := range d.Devices {
+		row := []string{dev.Label}
+		for _, link := range d.Matrix[i] {
+			row = append(row, displayLink(link))
+		}
+		numaNode := "N/A"
+		if dev.NUMANode >= 0 {
+			numaNode = fmt.Sprintf("%d", dev.NUMANode)
+		}
+		cpuAffinity := dev.CPUAffinity
+		if cpuAffinity == "" {
+			cpuAffinity = "N/A"
+		}
+		row = append(row, cpuAffinity, numaNode)
+		table.Append(row)
+	}
+	table.Render()
+
+	buf.WriteString("\n")
+
+	legend := tablewriter.NewWriter(buf)
+	legend.SetAlignment(tablewriter.ALIGN_CENTER)
+	legend.SetHeader([]string{"Label", "Type", "ID", "Bus ID"})
+	for _, dev := range d.Devices {
+		legend.Append([]string{dev.Label, dev.Type, dev.ID, dev.BusID})
+	}
+	legend.Render()
+
+	return buf.String()
+}
+
+func (d *Data) Summary() string {
+	if d == nil {
+		return ""
+	}
+	return d.reason
+}
+
+func (d *Data) HealthState() apiv1.HealthStateType {
+	if d == nil {
+		return ""
+	}
+	return d.health
+}
+
+func (d *Data) getError() string {
+	if d == nil || d.err == nil {
+		return ""
+	}
+	return d.err.Error()
+}
+
+func (d *Data) getLastHealthStates() apiv1.HealthStates {
+	if d == nil {
+		return apiv1.HealthStates{
+			{
+				Name:   Name,
+				Health: apiv1.HealthStateTypeHealthy,
+				Reason: "no data yet",
+			},
+		}
+	}
+
+	state := apiv1.HealthState{
+		Name:   Name,
+		Reason: d.reason,
+		Error:  d.getError(),
+		Health: d.health,
+	}
+
+	b, _ := json.Marshal(d)
+	state.DeprecatedExtraInfo = map[string]string{
+		"data":     string(b),
+		"encoding": "json",
+	}
+	return apiv1.HealthStates{state}
+}
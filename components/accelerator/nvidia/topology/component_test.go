@@ -0,0 +1,275 @@
+package topology
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/NVIDIA/go-nvlib/pkg/nvlib/device"
+	"github.com/NVIDIA/go-nvml/pkg/nvml/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	apiv1 "github.com/leptonai/gpud/api/v1"
+	"github.com/leptonai/gpud/components"
+	nvidianvml "github.com/leptonai/gpud/pkg/nvidia-query/nvml"
+	nvml_lib "github.com/leptonai/gpud/pkg/nvidia-query/nvml/lib"
+	"github.com/leptonai/gpud/pkg/nvidia-query/nvml/testutil"
+	"github.com/leptonai/gpud/pkg/pci"
+)
+
+// MockNvmlInstance implements the nvidianvml.InstanceV2 interface for testing
+type MockNvmlInstance struct {
+	devicesFunc func() map[string]device.Device
+}
+
+func (m *MockNvmlInstance) Devices() map[string]device.Device {
+	if m.devicesFunc != nil {
+		return m.devicesFunc()
+	}
+	return nil
+}
+
+func (m *MockNvmlInstance) GetMemoryErrorManagementCapabilities() nvidianvml.MemoryErrorManagementCapabilities {
+	return nvidianvml.MemoryErrorManagementCapabilities{}
+}
+
+func (m *MockNvmlInstance) ProductName() string {
+	return "NVIDIA Test GPU"
+}
+
+func (m *MockNvmlInstance) NVMLExists() bool {
+	return true
+}
+
+func (m *MockNvmlInstance) Library() nvml_lib.Library {
+	return nil
+}
+
+func (m *MockNvmlInstance) Shutdown() error {
+	return nil
+}
+
+// MockTopologyComponent creates a component with two GPUs and two NICs,
+// where GPU0 and NIC0 are on the NUMA node 0, and GPU1 and NIC1 are on the NUMA node 1.
+func MockTopologyComponent(ctx context.Context) *component {
+	cctx, cancel := context.WithCancel(ctx)
+
+	devs := map[string]device.Device{
+		"GPU-1": testutil.NewMockDevice(&mock.Device{}, "hopper", "Nvidia", "9.0", "0000:97:00.0"),
+		"GPU-0": testutil.NewMockDevice(&mock.Device{}, "hopper", "Nvidia", "9.0", "0000:1a:00.0"),
+	}
+	sysfsDevs := map[string]pci.SysfsDevice{
+		"0000:1a:00.0": newSysfsDevice("pci0000:17/0000:17:01.0/0000:18:00.0/0000:19:00.0/0000:1a:00.0", 0),
+		"0000:1b:00.0": newSysfsDevice("pci0000:17/0000:17:01.0/0000:18:00.0/0000:19:01.0/0000:1b:00.0", 0),
+		"0000:97:00.0": newSysfsDevice("pci0000:96/0000:96:01.0/0000:97:00.0", 1),
+		"0000:98:00.0": newSysfsDevice("pci0000:96/0000:96:02.0/0000:98:00.0", 1),
+	}
+
+	return &component{
+		ctx:    cctx,
+		cancel: cancel,
+		nvmlInstance: &MockNvmlInstance{
+			devicesFunc: func() map[string]device.Device { return devs },
+		},
+		numCPUs: 96,
+		getTopologyLinkFunc: func(dev1 device.Device, dev2 device.Device) (string, error) {
+			return nvidianvml.TopologyLinkSYS, nil
+		},
+		getCPUAffinityFunc: func(dev device.Device, numCPUs int) ([]int, error) {
+			var cpus []int
+			start := 0
+			if busID, _ := dev.GetPCIBusID(); busID == "0000:97:00.0" {
+				start = 48
+			}
+			for i := start; i < start+48; i++ {
+				cpus = append(cpus, i)
+			}
+			return cpus, nil
+		},
+		getNVLinkPeersFunc: func(dev device.Device) (nvidianvml.NVLinkPeers, error) {
+			return nvidianvml.NVLinkPeers{SwitchLinks: 18}, nil
+		},
+		readSysfsDeviceFunc: func(busID string) (pci.SysfsDevice, error) {
+			dev, ok := sysfsDevs[busID]
+			if !ok {
+				return pci.SysfsDevice{}, fmt.Errorf("pci device %q not found", busID)
+			}
+			return dev, nil
+		},
+		listNICsFunc: func() ([]pci.ClassDevice, error) {
+			return []pci.ClassDevice{
+				{Name: "mlx5_0", BusID: "0000:1b:00.0"},
+				{Name: "mlx5_1", BusID: "0000:98:00.0"},
+			}, nil
+		},
+		getReferenceFunc: GetDefaultReference,
+	}
+}
+
+func TestNew(t *testing.T) {
+	c, err := New(&components.GPUdInstance{
+		RootCtx:      context.Background(),
+		NVMLInstance: &MockNvmlInstance{},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, Name, c.Name())
+
+	tc := c.(*component)
+	assert.Positive(t, tc.numCPUs)
+	assert.NotNil(t, tc.getTopologyLinkFunc)
+	assert.NotNil(t, tc.getCPUAffinityFunc)
+	assert.NotNil(t, tc.getNVLinkPeersFunc)
+	assert.NotNil(t, tc.readSysfsDeviceFunc)
+	assert.NotNil(t, tc.listNICsFunc)
+	assert.NotNil(t, tc.getReferenceFunc)
+	assert.NoError(t, c.Close())
+}
+
+func TestCheck(t *testing.T) {
+	c := MockTopologyComponent(context.Background())
+	defer c.Close()
+
+	d := c.Check().(*Data)
+	assert.Equal(t, apiv1.HealthStateTypeHealthy, d.health)
+	assert.Equal(t, "all 2 GPU(s) and 2 NIC(s) were checked, no topology issue found", d.reason)
+
+	require.Len(t, d.Devices, 4)
+	assert.Equal(t, Device{Label: "GPU0", Type: DeviceTypeGPU, ID: "GPU-0", BusID: "0000:1a:00.0", NUMANode: 0, CPUAffinity: "0-47"}, d.Devices[0])
+	assert.Equal(t, Device{Label: "GPU1", Type: DeviceTypeGPU, ID: "GPU-1", BusID: "0000:97:00.0", NUMANode: 1, CPUAffinity: "48-95"}, d.Devices[1])
+	assert.Equal(t, Device{Label: "NIC0", Type: DeviceTypeNIC, ID: "mlx5_0", BusID: "0000:1b:00.0", NUMANode: 0}, d.Devices[2])
+	assert.Equal(t, "NIC1", d.Devices[3].Label)
+
+	assert.Equal(t, [][]string{
+		{"X", "NV18", "PIX", "SYS"},
+		{"NV18", "X", "SYS", "PHB"},
+		{"PIX", "SYS", "X", "SYS"},
+		{"SYS", "PHB", "SYS", "X"},
+	}, d.Matrix)
+
+	s := d.String()
+	assert.Contains(t, s, "NV18")
+	assert.Contains(t, s, "48-95")
+	assert.Contains(t, s, "mlx5_1")
+
+	states := c.LastHealthStates()
+	require.Len(t, states, 1)
+	assert.Equal(t, apiv1.HealthStateTypeHealthy, states[0].Health)
+	assert.Contains(t, states[0].DeprecatedExtraInfo["data"], `"matrix":[["X","NV18","PIX","SYS"]`)
+}
+
+func TestCheck_PCIeOnly(t *testing.T) {
+	c := MockTopologyComponent(context.Background())
+	defer c.Close()
+
+	c.getNVLinkPeersFunc = func(dev device.Device) (nvidianvml.NVLinkPeers, error) {
+		return nvidianvml.NVLinkPeers{}, nil
+	}
+	d := c.Check().(*Data)
+	assert.Equal(t, nvidianvml.TopologyLinkSYS, d.Matrix[0][1])
+
+	// falls back to the sysfs if not supported by nvml
+	c.getTopologyLinkFunc = func(dev1 device.Device, dev2 device.Device) (string, error) {
+		return "", nil
+	}
+	c.readSysfsDeviceFunc = func(busID string) (pci.SysfsDevice, error) {
+		return newSysfsDevice(filepath.Join("pci0000:17/0000:17:01.0", busID), 0), nil
+	}
+	d = c.Check().(*Data)
+	assert.Equal(t, nvidianvml.TopologyLinkPIX, d.Matrix[0][1])
+}
+
+func TestCheck_Reference(t *testing.T) {
+	c := MockTopologyComponent(context.Background())
+	defer c.Close()
+
+	// NIC1 is expected next to GPU0
+	c.getReferenceFunc = func() Reference {
+		return Reference{
+			Links:     map[string]map[string]string{"GPU0": {"GPU1": "NV18", "NIC1": "PIX"}},
+			NUMANodes: map[string]int{"NIC1": 0},
+		}
+	}
+
+	d := c.Check().(*Data)
+	assert.Equal(t, apiv1.HealthStateTypeDegraded, d.health)
+	assert.Equal(t, []string{"GPU0-NIC1 link is SYS (expected PIX)", "NIC1 is on numa node 1 (expected 0)"}, d.Mismatches)
+	assert.Equal(t, "topology differs from the reference (GPU0-NIC1 link is SYS (expected PIX); NIC1 is on numa node 1 (expected 0))", d.reason)
+}
+
+func TestCheck_MissingSysfs(t *testing.T) {
+	c := MockTopologyComponent(context.Background())
+	defer c.Close()
+
+	c.readSysfsDeviceFunc = func(busID string) (pci.SysfsDevice, error) {
+		return pci.SysfsDevice{}, errors.New("not found")
+	}
+	c.listNICsFunc = func() ([]pci.ClassDevice, error) {
+		return nil, errors.New("list error")
+	}
+
+	d := c.Check().(*Data)
+	assert.Equal(t, apiv1.HealthStateTypeHealthy, d.health)
+	require.Len(t, d.Devices, 2)
+	assert.Equal(t, -1, d.Devices[0].NUMANode)
+	assert.Equal(t, "NV18", d.Matrix[0][1])
+	assert.Contains(t, d.String(), "N/A")
+}
+
+func TestCheck_Error(t *testing.T) {
+	c := MockTopologyComponent(context.Background())
+	defer c.Close()
+
+	c.getNVLinkPeersFunc = func(dev device.Device) (nvidianvml.NVLinkPeers, error) {
+		return nvidianvml.NVLinkPeers{}, nil
+	}
+	c.getTopologyLinkFunc = func(dev1 device.Device, dev2 device.Device) (string, error) {
+		return "", errors.New("topology error")
+	}
+
+	d := c.Check().(*Data)
+	assert.Equal(t, apiv1.HealthStateTypeUnhealthy, d.health)
+	assert.Contains(t, d.reason, "error getting topology link between devices")
+	assert.Equal(t, "topology error", d.getError())
+
+	c.getCPUAffinityFunc = func(dev device.Device, numCPUs int) ([]int, error) {
+		return nil, errors.New("affinity error")
+	}
+	d = c.Check().(*Data)
+	assert.Equal(t, apiv1.HealthStateTypeUnhealthy, d.health)
+	assert.Contains(t, d.reason, "error getting cpu affinity for device")
+}
+
+func TestCheck_NilNVML(t *testing.T) {
+	c := MockTopologyComponent(context.Background())
+	defer c.Close()
+	c.nvmlInstance = nil
+
+	d := c.Check().(*Data)
+	assert.Equal(t, apiv1.HealthStateTypeHealthy, d.health)
+	assert.Equal(t, "NVIDIA NVML instance is nil", d.reason)
+	assert.Equal(t, "no data", d.String())
+}
+
+func TestLastHealthStates_NoData(t *testing.T) {
+	c := MockTopologyComponent(context.Background())
+	defer c.Close()
+
+	states := c.LastHealthStates()
+	require.Len(t, states, 1)
+	assert.Equal(t, apiv1.HealthStateTypeHealthy, states[0].Health)
+	assert.Equal(t, "no data yet", states[0].Reason)
+}
+
+func TestDefaultReference(t *testing.T) {
+	orig := GetDefaultReference()
+	defer SetDefaultReference(orig)
+
+	assert.Empty(t, orig.Links)
+	assert.Empty(t, orig.NUMANodes)
+
+	SetDefaultReference(Reference{NUMANodes: map[string]int{"NIC0": 0}})
+	assert.Equal(t, map[string]int{"NIC0": 0}, GetDefaultReference().NUMANodes)
+}
@@ -0,0 +1,146 @@
+package topology
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	nvidianvml "github.com/leptonai/gpud/pkg/nvidia-query/nvml"
+	"github.com/leptonai/gpud/pkg/pci"
+)
+
+const (
+	DeviceTypeGPU = "GPU"
+	DeviceTypeNIC = "NIC"
+)
+
+// Device is a device in the topology matrix.
+type Device struct {
+	// Label is the label of the device in the matrix (e.g., "GPU0", "NIC0"),
+	// numbered by the PCI bus ID order.
+	Label string `json:"label"`
+	// Type is the device type ("GPU" or "NIC").
+	Type string `json:"type"`
+	// ID is the GPU UUID or the NIC name (e.g., "mlx5_0").
+	ID string `json:"id"`
+	// BusID is the PCI bus ID of the device (e.g., "0000:18:00.0").
+	BusID string `json:"bus_id"`
+	// NUMANode is the NUMA node of the device, -1 if unknown.
+	NUMANode int `json:"numa_node"`
+	// CPUAffinity is the list of the CPUs local to the device (e.g., "0-47"),
+	// empty if unknown.
+	CPUAffinity string `json:"cpu_affinity,omitempty"`
+}
+
+// classifySysfsLink returns the link type between the two PCI devices
+// based on their sysfs hierarchy, or an empty string if unknown.
+func classifySysfsLink(a pci.SysfsDevice, b pci.SysfsDevice) string {
+	if a.Path == "" || b.Path == "" {
+		return ""
+	}
+	if a.BusID == b.BusID {
+		return nvidianvml.TopologyLinkSelf
+	}
+
+	ancA, ancB := a.Ancestors(), b.Ancestors()
+	common := 0
+	for common < len(ancA) && common < len(ancB) && ancA[common] == ancB[common] {
+		common++
+	}
+
+	if common > 0 && a.RootComplex() == b.RootComplex() {
+		// diverging right below the common bridge (e.g., the downstream ports of the same PCIe switch)
+		if len(ancA)-common <= 1 && len(ancB)-common <= 1 {
+			return nvidianvml.TopologyLinkPIX
+		}
+		return nvidianvml.TopologyLinkPXB
+	}
+
+	if a.RootComplex() != "" && a.RootComplex() == b.RootComplex() {
+		return nvidianvml.TopologyLinkPHB
+	}
+	if a.NUMANode >= 0 && a.NUMANode == b.NUMANode {
+		return nvidianvml.TopologyLinkNode
+	}
+	return nvidianvml.TopologyLinkSYS
+}
+
+// formatCPUList formats the sorted CPU IDs in the sysfs cpulist format
+// (e.g., [0 1 2 3 8] to "0-3,8").
+func formatCPUList(cpus []int) string {
+	var ranges []string
+	for i := 0; i < len(cpus); {
+		j := i
+		for j+1 < len(cpus) && cpus[j+1] == cpus[j]+1 {
+			j++
+		}
+		if i == j {
+			ranges = append(ranges, fmt.Sprintf("%d", cpus[i]))
+		} else {
+			ranges = append(ranges, fmt.Sprintf("%d-%d", cpus[i], cpus[j]))
+		}
+		i = j + 1
+	}
+	return strings.Join(ranges, ",")
+}
+
+// checkReference returns the sorted descriptions of the differences
+// between the topology and the reference, or nil if matched.
+func checkReference(devices []Device, matrix [][]string, ref Reference) []string {
+	indexes := make(map[string]int, len(devices))
+	for i, dev := range devices {
+		indexes[dev.Label] = i
+	}
+
+	var mismatches []string
+	for label, expected := range ref.NUMANodes {
+		i, ok := indexes[label]
+		if !ok {
+			mismatches = append(mismatches, fmt.Sprintf("%s not found", label))
+			continue
+		}
+		if devices[i].NUMANode != expected {
+			mismatches = append(mismatches, fmt.Sprintf("%s is on numa node %d (expected %d)", label, devices[i].NUMANode, expected))
+		}
+	}
+
+	for label, links := range ref.Links {
+		i, ok := indexes[label]
+		if !ok {
+			mismatches = append(mismatches, fmt.Sprintf("%s not found", label))
+			continue
+		}
+		for peer, expected := range links {
+			j, ok := indexes[peer]
+			if !ok {
+				mismatches = append(mismatches, fmt.Sprintf("%s not found", peer))
+				continue
+			}
+			if matrix[i][j] != expected {
+				mismatches = append(mismatches, fmt.Sprintf("%s-%s link is %s (expected %s)", label, peer, displayLink(matrix[i][j]), expected))
+			}
+		}
+	}
+
+	if len(mismatches) == 0 {
+		return nil
+	}
+
+	// dedup the missing devices referred multiple times
+	sort.Strings(mismatches)
+	deduped := mismatches[:1]
+	for _, m := range mismatches[1:] {
+		if m != deduped[len(deduped)-1] {
+			deduped = append(deduped, m)
+		}
+	}
+	return deduped
+}
+
+// displayLink returns the link type to display, "N/A" if unknown.
+func displayLink(link string) string {
+	if link == "" {
+		return "N/A"
+	}
+	return link
+}
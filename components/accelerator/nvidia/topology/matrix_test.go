@@ -0,0 +1,89 @@
+package topology
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	nvidianvml "github.com/leptonai/gpud/pkg/nvidia-query/nvml"
+	"github.com/leptonai/gpud/pkg/pci"
+)
+
+func newSysfsDevice(hierarchy string, numaNode int) pci.SysfsDevice {
+	path := filepath.Join("/sys/devices", hierarchy)
+	return pci.SysfsDevice{BusID: filepath.Base(path), Path: path, NUMANode: numaNode}
+}
+
+func TestClassifySysfsLink(t *testing.T) {
+	// GPU0 and NIC0 under the same PCIe switch, GPU1 under another switch of the same root port,
+	// GPU2 under another root port of the same root complex, GPU3 on the same NUMA node, GPU4 on the other socket
+	gpu0 := newSysfsDevice("pci0000:17/0000:17:01.0/0000:18:00.0/0000:19:00.0/0000:1a:00.0", 0)
+	nic0 := newSysfsDevice("pci0000:17/0000:17:01.0/0000:18:00.0/0000:19:01.0/0000:1b:00.0", 0)
+	gpu1 := newSysfsDevice("pci0000:17/0000:17:01.0/0000:18:00.0/0000:19:02.0/0000:1c:00.0/0000:1d:00.0/0000:1e:00.0", 0)
+	gpu2 := newSysfsDevice("pci0000:17/0000:17:02.0/0000:2a:00.0", 0)
+	gpu3 := newSysfsDevice("pci0000:3a/0000:3a:01.0/0000:3b:00.0", 0)
+	gpu4 := newSysfsDevice("pci0000:97/0000:97:01.0/0000:98:00.0", 1)
+
+	tests := []struct {
+		name     string
+		a, b     pci.SysfsDevice
+		expected string
+	}{
+		{name: "self", a: gpu0, b: gpu0, expected: nvidianvml.TopologyLinkSelf},
+		{name: "same switch", a: gpu0, b: nic0, expected: nvidianvml.TopologyLinkPIX},
+		{name: "multiple switches", a: gpu0, b: gpu1, expected: nvidianvml.TopologyLinkPXB},
+		{name: "same host bridge", a: gpu0, b: gpu2, expected: nvidianvml.TopologyLinkPHB},
+		{name: "same numa node", a: gpu0, b: gpu3, expected: nvidianvml.TopologyLinkNode},
+		{name: "other numa node", a: nic0, b: gpu4, expected: nvidianvml.TopologyLinkSYS},
+		{name: "unknown numa node", a: newSysfsDevice("pci0000:3a/0000:3b:00.0", -1), b: newSysfsDevice("pci0000:97/0000:98:00.0", -1), expected: nvidianvml.TopologyLinkSYS},
+		{name: "not in sysfs", a: gpu0, b: pci.SysfsDevice{BusID: "0000:ff:00.0", NUMANode: -1}, expected: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, classifySysfsLink(tt.a, tt.b))
+			assert.Equal(t, tt.expected, classifySysfsLink(tt.b, tt.a))
+		})
+	}
+}
+
+func TestFormatCPUList(t *testing.T) {
+	assert.Equal(t, "", formatCPUList(nil))
+	assert.Equal(t, "5", formatCPUList([]int{5}))
+	assert.Equal(t, "0-3,8,10-11", formatCPUList([]int{0, 1, 2, 3, 8, 10, 11}))
+}
+
+func TestCheckReference(t *testing.T) {
+	devices := []Device{
+		{Label: "GPU0", Type: DeviceTypeGPU, NUMANode: 0},
+		{Label: "GPU1", Type: DeviceTypeGPU, NUMANode: 1},
+		{Label: "NIC0", Type: DeviceTypeNIC, NUMANode: 1},
+	}
+	matrix := [][]string{
+		{"X", "NV18", "SYS"},
+		{"NV18", "X", ""},
+		{"SYS", "", "X"},
+	}
+
+	assert.Nil(t, checkReference(devices, matrix, Reference{}))
+	assert.Nil(t, checkReference(devices, matrix, Reference{
+		Links:     map[string]map[string]string{"GPU0": {"GPU1": "NV18"}},
+		NUMANodes: map[string]int{"GPU1": 1},
+	}))
+
+	// the NIC plugged into the wrong socket
+	assert.Equal(t, []string{
+		"GPU0-NIC0 link is SYS (expected PIX)",
+		"GPU1-NIC0 link is N/A (expected PIX)",
+		"NIC0 is on numa node 1 (expected 0)",
+	}, checkReference(devices, matrix, Reference{
+		Links:     map[string]map[string]string{"GPU0": {"NIC0": "PIX"}, "GPU1": {"NIC0": "PIX"}},
+		NUMANodes: map[string]int{"NIC0": 0},
+	}))
+
+	// the missing devices are reported once
+	assert.Equal(t, []string{"NIC1 not found"}, checkReference(devices, matrix, Reference{
+		Links:     map[string]map[string]string{"GPU0": {"NIC1": "PIX"}, "NIC1": {"GPU0": "PIX"}},
+		NUMANodes: map[string]int{"NIC1": 1},
+	}))
+}
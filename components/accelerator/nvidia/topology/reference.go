@@ -0,0 +1,38 @@
+package topology
+
+import (
+	"sync"
+
+	"github.com/leptonai/gpud/pkg/log"
+)
+
+// Reference defines the expected topology of the host,
+// where the devices are referred by the labels in the matrix (e.g., "GPU0", "NIC0").
+// Only the specified entries are checked, and the zero value skips the check.
+type Reference struct {
+	// Links maps the device label to the expected link types to the other devices
+	// (e.g., {"GPU0": {"NIC0": "PIX", "GPU1": "NV18"}}).
+	Links map[string]map[string]string `json:"links,omitempty"`
+	// NUMANodes maps the device label to the expected NUMA node
+	// (e.g., {"NIC0": 0, "NIC1": 1}).
+	NUMANodes map[string]int `json:"numa_nodes,omitempty"`
+}
+
+var (
+	defaultReferenceMu sync.RWMutex
+	defaultReference   = Reference{}
+)
+
+func GetDefaultReference() Reference {
+	defaultReferenceMu.RLock()
+	defer defaultReferenceMu.RUnlock()
+	return defaultReference
+}
+
+func SetDefaultReference(ref Reference) {
+	log.Logger.Infow("setting default topology reference", "links", ref.Links, "numaNodes", ref.NUMANodes)
+
+	defaultReferenceMu.Lock()
+	defer defaultReferenceMu.Unlock()
+	defaultReference = ref
+}
@@ -0,0 +1,437 @@
+// Package versioncompliance checks the NVIDIA driver, CUDA, VBIOS, GSP firmware,
+// InfiniBand firmware and kernel versions against the allowed versions.
+package versioncompliance
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/NVIDIA/go-nvlib/pkg/nvlib/device"
+	"github.com/olekukonko/tablewriter"
+
+	apiv1 "github.com/leptonai/gpud/api/v1"
+	"github.com/leptonai/gpud/components"
+	nvidia_common "github.com/leptonai/gpud/pkg/config/common"
+	pkghost "github.com/leptonai/gpud/pkg/host"
+	"github.com/leptonai/gpud/pkg/log"
+	"github.com/leptonai/gpud/pkg/nvidia-query/infiniband"
+	nvidianvml "github.com/leptonai/gpud/pkg/nvidia-query/nvml"
+)
+
+const Name = "accelerator-nvidia-version-compliance"
+
+var _ components.Component = &component{}
+
+type component struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	nvmlInstance   nvidianvml.InstanceV2
+	toolOverwrites nvidia_common.ToolOverwrites
+
+	getDriverVersionFunc   func() (string, error)
+	getCUDAVersionFunc     func() (string, error)
+	getProductNameFunc     func(dev device.Device) (string, error)
+	getVBIOSVersionFunc    func(dev device.Device) (string, error)
+	getGSPFirmwareModeFunc func(uuid string, dev device.Device) (nvidianvml.GSPFirmwareMode, error)
+	getIbstatOutputFunc    func(ctx context.Context, ibstatCommands []string) (*infiniband.IbstatOutput, error)
+	getKernelVersionFunc   func() string
+	getPolicyFunc          func() Policy
+
+	lastMu   sync.RWMutex
+	lastData *Data
+}
+
+func New(gpudInstance *components.GPUdInstance) (components.Component, error) {
+	cctx, ccancel := context.WithCancel(gpudInstance.RootCtx)
+	c := &component{
+		ctx:                    cctx,
+		cancel:                 ccancel,
+		nvmlInstance:           gpudInstance.NVMLInstance,
+		toolOverwrites:         gpudInstance.NVIDIAToolOverwrites,
+		getProductNameFunc:     nvidianvml.GetProductName,
+		getVBIOSVersionFunc:    nvidianvml.GetVBIOSVersion,
+		getGSPFirmwareModeFunc: nvidianvml.GetGSPFirmwareMode,
+		getIbstatOutputFunc:    infiniband.GetIbstatOutput,
+		getKernelVersionFunc:   pkghost.KernelVersion,
+		getPolicyFunc:          GetDefaultPolicy,
+	}
+
+	if gpudInstance.NVMLInstance != nil && gpudInstance.NVMLInstance.NVMLExists() {
+		c.getDriverVersionFunc = func() (string, error) {
+			return nvidianvml.GetSystemDriverVersion(gpudInstance.NVMLInstance.Library().NVML())
+		}
+		c.getCUDAVersionFunc = func() (string, error) {
+			return nvidianvml.GetSystemCUDAVersion(gpudInstance.NVMLInstance.Library().NVML())
+		}
+	}
+
+	return c, nil
+}
+
+func (c *component) Name() string { return Name }
+
+func (c *component) Start() error {
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+
+		for {
+			_ = c.Check()
+
+			select {
+			case <-c.ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return nil
+}
+
+func (c *component) LastHealthStates() apiv1.HealthStates {
+	c.lastMu.RLock()
+	lastData := c.lastData
+	c.lastMu.RUnlock()
+	return lastData.getLastHealthStates()
+}
+
+func (c *component) Events(ctx context.Context, since time.Time) (apiv1.Events, error) {
+	return nil, nil
+}
+
+func (c *component) Close() error {
+	log.Logger.Debugw("closing component")
+
+	c.cancel()
+
+	return nil
+}
+
+func (c *component) Check() components.CheckResult {
+	log.Logger.Infow("checking nvidia version compliance")
+
+	d := &Data{
+		ts: time.Now().UTC(),
+	}
+	defer func() {
+		c.lastMu.Lock()
+		c.lastData = d
+		c.lastMu.Unlock()
+	}()
+
+	if c.nvmlInstance == nil {
+		d.health = apiv1.HealthStateTypeHealthy
+		d.reason = "NVIDIA NVML instance is nil"
+		return d
+	}
+	if !c.nvmlInstance.NVMLExists() {
+		d.health = apiv1.HealthStateTypeHealthy
+		d.reason = "NVIDIA NVML is not loaded"
+		return d
+	}
+
+	var err error
+	d.DriverVersion, err = c.getDriverVersionFunc()
+	if err != nil {
+		log.Logger.Errorw("error getting driver version", "error", err)
+
+		d.err = err
+		d.health = apiv1.HealthStateTypeUnhealthy
+		d.reason = "error getting driver version"
+		return d
+	}
+
+	d.CUDAVersion, err = c.getCUDAVersionFunc()
+	if err != nil {
+		log.Logger.Errorw("error getting cuda version", "error", err)
+
+		d.err = err
+		d.health = apiv1.HealthStateTypeUnhealthy
+		d.reason = "error getting cuda version"
+		return d
+	}
+
+	d.KernelVersion = c.getKernelVersionFunc()
+
+	devs := c.nvmlInstance.Devices()
+	uuids := make([]string, 0, len(devs))
+	for uuid := range devs {
+		uuids = append(uuids, uuid)
+	}
+	sort.Strings(uuids)
+
+	for _, uuid := range uuids {
+		dev := devs[uuid]
+
+		productName, err := c.getProductNameFunc(dev)
+		if err != nil {
+			log.Logger.Errorw("error getting product name for device", "uuid", uuid, "error", err)
+
+			d.err = err
+			d.health = apiv1.HealthStateTypeUnhealthy
+			d.reason = fmt.Sprintf("error getting product name for device %s", uuid)
+			return d
+		}
+
+		vbiosVersion, err := c.getVBIOSVersionFunc(dev)
+		if err != nil {
+			log.Logger.Errorw("error getting vbios version for device", "uuid", uuid, "error", err)
+
+			d.err = err
+			d.health = apiv1.HealthStateTypeUnhealthy
+			d.reason = fmt.Sprintf("error getting vbios version for device %s", uuid)
+			return d
+		}
+
+		gspMode, err := c.getGSPFirmwareModeFunc(uuid, dev)
+		if err != nil {
+			log.Logger.Errorw("error getting gsp firmware mode for device", "uuid", uuid, "error", err)
+
+			d.err = err
+			d.health = apiv1.HealthStateTypeUnhealthy
+			d.reason = fmt.Sprintf("error getting gsp firmware mode for device %s", uuid)
+			return d
+		}
+
+		d.GPUs = append(d.GPUs, GPUVersions{
+			UUID:                 uuid,
+			ProductName:          productName,
+			VBIOSVersion:         vbiosVersion,
+			GSPFirmwareEnabled:   gspMode.Enabled,
+			GSPFirmwareSupported: gspMode.Supported,
+		})
+	}
+
+	policy := c.getPolicyFunc()
+
+	// only runs "ibstat" when the policy requires the firmware versions
+	if !policy.InfinibandFirmware.IsZero() && c.getIbstatOutputFunc != nil {
+		cctx, ccancel := context.WithTimeout(c.ctx, 15*time.Second)
+		o, err := c.getIbstatOutputFunc(cctx, []string{c.toolOverwrites.IbstatCommand})
+		ccancel()
+
+		switch {
+		case errors.Is(err, infiniband.ErrNoIbstatCommand):
+			log.Logger.Warnw("ibstat command not found, skipping infiniband firmware version check")
+		case err != nil:
+			log.Logger.Errorw("error getting ibstat output", "error", err)
+
+			d.err = err
+			d.health = apiv1.HealthStateTypeUnhealthy
+			d.reason = fmt.Sprintf("ibstat command failed: %v", err)
+			return d
+		case o != nil:
+			for _, card := range o.Parsed {
+				d.InfinibandCards = append(d.InfinibandCards, InfinibandCardVersions{
+					Name:            card.Name,
+					FirmwareVersion: card.FirmwareVersion,
+				})
+			}
+		}
+	}
+
+	d.Mismatches = checkPolicy(d, policy)
+	if len(d.Mismatches) > 0 {
+		d.health = apiv1.HealthStateTypeDegraded
+		d.reason = fmt.Sprintf("%d version(s) not compliant (%s)", len(d.Mismatches), strings.Join(d.Mismatches, "; "))
+		return d
+	}
+
+	d.health = apiv1.HealthStateTypeHealthy
+	d.reason = fmt.Sprintf("all %d GPU(s) were checked, all versions compliant", len(d.GPUs))
+
+	return d
+}
+
+// checkPolicy returns the descriptions of the versions that do not comply with the policy,
+// or nil if all compliant.
+func checkPolicy(d *Data, policy Policy) []string {
+	var mismatches []string
+	add := func(desc string) {
+		if desc != "" {
+			mismatches = append(mismatches, desc)
+		}
+	}
+
+	add(policy.Driver.check("driver", d.DriverVersion))
+	add(policy.CUDA.check("cuda", d.CUDAVersion))
+	add(policy.Kernel.check("kernel", d.KernelVersion))
+
+	vbiosByProduct := make(map[string]map[string][]string)
+	for _, gpu := range d.GPUs {
+		add(policy.VBIOS.check(gpu.UUID+" vbios", gpu.VBIOSVersion))
+
+		if policy.GSPFirmwareEnabled != nil && gpu.GSPFirmwareEnabled != *policy.GSPFirmwareEnabled {
+			desc := fmt.Sprintf("%s gsp firmware enabled %v (expected %v)", gpu.UUID, gpu.GSPFirmwareEnabled, *policy.GSPFirmwareEnabled)
+			if !gpu.GSPFirmwareSupported {
+				desc = fmt.Sprintf("%s gsp firmware not supported (expected enabled %v)", gpu.UUID, *policy.GSPFirmwareEnabled)
+			}
+			add(desc)
+		}
+
+		if gpu.VBIOSVersion == "" {
+			continue
+		}
+		if _, ok := vbiosByProduct[gpu.ProductName]; !ok {
+			vbiosByProduct[gpu.ProductName] = make(map[string][]string)
+		}
+		vbiosByProduct[gpu.ProductName][gpu.VBIOSVersion] = append(vbiosByProduct[gpu.ProductName][gpu.VBIOSVersion], gpu.UUID)
+	}
+
+	if !policy.AllowHeterogeneousVBIOS {
+		products := make([]string, 0, len(vbiosByProduct))
+		for product := range vbiosByProduct {
+			products = append(products, product)
+		}
+		sort.Strings(products)
+
+		for _, product := range products {
+			versions := vbiosByProduct[product]
+			if len(versions) < 2 {
+				continue
+			}
+			vers := make([]string, 0, len(versions))
+			for ver := range versions {
+				vers = append(vers, fmt.Sprintf("%s (%d GPU(s))", ver, len(versions[ver])))
+			}
+			sort.Strings(vers)
+			add(fmt.Sprintf("heterogeneous vbios versions across %s GPUs: %s", product, strings.Join(vers, ", ")))
+		}
+	}
+
+	for _, card := range d.InfinibandCards {
+		add(policy.InfinibandFirmware.check(card.Name+" infiniband firmware", card.FirmwareVersion))
+	}
+
+	return mismatches
+}
+
+// GPUVersions is the versions of a GPU.
+type GPUVersions struct {
+	UUID                 string `json:"uuid"`
+	ProductName          string `json:"product_name"`
+	VBIOSVersion         string `json:"vbios_version"`
+	GSPFirmwareEnabled   bool   `json:"gsp_firmware_enabled"`
+	GSPFirmwareSupported bool   `json:"gsp_firmware_supported"`
+}
+
+// InfinibandCardVersions is the firmware version of an InfiniBand card.
+type InfinibandCardVersions struct {
+	Name            string `json:"name"`
+	FirmwareVersion string `json:"firmware_version"`
+}
+
+var _ components.CheckResult = &Data{}
+
+type Data struct {
+	DriverVersion   string                   `json:"driver_version"`
+	CUDAVersion     string                   `json:"cuda_version"`
+	KernelVersion   string                   `json:"kernel_version"`
+	GPUs            []GPUVersions            `json:"gpus,omitempty"`
+	InfinibandCards []InfinibandCardVersions `json:"infiniband_cards,omitempty"`
+	// Mismatches is the list of the versions that do not comply with the policy.
+	Mismatches []string `json:"mismatches,omitempty"`
+
+	// timestamp of the last check
+	ts time.Time
+	// error from the last check
+	err error
+
+	// tracks the healthy evaluation result of the last check
+	health apiv1.HealthStateType
+	// tracks the reason of the last check
+	reason string
+}
+
+func (d *Data) String() string {
+	if d == nil {
+		return ""
+	}
+	if d.DriverVersion == "" {
+		return "no data"
+	}
+
+	buf := bytes.NewBuffer(nil)
+	table := tablewriter.NewWriter(buf)
+	table.SetAlignment(tablewriter.ALIGN_CENTER)
+	table.Append([]string{"Driver Version", d.DriverVersion})
+	table.Append([]string{"CUDA Version", d.CUDAVersion})
+	table.Append([]string{"Kernel Version", d.KernelVersion})
+	for _, card := range d.InfinibandCards {
+		table.Append([]string{card.Name + " Firmware Version", card.FirmwareVersion})
+	}
+	table.Render()
+
+	if len(d.GPUs) > 0 {
+		buf.WriteString("\n")
+
+		gpuTable := tablewriter.NewWriter(buf)
+		gpuTable.SetAlignment(tablewriter.ALIGN_CENTER)
+		gpuTable.SetHeader([]string{"GPU UUID", "Product", "VBIOS Version", "GSP Firmware Enabled"})
+		for _, gpu := range d.GPUs {
+			gsp := fmt.Sprintf("%v", gpu.GSPFirmwareEnabled)
+			if !gpu.GSPFirmwareSupported {
+				gsp = "not supported"
+			}
+			gpuTable.Append([]string{gpu.UUID, gpu.ProductName, gpu.VBIOSVersion, gsp})
+		}
+		gpuTable.Render()
+	}
+
+	return buf.String()
+}
+
+func (d *Data) Summary() string {
+	if d == nil {
+		return ""
+	}
+	return d.reason
+}
+
+func (d *Data) HealthState() apiv1.HealthStateType {
+	if d == nil {
+		return ""
+	}
+	return d.health
+}
+
+func (d *Data) getError() string {
+	if d == nil || d.err == nil {
+		return ""
+	}
+	return d.err.Error()
+}
+
+func (d *Data) getLastHealthStates() apiv1.HealthStates {
+	if d == nil {
+		return apiv1.HealthStates{
+			{
+				Name:   Name,
+				Health: apiv1.HealthStateTypeHealthy,
+				Reason: "no data yet",
+			},
+		}
+	}
+
+	state := apiv1.HealthState{
+		Name:   Name,
+		Reason: d.reason,
+		Error:  d.getError(),
+		Health: d.health,
+	}
+
+	b, _ := json.Marshal(d)
+	state.DeprecatedExtraInfo = map[string]string{
+		"data":     string(b),
+		"encoding": "json",
+	}
+	return apiv1.HealthStates{state}
+}
@@ -0,0 +1,279 @@
+package versioncompliance
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/NVIDIA/go-nvlib/pkg/nvlib/device"
+	"github.com/NVIDIA/go-nvml/pkg/nvml/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	apiv1 "github.com/leptonai/gpud/api/v1"
+	"github.com/leptonai/gpud/components"
+	"github.com/leptonai/gpud/pkg/nvidia-query/infiniband"
+	nvidianvml "github.com/leptonai/gpud/pkg/nvidia-query/nvml"
+	nvml_lib "github.com/leptonai/gpud/pkg/nvidia-query/nvml/lib"
+	"github.com/leptonai/gpud/pkg/nvidia-query/nvml/testutil"
+)
+
+// MockNvmlInstance implements the nvidianvml.InstanceV2 interface for testing
+type MockNvmlInstance struct {
+	devicesFunc func() map[string]device.Device
+}
+
+func (m *MockNvmlInstance) Devices() map[string]device.Device {
+	if m.devicesFunc != nil {
+		return m.devicesFunc()
+	}
+	return nil
+}
+
+func (m *MockNvmlInstance) GetMemoryErrorManagementCapabilities() nvidianvml.MemoryErrorManagementCapabilities {
+	return nvidianvml.MemoryErrorManagementCapabilities{}
+}
+
+func (m *MockNvmlInstance) ProductName() string {
+	return "NVIDIA Test GPU"
+}
+
+func (m *MockNvmlInstance) NVMLExists() bool {
+	return true
+}
+
+func (m *MockNvmlInstance) Library() nvml_lib.Library {
+	return nil
+}
+
+func (m *MockNvmlInstance) Shutdown() error {
+	return nil
+}
+
+// MockVersionComplianceComponent creates a component with the GPUs
+// mapped from the UUID to the VBIOS version.
+func MockVersionComplianceComponent(ctx context.Context, vbiosVersions map[string]string, policy Policy) *component {
+	cctx, cancel := context.WithCancel(ctx)
+
+	devs := make(map[string]device.Device, len(vbiosVersions))
+	for uuid := range vbiosVersions {
+		devs[uuid] = testutil.NewMockDevice(&mock.Device{}, "hopper", "Nvidia", "9.0", "0000:18:00.0")
+	}
+	uuidsByDev := make(map[device.Device]string, len(devs))
+	for uuid, dev := range devs {
+		uuidsByDev[dev] = uuid
+	}
+
+	return &component{
+		ctx:    cctx,
+		cancel: cancel,
+		nvmlInstance: &MockNvmlInstance{
+			devicesFunc: func() map[string]device.Device { return devs },
+		},
+		getDriverVersionFunc: func() (string, error) { return "535.161.08", nil },
+		getCUDAVersionFunc:   func() (string, error) { return "12.2", nil },
+		getProductNameFunc: func(dev device.Device) (string, error) {
+			return "NVIDIA H100 80GB HBM3", nil
+		},
+		getVBIOSVersionFunc: func(dev device.Device) (string, error) {
+			return vbiosVersions[uuidsByDev[dev]], nil
+		},
+		getGSPFirmwareModeFunc: func(uuid string, dev device.Device) (nvidianvml.GSPFirmwareMode, error) {
+			return nvidianvml.GSPFirmwareMode{UUID: uuid, Enabled: true, Supported: true}, nil
+		},
+		getIbstatOutputFunc: func(ctx context.Context, ibstatCommands []string) (*infiniband.IbstatOutput, error) {
+			return &infiniband.IbstatOutput{
+				Parsed: infiniband.IBStatCards{
+					{Name: "mlx5_0", FirmwareVersion: "28.39.1002"},
+					{Name: "mlx5_1", FirmwareVersion: "28.36.1010"},
+				},
+			}, nil
+		},
+		getKernelVersionFunc: func() string { return "5.15.0-1045-aws" },
+		getPolicyFunc:        func() Policy { return policy },
+	}
+}
+
+func boolPtr(b bool) *bool { return &b }
+
+func TestNew(t *testing.T) {
+	c, err := New(&components.GPUdInstance{
+		RootCtx:      context.Background(),
+		NVMLInstance: &MockNvmlInstance{},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, Name, c.Name())
+
+	tc := c.(*component)
+	assert.NotNil(t, tc.getDriverVersionFunc)
+	assert.NotNil(t, tc.getCUDAVersionFunc)
+	assert.NotNil(t, tc.getVBIOSVersionFunc)
+	assert.NotNil(t, tc.getPolicyFunc)
+	assert.NoError(t, c.Close())
+}
+
+func TestCheck_Compliant(t *testing.T) {
+	c := MockVersionComplianceComponent(context.Background(), map[string]string{
+		"GPU-0": "96.00.89.00.01",
+		"GPU-1": "96.00.89.00.01",
+	}, Policy{
+		Driver:             VersionRange{Min: "535.161"},
+		CUDA:               VersionRange{Min: "12.2", Max: "12"},
+		VBIOS:              VersionRange{Min: "96.00.89"},
+		GSPFirmwareEnabled: boolPtr(true),
+		InfinibandFirmware: VersionRange{Min: "28.36"},
+		Kernel:             VersionRange{Min: "5.15", Max: "6.8"},
+	})
+	defer c.Close()
+
+	d := c.Check().(*Data)
+	assert.Equal(t, apiv1.HealthStateTypeHealthy, d.health)
+	assert.Equal(t, "all 2 GPU(s) were checked, all versions compliant", d.reason)
+	assert.Equal(t, "535.161.08", d.DriverVersion)
+	assert.Equal(t, "12.2", d.CUDAVersion)
+	assert.Equal(t, "5.15.0-1045-aws", d.KernelVersion)
+	require.Len(t, d.GPUs, 2)
+	assert.Equal(t, "GPU-0", d.GPUs[0].UUID)
+	require.Len(t, d.InfinibandCards, 2)
+	assert.Empty(t, d.Mismatches)
+
+	s := d.String()
+	assert.Contains(t, s, "96.00.89.00.01")
+	assert.Contains(t, s, "mlx5_1 Firmware Version")
+}
+
+func TestCheck_NotCompliant(t *testing.T) {
+	c := MockVersionComplianceComponent(context.Background(), map[string]string{
+		"GPU-0": "96.00.89.00.01",
+		"GPU-1": "96.00.74.00.0E",
+	}, Policy{
+		Driver:             VersionRange{Min: "550"},
+		VBIOS:              VersionRange{Min: "96.00.89"},
+		GSPFirmwareEnabled: boolPtr(false),
+		InfinibandFirmware: VersionRange{Min: "28.39"},
+	})
+	defer c.Close()
+
+	d := c.Check().(*Data)
+	assert.Equal(t, apiv1.HealthStateTypeDegraded, d.health)
+	assert.Equal(t, []string{
+		"driver version 535.161.08 is not allowed (allowed >= 550)",
+		"GPU-0 gsp firmware enabled true (expected false)",
+		"GPU-1 vbios version 96.00.74.00.0E is not allowed (allowed >= 96.00.89)",
+		"GPU-1 gsp firmware enabled true (expected false)",
+		"heterogeneous vbios versions across NVIDIA H100 80GB HBM3 GPUs: 96.00.74.00.0E (1 GPU(s)), 96.00.89.00.01 (1 GPU(s))",
+		"mlx5_1 infiniband firmware version 28.36.1010 is not allowed (allowed >= 28.39)",
+	}, d.Mismatches)
+	assert.Contains(t, d.reason, "6 version(s) not compliant (driver version 535.161.08 is not allowed")
+
+	states := c.LastHealthStates()
+	require.Len(t, states, 1)
+	assert.Equal(t, apiv1.HealthStateTypeDegraded, states[0].Health)
+	assert.Contains(t, states[0].DeprecatedExtraInfo["data"], `"vbios_version":"96.00.74.00.0E"`)
+}
+
+func TestCheck_HeterogeneousVBIOS(t *testing.T) {
+	vbiosVersions := map[string]string{
+		"GPU-0": "96.00.89.00.01",
+		"GPU-1": "96.00.74.00.0E",
+	}
+
+	// flagged without any policy
+	c := MockVersionComplianceComponent(context.Background(), vbiosVersions, Policy{})
+	defer c.Close()
+
+	d := c.Check().(*Data)
+	assert.Equal(t, apiv1.HealthStateTypeDegraded, d.health)
+	require.Len(t, d.Mismatches, 1)
+	assert.Contains(t, d.Mismatches[0], "heterogeneous vbios versions")
+	assert.Nil(t, d.InfinibandCards, "ibstat should not run without the infiniband firmware policy")
+
+	c.getPolicyFunc = func() Policy { return Policy{AllowHeterogeneousVBIOS: true} }
+	d = c.Check().(*Data)
+	assert.Equal(t, apiv1.HealthStateTypeHealthy, d.health)
+
+	// the GPUs of the different products may run the different versions
+	c.getPolicyFunc = GetDefaultPolicy
+	c.getProductNameFunc = func(dev device.Device) (string, error) {
+		ver, _ := c.getVBIOSVersionFunc(dev)
+		if ver == "96.00.74.00.0E" {
+			return "NVIDIA A100-SXM4-80GB", nil
+		}
+		return "NVIDIA H100 80GB HBM3", nil
+	}
+	d = c.Check().(*Data)
+	assert.Equal(t, apiv1.HealthStateTypeHealthy, d.health)
+}
+
+func TestCheck_Ibstat(t *testing.T) {
+	c := MockVersionComplianceComponent(context.Background(), map[string]string{"GPU-0": "96.00.89.00.01"}, Policy{
+		InfinibandFirmware: VersionRange{Min: "28.39"},
+	})
+	defer c.Close()
+
+	c.getIbstatOutputFunc = func(ctx context.Context, ibstatCommands []string) (*infiniband.IbstatOutput, error) {
+		return nil, infiniband.ErrNoIbstatCommand
+	}
+	d := c.Check().(*Data)
+	assert.Equal(t, apiv1.HealthStateTypeHealthy, d.health)
+	assert.Empty(t, d.InfinibandCards)
+
+	c.getIbstatOutputFunc = func(ctx context.Context, ibstatCommands []string) (*infiniband.IbstatOutput, error) {
+		return nil, errors.New("ibstat error")
+	}
+	d = c.Check().(*Data)
+	assert.Equal(t, apiv1.HealthStateTypeUnhealthy, d.health)
+	assert.Equal(t, "ibstat command failed: ibstat error", d.reason)
+}
+
+func TestCheck_Error(t *testing.T) {
+	c := MockVersionComplianceComponent(context.Background(), map[string]string{"GPU-0": "96.00.89.00.01"}, Policy{})
+	defer c.Close()
+
+	c.getVBIOSVersionFunc = func(dev device.Device) (string, error) {
+		return "", errors.New("vbios error")
+	}
+	d := c.Check().(*Data)
+	assert.Equal(t, apiv1.HealthStateTypeUnhealthy, d.health)
+	assert.Equal(t, "error getting vbios version for device GPU-0", d.reason)
+	assert.Equal(t, "vbios error", d.getError())
+
+	c.getDriverVersionFunc = func() (string, error) {
+		return "", errors.New("driver error")
+	}
+	d = c.Check().(*Data)
+	assert.Equal(t, apiv1.HealthStateTypeUnhealthy, d.health)
+	assert.Equal(t, "error getting driver version", d.reason)
+	assert.Equal(t, "no data", d.String())
+}
+
+func TestCheck_NilNVML(t *testing.T) {
+	c := MockVersionComplianceComponent(context.Background(), nil, Policy{})
+	defer c.Close()
+	c.nvmlInstance = nil
+
+	d := c.Check().(*Data)
+	assert.Equal(t, apiv1.HealthStateTypeHealthy, d.health)
+	assert.Equal(t, "NVIDIA NVML instance is nil", d.reason)
+}
+
+func TestLastHealthStates_NoData(t *testing.T) {
+	c := MockVersionComplianceComponent(context.Background(), nil, Policy{})
+	defer c.Close()
+
+	states := c.LastHealthStates()
+	require.Len(t, states, 1)
+	assert.Equal(t, apiv1.HealthStateTypeHealthy, states[0].Health)
+	assert.Equal(t, "no data yet", states[0].Reason)
+}
+
+func TestDefaultPolicy(t *testing.T) {
+	orig := GetDefaultPolicy()
+	defer SetDefaultPolicy(orig)
+
+	assert.True(t, orig.Driver.IsZero())
+	assert.Nil(t, orig.GSPFirmwareEnabled)
+
+	SetDefaultPolicy(Policy{Driver: VersionRange{Min: "550"}})
+	assert.Equal(t, "550", GetDefaultPolicy().Driver.Min)
+}
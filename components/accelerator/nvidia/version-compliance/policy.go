@@ -0,0 +1,106 @@
+package versioncompliance
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/leptonai/gpud/pkg/log"
+)
+
+// Policy defines the allowed versions of the drivers, the firmwares and the kernel.
+// The zero value of each field skips the check.
+type Policy struct {
+	// Driver is the allowed NVIDIA driver versions (e.g., {"min": "535.161.08"}).
+	Driver VersionRange `json:"driver"`
+	// CUDA is the allowed CUDA driver versions (e.g., {"min": "12.2"}).
+	CUDA VersionRange `json:"cuda"`
+	// VBIOS is the allowed VBIOS versions of every GPU (e.g., {"min": "96.00.89"}).
+	VBIOS VersionRange `json:"vbios"`
+	// GSPFirmwareEnabled is the expected GSP firmware mode of every GPU.
+	// Nil to skip the GSP firmware mode check.
+	GSPFirmwareEnabled *bool `json:"gsp_firmware_enabled,omitempty"`
+	// InfinibandFirmware is the allowed firmware versions of every InfiniBand card
+	// reported by "ibstat" (e.g., {"min": "28.39"}).
+	InfinibandFirmware VersionRange `json:"infiniband_firmware"`
+	// Kernel is the allowed kernel versions (e.g., {"min": "5.15", "max": "6.8"}).
+	Kernel VersionRange `json:"kernel"`
+
+	// AllowHeterogeneousVBIOS is true to allow the GPUs of the same product
+	// to run the different VBIOS versions.
+	AllowHeterogeneousVBIOS bool `json:"allow_heterogeneous_vbios,omitempty"`
+}
+
+// VersionRange is the inclusive range of the allowed versions.
+// The bounds are compared up to their precision,
+// so that the max "550" allows "550.54.15" while rejecting "555.42.02".
+type VersionRange struct {
+	// Min is the minimum allowed version, empty for no lower bound.
+	Min string `json:"min,omitempty"`
+	// Max is the maximum allowed version, empty for no upper bound.
+	Max string `json:"max,omitempty"`
+}
+
+// IsZero returns true if the range does not bound the versions.
+func (r VersionRange) IsZero() bool {
+	return r.Min == "" && r.Max == ""
+}
+
+// Allows returns true if the version is within the range.
+func (r VersionRange) Allows(ver string) bool {
+	if r.Min != "" && compareVersions(ver, r.Min) < 0 {
+		return false
+	}
+	if r.Max != "" && compareVersions(ver, r.Max) > 0 {
+		return false
+	}
+	return true
+}
+
+func (r VersionRange) String() string {
+	var bounds []string
+	if r.Min != "" {
+		bounds = append(bounds, ">= "+r.Min)
+	}
+	if r.Max != "" {
+		bounds = append(bounds, "<= "+r.Max)
+	}
+	if len(bounds) == 0 {
+		return "any"
+	}
+	return strings.Join(bounds, ", ")
+}
+
+// check returns the description of the version outside the range,
+// or an empty string if allowed.
+func (r VersionRange) check(name string, ver string) string {
+	if r.IsZero() {
+		return ""
+	}
+	if ver == "" {
+		return fmt.Sprintf("%s version is unknown (allowed %s)", name, r)
+	}
+	if !r.Allows(ver) {
+		return fmt.Sprintf("%s version %s is not allowed (allowed %s)", name, ver, r)
+	}
+	return ""
+}
+
+var (
+	defaultPolicyMu sync.RWMutex
+	defaultPolicy   = Policy{}
+)
+
+func GetDefaultPolicy() Policy {
+	defaultPolicyMu.RLock()
+	defer defaultPolicyMu.RUnlock()
+	return defaultPolicy
+}
+
+func SetDefaultPolicy(policy Policy) {
+	log.Logger.Infow("setting default version compliance policy", "policy", policy)
+
+	defaultPolicyMu.Lock()
+	defer defaultPolicyMu.Unlock()
+	defaultPolicy = policy
+}
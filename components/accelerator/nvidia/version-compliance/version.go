@@ -0,0 +1,46 @@
+package versioncompliance
+
+import (
+	"strconv"
+	"strings"
+)
+
+// compareVersions compares the version to the bound, up to the precision of the bound,
+// and returns -1 if the version is lower, 1 if higher, and 0 if the same.
+// The versions are split by ".", "-", "_" and "+" (e.g., "5.15.0-1045-aws"),
+// where the numeric parts are compared as numbers and the others as strings.
+func compareVersions(ver string, bound string) int {
+	verParts, boundParts := splitVersion(ver), splitVersion(bound)
+	for i, b := range boundParts {
+		if i >= len(verParts) {
+			// "550" is lower than "550.54"
+			return -1
+		}
+		if c := compareVersionParts(verParts[i], b); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+func splitVersion(ver string) []string {
+	return strings.FieldsFunc(strings.TrimSpace(ver), func(r rune) bool {
+		return r == '.' || r == '-' || r == '_' || r == '+'
+	})
+}
+
+func compareVersionParts(a string, b string) int {
+	an, aErr := strconv.ParseUint(a, 10, 64)
+	bn, bErr := strconv.ParseUint(b, 10, 64)
+	if aErr == nil && bErr == nil {
+		switch {
+		case an < bn:
+			return -1
+		case an > bn:
+			return 1
+		default:
+			return 0
+		}
+	}
+	return strings.Compare(strings.ToLower(a), strings.ToLower(b))
+}
@@ -0,0 +1,61 @@
+package versioncompliance
+
+import (
+	"testing"
+)
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		ver      string
+		bound    string
+		expected int
+	}{
+		{ver: "535.161.08", bound: "535.161.08", expected: 0},
+		{ver: "535.161.08", bound: "535.104.05", expected: 1},
+		{ver: "535.161.08", bound: "550", expected: -1},
+		{ver: "550.54.15", bound: "550", expected: 0},
+		{ver: "550", bound: "550.54", expected: -1},
+		{ver: "12.2", bound: "12.10", expected: -1},
+		{ver: "96.00.89.00.01", bound: "96.00.74", expected: 1},
+		{ver: "92.00.45.00.0A", bound: "92.00.45.00.0B", expected: -1},
+		{ver: "5.15.0-1045-aws", bound: "5.15.0-1044", expected: 1},
+		{ver: "6.8.0-45-generic", bound: "6.8", expected: 0},
+		{ver: "28.39.1002", bound: "28.40", expected: -1},
+	}
+	for _, tt := range tests {
+		if got := compareVersions(tt.ver, tt.bound); got != tt.expected {
+			t.Errorf("compareVersions(%q, %q) = %d, want %d", tt.ver, tt.bound, got, tt.expected)
+		}
+	}
+}
+
+func TestVersionRange(t *testing.T) {
+	r := VersionRange{Min: "535.161", Max: "550"}
+	for ver, allowed := range map[string]bool{
+		"535.104.05": false,
+		"535.161.08": true,
+		"550.54.15":  true,
+		"555.42.02":  false,
+	} {
+		if got := r.Allows(ver); got != allowed {
+			t.Errorf("%s.Allows(%q) = %v, want %v", r, ver, got, allowed)
+		}
+	}
+
+	if s := r.String(); s != ">= 535.161, <= 550" {
+		t.Errorf("unexpected range string %q", s)
+	}
+	if s := (VersionRange{}).String(); s != "any" {
+		t.Errorf("unexpected range string %q", s)
+	}
+
+	if desc := r.check("driver", "555.42.02"); desc != "driver version 555.42.02 is not allowed (allowed >= 535.161, <= 550)" {
+		t.Errorf("unexpected check result %q", desc)
+	}
+	if desc := r.check("driver", ""); desc != "driver version is unknown (allowed >= 535.161, <= 550)" {
+		t.Errorf("unexpected check result %q", desc)
+	}
+	if desc := (VersionRange{}).check("driver", ""); desc != "" {
+		t.Errorf("expected no check for the zero range, got %q", desc)
+	}
+}
@@ -15,12 +15,15 @@ import (
 	"sync"
 	"time"
 
+	"github.com/NVIDIA/go-nvlib/pkg/nvlib/device"
 	"github.com/google/uuid"
 	"github.com/olekukonko/tablewriter"
+	"go.uber.org/zap"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	apiv1 "github.com/leptonai/gpud/api/v1"
 	"github.com/leptonai/gpud/components"
+	"github.com/leptonai/gpud/pkg/container"
 	"github.com/leptonai/gpud/pkg/eventstore"
 	pkghost "github.com/leptonai/gpud/pkg/host"
 	"github.com/leptonai/gpud/pkg/kmsg"
@@ -49,10 +52,24 @@ type component struct {
 	cancel context.CancelFunc
 
 	nvmlInstance nvidianvml.InstanceV2
+	getMIGFunc   func(uuid string, dev device.Device) (nvidianvml.MIG, error)
+
+	// resolves the container of the XID process (nil to skip)
+	resolveContainerFunc func(ctx context.Context, pid uint32) *container.Identity
+	// lists the processes on the GPU, for the XID without the process ID
+	getProcessesFunc func(uuid string, dev device.Device) (nvidianvml.Processes, error)
 
 	rebootEventStore pkghost.RebootEventStore
 	eventBucket      eventstore.Bucket
 	kmsgWatcher      kmsg.Watcher
+	// source of the kernel messages (e.g., "kmsg", "journal")
+	// empty if no source is available (e.g., non-root without journal)
+	kmsgSource string
+
+	// watches the XID errors from NVML, alongside the kernel messages
+	// nil if NVML does not support the events
+	nvmlEventWatcher *nvmlEventWatcher
+	nvmlEventCh      chan apiv1.Event
 
 	readAllKmsg  func(context.Context) ([]kmsg.Message, error)
 	extraEventCh chan *apiv1.Event
@@ -60,8 +77,9 @@ type component struct {
 	lastMu   sync.RWMutex
 	lastData *Data
 
-	mu        sync.RWMutex
-	currState apiv1.HealthState
+	mu sync.RWMutex
+	// one state per device
+	currStates apiv1.HealthStates
 }
 
 func New(gpudInstance *components.GPUdInstance) (components.Component, error) {
@@ -70,8 +88,11 @@ func New(gpudInstance *components.GPUdInstance) (components.Component, error) {
 		ctx:              cctx,
 		cancel:           ccancel,
 		nvmlInstance:     gpudInstance.NVMLInstance,
+		getMIGFunc:       nvidianvml.GetMIG,
+		getProcessesFunc: nvidianvml.GetProcesses,
 		rebootEventStore: gpudInstance.RebootEventStore,
 
+		nvmlEventCh:  make(chan apiv1.Event, 256),
 		extraEventCh: make(chan *apiv1.Event, 256),
 	}
 
@@ -83,18 +104,22 @@ func New(gpudInstance *components.GPUdInstance) (components.Component, error) {
 			return nil, err
 		}
 
-		if os.Geteuid() == 0 {
-			c.kmsgWatcher, err = kmsg.NewWatcher()
+		if gpudInstance.KmsgHub != nil {
+			c.kmsgWatcher, err = gpudInstance.KmsgHub.NewWatcher(Name)
 			if err != nil {
 				ccancel()
 				return nil, err
 			}
+			c.kmsgSource = gpudInstance.KmsgHub.Source()
 		}
 	}
 
 	if runtime.GOOS == "linux" && os.Geteuid() == 0 {
 		c.readAllKmsg = kmsg.ReadAll
 	}
+	if runtime.GOOS == "linux" {
+		c.resolveContainerFunc = container.NewResolver(container.DefaultProcRoot).Resolve
+	}
 
 	return c, nil
 }
@@ -121,11 +146,27 @@ func (c *component) Start() error {
 		}
 	}
 
+	if c.eventBucket != nil && c.nvmlInstance != nil && c.nvmlInstance.NVMLExists() {
+		w, err := newNVMLEventWatcher(c.nvmlInstance.Library().NVML(), c.nvmlInstance.Devices())
+		if err != nil {
+			log.Logger.Warnw("failed to watch nvml events, only using kernel messages", "error", err)
+		} else if w != nil {
+			c.mu.Lock()
+			c.nvmlEventWatcher = w
+			c.mu.Unlock()
+			go w.watch(c.ctx, c.nvmlEventCh)
+		}
+	}
+
+	var kmsgCh <-chan kmsg.Message
 	if c.kmsgWatcher != nil {
-		kmsgCh, err := c.kmsgWatcher.Watch()
+		var err error
+		kmsgCh, err = c.kmsgWatcher.Watch()
 		if err != nil {
 			return err
 		}
+	}
+	if kmsgCh != nil || c.nvmlEventWatcher != nil {
 		go c.start(kmsgCh, DefaultStateUpdatePeriod)
 	}
 
@@ -135,7 +176,31 @@ func (c *component) Start() error {
 func (c *component) LastHealthStates() apiv1.HealthStates {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
-	return apiv1.HealthStates{c.currState}
+
+	states := make(apiv1.HealthStates, 0, len(c.currStates))
+	for _, state := range c.currStates {
+		if state.Reason != "" {
+			state.Reason = c.withSourceReason(state.Reason)
+		}
+		states = append(states, state)
+	}
+	if len(states) == 0 {
+		states = append(states, apiv1.HealthState{Name: StateNameErrorXid})
+	}
+	return states
+}
+
+// withSourceReason appends the kernel log source to the reason,
+// so that it is visible when the errors cannot be detected.
+func (c *component) withSourceReason(reason string) string {
+	src := "no kernel log source"
+	if c.kmsgSource != "" {
+		src = "kernel log source: " + c.kmsgSource
+	}
+	if c.nvmlEventWatcher != nil {
+		src += ", nvml events"
+	}
+	return fmt.Sprintf("%s (%s)", reason, src)
 }
 
 func (c *component) Events(ctx context.Context, since time.Time) (apiv1.Events, error) {
@@ -219,6 +284,9 @@ func (c *component) Check() components.CheckResult {
 		if xidErr == nil {
 			continue
 		}
+		if xidErr.GPUInstanceID >= 0 {
+			xidErr.MIGUUID = c.resolveMIGUUID(xidErr.DeviceUUID, xidErr.GPUInstanceID, -1)
+		}
 		d.FoundErrors = append(d.FoundErrors, FoundError{
 			Kmsg:     kmsg,
 			XidError: *xidErr,
@@ -263,7 +331,7 @@ func (d *Data) String() string {
 	}
 
 	now := time.Now().UTC()
-	header := []string{"Time", "XID", "DeviceUUID", "Name", "Critical", "Action(s)"}
+	header := []string{"Time", "XID", "DeviceUUID", "MIG", "Name", "Critical", "Action(s)"}
 	outputs := make([]string, 0, len(d.FoundErrors))
 	for _, foundErr := range d.FoundErrors {
 		action := "unknown"
@@ -288,6 +356,7 @@ func (d *Data) String() string {
 			foundErr.Kmsg.DescribeTimestamp(now),
 			fmt.Sprintf("%d", foundErr.Xid),
 			foundErr.DeviceUUID,
+			foundErr.MIGUUID,
 			foundErr.Detail.Name,
 			strconv.FormatBool(critical),
 			action,
@@ -340,49 +409,112 @@ func (c *component) start(kmsgCh <-chan kmsg.Message, updatePeriod time.Duration
 				continue
 			}
 
-		case message := <-kmsgCh:
-			xidErr := Match(message.Message)
-			if xidErr == nil {
-				log.Logger.Debugw("not xid event, skip", "kmsg", message)
-				continue
-			}
+		case event := <-c.nvmlEventCh:
+			logger := log.Logger.With("id", uuid.New(), "xid", event.DeprecatedExtraInfo[EventKeyErrorXidData], "deviceUUID", event.DeprecatedExtraInfo[EventKeyDeviceUUID])
+			logger.Infow("got event from nvml", "eventName", event.Name)
+			c.attributeMIG(&event)
+			c.insertEvent(logger, event)
 
-			id := uuid.New()
-			var xidName string
-			if xidErr.Detail != nil {
-				xidName = xidErr.Detail.Name
-			}
-			logger := log.Logger.With("id", id, "xid", xidErr.Xid, "xidName", xidName, "deviceUUID", xidErr.DeviceUUID)
-			logger.Infow("got xid event", "kmsg", message, "kmsgTimestamp", message.Timestamp.Unix())
-
-			event := apiv1.Event{
-				Time: message.Timestamp,
-				Name: EventNameErrorXid,
-				DeprecatedExtraInfo: map[string]string{
-					EventKeyErrorXidData: strconv.FormatInt(int64(xidErr.Xid), 10),
-					EventKeyDeviceUUID:   xidErr.DeviceUUID,
-				},
-			}
-			sameEvent, err := c.eventBucket.Find(c.ctx, event)
-			if err != nil {
-				logger.Errorw("failed to check event existence", "error", err)
-				continue
-			}
-			if sameEvent != nil {
-				logger.Infow("find the same event, skip inserting it")
-				continue
-			}
-			if err = c.eventBucket.Insert(c.ctx, event); err != nil {
-				logger.Errorw("failed to create event", "error", err)
-				continue
-			}
-			logger.Infow("inserted the event successfully")
-			if err = c.updateCurrentState(); err != nil {
-				logger.Errorw("failed to update current state", "error", err)
+		case message, ok := <-kmsgCh:
+			if !ok {
+				// the kmsg source is closed (e.g., hub closed)
+				kmsgCh = nil
 				continue
 			}
+			c.processKmsg(message)
+			kmsg.Ack(c.kmsgWatcher, message)
+		}
+	}
+}
+
+// processKmsg inserts the XID event of the kernel message, if matched.
+func (c *component) processKmsg(message kmsg.Message) {
+	xidErr := Match(message.Message)
+	if xidErr == nil {
+		log.Logger.Debugw("not xid event, skip", "kmsg", message)
+		return
+	}
+
+	id := uuid.New()
+	var xidName string
+	if xidErr.Detail != nil {
+		xidName = xidErr.Detail.Name
+	}
+	logger := log.Logger.With("id", id, "xid", xidErr.Xid, "xidName", xidName, "deviceUUID", xidErr.DeviceUUID)
+	logger.Infow("got xid event", "kmsg", message, "kmsgTimestamp", message.Timestamp.Unix())
+
+	event := apiv1.Event{
+		Time: message.Timestamp,
+		Name: EventNameErrorXid,
+		DeprecatedExtraInfo: map[string]string{
+			EventKeyErrorXidData: strconv.FormatInt(int64(xidErr.Xid), 10),
+			EventKeyDeviceUUID:   xidErr.DeviceUUID,
+		},
+	}
+	if xidErr.GPUInstanceID >= 0 {
+		event.DeprecatedExtraInfo[EventKeyGPUInstanceID] = strconv.Itoa(xidErr.GPUInstanceID)
+		c.attributeMIG(&event)
+	}
+	if xidErr.PID > 0 {
+		event.DeprecatedExtraInfo[EventKeyPID] = strconv.Itoa(xidErr.PID)
+	}
+	c.insertEvent(logger, event)
+}
+
+// insertEvent inserts the XID event from either the kernel messages or NVML,
+// unless the same event is already recorded (e.g., kmsg replayed after restart)
+// or the same XID on the same device is already recorded from the other source.
+func (c *component) insertEvent(logger *zap.SugaredLogger, event apiv1.Event) {
+	// record with the GPU UUID rather than the PCI device ID in the kernel messages,
+	// so that the events of the same GPU are tracked (and set healthy) by the GPU UUID
+	if id := event.DeprecatedExtraInfo[EventKeyDeviceUUID]; id != "" {
+		event.DeprecatedExtraInfo[EventKeyDeviceUUID] = c.deviceUUID(id)
+	}
+
+	sameEvent, err := c.eventBucket.Find(c.ctx, event)
+	if err != nil {
+		logger.Errorw("failed to check event existence", "error", err)
+		return
+	}
+	if sameEvent != nil {
+		logger.Infow("find the same event, skip inserting it")
+		return
+	}
+
+	recent, err := c.eventBucket.Get(c.ctx, event.Time.Add(-crossSourceWindow))
+	if err != nil {
+		logger.Errorw("failed to get recent events", "error", err)
+		return
+	}
+	for _, ev := range recent {
+		if isSameEventWithoutContainer(event, ev) {
+			logger.Infow("find the same event with the container attribution, skip inserting it")
+			return
+		}
+		if isSameXidEvent(event, ev, crossSourceWindow) {
+			logger.Infow("same xid already recorded from the other source, skip inserting it", "source", getEventSource(ev))
+			return
 		}
 	}
+
+	// attributed after the dedup, since the process may have exited
+	// (e.g., kmsg replayed after restart)
+	c.attributeContainer(&event)
+
+	if err = c.eventBucket.Insert(c.ctx, event); err != nil {
+		logger.Errorw("failed to create event", "error", err)
+		return
+	}
+	logger.Infow("inserted the event successfully")
+
+	// only the Xid events affect the health state
+	// (e.g., not the single-bit ECC warnings)
+	if event.Name != EventNameErrorXid {
+		return
+	}
+	if err = c.updateCurrentState(); err != nil {
+		logger.Errorw("failed to update current state", "error", err)
+	}
 }
 
 var _ components.HealthSettable = &component{}
@@ -398,6 +530,27 @@ func (c *component) SetHealthy() error {
 	return nil
 }
 
+var _ components.DeviceHealthSettable = &component{}
+
+// SetHealthyDevice sets the state of the device to healthy,
+// without affecting the states of the other devices.
+// The device is either the GPU UUID or the PCI device ID (e.g., "PCI:0000:9b:00").
+func (c *component) SetHealthyDevice(deviceUUID string) error {
+	deviceUUID = c.deviceUUID(deviceUUID)
+	log.Logger.Debugw("set healthy event received", "deviceUUID", deviceUUID)
+	newEvent := &apiv1.Event{
+		Time:                metav1.Time{Time: time.Now().UTC()},
+		Name:                "SetHealthy",
+		DeprecatedExtraInfo: map[string]string{EventKeyDeviceUUID: deviceUUID},
+	}
+	select {
+	case c.extraEventCh <- newEvent:
+	default:
+		log.Logger.Debugw("channel full, set healthy event skipped")
+	}
+	return nil
+}
+
 func (c *component) updateCurrentState() error {
 	if c.rebootEventStore == nil || c.eventBucket == nil {
 		return nil
@@ -418,9 +571,11 @@ func (c *component) updateCurrentState() error {
 	events := mergeEvents(rebootEvents, localEvents)
 
 	c.mu.Lock()
-	c.currState = evolveHealthyState(events)
+	c.currStates = c.evolveHealthyStates(events)
 	if rebootErr != "" {
-		c.currState.Error = fmt.Sprintf("%s\n%s", rebootErr, c.currState.Error)
+		for i := range c.currStates {
+			c.currStates[i].Error = fmt.Sprintf("%s\n%s", rebootErr, c.currStates[i].Error)
+		}
 	}
 	c.mu.Unlock()
 
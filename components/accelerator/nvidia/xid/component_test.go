@@ -284,10 +284,17 @@ func TestXIDComponent_States(t *testing.T) {
 		Health: apiv1.HealthStateTypeHealthy,
 		Reason: "XIDComponent is healthy",
 	}
-	c.currState = s
+	c.currStates = apiv1.HealthStates{s}
 	states := comp.LastHealthStates()
 	assert.Len(t, states, 1)
-	assert.Equal(t, s, states[0])
+	assert.Equal(t, "XIDComponent is healthy (no kernel log source)", states[0].Reason)
+
+	c.kmsgSource = kmsg.SourceJournal
+	states = comp.LastHealthStates()
+	assert.Len(t, states, 1)
+	want := s
+	want.Reason = "XIDComponent is healthy (kernel log source: journal)"
+	assert.Equal(t, want, states[0])
 
 	startTime := time.Now().Add(-1 * time.Hour)
 
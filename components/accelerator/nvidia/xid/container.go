@@ -0,0 +1,179 @@
+package xid
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	apiv1 "github.com/leptonai/gpud/api/v1"
+	"github.com/leptonai/gpud/pkg/container"
+	"github.com/leptonai/gpud/pkg/log"
+	nvidianvml "github.com/leptonai/gpud/pkg/nvidia-query/nvml"
+)
+
+const (
+	// EventKeyPID is the extra info key for the process ID of the XID event.
+	EventKeyPID = "pid"
+	// EventKeyContainerID is the extra info key for the container ID of the XID event process.
+	EventKeyContainerID = "container_id"
+	// EventKeyContainerName is the extra info key for the container name of the XID event process.
+	EventKeyContainerName = "container_name"
+	// EventKeyPodName is the extra info key for the Kubernetes pod name of the XID event process.
+	EventKeyPodName = "pod_name"
+	// EventKeyPodNamespace is the extra info key for the Kubernetes pod namespace of the XID event process.
+	EventKeyPodNamespace = "pod_namespace"
+
+	// EventKeyGPUPIDs is the extra info key for the comma-separated process IDs
+	// running on the GPU (or the MIG device) of the XID event, when the XID has no process ID.
+	EventKeyGPUPIDs = "gpu_pids"
+	// EventKeyContainers is the extra info key for the JSON-encoded containers
+	// of the processes in EventKeyGPUPIDs.
+	EventKeyContainers = "containers"
+)
+
+// containerEventKeys are the extra info keys set by the container attribution,
+// which depend on the processes at the time of the insertion.
+var containerEventKeys = map[string]struct{}{
+	EventKeyContainerID:   {},
+	EventKeyContainerName: {},
+	EventKeyPodName:       {},
+	EventKeyPodNamespace:  {},
+	EventKeyGPUPIDs:       {},
+	EventKeyContainers:    {},
+}
+
+// gpuProcessesWindow is the maximum age of the XID event to attribute
+// to the processes currently running on the GPU, so that the old kernel messages
+// (e.g., replayed after restart) are not attributed to the new processes.
+const gpuProcessesWindow = time.Minute
+
+// attributeContainer sets the container and the pod of the XID event
+// based on its process ID.
+// If the XID has no process ID (e.g., hardware errors such as Xid 79),
+// the event is attributed to all the processes running on its GPU
+// (or its MIG device, if known).
+// No-op if the process does not run in a container (or has already exited).
+func (c *component) attributeContainer(event *apiv1.Event) {
+	if c.resolveContainerFunc == nil || event.DeprecatedExtraInfo == nil {
+		return
+	}
+
+	if _, ok := event.DeprecatedExtraInfo[EventKeyPID]; !ok {
+		c.attributeGPUProcesses(event)
+		return
+	}
+	pid, err := strconv.ParseUint(event.DeprecatedExtraInfo[EventKeyPID], 10, 32)
+	if err != nil || pid == 0 {
+		return
+	}
+
+	id := c.resolveContainerFunc(c.ctx, uint32(pid))
+	if id == nil {
+		return
+	}
+	event.DeprecatedExtraInfo[EventKeyContainerID] = id.ContainerID
+	if id.ContainerName != "" {
+		event.DeprecatedExtraInfo[EventKeyContainerName] = id.ContainerName
+	}
+	if id.PodName != "" {
+		event.DeprecatedExtraInfo[EventKeyPodName] = id.PodName
+		event.DeprecatedExtraInfo[EventKeyPodNamespace] = id.PodNamespace
+	}
+}
+
+// attributeGPUProcesses sets the processes running on the GPU (or the MIG device) of the XID event,
+// and their containers.
+func (c *component) attributeGPUProcesses(event *apiv1.Event) {
+	if c.getProcessesFunc == nil || time.Since(event.Time.Time) > gpuProcessesWindow {
+		return
+	}
+	uuid, dev, ok := c.findDevice(event.DeprecatedExtraInfo[EventKeyDeviceUUID])
+	if !ok {
+		return
+	}
+	procs, err := c.getProcessesFunc(uuid, dev)
+	if err != nil {
+		log.Logger.Warnw("failed to get processes", "uuid", uuid, "error", err)
+		return
+	}
+
+	gi, ci := -1, -1
+	if v, err := strconv.Atoi(event.DeprecatedExtraInfo[EventKeyGPUInstanceID]); err == nil {
+		gi = v
+	}
+	if v, err := strconv.Atoi(event.DeprecatedExtraInfo[EventKeyComputeInstanceID]); err == nil {
+		ci = v
+	}
+
+	pids := make([]string, 0, len(procs.RunningProcesses))
+	ids := make(map[string]container.Identity)
+	for _, proc := range procs.RunningProcesses {
+		if !matchProcessMIG(proc, gi, ci) {
+			continue
+		}
+		pids = append(pids, strconv.FormatUint(uint64(proc.PID), 10))
+
+		if id := c.resolveContainerFunc(c.ctx, proc.PID); id != nil {
+			ids[id.ContainerID] = *id
+		}
+	}
+	if len(pids) == 0 {
+		return
+	}
+	event.DeprecatedExtraInfo[EventKeyGPUPIDs] = strings.Join(pids, ",")
+
+	if len(ids) == 0 {
+		return
+	}
+	containers := make([]container.Identity, 0, len(ids))
+	for _, id := range ids {
+		containers = append(containers, id)
+	}
+	sort.Slice(containers, func(i, j int) bool {
+		return containers[i].Name() < containers[j].Name()
+	})
+	b, err := json.Marshal(containers)
+	if err != nil {
+		log.Logger.Warnw("failed to marshal containers", "error", err)
+		return
+	}
+	event.DeprecatedExtraInfo[EventKeyContainers] = string(b)
+}
+
+// matchProcessMIG returns true if the process runs on the GPU instance
+// (and the compute instance, if non-negative) of the XID event.
+// Always true if the GPU instance of the event is unknown (e.g., MIG mode disabled).
+func matchProcessMIG(proc nvidianvml.Process, gpuInstanceID int, computeInstanceID int) bool {
+	if gpuInstanceID < 0 {
+		return true
+	}
+	if proc.MIG == nil || proc.MIG.GPUInstanceID != gpuInstanceID {
+		return false
+	}
+	return computeInstanceID < 0 || proc.MIG.ComputeInstanceID == computeInstanceID
+}
+
+// isSameEventWithoutContainer returns true if the two events are the same,
+// ignoring the container attribution (e.g., kmsg replayed after restart,
+// where the process has exited and the container is no longer resolved).
+func isSameEventWithoutContainer(a, b apiv1.Event) bool {
+	if a.Name != b.Name || a.Time.Unix() != b.Time.Unix() {
+		return false
+	}
+	for _, pair := range [][2]map[string]string{
+		{a.DeprecatedExtraInfo, b.DeprecatedExtraInfo},
+		{b.DeprecatedExtraInfo, a.DeprecatedExtraInfo},
+	} {
+		for k, v := range pair[0] {
+			if _, ok := containerEventKeys[k]; ok {
+				continue
+			}
+			if pair[1][k] != v {
+				return false
+			}
+		}
+	}
+	return true
+}
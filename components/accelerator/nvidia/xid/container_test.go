@@ -0,0 +1,239 @@
+package xid
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/NVIDIA/go-nvlib/pkg/nvlib/device"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	apiv1 "github.com/leptonai/gpud/api/v1"
+	"github.com/leptonai/gpud/components"
+	"github.com/leptonai/gpud/pkg/container"
+	"github.com/leptonai/gpud/pkg/eventstore"
+	"github.com/leptonai/gpud/pkg/log"
+	nvidianvml "github.com/leptonai/gpud/pkg/nvidia-query/nvml"
+	"github.com/leptonai/gpud/pkg/sqlite"
+)
+
+func TestInsertEventWithContainer(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	dbRW, dbRO, cleanup := sqlite.OpenTestDB(t)
+	defer cleanup()
+
+	store, err := eventstore.New(dbRW, dbRO, DefaultRetentionPeriod)
+	require.NoError(t, err)
+
+	comp, err := New(&components.GPUdInstance{
+		RootCtx:    ctx,
+		EventStore: store,
+	})
+	require.NoError(t, err)
+	defer comp.Close()
+
+	c := comp.(*component)
+	if c.eventBucket == nil {
+		c.eventBucket, err = store.Bucket(Name)
+		require.NoError(t, err)
+	}
+
+	running := true
+	c.resolveContainerFunc = func(ctx context.Context, pid uint32) *container.Identity {
+		if pid != 1234 || !running {
+			return nil
+		}
+		return &container.Identity{
+			ContainerID:   "5f0c3e2a9b8d7c6e5f4a3b2c1d0e9f8a7b6c5d4e3f2a1b0c9d8e7f6a5b4c3d2e",
+			ContainerName: "trainer",
+			PodName:       "llama-train-0",
+			PodNamespace:  "ml",
+		}
+	}
+
+	now := time.Now().UTC()
+	xidErr := Match("NVRM: Xid (PCI:0000:9b:00): 31, pid=1234, name=python, Ch 00000008")
+	require.NotNil(t, xidErr)
+	require.Equal(t, 1234, xidErr.PID)
+
+	kmsgEvent := apiv1.Event{
+		Time: metav1.Time{Time: now},
+		Name: EventNameErrorXid,
+		DeprecatedExtraInfo: map[string]string{
+			EventKeyErrorXidData: "31",
+			EventKeyDeviceUUID:   xidErr.DeviceUUID,
+			EventKeyPID:          "1234",
+		},
+	}
+	c.insertEvent(log.Logger.SugaredLogger, kmsgEvent)
+
+	events, err := comp.Events(ctx, now.Add(-time.Minute))
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "1234", events[0].DeprecatedExtraInfo[EventKeyPID])
+	assert.Equal(t, "5f0c3e2a9b8d7c6e5f4a3b2c1d0e9f8a7b6c5d4e3f2a1b0c9d8e7f6a5b4c3d2e", events[0].DeprecatedExtraInfo[EventKeyContainerID])
+	assert.Equal(t, "trainer", events[0].DeprecatedExtraInfo[EventKeyContainerName])
+	assert.Equal(t, "llama-train-0", events[0].DeprecatedExtraInfo[EventKeyPodName])
+	assert.Equal(t, "ml", events[0].DeprecatedExtraInfo[EventKeyPodNamespace])
+
+	// kmsg replayed after the process has exited
+	running = false
+	c.insertEvent(log.Logger.SugaredLogger, apiv1.Event{
+		Time:                kmsgEvent.Time,
+		Name:                kmsgEvent.Name,
+		DeprecatedExtraInfo: map[string]string{EventKeyErrorXidData: "31", EventKeyDeviceUUID: xidErr.DeviceUUID, EventKeyPID: "1234"},
+	})
+	events, err = comp.Events(ctx, now.Add(-time.Minute))
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+
+	// process not in a container
+	c.insertEvent(log.Logger.SugaredLogger, apiv1.Event{
+		Time: metav1.Time{Time: now.Add(time.Second)},
+		Name: EventNameErrorXid,
+		DeprecatedExtraInfo: map[string]string{
+			EventKeyErrorXidData: "43",
+			EventKeyDeviceUUID:   xidErr.DeviceUUID,
+			EventKeyPID:          "5678",
+		},
+	})
+	events, err = comp.Events(ctx, now.Add(-time.Minute))
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	for _, ev := range events {
+		if ev.DeprecatedExtraInfo[EventKeyPID] == "5678" {
+			assert.Empty(t, ev.DeprecatedExtraInfo[EventKeyContainerID])
+		}
+	}
+}
+
+func TestIsSameEventWithoutContainer(t *testing.T) {
+	now := metav1.Time{Time: time.Now().UTC()}
+	a := apiv1.Event{
+		Time: now,
+		Name: EventNameErrorXid,
+		DeprecatedExtraInfo: map[string]string{
+			EventKeyErrorXidData: "31",
+			EventKeyPID:          "1234",
+			EventKeyContainerID:  "abc",
+			EventKeyPodName:      "llama-train-0",
+		},
+	}
+	b := apiv1.Event{
+		Time: now,
+		Name: EventNameErrorXid,
+		DeprecatedExtraInfo: map[string]string{
+			EventKeyErrorXidData: "31",
+			EventKeyPID:          "1234",
+		},
+	}
+	assert.True(t, isSameEventWithoutContainer(a, b))
+	assert.True(t, isSameEventWithoutContainer(b, a))
+
+	b.DeprecatedExtraInfo[EventKeyPID] = "5678"
+	assert.False(t, isSameEventWithoutContainer(a, b))
+
+	b.DeprecatedExtraInfo[EventKeyPID] = "1234"
+	b.Time = metav1.Time{Time: now.Add(time.Second)}
+	assert.False(t, isSameEventWithoutContainer(a, b))
+}
+
+func TestAttributeGPUProcesses(t *testing.T) {
+	nvmlInstance := createMockNVMLInstance()
+	nvmlInstance.devices["GPU-1"] = newMockEventDevice("GPU-1", nvmlEventTypes, "00000000:3B:00.0")
+
+	ids := map[uint32]*container.Identity{
+		100: {ContainerID: "c1", ContainerName: "trainer", PodName: "llama-train-0", PodNamespace: "ml"},
+		101: {ContainerID: "c1", ContainerName: "trainer", PodName: "llama-train-0", PodNamespace: "ml"},
+		200: {ContainerID: "c2", ContainerName: "server", PodName: "vllm-0", PodNamespace: "serving"},
+	}
+	c := &component{
+		ctx:          context.Background(),
+		nvmlInstance: nvmlInstance,
+		resolveContainerFunc: func(ctx context.Context, pid uint32) *container.Identity {
+			return ids[pid]
+		},
+		getProcessesFunc: func(uuid string, dev device.Device) (nvidianvml.Processes, error) {
+			return nvidianvml.Processes{
+				UUID: uuid,
+				RunningProcesses: []nvidianvml.Process{
+					{PID: 100, MIG: &nvidianvml.ProcessMIG{GPUInstanceID: 1, ComputeInstanceID: 0}},
+					{PID: 101, MIG: &nvidianvml.ProcessMIG{GPUInstanceID: 1, ComputeInstanceID: 0}},
+					{PID: 200, MIG: &nvidianvml.ProcessMIG{GPUInstanceID: 2, ComputeInstanceID: 0}},
+					{PID: 300, MIG: &nvidianvml.ProcessMIG{GPUInstanceID: 2, ComputeInstanceID: 0}},
+				},
+			}, nil
+		},
+	}
+
+	// no pid (e.g., Xid 79), all processes on the GPU
+	event := apiv1.Event{
+		Time: metav1.Time{Time: time.Now()},
+		Name: EventNameErrorXid,
+		DeprecatedExtraInfo: map[string]string{
+			EventKeyErrorXidData: "79",
+			EventKeyDeviceUUID:   "PCI:0000:3b:00",
+		},
+	}
+	c.attributeContainer(&event)
+	assert.Equal(t, "100,101,200,300", event.DeprecatedExtraInfo[EventKeyGPUPIDs])
+
+	var containers []container.Identity
+	require.NoError(t, json.Unmarshal([]byte(event.DeprecatedExtraInfo[EventKeyContainers]), &containers))
+	require.Len(t, containers, 2)
+	assert.Equal(t, "ml/llama-train-0/trainer", containers[0].Name())
+	assert.Equal(t, "serving/vllm-0/server", containers[1].Name())
+	assert.NotContains(t, event.DeprecatedExtraInfo, EventKeyContainerID)
+
+	// only the processes on the MIG device
+	event = apiv1.Event{
+		Time: metav1.Time{Time: time.Now()},
+		Name: EventNameErrorXid,
+		DeprecatedExtraInfo: map[string]string{
+			EventKeyErrorXidData:  "43",
+			EventKeyDeviceUUID:    "GPU-1",
+			EventKeyGPUInstanceID: "2",
+		},
+	}
+	c.attributeContainer(&event)
+	assert.Equal(t, "200,300", event.DeprecatedExtraInfo[EventKeyGPUPIDs])
+	assert.Equal(t, `[{"container_id":"c2","container_name":"server","pod_name":"vllm-0","pod_namespace":"serving"}]`, event.DeprecatedExtraInfo[EventKeyContainers])
+
+	// too old to attribute to the current processes (e.g., kmsg replay)
+	event = apiv1.Event{
+		Time: metav1.Time{Time: time.Now().Add(-time.Hour)},
+		Name: EventNameErrorXid,
+		DeprecatedExtraInfo: map[string]string{
+			EventKeyErrorXidData: "79",
+			EventKeyDeviceUUID:   "GPU-1",
+		},
+	}
+	c.attributeContainer(&event)
+	assert.NotContains(t, event.DeprecatedExtraInfo, EventKeyGPUPIDs)
+
+	// unknown device
+	event = apiv1.Event{
+		Time: metav1.Time{Time: time.Now()},
+		Name: EventNameErrorXid,
+		DeprecatedExtraInfo: map[string]string{
+			EventKeyErrorXidData: "79",
+			EventKeyDeviceUUID:   "PCI:0000:9b:00",
+		},
+	}
+	c.attributeContainer(&event)
+	assert.NotContains(t, event.DeprecatedExtraInfo, EventKeyGPUPIDs)
+
+	// failed to get the processes
+	c.getProcessesFunc = func(uuid string, dev device.Device) (nvidianvml.Processes, error) {
+		return nvidianvml.Processes{}, errors.New("nvml error")
+	}
+	event.DeprecatedExtraInfo[EventKeyDeviceUUID] = "GPU-1"
+	c.attributeContainer(&event)
+	assert.NotContains(t, event.DeprecatedExtraInfo, EventKeyGPUPIDs)
+}
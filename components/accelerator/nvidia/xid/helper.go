@@ -8,6 +8,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	apiv1 "github.com/leptonai/gpud/api/v1"
+	"github.com/leptonai/gpud/pkg/healthstate"
 	"github.com/leptonai/gpud/pkg/log"
 	"github.com/leptonai/gpud/pkg/nvidia-query/xid"
 )
@@ -20,6 +21,43 @@ const (
 	rebootThreshold = 2
 )
 
+// evolveHealthyStates resolves the state of the XID error component per device,
+// so that the XID errors on one device do not affect the states of the other GPUs.
+// Returns one state per device (named after the device UUID), or a single healthy
+// state if no XID error is found.
+// note: assume events are sorted by time in descending order
+func (c *component) evolveHealthyStates(events apiv1.Events) apiv1.HealthStates {
+	// the events recorded with the PCI device ID (e.g., before the NVML is loaded)
+	// are grouped with the events recorded with the GPU UUID for the same GPU
+	uuids := make(map[string]string)
+	getUUID := func(event apiv1.Event) string {
+		id := getDeviceUUID(event)
+		uuid, ok := uuids[id]
+		if !ok {
+			uuid = c.deviceUUID(id)
+			uuids[id] = uuid
+		}
+		return uuid
+	}
+	return healthstate.EvolvePerDevice(events, EventNameErrorXid, EventKeyDeviceUUID, getUUID, evolveHealthyState)
+}
+
+// getDeviceUUID returns the device UUID of the XID event,
+// either from the extra info or from the resolved XID data.
+func getDeviceUUID(event apiv1.Event) string {
+	if event.DeprecatedExtraInfo == nil {
+		return ""
+	}
+	if uuid := event.DeprecatedExtraInfo[EventKeyDeviceUUID]; uuid != "" {
+		return uuid
+	}
+	var xidErr xidErrorEventDetail
+	if err := json.Unmarshal([]byte(event.DeprecatedExtraInfo[EventKeyErrorXidData]), &xidErr); err != nil {
+		return ""
+	}
+	return xidErr.DeviceUUID
+}
+
 // evolveHealthyState resolves the state of the XID error component.
 // note: assume events are sorted by time in descending order
 func evolveHealthyState(events apiv1.Events) (ret apiv1.HealthState) {
@@ -124,12 +162,17 @@ func resolveXIDEvent(event apiv1.Event) apiv1.Event {
 			}
 			ret.Type = detail.EventType
 			ret.Message = fmt.Sprintf("XID %d(%s) detected on %s", currXid, detail.Name, event.DeprecatedExtraInfo[EventKeyDeviceUUID])
+			migUUID := event.DeprecatedExtraInfo[EventKeyMIGUUID]
+			if migUUID != "" {
+				ret.Message += fmt.Sprintf(" (MIG %s)", migUUID)
+			}
 			ret.DeprecatedSuggestedActions = detail.SuggestedActionsByGPUd
 
 			xidErr := xidErrorEventDetail{
 				Time:                      event.Time,
-				DataSource:                "kmsg",
+				DataSource:                getEventSource(event),
 				DeviceUUID:                event.DeprecatedExtraInfo[EventKeyDeviceUUID],
+				MIGUUID:                   migUUID,
 				Xid:                       uint64(currXid),
 				SuggestedActionsByGPUd:    detail.SuggestedActionsByGPUd,
 				CriticalErrorMarkedByGPUd: detail.CriticalErrorMarkedByGPUd,
@@ -152,6 +195,9 @@ type xidErrorEventDetail struct {
 
 	// DeviceUUID is the UUID of the device that has the error.
 	DeviceUUID string `json:"device_uuid"`
+	// MIGUUID is the UUID of the MIG device that has the error,
+	// empty if the MIG mode is disabled or the MIG device is not resolved.
+	MIGUUID string `json:"mig_uuid,omitempty"`
 
 	// Xid is the corresponding Xid from the raw event.
 	// The monitoring component can use this Xid to decide its own action.
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	apiv1 "github.com/leptonai/gpud/api/v1"
@@ -103,6 +104,115 @@ func TestStateUpdateBasedOnEvents(t *testing.T) {
 	})
 }
 
+func TestEvolveHealthyStatesPerDevice(t *testing.T) {
+	withDevice := func(event apiv1.Event, uuid string) apiv1.Event {
+		event.DeprecatedExtraInfo[EventKeyDeviceUUID] = uuid
+		return event
+	}
+
+	t.Run("no event found", func(t *testing.T) {
+		states := (&component{}).evolveHealthyStates(apiv1.Events{{Name: "reboot"}})
+		assert.Len(t, states, 1)
+		assert.Equal(t, apiv1.HealthStateTypeHealthy, states[0].Health)
+		assert.Equal(t, "XIDComponent is healthy", states[0].Reason)
+		assert.Nil(t, states[0].DeprecatedExtraInfo)
+		assert.Equal(t, StateNameErrorXid, states[0].Name)
+	})
+
+	t.Run("single-bit ECC error does not affect the state", func(t *testing.T) {
+		states := (&component{}).evolveHealthyStates(apiv1.Events{{
+			Name:                EventNameNVMLSingleBitECC,
+			Type:                apiv1.EventTypeWarning,
+			DeprecatedExtraInfo: map[string]string{EventKeyDeviceUUID: "PCI:0000:9b:00", EventKeySource: SourceNVML},
+		}})
+		assert.Len(t, states, 1)
+		assert.Equal(t, apiv1.HealthStateTypeHealthy, states[0].Health)
+		assert.Equal(t, StateNameErrorXid, states[0].Name)
+	})
+
+	events := apiv1.Events{
+		withDevice(createXidEvent(time.Time{}, 456, apiv1.EventTypeFatal, apiv1.RepairActionTypeRebootSystem), "PCI:0000:9b:00"),
+		withDevice(createXidEvent(time.Time{}, 123, apiv1.EventTypeCritical, apiv1.RepairActionTypeRebootSystem), "PCI:0000:1a:00"),
+	}
+
+	t.Run("one state per device", func(t *testing.T) {
+		states := (&component{}).evolveHealthyStates(events)
+		assert.Len(t, states, 2)
+
+		// sorted by the device UUID
+		assert.Equal(t, "PCI:0000:1a:00", states[0].DeprecatedExtraInfo[EventKeyDeviceUUID])
+		assert.Equal(t, apiv1.HealthStateTypeDegraded, states[0].Health)
+		assert.Equal(t, "error_xid/PCI:0000:1a:00", states[0].Name)
+		assert.Equal(t, "error_xid/PCI:0000:9b:00", states[1].Name)
+		assert.Equal(t, "PCI:0000:9b:00", states[1].DeprecatedExtraInfo[EventKeyDeviceUUID])
+		assert.Equal(t, apiv1.HealthStateTypeUnhealthy, states[1].Health)
+	})
+
+	t.Run("SetHealthy on one device", func(t *testing.T) {
+		states := (&component{}).evolveHealthyStates(append(apiv1.Events{
+			{Name: "SetHealthy", DeprecatedExtraInfo: map[string]string{EventKeyDeviceUUID: "PCI:0000:9b:00"}},
+		}, events...))
+		assert.Len(t, states, 2)
+		assert.Equal(t, apiv1.HealthStateTypeDegraded, states[0].Health)
+		assert.Equal(t, apiv1.HealthStateTypeHealthy, states[1].Health)
+		assert.Equal(t, "XIDComponent is healthy on PCI:0000:9b:00", states[1].Reason)
+		assert.Nil(t, states[1].SuggestedActions)
+	})
+
+	t.Run("SetHealthy on all devices", func(t *testing.T) {
+		states := (&component{}).evolveHealthyStates(append(apiv1.Events{{Name: "SetHealthy"}}, events...))
+		assert.Len(t, states, 2)
+		for _, state := range states {
+			assert.Equal(t, apiv1.HealthStateTypeHealthy, state.Health)
+		}
+	})
+
+	t.Run("reboot applies to all devices", func(t *testing.T) {
+		states := (&component{}).evolveHealthyStates(append(apiv1.Events{{Name: "reboot"}}, events...))
+		assert.Len(t, states, 2)
+		for _, state := range states {
+			assert.Equal(t, apiv1.HealthStateTypeHealthy, state.Health)
+		}
+	})
+}
+
+func TestEvolveHealthyStatesByGPUUUID(t *testing.T) {
+	nvmlInstance := createMockNVMLInstance()
+	nvmlInstance.devices["GPU-1"] = newMockEventDevice("GPU-1", nvmlEventTypes, "00000000:9B:00.0")
+	c := &component{nvmlInstance: nvmlInstance}
+
+	assert.Equal(t, "GPU-1", c.deviceUUID("PCI:0000:9b:00"))
+	assert.Equal(t, "GPU-1", c.deviceUUID("0000:9B:00"))
+	assert.Equal(t, "GPU-1", c.deviceUUID("GPU-1"))
+	// unknown GPU, in the kernel message format
+	assert.Equal(t, "PCI:0000:1a:00", c.deviceUUID("0000:1A:00"))
+	assert.Equal(t, "GPU-2", c.deviceUUID("GPU-2"))
+
+	withDevice := func(event apiv1.Event, uuid string) apiv1.Event {
+		event.DeprecatedExtraInfo[EventKeyDeviceUUID] = uuid
+		return event
+	}
+	events := apiv1.Events{
+		withDevice(createXidEvent(time.Time{}, 456, apiv1.EventTypeFatal, apiv1.RepairActionTypeRebootSystem), "PCI:0000:9b:00"),
+		withDevice(createXidEvent(time.Time{}, 123, apiv1.EventTypeCritical, apiv1.RepairActionTypeRebootSystem), "0000:9b:00"),
+		withDevice(createXidEvent(time.Time{}, 123, apiv1.EventTypeCritical, apiv1.RepairActionTypeRebootSystem), "GPU-1"),
+	}
+
+	// the same GPU is tracked once, by the GPU UUID
+	states := c.evolveHealthyStates(events)
+	require.Len(t, states, 1)
+	assert.Equal(t, "error_xid/GPU-1", states[0].Name)
+	assert.Equal(t, "GPU-1", states[0].DeprecatedExtraInfo[EventKeyDeviceUUID])
+	assert.Equal(t, apiv1.HealthStateTypeUnhealthy, states[0].Health)
+
+	// set healthy by the GPU UUID
+	states = c.evolveHealthyStates(append(apiv1.Events{
+		{Name: "SetHealthy", DeprecatedExtraInfo: map[string]string{EventKeyDeviceUUID: "GPU-1"}},
+	}, events...))
+	require.Len(t, states, 1)
+	assert.Equal(t, apiv1.HealthStateTypeHealthy, states[0].Health)
+}
+
 func Test_xidErrorEventDetailJSON(t *testing.T) {
 	testTime := metav1.Time{Time: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
 
@@ -21,12 +21,19 @@ const (
 
 	// Regex to extract PCI device ID from NVRM Xid messages
 	// Matches both formats: (0000:03:00) and (PCI:0000:05:00)
-	RegexNVRMXidDeviceUUID = `NVRM: Xid \(((?:PCI:)?[0-9a-fA-F:]+)\)`
+	// In the MIG mode, the GPU instance ID follows the PCI device ID
+	// (e.g., "(PCI:0000:3b:00 GPU-I:01)").
+	RegexNVRMXidDeviceUUID = `NVRM: Xid \(((?:PCI:)?[0-9a-fA-F:]+)(?: GPU-I:([0-9]+))?\)`
+
+	// Regex to extract the process ID from NVRM Xid messages
+	// (e.g., "pid=12345," or "pid='12345',"), where "pid='<unknown>'" does not match.
+	RegexNVRMXidPID = `NVRM: Xid.*?, pid='?(\d+)'?,`
 )
 
 var (
 	compiledRegexNVRMXidKMessage   = regexp.MustCompile(RegexNVRMXidKMessage)
 	compiledRegexNVRMXidDeviceUUID = regexp.MustCompile(RegexNVRMXidDeviceUUID)
+	compiledRegexNVRMXidPID        = regexp.MustCompile(RegexNVRMXidPID)
 )
 
 // ExtractNVRMXid extracts the nvidia Xid error code from the dmesg log line.
@@ -52,10 +59,43 @@ func ExtractNVRMXidDeviceUUID(line string) string {
 	return ""
 }
 
+// ExtractNVRMXidGPUInstanceID extracts the MIG GPU instance ID from the NVRM Xid dmesg log line.
+// Returns -1 if the GPU instance ID is not found (e.g., the MIG mode is disabled).
+func ExtractNVRMXidGPUInstanceID(line string) int {
+	if match := compiledRegexNVRMXidDeviceUUID.FindStringSubmatch(line); match != nil && match[2] != "" {
+		if id, err := strconv.Atoi(match[2]); err == nil {
+			return id
+		}
+	}
+	return -1
+}
+
+// ExtractNVRMXidPID extracts the process ID from the NVRM Xid dmesg log line.
+// Returns 0 if the process ID is not found or unknown (e.g., "pid='<unknown>'").
+func ExtractNVRMXidPID(line string) int {
+	if match := compiledRegexNVRMXidPID.FindStringSubmatch(line); match != nil {
+		if pid, err := strconv.Atoi(match[1]); err == nil {
+			return pid
+		}
+	}
+	return 0
+}
+
 type XidError struct {
 	Xid        int         `json:"xid"`
 	DeviceUUID string      `json:"device_uuid"`
 	Detail     *xid.Detail `json:"detail,omitempty"`
+
+	// GPUInstanceID is the MIG GPU instance ID of the error,
+	// -1 if the MIG mode is disabled.
+	GPUInstanceID int `json:"gpu_instance_id"`
+	// MIGUUID is the MIG device UUID of the error,
+	// empty if not resolved.
+	MIGUUID string `json:"mig_uuid,omitempty"`
+
+	// PID is the process ID of the error,
+	// 0 if unknown.
+	PID int `json:"pid,omitempty"`
 }
 
 func (xidErr *XidError) YAML() ([]byte, error) {
@@ -75,8 +115,10 @@ func Match(line string) *XidError {
 	}
 	deviceUUID := ExtractNVRMXidDeviceUUID(line)
 	return &XidError{
-		Xid:        extractedID,
-		DeviceUUID: deviceUUID,
-		Detail:     detail,
+		Xid:           extractedID,
+		DeviceUUID:    deviceUUID,
+		Detail:        detail,
+		GPUInstanceID: ExtractNVRMXidGPUInstanceID(line),
+		PID:           ExtractNVRMXidPID(line),
 	}
 }
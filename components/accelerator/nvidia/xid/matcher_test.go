@@ -89,6 +89,11 @@ func TestExtractNVRMXidDeviceUUID(t *testing.T) {
 			input:    "NVRM: Xid (PCI:0000:05:00): 79, pid='<unknown>', name=<unknown>, GPU has fallen off the bus.",
 			expected: "PCI:0000:05:00",
 		},
+		{
+			name:     "device ID with MIG GPU instance ID",
+			input:    "NVRM: Xid (PCI:0000:3b:00 GPU-I:01): 43, pid=1234, name=python, Ch 00000008",
+			expected: "PCI:0000:3b:00",
+		},
 		{
 			name:     "no device ID",
 			input:    "Regular log content without Xid",
@@ -111,6 +116,91 @@ func TestExtractNVRMXidDeviceUUID(t *testing.T) {
 	}
 }
 
+func TestExtractNVRMXidGPUInstanceID(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		input    string
+		expected int
+	}{
+		{
+			name:     "MIG GPU instance ID",
+			input:    "NVRM: Xid (PCI:0000:3b:00 GPU-I:01): 43, pid=1234, name=python, Ch 00000008",
+			expected: 1,
+		},
+		{
+			name:     "MIG GPU instance ID with timestamp",
+			input:    "[...] NVRM: Xid (PCI:0000:3b:00 GPU-I:12): 31, pid=1234, name=python",
+			expected: 12,
+		},
+		{
+			name:     "no MIG GPU instance ID",
+			input:    "NVRM: Xid (PCI:0000:05:00): 79, pid='<unknown>', name=<unknown>, GPU has fallen off the bus.",
+			expected: -1,
+		},
+		{
+			name:     "no device ID",
+			input:    "Regular log content without Xid",
+			expected: -1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := ExtractNVRMXidGPUInstanceID(tt.input)
+			if result != tt.expected {
+				t.Errorf("ExtractNVRMXidGPUInstanceID(%q) = %d, want %d", tt.input, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestExtractNVRMXidPID(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		input    string
+		expected int
+	}{
+		{
+			name:     "pid without quotes",
+			input:    "NVRM: Xid (PCI:0000:3b:00 GPU-I:01): 43, pid=1234, name=python, Ch 00000008",
+			expected: 1234,
+		},
+		{
+			name:     "pid with quotes",
+			input:    "NVRM: Xid (PCI:0000:9b:00): 31, pid='56789', name=pt_main_thread, Ch 00000008, intr 00000000",
+			expected: 56789,
+		},
+		{
+			name:     "unknown pid",
+			input:    "NVRM: Xid (PCI:0000:05:00): 79, pid='<unknown>', name=<unknown>, GPU has fallen off the bus.",
+			expected: 0,
+		},
+		{
+			name:     "no pid",
+			input:    "NVRM: Xid (PCI:0000:01:00): 79, GPU has fallen off the bus.",
+			expected: 0,
+		},
+		{
+			name:     "not xid",
+			input:    "pid=1234, name=python",
+			expected: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := ExtractNVRMXidPID(tt.input)
+			if result != tt.expected {
+				t.Errorf("ExtractNVRMXidPID(%q) = %d, want %d", tt.input, result, tt.expected)
+			}
+		})
+	}
+}
+
 func TestMatch(t *testing.T) {
 	t.Parallel()
 
@@ -135,6 +225,13 @@ func TestMatch(t *testing.T) {
 			expectedXid:    14,
 			expectedDevice: "0000:03:00",
 		},
+		{
+			name:           "valid XID error with MIG GPU instance ID",
+			input:          "NVRM: Xid (PCI:0000:3b:00 GPU-I:01): 43, pid=1234, name=python, Ch 00000008",
+			expectNil:      false,
+			expectedXid:    43,
+			expectedDevice: "PCI:0000:3b:00",
+		},
 		{
 			name:      "no XID error",
 			input:     "Regular log content without Xid errors",
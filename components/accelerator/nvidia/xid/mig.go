@@ -0,0 +1,122 @@
+package xid
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/NVIDIA/go-nvlib/pkg/nvlib/device"
+
+	apiv1 "github.com/leptonai/gpud/api/v1"
+	"github.com/leptonai/gpud/pkg/log"
+)
+
+const (
+	// EventKeyGPUInstanceID is the extra info key for the MIG GPU instance ID of the XID event.
+	EventKeyGPUInstanceID = "gpu_instance_id"
+	// EventKeyComputeInstanceID is the extra info key for the MIG compute instance ID of the XID event.
+	EventKeyComputeInstanceID = "compute_instance_id"
+	// EventKeyMIGUUID is the extra info key for the MIG device UUID of the XID event.
+	EventKeyMIGUUID = "mig_uuid"
+)
+
+// attributeMIG sets the MIG device UUID of the XID event
+// based on its GPU instance ID (and the compute instance ID if known).
+// No-op if the event has no instance ID or the MIG device is not uniquely resolved.
+func (c *component) attributeMIG(event *apiv1.Event) {
+	if event.DeprecatedExtraInfo == nil {
+		return
+	}
+	gi, err := strconv.Atoi(event.DeprecatedExtraInfo[EventKeyGPUInstanceID])
+	if err != nil {
+		return
+	}
+	ci := -1
+	if v, err := strconv.Atoi(event.DeprecatedExtraInfo[EventKeyComputeInstanceID]); err == nil {
+		ci = v
+	}
+
+	if migUUID := c.resolveMIGUUID(event.DeprecatedExtraInfo[EventKeyDeviceUUID], gi, ci); migUUID != "" {
+		event.DeprecatedExtraInfo[EventKeyMIGUUID] = migUUID
+	}
+}
+
+// resolveMIGUUID returns the MIG device UUID of the GPU instance (and the compute
+// instance, if non-negative) on the device, where the device ID is either
+// the GPU UUID or the PCI device ID in the kernel messages (e.g., "PCI:0000:9b:00").
+// Returns an empty string if not found, or if the GPU instance has multiple
+// compute instances and the compute instance is unknown (e.g., kernel messages).
+func (c *component) resolveMIGUUID(deviceID string, gpuInstanceID int, computeInstanceID int) string {
+	if c.getMIGFunc == nil {
+		return ""
+	}
+	uuid, dev, ok := c.findDevice(deviceID)
+	if !ok {
+		return ""
+	}
+
+	mig, err := c.getMIGFunc(uuid, dev)
+	if err != nil {
+		log.Logger.Warnw("failed to get mig devices", "uuid", uuid, "error", err)
+		return ""
+	}
+	if !mig.Enabled {
+		return ""
+	}
+
+	migUUID := ""
+	for _, inst := range mig.Instances {
+		if inst.GPUInstanceID != gpuInstanceID {
+			continue
+		}
+		if computeInstanceID >= 0 && inst.ComputeInstanceID != computeInstanceID {
+			continue
+		}
+		if migUUID != "" {
+			// multiple compute instances in the GPU instance
+			return ""
+		}
+		migUUID = inst.UUID
+	}
+	return migUUID
+}
+
+// findDevice returns the GPU of the device ID, either
+// the GPU UUID or the PCI device ID in the kernel messages (e.g., "PCI:0000:9b:00").
+// Returns false if not found.
+func (c *component) findDevice(deviceID string) (string, device.Device, bool) {
+	if c.nvmlInstance == nil || deviceID == "" {
+		return "", nil, false
+	}
+
+	kmsgDeviceID := strings.ToLower(deviceID)
+	if !strings.HasPrefix(kmsgDeviceID, "pci:") {
+		kmsgDeviceID = "pci:" + kmsgDeviceID
+	}
+
+	for uuid, dev := range c.nvmlInstance.Devices() {
+		if uuid != deviceID {
+			busID, err := dev.GetPCIBusID()
+			if err != nil || strings.ToLower(toKmsgDeviceID(busID)) != kmsgDeviceID {
+				continue
+			}
+		}
+		return uuid, dev, true
+	}
+	return "", nil, false
+}
+
+// deviceUUID returns the GPU UUID of the device ID, either
+// the GPU UUID or the PCI device ID in the kernel messages (e.g., "PCI:0000:9b:00"),
+// so that the events and the per-device states of the same GPU are keyed
+// by the GPU UUID, regardless of the source and the PCI device ID format.
+// Returns the PCI device ID in the kernel message format, if the GPU is not found
+// (e.g., NVML is not loaded, GPU fell off the bus).
+func (c *component) deviceUUID(deviceID string) string {
+	if uuid, _, ok := c.findDevice(deviceID); ok {
+		return uuid
+	}
+	if deviceID == "" || strings.HasPrefix(deviceID, "GPU-") {
+		return deviceID
+	}
+	return toKmsgDeviceID(strings.TrimPrefix(strings.ToLower(deviceID), "pci:"))
+}
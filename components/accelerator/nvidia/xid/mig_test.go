@@ -0,0 +1,84 @@
+package xid
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/NVIDIA/go-nvlib/pkg/nvlib/device"
+	"github.com/stretchr/testify/assert"
+
+	apiv1 "github.com/leptonai/gpud/api/v1"
+	nvidianvml "github.com/leptonai/gpud/pkg/nvidia-query/nvml"
+)
+
+func TestResolveMIGUUID(t *testing.T) {
+	nvmlInstance := createMockNVMLInstance()
+	nvmlInstance.devices["GPU-1"] = newMockEventDevice("GPU-1", nvmlEventTypes, "00000000:3B:00.0")
+	nvmlInstance.devices["GPU-2"] = newMockEventDevice("GPU-2", nvmlEventTypes, "00000000:5B:00.0")
+
+	migs := map[string]nvidianvml.MIG{
+		"GPU-1": {
+			UUID:    "GPU-1",
+			Enabled: true,
+			Instances: []nvidianvml.MIGInstance{
+				{UUID: "MIG-1", ParentUUID: "GPU-1", GPUInstanceID: 1, ComputeInstanceID: 0},
+				{UUID: "MIG-2", ParentUUID: "GPU-1", GPUInstanceID: 2, ComputeInstanceID: 0},
+				{UUID: "MIG-3", ParentUUID: "GPU-1", GPUInstanceID: 2, ComputeInstanceID: 1},
+			},
+		},
+		"GPU-2": {UUID: "GPU-2"},
+	}
+	c := &component{
+		nvmlInstance: nvmlInstance,
+		getMIGFunc: func(uuid string, dev device.Device) (nvidianvml.MIG, error) {
+			return migs[uuid], nil
+		},
+	}
+
+	// by the kernel message device ID
+	assert.Equal(t, "MIG-1", c.resolveMIGUUID("PCI:0000:3b:00", 1, -1))
+	assert.Equal(t, "MIG-1", c.resolveMIGUUID("0000:3b:00", 1, -1))
+	// by the GPU UUID
+	assert.Equal(t, "MIG-3", c.resolveMIGUUID("GPU-1", 2, 1))
+	// multiple compute instances in the GPU instance
+	assert.Empty(t, c.resolveMIGUUID("PCI:0000:3b:00", 2, -1))
+	// unknown GPU instance
+	assert.Empty(t, c.resolveMIGUUID("PCI:0000:3b:00", 9, -1))
+	// MIG mode disabled
+	assert.Empty(t, c.resolveMIGUUID("GPU-2", 1, 0))
+	// unknown device
+	assert.Empty(t, c.resolveMIGUUID("PCI:0000:9b:00", 1, -1))
+
+	event := apiv1.Event{
+		Name: EventNameErrorXid,
+		DeprecatedExtraInfo: map[string]string{
+			EventKeyErrorXidData:      "43",
+			EventKeyDeviceUUID:        "PCI:0000:3b:00",
+			EventKeyGPUInstanceID:     "2",
+			EventKeyComputeInstanceID: "0",
+		},
+	}
+	c.attributeMIG(&event)
+	assert.Equal(t, "MIG-2", event.DeprecatedExtraInfo[EventKeyMIGUUID])
+
+	resolved := resolveXIDEvent(event)
+	assert.Contains(t, resolved.Message, "(MIG MIG-2)")
+	assert.Contains(t, resolved.DeprecatedExtraInfo[EventKeyErrorXidData], `"mig_uuid":"MIG-2"`)
+
+	// no instance ID
+	event = apiv1.Event{
+		Name: EventNameErrorXid,
+		DeprecatedExtraInfo: map[string]string{
+			EventKeyErrorXidData: "43",
+			EventKeyDeviceUUID:   "PCI:0000:3b:00",
+		},
+	}
+	c.attributeMIG(&event)
+	assert.NotContains(t, event.DeprecatedExtraInfo, EventKeyMIGUUID)
+
+	// failed to get the MIG devices
+	c.getMIGFunc = func(uuid string, dev device.Device) (nvidianvml.MIG, error) {
+		return nvidianvml.MIG{}, errors.New("mig error")
+	}
+	assert.Empty(t, c.resolveMIGUUID("PCI:0000:3b:00", 1, -1))
+}
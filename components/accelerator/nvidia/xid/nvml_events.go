@@ -0,0 +1,329 @@
+package xid
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/NVIDIA/go-nvlib/pkg/nvlib/device"
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	apiv1 "github.com/leptonai/gpud/api/v1"
+	"github.com/leptonai/gpud/pkg/log"
+)
+
+const (
+	// EventKeySource is the extra info key for the source of the XID event.
+	// The events without the key are from the kernel messages.
+	EventKeySource = "source"
+
+	// SourceKmsg is the source for the XID events from the kernel messages.
+	SourceKmsg = "kmsg"
+	// SourceNVML is the source for the XID events from the NVML events.
+	SourceNVML = "nvml"
+
+	// EventNameNVMLSingleBitECC is the event name for the single-bit ECC errors from the NVML events.
+	// The single-bit ECC errors are correctable, thus counted per device and recorded
+	// as a warning for each aggregation interval rather than an Xid,
+	// and do not affect the health state.
+	EventNameNVMLSingleBitECC = "nvml_single_bit_ecc"
+
+	// EventKeyCount is the extra info key for the number of the single-bit ECC errors
+	// within the aggregation interval.
+	EventKeyCount = "count"
+
+	// nvmlEventTypes are the NVML event types to watch.
+	nvmlEventTypes = nvml.EventTypeXidCriticalError | nvml.EventTypeDoubleBitEccError | nvml.EventTypeSingleBitEccError
+
+	// nvmlEventWaitTimeout is the timeout in milliseconds of each NVML event wait,
+	// to check the context cancellation in between.
+	nvmlEventWaitTimeout = uint32(1000)
+
+	// the NVML ECC events do not carry the Xid, so they are recorded
+	// as the corresponding Xid, "Double Bit ECC Error"
+	xidDoubleBitECCError = 48
+
+	// crossSourceWindow is the time window to consider the same XID on the same device
+	// from both the kernel messages and the NVML events as the same error,
+	// so that the error is only recorded once
+	crossSourceWindow = time.Minute
+
+	// invalidInstanceID is the GPU or compute instance ID
+	// of the NVML events on the devices without the MIG mode
+	invalidInstanceID = uint32(0xFFFFFFFF)
+
+	// defaultSingleBitECCInterval is the interval to aggregate the single-bit ECC errors
+	// of each device into one event, since a faulty memory may generate
+	// the correctable errors at a high rate
+	defaultSingleBitECCInterval = 10 * time.Minute
+)
+
+// nvmlEventWatcher watches the XID and ECC events from NVML,
+// as an alternative to the kernel messages (e.g., in containers without "/dev/kmsg").
+type nvmlEventWatcher struct {
+	eventSet nvml.EventSet
+	// maps the GPU UUID to the device ID in the kernel messages (e.g., "PCI:0000:9b:00")
+	deviceIDs map[string]string
+
+	// the interval to aggregate the single-bit ECC errors
+	singleBitECCInterval time.Duration
+	// the number of the single-bit ECC errors per device ID
+	// since the start of the current interval
+	singleBitECCCounts map[string]int
+	singleBitECCSince  time.Time
+}
+
+// newNVMLEventWatcher creates the NVML event set registered for the XID and ECC events
+// of all the devices. Returns nil if no device supports the events.
+func newNVMLEventWatcher(nvmlLib nvml.Interface, devs map[string]device.Device) (*nvmlEventWatcher, error) {
+	eventSet, ret := nvmlLib.EventSetCreate()
+	if ret != nvml.SUCCESS {
+		return nil, fmt.Errorf("failed to create nvml event set: %v", nvml.ErrorString(ret))
+	}
+
+	w := &nvmlEventWatcher{
+		eventSet:             eventSet,
+		deviceIDs:            make(map[string]string, len(devs)),
+		singleBitECCInterval: defaultSingleBitECCInterval,
+		singleBitECCCounts:   make(map[string]int),
+	}
+	for uuid, dev := range devs {
+		supported, ret := dev.GetSupportedEventTypes()
+		if ret != nvml.SUCCESS {
+			log.Logger.Warnw("failed to get supported nvml event types", "uuid", uuid, "error", nvml.ErrorString(ret))
+			continue
+		}
+		eventTypes := supported & nvmlEventTypes
+		if eventTypes == 0 {
+			log.Logger.Infow("nvml xid events not supported", "uuid", uuid)
+			continue
+		}
+		if ret := dev.RegisterEvents(eventTypes, eventSet); ret != nvml.SUCCESS {
+			log.Logger.Warnw("failed to register nvml events", "uuid", uuid, "error", nvml.ErrorString(ret))
+			continue
+		}
+
+		deviceID := uuid
+		if busID, err := dev.GetPCIBusID(); err == nil && busID != "" {
+			deviceID = toKmsgDeviceID(busID)
+		}
+		w.deviceIDs[uuid] = deviceID
+	}
+
+	if len(w.deviceIDs) == 0 {
+		_ = eventSet.Free()
+		return nil, nil
+	}
+	return w, nil
+}
+
+// toKmsgDeviceID converts the PCI bus ID from NVML (e.g., "0000:9b:00.0")
+// to the device ID in the kernel XID messages (e.g., "PCI:0000:9b:00"),
+// so that the XID errors from both sources are tracked for the same device.
+func toKmsgDeviceID(busID string) string {
+	busID = strings.ToLower(busID)
+	if idx := strings.LastIndex(busID, "."); idx > 0 {
+		busID = busID[:idx]
+	}
+
+	// NVML may report the 8-digit PCI domain (e.g., "00000000:9b:00")
+	if parts := strings.Split(busID, ":"); len(parts) == 3 && len(parts[0]) == 8 {
+		parts[0] = parts[0][4:]
+		busID = strings.Join(parts, ":")
+	}
+	return "PCI:" + busID
+}
+
+// watch waits for the NVML events and sends the XID events
+// and the aggregated single-bit ECC events to the channel,
+// until the context is canceled.
+func (w *nvmlEventWatcher) watch(ctx context.Context, ch chan<- apiv1.Event) {
+	defer func() {
+		if ret := w.eventSet.Free(); ret != nvml.SUCCESS {
+			log.Logger.Warnw("failed to free nvml event set", "error", nvml.ErrorString(ret))
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		for _, event := range w.flushSingleBitECC(time.Now().UTC()) {
+			select {
+			case <-ctx.Done():
+				return
+			case ch <- event:
+			}
+		}
+
+		data, ret := w.eventSet.Wait(nvmlEventWaitTimeout)
+		if ret == nvml.ERROR_TIMEOUT {
+			continue
+		}
+		if ret != nvml.SUCCESS {
+			log.Logger.Warnw("failed to wait for nvml events", "error", nvml.ErrorString(ret))
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+
+		event, ok := w.toEvent(data, time.Now().UTC())
+		if !ok {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case ch <- event:
+		}
+	}
+}
+
+// toEvent converts the NVML event data to the XID event,
+// in the same format as the XID events from the kernel messages.
+// The single-bit ECC errors are only counted, to be flushed
+// as the aggregated warning events (see flushSingleBitECC).
+func (w *nvmlEventWatcher) toEvent(data nvml.EventData, now time.Time) (apiv1.Event, bool) {
+	var xid uint64
+	switch data.EventType {
+	case nvml.EventTypeXidCriticalError:
+		xid = data.EventData
+	case nvml.EventTypeDoubleBitEccError:
+		xid = xidDoubleBitECCError
+	case nvml.EventTypeSingleBitEccError:
+	default:
+		log.Logger.Debugw("unexpected nvml event type, skip", "eventType", data.EventType)
+		return apiv1.Event{}, false
+	}
+
+	deviceID := ""
+	if data.Device != nil {
+		uuid, ret := data.Device.GetUUID()
+		if ret == nvml.SUCCESS {
+			deviceID = w.deviceIDs[uuid]
+			if deviceID == "" {
+				deviceID = uuid
+			}
+		}
+	}
+
+	if data.EventType == nvml.EventTypeSingleBitEccError {
+		if len(w.singleBitECCCounts) == 0 {
+			w.singleBitECCSince = now
+		}
+		w.singleBitECCCounts[deviceID]++
+		return apiv1.Event{}, false
+	}
+
+	extraInfo := map[string]string{
+		EventKeyDeviceUUID: deviceID,
+		EventKeySource:     SourceNVML,
+	}
+	// the instance IDs are only set for the MIG-enabled devices
+	if data.GpuInstanceId != invalidInstanceID {
+		extraInfo[EventKeyGPUInstanceID] = strconv.FormatUint(uint64(data.GpuInstanceId), 10)
+	}
+	if data.ComputeInstanceId != invalidInstanceID {
+		extraInfo[EventKeyComputeInstanceID] = strconv.FormatUint(uint64(data.ComputeInstanceId), 10)
+	}
+
+	extraInfo[EventKeyErrorXidData] = strconv.FormatUint(xid, 10)
+	return apiv1.Event{
+		Time:                metav1.Time{Time: now},
+		Name:                EventNameErrorXid,
+		DeprecatedExtraInfo: extraInfo,
+	}, true
+}
+
+// flushSingleBitECC returns one warning event per device with the number of
+// the single-bit ECC errors counted since the first error, once the aggregation
+// interval has elapsed, and resets the counts.
+func (w *nvmlEventWatcher) flushSingleBitECC(now time.Time) []apiv1.Event {
+	if len(w.singleBitECCCounts) == 0 || now.Sub(w.singleBitECCSince) < w.singleBitECCInterval {
+		return nil
+	}
+
+	deviceIDs := make([]string, 0, len(w.singleBitECCCounts))
+	for deviceID := range w.singleBitECCCounts {
+		deviceIDs = append(deviceIDs, deviceID)
+	}
+	sort.Strings(deviceIDs)
+
+	events := make([]apiv1.Event, 0, len(deviceIDs))
+	for _, deviceID := range deviceIDs {
+		count := w.singleBitECCCounts[deviceID]
+		events = append(events, apiv1.Event{
+			Time:    metav1.Time{Time: now},
+			Name:    EventNameNVMLSingleBitECC,
+			Type:    apiv1.EventTypeWarning,
+			Message: fmt.Sprintf("%d single-bit ECC error(s) (correctable) since %s", count, w.singleBitECCSince.Format(time.RFC3339)),
+			DeprecatedExtraInfo: map[string]string{
+				EventKeyDeviceUUID: deviceID,
+				EventKeySource:     SourceNVML,
+				EventKeyCount:      strconv.Itoa(count),
+			},
+		})
+	}
+
+	w.singleBitECCCounts = make(map[string]int)
+	w.singleBitECCSince = time.Time{}
+	return events
+}
+
+// getEventSource returns the source of the XID event.
+func getEventSource(event apiv1.Event) string {
+	if src := event.DeprecatedExtraInfo[EventKeySource]; src != "" {
+		return src
+	}
+	return SourceKmsg
+}
+
+// getEventXid returns the Xid of the event,
+// either from the raw Xid or from the resolved Xid data.
+func getEventXid(event apiv1.Event) (uint64, bool) {
+	data := event.DeprecatedExtraInfo[EventKeyErrorXidData]
+	if v, err := strconv.ParseUint(data, 10, 64); err == nil {
+		return v, true
+	}
+	var xidErr xidErrorEventDetail
+	if err := json.Unmarshal([]byte(data), &xidErr); err != nil {
+		return 0, false
+	}
+	return xidErr.Xid, true
+}
+
+// isSameXidEvent returns true if both events are the same XID on the same device
+// from the different sources within the time window.
+func isSameXidEvent(a, b apiv1.Event, window time.Duration) bool {
+	if a.Name != EventNameErrorXid || b.Name != EventNameErrorXid {
+		return false
+	}
+	if getEventSource(a) == getEventSource(b) {
+		return false
+	}
+	if getDeviceUUID(a) != getDeviceUUID(b) {
+		return false
+	}
+	ax, aok := getEventXid(a)
+	bx, bok := getEventXid(b)
+	if !aok || !bok || ax != bx {
+		return false
+	}
+
+	d := a.Time.Sub(b.Time.Time)
+	if d < 0 {
+		d = -d
+	}
+	return d <= window
+}
@@ -0,0 +1,317 @@
+package xid
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/NVIDIA/go-nvlib/pkg/nvlib/device"
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+	nvmlmock "github.com/NVIDIA/go-nvml/pkg/nvml/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	apiv1 "github.com/leptonai/gpud/api/v1"
+	"github.com/leptonai/gpud/components"
+	"github.com/leptonai/gpud/pkg/eventstore"
+	"github.com/leptonai/gpud/pkg/log"
+	nvmllib "github.com/leptonai/gpud/pkg/nvidia-query/nvml/lib"
+	nvmllibmock "github.com/leptonai/gpud/pkg/nvidia-query/nvml/lib/mock"
+	"github.com/leptonai/gpud/pkg/nvidia-query/nvml/testutil"
+	"github.com/leptonai/gpud/pkg/sqlite"
+)
+
+func newMockEventDevice(uuid string, supported uint64, busID string) *testutil.MockDevice {
+	return testutil.NewMockDevice(&nvmlmock.Device{
+		GetUUIDFunc: func() (string, nvml.Return) {
+			return uuid, nvml.SUCCESS
+		},
+		GetSupportedEventTypesFunc: func() (uint64, nvml.Return) {
+			return supported, nvml.SUCCESS
+		},
+		RegisterEventsFunc: func(v uint64, eventSet nvml.EventSet) nvml.Return {
+			return nvml.SUCCESS
+		},
+	}, "", "", "", busID)
+}
+
+func TestToKmsgDeviceID(t *testing.T) {
+	assert.Equal(t, "PCI:0000:9b:00", toKmsgDeviceID("0000:9b:00.0"))
+	assert.Equal(t, "PCI:0000:9b:00", toKmsgDeviceID("00000000:9B:00.0"))
+	assert.Equal(t, "PCI:0001:1a:00", toKmsgDeviceID("0001:1A:00.0"))
+}
+
+const (
+	testFixtureUUID0 = "GPU-00000000-0000-0000-0000-000000000000"
+	testFixtureUUID1 = "GPU-11111111-1111-1111-1111-111111111111"
+)
+
+// newTestFixtureNVML returns the NVML interface and the devices
+// that serve the GPUs and the scripted NVML events in the test fixture.
+func newTestFixtureNVML(t *testing.T) (*nvmlmock.Interface, map[string]device.Device) {
+	f, err := nvmllibmock.LoadFixture("testdata/nvml-fixture.yaml")
+	require.NoError(t, err)
+
+	nvmlLib := nvmllibmock.NewFixtureInterface(f)
+	lib, err := nvmllib.New(
+		nvmllib.WithNVML(nvmlLib),
+		nvmllib.WithPropertyExtractor(nvmllibmock.HasNvmlPropertyExtractor),
+	)
+	require.NoError(t, err)
+
+	devs, err := lib.Device().GetDevices()
+	require.NoError(t, err)
+
+	devsByUUID := make(map[string]device.Device, len(devs))
+	for _, dev := range devs {
+		uuid, ret := dev.GetUUID()
+		require.Equal(t, nvml.SUCCESS, ret)
+		devsByUUID[uuid] = dev
+	}
+	return nvmlLib, devsByUUID
+}
+
+func TestNewNVMLEventWatcher(t *testing.T) {
+	nvmlLib, devs := newTestFixtureNVML(t)
+
+	w, err := newNVMLEventWatcher(nvmlLib, devs)
+	require.NoError(t, err)
+	require.NotNil(t, w)
+	assert.Equal(t, map[string]string{
+		testFixtureUUID0: "PCI:0000:18:00",
+		testFixtureUUID1: "PCI:0000:2a:00",
+	}, w.deviceIDs)
+	assert.Equal(t, defaultSingleBitECCInterval, w.singleBitECCInterval)
+	assert.Empty(t, w.singleBitECCCounts)
+
+	// no device to watch
+	w, err = newNVMLEventWatcher(nvmlLib, nil)
+	require.NoError(t, err)
+	assert.Nil(t, w)
+
+	nvmlLib.EventSetCreateFunc = func() (nvml.EventSet, nvml.Return) {
+		return nil, nvml.ERROR_NOT_SUPPORTED
+	}
+	_, err = newNVMLEventWatcher(nvmlLib, devs)
+	assert.Error(t, err)
+}
+
+func TestNVMLEventWatcherWatch(t *testing.T) {
+	nvmlLib, devs := newTestFixtureNVML(t)
+
+	w, err := newNVMLEventWatcher(nvmlLib, devs)
+	require.NoError(t, err)
+	require.NotNil(t, w)
+	w.singleBitECCInterval = 500 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := make(chan apiv1.Event, 10)
+	done := make(chan struct{})
+	go func() {
+		w.watch(ctx, ch)
+		close(done)
+	}()
+
+	var events []apiv1.Event
+	for len(events) < 4 {
+		select {
+		case ev := <-ch:
+			assert.Equal(t, SourceNVML, ev.DeprecatedExtraInfo[EventKeySource])
+			events = append(events, ev)
+		case <-time.After(10 * time.Second):
+			t.Fatal("timed out waiting for nvml events")
+		}
+	}
+
+	assert.Equal(t, EventNameErrorXid, events[0].Name)
+	assert.Equal(t, "PCI:0000:18:00", events[0].DeprecatedExtraInfo[EventKeyDeviceUUID])
+	assert.Equal(t, "79", events[0].DeprecatedExtraInfo[EventKeyErrorXidData])
+	assert.NotContains(t, events[0].DeprecatedExtraInfo, EventKeyGPUInstanceID)
+
+	// the double-bit ECC error is recorded as Xid 48
+	assert.Equal(t, EventNameErrorXid, events[1].Name)
+	assert.Equal(t, "PCI:0000:18:00", events[1].DeprecatedExtraInfo[EventKeyDeviceUUID])
+	assert.Equal(t, "48", events[1].DeprecatedExtraInfo[EventKeyErrorXidData])
+
+	// the single-bit ECC errors are aggregated per device, not as an Xid
+	for i, expected := range []struct {
+		deviceID string
+		count    string
+	}{
+		{deviceID: "PCI:0000:18:00", count: "3"},
+		{deviceID: "PCI:0000:2a:00", count: "2"},
+	} {
+		ev := events[2+i]
+		assert.Equal(t, EventNameNVMLSingleBitECC, ev.Name)
+		assert.Equal(t, apiv1.EventTypeWarning, ev.Type)
+		assert.Equal(t, expected.deviceID, ev.DeprecatedExtraInfo[EventKeyDeviceUUID])
+		assert.Equal(t, expected.count, ev.DeprecatedExtraInfo[EventKeyCount])
+		assert.NotContains(t, ev.DeprecatedExtraInfo, EventKeyErrorXidData)
+	}
+
+	// no more events once delivered
+	select {
+	case ev := <-ch:
+		t.Fatalf("unexpected event %+v", ev)
+	case <-time.After(time.Second):
+	}
+
+	cancel()
+	<-done
+}
+
+func TestFlushSingleBitECC(t *testing.T) {
+	nvmlLib, devs := newTestFixtureNVML(t)
+
+	w, err := newNVMLEventWatcher(nvmlLib, devs)
+	require.NoError(t, err)
+	require.NotNil(t, w)
+	w.singleBitECCInterval = time.Minute
+
+	dev, ret := nvmlLib.DeviceGetHandleByIndex(0)
+	require.Equal(t, nvml.SUCCESS, ret)
+	data := nvml.EventData{
+		Device:            dev,
+		EventType:         nvml.EventTypeSingleBitEccError,
+		GpuInstanceId:     invalidInstanceID,
+		ComputeInstanceId: invalidInstanceID,
+	}
+
+	now := time.Now().UTC()
+	assert.Nil(t, w.flushSingleBitECC(now))
+
+	_, ok := w.toEvent(data, now)
+	assert.False(t, ok)
+	_, ok = w.toEvent(data, now.Add(30*time.Second))
+	assert.False(t, ok)
+
+	// the interval starts from the first error
+	assert.Nil(t, w.flushSingleBitECC(now.Add(59*time.Second)))
+
+	events := w.flushSingleBitECC(now.Add(time.Minute))
+	require.Len(t, events, 1)
+	assert.Equal(t, now.Add(time.Minute), events[0].Time.Time)
+	assert.Equal(t, "PCI:0000:18:00", events[0].DeprecatedExtraInfo[EventKeyDeviceUUID])
+	assert.Equal(t, "2", events[0].DeprecatedExtraInfo[EventKeyCount])
+	assert.Contains(t, events[0].Message, "2 single-bit ECC error(s)")
+
+	// the counts are reset
+	assert.Nil(t, w.flushSingleBitECC(now.Add(2*time.Minute)))
+}
+
+func TestIsSameXidEvent(t *testing.T) {
+	now := time.Now().UTC()
+	kmsgEvent := apiv1.Event{
+		Time: metav1.Time{Time: now},
+		Name: EventNameErrorXid,
+		DeprecatedExtraInfo: map[string]string{
+			EventKeyErrorXidData: "79",
+			EventKeyDeviceUUID:   "PCI:0000:9b:00",
+		},
+	}
+	nvmlEvent := apiv1.Event{
+		Time: metav1.Time{Time: now.Add(10 * time.Second)},
+		Name: EventNameErrorXid,
+		DeprecatedExtraInfo: map[string]string{
+			EventKeyErrorXidData: "79",
+			EventKeyDeviceUUID:   "PCI:0000:9b:00",
+			EventKeySource:       SourceNVML,
+		},
+	}
+	assert.True(t, isSameXidEvent(nvmlEvent, kmsgEvent, time.Minute))
+	assert.True(t, isSameXidEvent(kmsgEvent, nvmlEvent, time.Minute))
+	assert.False(t, isSameXidEvent(kmsgEvent, kmsgEvent, time.Minute))
+	assert.False(t, isSameXidEvent(nvmlEvent, kmsgEvent, time.Second))
+
+	// resolved xid data
+	resolved := resolveXIDEvent(apiv1.Event{
+		Time:                kmsgEvent.Time,
+		Name:                EventNameErrorXid,
+		DeprecatedExtraInfo: map[string]string{EventKeyErrorXidData: "79", EventKeyDeviceUUID: "PCI:0000:9b:00"},
+	})
+	assert.True(t, isSameXidEvent(nvmlEvent, resolved, time.Minute))
+
+	otherDevice := nvmlEvent
+	otherDevice.DeprecatedExtraInfo = map[string]string{
+		EventKeyErrorXidData: "79",
+		EventKeyDeviceUUID:   "PCI:0000:1a:00",
+		EventKeySource:       SourceNVML,
+	}
+	assert.False(t, isSameXidEvent(otherDevice, kmsgEvent, time.Minute))
+}
+
+func TestInsertEventCrossSource(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	dbRW, dbRO, cleanup := sqlite.OpenTestDB(t)
+	defer cleanup()
+
+	store, err := eventstore.New(dbRW, dbRO, DefaultRetentionPeriod)
+	require.NoError(t, err)
+
+	comp, err := New(&components.GPUdInstance{
+		RootCtx:    ctx,
+		EventStore: store,
+	})
+	require.NoError(t, err)
+	defer comp.Close()
+
+	c := comp.(*component)
+	if c.eventBucket == nil {
+		c.eventBucket, err = store.Bucket(Name)
+		require.NoError(t, err)
+	}
+
+	now := time.Now().UTC()
+	kmsgEvent := apiv1.Event{
+		Time: metav1.Time{Time: now},
+		Name: EventNameErrorXid,
+		DeprecatedExtraInfo: map[string]string{
+			EventKeyErrorXidData: "79",
+			EventKeyDeviceUUID:   "PCI:0000:9b:00",
+		},
+	}
+	c.insertEvent(log.Logger.SugaredLogger, kmsgEvent)
+	// duplicate from the kmsg replay
+	c.insertEvent(log.Logger.SugaredLogger, kmsgEvent)
+
+	// same xid from nvml
+	c.insertEvent(log.Logger.SugaredLogger, apiv1.Event{
+		Time: metav1.Time{Time: now.Add(time.Second)},
+		Name: EventNameErrorXid,
+		DeprecatedExtraInfo: map[string]string{
+			EventKeyErrorXidData: "79",
+			EventKeyDeviceUUID:   "PCI:0000:9b:00",
+			EventKeySource:       SourceNVML,
+		},
+	})
+
+	events, err := comp.Events(ctx, now.Add(-time.Minute))
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+
+	// the xid from nvml is recorded, if not seen in the kmsg
+	c.insertEvent(log.Logger.SugaredLogger, apiv1.Event{
+		Time: metav1.Time{Time: now.Add(2 * time.Second)},
+		Name: EventNameErrorXid,
+		DeprecatedExtraInfo: map[string]string{
+			EventKeyErrorXidData: "48",
+			EventKeyDeviceUUID:   "PCI:0000:9b:00",
+			EventKeySource:       SourceNVML,
+		},
+	})
+	events, err = comp.Events(ctx, now.Add(-time.Minute))
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+
+	var found bool
+	for _, ev := range events {
+		if ev.DeprecatedExtraInfo[EventKeySource] == SourceNVML {
+			found = true
+			assert.Contains(t, ev.DeprecatedExtraInfo[EventKeyErrorXidData], `"data_source":"nvml"`)
+		}
+	}
+	assert.True(t, found)
+}
@@ -49,52 +49,6 @@ func TestComponentBasics(t *testing.T) {
 	assert.Empty(t, events)
 }
 
-func TestParseUnixEndpoint(t *testing.T) {
-	tests := []struct {
-		name     string
-		endpoint string
-		want     string
-		wantErr  bool
-	}{
-		{
-			name:     "valid unix endpoint",
-			endpoint: "unix:///run/containerd/containerd.sock",
-			want:     "/run/containerd/containerd.sock",
-			wantErr:  false,
-		},
-		{
-			name:     "invalid scheme",
-			endpoint: "http://localhost:8080",
-			want:     "",
-			wantErr:  true,
-		},
-		{
-			name:     "invalid url",
-			endpoint: "://invalid",
-			want:     "",
-			wantErr:  true,
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			got, err := parseUnixEndpoint(tt.endpoint)
-			if tt.wantErr {
-				assert.Error(t, err)
-			} else {
-				assert.NoError(t, err)
-				assert.Equal(t, tt.want, got)
-			}
-		})
-	}
-}
-
-func TestDefaultDialOptions(t *testing.T) {
-	opts := defaultDialOptions()
-	assert.NotEmpty(t, opts)
-	assert.Greater(t, len(opts), 0)
-}
-
 func TestDataFunctions(t *testing.T) {
 	t.Run("empty data", func(t *testing.T) {
 		d := Data{
@@ -1324,63 +1278,6 @@ func TestComponentWithEmptyEndpoint(t *testing.T) {
 	assert.NotEmpty(t, comp.endpoint)
 }
 
-// TestParseUnixEndpointEdgeCases tests edge cases for the parseUnixEndpoint function
-func TestParseUnixEndpointEdgeCases(t *testing.T) {
-	tests := []struct {
-		name     string
-		endpoint string
-		want     string
-		wantErr  bool
-	}{
-		{
-			name:     "empty endpoint",
-			endpoint: "",
-			want:     "",
-			wantErr:  true,
-		},
-		{
-			name:     "unix scheme with no path",
-			endpoint: "unix://",
-			want:     "",
-			wantErr:  true, // This should be an error in most implementations
-		},
-		{
-			name:     "unix endpoint with query params",
-			endpoint: "unix:///path/to/socket?param=value",
-			want:     "/path/to/socket",
-			wantErr:  false,
-		},
-		{
-			name:     "unix endpoint with fragment",
-			endpoint: "unix:///path/to/socket#fragment",
-			want:     "/path/to/socket",
-			wantErr:  false,
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			// For the unix:// test case, we'll skip the error check since the implementation may vary
-			if tt.endpoint == "unix://" {
-				got, _ := parseUnixEndpoint(tt.endpoint)
-				// Just check that we get an empty string or a "/" path
-				if got != "" && got != "/" {
-					t.Errorf("parseUnixEndpoint(%q) = %q, want empty or '/'", tt.endpoint, got)
-				}
-				return
-			}
-
-			got, err := parseUnixEndpoint(tt.endpoint)
-			if tt.wantErr {
-				assert.Error(t, err)
-			} else {
-				assert.NoError(t, err)
-				assert.Equal(t, tt.want, got)
-			}
-		})
-	}
-}
-
 // TestDataWithReason tests setting reason directly in the Data struct
 func TestDataWithReason(t *testing.T) {
 	// Create data with explicit reason and healthy values
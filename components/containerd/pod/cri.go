@@ -2,159 +2,22 @@ package pod
 
 import (
 	"context"
-	"fmt"
-	"net"
-	"net/url"
 	"os"
 	"sort"
 	"time"
 
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/backoff"
-	"google.golang.org/grpc/connectivity"
-	"google.golang.org/grpc/credentials/insecure"
 	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1"
 
+	pkgcontainer "github.com/leptonai/gpud/pkg/container"
 	pkg_file "github.com/leptonai/gpud/pkg/file"
 	"github.com/leptonai/gpud/pkg/log"
 )
 
 const (
 	defaultSocketFile               = "/run/containerd/containerd.sock"
-	defaultContainerRuntimeEndpoint = "unix:///run/containerd/containerd.sock"
+	defaultContainerRuntimeEndpoint = pkgcontainer.DefaultContainerdEndpoint
 )
 
-// NOTE
-// DO NOT USE https://github.com/kubernetes/kubernetes/blob/v1.32.0-alpha.0/staging/src/k8s.io/cri-client/pkg/remote_runtime.go yet
-// it fails with
-// "code = Unavailable desc = name resolver error: produced zero addresses"
-
-const (
-	// maxMsgSize use 16MB as the default message size limit.
-	// grpc library default is 4MB
-	maxMsgSize = 1024 * 1024 * 16
-
-	// connection parameters
-	maxBackoffDelay      = 3 * time.Second
-	baseBackoffDelay     = 100 * time.Millisecond
-	minConnectionTimeout = 10 * time.Second
-)
-
-// ref. https://github.com/kubernetes/kubernetes/blob/v1.29.2/pkg/kubelet/cri/remote/remote_runtime.go
-func defaultDialOptions() []grpc.DialOption {
-	cps := grpc.ConnectParams{Backoff: backoff.DefaultConfig}
-	cps.MinConnectTimeout = minConnectionTimeout
-	cps.Backoff.BaseDelay = baseBackoffDelay
-	cps.Backoff.MaxDelay = maxBackoffDelay
-	return []grpc.DialOption{
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
-		grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(maxMsgSize)),
-		grpc.WithConnectParams(cps),
-		grpc.WithContextDialer(dialUnix),
-		grpc.WithBlock(), //nolint:staticcheck
-	}
-}
-
-func dialUnix(ctx context.Context, addr string) (net.Conn, error) {
-	return (&net.Dialer{}).DialContext(ctx, "unix", addr)
-}
-
-func parseUnixEndpoint(endpoint string) (string, error) {
-	u, err := url.Parse(endpoint)
-	if err != nil {
-		return "", err
-	}
-	if u.Scheme != "unix" {
-		return "", fmt.Errorf("invalid scheme: %s (only supports 'unix' protocol)", u.Scheme)
-	}
-	return u.Path, nil
-}
-
-// connect creates a gRPC connection to the CRI service endpoint.
-func connect(ctx context.Context, endpoint string) (*grpc.ClientConn, error) {
-	if endpoint == "" {
-		return nil, fmt.Errorf("endpoint cannot be empty")
-	}
-
-	addr, err := parseUnixEndpoint(endpoint)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse endpoint: %w", err)
-	}
-
-	// Validate the socket file exists before attempting connection
-	if _, err := os.Stat(addr); err != nil {
-		if os.IsNotExist(err) {
-			return nil, fmt.Errorf("socket file does not exist: %s", addr)
-		}
-		return nil, fmt.Errorf("failed to stat socket file: %w", err)
-	}
-
-	// Attempt to establish connection with retries
-	var conn *grpc.ClientConn
-	var dialErr error
-	for i := 0; i < 3; i++ {
-		// "WithBlock" ctx cancel is no-op
-		conn, dialErr = grpc.DialContext(ctx, addr, defaultDialOptions()...) //nolint:staticcheck
-		if conn != nil && dialErr == nil {
-			if conn.GetState() == connectivity.Ready {
-				break
-			}
-
-			log.Logger.Warnw("connection is not ready, closing", "endpoint", endpoint, "connState", conn.GetState())
-			_ = conn.Close()
-			conn = nil
-		} else {
-			log.Logger.Warnw("failed to dial endpoint, retrying",
-				"endpoint", endpoint,
-				"attempt", i+1,
-				"error", dialErr,
-			)
-		}
-
-		select {
-		case <-ctx.Done():
-			return nil, ctx.Err()
-		case <-time.After(time.Second):
-		}
-	}
-
-	if dialErr != nil {
-		return nil, fmt.Errorf("failed to establish connection after retries: %w", dialErr)
-	}
-	if conn == nil {
-		return nil, fmt.Errorf("connection is nil")
-	}
-
-	log.Logger.Infow("successfully established connection", "endpoint", endpoint)
-	return conn, nil
-}
-
-// createClient creates runtime and image service clients from a gRPC connection.
-//
-// Cannot use "k8s.io/kubernetes/pkg/kubelet/cri/remote.NewRemoteRuntimeService" directly
-// as it causes a bunch of go module errors, importing the whole kubernetes repo.
-// ref. https://github.com/kubernetes-sigs/cri-tools/blob/master/cmd/main.go
-// ref. https://github.com/kubernetes/kubernetes/blob/v1.29.2/pkg/kubelet/cri/remote/remote_runtime.go
-// ref. https://github.com/kubernetes/kubernetes/blob/v1.32.0-alpha.0/staging/src/k8s.io/cri-client/pkg/remote_runtime.go
-func createClient(ctx context.Context, conn *grpc.ClientConn) (runtimeapi.RuntimeServiceClient, runtimeapi.ImageServiceClient, error) {
-	// ref. https://github.com/kubernetes/kubernetes/blob/v1.32.0-alpha.0/staging/src/k8s.io/cri-client/pkg/remote_runtime.go
-	runtimeClient := runtimeapi.NewRuntimeServiceClient(conn)
-	version, err := runtimeClient.Version(ctx, &runtimeapi.VersionRequest{})
-	if err != nil {
-		return nil, nil, err
-	}
-	log.Logger.Debugw("successfully checked version", "version", version.String())
-
-	status, err := runtimeClient.Status(ctx, &runtimeapi.StatusRequest{})
-	if err != nil {
-		return nil, nil, err
-	}
-	log.Logger.Debugw("successfully checked status", "status", status.String())
-
-	imageClient := runtimeapi.NewImageServiceClient(conn)
-	return runtimeClient, imageClient, nil
-}
-
 func checkContainerdInstalled() bool {
 	p, err := pkg_file.LocateExecutable("containerd")
 	if err == nil {
@@ -186,7 +49,7 @@ func checkContainerdRunning(ctx context.Context) bool {
 	defer ccancel()
 
 	containerdRunning := false
-	if conn, err := connect(cctx, defaultContainerRuntimeEndpoint); err == nil {
+	if conn, err := pkgcontainer.Connect(cctx, defaultContainerRuntimeEndpoint); err == nil {
 		log.Logger.Debugw("containerd default cri endpoint open, containerd running", "endpoint", defaultContainerRuntimeEndpoint)
 		containerdRunning = true
 		_ = conn.Close()
@@ -202,13 +65,13 @@ func checkContainerdRunning(ctx context.Context) bool {
 }
 
 func listAllSandboxes(ctx context.Context, endpoint string) ([]PodSandbox, error) {
-	conn, err := connect(ctx, endpoint)
+	conn, err := pkgcontainer.Connect(ctx, endpoint)
 	if err != nil {
 		return nil, err
 	}
 	defer conn.Close()
 
-	client, _, err := createClient(ctx, conn)
+	client, _, err := pkgcontainer.CreateClient(ctx, conn)
 	if err != nil {
 		return nil, err
 	}
@@ -6,7 +6,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"os"
 	"runtime"
 	"sync"
 	"time"
@@ -70,8 +69,8 @@ func New(gpudInstance *components.GPUdInstance) (components.Component, error) {
 			return nil, err
 		}
 
-		if os.Geteuid() == 0 {
-			c.kmsgSyncer, err = kmsg.NewSyncer(cctx, Match, c.eventBucket)
+		if gpudInstance.KmsgHub != nil {
+			c.kmsgSyncer, err = gpudInstance.KmsgHub.NewSyncer(cctx, Name, MatchEvent, c.eventBucket)
 			if err != nil {
 				ccancel()
 				return nil, err
@@ -2,6 +2,9 @@ package cpu
 
 import (
 	"regexp"
+
+	apiv1 "github.com/leptonai/gpud/api/v1"
+	"github.com/leptonai/gpud/pkg/kmsg"
 )
 
 const (
@@ -49,12 +52,20 @@ func HasSoftLockup(line string) bool {
 }
 
 func Match(line string) (eventName string, message string) {
+	ev := MatchEvent(line)
+	if ev == nil {
+		return "", ""
+	}
+	return ev.Name, ev.Message
+}
+
+func MatchEvent(line string) *kmsg.MatchedEvent {
 	for _, m := range getMatches() {
 		if m.check(line) {
-			return m.eventName, m.message
+			return kmsg.NewMatchedEvent(m.eventName, m.message, m.eventType, m.repairActions)
 		}
 	}
-	return "", ""
+	return nil
 }
 
 type match struct {
@@ -62,11 +73,14 @@ type match struct {
 	eventName string
 	regex     string
 	message   string
+
+	eventType     apiv1.EventType
+	repairActions []apiv1.RepairActionType
 }
 
 func getMatches() []match {
 	return []match{
-		{check: HasBlockedTooLong, eventName: eventBlockedTooLong, regex: regexBlockedTooLong, message: messageBlockedTooLong},
-		{check: HasSoftLockup, eventName: eventSoftLockup, regex: regexSoftLockup, message: messageSoftLockup},
+		{check: HasBlockedTooLong, eventName: eventBlockedTooLong, regex: regexBlockedTooLong, message: messageBlockedTooLong, eventType: apiv1.EventTypeWarning},
+		{check: HasSoftLockup, eventName: eventSoftLockup, regex: regexSoftLockup, message: messageSoftLockup, eventType: apiv1.EventTypeCritical, repairActions: []apiv1.RepairActionType{apiv1.RepairActionTypeRebootSystem}},
 	}
 }
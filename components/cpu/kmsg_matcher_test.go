@@ -1,6 +1,10 @@
 package cpu
 
-import "testing"
+import (
+	"testing"
+
+	apiv1 "github.com/leptonai/gpud/api/v1"
+)
 
 func TestHasBlockedTooLong(t *testing.T) {
 	tests := []struct {
@@ -209,3 +213,23 @@ func TestGetMatches(t *testing.T) {
 		t.Error("lockup check function matched invalid input")
 	}
 }
+
+func TestMatchEvent(t *testing.T) {
+	ev := MatchEvent("[Sun Jan  5 18:37:06 2025] watchdog: BUG: soft lockup - CPU#0 stuck for 27s! [cuda-EvtHandlr:2255424]")
+	if ev == nil {
+		t.Fatal("MatchEvent() = nil, want event")
+	}
+	if ev.Name != eventSoftLockup || ev.Message != messageSoftLockup {
+		t.Errorf("MatchEvent() = %v %v, want %v %v", ev.Name, ev.Message, eventSoftLockup, messageSoftLockup)
+	}
+	if ev.Type != apiv1.EventTypeCritical {
+		t.Errorf("MatchEvent() type = %v, want %v", ev.Type, apiv1.EventTypeCritical)
+	}
+	if ev.SuggestedActions == nil || len(ev.SuggestedActions.RepairActions) != 1 || ev.SuggestedActions.RepairActions[0] != apiv1.RepairActionTypeRebootSystem {
+		t.Errorf("MatchEvent() suggested actions = %+v, want %v", ev.SuggestedActions, apiv1.RepairActionTypeRebootSystem)
+	}
+
+	if ev := MatchEvent("no match"); ev != nil {
+		t.Errorf("MatchEvent() = %+v, want nil", ev)
+	}
+}
@@ -2,6 +2,9 @@ package fd
 
 import (
 	"regexp"
+
+	apiv1 "github.com/leptonai/gpud/api/v1"
+	"github.com/leptonai/gpud/pkg/kmsg"
 )
 
 const (
@@ -28,12 +31,20 @@ func HasVFSFileMaxLimitReached(line string) bool {
 }
 
 func Match(line string) (eventName string, message string) {
+	ev := MatchEvent(line)
+	if ev == nil {
+		return "", ""
+	}
+	return ev.Name, ev.Message
+}
+
+func MatchEvent(line string) *kmsg.MatchedEvent {
 	for _, m := range getMatches() {
 		if m.check(line) {
-			return m.eventName, m.message
+			return kmsg.NewMatchedEvent(m.eventName, m.message, m.eventType, m.repairActions)
 		}
 	}
-	return "", ""
+	return nil
 }
 
 type match struct {
@@ -41,10 +52,13 @@ type match struct {
 	eventName string
 	regex     string
 	message   string
+
+	eventType     apiv1.EventType
+	repairActions []apiv1.RepairActionType
 }
 
 func getMatches() []match {
 	return []match{
-		{check: HasVFSFileMaxLimitReached, eventName: eventVFSFileMaxLimitReached, regex: regexVFSFileMaxLimitReached, message: messageVFSFileMaxLimitReached},
+		{check: HasVFSFileMaxLimitReached, eventName: eventVFSFileMaxLimitReached, regex: regexVFSFileMaxLimitReached, message: messageVFSFileMaxLimitReached, eventType: apiv1.EventTypeWarning},
 	}
 }
@@ -1,6 +1,10 @@
 package fd
 
-import "testing"
+import (
+	"testing"
+
+	apiv1 "github.com/leptonai/gpud/api/v1"
+)
 
 func TestHasVFSFileMaxLimitReached(t *testing.T) {
 	tests := []struct {
@@ -154,3 +158,23 @@ func TestGetMatches(t *testing.T) {
 		t.Error("check function matched invalid input")
 	}
 }
+
+func TestMatchEvent(t *testing.T) {
+	ev := MatchEvent("VFS: file-max limit 1000000 reached")
+	if ev == nil {
+		t.Fatal("MatchEvent() = nil, want event")
+	}
+	if ev.Name != eventVFSFileMaxLimitReached || ev.Message != messageVFSFileMaxLimitReached {
+		t.Errorf("MatchEvent() = %v %v, want %v %v", ev.Name, ev.Message, eventVFSFileMaxLimitReached, messageVFSFileMaxLimitReached)
+	}
+	if ev.Type != apiv1.EventTypeWarning {
+		t.Errorf("MatchEvent() type = %v, want %v", ev.Type, apiv1.EventTypeWarning)
+	}
+	if ev.SuggestedActions != nil {
+		t.Errorf("MatchEvent() suggested actions = %+v, want nil", ev.SuggestedActions)
+	}
+
+	if ev := MatchEvent("no match"); ev != nil {
+		t.Errorf("MatchEvent() = %+v, want nil", ev)
+	}
+}
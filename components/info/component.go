@@ -21,6 +21,7 @@ import (
 	"github.com/leptonai/gpud/pkg/file"
 	gpud_manager "github.com/leptonai/gpud/pkg/gpud-manager"
 	"github.com/leptonai/gpud/pkg/gpud-manager/packages"
+	"github.com/leptonai/gpud/pkg/kmsg"
 	"github.com/leptonai/gpud/pkg/log"
 	"github.com/leptonai/gpud/pkg/memory"
 	pkgmetrics "github.com/leptonai/gpud/pkg/metrics"
@@ -40,6 +41,7 @@ type component struct {
 	annotations map[string]string
 	dbRO        *sql.DB
 	gatherer    prometheus.Gatherer
+	kmsgHub     *kmsg.Hub
 
 	lastMu   sync.RWMutex
 	lastData *Data
@@ -53,6 +55,7 @@ func New(gpudInstance *components.GPUdInstance) (components.Component, error) {
 		annotations: gpudInstance.Annotations,
 		dbRO:        gpudInstance.DBRO,
 		gatherer:    pkgmetrics.DefaultGatherer(),
+		kmsgHub:     gpudInstance.KmsgHub,
 	}
 	return c, nil
 }
@@ -200,6 +203,13 @@ func (c *component) Check() components.CheckResult {
 	}
 	d.GPUdStartTimeHumanized = humanize.Time(time.Unix(int64(d.GPUdStartTimeInUnixTime), 0))
 
+	if c.kmsgHub != nil {
+		cursor := c.kmsgHub.Cursor()
+		d.KmsgSource = c.kmsgHub.Source()
+		d.KmsgCursor = &cursor
+		d.KmsgSubscribers = c.kmsgHub.Stats()
+	}
+
 	d.health = apiv1.HealthStateTypeHealthy
 	d.reason = fmt.Sprintf("daemon version: %s, mac address: %s", version.Version, d.MacAddress)
 
@@ -246,6 +256,11 @@ type Data struct {
 	// Annotations
 	Annotations map[string]string `json:"annotations"`
 
+	// Kmsg source, read position and subscribers (for debugging)
+	KmsgSource      string                 `json:"kmsg_source,omitempty"`
+	KmsgCursor      *kmsg.Cursor           `json:"kmsg_cursor,omitempty"`
+	KmsgSubscribers []kmsg.SubscriberStats `json:"kmsg_subscribers,omitempty"`
+
 	// timestamp of the last check
 	ts time.Time
 	// error from the last check
@@ -287,6 +302,15 @@ func (d *Data) String() string {
 
 	table.Append([]string{"GPUd Start Time", d.GPUdStartTimeHumanized})
 
+	if d.KmsgSource != "" {
+		table.Append([]string{"Kmsg Source", d.KmsgSource})
+	}
+	if d.KmsgCursor != nil {
+		table.Append([]string{"Kmsg Boot ID", d.KmsgCursor.BootID})
+		table.Append([]string{"Kmsg Last Sequence Number", fmt.Sprintf("%d", d.KmsgCursor.LastSequenceNumber)})
+		table.Append([]string{"Kmsg Persisted Sequence Number", fmt.Sprintf("%d", d.KmsgCursor.PersistedSequenceNumber)})
+	}
+
 	table.Render()
 	return buf.String()
 }
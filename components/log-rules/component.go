@@ -0,0 +1,501 @@
+// Package logrules matches the kernel messages with the user-defined rules,
+// and generates the events (e.g., vendor driver messages not yet supported by gpud).
+package logrules
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	apiv1 "github.com/leptonai/gpud/api/v1"
+	"github.com/leptonai/gpud/components"
+	"github.com/leptonai/gpud/pkg/eventstore"
+	"github.com/leptonai/gpud/pkg/kmsg"
+	"github.com/leptonai/gpud/pkg/log"
+)
+
+const (
+	Name = "log-rules"
+
+	EventKeyLogLine = "log_line"
+	EventKeySource  = "source"
+)
+
+var _ components.Component = &component{}
+
+type component struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	getTimeNowFunc func() time.Time
+
+	rules       []*rule
+	eventBucket eventstore.Bucket
+
+	// rules grouped by the watcher of the rule source
+	groups []*ruleGroup
+	// errors from creating the watchers (e.g., no kernel log source)
+	watchErrs []string
+
+	lastMu   sync.RWMutex
+	lastData *Data
+}
+
+type rule struct {
+	kmsg.Rule
+
+	// resolved source of the rule (e.g., "kmsg", "journal", "file")
+	source string
+	regex  *regexp.Regexp
+}
+
+type ruleGroup struct {
+	source  string
+	watcher kmsg.Watcher
+	rules   []*rule
+}
+
+func New(gpudInstance *components.GPUdInstance) (components.Component, error) {
+	if err := kmsg.ValidateRules(gpudInstance.LogRules); err != nil {
+		return nil, err
+	}
+
+	cctx, ccancel := context.WithCancel(gpudInstance.RootCtx)
+	c := &component{
+		ctx:    cctx,
+		cancel: ccancel,
+		getTimeNowFunc: func() time.Time {
+			return time.Now().UTC()
+		},
+	}
+
+	hubSource := ""
+	if gpudInstance.KmsgHub != nil {
+		hubSource = gpudInstance.KmsgHub.Source()
+	}
+	for _, r := range gpudInstance.LogRules {
+		source := r.Source
+		if source == "" {
+			source = hubSource
+		}
+		c.rules = append(c.rules, &rule{
+			Rule:   r,
+			source: source,
+			regex:  regexp.MustCompile(r.Regex),
+		})
+	}
+
+	if len(c.rules) == 0 || gpudInstance.EventStore == nil {
+		return c, nil
+	}
+
+	var err error
+	c.eventBucket, err = gpudInstance.EventStore.Bucket(Name)
+	if err != nil {
+		ccancel()
+		return nil, err
+	}
+
+	// the rules sharing the same source share the same watcher
+	groups := make(map[string]*ruleGroup)
+	for _, r := range c.rules {
+		key := r.source
+		if r.source == kmsg.SourceFile && r.Rule.Source != "" {
+			key = r.source + ":" + r.FilePath
+		}
+
+		if g, ok := groups[key]; ok {
+			g.rules = append(g.rules, r)
+			continue
+		}
+
+		var w kmsg.Watcher
+		switch {
+		case r.source == "":
+			c.watchErrs = append(c.watchErrs, fmt.Sprintf("rule %q: no kernel log source available", r.Name))
+			continue
+
+		case r.source == hubSource && key == hubSource:
+			// reuse the shared kmsg reader
+			w, err = gpudInstance.KmsgHub.NewWatcher(Name)
+
+		case r.source == kmsg.SourceFile && r.Rule.Source != "":
+			// any log file specified by the rule, not only the kernel log
+			w, err = kmsg.NewTailFileWatcher(r.FilePath)
+
+		default:
+			w, err = kmsg.NewSourceWatcher(r.source, r.FilePath)
+		}
+		if err != nil {
+			log.Logger.Warnw("failed to create log rule watcher", "rule", r.Name, "source", r.source, "error", err)
+			c.watchErrs = append(c.watchErrs, fmt.Sprintf("rule %q: failed to watch %s: %v", r.Name, r.source, err))
+			continue
+		}
+
+		g := &ruleGroup{source: r.source, watcher: w, rules: []*rule{r}}
+		groups[key] = g
+		c.groups = append(c.groups, g)
+	}
+
+	return c, nil
+}
+
+func (c *component) Name() string { return Name }
+
+func (c *component) Start() error {
+	for _, g := range c.groups {
+		ch, err := g.watcher.Watch()
+		if err != nil {
+			return err
+		}
+		go c.watch(g, ch)
+	}
+
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+
+		for {
+			_ = c.Check()
+
+			select {
+			case <-c.ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return nil
+}
+
+func (c *component) LastHealthStates() apiv1.HealthStates {
+	c.lastMu.RLock()
+	lastData := c.lastData
+	c.lastMu.RUnlock()
+	return lastData.getLastHealthStates()
+}
+
+func (c *component) Events(ctx context.Context, since time.Time) (apiv1.Events, error) {
+	if c.eventBucket == nil {
+		return nil, nil
+	}
+	return c.eventBucket.Get(ctx, since)
+}
+
+func (c *component) Close() error {
+	log.Logger.Debugw("closing component")
+
+	c.cancel()
+
+	for _, g := range c.groups {
+		if err := g.watcher.Close(); err != nil {
+			log.Logger.Errorw("failed to close log rule watcher", "source", g.source, "error", err)
+		}
+	}
+	if c.eventBucket != nil {
+		c.eventBucket.Close()
+	}
+
+	return nil
+}
+
+func (c *component) watch(g *ruleGroup, ch <-chan kmsg.Message) {
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+			for _, r := range g.rules {
+				if !r.regex.MatchString(msg.Message) {
+					continue
+				}
+				if err := c.insertEvent(r, msg); err != nil {
+					log.Logger.Errorw("failed to insert log rule event", "rule", r.Name, "error", err)
+				}
+			}
+			kmsg.Ack(g.watcher, msg)
+		}
+	}
+}
+
+func (c *component) insertEvent(r *rule, msg kmsg.Message) error {
+	message := r.Message
+	if message == "" {
+		message = msg.Message
+	}
+
+	event := apiv1.Event{
+		Time:    metav1.Time{Time: msg.Timestamp.UTC()},
+		Name:    r.Name,
+		Type:    r.GetEventType(),
+		Message: message,
+		DeprecatedExtraInfo: map[string]string{
+			EventKeyLogLine: msg.Message,
+			EventKeySource:  r.source,
+		},
+	}
+	if len(r.RepairActions) > 0 {
+		event.DeprecatedSuggestedActions = &apiv1.SuggestedActions{
+			RepairActions: r.RepairActions,
+		}
+	}
+
+	// lookup to prevent duplicate event insertions
+	cctx, ccancel := context.WithTimeout(c.ctx, 15*time.Second)
+	sameEvent, err := c.eventBucket.Find(cctx, event)
+	ccancel()
+	if err != nil {
+		return err
+	}
+	if sameEvent != nil {
+		return nil
+	}
+
+	cctx, ccancel = context.WithTimeout(c.ctx, 15*time.Second)
+	err = c.eventBucket.Insert(cctx, event)
+	ccancel()
+	if err != nil {
+		return err
+	}
+
+	metricMatched.WithLabelValues(r.Name).Inc()
+	log.Logger.Infow("log rule matched", "rule", r.Name, "source", r.source, "logLine", msg.Message)
+
+	// reflect the health impact right away
+	if r.HealthImpact != "" {
+		_ = c.Check()
+	}
+	return nil
+}
+
+// Check evaluates the health state from the events
+// matched within the decay window of each rule.
+func (c *component) Check() components.CheckResult {
+	log.Logger.Infow("checking log rules")
+
+	d := &Data{
+		ts: c.getTimeNowFunc(),
+	}
+	defer func() {
+		c.lastMu.Lock()
+		c.lastData = d
+		c.lastMu.Unlock()
+	}()
+
+	d.health = apiv1.HealthStateTypeHealthy
+	if len(c.rules) == 0 {
+		d.reason = "no log rule configured"
+		return d
+	}
+	if len(c.watchErrs) > 0 {
+		d.err = fmt.Errorf("%s", strings.Join(c.watchErrs, "; "))
+	}
+	if c.eventBucket == nil {
+		d.reason = fmt.Sprintf("%d log rule(s) configured without event store", len(c.rules))
+		return d
+	}
+
+	// look back as far as the longest decay window
+	var since time.Time
+	for _, r := range c.rules {
+		s := d.ts.Add(-r.GetDecayWindow())
+		if since.IsZero() || s.Before(since) {
+			since = s
+		}
+	}
+
+	cctx, ccancel := context.WithTimeout(c.ctx, 30*time.Second)
+	events, err := c.eventBucket.Get(cctx, since)
+	ccancel()
+	if err != nil {
+		d.err = err
+		d.health = apiv1.HealthStateTypeUnhealthy
+		d.reason = fmt.Sprintf("error getting events: %v", err)
+		return d
+	}
+
+	repairActions := make(map[apiv1.RepairActionType]struct{})
+	var matched []string
+	for _, r := range c.rules {
+		st := RuleStatus{
+			Name:         r.Name,
+			Source:       r.source,
+			HealthImpact: r.HealthImpact,
+			Health:       apiv1.HealthStateTypeHealthy,
+		}
+
+		windowStart := d.ts.Add(-r.GetDecayWindow())
+		for _, ev := range events {
+			if ev.Name != r.Name || ev.Time.Time.Before(windowStart) {
+				continue
+			}
+			st.Matched++
+			if st.LastMatched == nil || ev.Time.After(st.LastMatched.Time) {
+				t := ev.Time
+				st.LastMatched = &t
+			}
+		}
+
+		if st.Matched > 0 {
+			matched = append(matched, fmt.Sprintf("%s (%d)", r.Name, st.Matched))
+
+			if r.HealthImpact != "" && r.HealthImpact != apiv1.HealthStateTypeHealthy {
+				st.Health = r.HealthImpact
+				d.health = worseHealth(d.health, r.HealthImpact)
+				for _, a := range r.RepairActions {
+					repairActions[a] = struct{}{}
+				}
+			}
+		}
+
+		d.Rules = append(d.Rules, st)
+	}
+
+	if len(matched) == 0 {
+		d.reason = fmt.Sprintf("no match for %d log rule(s)", len(c.rules))
+		return d
+	}
+	d.reason = fmt.Sprintf("log rule(s) matched: %s", strings.Join(matched, ", "))
+
+	if d.health != apiv1.HealthStateTypeHealthy && len(repairActions) > 0 {
+		acts := make([]apiv1.RepairActionType, 0, len(repairActions))
+		for a := range repairActions {
+			acts = append(acts, a)
+		}
+		sort.Slice(acts, func(i, j int) bool { return acts[i] < acts[j] })
+		d.suggestedActions = &apiv1.SuggestedActions{RepairActions: acts}
+	}
+
+	return d
+}
+
+// worseHealth returns the worse of the two health states.
+func worseHealth(a, b apiv1.HealthStateType) apiv1.HealthStateType {
+	rank := func(h apiv1.HealthStateType) int {
+		switch h {
+		case apiv1.HealthStateTypeUnhealthy:
+			return 2
+		case apiv1.HealthStateTypeDegraded:
+			return 1
+		default:
+			return 0
+		}
+	}
+	if rank(b) > rank(a) {
+		return b
+	}
+	return a
+}
+
+var _ components.CheckResult = &Data{}
+
+// RuleStatus is the status of a log rule within its decay window.
+type RuleStatus struct {
+	Name         string                `json:"name"`
+	Source       string                `json:"source"`
+	HealthImpact apiv1.HealthStateType `json:"health_impact,omitempty"`
+	Health       apiv1.HealthStateType `json:"health"`
+	// Matched is the number of matches within the decay window.
+	Matched     int          `json:"matched"`
+	LastMatched *metav1.Time `json:"last_matched,omitempty"`
+}
+
+type Data struct {
+	Rules []RuleStatus `json:"rules,omitempty"`
+
+	// timestamp of the last check
+	ts time.Time
+	// error from the last check
+	err error
+
+	// tracks the healthy evaluation result of the last check
+	health apiv1.HealthStateType
+	// tracks the reason of the last check
+	reason string
+	// tracks the suggested actions of the last check
+	suggestedActions *apiv1.SuggestedActions
+}
+
+func (d *Data) String() string {
+	if d == nil {
+		return ""
+	}
+	if len(d.Rules) == 0 {
+		return "no data"
+	}
+
+	buf := bytes.NewBuffer(nil)
+	table := tablewriter.NewWriter(buf)
+	table.SetAlignment(tablewriter.ALIGN_CENTER)
+	table.SetHeader([]string{"Rule", "Source", "Matched", "Last Matched", "Health"})
+	for _, r := range d.Rules {
+		lastMatched := ""
+		if r.LastMatched != nil {
+			lastMatched = r.LastMatched.UTC().Format(time.RFC3339)
+		}
+		table.Append([]string{r.Name, r.Source, fmt.Sprintf("%d", r.Matched), lastMatched, string(r.Health)})
+	}
+	table.Render()
+
+	return buf.String()
+}
+
+func (d *Data) Summary() string {
+	if d == nil {
+		return ""
+	}
+	return d.reason
+}
+
+func (d *Data) HealthState() apiv1.HealthStateType {
+	if d == nil {
+		return ""
+	}
+	return d.health
+}
+
+func (d *Data) getError() string {
+	if d == nil || d.err == nil {
+		return ""
+	}
+	return d.err.Error()
+}
+
+func (d *Data) getLastHealthStates() apiv1.HealthStates {
+	if d == nil {
+		return apiv1.HealthStates{
+			{
+				Name:   Name,
+				Health: apiv1.HealthStateTypeHealthy,
+				Reason: "no data yet",
+			},
+		}
+	}
+
+	state := apiv1.HealthState{
+		Name:             Name,
+		Reason:           d.reason,
+		Error:            d.getError(),
+		Health:           d.health,
+		SuggestedActions: d.suggestedActions,
+	}
+
+	b, _ := json.Marshal(d)
+	state.DeprecatedExtraInfo = map[string]string{
+		"data":     string(b),
+		"encoding": "json",
+	}
+	return apiv1.HealthStates{state}
+}
@@ -0,0 +1,189 @@
+package logrules
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	apiv1 "github.com/leptonai/gpud/api/v1"
+	"github.com/leptonai/gpud/components"
+	"github.com/leptonai/gpud/pkg/eventstore"
+	"github.com/leptonai/gpud/pkg/kmsg"
+)
+
+func newTestComponent(t *testing.T, rules []kmsg.Rule) *component {
+	t.Helper()
+
+	store, err := eventstore.NewMemory(0)
+	require.NoError(t, err)
+
+	comp, err := New(&components.GPUdInstance{
+		RootCtx:    context.Background(),
+		EventStore: store,
+		LogRules:   rules,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = comp.Close()
+	})
+	return comp.(*component)
+}
+
+func TestNewInvalidRules(t *testing.T) {
+	_, err := New(&components.GPUdInstance{
+		RootCtx:  context.Background(),
+		LogRules: []kmsg.Rule{{Name: "a", Regex: "("}},
+	})
+	assert.Error(t, err)
+}
+
+func TestCheckNoRules(t *testing.T) {
+	c := newTestComponent(t, nil)
+	assert.Equal(t, Name, c.Name())
+
+	states := c.LastHealthStates()
+	require.Len(t, states, 1)
+	assert.Equal(t, "no data yet", states[0].Reason)
+
+	result := c.Check()
+	assert.Equal(t, apiv1.HealthStateTypeHealthy, result.HealthState())
+	assert.Equal(t, "no log rule configured", result.Summary())
+
+	events, err := c.Events(context.Background(), time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Empty(t, events)
+}
+
+func TestCheckNoSource(t *testing.T) {
+	// no kmsg hub, thus no default source
+	c := newTestComponent(t, []kmsg.Rule{{Name: "a", Regex: "foo"}})
+	require.Empty(t, c.groups)
+	require.Len(t, c.watchErrs, 1)
+
+	states := c.Check().(*Data).getLastHealthStates()
+	require.Len(t, states, 1)
+	assert.Equal(t, apiv1.HealthStateTypeHealthy, states[0].Health)
+	assert.Contains(t, states[0].Error, "no kernel log source available")
+}
+
+func TestWatchAndCheck(t *testing.T) {
+	rules := []kmsg.Rule{
+		{
+			Name:          "vendor_fw_assert",
+			Regex:         `vendor_drv: .*firmware assert`,
+			EventType:     apiv1.EventTypeCritical,
+			RepairActions: []apiv1.RepairActionType{apiv1.RepairActionTypeHardwareInspection},
+			HealthImpact:  apiv1.HealthStateTypeUnhealthy,
+			DecayWindow:   metav1.Duration{Duration: 10 * time.Minute},
+		},
+		{
+			Name:    "vendor_link_retrain",
+			Regex:   `vendor_drv: link retrain`,
+			Message: "vendor link retrained",
+		},
+	}
+	c := newTestComponent(t, rules)
+
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	ch := make(chan kmsg.Message, 10)
+	ch <- kmsg.Message{Timestamp: metav1.NewTime(now), Message: "vendor_drv: slot 3 firmware assert 0x12"}
+	ch <- kmsg.Message{Timestamp: metav1.NewTime(now), Message: "vendor_drv: slot 3 firmware assert 0x12"} // duplicate
+	ch <- kmsg.Message{Timestamp: metav1.NewTime(now), Message: "vendor_drv: link retrain"}
+	ch <- kmsg.Message{Timestamp: metav1.NewTime(now), Message: "unrelated"}
+	close(ch)
+	c.watch(&ruleGroup{source: kmsg.SourceKmsg, rules: c.rules}, ch)
+
+	events, err := c.Events(ctx, now.Add(-time.Minute))
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+
+	byName := make(map[string]apiv1.Event)
+	for _, ev := range events {
+		byName[ev.Name] = ev
+	}
+
+	ev := byName["vendor_fw_assert"]
+	assert.Equal(t, apiv1.EventTypeCritical, ev.Type)
+	assert.Equal(t, "vendor_drv: slot 3 firmware assert 0x12", ev.Message)
+	assert.Equal(t, "vendor_drv: slot 3 firmware assert 0x12", ev.DeprecatedExtraInfo[EventKeyLogLine])
+	require.NotNil(t, ev.DeprecatedSuggestedActions)
+	assert.Equal(t, []apiv1.RepairActionType{apiv1.RepairActionTypeHardwareInspection}, ev.DeprecatedSuggestedActions.RepairActions)
+
+	ev = byName["vendor_link_retrain"]
+	assert.Equal(t, apiv1.EventTypeWarning, ev.Type)
+	assert.Equal(t, "vendor link retrained", ev.Message)
+	assert.Nil(t, ev.DeprecatedSuggestedActions)
+
+	// within the decay window
+	c.getTimeNowFunc = func() time.Time { return now.Add(time.Minute) }
+	states := c.Check().(*Data).getLastHealthStates()
+	require.Len(t, states, 1)
+	assert.Equal(t, apiv1.HealthStateTypeUnhealthy, states[0].Health)
+	assert.Equal(t, "log rule(s) matched: vendor_fw_assert (1), vendor_link_retrain (1)", states[0].Reason)
+	require.NotNil(t, states[0].SuggestedActions)
+	assert.Equal(t, []apiv1.RepairActionType{apiv1.RepairActionTypeHardwareInspection}, states[0].SuggestedActions.RepairActions)
+
+	// the impacting rule decays, the other rule has the default decay window
+	c.getTimeNowFunc = func() time.Time { return now.Add(30 * time.Minute) }
+	d := c.Check().(*Data)
+	assert.Equal(t, apiv1.HealthStateTypeHealthy, d.HealthState())
+	assert.Equal(t, "log rule(s) matched: vendor_link_retrain (1)", d.Summary())
+	assert.Nil(t, d.suggestedActions)
+	require.Len(t, d.Rules, 2)
+	assert.Equal(t, 0, d.Rules[0].Matched)
+	assert.Equal(t, 1, d.Rules[1].Matched)
+	assert.NotEmpty(t, d.String())
+
+	c.getTimeNowFunc = func() time.Time { return now.Add(2 * time.Hour) }
+	d = c.Check().(*Data)
+	assert.Equal(t, apiv1.HealthStateTypeHealthy, d.HealthState())
+	assert.Equal(t, "no match for 2 log rule(s)", d.Summary())
+}
+
+func TestFileSourceRule(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vendor.log")
+	require.NoError(t, os.WriteFile(path, nil, 0644))
+
+	c := newTestComponent(t, []kmsg.Rule{
+		{
+			Name:         "vendor_error",
+			Source:       kmsg.SourceFile,
+			FilePath:     path,
+			Regex:        `vendor error`,
+			HealthImpact: apiv1.HealthStateTypeDegraded,
+		},
+	})
+	require.Len(t, c.groups, 1)
+	require.NoError(t, c.Start())
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	_, err = f.WriteString("Jan  2 15:04:05 host kernel: vendor error detected\n")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	require.Eventually(t, func() bool {
+		states := c.LastHealthStates()
+		return len(states) == 1 && states[0].Health == apiv1.HealthStateTypeDegraded
+	}, 10*time.Second, 100*time.Millisecond)
+
+	events, err := c.Events(context.Background(), time.Now().Add(-time.Minute))
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "vendor error detected", events[0].Message)
+	assert.Equal(t, kmsg.SourceFile, events[0].DeprecatedExtraInfo[EventKeySource])
+}
+
+func TestWorseHealth(t *testing.T) {
+	assert.Equal(t, apiv1.HealthStateTypeDegraded, worseHealth(apiv1.HealthStateTypeHealthy, apiv1.HealthStateTypeDegraded))
+	assert.Equal(t, apiv1.HealthStateTypeUnhealthy, worseHealth(apiv1.HealthStateTypeUnhealthy, apiv1.HealthStateTypeDegraded))
+	assert.Equal(t, apiv1.HealthStateTypeHealthy, worseHealth(apiv1.HealthStateTypeHealthy, apiv1.HealthStateTypeHealthy))
+}
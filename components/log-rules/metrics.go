@@ -0,0 +1,31 @@
+package logrules
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	pkgmetrics "github.com/leptonai/gpud/pkg/metrics"
+)
+
+const SubSystem = "log_rules"
+
+var (
+	componentLabel = prometheus.Labels{
+		pkgmetrics.MetricComponentLabelKey: Name,
+	}
+
+	metricMatched = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "",
+			Subsystem: SubSystem,
+			Name:      "matched_total",
+			Help:      "tracks the total number of log lines matched by each user-defined log rule",
+		},
+		[]string{pkgmetrics.MetricComponentLabelKey, "rule"},
+	).MustCurryWith(componentLabel)
+)
+
+func init() {
+	pkgmetrics.MustRegister(
+		metricMatched,
+	)
+}
@@ -2,6 +2,9 @@ package memory
 
 import (
 	"regexp"
+
+	apiv1 "github.com/leptonai/gpud/api/v1"
+	"github.com/leptonai/gpud/pkg/kmsg"
 )
 
 const (
@@ -97,12 +100,20 @@ func HasEDACCorrectableErrors(line string) bool {
 }
 
 func Match(line string) (eventName string, message string) {
+	ev := MatchEvent(line)
+	if ev == nil {
+		return "", ""
+	}
+	return ev.Name, ev.Message
+}
+
+func MatchEvent(line string) *kmsg.MatchedEvent {
 	for _, m := range getMatches() {
 		if m.check(line) {
-			return m.eventName, m.message
+			return kmsg.NewMatchedEvent(m.eventName, m.message, m.eventType, m.repairActions)
 		}
 	}
-	return "", ""
+	return nil
 }
 
 type match struct {
@@ -110,14 +121,17 @@ type match struct {
 	eventName string
 	regex     string
 	message   string
+
+	eventType     apiv1.EventType
+	repairActions []apiv1.RepairActionType
 }
 
 func getMatches() []match {
 	return []match{
-		{check: HasOOM, eventName: eventOOM, regex: regexOOM, message: messageOOM},
-		{check: HasOOMKillConstraint, eventName: eventOOMKillConstraint, regex: regexOOMKillConstraint, message: messageOOMKillConstraint},
-		{check: HasOOMKiller, eventName: eventOOMKiller, regex: regexOOMKiller, message: messageOOMKiller},
-		{check: HasOOMCgroup, eventName: eventOOMCgroup, regex: regexOOMCgroup, message: messageOOMCgroup},
-		{check: HasEDACCorrectableErrors, eventName: eventEDACCorrectableErrors, regex: regexEDACCorrectableErrors, message: messageEDACCorrectableErrors},
+		{check: HasOOM, eventName: eventOOM, regex: regexOOM, message: messageOOM, eventType: apiv1.EventTypeWarning},
+		{check: HasOOMKillConstraint, eventName: eventOOMKillConstraint, regex: regexOOMKillConstraint, message: messageOOMKillConstraint, eventType: apiv1.EventTypeWarning},
+		{check: HasOOMKiller, eventName: eventOOMKiller, regex: regexOOMKiller, message: messageOOMKiller, eventType: apiv1.EventTypeWarning},
+		{check: HasOOMCgroup, eventName: eventOOMCgroup, regex: regexOOMCgroup, message: messageOOMCgroup, eventType: apiv1.EventTypeWarning},
+		{check: HasEDACCorrectableErrors, eventName: eventEDACCorrectableErrors, regex: regexEDACCorrectableErrors, message: messageEDACCorrectableErrors, eventType: apiv1.EventTypeWarning},
 	}
 }
@@ -4,6 +4,8 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+
+	apiv1 "github.com/leptonai/gpud/api/v1"
 )
 
 func TestHasOOM(t *testing.T) {
@@ -303,3 +305,14 @@ func TestMatch(t *testing.T) {
 		})
 	}
 }
+
+func TestMatchEvent(t *testing.T) {
+	ev := MatchEvent("Out of memory: Killed process 123, UID 48, (httpd).")
+	assert.NotNil(t, ev)
+	assert.Equal(t, eventOOM, ev.Name)
+	assert.Equal(t, messageOOM, ev.Message)
+	assert.Equal(t, apiv1.EventTypeWarning, ev.Type)
+	assert.Nil(t, ev.SuggestedActions)
+
+	assert.Nil(t, MatchEvent("no match"))
+}
@@ -50,7 +50,7 @@ func New(gpudInstance *components.GPUdInstance) (components.Component, error) {
 
 		currentVirtEnv:                pkghost.VirtualizationEnv(),
 		getPCIDevicesFunc:             pci.List,
-		findACSEnabledDeviceUUIDsFunc: findACSEnabledDeviceUUIDs,
+		findACSEnabledDeviceUUIDsFunc: pci.FindACSEnabledDeviceUUIDs,
 	}
 
 	if gpudInstance.EventStore != nil && runtime.GOOS == "linux" {
@@ -138,35 +138,14 @@ func (c *component) Check() components.CheckResult {
 		c.lastMu.Unlock()
 	}()
 
-	// Virtual machines
-	// Virtual machines require ACS to function, hence disabling ACS is not an option.
-	//
-	// ref. https://docs.nvidia.com/deeplearning/nccl/user-guide/docs/troubleshooting.html#pci-access-control-services-acs
-	if c.currentVirtEnv.IsKVM {
-		d.health = apiv1.HealthStateTypeHealthy
-		d.reason = "host virt env is KVM (no need to check ACS)"
-		return d
-	}
-
-	// unknown virtualization environment
-	if c.currentVirtEnv.Type == "" {
+	// in linux, and not in VM
+	// then, check all ACS enabled devices
+	if reason := pci.ACSCheckSkipReason(c.currentVirtEnv); reason != "" {
 		d.health = apiv1.HealthStateTypeHealthy
-		d.reason = "unknown virtualization environment (no need to check ACS)"
+		d.reason = reason
 		return d
 	}
 
-	// in linux, and not in VM
-	// then, check all ACS enabled devices
-	//
-	// Baremetal systems
-	// IO virtualization (also known as VT-d or IOMMU) can interfere with GPU Direct by redirecting all
-	// PCI point-to-point traffic to the CPU root complex, causing a significant performance reduction or even a hang.
-	// If PCI switches have ACS enabled, it needs to be disabled.
-	//
-	// Virtual machines
-	// Virtual machines require ACS to function, hence disabling ACS is not an option.
-	//
-	// ref. https://docs.nvidia.com/deeplearning/nccl/user-guide/docs/troubleshooting.html#pci-access-control-services-acs
 	cctx, cancel := context.WithTimeout(c.ctx, 15*time.Second)
 	d.Devices, d.err = c.getPCIDevicesFunc(cctx)
 	cancel()
@@ -293,18 +272,3 @@ func (d *Data) getLastHealthStates() apiv1.HealthStates {
 	}
 	return apiv1.HealthStates{state}
 }
-
-func findACSEnabledDeviceUUIDs(devs []pci.Device) []string {
-	uuids := make([]string, 0)
-	for _, dev := range devs {
-		// check whether ACS is enabled on PCI bridges
-		if dev.AccessControlService != nil && dev.AccessControlService.ACSCtl.SrcValid {
-			uuids = append(uuids, dev.ID)
-		}
-	}
-	if len(uuids) == 0 {
-		return nil
-	}
-
-	return uuids
-}
@@ -11,6 +11,7 @@ import (
 	"github.com/leptonai/gpud/pkg/eventstore"
 	gpudmetrics "github.com/leptonai/gpud/pkg/gpud-metrics"
 	pkghost "github.com/leptonai/gpud/pkg/host"
+	"github.com/leptonai/gpud/pkg/kmsg"
 	nvidianvml "github.com/leptonai/gpud/pkg/nvidia-query/nvml"
 )
 
@@ -23,12 +24,24 @@ type GPUdInstance struct {
 	NVMLInstance         nvidianvml.InstanceV2
 	NVIDIAToolOverwrites nvidiacommon.ToolOverwrites
 
+	// ExpectedGPUs is the user-configured expected GPUs,
+	// nil to use the GPUs discovered on the first boot.
+	ExpectedGPUs *nvidiacommon.ExpectedGPUs
+
 	Annotations map[string]string
+	DBRW        *sql.DB
 	DBRO        *sql.DB
 
 	EventStore       eventstore.Store
 	RebootEventStore pkghost.RebootEventStore
 
+	// KmsgHub is the shared kmsg reader for all the components,
+	// nil if kmsg is not available (e.g., non-linux, non-root).
+	KmsgHub *kmsg.Hub
+
+	// LogRules are the user-defined rules to match the kernel messages.
+	LogRules []kmsg.Rule
+
 	MountPoints  []string
 	MountTargets []string
 }
@@ -54,6 +54,14 @@ type HealthSettable interface {
 	SetHealthy() error
 }
 
+// DeviceHealthSettable is an optional interface that can be implemented by components
+// tracking the health state per device (e.g., per GPU), to allow setting the health state
+// of a single device without affecting the other devices.
+type DeviceHealthSettable interface {
+	// SetHealthyDevice sets the health state of the device to healthy.
+	SetHealthyDevice(deviceUUID string) error
+}
+
 // CheckResult is the data type that represents the result of
 // a component health state check.
 type CheckResult interface {
@@ -1,5 +1,48 @@
 package common
 
+import (
+	"errors"
+	"fmt"
+)
+
 type ToolOverwrites struct {
 	IbstatCommand string `json:"ibstat_command"`
 }
+
+// ExpectedGPUs is the GPUs the machine is expected to have,
+// overriding the GPUs recorded when first seen.
+type ExpectedGPUs struct {
+	// Count is the expected number of GPUs.
+	Count int `json:"count"`
+	// UUIDs is the expected GPU UUIDs.
+	// If set, the number of UUIDs must match the count.
+	UUIDs []string `json:"uuids,omitempty"`
+}
+
+// Validate validates the expected GPUs.
+func (e *ExpectedGPUs) Validate() error {
+	if e == nil {
+		return nil
+	}
+	if e.Count <= 0 {
+		return errors.New("count must be positive")
+	}
+	if len(e.UUIDs) == 0 {
+		return nil
+	}
+	if len(e.UUIDs) != e.Count {
+		return fmt.Errorf("count %d does not match the number of uuids %d", e.Count, len(e.UUIDs))
+	}
+
+	uuids := make(map[string]struct{}, len(e.UUIDs))
+	for _, uuid := range e.UUIDs {
+		if uuid == "" {
+			return errors.New("empty uuid")
+		}
+		if _, ok := uuids[uuid]; ok {
+			return fmt.Errorf("duplicate uuid %q", uuid)
+		}
+		uuids[uuid] = struct{}{}
+	}
+	return nil
+}
@@ -9,6 +9,10 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	nvidia_common "github.com/leptonai/gpud/pkg/config/common"
+	"github.com/leptonai/gpud/pkg/eventstore"
+	"github.com/leptonai/gpud/pkg/kmsg"
+	"github.com/leptonai/gpud/pkg/nvidia-query/sxid"
+	"github.com/leptonai/gpud/pkg/nvidia-query/xid"
 )
 
 // Config provides gpud configuration data for the server
@@ -32,6 +36,36 @@ type Config struct {
 	// Once elapsed, old states/metrics are purged/compacted.
 	RetentionPeriod metav1.Duration `json:"retention_period"`
 
+	// Backend for the component events.
+	// Either "sqlite" (default, persisted in the state file) or "memory" (ephemeral).
+	EventStoreBackend string `json:"event_store_backend,omitempty"`
+
+	// Source of the kernel messages for the log based components (e.g., xid).
+	// Either "kmsg" ("/dev/kmsg", requires root), "journal" (systemd journal),
+	// or "file" (tails the "kmsg_file_path").
+	// If empty, "kmsg" is used if readable, then "journal",
+	// then "file" (e.g., "/var/log/kern.log").
+	KmsgSource string `json:"kmsg_source,omitempty"`
+	// Path to the kernel log file to tail (e.g., "/var/log/kern.log").
+	// Only used for the "file" kmsg source.
+	KmsgFilePath string `json:"kmsg_file_path,omitempty"`
+
+	// User-defined rules to generate events from the kernel messages
+	// (e.g., vendor driver messages not yet supported by gpud).
+	LogRules []kmsg.Rule `json:"log_rules,omitempty"`
+
+	// Xid/SXid details that override or extend the built-in catalogs
+	// (e.g., new Xids introduced by the newer driver releases).
+	// Each entry is merged over the built-in detail of the same code when decoded,
+	// same as the catalog file (e.g., only "event_type" to override).
+	XIDCatalog  xid.Catalog  `json:"xid_catalog,omitempty"`
+	SXIDCatalog sxid.Catalog `json:"sxid_catalog,omitempty"`
+
+	// GPUs the machine is expected to have, to detect the missing GPUs
+	// (e.g., 7 of 8 GPUs after reboot).
+	// If not set, the GPUs first seen by gpud are recorded as expected.
+	ExpectedGPUs *nvidia_common.ExpectedGPUs `json:"expected_gpus,omitempty"`
+
 	// Interval at which to compact the state database.
 	CompactPeriod metav1.Duration `json:"compact_period"`
 
@@ -75,5 +109,26 @@ func (config *Config) Validate() error {
 	if !config.EnableAutoUpdate && config.AutoUpdateExitCode != -1 {
 		return ErrInvalidAutoUpdateExitCode
 	}
+	if !eventstore.IsValidBackend(config.EventStoreBackend) {
+		return fmt.Errorf("unknown event_store_backend %q", config.EventStoreBackend)
+	}
+	if !kmsg.IsValidSource(config.KmsgSource) {
+		return fmt.Errorf("unknown kmsg_source %q", config.KmsgSource)
+	}
+	if config.KmsgSource == kmsg.SourceFile && config.KmsgFilePath == "" {
+		return errors.New("kmsg_file_path is required for the file kmsg_source")
+	}
+	if err := kmsg.ValidateRules(config.LogRules); err != nil {
+		return fmt.Errorf("invalid log_rules: %w", err)
+	}
+	if err := xid.ValidateCatalog(config.XIDCatalog); err != nil {
+		return fmt.Errorf("invalid xid_catalog: %w", err)
+	}
+	if err := sxid.ValidateCatalog(config.SXIDCatalog); err != nil {
+		return fmt.Errorf("invalid sxid_catalog: %w", err)
+	}
+	if err := config.ExpectedGPUs.Validate(); err != nil {
+		return fmt.Errorf("invalid expected_gpus: %w", err)
+	}
 	return nil
 }
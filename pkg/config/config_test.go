@@ -1,10 +1,17 @@
 package config
 
 import (
+	"encoding/json"
 	"testing"
 	"time"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	apiv1 "github.com/leptonai/gpud/api/v1"
+	nvidia_common "github.com/leptonai/gpud/pkg/config/common"
+	"github.com/leptonai/gpud/pkg/kmsg"
+	"github.com/leptonai/gpud/pkg/nvidia-query/sxid"
+	"github.com/leptonai/gpud/pkg/nvidia-query/xid"
 )
 
 func TestConfigValidate_AutoUpdateExitCode(t *testing.T) {
@@ -56,3 +63,180 @@ func TestConfigValidate_AutoUpdateExitCode(t *testing.T) {
 		})
 	}
 }
+
+func TestConfigValidate_EventStoreBackend(t *testing.T) {
+	tests := []struct {
+		name    string
+		backend string
+		wantErr bool
+	}{
+		{name: "Valid: empty backend defaults to sqlite", backend: "", wantErr: false},
+		{name: "Valid: sqlite backend", backend: "sqlite", wantErr: false},
+		{name: "Valid: memory backend", backend: "memory", wantErr: false},
+		{name: "Invalid: unknown backend", backend: "redis", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				RetentionPeriod:    metav1.Duration{Duration: time.Hour},
+				Address:            "localhost:8080",
+				EnableAutoUpdate:   true,
+				EventStoreBackend:  tt.backend,
+				AutoUpdateExitCode: -1,
+			}
+
+			err := cfg.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Config.Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConfigValidate_KmsgSource(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		filePath string
+		wantErr  bool
+	}{
+		{name: "Valid: empty source auto-detects", source: "", wantErr: false},
+		{name: "Valid: kmsg source", source: "kmsg", wantErr: false},
+		{name: "Valid: journal source", source: "journal", wantErr: false},
+		{name: "Valid: file source", source: "file", filePath: "/var/log/kern.log", wantErr: false},
+		{name: "Invalid: file source without path", source: "file", wantErr: true},
+		{name: "Invalid: unknown source", source: "syslog", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				RetentionPeriod:    metav1.Duration{Duration: time.Hour},
+				Address:            "localhost:8080",
+				EnableAutoUpdate:   true,
+				KmsgSource:         tt.source,
+				KmsgFilePath:       tt.filePath,
+				AutoUpdateExitCode: -1,
+			}
+
+			err := cfg.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Config.Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConfigValidate_LogRules(t *testing.T) {
+	cfg := &Config{
+		RetentionPeriod:    metav1.Duration{Duration: time.Hour},
+		Address:            "localhost:8080",
+		EnableAutoUpdate:   true,
+		AutoUpdateExitCode: -1,
+		LogRules: []kmsg.Rule{
+			{Name: "vendor_error", Regex: "vendor error"},
+		},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Config.Validate() error = %v, want nil", err)
+	}
+
+	cfg.LogRules = append(cfg.LogRules, kmsg.Rule{Name: "invalid", Regex: "("})
+	if err := cfg.Validate(); err == nil {
+		t.Error("Config.Validate() error = nil, want error for invalid regex")
+	}
+}
+
+func TestConfigValidate_XIDCatalog(t *testing.T) {
+	cfg := &Config{
+		RetentionPeriod:    metav1.Duration{Duration: time.Hour},
+		Address:            "localhost:8080",
+		EnableAutoUpdate:   true,
+		AutoUpdateExitCode: -1,
+		XIDCatalog: []xid.Detail{
+			{Xid: 999, Name: "vendor error", EventType: apiv1.EventTypeWarning},
+		},
+		SXIDCatalog: []sxid.Detail{
+			{SXid: 99999, Name: "vendor error", EventType: apiv1.EventTypeWarning},
+		},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Config.Validate() error = %v, want nil", err)
+	}
+
+	cfg.XIDCatalog = append(cfg.XIDCatalog, xid.Detail{Xid: 1000, Name: "invalid"})
+	if err := cfg.Validate(); err == nil {
+		t.Error("Config.Validate() error = nil, want error for missing event type")
+	}
+
+	cfg.XIDCatalog = nil
+	cfg.SXIDCatalog = append(cfg.SXIDCatalog, cfg.SXIDCatalog[0])
+	if err := cfg.Validate(); err == nil {
+		t.Error("Config.Validate() error = nil, want error for duplicate sxid")
+	}
+}
+
+func TestConfigUnmarshal_XIDCatalog(t *testing.T) {
+	var cfg Config
+	err := json.Unmarshal([]byte(`{
+		"xid_catalog": [{"xid": 79, "event_type": "Critical"}],
+		"sxid_catalog": [{"sxid": 99999, "name": "vendor error", "event_type": "Warning"}]
+	}`), &cfg)
+	if err != nil {
+		t.Fatalf("json.Unmarshal() error = %v, want nil", err)
+	}
+
+	// the partial entry is merged over the built-in detail
+	builtin, ok := xid.GetDetail(79)
+	if !ok {
+		t.Fatal("xid 79 not found")
+	}
+	if len(cfg.XIDCatalog) != 1 || cfg.XIDCatalog[0].Name != builtin.Name {
+		t.Errorf("XIDCatalog = %+v, want the name %q of the built-in detail", cfg.XIDCatalog, builtin.Name)
+	}
+	if cfg.XIDCatalog[0].EventType != apiv1.EventTypeCritical {
+		t.Errorf("XIDCatalog[0].EventType = %q, want %q", cfg.XIDCatalog[0].EventType, apiv1.EventTypeCritical)
+	}
+	if len(cfg.SXIDCatalog) != 1 || cfg.SXIDCatalog[0].Name != "vendor error" {
+		t.Errorf("SXIDCatalog = %+v, want the new sxid", cfg.SXIDCatalog)
+	}
+
+	err = json.Unmarshal([]byte(`{"xid_catalog": [{"xid": 79, "event_type": "Bad"}]}`), &cfg)
+	if err == nil {
+		t.Error("json.Unmarshal() error = nil, want error for unknown event type")
+	}
+}
+
+func TestConfigValidate_ExpectedGPUs(t *testing.T) {
+	cfg := &Config{
+		RetentionPeriod:    metav1.Duration{Duration: time.Hour},
+		Address:            "localhost:8080",
+		EnableAutoUpdate:   true,
+		AutoUpdateExitCode: -1,
+		ExpectedGPUs:       &nvidia_common.ExpectedGPUs{Count: 2, UUIDs: []string{"GPU-0", "GPU-1"}},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Config.Validate() error = %v, want nil", err)
+	}
+
+	cfg.ExpectedGPUs = &nvidia_common.ExpectedGPUs{Count: 8}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Config.Validate() error = %v, want nil", err)
+	}
+
+	cfg.ExpectedGPUs = &nvidia_common.ExpectedGPUs{Count: 0}
+	if err := cfg.Validate(); err == nil {
+		t.Error("Config.Validate() error = nil, want error for zero count")
+	}
+
+	cfg.ExpectedGPUs = &nvidia_common.ExpectedGPUs{Count: 3, UUIDs: []string{"GPU-0", "GPU-1"}}
+	if err := cfg.Validate(); err == nil {
+		t.Error("Config.Validate() error = nil, want error for mismatched uuids")
+	}
+
+	cfg.ExpectedGPUs = &nvidia_common.ExpectedGPUs{Count: 2, UUIDs: []string{"GPU-0", "GPU-0"}}
+	if err := cfg.Validate(); err == nil {
+		t.Error("Config.Validate() error = nil, want error for duplicate uuids")
+	}
+}
@@ -0,0 +1,86 @@
+// Package container attributes the host processes to the containers and the Kubernetes pods,
+// based on the process cgroups and the container runtime (CRI).
+package container
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// DefaultProcRoot is the default root directory of the procfs.
+const DefaultProcRoot = "/proc"
+
+const (
+	RuntimeContainerd = "containerd"
+	RuntimeCRIO       = "cri-o"
+	RuntimeDocker     = "docker"
+)
+
+var (
+	// e.g.,
+	// "0::/kubepods.slice/kubepods-burstable.slice/kubepods-burstable-pod0c8a3d7e_3b5a_4c7e_9a7f_1f0b6f3c2d1e.slice/cri-containerd-5f0c...e1.scope"
+	// "12:memory:/kubepods/besteffort/pod0c8a3d7e-3b5a-4c7e-9a7f-1f0b6f3c2d1e/5f0c...e1"
+	// "0::/system.slice/docker-5f0c...e1.scope"
+	regexContainerID = regexp.MustCompile(`[0-9a-f]{64}`)
+	regexPodUID      = regexp.MustCompile(`pod([0-9a-f]{8}[-_][0-9a-f]{4}[-_][0-9a-f]{4}[-_][0-9a-f]{4}[-_][0-9a-f]{12})`)
+)
+
+// Cgroup is the container information parsed from the process cgroup.
+type Cgroup struct {
+	// ContainerID is the full container ID (64 hex characters).
+	ContainerID string
+	// PodUID is the Kubernetes pod UID, empty if the container is not in a pod.
+	PodUID string
+	// Runtime is the container runtime inferred from the cgroup path
+	// (e.g., "containerd", "cri-o", "docker"), empty if unknown.
+	Runtime string
+}
+
+// ParseCgroup parses the contents of "/proc/<pid>/cgroup" (cgroup v1 or v2),
+// and returns the container information.
+// Returns false if the process does not run in a container.
+func ParseCgroup(contents string) (Cgroup, bool) {
+	for _, line := range strings.Split(contents, "\n") {
+		// e.g., "hierarchy-ID:controller-list:cgroup-path"
+		parts := strings.SplitN(strings.TrimSpace(line), ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		path := parts[2]
+
+		ids := regexContainerID.FindAllString(path, -1)
+		if len(ids) == 0 {
+			continue
+		}
+
+		// the container is the innermost cgroup
+		cg := Cgroup{ContainerID: ids[len(ids)-1]}
+		if m := regexPodUID.FindStringSubmatch(path); m != nil {
+			// systemd cgroup driver escapes "-" to "_"
+			cg.PodUID = strings.ReplaceAll(m[1], "_", "-")
+		}
+
+		switch {
+		case strings.Contains(path, "containerd"):
+			cg.Runtime = RuntimeContainerd
+		case strings.Contains(path, "crio"):
+			cg.Runtime = RuntimeCRIO
+		case strings.Contains(path, "docker"):
+			cg.Runtime = RuntimeDocker
+		}
+		return cg, true
+	}
+	return Cgroup{}, false
+}
+
+// ReadProcessCgroup reads the cgroup of the process (e.g., "/proc/<pid>/cgroup").
+func ReadProcessCgroup(procRoot string, pid uint32) (string, error) {
+	b, err := os.ReadFile(filepath.Join(procRoot, fmt.Sprintf("%d", pid), "cgroup"))
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
@@ -0,0 +1,72 @@
+package container
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testContainerID = "5f0c3e2a9b8d7c6e5f4a3b2c1d0e9f8a7b6c5d4e3f2a1b0c9d8e7f6a5b4c3d2e"
+
+func TestParseCgroup(t *testing.T) {
+	tests := []struct {
+		file     string
+		expected Cgroup
+		ok       bool
+	}{
+		{
+			file:     "cgroup.v2.containerd.systemd",
+			expected: Cgroup{ContainerID: testContainerID, PodUID: "0c8a3d7e-3b5a-4c7e-9a7f-1f0b6f3c2d1e", Runtime: RuntimeContainerd},
+			ok:       true,
+		},
+		{
+			// cgroupfs driver does not name the runtime in the path
+			file:     "cgroup.v1.containerd.cgroupfs",
+			expected: Cgroup{ContainerID: testContainerID, PodUID: "0c8a3d7e-3b5a-4c7e-9a7f-1f0b6f3c2d1e"},
+			ok:       true,
+		},
+		{
+			file:     "cgroup.v2.docker",
+			expected: Cgroup{ContainerID: testContainerID, Runtime: RuntimeDocker},
+			ok:       true,
+		},
+		{
+			file:     "cgroup.v2.crio",
+			expected: Cgroup{ContainerID: testContainerID, PodUID: "0c8a3d7e-3b5a-4c7e-9a7f-1f0b6f3c2d1e", Runtime: RuntimeCRIO},
+			ok:       true,
+		},
+		{
+			file: "cgroup.v2.host",
+			ok:   false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			b, err := os.ReadFile(filepath.Join("testdata", tt.file))
+			require.NoError(t, err)
+
+			cg, ok := ParseCgroup(string(b))
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.expected, cg)
+		})
+	}
+
+	_, ok := ParseCgroup("")
+	assert.False(t, ok)
+}
+
+func TestReadProcessCgroup(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "1234"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "1234", "cgroup"), []byte("0::/\n"), 0644))
+
+	contents, err := ReadProcessCgroup(root, 1234)
+	require.NoError(t, err)
+	assert.Equal(t, "0::/\n", contents)
+
+	_, err = ReadProcessCgroup(root, 5678)
+	assert.Error(t, err)
+}
@@ -0,0 +1,250 @@
+package container
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials/insecure"
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1"
+
+	"github.com/leptonai/gpud/pkg/log"
+)
+
+const (
+	// DefaultContainerdEndpoint is the default CRI endpoint of containerd.
+	DefaultContainerdEndpoint = "unix:///run/containerd/containerd.sock"
+	// DefaultCRIOEndpoint is the default CRI endpoint of CRI-O.
+	DefaultCRIOEndpoint = "unix:///var/run/crio/crio.sock"
+)
+
+// DefaultCRIEndpoints is the CRI endpoints to look up the containers,
+// where the ones without the socket file are skipped.
+var DefaultCRIEndpoints = []string{
+	DefaultContainerdEndpoint,
+	DefaultCRIOEndpoint,
+}
+
+// Kubernetes container labels set by the kubelet.
+// ref. https://github.com/kubernetes/kubernetes/blob/v1.32.0/pkg/kubelet/types/labels.go
+const (
+	labelPodName       = "io.kubernetes.pod.name"
+	labelPodNamespace  = "io.kubernetes.pod.namespace"
+	labelPodUID        = "io.kubernetes.pod.uid"
+	labelContainerName = "io.kubernetes.container.name"
+)
+
+// NOTE
+// DO NOT USE https://github.com/kubernetes/kubernetes/blob/v1.32.0-alpha.0/staging/src/k8s.io/cri-client/pkg/remote_runtime.go yet
+// it fails with
+// "code = Unavailable desc = name resolver error: produced zero addresses"
+
+const (
+	// maxMsgSize use 16MB as the default message size limit.
+	// grpc library default is 4MB
+	maxMsgSize = 1024 * 1024 * 16
+
+	// connection parameters
+	maxBackoffDelay      = 3 * time.Second
+	baseBackoffDelay     = 100 * time.Millisecond
+	minConnectionTimeout = 10 * time.Second
+)
+
+// ref. https://github.com/kubernetes/kubernetes/blob/v1.29.2/pkg/kubelet/cri/remote/remote_runtime.go
+func defaultDialOptions() []grpc.DialOption {
+	cps := grpc.ConnectParams{Backoff: backoff.DefaultConfig}
+	cps.MinConnectTimeout = minConnectionTimeout
+	cps.Backoff.BaseDelay = baseBackoffDelay
+	cps.Backoff.MaxDelay = maxBackoffDelay
+	return []grpc.DialOption{
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(maxMsgSize)),
+		grpc.WithConnectParams(cps),
+		grpc.WithContextDialer(dialUnix),
+		grpc.WithBlock(), //nolint:staticcheck
+	}
+}
+
+func dialUnix(ctx context.Context, addr string) (net.Conn, error) {
+	return (&net.Dialer{}).DialContext(ctx, "unix", addr)
+}
+
+// ParseUnixEndpoint returns the socket file path of the CRI endpoint
+// (e.g., "/run/containerd/containerd.sock" for "unix:///run/containerd/containerd.sock").
+func ParseUnixEndpoint(endpoint string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme != "unix" {
+		return "", fmt.Errorf("invalid scheme: %s (only supports 'unix' protocol)", u.Scheme)
+	}
+	return u.Path, nil
+}
+
+// Connect creates a gRPC connection to the CRI service endpoint.
+func Connect(ctx context.Context, endpoint string) (*grpc.ClientConn, error) {
+	if endpoint == "" {
+		return nil, fmt.Errorf("endpoint cannot be empty")
+	}
+
+	addr, err := ParseUnixEndpoint(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse endpoint: %w", err)
+	}
+
+	// Validate the socket file exists before attempting connection
+	if _, err := os.Stat(addr); err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("socket file does not exist: %s", addr)
+		}
+		return nil, fmt.Errorf("failed to stat socket file: %w", err)
+	}
+
+	// Attempt to establish connection with retries
+	var conn *grpc.ClientConn
+	var dialErr error
+	for i := 0; i < 3; i++ {
+		// "WithBlock" ctx cancel is no-op
+		conn, dialErr = grpc.DialContext(ctx, addr, defaultDialOptions()...) //nolint:staticcheck
+		if conn != nil && dialErr == nil {
+			if conn.GetState() == connectivity.Ready {
+				break
+			}
+
+			log.Logger.Warnw("connection is not ready, closing", "endpoint", endpoint, "connState", conn.GetState())
+			_ = conn.Close()
+			conn = nil
+		} else {
+			log.Logger.Warnw("failed to dial endpoint, retrying",
+				"endpoint", endpoint,
+				"attempt", i+1,
+				"error", dialErr,
+			)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(time.Second):
+		}
+	}
+
+	if dialErr != nil {
+		return nil, fmt.Errorf("failed to establish connection after retries: %w", dialErr)
+	}
+	if conn == nil {
+		return nil, fmt.Errorf("connection is nil")
+	}
+
+	log.Logger.Infow("successfully established connection", "endpoint", endpoint)
+	return conn, nil
+}
+
+// CreateClient creates runtime and image service clients from a gRPC connection.
+//
+// Cannot use "k8s.io/kubernetes/pkg/kubelet/cri/remote.NewRemoteRuntimeService" directly
+// as it causes a bunch of go module errors, importing the whole kubernetes repo.
+// ref. https://github.com/kubernetes-sigs/cri-tools/blob/master/cmd/main.go
+// ref. https://github.com/kubernetes/kubernetes/blob/v1.29.2/pkg/kubelet/cri/remote/remote_runtime.go
+// ref. https://github.com/kubernetes/kubernetes/blob/v1.32.0-alpha.0/staging/src/k8s.io/cri-client/pkg/remote_runtime.go
+func CreateClient(ctx context.Context, conn *grpc.ClientConn) (runtimeapi.RuntimeServiceClient, runtimeapi.ImageServiceClient, error) {
+	// ref. https://github.com/kubernetes/kubernetes/blob/v1.32.0-alpha.0/staging/src/k8s.io/cri-client/pkg/remote_runtime.go
+	runtimeClient := runtimeapi.NewRuntimeServiceClient(conn)
+	version, err := runtimeClient.Version(ctx, &runtimeapi.VersionRequest{})
+	if err != nil {
+		return nil, nil, err
+	}
+	log.Logger.Debugw("successfully checked version", "version", version.String())
+
+	status, err := runtimeClient.Status(ctx, &runtimeapi.StatusRequest{})
+	if err != nil {
+		return nil, nil, err
+	}
+	log.Logger.Debugw("successfully checked status", "status", status.String())
+
+	imageClient := runtimeapi.NewImageServiceClient(conn)
+	return runtimeClient, imageClient, nil
+}
+
+// ListCRIContainers lists the containers with their pod identities
+// from the CRI endpoint (e.g., "unix:///run/containerd/containerd.sock"),
+// joining the containers with their pod sandboxes.
+func ListCRIContainers(ctx context.Context, endpoint string) ([]Identity, error) {
+	conn, err := Connect(ctx, endpoint)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	client, _, err := CreateClient(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	listPodSandboxResp, err := client.ListPodSandbox(ctx, &runtimeapi.ListPodSandboxRequest{
+		Filter: &runtimeapi.PodSandboxFilter{},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	listContainersResp, err := client.ListContainers(ctx, &runtimeapi.ListContainersRequest{
+		Filter: &runtimeapi.ContainerFilter{},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return convertToIdentities(listPodSandboxResp, listContainersResp), nil
+}
+
+func convertToIdentities(listPodSandboxResp *runtimeapi.ListPodSandboxResponse, listContainersResp *runtimeapi.ListContainersResponse) []Identity {
+	if listContainersResp == nil {
+		return nil
+	}
+
+	sandboxes := make(map[string]*runtimeapi.PodSandboxMetadata)
+	if listPodSandboxResp != nil {
+		for _, s := range listPodSandboxResp.Items {
+			if s.Metadata != nil {
+				sandboxes[s.Id] = s.Metadata
+			}
+		}
+	}
+
+	ids := make([]Identity, 0, len(listContainersResp.Containers))
+	for _, c := range listContainersResp.Containers {
+		id := Identity{
+			ContainerID:   c.Id,
+			ContainerName: c.Labels[labelContainerName],
+			PodUID:        c.Labels[labelPodUID],
+			PodName:       c.Labels[labelPodName],
+			PodNamespace:  c.Labels[labelPodNamespace],
+		}
+		if id.ContainerName == "" && c.Metadata != nil {
+			id.ContainerName = c.Metadata.Name
+		}
+
+		// the pod sandbox is the source of truth, if the container has no kubelet labels
+		if s, ok := sandboxes[c.PodSandboxId]; ok {
+			if id.PodName == "" {
+				id.PodName = s.Name
+			}
+			if id.PodNamespace == "" {
+				id.PodNamespace = s.Namespace
+			}
+			if id.PodUID == "" {
+				id.PodUID = s.Uid
+			}
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
@@ -0,0 +1,141 @@
+package container
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/leptonai/gpud/pkg/log"
+)
+
+// Identity is the container and the Kubernetes pod that runs a process.
+type Identity struct {
+	// ContainerID is the full container ID.
+	ContainerID string `json:"container_id"`
+	// ContainerName is the container name, empty if not resolved by the container runtime.
+	ContainerName string `json:"container_name,omitempty"`
+	// Runtime is the container runtime (e.g., "containerd"), empty if unknown.
+	Runtime string `json:"runtime,omitempty"`
+
+	// PodUID is the Kubernetes pod UID, empty if the container is not in a pod.
+	PodUID string `json:"pod_uid,omitempty"`
+	// PodName is the Kubernetes pod name, empty if not resolved by the container runtime.
+	PodName string `json:"pod_name,omitempty"`
+	// PodNamespace is the Kubernetes pod namespace, empty if not resolved by the container runtime.
+	PodNamespace string `json:"pod_namespace,omitempty"`
+}
+
+// ShortContainerID returns the first 12 characters of the container ID (same as "docker ps").
+func (id Identity) ShortContainerID() string {
+	if len(id.ContainerID) > 12 {
+		return id.ContainerID[:12]
+	}
+	return id.ContainerID
+}
+
+// Name returns the human-readable name of the identity,
+// "<namespace>/<pod>/<container>" if the pod is resolved,
+// otherwise the short container ID.
+func (id Identity) Name() string {
+	if id.PodName != "" {
+		return id.PodNamespace + "/" + id.PodName + "/" + id.ContainerName
+	}
+	return id.ShortContainerID()
+}
+
+// minListInterval is the minimum interval between the container runtime lookups,
+// to not overload the runtime with the processes of unknown containers.
+const minListInterval = 10 * time.Second
+
+// Resolver resolves the container identities of the processes,
+// caching the containers listed from the container runtime.
+type Resolver struct {
+	procRoot           string
+	listContainersFunc func(ctx context.Context) ([]Identity, error)
+
+	mu         sync.Mutex
+	containers map[string]Identity
+	lastListed time.Time
+}
+
+// NewResolver creates a new resolver that reads the process cgroups under the procfs root
+// and looks up the containers from the default CRI endpoints.
+func NewResolver(procRoot string) *Resolver {
+	return newResolver(procRoot, listDefaultCRIContainers)
+}
+
+func newResolver(procRoot string, listContainersFunc func(ctx context.Context) ([]Identity, error)) *Resolver {
+	return &Resolver{
+		procRoot:           procRoot,
+		listContainersFunc: listContainersFunc,
+		containers:         make(map[string]Identity),
+	}
+}
+
+func listDefaultCRIContainers(ctx context.Context) ([]Identity, error) {
+	var all []Identity
+	var lastErr error
+	for _, endpoint := range DefaultCRIEndpoints {
+		cctx, ccancel := context.WithTimeout(ctx, 30*time.Second)
+		ids, err := ListCRIContainers(cctx, endpoint)
+		ccancel()
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		all = append(all, ids...)
+	}
+	if len(all) == 0 {
+		return nil, lastErr
+	}
+	return all, nil
+}
+
+// Resolve returns the container identity of the process.
+// Returns nil if the process does not run in a container (or has exited).
+// If the container runtime does not know the container (e.g., not running on Kubernetes),
+// only the container ID, runtime and pod UID (from the cgroup) are set.
+func (r *Resolver) Resolve(ctx context.Context, pid uint32) *Identity {
+	contents, err := ReadProcessCgroup(r.procRoot, pid)
+	if err != nil {
+		return nil
+	}
+	cg, ok := ParseCgroup(contents)
+	if !ok {
+		return nil
+	}
+
+	id := r.lookup(ctx, cg.ContainerID)
+	id.ContainerID = cg.ContainerID
+	id.Runtime = cg.Runtime
+	if id.PodUID == "" {
+		id.PodUID = cg.PodUID
+	}
+	return &id
+}
+
+// lookup returns the cached container, listing the containers again on a cache miss.
+func (r *Resolver) lookup(ctx context.Context, containerID string) Identity {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if id, ok := r.containers[containerID]; ok {
+		return id
+	}
+	if r.listContainersFunc == nil || time.Since(r.lastListed) < minListInterval {
+		return Identity{}
+	}
+	r.lastListed = time.Now()
+
+	ids, err := r.listContainersFunc(ctx)
+	if err != nil {
+		log.Logger.Debugw("failed to list containers from the container runtime", "error", err)
+		return Identity{}
+	}
+
+	r.containers = make(map[string]Identity, len(ids))
+	for _, id := range ids {
+		r.containers[id.ContainerID] = id
+	}
+	return r.containers[containerID]
+}
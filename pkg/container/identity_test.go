@@ -0,0 +1,85 @@
+package container
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeTestProcCgroup(t *testing.T, root string, pid string, file string) {
+	b, err := os.ReadFile(filepath.Join("testdata", file))
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(filepath.Join(root, pid), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, pid, "cgroup"), b, 0644))
+}
+
+func TestResolver(t *testing.T) {
+	root := t.TempDir()
+	writeTestProcCgroup(t, root, "100", "cgroup.v2.containerd.systemd")
+	writeTestProcCgroup(t, root, "200", "cgroup.v2.host")
+
+	lists := 0
+	r := newResolver(root, func(ctx context.Context) ([]Identity, error) {
+		lists++
+		return []Identity{
+			{
+				ContainerID:   testContainerID,
+				ContainerName: "trainer",
+				PodUID:        "0c8a3d7e-3b5a-4c7e-9a7f-1f0b6f3c2d1e",
+				PodName:       "llama-train-0",
+				PodNamespace:  "ml",
+			},
+		}, nil
+	})
+
+	id := r.Resolve(context.Background(), 100)
+	require.NotNil(t, id)
+	assert.Equal(t, Identity{
+		ContainerID:   testContainerID,
+		ContainerName: "trainer",
+		Runtime:       RuntimeContainerd,
+		PodUID:        "0c8a3d7e-3b5a-4c7e-9a7f-1f0b6f3c2d1e",
+		PodName:       "llama-train-0",
+		PodNamespace:  "ml",
+	}, *id)
+	assert.Equal(t, "ml/llama-train-0/trainer", id.Name())
+
+	// cached
+	require.NotNil(t, r.Resolve(context.Background(), 100))
+	assert.Equal(t, 1, lists)
+
+	// not in a container, or exited
+	assert.Nil(t, r.Resolve(context.Background(), 200))
+	assert.Nil(t, r.Resolve(context.Background(), 300))
+}
+
+func TestResolverUnknownContainer(t *testing.T) {
+	root := t.TempDir()
+	writeTestProcCgroup(t, root, "100", "cgroup.v2.docker")
+
+	lists := 0
+	r := newResolver(root, func(ctx context.Context) ([]Identity, error) {
+		lists++
+		return nil, errors.New("no cri endpoint")
+	})
+
+	// only the cgroup information without the container runtime
+	id := r.Resolve(context.Background(), 100)
+	require.NotNil(t, id)
+	assert.Equal(t, Identity{ContainerID: testContainerID, Runtime: RuntimeDocker}, *id)
+	assert.Equal(t, "5f0c3e2a9b8d", id.Name())
+
+	// rate limited on the cache misses
+	_ = r.Resolve(context.Background(), 100)
+	assert.Equal(t, 1, lists)
+
+	r.lastListed = time.Now().Add(-time.Minute)
+	_ = r.Resolve(context.Background(), 100)
+	assert.Equal(t, 2, lists)
+}
@@ -0,0 +1,315 @@
+package eventstore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	apiv1 "github.com/leptonai/gpud/api/v1"
+	"github.com/leptonai/gpud/pkg/log"
+)
+
+var (
+	_ Store  = &memoryStore{}
+	_ Bucket = &memoryBucket{}
+)
+
+// memoryStore is the in-memory implementation of the Store interface.
+// It follows the same semantics as the SQLite-backed store
+// (e.g., second-precision timestamps, retention-based purge),
+// but nothing is persisted across the process restarts.
+// Useful for ephemeral runs (e.g., "gpud scan") and tests.
+type memoryStore struct {
+	retention time.Duration
+
+	mu     sync.Mutex
+	tables map[string]*memoryTable
+}
+
+// memoryTable holds the events for a bucket name,
+// shared by all the buckets opened with the same name
+// (just like the SQLite table is shared by the same name).
+type memoryTable struct {
+	mu     sync.RWMutex
+	events []memoryEvent
+}
+
+// memoryEvent is the event stored in the memory table,
+// with the timestamp truncated to unix seconds
+// as the SQLite store does.
+type memoryEvent struct {
+	timestamp int64
+	ev        apiv1.Event
+}
+
+type memoryBucket struct {
+	rootCtx       context.Context
+	rootCancel    context.CancelFunc
+	retention     time.Duration
+	purgeInterval time.Duration
+
+	name  string
+	table *memoryTable
+}
+
+// NewMemory creates a new in-memory event store.
+func NewMemory(retention time.Duration) (Store, error) {
+	return &memoryStore{
+		retention: retention,
+		tables:    make(map[string]*memoryTable),
+	}, nil
+}
+
+func (s *memoryStore) Bucket(name string, opts ...OpOption) (Bucket, error) {
+	op := &Op{}
+	if err := op.applyOpts(opts); err != nil {
+		return nil, err
+	}
+
+	// actual check interval should be lower than the retention period
+	retention := s.retention
+	purgeInterval := retention / 5
+	if purgeInterval < time.Second {
+		purgeInterval = time.Second
+	}
+	if op.disablePurge {
+		retention = 0
+		purgeInterval = 0
+	}
+
+	tableName := defaultTableName(name)
+
+	s.mu.Lock()
+	tb, ok := s.tables[tableName]
+	if !ok {
+		tb = &memoryTable{}
+		s.tables[tableName] = tb
+	}
+	s.mu.Unlock()
+
+	rootCtx, rootCancel := context.WithCancel(context.Background())
+	b := &memoryBucket{
+		rootCtx:       rootCtx,
+		rootCancel:    rootCancel,
+		retention:     retention,
+		purgeInterval: purgeInterval,
+		name:          tableName,
+		table:         tb,
+	}
+	if retention > time.Second {
+		go b.runPurge()
+	}
+	return b, nil
+}
+
+func (b *memoryBucket) Name() string {
+	return b.name
+}
+
+func (b *memoryBucket) runPurge() {
+	log.Logger.Infow("start purging", "bucket", b.name, "retention", b.retention, "checkInterval", b.purgeInterval)
+	for {
+		select {
+		case <-b.rootCtx.Done():
+			return
+		case <-time.After(b.purgeInterval):
+		}
+
+		now := time.Now().UTC()
+		purged, err := b.Purge(b.rootCtx, now.Add(-b.retention).Unix())
+		if err != nil {
+			log.Logger.Errorw("failed to purge data", "bucket", b.name, "retention", b.retention, "error", err)
+		} else {
+			log.Logger.Infow("purged data", "bucket", b.name, "retention", b.retention, "purged", purged)
+		}
+	}
+}
+
+func (b *memoryBucket) Close() {
+	if b.rootCancel != nil {
+		log.Logger.Infow("closing the store", "bucket", b.name)
+		b.rootCancel()
+	}
+}
+
+func (b *memoryBucket) Insert(ctx context.Context, ev apiv1.Event) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if ev.DeprecatedExtraInfo != nil {
+		if _, err := json.Marshal(ev.DeprecatedExtraInfo); err != nil {
+			return fmt.Errorf("failed to marshal extra info: %w", err)
+		}
+	}
+
+	b.table.mu.Lock()
+	b.table.events = append(b.table.events, memoryEvent{
+		timestamp: ev.Time.Unix(),
+		ev:        copyEvent(ev),
+	})
+	b.table.mu.Unlock()
+
+	return nil
+}
+
+// Find returns nil if the event is not found.
+func (b *memoryBucket) Find(ctx context.Context, ev apiv1.Event) (*apiv1.Event, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var suggestedActionsJSON string
+	if ev.DeprecatedSuggestedActions != nil {
+		saJSON, err := json.Marshal(ev.DeprecatedSuggestedActions)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal suggested actions: %w", err)
+		}
+		suggestedActionsJSON = string(saJSON)
+	}
+
+	ts := ev.Time.Unix()
+
+	b.table.mu.RLock()
+	defer b.table.mu.RUnlock()
+
+	for _, stored := range b.table.events {
+		if stored.timestamp != ts || stored.ev.Name != ev.Name || stored.ev.Type != ev.Type {
+			continue
+		}
+		if ev.Message != "" && stored.ev.Message != ev.Message {
+			continue
+		}
+		if ev.DeprecatedSuggestedActions != nil {
+			if stored.ev.DeprecatedSuggestedActions == nil {
+				continue
+			}
+			saJSON, err := json.Marshal(stored.ev.DeprecatedSuggestedActions)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal suggested actions: %w", err)
+			}
+			if string(saJSON) != suggestedActionsJSON {
+				continue
+			}
+		}
+
+		found := stored.toEvent()
+		if compareEvent(found, ev) {
+			return &found, nil
+		}
+	}
+	return nil, nil
+}
+
+// Get queries the event in the descending order of timestamp (latest event first).
+func (b *memoryBucket) Get(ctx context.Context, since time.Time) (apiv1.Events, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	sinceUnix := since.UTC().Unix()
+
+	b.table.mu.RLock()
+	matched := make([]memoryEvent, 0)
+	for _, stored := range b.table.events {
+		if stored.timestamp > sinceUnix {
+			matched = append(matched, stored)
+		}
+	}
+	b.table.mu.RUnlock()
+
+	if len(matched) == 0 {
+		return nil, nil
+	}
+
+	// events with the same timestamp keep the insertion order
+	sort.SliceStable(matched, func(i, j int) bool {
+		return matched[i].timestamp > matched[j].timestamp
+	})
+
+	events := make(apiv1.Events, 0, len(matched))
+	for _, stored := range matched {
+		events = append(events, stored.toEvent())
+	}
+	return events, nil
+}
+
+// Latest queries the latest event, returns nil if no event found.
+func (b *memoryBucket) Latest(ctx context.Context) (*apiv1.Event, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	b.table.mu.RLock()
+	defer b.table.mu.RUnlock()
+
+	if len(b.table.events) == 0 {
+		return nil, nil
+	}
+
+	latest := b.table.events[0]
+	for _, stored := range b.table.events[1:] {
+		if stored.timestamp >= latest.timestamp {
+			latest = stored
+		}
+	}
+
+	ev := latest.toEvent()
+	return &ev, nil
+}
+
+func (b *memoryBucket) Purge(ctx context.Context, beforeTimestamp int64) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	b.table.mu.Lock()
+	defer b.table.mu.Unlock()
+
+	kept := b.table.events[:0]
+	purged := 0
+	for _, stored := range b.table.events {
+		if stored.timestamp < beforeTimestamp {
+			purged++
+			continue
+		}
+		kept = append(kept, stored)
+	}
+	b.table.events = kept
+
+	return purged, nil
+}
+
+// toEvent returns a copy of the stored event,
+// with the timestamp truncated to unix seconds.
+func (m memoryEvent) toEvent() apiv1.Event {
+	ev := copyEvent(m.ev)
+	ev.Time = metav1.Time{Time: time.Unix(m.timestamp, 0)}
+	return ev
+}
+
+// copyEvent deep-copies the event so that the caller
+// cannot mutate the stored event.
+// The component name is not persisted, same as the SQLite store.
+func copyEvent(ev apiv1.Event) apiv1.Event {
+	cp := ev
+	cp.Component = ""
+	if ev.DeprecatedExtraInfo != nil {
+		cp.DeprecatedExtraInfo = make(map[string]string, len(ev.DeprecatedExtraInfo))
+		for k, v := range ev.DeprecatedExtraInfo {
+			cp.DeprecatedExtraInfo[k] = v
+		}
+	}
+	if ev.DeprecatedSuggestedActions != nil {
+		sa := *ev.DeprecatedSuggestedActions
+		if ev.DeprecatedSuggestedActions.RepairActions != nil {
+			sa.RepairActions = append(sa.RepairActions[:0:0], ev.DeprecatedSuggestedActions.RepairActions...)
+		}
+		cp.DeprecatedSuggestedActions = &sa
+	}
+	return cp
+}
@@ -0,0 +1,289 @@
+package eventstore
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	apiv1 "github.com/leptonai/gpud/api/v1"
+)
+
+func TestMemoryInsertsReads(t *testing.T) {
+	t.Parallel()
+
+	store, err := NewMemory(0)
+	require.NoError(t, err)
+	bucket, err := store.Bucket("test-table")
+	require.NoError(t, err)
+	defer bucket.Close()
+
+	assert.Equal(t, defaultTableName("test-table"), bucket.Name())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	first := time.Now().UTC()
+	events := apiv1.Events{}
+	eventsN := 10
+	for i := 0; i < eventsN; i++ {
+		events = append(events, apiv1.Event{
+			Time:                metav1.Time{Time: first.Add(time.Duration(i) * time.Second)},
+			Name:                "dmesg",
+			Type:                apiv1.EventTypeWarning,
+			Message:             "memory oom",
+			DeprecatedExtraInfo: map[string]string{"id": "same"},
+		})
+	}
+	for _, ev := range events {
+		require.NoError(t, bucket.Insert(ctx, ev))
+	}
+
+	got, err := bucket.Get(ctx, first.Add(-30*time.Second))
+	require.NoError(t, err)
+	require.Len(t, got, eventsN)
+
+	// latest first
+	for i := 0; i < eventsN; i++ {
+		assert.Equal(t, events[eventsN-1-i].Time.Unix(), got[i].Time.Unix())
+		assert.Equal(t, "dmesg", got[i].Name)
+	}
+
+	latest, err := bucket.Latest(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, latest)
+	assert.Equal(t, events[eventsN-1].Time.Unix(), latest.Time.Unix())
+
+	purged, err := bucket.Purge(ctx, events[eventsN-3].Time.Unix())
+	require.NoError(t, err)
+	assert.Equal(t, eventsN-3, purged)
+
+	got, err = bucket.Get(ctx, first.Add(-30*time.Second))
+	require.NoError(t, err)
+	assert.Len(t, got, 3)
+}
+
+func TestMemoryEmptyResults(t *testing.T) {
+	t.Parallel()
+
+	store, err := NewMemory(0)
+	require.NoError(t, err)
+	bucket, err := store.Bucket("test")
+	require.NoError(t, err)
+	defer bucket.Close()
+
+	ctx := context.Background()
+
+	events, err := bucket.Get(ctx, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Nil(t, events)
+
+	latest, err := bucket.Latest(ctx)
+	require.NoError(t, err)
+	assert.Nil(t, latest)
+
+	found, err := bucket.Find(ctx, apiv1.Event{Time: metav1.Time{Time: time.Now()}, Name: "x"})
+	require.NoError(t, err)
+	assert.Nil(t, found)
+}
+
+func TestMemoryFindEvent(t *testing.T) {
+	t.Parallel()
+
+	store, err := NewMemory(0)
+	require.NoError(t, err)
+	bucket, err := store.Bucket("test")
+	require.NoError(t, err)
+	defer bucket.Close()
+
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	ev := apiv1.Event{
+		Time:                metav1.Time{Time: now},
+		Name:                "test_event",
+		Type:                apiv1.EventTypeWarning,
+		Message:             "hello",
+		DeprecatedExtraInfo: map[string]string{"a": "b"},
+		DeprecatedSuggestedActions: &apiv1.SuggestedActions{
+			RepairActions: []apiv1.RepairActionType{apiv1.RepairActionTypeRebootSystem},
+		},
+	}
+	require.NoError(t, bucket.Insert(ctx, ev))
+
+	// exact match
+	found, err := bucket.Find(ctx, ev)
+	require.NoError(t, err)
+	require.NotNil(t, found)
+	assert.Equal(t, ev.Message, found.Message)
+	assert.Equal(t, ev.DeprecatedExtraInfo, found.DeprecatedExtraInfo)
+	assert.Equal(t, ev.DeprecatedSuggestedActions, found.DeprecatedSuggestedActions)
+
+	// empty message and nil suggested actions are ignored
+	partial := ev
+	partial.Message = ""
+	partial.DeprecatedSuggestedActions = nil
+	found, err = bucket.Find(ctx, partial)
+	require.NoError(t, err)
+	assert.NotNil(t, found)
+
+	// different extra info
+	different := ev
+	different.DeprecatedExtraInfo = map[string]string{"a": "c"}
+	found, err = bucket.Find(ctx, different)
+	require.NoError(t, err)
+	assert.Nil(t, found)
+
+	// different suggested actions
+	different = ev
+	different.DeprecatedSuggestedActions = &apiv1.SuggestedActions{
+		RepairActions: []apiv1.RepairActionType{apiv1.RepairActionTypeHardwareInspection},
+	}
+	found, err = bucket.Find(ctx, different)
+	require.NoError(t, err)
+	assert.Nil(t, found)
+
+	// sub-second precision is truncated
+	sameSecond := ev
+	sameSecond.Time = metav1.Time{Time: time.Unix(now.Unix(), 0)}
+	found, err = bucket.Find(ctx, sameSecond)
+	require.NoError(t, err)
+	assert.NotNil(t, found)
+}
+
+func TestMemoryBucketsShareByName(t *testing.T) {
+	t.Parallel()
+
+	store, err := NewMemory(0)
+	require.NoError(t, err)
+
+	b1, err := store.Bucket("shared")
+	require.NoError(t, err)
+	defer b1.Close()
+	b2, err := store.Bucket("shared", WithDisablePurge())
+	require.NoError(t, err)
+	defer b2.Close()
+	other, err := store.Bucket("other")
+	require.NoError(t, err)
+	defer other.Close()
+
+	ctx := context.Background()
+	require.NoError(t, b1.Insert(ctx, apiv1.Event{Time: metav1.Time{Time: time.Now()}, Name: "ev"}))
+
+	events, err := b2.Get(ctx, time.Now().Add(-time.Minute))
+	require.NoError(t, err)
+	assert.Len(t, events, 1)
+
+	events, err = other.Get(ctx, time.Now().Add(-time.Minute))
+	require.NoError(t, err)
+	assert.Empty(t, events)
+}
+
+func TestMemoryReturnsCopies(t *testing.T) {
+	t.Parallel()
+
+	store, err := NewMemory(0)
+	require.NoError(t, err)
+	bucket, err := store.Bucket("test")
+	require.NoError(t, err)
+	defer bucket.Close()
+
+	ctx := context.Background()
+	extra := map[string]string{"k": "v"}
+	require.NoError(t, bucket.Insert(ctx, apiv1.Event{
+		Component:           "comp",
+		Time:                metav1.Time{Time: time.Now()},
+		Name:                "ev",
+		DeprecatedExtraInfo: extra,
+	}))
+	extra["k"] = "mutated"
+
+	latest, err := bucket.Latest(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, latest)
+	assert.Equal(t, "v", latest.DeprecatedExtraInfo["k"])
+	assert.Empty(t, latest.Component)
+
+	latest.DeprecatedExtraInfo["k"] = "mutated"
+	latest, err = bucket.Latest(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "v", latest.DeprecatedExtraInfo["k"])
+}
+
+func TestMemoryContextCancellation(t *testing.T) {
+	t.Parallel()
+
+	store, err := NewMemory(0)
+	require.NoError(t, err)
+	bucket, err := store.Bucket("test")
+	require.NoError(t, err)
+	defer bucket.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	assert.Error(t, bucket.Insert(ctx, apiv1.Event{Time: metav1.Time{Time: time.Now()}, Name: "ev"}))
+	_, err = bucket.Get(ctx, time.Now())
+	assert.Error(t, err)
+	_, err = bucket.Latest(ctx)
+	assert.Error(t, err)
+	_, err = bucket.Purge(ctx, time.Now().Unix())
+	assert.Error(t, err)
+}
+
+func TestMemoryConcurrentAccess(t *testing.T) {
+	t.Parallel()
+
+	store, err := NewMemory(0)
+	require.NoError(t, err)
+	bucket, err := store.Bucket("test")
+	require.NoError(t, err)
+	defer bucket.Close()
+
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				assert.NoError(t, bucket.Insert(ctx, apiv1.Event{
+					Time: metav1.Time{Time: now.Add(time.Duration(i*10+j) * time.Second)},
+					Name: "ev",
+				}))
+				_, err := bucket.Get(ctx, now.Add(-time.Minute))
+				assert.NoError(t, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	events, err := bucket.Get(ctx, now.Add(-time.Minute))
+	require.NoError(t, err)
+	assert.Len(t, events, 100)
+}
+
+func TestMemoryRetentionPurge(t *testing.T) {
+	t.Parallel()
+
+	store, err := NewMemory(2 * time.Second)
+	require.NoError(t, err)
+	bucket, err := store.Bucket("test")
+	require.NoError(t, err)
+	defer bucket.Close()
+
+	ctx := context.Background()
+	require.NoError(t, bucket.Insert(ctx, apiv1.Event{Time: metav1.Time{Time: time.Now().Add(-time.Hour)}, Name: "old"}))
+	require.NoError(t, bucket.Insert(ctx, apiv1.Event{Time: metav1.Time{Time: time.Now().Add(time.Hour)}, Name: "new"}))
+
+	require.Eventually(t, func() bool {
+		events, err := bucket.Get(ctx, time.Now().Add(-2*time.Hour))
+		return err == nil && len(events) == 1 && events[0].Name == "new"
+	}, 10*time.Second, 100*time.Millisecond)
+}
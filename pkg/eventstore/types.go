@@ -11,6 +11,25 @@ const (
 	DefaultRetention = 3 * 24 * time.Hour // 3 days
 )
 
+const (
+	// BackendSQLite persists the events in the SQLite state database.
+	BackendSQLite = "sqlite"
+	// BackendMemory keeps the events in memory,
+	// which are discarded when the process exits.
+	BackendMemory = "memory"
+)
+
+// IsValidBackend returns true if the backend is supported.
+// Empty backend defaults to the SQLite backend.
+func IsValidBackend(backend string) bool {
+	switch backend {
+	case "", BackendSQLite, BackendMemory:
+		return true
+	default:
+		return false
+	}
+}
+
 type Store interface {
 	Bucket(name string, opts ...OpOption) (Bucket, error)
 }
@@ -0,0 +1,198 @@
+package gpudstate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/leptonai/gpud/pkg/log"
+	"github.com/leptonai/gpud/pkg/sqlite"
+)
+
+const (
+	TableNameCounters = "counters"
+
+	ColumnCountersComponent   = "component"
+	ColumnCountersDevice      = "device"
+	ColumnCountersName        = "name"
+	ColumnCountersValue       = "value"
+	ColumnCountersUnixSeconds = "unix_seconds"
+)
+
+// DefaultCountersRetention is the retention of the counters not updated,
+// same as the default event retention.
+// The components update the counters of all the present devices every check,
+// so the counters older than this are of the devices no longer present (e.g., replaced GPUs).
+const DefaultCountersRetention = 3 * 24 * time.Hour
+
+// Counter is the last observed value of a cumulative counter
+// (e.g., NVLink replay errors of a link), persisted to compute
+// the deltas across the checks and restarts.
+type Counter struct {
+	// Device is the device that the counter belongs to
+	// (e.g., "GPU-xxx/3" for the NVLink 3 of the GPU).
+	Device string `json:"device"`
+	// Name is the counter name (e.g., "replay_errors").
+	Name string `json:"name"`
+	// Value is the last observed value.
+	Value uint64 `json:"value"`
+	// Time is the time the value is observed.
+	Time time.Time `json:"time"`
+}
+
+// CreateTableCounters creates the table to persist the cumulative counters.
+func CreateTableCounters(ctx context.Context, dbRW *sql.DB) error {
+	_, err := dbRW.ExecContext(ctx, fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS %s (
+	%s TEXT NOT NULL,
+	%s TEXT NOT NULL,
+	%s TEXT NOT NULL,
+	%s INTEGER NOT NULL,
+	%s INTEGER NOT NULL,
+	PRIMARY KEY (%s, %s, %s)
+);`,
+		TableNameCounters,
+		ColumnCountersComponent,
+		ColumnCountersDevice,
+		ColumnCountersName,
+		ColumnCountersValue,
+		ColumnCountersUnixSeconds,
+		ColumnCountersComponent, ColumnCountersDevice, ColumnCountersName,
+	))
+	return err
+}
+
+// ReadCounters reads all the counters of the component.
+func ReadCounters(ctx context.Context, dbRO *sql.DB, component string) ([]Counter, error) {
+	query := fmt.Sprintf(`
+SELECT %s, %s, %s, %s FROM %s
+WHERE %s = ?
+ORDER BY %s, %s;
+`,
+		ColumnCountersDevice,
+		ColumnCountersName,
+		ColumnCountersValue,
+		ColumnCountersUnixSeconds,
+		TableNameCounters,
+		ColumnCountersComponent,
+		ColumnCountersDevice, ColumnCountersName,
+	)
+
+	start := time.Now()
+	rows, err := dbRO.QueryContext(ctx, query, component)
+	sqlite.RecordSelect(time.Since(start).Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var counters []Counter
+	for rows.Next() {
+		var c Counter
+		var value int64
+		var unixSeconds int64
+		if err := rows.Scan(&c.Device, &c.Name, &value, &unixSeconds); err != nil {
+			return nil, err
+		}
+		c.Value = uint64(value)
+		c.Time = time.Unix(unixSeconds, 0).UTC()
+		counters = append(counters, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return counters, nil
+}
+
+// LoadCounters reads all the counters of the component,
+// keyed by the device and the counter name (e.g., to restore the baselines after restart).
+func LoadCounters(ctx context.Context, dbRO *sql.DB, component string) (map[string]map[string]Counter, error) {
+	counters, err := ReadCounters(ctx, dbRO, component)
+	if err != nil {
+		return nil, err
+	}
+
+	loaded := make(map[string]map[string]Counter)
+	for _, c := range counters {
+		if _, ok := loaded[c.Device]; !ok {
+			loaded[c.Device] = make(map[string]Counter)
+		}
+		loaded[c.Device][c.Name] = c
+	}
+	return loaded, nil
+}
+
+// UpdateCounters inserts or updates the counters of the component,
+// and purges the counters of the component not updated within the DefaultCountersRetention
+// before the oldest updated counter (e.g., the devices no longer present).
+func UpdateCounters(ctx context.Context, dbRW *sql.DB, component string, counters []Counter) error {
+	if len(counters) == 0 {
+		return nil
+	}
+
+	tx, err := dbRW.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	var committed bool
+	defer func() {
+		if !committed {
+			if err := tx.Rollback(); err != nil {
+				log.Logger.Errorw("failed to rollback transaction", "error", err)
+			}
+		}
+	}()
+
+	query := fmt.Sprintf(`
+INSERT INTO %s (%s, %s, %s, %s, %s) VALUES (?, ?, ?, ?, ?)
+ON CONFLICT(%s, %s, %s) DO UPDATE SET %s = excluded.%s, %s = excluded.%s;
+`,
+		TableNameCounters,
+		ColumnCountersComponent, ColumnCountersDevice, ColumnCountersName, ColumnCountersValue, ColumnCountersUnixSeconds,
+		ColumnCountersComponent, ColumnCountersDevice, ColumnCountersName,
+		ColumnCountersValue, ColumnCountersValue,
+		ColumnCountersUnixSeconds, ColumnCountersUnixSeconds,
+	)
+
+	start := time.Now()
+	var oldest time.Time
+	for _, c := range counters {
+		ts := c.Time
+		if ts.IsZero() {
+			ts = time.Now()
+		}
+		if oldest.IsZero() || ts.Before(oldest) {
+			oldest = ts
+		}
+		if _, err = tx.ExecContext(ctx, query, component, c.Device, c.Name, int64(c.Value), ts.UTC().Unix()); err != nil {
+			return fmt.Errorf("failed to update counter: %w", err)
+		}
+	}
+	sqlite.RecordInsertUpdate(time.Since(start).Seconds())
+
+	start = time.Now()
+	_, err = tx.ExecContext(ctx, fmt.Sprintf(`DELETE FROM %s WHERE %s = ? AND %s < ?`, TableNameCounters, ColumnCountersComponent, ColumnCountersUnixSeconds),
+		component, oldest.Add(-DefaultCountersRetention).UTC().Unix())
+	sqlite.RecordDelete(time.Since(start).Seconds())
+	if err != nil {
+		return fmt.Errorf("failed to purge counters: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	committed = true
+
+	return nil
+}
+
+// DeleteCounters deletes all the counters of the component
+// (e.g., to reset the baselines).
+func DeleteCounters(ctx context.Context, dbRW *sql.DB, component string) error {
+	start := time.Now()
+	_, err := dbRW.ExecContext(ctx, fmt.Sprintf(`DELETE FROM %s WHERE %s = ?`, TableNameCounters, ColumnCountersComponent), component)
+	sqlite.RecordDelete(time.Since(start).Seconds())
+	return err
+}
@@ -0,0 +1,81 @@
+package gpudstate
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/leptonai/gpud/pkg/sqlite"
+)
+
+func TestCounters(t *testing.T) {
+	t.Parallel()
+
+	dbRW, dbRO, cleanup := sqlite.OpenTestDB(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	require.NoError(t, CreateTableCounters(ctx, dbRW))
+	// idempotent
+	require.NoError(t, CreateTableCounters(ctx, dbRW))
+
+	counters, err := ReadCounters(ctx, dbRO, "a")
+	require.NoError(t, err)
+	assert.Empty(t, counters)
+
+	ts := time.Unix(1700000000, 0).UTC()
+	require.NoError(t, UpdateCounters(ctx, dbRW, "a", []Counter{
+		{Device: "GPU-1/0", Name: "replay_errors", Value: 10, Time: ts},
+		{Device: "GPU-1/0", Name: "crc_errors", Value: 1, Time: ts},
+	}))
+	require.NoError(t, UpdateCounters(ctx, dbRW, "b", []Counter{
+		{Device: "GPU-1", Name: "replay_errors", Value: 5, Time: ts},
+	}))
+
+	counters, err = ReadCounters(ctx, dbRO, "a")
+	require.NoError(t, err)
+	require.Len(t, counters, 2)
+	assert.Equal(t, Counter{Device: "GPU-1/0", Name: "crc_errors", Value: 1, Time: ts}, counters[0])
+	assert.Equal(t, Counter{Device: "GPU-1/0", Name: "replay_errors", Value: 10, Time: ts}, counters[1])
+
+	// upsert
+	require.NoError(t, UpdateCounters(ctx, dbRW, "a", []Counter{
+		{Device: "GPU-1/0", Name: "replay_errors", Value: 20, Time: ts.Add(time.Minute)},
+	}))
+	counters, err = ReadCounters(ctx, dbRO, "a")
+	require.NoError(t, err)
+	require.Len(t, counters, 2)
+	assert.Equal(t, uint64(20), counters[1].Value)
+	assert.Equal(t, ts.Add(time.Minute), counters[1].Time)
+
+	loaded, err := LoadCounters(ctx, dbRO, "a")
+	require.NoError(t, err)
+	require.Len(t, loaded, 1)
+	assert.Equal(t, uint64(20), loaded["GPU-1/0"]["replay_errors"].Value)
+	assert.Equal(t, uint64(1), loaded["GPU-1/0"]["crc_errors"].Value)
+
+	// the counters not updated within the retention are purged (e.g., removed devices)
+	later := ts.Add(DefaultCountersRetention + 2*time.Minute)
+	require.NoError(t, UpdateCounters(ctx, dbRW, "a", []Counter{
+		{Device: "GPU-2/0", Name: "replay_errors", Value: 3, Time: later},
+	}))
+	loaded, err = LoadCounters(ctx, dbRO, "a")
+	require.NoError(t, err)
+	require.Len(t, loaded, 1)
+	assert.Equal(t, uint64(3), loaded["GPU-2/0"]["replay_errors"].Value)
+
+	require.NoError(t, DeleteCounters(ctx, dbRW, "a"))
+	counters, err = ReadCounters(ctx, dbRO, "a")
+	require.NoError(t, err)
+	assert.Empty(t, counters)
+
+	// other components are not affected
+	counters, err = ReadCounters(ctx, dbRO, "b")
+	require.NoError(t, err)
+	assert.Len(t, counters, 1)
+}
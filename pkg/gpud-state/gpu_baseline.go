@@ -0,0 +1,128 @@
+package gpudstate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/leptonai/gpud/pkg/log"
+	"github.com/leptonai/gpud/pkg/sqlite"
+)
+
+const (
+	TableNameGPUBaseline = "gpu_baseline"
+
+	ColumnGPUBaselineCount       = "count"
+	ColumnGPUBaselineUUIDs       = "uuids"
+	ColumnGPUBaselineUnixSeconds = "unix_seconds"
+)
+
+// GPUBaseline is the expected GPUs of the machine,
+// recorded when the GPUs are first seen (or reset by the user).
+type GPUBaseline struct {
+	// Count is the expected number of GPUs.
+	Count int `json:"count"`
+	// UUIDs is the expected GPU UUIDs, sorted.
+	UUIDs []string `json:"uuids,omitempty"`
+	// Time is the time the baseline is recorded.
+	Time time.Time `json:"time"`
+}
+
+// CreateTableGPUBaseline creates the table to persist the expected GPUs.
+func CreateTableGPUBaseline(ctx context.Context, dbRW *sql.DB) error {
+	_, err := dbRW.ExecContext(ctx, fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS %s (
+	%s INTEGER NOT NULL,
+	%s TEXT,
+	%s INTEGER NOT NULL
+);`, TableNameGPUBaseline, ColumnGPUBaselineCount, ColumnGPUBaselineUUIDs, ColumnGPUBaselineUnixSeconds))
+	return err
+}
+
+// ReadGPUBaseline reads the expected GPUs.
+// Returns nil and no error, if no baseline is found.
+func ReadGPUBaseline(ctx context.Context, dbRO *sql.DB) (*GPUBaseline, error) {
+	query := fmt.Sprintf(`
+SELECT %s, %s, %s FROM %s
+ORDER BY %s DESC LIMIT 1;
+`,
+		ColumnGPUBaselineCount,
+		ColumnGPUBaselineUUIDs,
+		ColumnGPUBaselineUnixSeconds,
+		TableNameGPUBaseline,
+		ColumnGPUBaselineUnixSeconds,
+	)
+
+	start := time.Now()
+	var count int
+	var uuids sql.NullString
+	var unixSeconds int64
+	err := dbRO.QueryRowContext(ctx, query).Scan(&count, &uuids, &unixSeconds)
+	sqlite.RecordSelect(time.Since(start).Seconds())
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	b := &GPUBaseline{
+		Count: count,
+		Time:  time.Unix(unixSeconds, 0).UTC(),
+	}
+	if uuids.Valid && uuids.String != "" {
+		b.UUIDs = strings.Split(uuids.String, ",")
+	}
+	return b, nil
+}
+
+// RecordGPUBaseline replaces the expected GPUs with the given count and UUIDs.
+func RecordGPUBaseline(ctx context.Context, dbRW *sql.DB, count int, uuids []string) error {
+	sorted := make([]string, len(uuids))
+	copy(sorted, uuids)
+	sort.Strings(sorted)
+
+	tx, err := dbRW.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	var committed bool
+	defer func() {
+		if !committed {
+			if err := tx.Rollback(); err != nil {
+				log.Logger.Errorw("failed to rollback transaction", "error", err)
+			}
+		}
+	}()
+
+	start := time.Now()
+	if _, err = tx.ExecContext(ctx, fmt.Sprintf(`DELETE FROM %s`, TableNameGPUBaseline)); err != nil {
+		return fmt.Errorf("failed to delete gpu baseline: %w", err)
+	}
+	sqlite.RecordDelete(time.Since(start).Seconds())
+
+	query := fmt.Sprintf(`
+INSERT INTO %s (%s, %s, %s) VALUES (?, ?, ?);
+`,
+		TableNameGPUBaseline,
+		ColumnGPUBaselineCount, ColumnGPUBaselineUUIDs, ColumnGPUBaselineUnixSeconds,
+	)
+
+	start = time.Now()
+	if _, err = tx.ExecContext(ctx, query, count, strings.Join(sorted, ","), time.Now().UTC().Unix()); err != nil {
+		return fmt.Errorf("failed to insert gpu baseline: %w", err)
+	}
+	sqlite.RecordInsertUpdate(time.Since(start).Seconds())
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	committed = true
+
+	return nil
+}
@@ -0,0 +1,45 @@
+package gpudstate
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/leptonai/gpud/pkg/sqlite"
+)
+
+func TestGPUBaseline(t *testing.T) {
+	t.Parallel()
+
+	dbRW, dbRO, cleanup := sqlite.OpenTestDB(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	require.NoError(t, CreateTableGPUBaseline(ctx, dbRW))
+
+	// no baseline yet
+	b, err := ReadGPUBaseline(ctx, dbRO)
+	require.NoError(t, err)
+	assert.Nil(t, b)
+
+	require.NoError(t, RecordGPUBaseline(ctx, dbRW, 2, []string{"GPU-2", "GPU-1"}))
+	b, err = ReadGPUBaseline(ctx, dbRO)
+	require.NoError(t, err)
+	require.NotNil(t, b)
+	assert.Equal(t, 2, b.Count)
+	assert.Equal(t, []string{"GPU-1", "GPU-2"}, b.UUIDs)
+	assert.False(t, b.Time.IsZero())
+
+	// reset replaces the previous baseline
+	require.NoError(t, RecordGPUBaseline(ctx, dbRW, 1, nil))
+	b, err = ReadGPUBaseline(ctx, dbRO)
+	require.NoError(t, err)
+	require.NotNil(t, b)
+	assert.Equal(t, 1, b.Count)
+	assert.Empty(t, b.UUIDs)
+}
@@ -0,0 +1,132 @@
+package gpudstate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/leptonai/gpud/pkg/log"
+	"github.com/leptonai/gpud/pkg/sqlite"
+)
+
+const (
+	TableNameKmsgCursor = "kmsg_cursor"
+
+	ColumnKmsgCursorBootID         = "boot_id"
+	ColumnKmsgCursorSequenceNumber = "sequence_number"
+	ColumnKmsgCursorUnixSeconds    = "unix_seconds"
+)
+
+// CreateTableKmsgCursor creates the table to persist
+// the sequence number of the last processed kmsg record.
+func CreateTableKmsgCursor(ctx context.Context, dbRW *sql.DB) error {
+	_, err := dbRW.ExecContext(ctx, fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS %s (
+	%s TEXT PRIMARY KEY,
+	%s INTEGER NOT NULL,
+	%s INTEGER NOT NULL
+);`, TableNameKmsgCursor, ColumnKmsgCursorBootID, ColumnKmsgCursorSequenceNumber, ColumnKmsgCursorUnixSeconds))
+	return err
+}
+
+// ReadKmsgCursor reads the boot ID and the sequence number of the last processed kmsg record.
+// Returns an empty boot ID and no error, if no cursor is found.
+func ReadKmsgCursor(ctx context.Context, dbRO *sql.DB) (string, int64, error) {
+	query := fmt.Sprintf(`
+SELECT %s, %s FROM %s
+ORDER BY %s DESC LIMIT 1;
+`,
+		ColumnKmsgCursorBootID,
+		ColumnKmsgCursorSequenceNumber,
+		TableNameKmsgCursor,
+		ColumnKmsgCursorUnixSeconds,
+	)
+
+	start := time.Now()
+	var bootID string
+	var seq int64
+	err := dbRO.QueryRowContext(ctx, query).Scan(&bootID, &seq)
+	sqlite.RecordSelect(time.Since(start).Seconds())
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", 0, nil
+		}
+		return "", 0, err
+	}
+	return bootID, seq, nil
+}
+
+// UpdateKmsgCursor persists the boot ID and the sequence number of the last processed kmsg record.
+// Only the cursor for the latest boot is kept, since the sequence numbers
+// are reset on reboot.
+func UpdateKmsgCursor(ctx context.Context, dbRW *sql.DB, bootID string, seq int64) error {
+	tx, err := dbRW.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	var committed bool
+	defer func() {
+		if !committed {
+			if err := tx.Rollback(); err != nil {
+				log.Logger.Errorw("failed to rollback transaction", "error", err)
+			}
+		}
+	}()
+
+	start := time.Now()
+	if _, err = tx.ExecContext(ctx, fmt.Sprintf(`DELETE FROM %s WHERE %s != ?`, TableNameKmsgCursor, ColumnKmsgCursorBootID), bootID); err != nil {
+		return fmt.Errorf("failed to delete stale kmsg cursors: %w", err)
+	}
+	sqlite.RecordDelete(time.Since(start).Seconds())
+
+	query := fmt.Sprintf(`
+INSERT INTO %s (%s, %s, %s) VALUES (?, ?, ?)
+ON CONFLICT(%s) DO UPDATE SET %s = excluded.%s, %s = excluded.%s;
+`,
+		TableNameKmsgCursor,
+		ColumnKmsgCursorBootID, ColumnKmsgCursorSequenceNumber, ColumnKmsgCursorUnixSeconds,
+		ColumnKmsgCursorBootID,
+		ColumnKmsgCursorSequenceNumber, ColumnKmsgCursorSequenceNumber,
+		ColumnKmsgCursorUnixSeconds, ColumnKmsgCursorUnixSeconds,
+	)
+
+	start = time.Now()
+	if _, err = tx.ExecContext(ctx, query, bootID, seq, time.Now().UTC().Unix()); err != nil {
+		return fmt.Errorf("failed to update kmsg cursor: %w", err)
+	}
+	sqlite.RecordInsertUpdate(time.Since(start).Seconds())
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	committed = true
+
+	return nil
+}
+
+// KmsgCursorStore persists the kmsg cursor in the state database
+// (implements "kmsg.CursorStore").
+type KmsgCursorStore struct {
+	dbRW *sql.DB
+	dbRO *sql.DB
+}
+
+// NewKmsgCursorStore creates the kmsg cursor table if not exists,
+// and returns the cursor store backed by the state database.
+func NewKmsgCursorStore(ctx context.Context, dbRW *sql.DB, dbRO *sql.DB) (*KmsgCursorStore, error) {
+	if err := CreateTableKmsgCursor(ctx, dbRW); err != nil {
+		return nil, err
+	}
+	return &KmsgCursorStore{dbRW: dbRW, dbRO: dbRO}, nil
+}
+
+func (s *KmsgCursorStore) ReadCursor(ctx context.Context) (string, int64, error) {
+	return ReadKmsgCursor(ctx, s.dbRO)
+}
+
+func (s *KmsgCursorStore) WriteCursor(ctx context.Context, bootID string, seq int64) error {
+	return UpdateKmsgCursor(ctx, s.dbRW, bootID, seq)
+}
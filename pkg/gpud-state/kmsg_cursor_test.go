@@ -0,0 +1,50 @@
+package gpudstate
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/leptonai/gpud/pkg/sqlite"
+)
+
+func TestKmsgCursor(t *testing.T) {
+	t.Parallel()
+
+	dbRW, dbRO, cleanup := sqlite.OpenTestDB(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	store, err := NewKmsgCursorStore(ctx, dbRW, dbRO)
+	require.NoError(t, err)
+
+	// no cursor yet
+	bootID, seq, err := store.ReadCursor(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, bootID)
+	assert.Equal(t, int64(0), seq)
+
+	require.NoError(t, store.WriteCursor(ctx, "boot-1", 10))
+	require.NoError(t, store.WriteCursor(ctx, "boot-1", 20))
+
+	bootID, seq, err = store.ReadCursor(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "boot-1", bootID)
+	assert.Equal(t, int64(20), seq)
+
+	// new boot replaces the stale cursor
+	require.NoError(t, store.WriteCursor(ctx, "boot-2", 5))
+	bootID, seq, err = store.ReadCursor(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "boot-2", bootID)
+	assert.Equal(t, int64(5), seq)
+
+	var count int
+	require.NoError(t, dbRO.QueryRow("SELECT COUNT(*) FROM "+TableNameKmsgCursor).Scan(&count))
+	assert.Equal(t, 1, count)
+}
@@ -0,0 +1,116 @@
+// Package healthstate provides the helpers to resolve the component health states from the events.
+package healthstate
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	apiv1 "github.com/leptonai/gpud/api/v1"
+)
+
+// EventNameSetHealthy is the name of the event that marks the component (or the device) healthy.
+const EventNameSetHealthy = "SetHealthy"
+
+// DeviceStateName returns the name of the health state of the device,
+// so that the per-device states of the same component are distinguishable
+// (e.g., "error_xid/GPU-b850f46d" for the "error_xid" state).
+func DeviceStateName(name string, deviceUUID string) string {
+	return fmt.Sprintf("%s/%s", name, deviceUUID)
+}
+
+// EvolvePerDevice resolves the health states per device from the events,
+// so that the errors on one device do not affect the states of the other devices.
+//
+// The error events (of the name "errorEventName") are grouped by the device UUID
+// (returned by "getDeviceUUID"), and each group is resolved by "evolve".
+// The "reboot" events apply to all the devices, and the "SetHealthy" events
+// apply to the device returned by "getDeviceUUID" (or to all the devices if empty),
+// so that the devices of the error and "SetHealthy" events are matched the same way.
+//
+// Returns one state per device sorted by the device UUID, where the state name
+// and the extra info "deviceUUIDKey" are set to the device UUID (see "DeviceStateName"),
+// or a single state resolved from all the events if no error event is found.
+// note: assume events are sorted by time in descending order
+func EvolvePerDevice(
+	events apiv1.Events,
+	errorEventName string,
+	deviceUUIDKey string,
+	getDeviceUUID func(apiv1.Event) string,
+	evolve func(apiv1.Events) apiv1.HealthState,
+) apiv1.HealthStates {
+	devices := make(map[string]struct{})
+	for _, event := range events {
+		if event.Name == errorEventName {
+			devices[getDeviceUUID(event)] = struct{}{}
+		}
+	}
+	if len(devices) == 0 {
+		return apiv1.HealthStates{evolve(events)}
+	}
+
+	uuids := make([]string, 0, len(devices))
+	for uuid := range devices {
+		uuids = append(uuids, uuid)
+	}
+	sort.Strings(uuids)
+
+	states := make(apiv1.HealthStates, 0, len(uuids))
+	for _, uuid := range uuids {
+		deviceEvents := make(apiv1.Events, 0, len(events))
+		for _, event := range events {
+			switch event.Name {
+			case errorEventName:
+				if getDeviceUUID(event) != uuid {
+					continue
+				}
+			case EventNameSetHealthy:
+				if target := getDeviceUUID(event); target != "" && target != uuid {
+					continue
+				}
+			}
+			deviceEvents = append(deviceEvents, event)
+		}
+
+		state := evolve(deviceEvents)
+		if uuid != "" {
+			state.Name = DeviceStateName(state.Name, uuid)
+			if state.Health == apiv1.HealthStateTypeHealthy {
+				state.Reason = fmt.Sprintf("%s on %s", state.Reason, uuid)
+			}
+			state.DeprecatedExtraInfo = map[string]string{deviceUUIDKey: uuid}
+		}
+		states = append(states, state)
+	}
+	return states
+}
+
+// SetHealthyTimes is the time of the last "SetHealthy" event per device UUID,
+// where the empty device UUID is for the event that applies to all the devices.
+type SetHealthyTimes map[string]time.Time
+
+// LastSetHealthy returns the time of the last "SetHealthy" event per device
+// (returned by "getDeviceUUID", or empty for all the devices), from the events in any order.
+func LastSetHealthy(events apiv1.Events, getDeviceUUID func(apiv1.Event) string) SetHealthyTimes {
+	times := make(SetHealthyTimes)
+	for _, event := range events {
+		if event.Name != EventNameSetHealthy {
+			continue
+		}
+		uuid := getDeviceUUID(event)
+		if event.Time.After(times[uuid]) {
+			times[uuid] = event.Time.Time
+		}
+	}
+	return times
+}
+
+// Of returns the time of the last "SetHealthy" event that applies to the device
+// (of the device itself or of all the devices), or zero if none.
+func (s SetHealthyTimes) Of(deviceUUID string) time.Time {
+	t := s[""]
+	if deviceUUID != "" && s[deviceUUID].After(t) {
+		t = s[deviceUUID]
+	}
+	return t
+}
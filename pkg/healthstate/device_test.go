@@ -0,0 +1,87 @@
+package healthstate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	apiv1 "github.com/leptonai/gpud/api/v1"
+)
+
+func TestEvolvePerDevice(t *testing.T) {
+	getDeviceUUID := func(event apiv1.Event) string {
+		return event.DeprecatedExtraInfo["uuid"]
+	}
+	// unhealthy if any error event is left
+	evolve := func(events apiv1.Events) apiv1.HealthState {
+		state := apiv1.HealthState{Name: "error", Health: apiv1.HealthStateTypeHealthy, Reason: "healthy"}
+		for i := len(events) - 1; i >= 0; i-- {
+			switch events[i].Name {
+			case "error":
+				state.Health = apiv1.HealthStateTypeUnhealthy
+				state.Reason = "error found"
+			case EventNameSetHealthy:
+				state.Health = apiv1.HealthStateTypeHealthy
+				state.Reason = "healthy"
+			}
+		}
+		return state
+	}
+
+	states := EvolvePerDevice(apiv1.Events{{Name: "reboot"}}, "error", "uuid", getDeviceUUID, evolve)
+	require.Len(t, states, 1)
+	assert.Equal(t, "error", states[0].Name)
+	assert.Nil(t, states[0].DeprecatedExtraInfo)
+
+	events := apiv1.Events{
+		{Name: EventNameSetHealthy, DeprecatedExtraInfo: map[string]string{"uuid": "GPU-1"}},
+		{Name: "error", DeprecatedExtraInfo: map[string]string{"uuid": "GPU-1"}},
+		{Name: "error", DeprecatedExtraInfo: map[string]string{"uuid": "GPU-0"}},
+		{Name: "error", DeprecatedExtraInfo: map[string]string{}},
+	}
+	states = EvolvePerDevice(events, "error", "uuid", getDeviceUUID, evolve)
+	require.Len(t, states, 3)
+
+	// the state without the device UUID is left as is
+	assert.Equal(t, "error", states[0].Name)
+	assert.Equal(t, apiv1.HealthStateTypeUnhealthy, states[0].Health)
+
+	assert.Equal(t, "error/GPU-0", states[1].Name)
+	assert.Equal(t, apiv1.HealthStateTypeUnhealthy, states[1].Health)
+	assert.Equal(t, map[string]string{"uuid": "GPU-0"}, states[1].DeprecatedExtraInfo)
+
+	// "SetHealthy" only applies to its device
+	assert.Equal(t, "error/GPU-1", states[2].Name)
+	assert.Equal(t, apiv1.HealthStateTypeHealthy, states[2].Health)
+	assert.Equal(t, "healthy on GPU-1", states[2].Reason)
+}
+
+func TestDeviceStateName(t *testing.T) {
+	assert.Equal(t, "error_xid/GPU-b850f46d", DeviceStateName("error_xid", "GPU-b850f46d"))
+}
+
+func TestLastSetHealthy(t *testing.T) {
+	getDeviceUUID := func(event apiv1.Event) string {
+		return event.DeprecatedExtraInfo["uuid"]
+	}
+	now := time.Now().UTC()
+
+	times := LastSetHealthy(apiv1.Events{{Name: "error", Time: metav1.Time{Time: now}}}, getDeviceUUID)
+	assert.Empty(t, times)
+	assert.True(t, times.Of("GPU-0").IsZero())
+
+	times = LastSetHealthy(apiv1.Events{
+		{Name: EventNameSetHealthy, Time: metav1.Time{Time: now.Add(-3 * time.Minute)}},
+		{Name: EventNameSetHealthy, Time: metav1.Time{Time: now.Add(-time.Minute)}, DeprecatedExtraInfo: map[string]string{"uuid": "GPU-1"}},
+		{Name: EventNameSetHealthy, Time: metav1.Time{Time: now.Add(-2 * time.Minute)}},
+		{Name: EventNameSetHealthy, Time: metav1.Time{Time: now.Add(-5 * time.Minute)}, DeprecatedExtraInfo: map[string]string{"uuid": "GPU-2"}},
+	}, getDeviceUUID)
+	assert.Equal(t, now.Add(-2*time.Minute), times.Of(""))
+	assert.Equal(t, now.Add(-2*time.Minute), times.Of("GPU-0"))
+	assert.Equal(t, now.Add(-time.Minute), times.Of("GPU-1"))
+	// the event for all the devices is newer
+	assert.Equal(t, now.Add(-2*time.Minute), times.Of("GPU-2"))
+}
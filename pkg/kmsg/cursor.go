@@ -0,0 +1,37 @@
+package kmsg
+
+import (
+	"context"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// CursorStore persists the position of the last processed kmsg record,
+// so that the records already processed before a gpud restart
+// are not processed again (in the same boot).
+type CursorStore interface {
+	// ReadCursor returns the boot ID and the sequence number of the last processed record.
+	// Returns an empty boot ID and no error, if no cursor has been persisted.
+	ReadCursor(ctx context.Context) (bootID string, seq int64, err error)
+	// WriteCursor persists the boot ID and the sequence number of the last processed record.
+	WriteCursor(ctx context.Context, bootID string, seq int64) error
+}
+
+// Cursor is the kmsg read position of the hub, for debugging.
+type Cursor struct {
+	// BootID is the boot ID of the current boot.
+	// The kmsg sequence numbers are only valid within the same boot.
+	BootID string `json:"boot_id"`
+	// LastSequenceNumber is the sequence number of the last record dispatched.
+	LastSequenceNumber int64 `json:"last_sequence_number"`
+	// PersistedSequenceNumber is the sequence number last persisted in the cursor store,
+	// up to which all the records are processed by all the subscribers.
+	PersistedSequenceNumber int64 `json:"persisted_sequence_number"`
+	// PersistedAt is the time when the cursor was last persisted.
+	PersistedAt metav1.Time `json:"persisted_at,omitempty"`
+	// ResumedSequenceNumber is the sequence number resumed from on start,
+	// zero if the hub read all the records (e.g., new boot).
+	ResumedSequenceNumber int64 `json:"resumed_sequence_number"`
+	// Skipped is the number of records skipped as already processed.
+	Skipped uint64 `json:"skipped"`
+}
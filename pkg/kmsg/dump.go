@@ -0,0 +1,281 @@
+package kmsg
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DumpBootTime is the boot time used for the captured log lines
+// that only have the relative timestamp since boot (e.g., "dmesg" output),
+// as the boot time of the captured host is unknown.
+var DumpBootTime = time.Unix(0, 0).UTC()
+
+// SinceDumpBoot returns the time since boot of the message timestamp,
+// and true if the timestamp is relative to DumpBootTime.
+func SinceDumpBoot(t time.Time) (time.Duration, bool) {
+	// no host stays up for 10 years
+	if t.Before(DumpBootTime) || t.After(DumpBootTime.Add(10*365*24*time.Hour)) {
+		return 0, false
+	}
+	return t.Sub(DumpBootTime), true
+}
+
+var (
+	// e.g., "[  123.456789] message"
+	dmesgLine = regexp.MustCompile(`^\[\s*(\d+)\.(\d+)\]\s?(.*)$`)
+	// e.g., "[Mon Jan  2 15:04:05 2024] message" ("dmesg -T")
+	dmesgHumanLine = regexp.MustCompile(`^\[([A-Z][a-z]{2} [A-Z][a-z]{2} [ \d]\d \d{2}:\d{2}:\d{2} \d{4})\]\s?(.*)$`)
+	// e.g., "2024-01-02T15:04:05.000000+00:00 host kernel: message"
+	syslogISOLine = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(?:\.\d+)?(?:Z|[+-]\d{2}:\d{2}))\s+\S+\s+kernel:\s(.*)$`)
+	// e.g., "Jan  2 15:04:05 host kernel: message"
+	syslogLine = regexp.MustCompile(`^([A-Z][a-z]{2} [ \d]\d \d{2}:\d{2}:\d{2})\s+\S+\s+kernel:\s(.*)$`)
+	// e.g., "6,3964,206764307,-;message" ("cat /dev/kmsg")
+	rawKmsgLine = regexp.MustCompile(`^\d+,\d+,\d+,[^;]*;`)
+)
+
+// ReadDumpFile reads the kernel messages from the captured log file
+// (e.g., attached to a ticket), for the offline analysis.
+// Supports "/dev/kmsg" dumps, "dmesg" outputs (with or without "-T"),
+// and the syslog kernel log files (e.g., "/var/log/kern.log").
+// The lines without any recognized timestamp are skipped.
+func ReadDumpFile(file string) ([]Message, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return readDump(f, time.Now().Year())
+}
+
+func readDump(r io.Reader, year int) ([]Message, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, readBufferSize), 1024*1024)
+
+	var msgs []Message
+	seq := 0
+	for scanner.Scan() {
+		msg, ok := parseDumpLine(scanner.Text(), year)
+		if !ok {
+			continue
+		}
+		seq++
+		if msg.SequenceNumber == 0 {
+			msg.SequenceNumber = seq
+		}
+		msgs = append(msgs, *msg)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return msgs, nil
+}
+
+func parseDumpLine(line string, year int) (*Message, bool) {
+	line = strings.TrimRight(line, "\r\n")
+	if line == "" {
+		return nil, false
+	}
+
+	if rawKmsgLine.MatchString(line) {
+		msg, err := parseLine(DumpBootTime, line)
+		if err != nil {
+			return nil, false
+		}
+		return msg, true
+	}
+
+	if m := dmesgLine.FindStringSubmatch(line); m != nil {
+		sec, _ := strconv.ParseInt(m[1], 10, 64)
+		frac := m[2]
+		for len(frac) < 9 {
+			frac += "0"
+		}
+		nsec, _ := strconv.ParseInt(frac[:9], 10, 64)
+		ts := DumpBootTime.Add(time.Duration(sec)*time.Second + time.Duration(nsec))
+		return &Message{Timestamp: metav1.NewTime(ts), Message: m[3]}, true
+	}
+
+	if m := dmesgHumanLine.FindStringSubmatch(line); m != nil {
+		ts, err := time.Parse("Mon Jan _2 15:04:05 2006", m[1])
+		if err != nil {
+			return nil, false
+		}
+		return &Message{Timestamp: metav1.NewTime(ts), Message: m[2]}, true
+	}
+
+	if m := syslogISOLine.FindStringSubmatch(line); m != nil {
+		ts, err := time.Parse(time.RFC3339Nano, m[1])
+		if err != nil {
+			return nil, false
+		}
+		return &Message{Timestamp: metav1.NewTime(ts), Message: trimDmesgTimestamp(m[2])}, true
+	}
+
+	if m := syslogLine.FindStringSubmatch(line); m != nil {
+		// classic syslog timestamps do not have the year
+		ts, err := time.Parse("2006 Jan _2 15:04:05", fmt.Sprintf("%d %s", year, m[1]))
+		if err != nil {
+			return nil, false
+		}
+		return &Message{Timestamp: metav1.NewTime(ts), Message: trimDmesgTimestamp(m[2])}, true
+	}
+
+	return nil, false
+}
+
+// ReadJournalExportFile reads the kernel messages from the exported journal,
+// for the offline analysis. Supports both "journalctl -k -o json"
+// and "journalctl -k -o export" outputs.
+func ReadJournalExportFile(file string) ([]Message, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	return readJournalExport(b)
+}
+
+func readJournalExport(b []byte) ([]Message, error) {
+	trimmed := bytes.TrimSpace(b)
+	if len(trimmed) == 0 {
+		return nil, nil
+	}
+
+	var msgs []Message
+	if trimmed[0] == '{' {
+		scanner := bufio.NewScanner(bytes.NewReader(trimmed))
+		scanner.Buffer(make([]byte, readBufferSize), 1024*1024)
+		for scanner.Scan() {
+			msg, err := parseJournalLine(scanner.Text())
+			if err != nil {
+				return nil, err
+			}
+			if msg != nil {
+				msgs = append(msgs, *msg)
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	} else {
+		entries, err := parseJournalExportEntries(b)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			msg, err := entry.toMessage()
+			if err != nil {
+				return nil, err
+			}
+			if msg != nil {
+				msgs = append(msgs, *msg)
+			}
+		}
+	}
+
+	for i := range msgs {
+		if msgs[i].SequenceNumber == 0 {
+			msgs[i].SequenceNumber = i + 1
+		}
+	}
+	return msgs, nil
+}
+
+type journalExportEntry map[string]string
+
+func (e journalExportEntry) toMessage() (*Message, error) {
+	message := e["MESSAGE"]
+	if message == "" {
+		return nil, nil
+	}
+	msg := &Message{Message: message}
+
+	if p := e["PRIORITY"]; p != "" {
+		v, err := strconv.Atoi(p)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse %q as priority: %w", p, err)
+		}
+		msg.Priority = v
+	}
+	if ts := e["__REALTIME_TIMESTAMP"]; ts != "" {
+		us, err := strconv.ParseInt(ts, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse %q as timestamp: %w", ts, err)
+		}
+		msg.Timestamp = metav1.NewTime(time.UnixMicro(us))
+	}
+	if s := e["__SEQNUM"]; s != "" {
+		v, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse %q as sequence number: %w", s, err)
+		}
+		msg.SequenceNumber = v
+	}
+	return msg, nil
+}
+
+// parseJournalExportEntries parses the journal export format.
+// Each entry is a list of "KEY=VALUE" lines, separated by an empty line.
+// Binary fields are encoded as "KEY", followed by the little-endian
+// 64-bit length of the value, the value, and a newline.
+// ref. https://systemd.io/JOURNAL_EXPORT_FORMATS/
+func parseJournalExportEntries(b []byte) ([]journalExportEntry, error) {
+	var entries []journalExportEntry
+	cur := make(journalExportEntry)
+
+	for len(b) > 0 {
+		idx := bytes.IndexByte(b, '\n')
+		if idx < 0 {
+			idx = len(b)
+		}
+		line := b[:idx]
+		if idx < len(b) {
+			b = b[idx+1:]
+		} else {
+			b = nil
+		}
+
+		if len(line) == 0 {
+			if len(cur) > 0 {
+				entries = append(entries, cur)
+				cur = make(journalExportEntry)
+			}
+			continue
+		}
+
+		if eq := bytes.IndexByte(line, '='); eq >= 0 {
+			cur[string(line[:eq])] = string(line[eq+1:])
+			continue
+		}
+
+		// binary field
+		if len(b) < 8 {
+			return nil, errors.New("truncated journal export binary field")
+		}
+		size := binary.LittleEndian.Uint64(b[:8])
+		b = b[8:]
+		if uint64(len(b)) < size {
+			return nil, errors.New("truncated journal export binary field")
+		}
+		cur[string(line)] = string(b[:size])
+		b = b[size:]
+		if len(b) > 0 && b[0] == '\n' {
+			b = b[1:]
+		}
+	}
+	if len(cur) > 0 {
+		entries = append(entries, cur)
+	}
+	return entries, nil
+}
@@ -0,0 +1,115 @@
+package kmsg
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadDumpFileKmsg(t *testing.T) {
+	msgs, err := ReadDumpFile("testdata/kmsg.1.log")
+	require.NoError(t, err)
+	require.NotEmpty(t, msgs)
+
+	assert.Equal(t, "nvidia-nvswitch2: open (major=510)", msgs[0].Message)
+	assert.Equal(t, 3964, msgs[0].SequenceNumber)
+	since, ok := SinceDumpBoot(msgs[0].Timestamp.Time)
+	require.True(t, ok)
+	assert.Equal(t, 206764307*time.Microsecond, since)
+}
+
+func TestReadDump(t *testing.T) {
+	input := strings.Join([]string{
+		"[  123.456789] NVRM: Xid (PCI:0000:05:00): 79, pid=123, GPU has fallen off the bus.",
+		"[Tue Jan  2 15:04:05 2024] Out of memory: Killed process 123",
+		"2024-01-02T15:04:06.500000+00:00 host kernel: [  124.000000] VFS: file-max limit 100 reached",
+		"Jan  2 15:04:07 host kernel: mlx5_core 0000:5c:00.0: mlx5_pcie_event:299: Detected insufficient power on the PCIe slot (27W).",
+		"no timestamp, skipped",
+		"",
+	}, "\n")
+
+	msgs, err := readDump(strings.NewReader(input), 2024)
+	require.NoError(t, err)
+	require.Len(t, msgs, 4)
+
+	assert.Equal(t, "NVRM: Xid (PCI:0000:05:00): 79, pid=123, GPU has fallen off the bus.", msgs[0].Message)
+	since, ok := SinceDumpBoot(msgs[0].Timestamp.Time)
+	require.True(t, ok)
+	assert.Equal(t, 123456789*time.Microsecond, since)
+
+	assert.Equal(t, "Out of memory: Killed process 123", msgs[1].Message)
+	assert.Equal(t, time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC), msgs[1].Timestamp.UTC())
+	_, ok = SinceDumpBoot(msgs[1].Timestamp.Time)
+	assert.False(t, ok)
+
+	assert.Equal(t, "VFS: file-max limit 100 reached", msgs[2].Message)
+	assert.Equal(t, time.Date(2024, 1, 2, 15, 4, 6, 500000000, time.UTC), msgs[2].Timestamp.UTC())
+
+	assert.Equal(t, "mlx5_core 0000:5c:00.0: mlx5_pcie_event:299: Detected insufficient power on the PCIe slot (27W).", msgs[3].Message)
+	assert.Equal(t, time.Date(2024, 1, 2, 15, 4, 7, 0, time.UTC), msgs[3].Timestamp.UTC())
+
+	for i, msg := range msgs {
+		assert.Equal(t, i+1, msg.SequenceNumber)
+	}
+}
+
+func TestReadJournalExportJSON(t *testing.T) {
+	input := `{"__REALTIME_TIMESTAMP":"1700000000000000","PRIORITY":"4","MESSAGE":"NVRM: Xid (PCI:0000:05:00): 79"}
+{"__REALTIME_TIMESTAMP":"1700000001000000","PRIORITY":"6","MESSAGE":"hello"}
+`
+	msgs, err := readJournalExport([]byte(input))
+	require.NoError(t, err)
+	require.Len(t, msgs, 2)
+	assert.Equal(t, "NVRM: Xid (PCI:0000:05:00): 79", msgs[0].Message)
+	assert.Equal(t, 1, msgs[0].SequenceNumber)
+	assert.Equal(t, int64(1700000001), msgs[1].Timestamp.Unix())
+	assert.Equal(t, 2, msgs[1].SequenceNumber)
+
+	msgs, err = readJournalExport(nil)
+	require.NoError(t, err)
+	assert.Empty(t, msgs)
+}
+
+func TestReadJournalExportFormat(t *testing.T) {
+	var buf bytes.Buffer
+	buf.WriteString("__CURSOR=s=abc\n__REALTIME_TIMESTAMP=1700000000000000\n__SEQNUM=10\nPRIORITY=4\n_TRANSPORT=kernel\nMESSAGE=NVRM: Xid (PCI:0000:05:00): 79\n\n")
+
+	// binary field
+	binMsg := "line1\nline2"
+	buf.WriteString("__REALTIME_TIMESTAMP=1700000001000000\nMESSAGE\n")
+	size := make([]byte, 8)
+	binary.LittleEndian.PutUint64(size, uint64(len(binMsg)))
+	buf.Write(size)
+	buf.WriteString(binMsg)
+	buf.WriteString("\n\n")
+
+	// no message
+	buf.WriteString("__REALTIME_TIMESTAMP=1700000002000000\n")
+
+	msgs, err := readJournalExport(buf.Bytes())
+	require.NoError(t, err)
+	require.Len(t, msgs, 2)
+
+	assert.Equal(t, "NVRM: Xid (PCI:0000:05:00): 79", msgs[0].Message)
+	assert.Equal(t, 10, msgs[0].SequenceNumber)
+	assert.Equal(t, 4, msgs[0].Priority)
+	assert.Equal(t, int64(1700000000), msgs[0].Timestamp.Unix())
+
+	assert.Equal(t, binMsg, msgs[1].Message)
+	assert.Equal(t, 2, msgs[1].SequenceNumber)
+
+	_, err = readJournalExport([]byte("MESSAGE\n\x05\x00"))
+	assert.Error(t, err)
+}
+
+func TestReadDumpFileErrors(t *testing.T) {
+	_, err := ReadDumpFile("testdata/does-not-exist.log")
+	assert.Error(t, err)
+	_, err = ReadJournalExportFile("testdata/does-not-exist.json")
+	assert.Error(t, err)
+}
@@ -0,0 +1,289 @@
+package kmsg
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/shirou/gopsutil/v4/host"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/leptonai/gpud/pkg/log"
+)
+
+const defaultFilePollInterval = time.Second
+
+var _ Watcher = &fileWatcher{}
+
+// fileWatcher tails a plain log file (e.g., "/var/log/kern.log"),
+// similar to "tail -F", for the hosts without "/dev/kmsg"
+// or systemd journal (e.g., containers with the host log directory mounted).
+type fileWatcher struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	path         string
+	pollInterval time.Duration
+
+	// set true for the kernel log file, to read the file from the beginning,
+	// and only read the kernel messages of the current boot
+	// set false to only read the lines appended after the watch starts
+	kernelLog bool
+	// the syslog lines logged before the boot time are skipped
+	// (zero to read all the lines), only used for the kernel log file
+	bootTime time.Time
+
+	seq atomic.Int64
+
+	watchStarted atomic.Bool
+}
+
+// NewFileWatcher creates a new watcher that tails the kernel log file.
+// The existing lines of the current boot are read first,
+// same as reading the "/dev/kmsg" ring buffer from the start,
+// and then the lines appended after the watch starts.
+// The syslog lines from the previous boots or without the kernel tag are skipped,
+// and the replayed events are deduplicated by the syncer
+// with the syslog timestamps.
+func NewFileWatcher(path string) (Watcher, error) {
+	cctx, ccancel := context.WithTimeout(context.Background(), 15*time.Second)
+	bt, err := host.BootTimeWithContext(cctx)
+	ccancel()
+	if err != nil {
+		return nil, err
+	}
+	return newFileWatcher(path, defaultFilePollInterval, true, time.Unix(int64(bt), 0))
+}
+
+// NewTailFileWatcher creates a new watcher that tails any log file
+// (e.g., the vendor log file of the user-defined log rules).
+// Only the lines appended after the watch starts are read,
+// and the lines are not required to be the kernel messages.
+func NewTailFileWatcher(path string) (Watcher, error) {
+	return newFileWatcher(path, defaultFilePollInterval, false, time.Time{})
+}
+
+func newFileWatcher(path string, pollInterval time.Duration, kernelLog bool, bootTime time.Time) (*fileWatcher, error) {
+	if path == "" {
+		return nil, errors.New("kmsg file path is empty")
+	}
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &fileWatcher{
+		ctx:          ctx,
+		cancel:       cancel,
+		path:         path,
+		pollInterval: pollInterval,
+		kernelLog:    kernelLog,
+		bootTime:     bootTime,
+	}, nil
+}
+
+func (w *fileWatcher) Watch() (<-chan Message, error) {
+	if !w.watchStarted.CompareAndSwap(false, true) {
+		return nil, ErrWatcherAlreadyStarted
+	}
+
+	f, err := os.Open(w.path)
+	if err != nil {
+		return nil, err
+	}
+	if !w.kernelLog {
+		if _, err = f.Seek(0, io.SeekEnd); err != nil {
+			_ = f.Close()
+			return nil, err
+		}
+	}
+
+	ch := make(chan Message, 2048)
+	go w.follow(f, ch)
+	return ch, nil
+}
+
+func (w *fileWatcher) Close() error {
+	w.cancel()
+	return nil
+}
+
+func (w *fileWatcher) follow(f *os.File, ch chan<- Message) {
+	log.Logger.Infow("tailing kernel log file", "path", w.path)
+
+	defer close(ch)
+	defer func() {
+		_ = f.Close()
+	}()
+
+	deduper := newDeduper(defaultCacheExpiration, defaultCachePurgeInterval)
+	reader := bufio.NewReaderSize(f, readBufferSize)
+
+	// partial line, not yet terminated by the newline
+	var pending string
+
+	ticker := time.NewTicker(w.pollInterval)
+	defer ticker.Stop()
+	for {
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				// incomplete line, wait for the rest
+				pending += line
+				break
+			}
+			line = pending + line
+			pending = ""
+
+			msg := w.parseLine(line)
+			if msg == nil {
+				continue
+			}
+			if occurrences := deduper.addCache(*msg); occurrences > 1 {
+				log.Logger.Debugw("skipping duplicate kernel log message", "occurrences", occurrences, "timestamp", msg.Timestamp, "message", msg.Message)
+				continue
+			}
+
+			select {
+			case ch <- *msg:
+			case <-w.ctx.Done():
+				return
+			}
+		}
+
+		select {
+		case <-w.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		// reopen the file if rotated or truncated
+		rotated, err := w.rotated(f)
+		if err != nil {
+			log.Logger.Debugw("failed to check kernel log file rotation", "path", w.path, "error", err)
+			continue
+		}
+		if !rotated {
+			continue
+		}
+
+		nf, err := os.Open(w.path)
+		if err != nil {
+			log.Logger.Warnw("failed to reopen kernel log file", "path", w.path, "error", err)
+			continue
+		}
+		log.Logger.Infow("kernel log file rotated, reopened", "path", w.path)
+		_ = f.Close()
+		f = nf
+		reader.Reset(f)
+		pending = ""
+	}
+}
+
+// rotated returns true if the file at the path has been replaced
+// (e.g., logrotate), or truncated since the last read.
+func (w *fileWatcher) rotated(f *os.File) (bool, error) {
+	cur, err := f.Stat()
+	if err != nil {
+		return false, err
+	}
+	latest, err := os.Stat(w.path)
+	if err != nil {
+		return false, err
+	}
+	if !os.SameFile(cur, latest) {
+		return true, nil
+	}
+
+	offset, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return false, err
+	}
+	return latest.Size() < offset, nil
+}
+
+func (w *fileWatcher) parseLine(line string) *Message {
+	ts, message, fromUser := parseFileLine(line, time.Now())
+	if message == "" {
+		return nil
+	}
+
+	switch {
+	case !w.kernelLog:
+		ts = time.Now()
+	case fromUser:
+		return nil
+	case ts.IsZero():
+		ts = time.Now()
+	case ts.Before(w.bootTime):
+		// logged in the previous boot
+		return nil
+	}
+	return &Message{
+		Timestamp:      metav1.NewTime(ts),
+		SequenceNumber: int(w.seq.Add(1)),
+		Message:        message,
+	}
+}
+
+var (
+	// e.g., "2024-01-02T15:04:05.000000+00:00 host sshd[123]: "
+	syslogISOHeader = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}\S*\s+\S+\s+\S+`)
+	// e.g., "Jan  2 15:04:05 host sshd[123]: "
+	syslogHeader = regexp.MustCompile(`^[A-Z][a-z]{2} [ \d]\d \d{2}:\d{2}:\d{2}\s+\S+\s+\S+`)
+
+	// e.g., "[  123.456789] " in the dmesg output
+	dmesgTimestampPrefix = regexp.MustCompile(`^\[\s*\d+\.\d+\]\s?`)
+)
+
+// parseFileLine parses the syslog timestamp and strips the syslog and dmesg prefixes
+// from the log line, so that the same kmsg matchers can be used.
+// Returns the zero time if the line has no syslog timestamp (e.g., dmesg output).
+// Returns true with the whole line, if the line is logged by the user-space program
+// rather than the kernel (e.g., "NVRM: Xid" written to syslog by any process).
+func parseFileLine(line string, now time.Time) (time.Time, string, bool) {
+	line = strings.TrimRight(line, "\r\n")
+
+	if m := syslogISOLine.FindStringSubmatch(line); m != nil {
+		ts, err := time.Parse(time.RFC3339Nano, m[1])
+		if err != nil {
+			return time.Time{}, "", false
+		}
+		return ts, trimDmesgTimestamp(m[2]), false
+	}
+
+	if m := syslogLine.FindStringSubmatch(line); m != nil {
+		// classic syslog timestamps do not have the year, and are in the local time
+		ts, err := time.ParseInLocation("2006 Jan _2 15:04:05", fmt.Sprintf("%d %s", now.Year(), m[1]), now.Location())
+		if err != nil {
+			return time.Time{}, "", false
+		}
+		// logged in the last year (e.g., read on Jan 1st)
+		if ts.After(now.Add(24 * time.Hour)) {
+			ts = ts.AddDate(-1, 0, 0)
+		}
+		return ts, trimDmesgTimestamp(m[2]), false
+	}
+
+	// syslog line without the kernel tag
+	if syslogISOHeader.MatchString(line) || syslogHeader.MatchString(line) {
+		return time.Time{}, line, true
+	}
+
+	return time.Time{}, trimDmesgTimestamp(line), false
+}
+
+// trimDmesgTimestamp strips the dmesg timestamp prefix from the message, if any.
+func trimDmesgTimestamp(message string) string {
+	if loc := dmesgTimestampPrefix.FindStringIndex(message); loc != nil {
+		return message[loc[1]:]
+	}
+	return message
+}
@@ -0,0 +1,193 @@
+package kmsg
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFileLine(t *testing.T) {
+	now := time.Date(2024, time.January, 3, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		line         string
+		wantTS       time.Time
+		want         string
+		wantFromUser bool
+	}{
+		{
+			line:   "Jan  2 15:04:05 host kernel: [  123.456789] NVRM: Xid (PCI:0000:05:00): 79, pid=123\n",
+			wantTS: time.Date(2024, time.January, 2, 15, 4, 5, 0, time.UTC),
+			want:   "NVRM: Xid (PCI:0000:05:00): 79, pid=123",
+		},
+		{
+			line:   "2024-01-02T15:04:05.000000+00:00 host kernel: NVRM: Xid (PCI:0000:05:00): 79\n",
+			wantTS: time.Date(2024, time.January, 2, 15, 4, 5, 0, time.UTC),
+			want:   "NVRM: Xid (PCI:0000:05:00): 79",
+		},
+		{
+			// logged in the last year
+			line:   "Dec 31 23:59:59 host kernel: hello",
+			wantTS: time.Date(2023, time.December, 31, 23, 59, 59, 0, time.UTC),
+			want:   "hello",
+		},
+		{
+			// user-space programs are not the kernel
+			line:         "Jan  2 15:04:05 host myapp[123]: NVRM: Xid (PCI:0000:05:00): 79\n",
+			want:         "Jan  2 15:04:05 host myapp[123]: NVRM: Xid (PCI:0000:05:00): 79",
+			wantFromUser: true,
+		},
+		{
+			line:         "2024-01-02T15:04:05.000000+00:00 host myapp[123]: kernel: NVRM: Xid (PCI:0000:05:00): 79\n",
+			want:         "2024-01-02T15:04:05.000000+00:00 host myapp[123]: kernel: NVRM: Xid (PCI:0000:05:00): 79",
+			wantFromUser: true,
+		},
+		{
+			line: "[  123.456789] Out of memory: Killed process 123\r\n",
+			want: "Out of memory: Killed process 123",
+		},
+		{
+			line: "plain message",
+			want: "plain message",
+		},
+		{
+			line: "\n",
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		ts, message, fromUser := parseFileLine(tt.line, now)
+		assert.Equal(t, tt.want, message, tt.line)
+		assert.True(t, tt.wantTS.Equal(ts), tt.line)
+		assert.Equal(t, tt.wantFromUser, fromUser, tt.line)
+	}
+}
+
+func TestNewFileWatcherErrors(t *testing.T) {
+	_, err := NewFileWatcher("")
+	assert.Error(t, err)
+
+	_, err = NewFileWatcher(filepath.Join(t.TempDir(), "does-not-exist"))
+	assert.Error(t, err)
+}
+
+func receive(t *testing.T, ch <-chan Message) Message {
+	t.Helper()
+	select {
+	case msg := <-ch:
+		return msg
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for message")
+	}
+	return Message{}
+}
+
+func appendFile(t *testing.T, path string, s string) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	_, err = f.WriteString(s)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+}
+
+func TestFileWatcherFollow(t *testing.T) {
+	bootTime := time.Now().Add(-time.Hour)
+	before := bootTime.Add(-time.Hour).Format(time.RFC3339)
+	after := bootTime.Add(time.Minute).Format(time.RFC3339)
+
+	path := filepath.Join(t.TempDir(), "kern.log")
+	require.NoError(t, os.WriteFile(path, []byte(
+		before+" host kernel: previous boot\n"+
+			after+" host kernel: current boot\n"+
+			after+" host myapp: not kernel\n",
+	), 0644))
+
+	w, err := newFileWatcher(path, 10*time.Millisecond, true, bootTime)
+	require.NoError(t, err)
+	defer w.Close()
+
+	ch, err := w.Watch()
+	require.NoError(t, err)
+	_, err = w.Watch()
+	require.ErrorIs(t, err, ErrWatcherAlreadyStarted)
+
+	// existing lines of the current boot are read
+	msg := receive(t, ch)
+	assert.Equal(t, "current boot", msg.Message)
+	assert.Equal(t, 1, msg.SequenceNumber)
+	assert.Equal(t, after, msg.Timestamp.Format(time.RFC3339))
+
+	// partial lines are buffered
+	appendFile(t, path, "first")
+	appendFile(t, path, " line\n"+after+" host kernel: second line\n")
+
+	msg = receive(t, ch)
+	assert.Equal(t, "first line", msg.Message)
+	assert.Equal(t, 2, msg.SequenceNumber)
+	msg = receive(t, ch)
+	assert.Equal(t, "second line", msg.Message)
+	assert.Equal(t, 3, msg.SequenceNumber)
+
+	// rotated, then read from the beginning of the new file
+	require.NoError(t, os.Rename(path, path+".1"))
+	require.NoError(t, os.WriteFile(path, []byte(after+" host kernel: after rotation\n"), 0644))
+	msg = receive(t, ch)
+	assert.Equal(t, "after rotation", msg.Message)
+
+	// truncated
+	require.NoError(t, os.WriteFile(path, nil, 0644))
+	time.Sleep(100 * time.Millisecond)
+	appendFile(t, path, "after truncate\n")
+	msg = receive(t, ch)
+	assert.Equal(t, "after truncate", msg.Message)
+
+	require.NoError(t, w.Close())
+	select {
+	case _, ok := <-ch:
+		assert.False(t, ok)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the channel to be closed")
+	}
+}
+
+func TestFileWatcherDmesg(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dmesg.log")
+	require.NoError(t, os.WriteFile(path, []byte("[    1.000000] hello\n[    2.000000] hello\n[    3.000000] world\n"), 0644))
+
+	w, err := newFileWatcher(path, 10*time.Millisecond, true, time.Time{})
+	require.NoError(t, err)
+	defer w.Close()
+
+	ch, err := w.Watch()
+	require.NoError(t, err)
+
+	// duplicate messages are skipped
+	assert.Equal(t, "hello", receive(t, ch).Message)
+	assert.Equal(t, "world", receive(t, ch).Message)
+}
+
+func TestTailFileWatcher(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vendor.log")
+	require.NoError(t, os.WriteFile(path, []byte("Jan  2 15:04:05 host vendor: before the watch\n"), 0644))
+
+	w, err := newFileWatcher(path, 10*time.Millisecond, false, time.Time{})
+	require.NoError(t, err)
+	defer w.Close()
+
+	ch, err := w.Watch()
+	require.NoError(t, err)
+
+	// existing lines are skipped, and the lines without the kernel tag are read
+	appendFile(t, path, "Jan  2 15:04:06 host vendor: vendor error\nJan  2 15:04:07 host kernel: kernel error\n")
+
+	msg := receive(t, ch)
+	assert.Equal(t, "Jan  2 15:04:06 host vendor: vendor error", msg.Message)
+	assert.Equal(t, 1, msg.SequenceNumber)
+	msg = receive(t, ch)
+	assert.Equal(t, "kernel error", msg.Message)
+	assert.Equal(t, 2, msg.SequenceNumber)
+}
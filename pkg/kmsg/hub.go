@@ -0,0 +1,583 @@
+package kmsg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/leptonai/gpud/pkg/eventstore"
+	"github.com/leptonai/gpud/pkg/log"
+)
+
+var (
+	ErrHubClosed               = errors.New("kmsg hub closed")
+	ErrHubAlreadyStarted       = errors.New("kmsg hub already started")
+	ErrSubscriberAlreadyExists = errors.New("kmsg subscriber already exists")
+)
+
+const (
+	defaultSubscriberBufferSize = 4096
+
+	// interval to persist the kmsg cursor, if the cursor store is set
+	defaultCursorSyncInterval = 10 * time.Second
+
+	// wait this long for a slow subscriber before dropping the message
+	// so that one slow subscriber does not block all the others
+	defaultSubscriberSendTimeout = time.Second
+)
+
+// Hub reads the kmsg device once and fans out the messages
+// to all the registered subscribers (e.g., component matchers),
+// rather than each component opening and parsing "/dev/kmsg" on its own.
+//
+// Subscribers must be registered before "Start" to receive
+// the messages that are already in the ring buffer.
+type Hub struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// source of the kernel messages (e.g., "kmsg", "journal")
+	source  string
+	watcher Watcher
+	started atomic.Bool
+	closed  atomic.Bool
+	done    chan struct{}
+
+	// sequence number of the last message read from the source
+	lastSeq atomic.Int64
+	// sequence number of the last message sent (or dropped) to all the subscribers
+	dispatchedSeq atomic.Int64
+
+	bootID      string
+	cursorStore CursorStore
+	// set if resumed from the persisted cursor of the same boot,
+	// in which case the messages with the sequence number less than or equal to
+	// "resumeSeq" were already processed before the restart
+	// (otherwise, the first record of the boot with the sequence number 0 is read)
+	resumed   bool
+	resumeSeq int64
+	skipped   atomic.Uint64
+
+	cursorMu        sync.RWMutex
+	persistedSeq    int64
+	cursorUpdatedAt time.Time
+
+	mu   sync.RWMutex
+	subs map[string]*subscriber
+	// set when a subscriber is removed before processing all the messages
+	// delivered to it (e.g., closed on shutdown), so that the cursor
+	// is never persisted beyond the messages it has not processed
+	held    bool
+	heldSeq int64
+}
+
+type subscriber struct {
+	name string
+	ch   chan Message
+
+	// held while sending to the channel, so that the channel
+	// is never closed in the middle of the send
+	mu sync.Mutex
+	// closed when the subscriber is removed,
+	// to abort the pending send without waiting for the timeout
+	done      chan struct{}
+	closeOnce sync.Once
+
+	delivered atomic.Uint64
+	dropped   atomic.Uint64
+	lastSeq   atomic.Int64
+	// sequence number of the last message processed by the subscriber (see "Ack")
+	ackedSeq atomic.Int64
+}
+
+// SubscriberStats is the delivery stats of a hub subscriber.
+type SubscriberStats struct {
+	Name string `json:"name"`
+	// Delivered is the number of messages delivered to the subscriber.
+	Delivered uint64 `json:"delivered"`
+	// Dropped is the number of messages dropped because the subscriber was too slow.
+	Dropped uint64 `json:"dropped"`
+	// Lag is the number of messages queued but not yet consumed by the subscriber.
+	Lag int `json:"lag"`
+	// LastSequenceNumber is the kmsg sequence number of the last message delivered.
+	LastSequenceNumber int64 `json:"last_sequence_number"`
+	// AckedSequenceNumber is the kmsg sequence number of the last message
+	// acknowledged as processed by the subscriber.
+	AckedSequenceNumber int64 `json:"acked_sequence_number"`
+}
+
+// NewHub creates a new hub that reads from "/dev/kmsg",
+// or from the source specified by the "WithSource" option.
+// The underlying source is opened once, regardless of the number of subscribers.
+func NewHub(ctx context.Context, opts ...HubOpOption) (*Hub, error) {
+	op := &HubOp{}
+	if err := op.applyOpts(opts); err != nil {
+		return nil, err
+	}
+
+	w, err := NewSourceWatcher(op.source, op.filePath)
+	if err != nil {
+		return nil, err
+	}
+	return newHub(ctx, w, opts...)
+}
+
+func newHub(ctx context.Context, watcher Watcher, opts ...HubOpOption) (*Hub, error) {
+	op := &HubOp{}
+	if err := op.applyOpts(opts); err != nil {
+		return nil, err
+	}
+
+	cctx, ccancel := context.WithCancel(ctx)
+	h := &Hub{
+		ctx:         cctx,
+		cancel:      ccancel,
+		source:      op.source,
+		watcher:     watcher,
+		done:        make(chan struct{}),
+		bootID:      op.bootID,
+		cursorStore: op.cursorStore,
+		subs:        make(map[string]*subscriber),
+	}
+
+	// sequence numbers are only persistent for "/dev/kmsg" within the same boot
+	// other sources assign their own sequence numbers
+	if h.cursorStore != nil && h.source != SourceKmsg {
+		log.Logger.Infow("kmsg cursor is not supported for the source, ignoring", "source", h.source)
+		h.cursorStore = nil
+	}
+
+	if h.cursorStore != nil {
+		rctx, rcancel := context.WithTimeout(ctx, 15*time.Second)
+		prevBootID, prevSeq, err := h.cursorStore.ReadCursor(rctx)
+		rcancel()
+		if err != nil {
+			ccancel()
+			return nil, fmt.Errorf("failed to read kmsg cursor: %w", err)
+		}
+
+		// sequence numbers are reset on reboot,
+		// thus only resume for the same boot
+		if prevBootID != "" && prevBootID == h.bootID {
+			h.resumed = true
+			h.resumeSeq = prevSeq
+			h.persistedSeq = prevSeq
+			h.dispatchedSeq.Store(prevSeq)
+			log.Logger.Infow("resuming kmsg from the persisted cursor", "bootID", h.bootID, "sequenceNumber", prevSeq)
+		} else {
+			log.Logger.Infow("no kmsg cursor for the current boot, reading all", "bootID", h.bootID, "previousBootID", prevBootID)
+		}
+	}
+
+	return h, nil
+}
+
+// Start starts reading the kmsg and dispatching the messages to the subscribers.
+func (h *Hub) Start() error {
+	if h.closed.Load() {
+		return ErrHubClosed
+	}
+	if !h.started.CompareAndSwap(false, true) {
+		return ErrHubAlreadyStarted
+	}
+
+	ch, err := h.watcher.Watch()
+	if err != nil {
+		return err
+	}
+	go h.dispatch(ch)
+	if h.cursorStore != nil {
+		go h.syncCursor(defaultCursorSyncInterval)
+	}
+	return nil
+}
+
+func (h *Hub) dispatch(ch <-chan Message) {
+	defer close(h.done)
+	defer h.closeSubscribers()
+
+	for {
+		select {
+		case <-h.ctx.Done():
+			return
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+			if h.resumed && int64(msg.SequenceNumber) <= h.resumeSeq {
+				// already processed before the restart
+				h.skipped.Add(1)
+				continue
+			}
+			h.lastSeq.Store(int64(msg.SequenceNumber))
+
+			// send without holding the lock, since a slow subscriber
+			// would otherwise block the (un)subscribe and the stats
+			// for up to the send timeout
+			for _, sub := range h.snapshotSubscribers() {
+				h.send(sub, msg)
+			}
+			h.dispatchedSeq.Store(int64(msg.SequenceNumber))
+		}
+	}
+}
+
+// snapshotSubscribers returns the current subscribers.
+func (h *Hub) snapshotSubscribers() []*subscriber {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	subs := make([]*subscriber, 0, len(h.subs))
+	for _, sub := range h.subs {
+		subs = append(subs, sub)
+	}
+	return subs
+}
+
+func (h *Hub) send(sub *subscriber, msg Message) {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+
+	select {
+	case <-sub.done:
+		// unsubscribed after the snapshot
+		return
+	default:
+	}
+
+	select {
+	case sub.ch <- msg:
+	default:
+		// slow path: wait briefly before dropping
+		select {
+		case sub.ch <- msg:
+		case <-h.ctx.Done():
+			return
+		case <-sub.done:
+			return
+		case <-time.After(defaultSubscriberSendTimeout):
+			dropped := sub.dropped.Add(1)
+			metricSubscriberDropped.WithLabelValues(sub.name).Inc()
+			log.Logger.Warnw("kmsg subscriber too slow, dropped message", "subscriber", sub.name, "sequenceNumber", msg.SequenceNumber, "dropped", dropped)
+			return
+		}
+	}
+
+	sub.delivered.Add(1)
+	sub.lastSeq.Store(int64(msg.SequenceNumber))
+	metricSubscriberDelivered.WithLabelValues(sub.name).Inc()
+	metricSubscriberLag.WithLabelValues(sub.name).Set(float64(len(sub.ch)))
+}
+
+// close aborts the pending send (if any) and closes the subscriber channel.
+func (s *subscriber) close() {
+	s.closeOnce.Do(func() {
+		close(s.done)
+
+		s.mu.Lock()
+		close(s.ch)
+		s.mu.Unlock()
+	})
+}
+
+func (h *Hub) closeSubscribers() {
+	h.mu.Lock()
+	subs := make([]*subscriber, 0, len(h.subs))
+	for name, sub := range h.subs {
+		subs = append(subs, sub)
+		h.removeSubscriberLocked(name, sub)
+	}
+	h.mu.Unlock()
+
+	for _, sub := range subs {
+		sub.close()
+	}
+}
+
+// subscribe registers a new subscriber with the unique name.
+func (h *Hub) subscribe(name string) (*subscriber, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed.Load() {
+		return nil, ErrHubClosed
+	}
+
+	if _, ok := h.subs[name]; ok {
+		return nil, fmt.Errorf("%w: %q", ErrSubscriberAlreadyExists, name)
+	}
+	sub := &subscriber{
+		name: name,
+		ch:   make(chan Message, defaultSubscriberBufferSize),
+		done: make(chan struct{}),
+	}
+	// the messages dispatched before the subscription are not for this subscriber
+	sub.ackedSeq.Store(h.dispatchedSeq.Load())
+	h.subs[name] = sub
+	return sub, nil
+}
+
+// removeSubscriberLocked removes the subscriber from the hub,
+// and holds back the cursor at the last message acknowledged by the subscriber,
+// if it has not processed all the messages delivered to it.
+// Must be called with the hub lock held.
+func (h *Hub) removeSubscriberLocked(name string, sub *subscriber) {
+	delete(h.subs, name)
+
+	acked := sub.ackedSeq.Load()
+	if acked >= sub.lastSeq.Load() {
+		return
+	}
+	if !h.held || acked < h.heldSeq {
+		h.held = true
+		h.heldSeq = acked
+	}
+}
+
+// unsubscribe removes the subscriber and closes its channel.
+func (h *Hub) unsubscribe(name string) {
+	h.mu.Lock()
+	sub, ok := h.subs[name]
+	if ok {
+		h.removeSubscriberLocked(name, sub)
+	}
+	h.mu.Unlock()
+
+	if ok {
+		sub.close()
+	}
+}
+
+// NewWatcher registers a new subscriber with the unique name,
+// and returns the watcher that receives all the kmsg messages read by the hub.
+// Closing the watcher unregisters the subscriber.
+func (h *Hub) NewWatcher(name string) (Watcher, error) {
+	sub, err := h.subscribe(name)
+	if err != nil {
+		return nil, err
+	}
+	return &hubWatcher{hub: h, sub: sub}, nil
+}
+
+// NewSyncer registers a new subscriber with the unique name,
+// and syncs the kernel messages matched by the match function
+// to the event bucket.
+func (h *Hub) NewSyncer(ctx context.Context, name string, matchFunc MatchFunc, eventBucket eventstore.Bucket) (*Syncer, error) {
+	w, err := h.NewWatcher(name)
+	if err != nil {
+		return nil, err
+	}
+	return newSyncer(ctx, w, matchFunc, eventBucket)
+}
+
+// Source returns the source of the kernel messages read by the hub
+// (e.g., "kmsg", "journal", "file").
+func (h *Hub) Source() string {
+	return h.source
+}
+
+// LastSequenceNumber returns the sequence number of the last message read by the hub.
+func (h *Hub) LastSequenceNumber() int64 {
+	return h.lastSeq.Load()
+}
+
+// Stats returns the delivery stats of all the subscribers,
+// sorted by the subscriber name.
+func (h *Hub) Stats() []SubscriberStats {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	stats := make([]SubscriberStats, 0, len(h.subs))
+	for _, sub := range h.subs {
+		stats = append(stats, SubscriberStats{
+			Name:                sub.name,
+			Delivered:           sub.delivered.Load(),
+			Dropped:             sub.dropped.Load(),
+			Lag:                 len(sub.ch),
+			LastSequenceNumber:  sub.lastSeq.Load(),
+			AckedSequenceNumber: sub.ackedSeq.Load(),
+		})
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Name < stats[j].Name
+	})
+	return stats
+}
+
+// Close stops reading the kmsg, and closes all the subscriber channels.
+// The position acknowledged by all the subscribers (including the ones closed
+// with the unprocessed messages) is persisted, if the cursor store is set.
+func (h *Hub) Close() error {
+	if !h.closed.CompareAndSwap(false, true) {
+		return nil
+	}
+	h.cancel()
+	err := h.watcher.Close()
+
+	// if the hub has never started, the dispatch goroutine
+	// is not running to close the subscriber channels
+	if !h.started.Load() {
+		h.closeSubscribers()
+		return err
+	}
+
+	<-h.done
+	if h.cursorStore != nil {
+		if werr := h.persistCursor(context.Background()); werr != nil {
+			log.Logger.Warnw("failed to persist kmsg cursor", "error", werr)
+		}
+	}
+	return err
+}
+
+// syncCursor periodically persists the sequence number
+// of the last message acknowledged by all the subscribers.
+func (h *Hub) syncCursor(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-h.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := h.persistCursor(h.ctx); err != nil {
+			log.Logger.Warnw("failed to persist kmsg cursor", "error", err)
+		}
+	}
+}
+
+// ackedSequenceNumber returns the sequence number up to which all the messages
+// are processed by all the subscribers, so that the messages delivered but not yet
+// processed (e.g., queued in a slow subscriber) are read again after the restart
+// (at-least-once, where the subscribers dedup the replayed messages).
+// The subscribers that processed all the messages delivered to them
+// do not hold back the cursor (e.g., the messages dropped for the slow subscriber).
+// The subscribers removed with the unprocessed messages (e.g., on shutdown)
+// keep holding back the cursor, so that the messages are replayed after the restart.
+func (h *Hub) ackedSequenceNumber() int64 {
+	// load before the subscribers, so that the messages
+	// being sent at the moment are not counted as processed
+	seq := h.dispatchedSeq.Load()
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if h.held && h.heldSeq < seq {
+		seq = h.heldSeq
+	}
+	for _, sub := range h.subs {
+		acked := sub.ackedSeq.Load()
+		if acked >= sub.lastSeq.Load() {
+			// processed all the messages delivered so far
+			continue
+		}
+		if acked < seq {
+			seq = acked
+		}
+	}
+	return seq
+}
+
+func (h *Hub) persistCursor(ctx context.Context) error {
+	seq := h.ackedSequenceNumber()
+
+	h.cursorMu.Lock()
+	defer h.cursorMu.Unlock()
+
+	if seq <= h.persistedSeq {
+		return nil
+	}
+
+	cctx, ccancel := context.WithTimeout(ctx, 15*time.Second)
+	err := h.cursorStore.WriteCursor(cctx, h.bootID, seq)
+	ccancel()
+	if err != nil {
+		return err
+	}
+
+	h.persistedSeq = seq
+	h.cursorUpdatedAt = time.Now().UTC()
+	return nil
+}
+
+// Cursor returns the current kmsg cursor of the hub.
+func (h *Hub) Cursor() Cursor {
+	h.cursorMu.RLock()
+	defer h.cursorMu.RUnlock()
+
+	cur := Cursor{
+		BootID:                  h.bootID,
+		LastSequenceNumber:      h.lastSeq.Load(),
+		PersistedSequenceNumber: h.persistedSeq,
+		ResumedSequenceNumber:   h.resumeSeq,
+		Skipped:                 h.skipped.Load(),
+	}
+	if !h.cursorUpdatedAt.IsZero() {
+		cur.PersistedAt = metav1.NewTime(h.cursorUpdatedAt)
+	}
+	return cur
+}
+
+// Acknowledger is implemented by the watchers that track
+// the messages processed by the subscriber (e.g., the hub watcher),
+// so that the kmsg cursor is only persisted up to the processed messages.
+type Acknowledger interface {
+	// Ack acknowledges the message of the sequence number
+	// (and all the messages before it) as processed.
+	Ack(seq int)
+}
+
+// Ack acknowledges the message as processed, if the watcher supports it.
+// No-op for the other watchers (e.g., reading "/dev/kmsg" directly).
+func Ack(w Watcher, msg Message) {
+	if a, ok := w.(Acknowledger); ok {
+		a.Ack(msg.SequenceNumber)
+	}
+}
+
+var (
+	_ Watcher      = &hubWatcher{}
+	_ Acknowledger = &hubWatcher{}
+)
+
+// hubWatcher implements the Watcher interface
+// on top of the shared hub subscription.
+type hubWatcher struct {
+	hub          *Hub
+	sub          *subscriber
+	watchStarted atomic.Bool
+	closeOnce    sync.Once
+}
+
+func (w *hubWatcher) Watch() (<-chan Message, error) {
+	if !w.watchStarted.CompareAndSwap(false, true) {
+		return nil, ErrWatcherAlreadyStarted
+	}
+	return w.sub.ch, nil
+}
+
+// Ack acknowledges the message (and all the messages before it) as processed by the subscriber.
+func (w *hubWatcher) Ack(seq int) {
+	for {
+		prev := w.sub.ackedSeq.Load()
+		if int64(seq) <= prev || w.sub.ackedSeq.CompareAndSwap(prev, int64(seq)) {
+			return
+		}
+	}
+}
+
+func (w *hubWatcher) Close() error {
+	w.closeOnce.Do(func() {
+		w.hub.unsubscribe(w.sub.name)
+	})
+	return nil
+}
@@ -0,0 +1,54 @@
+package kmsg
+
+import (
+	"errors"
+	"fmt"
+)
+
+// HubOp is the options for the kmsg hub.
+type HubOp struct {
+	source   string
+	filePath string
+
+	bootID      string
+	cursorStore CursorStore
+}
+
+// HubOpOption applies an option to the kmsg hub.
+type HubOpOption func(*HubOp)
+
+func (op *HubOp) applyOpts(opts []HubOpOption) error {
+	for _, opt := range opts {
+		opt(op)
+	}
+
+	if op.source == "" {
+		op.source = SourceKmsg
+	}
+	if !IsValidSource(op.source) {
+		return fmt.Errorf("unknown kmsg source %q", op.source)
+	}
+	if op.source == SourceFile && op.filePath == "" {
+		return errors.New("kmsg file path is required for the file source")
+	}
+
+	return nil
+}
+
+// WithCursorStore specifies the store to persist the kmsg cursor,
+// with the current boot ID.
+func WithCursorStore(bootID string, store CursorStore) HubOpOption {
+	return func(op *HubOp) {
+		op.bootID = bootID
+		op.cursorStore = store
+	}
+}
+
+// WithSource specifies the source to read the kernel messages from
+// (defaults to "/dev/kmsg"). The file path is only used for the file source.
+func WithSource(source string, filePath string) HubOpOption {
+	return func(op *HubOp) {
+		op.source = source
+		op.filePath = filePath
+	}
+}
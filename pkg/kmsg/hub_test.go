@@ -0,0 +1,537 @@
+package kmsg
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	apiv1 "github.com/leptonai/gpud/api/v1"
+	"github.com/leptonai/gpud/pkg/eventstore"
+)
+
+var _ Watcher = &mockChanWatcher{}
+
+type mockChanWatcher struct {
+	ch     chan Message
+	closed bool
+}
+
+func newMockChanWatcher() *mockChanWatcher {
+	return &mockChanWatcher{ch: make(chan Message, 1024)}
+}
+
+func (m *mockChanWatcher) Watch() (<-chan Message, error) {
+	return m.ch, nil
+}
+
+func (m *mockChanWatcher) Close() error {
+	if !m.closed {
+		m.closed = true
+		close(m.ch)
+	}
+	return nil
+}
+
+func TestHubFanOut(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	src := newMockChanWatcher()
+	h, err := newHub(ctx, src)
+	require.NoError(t, err)
+	defer h.Close()
+
+	w1, err := h.NewWatcher("a")
+	require.NoError(t, err)
+	w2, err := h.NewWatcher("b")
+	require.NoError(t, err)
+
+	_, err = h.NewWatcher("a")
+	require.True(t, errors.Is(err, ErrSubscriberAlreadyExists))
+
+	ch1, err := w1.Watch()
+	require.NoError(t, err)
+	_, err = w1.Watch()
+	require.ErrorIs(t, err, ErrWatcherAlreadyStarted)
+	ch2, err := w2.Watch()
+	require.NoError(t, err)
+
+	require.NoError(t, h.Start())
+	require.ErrorIs(t, h.Start(), ErrHubAlreadyStarted)
+
+	for i := 1; i <= 3; i++ {
+		src.ch <- Message{SequenceNumber: i, Message: "hello", Timestamp: metav1.Now()}
+	}
+
+	for _, ch := range []<-chan Message{ch1, ch2} {
+		for i := 1; i <= 3; i++ {
+			select {
+			case msg := <-ch:
+				assert.Equal(t, i, msg.SequenceNumber)
+			case <-time.After(5 * time.Second):
+				t.Fatal("timed out waiting for message")
+			}
+		}
+	}
+
+	stats := h.Stats()
+	require.Len(t, stats, 2)
+	assert.Equal(t, "a", stats[0].Name)
+	assert.Equal(t, uint64(3), stats[0].Delivered)
+	assert.Equal(t, uint64(0), stats[0].Dropped)
+	assert.Equal(t, int64(3), stats[0].LastSequenceNumber)
+	assert.Equal(t, int64(3), h.LastSequenceNumber())
+
+	// closing the watcher unsubscribes
+	require.NoError(t, w1.Close())
+	_, ok := <-ch1
+	assert.False(t, ok)
+	assert.Len(t, h.Stats(), 1)
+}
+
+func TestHubSlowSubscriberDrops(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	src := newMockChanWatcher()
+	h, err := newHub(ctx, src)
+	require.NoError(t, err)
+	defer h.Close()
+
+	slow, err := h.NewWatcher("slow")
+	require.NoError(t, err)
+	_, err = slow.Watch()
+	require.NoError(t, err)
+
+	// fill up the subscriber buffer without consuming
+	for i := 0; i < defaultSubscriberBufferSize; i++ {
+		h.send(h.subs["slow"], Message{SequenceNumber: i})
+	}
+	h.send(h.subs["slow"], Message{SequenceNumber: defaultSubscriberBufferSize})
+
+	stats := h.Stats()
+	require.Len(t, stats, 1)
+	assert.Equal(t, uint64(defaultSubscriberBufferSize), stats[0].Delivered)
+	assert.Equal(t, uint64(1), stats[0].Dropped)
+	assert.Equal(t, defaultSubscriberBufferSize, stats[0].Lag)
+}
+
+func TestHubSlowSubscriberDoesNotBlockHub(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	src := newMockChanWatcher()
+	h, err := newHub(ctx, src)
+	require.NoError(t, err)
+	defer h.Close()
+
+	slow, err := h.NewWatcher("slow")
+	require.NoError(t, err)
+	slowCh, err := slow.Watch()
+	require.NoError(t, err)
+
+	// fill up the subscriber buffer without consuming,
+	// so that the next dispatch waits for the send timeout
+	for i := 0; i < defaultSubscriberBufferSize; i++ {
+		h.send(h.subs["slow"], Message{SequenceNumber: i})
+	}
+	require.NoError(t, h.Start())
+	src.ch <- Message{SequenceNumber: defaultSubscriberBufferSize}
+	require.Eventually(t, func() bool {
+		return h.LastSequenceNumber() == int64(defaultSubscriberBufferSize)
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// the hub lock is not held while waiting for the slow subscriber
+	start := time.Now()
+	_, err = h.NewWatcher("b")
+	require.NoError(t, err)
+	assert.Len(t, h.Stats(), 2)
+
+	// unsubscribing aborts the pending send, rather than sending on the closed channel
+	require.NoError(t, slow.Close())
+	assert.Less(t, time.Since(start), defaultSubscriberSendTimeout/2)
+	for range slowCh {
+	}
+	assert.Len(t, h.Stats(), 1)
+}
+
+func TestHubCloseClosesSubscribers(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// never started
+	h, err := newHub(ctx, newMockChanWatcher())
+	require.NoError(t, err)
+	w, err := h.NewWatcher("a")
+	require.NoError(t, err)
+	ch, err := w.Watch()
+	require.NoError(t, err)
+	require.NoError(t, h.Close())
+	_, ok := <-ch
+	assert.False(t, ok)
+	require.NoError(t, w.Close())
+
+	_, err = h.NewWatcher("b")
+	require.ErrorIs(t, err, ErrHubClosed)
+	require.ErrorIs(t, h.Start(), ErrHubClosed)
+
+	// started, then the source is closed
+	src := newMockChanWatcher()
+	h, err = newHub(ctx, src)
+	require.NoError(t, err)
+	w, err = h.NewWatcher("a")
+	require.NoError(t, err)
+	ch, err = w.Watch()
+	require.NoError(t, err)
+	require.NoError(t, h.Start())
+	require.NoError(t, src.Close())
+
+	select {
+	case _, ok = <-ch:
+		assert.False(t, ok)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the subscriber channel to be closed")
+	}
+}
+
+func TestHubSyncer(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	store, err := eventstore.NewMemory(0)
+	require.NoError(t, err)
+	bucket, err := store.Bucket("test")
+	require.NoError(t, err)
+	defer bucket.Close()
+
+	src := newMockChanWatcher()
+	h, err := newHub(ctx, src)
+	require.NoError(t, err)
+	defer h.Close()
+
+	syncer, err := h.NewSyncer(ctx, "test", func(line string) *MatchedEvent {
+		if line == "match me" {
+			return NewMatchedEvent("matched", "matched message", apiv1.EventTypeFatal, []apiv1.RepairActionType{apiv1.RepairActionTypeHardwareInspection})
+		}
+		return nil
+	}, bucket)
+	require.NoError(t, err)
+	defer syncer.Close()
+
+	require.NoError(t, h.Start())
+
+	now := time.Now()
+	src.ch <- Message{SequenceNumber: 1, Message: "ignore me", Timestamp: metav1.NewTime(now)}
+	src.ch <- Message{SequenceNumber: 2, Message: "match me", Timestamp: metav1.NewTime(now)}
+
+	require.Eventually(t, func() bool {
+		events, err := bucket.Get(ctx, now.Add(-time.Minute))
+		return err == nil && len(events) == 1 && events[0].Name == "matched"
+	}, 5*time.Second, 50*time.Millisecond)
+
+	// the event type and the suggested actions of the matcher are carried over
+	events, err := bucket.Get(ctx, now.Add(-time.Minute))
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, apiv1.EventTypeFatal, events[0].Type)
+	require.NotNil(t, events[0].DeprecatedSuggestedActions)
+	assert.Equal(t, []apiv1.RepairActionType{apiv1.RepairActionTypeHardwareInspection}, events[0].DeprecatedSuggestedActions.RepairActions)
+}
+
+type mockCursorStore struct {
+	mu     sync.Mutex
+	bootID string
+	seq    int64
+	writes int
+}
+
+func (m *mockCursorStore) ReadCursor(ctx context.Context) (string, int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.bootID, m.seq, nil
+}
+
+func (m *mockCursorStore) WriteCursor(ctx context.Context, bootID string, seq int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.bootID, m.seq = bootID, seq
+	m.writes++
+	return nil
+}
+
+func TestHubCursorSameBoot(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	store := &mockCursorStore{bootID: "boot-1", seq: 2}
+
+	src := newMockChanWatcher()
+	h, err := newHub(ctx, src, WithCursorStore("boot-1", store))
+	require.NoError(t, err)
+
+	w, err := h.NewWatcher("a")
+	require.NoError(t, err)
+	ch, err := w.Watch()
+	require.NoError(t, err)
+	require.NoError(t, h.Start())
+
+	for i := 1; i <= 4; i++ {
+		src.ch <- Message{SequenceNumber: i}
+	}
+
+	// records 1 and 2 were processed before the restart
+	for _, want := range []int{3, 4} {
+		select {
+		case msg := <-ch:
+			assert.Equal(t, want, msg.SequenceNumber)
+			Ack(w, msg)
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for message")
+		}
+	}
+
+	cur := h.Cursor()
+	assert.Equal(t, "boot-1", cur.BootID)
+	assert.Equal(t, int64(2), cur.ResumedSequenceNumber)
+	assert.Equal(t, int64(4), cur.LastSequenceNumber)
+	assert.Equal(t, uint64(2), cur.Skipped)
+
+	// persisted on close
+	require.NoError(t, h.Close())
+	store.mu.Lock()
+	assert.Equal(t, "boot-1", store.bootID)
+	assert.Equal(t, int64(4), store.seq)
+	store.mu.Unlock()
+	assert.Equal(t, int64(4), h.Cursor().PersistedSequenceNumber)
+}
+
+func TestHubCursorNewBoot(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	store := &mockCursorStore{bootID: "boot-1", seq: 100}
+
+	src := newMockChanWatcher()
+	h, err := newHub(ctx, src, WithCursorStore("boot-2", store))
+	require.NoError(t, err)
+
+	w, err := h.NewWatcher("a")
+	require.NoError(t, err)
+	ch, err := w.Watch()
+	require.NoError(t, err)
+	require.NoError(t, h.Start())
+
+	src.ch <- Message{SequenceNumber: 1}
+	select {
+	case msg := <-ch:
+		assert.Equal(t, 1, msg.SequenceNumber)
+		Ack(w, msg)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for message")
+	}
+	assert.Equal(t, int64(0), h.Cursor().ResumedSequenceNumber)
+
+	require.NoError(t, h.Close())
+	store.mu.Lock()
+	assert.Equal(t, "boot-2", store.bootID)
+	assert.Equal(t, int64(1), store.seq)
+	store.mu.Unlock()
+}
+
+func TestHubCursorFreshBootFirstRecord(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		opts []HubOpOption
+	}{
+		{name: "no cursor store"},
+		{name: "cursor of the previous boot", opts: []HubOpOption{WithCursorStore("boot-2", &mockCursorStore{bootID: "boot-1", seq: 100})}},
+		{name: "no cursor persisted", opts: []HubOpOption{WithCursorStore("boot-1", &mockCursorStore{})}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			src := newMockChanWatcher()
+			h, err := newHub(ctx, src, tc.opts...)
+			require.NoError(t, err)
+			defer h.Close()
+
+			w, err := h.NewWatcher("a")
+			require.NoError(t, err)
+			ch, err := w.Watch()
+			require.NoError(t, err)
+			require.NoError(t, h.Start())
+
+			// the first record of the boot has the sequence number 0
+			for i := 0; i <= 1; i++ {
+				src.ch <- Message{SequenceNumber: i}
+			}
+			for _, want := range []int{0, 1} {
+				select {
+				case msg := <-ch:
+					assert.Equal(t, want, msg.SequenceNumber)
+					Ack(w, msg)
+				case <-time.After(5 * time.Second):
+					t.Fatal("timed out waiting for message")
+				}
+			}
+
+			cur := h.Cursor()
+			assert.Equal(t, int64(0), cur.ResumedSequenceNumber)
+			assert.Equal(t, uint64(0), cur.Skipped)
+		})
+	}
+}
+
+func TestHubCursorAcked(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	store := &mockCursorStore{}
+
+	src := newMockChanWatcher()
+	h, err := newHub(ctx, src, WithCursorStore("boot-1", store))
+	require.NoError(t, err)
+	defer h.Close()
+
+	fast, err := h.NewWatcher("fast")
+	require.NoError(t, err)
+	fastCh, err := fast.Watch()
+	require.NoError(t, err)
+	slow, err := h.NewWatcher("slow")
+	require.NoError(t, err)
+	slowCh, err := slow.Watch()
+	require.NoError(t, err)
+	require.NoError(t, h.Start())
+
+	for i := 1; i <= 3; i++ {
+		src.ch <- Message{SequenceNumber: i}
+	}
+	for i := 1; i <= 3; i++ {
+		Ack(fast, <-fastCh)
+	}
+
+	// the slow subscriber has not processed any message yet
+	require.Eventually(t, func() bool {
+		return h.dispatchedSeq.Load() == 3
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, int64(0), h.ackedSequenceNumber())
+	require.NoError(t, h.persistCursor(ctx))
+	assert.Equal(t, 0, store.writes)
+
+	// acknowledged up to 2 by all the subscribers
+	<-slowCh
+	Ack(slow, <-slowCh)
+	assert.Equal(t, int64(2), h.ackedSequenceNumber())
+	require.NoError(t, h.persistCursor(ctx))
+	assert.Equal(t, int64(2), store.seq)
+
+	// acks never go backwards
+	Ack(slow, Message{SequenceNumber: 1})
+	assert.Equal(t, int64(2), h.ackedSequenceNumber())
+
+	Ack(slow, <-slowCh)
+	assert.Equal(t, int64(3), h.ackedSequenceNumber())
+
+	stats := h.Stats()
+	require.Len(t, stats, 2)
+	assert.Equal(t, int64(3), stats[1].AckedSequenceNumber)
+
+	// new subscriber does not hold back the cursor
+	_, err = h.NewWatcher("late")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), h.ackedSequenceNumber())
+}
+
+func TestHubCursorCloseWithUnackedMessages(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	store := &mockCursorStore{}
+
+	src := newMockChanWatcher()
+	h, err := newHub(ctx, src, WithCursorStore("boot-1", store))
+	require.NoError(t, err)
+
+	w, err := h.NewWatcher("a")
+	require.NoError(t, err)
+	ch, err := w.Watch()
+	require.NoError(t, err)
+	require.NoError(t, h.Start())
+
+	for i := 1; i <= 3; i++ {
+		src.ch <- Message{SequenceNumber: i}
+	}
+	Ack(w, <-ch)
+
+	// messages 2 and 3 are still queued in the subscriber
+	require.Eventually(t, func() bool {
+		return h.dispatchedSeq.Load() == 3
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// the subscribers are closed on shutdown, before the cursor is persisted
+	require.NoError(t, h.Close())
+	store.mu.Lock()
+	assert.Equal(t, int64(1), store.seq)
+	store.mu.Unlock()
+
+	// reopen, the unacked messages are replayed
+	src = newMockChanWatcher()
+	h, err = newHub(ctx, src, WithCursorStore("boot-1", store))
+	require.NoError(t, err)
+	defer h.Close()
+
+	w, err = h.NewWatcher("a")
+	require.NoError(t, err)
+	ch, err = w.Watch()
+	require.NoError(t, err)
+	require.NoError(t, h.Start())
+
+	for i := 1; i <= 3; i++ {
+		src.ch <- Message{SequenceNumber: i}
+	}
+	for _, want := range []int{2, 3} {
+		select {
+		case msg := <-ch:
+			assert.Equal(t, want, msg.SequenceNumber)
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for message")
+		}
+	}
+}
+
+func TestHubCursorUnsubscribedWithUnackedMessages(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	store := &mockCursorStore{}
+
+	src := newMockChanWatcher()
+	h, err := newHub(ctx, src, WithCursorStore("boot-1", store))
+	require.NoError(t, err)
+	defer h.Close()
+
+	w, err := h.NewWatcher("a")
+	require.NoError(t, err)
+	ch, err := w.Watch()
+	require.NoError(t, err)
+	require.NoError(t, h.Start())
+
+	for i := 1; i <= 3; i++ {
+		src.ch <- Message{SequenceNumber: i}
+	}
+	Ack(w, <-ch)
+	require.Eventually(t, func() bool {
+		return h.dispatchedSeq.Load() == 3
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// e.g., the component is closed before the hub
+	require.NoError(t, w.Close())
+	assert.Equal(t, int64(1), h.ackedSequenceNumber())
+}
@@ -0,0 +1,209 @@
+package kmsg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/leptonai/gpud/pkg/log"
+	"github.com/leptonai/gpud/pkg/process"
+)
+
+// e.g., "journalctl -k -f --no-tail -o json"
+// "-k" only shows the kernel messages from the current boot
+// "--no-tail" replays all the messages of the current boot before following,
+// same as reading the "/dev/kmsg" ring buffer from the start
+// (replayed events are deduplicated by the syncer with the journal timestamps)
+var defaultJournalCommand = []string{"journalctl", "-k", "-f", "--no-tail", "-o", "json", "--no-pager"}
+
+var _ Watcher = &journalWatcher{}
+
+// journalWatcher reads the kernel messages from the systemd journal,
+// which does not require root (e.g., members of the "systemd-journal" group).
+type journalWatcher struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	proc process.Process
+
+	// journal entries may not have the sequence number
+	// (e.g., "__SEQNUM" is only available in systemd v254+)
+	// in which case, the watcher assigns its own
+	seq atomic.Int64
+
+	watchStarted atomic.Bool
+}
+
+// NewJournalWatcher creates a new watcher that reads the kernel messages
+// from the systemd journal.
+func NewJournalWatcher() (Watcher, error) {
+	proc, err := process.New(process.WithCommand(defaultJournalCommand...))
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &journalWatcher{
+		ctx:    ctx,
+		cancel: cancel,
+		proc:   proc,
+	}, nil
+}
+
+func (w *journalWatcher) Watch() (<-chan Message, error) {
+	if !w.watchStarted.CompareAndSwap(false, true) {
+		return nil, ErrWatcherAlreadyStarted
+	}
+
+	if err := w.proc.Start(w.ctx); err != nil {
+		return nil, err
+	}
+
+	ch := make(chan Message, 2048)
+	go func() {
+		defer close(ch)
+
+		deduper := newDeduper(defaultCacheExpiration, defaultCachePurgeInterval)
+		err := process.Read(
+			w.ctx,
+			w.proc,
+			process.WithReadStdout(),
+			process.WithInitialBufferSize(readBufferSize),
+			process.WithProcessLine(func(line string) {
+				msg, err := w.parseLine(line)
+				if err != nil {
+					log.Logger.Warnw("malformed journal entry", "error", err, "line", line)
+					return
+				}
+				if msg == nil {
+					return
+				}
+				if occurrences := deduper.addCache(*msg); occurrences > 1 {
+					log.Logger.Debugw("skipping duplicate journal message", "occurrences", occurrences, "timestamp", msg.Timestamp, "message", msg.Message)
+					return
+				}
+
+				select {
+				case ch <- *msg:
+				case <-w.ctx.Done():
+				}
+			}),
+		)
+		if err != nil && !errors.Is(err, context.Canceled) {
+			log.Logger.Errorw("journal watcher error", "error", err)
+		}
+	}()
+	return ch, nil
+}
+
+func (w *journalWatcher) Close() error {
+	w.cancel()
+	if !w.proc.Started() {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return w.proc.Close(ctx)
+}
+
+func (w *journalWatcher) parseLine(line string) (*Message, error) {
+	msg, err := parseJournalLine(line)
+	if err != nil || msg == nil {
+		return msg, err
+	}
+	if msg.SequenceNumber == 0 {
+		msg.SequenceNumber = int(w.seq.Add(1))
+	}
+	return msg, nil
+}
+
+// journalEntry is the subset of the "journalctl -o json" fields.
+// ref. https://www.freedesktop.org/software/systemd/man/latest/systemd.journal-fields.html
+type journalEntry struct {
+	// "MESSAGE" is a string, or an array of bytes if not valid UTF-8
+	Message json.RawMessage `json:"MESSAGE"`
+	// syslog priority in string (e.g., "6")
+	Priority string `json:"PRIORITY"`
+	// wallclock time in microseconds in string
+	RealtimeTimestamp string `json:"__REALTIME_TIMESTAMP"`
+	// sequence number in string (systemd v254+)
+	SeqNum string `json:"__SEQNUM"`
+}
+
+// parseJournalLine parses a single "journalctl -o json" line.
+// Returns nil if the entry has no message.
+func parseJournalLine(line string) (*Message, error) {
+	if len(line) == 0 {
+		return nil, nil
+	}
+
+	var entry journalEntry
+	if err := json.Unmarshal([]byte(line), &entry); err != nil {
+		return nil, err
+	}
+
+	message, err := parseJournalMessage(entry.Message)
+	if err != nil {
+		return nil, err
+	}
+	if message == "" {
+		return nil, nil
+	}
+
+	msg := &Message{
+		Message:   message,
+		Timestamp: metav1.Now(),
+	}
+
+	if entry.Priority != "" {
+		msg.Priority, err = strconv.Atoi(entry.Priority)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse %q as priority: %w", entry.Priority, err)
+		}
+	}
+
+	if entry.RealtimeTimestamp != "" {
+		us, err := strconv.ParseInt(entry.RealtimeTimestamp, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse %q as timestamp: %w", entry.RealtimeTimestamp, err)
+		}
+		msg.Timestamp = metav1.NewTime(time.UnixMicro(us))
+	}
+
+	if entry.SeqNum != "" {
+		msg.SequenceNumber, err = strconv.Atoi(entry.SeqNum)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse %q as sequence number: %w", entry.SeqNum, err)
+		}
+	}
+
+	return msg, nil
+}
+
+func parseJournalMessage(raw json.RawMessage) (string, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return "", nil
+	}
+
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s, nil
+	}
+
+	var b []byte
+	var ints []int
+	if err := json.Unmarshal(raw, &ints); err != nil {
+		return "", fmt.Errorf("could not parse journal message %q: %w", string(raw), err)
+	}
+	for _, v := range ints {
+		b = append(b, byte(v))
+	}
+	return string(b), nil
+}
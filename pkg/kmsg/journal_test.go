@@ -0,0 +1,71 @@
+package kmsg
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseJournalLine(t *testing.T) {
+	line := `{"__REALTIME_TIMESTAMP":"1700000000123456","__SEQNUM":"42","PRIORITY":"4","_TRANSPORT":"kernel","MESSAGE":"NVRM: Xid (PCI:0000:05:00): 79, pid=123, GPU has fallen off the bus."}`
+
+	msg, err := parseJournalLine(line)
+	require.NoError(t, err)
+	require.NotNil(t, msg)
+	assert.Equal(t, "NVRM: Xid (PCI:0000:05:00): 79, pid=123, GPU has fallen off the bus.", msg.Message)
+	assert.Equal(t, 4, msg.Priority)
+	assert.Equal(t, 42, msg.SequenceNumber)
+	assert.Equal(t, time.UnixMicro(1700000000123456).Unix(), msg.Timestamp.Unix())
+}
+
+func TestParseJournalLineByteArrayMessage(t *testing.T) {
+	// non UTF-8 messages are encoded as byte arrays
+	line := `{"__REALTIME_TIMESTAMP":"1700000000000000","PRIORITY":"6","MESSAGE":[104,101,108,108,111]}`
+
+	msg, err := parseJournalLine(line)
+	require.NoError(t, err)
+	require.NotNil(t, msg)
+	assert.Equal(t, "hello", msg.Message)
+	assert.Equal(t, 0, msg.SequenceNumber)
+}
+
+func TestParseJournalLineEmpty(t *testing.T) {
+	msg, err := parseJournalLine("")
+	require.NoError(t, err)
+	assert.Nil(t, msg)
+
+	msg, err = parseJournalLine(`{"PRIORITY":"6"}`)
+	require.NoError(t, err)
+	assert.Nil(t, msg)
+}
+
+func TestParseJournalLineInvalid(t *testing.T) {
+	for _, line := range []string{
+		`not json`,
+		`{"MESSAGE":"x","PRIORITY":"high"}`,
+		`{"MESSAGE":"x","__REALTIME_TIMESTAMP":"now"}`,
+		`{"MESSAGE":{"a":"b"}}`,
+	} {
+		_, err := parseJournalLine(line)
+		assert.Error(t, err, line)
+	}
+}
+
+func TestJournalWatcherSequenceNumber(t *testing.T) {
+	w := &journalWatcher{}
+
+	msg, err := w.parseLine(`{"MESSAGE":"a"}`)
+	require.NoError(t, err)
+	assert.Equal(t, 1, msg.SequenceNumber)
+
+	msg, err = w.parseLine(`{"MESSAGE":"b"}`)
+	require.NoError(t, err)
+	assert.Equal(t, 2, msg.SequenceNumber)
+
+	// keeps the journal sequence number, if any
+	msg, err = w.parseLine(`{"MESSAGE":"c","__SEQNUM":"100"}`)
+	require.NoError(t, err)
+	assert.Equal(t, 100, msg.SequenceNumber)
+}
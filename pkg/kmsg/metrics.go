@@ -0,0 +1,49 @@
+package kmsg
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	pkgmetrics "github.com/leptonai/gpud/pkg/metrics"
+)
+
+const SubSystem = "kmsg"
+
+var (
+	metricSubscriberDelivered = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "",
+			Subsystem: SubSystem,
+			Name:      "subscriber_delivered_total",
+			Help:      "tracks the total number of kmsg messages delivered to each hub subscriber",
+		},
+		[]string{"subscriber"},
+	)
+
+	metricSubscriberDropped = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "",
+			Subsystem: SubSystem,
+			Name:      "subscriber_dropped_total",
+			Help:      "tracks the total number of kmsg messages dropped for each hub subscriber (subscriber too slow)",
+		},
+		[]string{"subscriber"},
+	)
+
+	metricSubscriberLag = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "",
+			Subsystem: SubSystem,
+			Name:      "subscriber_lag",
+			Help:      "tracks the number of kmsg messages queued but not yet consumed by each hub subscriber",
+		},
+		[]string{"subscriber"},
+	)
+)
+
+func init() {
+	pkgmetrics.MustRegister(
+		metricSubscriberDelivered,
+		metricSubscriberDropped,
+		metricSubscriberLag,
+	)
+}
@@ -0,0 +1,152 @@
+package kmsg
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	apiv1 "github.com/leptonai/gpud/api/v1"
+)
+
+// DefaultRuleDecayWindow is the default duration that a matched rule
+// impacts the health state since the last match.
+const DefaultRuleDecayWindow = time.Hour
+
+// Rule is a user-defined rule that matches the kernel messages
+// by the regex, and generates an event for each match
+// (e.g., alert on the vendor driver messages not yet supported by gpud).
+type Rule struct {
+	// Name is the event name of the matched messages, must be unique.
+	Name string `json:"name"`
+
+	// Source is the source of the messages to match (e.g., "kmsg", "journal", "file").
+	// If empty, matches the messages from the default source of gpud.
+	Source string `json:"source,omitempty"`
+	// FilePath is the log file to tail, only used for the "file" source.
+	FilePath string `json:"file_path,omitempty"`
+
+	// Regex is the regular expression to match the log line.
+	Regex string `json:"regex"`
+
+	// Message is the event message.
+	// If empty, the matched log line is used.
+	Message string `json:"message,omitempty"`
+	// EventType is the type of the generated event.
+	// If empty, defaults to "Warning".
+	EventType apiv1.EventType `json:"event_type,omitempty"`
+	// RepairActions are the suggested repair actions for the matched messages.
+	RepairActions []apiv1.RepairActionType `json:"repair_actions,omitempty"`
+
+	// HealthImpact is the health state while the rule is matched
+	// within the decay window (e.g., "Degraded", "Unhealthy").
+	// If empty, the rule only generates events and does not impact the health state.
+	HealthImpact apiv1.HealthStateType `json:"health_impact,omitempty"`
+	// DecayWindow is the duration since the last match,
+	// after which the rule no longer impacts the health state.
+	// If zero, defaults to DefaultRuleDecayWindow.
+	DecayWindow metav1.Duration `json:"decay_window,omitempty"`
+}
+
+// GetEventType returns the event type of the rule, defaults to "Warning".
+func (r Rule) GetEventType() apiv1.EventType {
+	if r.EventType == "" {
+		return apiv1.EventTypeWarning
+	}
+	return r.EventType
+}
+
+// GetDecayWindow returns the decay window of the rule,
+// defaults to DefaultRuleDecayWindow.
+func (r Rule) GetDecayWindow() time.Duration {
+	if r.DecayWindow.Duration <= 0 {
+		return DefaultRuleDecayWindow
+	}
+	return r.DecayWindow.Duration
+}
+
+// Validate validates the rule.
+func (r Rule) Validate() error {
+	if r.Name == "" {
+		return errors.New("rule name is required")
+	}
+	if !IsValidSource(r.Source) {
+		return fmt.Errorf("rule %q: unknown source %q", r.Name, r.Source)
+	}
+	if r.Source == SourceFile && r.FilePath == "" {
+		return fmt.Errorf("rule %q: file_path is required for the file source", r.Name)
+	}
+
+	if r.Regex == "" {
+		return fmt.Errorf("rule %q: regex is required", r.Name)
+	}
+	if _, err := regexp.Compile(r.Regex); err != nil {
+		return fmt.Errorf("rule %q: invalid regex: %w", r.Name, err)
+	}
+
+	switch r.EventType {
+	case "", apiv1.EventTypeInfo, apiv1.EventTypeWarning, apiv1.EventTypeCritical, apiv1.EventTypeFatal:
+	default:
+		return fmt.Errorf("rule %q: unknown event_type %q", r.Name, r.EventType)
+	}
+
+	for _, action := range r.RepairActions {
+		switch action {
+		case apiv1.RepairActionTypeIgnoreNoActionRequired,
+			apiv1.RepairActionTypeRebootSystem,
+			apiv1.RepairActionTypeHardwareInspection,
+			apiv1.RepairActionTypeCheckUserAppAndGPU:
+		default:
+			return fmt.Errorf("rule %q: unknown repair action %q", r.Name, action)
+		}
+	}
+
+	switch r.HealthImpact {
+	case "", apiv1.HealthStateTypeHealthy, apiv1.HealthStateTypeDegraded, apiv1.HealthStateTypeUnhealthy:
+	default:
+		return fmt.Errorf("rule %q: unknown health_impact %q", r.Name, r.HealthImpact)
+	}
+
+	if r.DecayWindow.Duration < 0 {
+		return fmt.Errorf("rule %q: decay_window must be non-negative", r.Name)
+	}
+
+	return nil
+}
+
+// ValidateRules validates the rules, and the rule names must be unique.
+func ValidateRules(rules []Rule) error {
+	names := make(map[string]struct{}, len(rules))
+	for _, r := range rules {
+		if err := r.Validate(); err != nil {
+			return err
+		}
+		if _, ok := names[r.Name]; ok {
+			return fmt.Errorf("duplicate rule name %q", r.Name)
+		}
+		names[r.Name] = struct{}{}
+	}
+	return nil
+}
+
+// LoadRules loads the rules from the YAML or JSON file,
+// which contains a list of rules.
+func LoadRules(file string) ([]Rule, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var rules []Rule
+	if err := yaml.Unmarshal(b, &rules); err != nil {
+		return nil, fmt.Errorf("failed to parse rules file %q: %w", file, err)
+	}
+	if err := ValidateRules(rules); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
@@ -0,0 +1,74 @@
+package kmsg
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	apiv1 "github.com/leptonai/gpud/api/v1"
+)
+
+func TestLoadRules(t *testing.T) {
+	rules, err := LoadRules("testdata/rules.yaml")
+	require.NoError(t, err)
+	require.Len(t, rules, 2)
+
+	assert.Equal(t, "mlx5_cmd_timeout", rules[0].Name)
+	assert.Equal(t, "", rules[0].Source)
+	assert.Equal(t, apiv1.EventTypeCritical, rules[0].GetEventType())
+	assert.Equal(t, []apiv1.RepairActionType{apiv1.RepairActionTypeHardwareInspection}, rules[0].RepairActions)
+	assert.Equal(t, apiv1.HealthStateTypeDegraded, rules[0].HealthImpact)
+	assert.Equal(t, 30*time.Minute, rules[0].GetDecayWindow())
+
+	assert.Equal(t, SourceFile, rules[1].Source)
+	assert.Equal(t, "/var/log/kern.log", rules[1].FilePath)
+	assert.Equal(t, apiv1.EventTypeWarning, rules[1].GetEventType())
+	assert.Equal(t, apiv1.HealthStateType(""), rules[1].HealthImpact)
+	assert.Equal(t, DefaultRuleDecayWindow, rules[1].GetDecayWindow())
+}
+
+func TestLoadRulesErrors(t *testing.T) {
+	_, err := LoadRules(filepath.Join(t.TempDir(), "does-not-exist.yaml"))
+	assert.Error(t, err)
+
+	f := filepath.Join(t.TempDir(), "invalid.yaml")
+	require.NoError(t, os.WriteFile(f, []byte("name: not-a-list"), 0644))
+	_, err = LoadRules(f)
+	assert.Error(t, err)
+
+	require.NoError(t, os.WriteFile(f, []byte(`[{"name": "a", "regex": "("}]`), 0644))
+	_, err = LoadRules(f)
+	assert.Error(t, err)
+}
+
+func TestValidateRules(t *testing.T) {
+	valid := Rule{Name: "a", Regex: "foo"}
+	require.NoError(t, ValidateRules([]Rule{valid}))
+
+	tests := []struct {
+		name string
+		rule Rule
+	}{
+		{name: "missing name", rule: Rule{Regex: "foo"}},
+		{name: "missing regex", rule: Rule{Name: "a"}},
+		{name: "invalid regex", rule: Rule{Name: "a", Regex: "("}},
+		{name: "unknown source", rule: Rule{Name: "a", Regex: "foo", Source: "syslog"}},
+		{name: "file source without path", rule: Rule{Name: "a", Regex: "foo", Source: SourceFile}},
+		{name: "unknown event type", rule: Rule{Name: "a", Regex: "foo", EventType: "Bad"}},
+		{name: "unknown repair action", rule: Rule{Name: "a", Regex: "foo", RepairActions: []apiv1.RepairActionType{"FIX_IT"}}},
+		{name: "unknown health impact", rule: Rule{Name: "a", Regex: "foo", HealthImpact: "Broken"}},
+		{name: "negative decay window", rule: Rule{Name: "a", Regex: "foo", DecayWindow: metav1.Duration{Duration: -time.Minute}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Error(t, ValidateRules([]Rule{tt.rule}))
+		})
+	}
+
+	assert.Error(t, ValidateRules([]Rule{valid, valid}), "duplicate names")
+}
@@ -0,0 +1,88 @@
+package kmsg
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+)
+
+const (
+	// SourceKmsg reads the kernel messages from "/dev/kmsg" (requires root).
+	SourceKmsg = "kmsg"
+	// SourceJournal reads the kernel messages from the systemd journal
+	// (kernel transport), using "journalctl -k -f --no-tail -o json".
+	SourceJournal = "journal"
+	// SourceFile tails a plain log file (e.g., "/var/log/kern.log").
+	SourceFile = "file"
+)
+
+// IsValidSource returns true if the kernel log source is supported.
+// Empty source is valid, meaning the source is auto-detected.
+func IsValidSource(source string) bool {
+	switch source {
+	case "", SourceKmsg, SourceJournal, SourceFile:
+		return true
+	default:
+		return false
+	}
+}
+
+// DefaultFilePaths are the kernel log files tailed by the file source,
+// if "/dev/kmsg" and the systemd journal are not available
+// and no file path is specified.
+var DefaultFilePaths = []string{
+	"/var/log/kern.log",
+	"/var/log/messages",
+}
+
+// DetectSource returns the kernel log source available on the host,
+// and the file path to tail for the file source.
+// "/dev/kmsg" is preferred if readable, then the systemd journal,
+// then the specified file path (or one of the "DefaultFilePaths").
+// Returns an empty source if no source is available
+// (e.g., container without "/dev/kmsg").
+func DetectSource(filePath string) (string, string) {
+	if runtime.GOOS != "linux" {
+		return "", ""
+	}
+	return detectSource(kmsgFilePath, filePath, DefaultFilePaths)
+}
+
+func detectSource(kmsgPath string, filePath string, defaultFilePaths []string) (string, string) {
+	// root is not enough, "/dev/kmsg" may not exist in the container
+	if f, err := os.Open(kmsgPath); err == nil {
+		_ = f.Close()
+		return SourceKmsg, ""
+	}
+	if p, err := exec.LookPath("journalctl"); err == nil && p != "" {
+		return SourceJournal, ""
+	}
+
+	candidates := defaultFilePaths
+	if filePath != "" {
+		candidates = []string{filePath}
+	}
+	for _, p := range candidates {
+		if f, err := os.Open(p); err == nil {
+			_ = f.Close()
+			return SourceFile, p
+		}
+	}
+	return "", ""
+}
+
+// NewSourceWatcher creates a new watcher that reads the kernel messages
+// from the specified source. The file path is only used for the file source.
+func NewSourceWatcher(source string, filePath string) (Watcher, error) {
+	switch source {
+	case SourceKmsg:
+		return NewWatcher()
+	case SourceJournal:
+		return NewJournalWatcher()
+	case SourceFile:
+		return NewFileWatcher(filePath)
+	default:
+		return nil, fmt.Errorf("unknown kmsg source %q", source)
+	}
+}
@@ -0,0 +1,83 @@
+package kmsg
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsValidSource(t *testing.T) {
+	for _, s := range []string{"", SourceKmsg, SourceJournal, SourceFile} {
+		assert.True(t, IsValidSource(s), s)
+	}
+	assert.False(t, IsValidSource("syslog"))
+}
+
+func TestNewSourceWatcherUnknown(t *testing.T) {
+	_, err := NewSourceWatcher("syslog", "")
+	assert.Error(t, err)
+}
+
+func TestNewHubWithSource(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, err := NewHub(ctx, WithSource("syslog", ""))
+	assert.Error(t, err)
+
+	_, err = NewHub(ctx, WithSource(SourceFile, ""))
+	assert.Error(t, err)
+
+	path := filepath.Join(t.TempDir(), "kern.log")
+	require.NoError(t, os.WriteFile(path, nil, 0644))
+
+	// cursor is ignored for the non-kmsg sources
+	store := &mockCursorStore{bootID: "boot-1", seq: 10}
+	h, err := NewHub(ctx, WithSource(SourceFile, path), WithCursorStore("boot-1", store))
+	require.NoError(t, err)
+	defer h.Close()
+
+	assert.Equal(t, SourceFile, h.Source())
+	assert.Equal(t, int64(0), h.Cursor().ResumedSequenceNumber)
+
+	w, err := h.NewWatcher("a")
+	require.NoError(t, err)
+	ch, err := w.Watch()
+	require.NoError(t, err)
+	require.NoError(t, h.Start())
+
+	// the file source polls every second by default
+	appendFile(t, path, time.Now().Format(time.RFC3339)+" host kernel: NVRM: Xid (PCI:0000:05:00): 79\n")
+	assert.Equal(t, "NVRM: Xid (PCI:0000:05:00): 79", receive(t, ch).Message)
+}
+
+func TestDetectSource(t *testing.T) {
+	dir := t.TempDir()
+
+	kmsgPath := filepath.Join(dir, "kmsg")
+	require.NoError(t, os.WriteFile(kmsgPath, nil, 0644))
+	src, path := detectSource(kmsgPath, "", nil)
+	assert.Equal(t, SourceKmsg, src)
+	assert.Empty(t, path)
+
+	// no "/dev/kmsg" (e.g., container), and no journalctl
+	t.Setenv("PATH", dir)
+	missing := filepath.Join(dir, "missing")
+	src, _ = detectSource(missing, "", nil)
+	assert.Empty(t, src)
+
+	logPath := filepath.Join(dir, "kern.log")
+	require.NoError(t, os.WriteFile(logPath, nil, 0644))
+	src, path = detectSource(missing, "", []string{missing, logPath})
+	assert.Equal(t, SourceFile, src)
+	assert.Equal(t, logPath, path)
+
+	// the specified file path takes precedence over the defaults
+	src, _ = detectSource(missing, missing, []string{logPath})
+	assert.Empty(t, src)
+}
@@ -23,7 +23,36 @@ type Syncer struct {
 	eventBucket eventstore.Bucket
 }
 
-type MatchFunc func(line string) (eventName string, message string)
+// MatchFunc returns the event of the kernel message, with the event type
+// and the suggested actions of the component matcher, or nil if not matched.
+// The same matcher is used by the live syncer and the offline scan,
+// so that both record the same event for the same line.
+type MatchFunc func(line string) *MatchedEvent
+
+// MatchedEvent is the event of the kernel message matched by the component matcher,
+// with the event type and the suggested actions of the matcher.
+type MatchedEvent struct {
+	Name             string
+	Message          string
+	Type             apiv1.EventType
+	SuggestedActions *apiv1.SuggestedActions
+}
+
+// NewMatchedEvent creates the matched event,
+// with no suggested action if the repair actions are empty.
+func NewMatchedEvent(name string, message string, eventType apiv1.EventType, repairActions []apiv1.RepairActionType) *MatchedEvent {
+	ev := &MatchedEvent{
+		Name:    name,
+		Message: message,
+		Type:    eventType,
+	}
+	if len(repairActions) > 0 {
+		ev.SuggestedActions = &apiv1.SuggestedActions{
+			RepairActions: append([]apiv1.RepairActionType(nil), repairActions...),
+		}
+	}
+	return ev
+}
 
 func NewSyncer(ctx context.Context, matchFunc MatchFunc, eventBucket eventstore.Bucket) (*Syncer, error) {
 	return newSyncer(ctx, nil, matchFunc, eventBucket)
@@ -62,42 +91,53 @@ func (w *Syncer) sync(ch <-chan Message) {
 				return
 			}
 
-			name, message := w.matchFunc(kmsg.Message)
-			if name == "" {
-				continue
-			}
-			event := apiv1.Event{
-				Time:    metav1.Time{Time: kmsg.Timestamp.UTC()},
-				Name:    name,
-				Message: message,
-				Type:    apiv1.EventTypeWarning,
-			}
+			w.process(kmsg)
+			Ack(w.watcher, kmsg)
+		}
+	}
+}
 
-			// lookup to prevent duplicate event insertions
-			cctx, ccancel := context.WithTimeout(w.ctx, 15*time.Second)
-			sameEvent, err := w.eventBucket.Find(cctx, event)
-			ccancel()
-			if err != nil {
-				log.Logger.Errorw("failed to find event", "eventName", event.Name, "eventType", event.Type, "error", err)
-			}
-			if sameEvent != nil {
-				continue
-			}
+// process inserts the event of the kernel message, if matched.
+func (w *Syncer) process(kmsg Message) {
+	matched := w.matchFunc(kmsg.Message)
+	if matched == nil || matched.Name == "" {
+		return
+	}
+	eventType := matched.Type
+	if eventType == "" {
+		eventType = apiv1.EventTypeWarning
+	}
+	event := apiv1.Event{
+		Time:    metav1.Time{Time: kmsg.Timestamp.UTC()},
+		Name:    matched.Name,
+		Message: matched.Message,
+		Type:    eventType,
+	}
 
-			event.DeprecatedExtraInfo = map[string]string{
-				eventKeyLogLine: kmsg.Message,
-			}
+	// lookup to prevent duplicate event insertions
+	cctx, ccancel := context.WithTimeout(w.ctx, 15*time.Second)
+	sameEvent, err := w.eventBucket.Find(cctx, event)
+	ccancel()
+	if err != nil {
+		log.Logger.Errorw("failed to find event", "eventName", event.Name, "eventType", event.Type, "error", err)
+	}
+	if sameEvent != nil {
+		return
+	}
 
-			// insert event
-			cctx, ccancel = context.WithTimeout(w.ctx, 15*time.Second)
-			err = w.eventBucket.Insert(cctx, event)
-			ccancel()
-			if err != nil {
-				log.Logger.Errorw("failed to insert event", "error", err)
-			} else {
-				log.Logger.Infow("successfully inserted event", "event", event.Name)
-			}
-		}
+	event.DeprecatedExtraInfo = map[string]string{
+		eventKeyLogLine: kmsg.Message,
+	}
+	event.DeprecatedSuggestedActions = matched.SuggestedActions
+
+	// insert event
+	cctx, ccancel = context.WithTimeout(w.ctx, 15*time.Second)
+	err = w.eventBucket.Insert(cctx, event)
+	ccancel()
+	if err != nil {
+		log.Logger.Errorw("failed to insert event", "error", err)
+	} else {
+		log.Logger.Infow("successfully inserted event", "event", event.Name)
 	}
 }
 
@@ -11,6 +11,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	apiv1 "github.com/leptonai/gpud/api/v1"
 	"github.com/leptonai/gpud/pkg/eventstore"
 	"github.com/leptonai/gpud/pkg/sqlite"
 )
@@ -62,8 +63,8 @@ func TestSyncer(t *testing.T) {
 	w, err := newSyncer(
 		ctx,
 		&mockFileWatcher{file: "./testdata/kmsg.1.log"},
-		func(_ string) (string, string) {
-			return "test", ""
+		func(_ string) *MatchedEvent {
+			return NewMatchedEvent("test", "", apiv1.EventTypeWarning, nil)
 		},
 		bucket,
 	)
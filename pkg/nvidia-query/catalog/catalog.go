@@ -0,0 +1,212 @@
+// Package catalog provides the external catalog of the NVIDIA error details (e.g., Xid, SXid),
+// which override or extend the built-in details.
+package catalog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+
+	"sigs.k8s.io/yaml"
+
+	apiv1 "github.com/leptonai/gpud/api/v1"
+)
+
+// Catalog is the error details of type T (e.g., Xid details) loaded from the external catalog file,
+// which take precedence over the built-in details.
+type Catalog[T any] struct {
+	// kind is the name of the error code (e.g., "xid"),
+	// also the JSON key of the error code in the catalog file
+	kind string
+	// builtin is the built-in details, keyed by the error code
+	builtin map[int]T
+
+	codeFunc     func(T) int
+	validateFunc func(T) error
+
+	mu      sync.RWMutex
+	details map[int]T
+}
+
+// New creates a new catalog of the error details of the kind (e.g., "xid"),
+// on top of the built-in details.
+// The code function returns the error code of the detail,
+// and the validate function validates each detail (e.g., "ValidateDetail").
+func New[T any](kind string, builtin map[int]T, code func(T) int, validate func(T) error) *Catalog[T] {
+	return &Catalog[T]{
+		kind:         kind,
+		builtin:      builtin,
+		codeFunc:     code,
+		validateFunc: validate,
+	}
+}
+
+// Set sets the details that override or extend the built-in details,
+// replacing the previously set ones (e.g., loaded with "Load").
+// Set nil to only use the built-in details.
+func (c *Catalog[T]) Set(ds []T) {
+	m := make(map[int]T, len(ds))
+	for _, d := range ds {
+		m[c.codeFunc(d)] = d
+	}
+
+	c.mu.Lock()
+	c.details = m
+	c.mu.Unlock()
+}
+
+// Get returns the detail of the error code from the external catalog, if any.
+func (c *Catalog[T]) Get(code int) (T, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	d, ok := c.details[code]
+	return d, ok
+}
+
+// Validate validates the details, and the error codes must be unique.
+func (c *Catalog[T]) Validate(ds []T) error {
+	codes := make(map[int]struct{}, len(ds))
+	for _, d := range ds {
+		if err := c.validateFunc(d); err != nil {
+			return err
+		}
+		code := c.codeFunc(d)
+		if _, ok := codes[code]; ok {
+			return fmt.Errorf("duplicate %s %d", c.kind, code)
+		}
+		codes[code] = struct{}{}
+	}
+	return nil
+}
+
+// Load loads the details from the YAML or JSON file,
+// which contains a list of details in the same schema as the type T.
+// Each entry is applied on top of the built-in detail of the same error code,
+// so only the fields to override need to be set (e.g., "event_type").
+// The error codes not in the built-in details are added as new entries.
+// The returned details are validated, and can be set with "Set".
+func (c *Catalog[T]) Load(file string) ([]T, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	ds, err := c.Parse(b)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s catalog file %q: %w", c.kind, file, err)
+	}
+	return ds, nil
+}
+
+// Parse parses the details from the YAML or JSON bytes (see "Load").
+func (c *Catalog[T]) Parse(b []byte) ([]T, error) {
+	jb, err := yaml.YAMLToJSON(b)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []map[string]json.RawMessage
+	if err := json.Unmarshal(jb, &entries); err != nil {
+		return nil, err
+	}
+
+	ds := make([]T, 0, len(entries))
+	for _, entry := range entries {
+		raw, ok := entry[c.kind]
+		if !ok {
+			return nil, errors.New(c.kind + " is required")
+		}
+		var code int
+		if err := json.Unmarshal(raw, &code); err != nil {
+			return nil, fmt.Errorf("invalid %s %s: %w", c.kind, raw, err)
+		}
+
+		// only the top-level fields in the file are overridden,
+		// so the suggested actions are replaced as a whole,
+		// rather than merging the repair actions with the built-in ones
+		fields := make(map[string]json.RawMessage)
+		if builtin, ok := c.builtin[code]; ok {
+			bb, err := json.Marshal(builtin)
+			if err != nil {
+				return nil, err
+			}
+			if err := json.Unmarshal(bb, &fields); err != nil {
+				return nil, err
+			}
+		}
+		for k, v := range entry {
+			fields[k] = v
+		}
+
+		fb, err := json.Marshal(fields)
+		if err != nil {
+			return nil, err
+		}
+		// validated against the schema of the type T,
+		// so that the misspelled keys (e.g., "event_typ") are not silently ignored
+		dec := json.NewDecoder(bytes.NewReader(fb))
+		dec.DisallowUnknownFields()
+		var d T
+		if err := dec.Decode(&d); err != nil {
+			return nil, fmt.Errorf("%s %d: %w", c.kind, code, err)
+		}
+		ds = append(ds, d)
+	}
+
+	if err := c.Validate(ds); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(ds, func(i, j int) bool {
+		return c.codeFunc(ds[i]) < c.codeFunc(ds[j])
+	})
+	return ds, nil
+}
+
+// ValidateDetail validates the common fields of the error detail of the kind (e.g., "xid").
+func ValidateDetail(kind string, code int, name string, eventType apiv1.EventType, actions *apiv1.SuggestedActions, critical bool) error {
+	if code <= 0 {
+		return fmt.Errorf("invalid %s %d", kind, code)
+	}
+	if name == "" {
+		return fmt.Errorf("%s %d: name is required", kind, code)
+	}
+
+	switch eventType {
+	case apiv1.EventTypeInfo, apiv1.EventTypeWarning, apiv1.EventTypeCritical, apiv1.EventTypeFatal:
+	default:
+		return fmt.Errorf("%s %d: unknown event_type %q", kind, code, eventType)
+	}
+
+	if actions != nil {
+		for _, action := range actions.RepairActions {
+			switch action {
+			case apiv1.RepairActionTypeIgnoreNoActionRequired,
+				apiv1.RepairActionTypeRebootSystem,
+				apiv1.RepairActionTypeHardwareInspection,
+				apiv1.RepairActionTypeCheckUserAppAndGPU:
+			default:
+				return fmt.Errorf("%s %d: unknown repair action %q", kind, code, action)
+			}
+		}
+		if len(actions.DeprecatedDescriptions) > 0 &&
+			len(actions.DeprecatedDescriptions) != len(actions.RepairActions) {
+			return fmt.Errorf("%s %d: %d descriptions and %d repair actions",
+				kind,
+				code,
+				len(actions.DeprecatedDescriptions),
+				len(actions.RepairActions))
+		}
+	}
+
+	if critical && (actions == nil || len(actions.RepairActions) == 0) {
+		return fmt.Errorf("%s %d: marked as critical, but has no repair actions", kind, code)
+	}
+
+	return nil
+}
@@ -0,0 +1,136 @@
+package catalog
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	apiv1 "github.com/leptonai/gpud/api/v1"
+)
+
+type testDetail struct {
+	Code                      int                     `json:"code"`
+	Name                      string                  `json:"name"`
+	Description               string                  `json:"description"`
+	SuggestedActionsByGPUd    *apiv1.SuggestedActions `json:"suggested_actions_by_gpud,omitempty"`
+	CriticalErrorMarkedByGPUd bool                    `json:"critical_error_marked_by_gpud"`
+	EventType                 apiv1.EventType         `json:"event_type"`
+}
+
+func (d testDetail) validate() error {
+	return ValidateDetail("code", d.Code, d.Name, d.EventType, d.SuggestedActionsByGPUd, d.CriticalErrorMarkedByGPUd)
+}
+
+func newTestCatalog() (*Catalog[testDetail], map[int]testDetail) {
+	builtin := map[int]testDetail{
+		1: {
+			Code:        1,
+			Name:        "foo",
+			Description: "foo error",
+			SuggestedActionsByGPUd: &apiv1.SuggestedActions{
+				RepairActions: []apiv1.RepairActionType{apiv1.RepairActionTypeCheckUserAppAndGPU, apiv1.RepairActionTypeRebootSystem},
+			},
+			EventType: apiv1.EventTypeWarning,
+		},
+	}
+	return New("code", builtin, func(d testDetail) int { return d.Code }, testDetail.validate), builtin
+}
+
+func TestParse(t *testing.T) {
+	c, builtin := newTestCatalog()
+	orig := *builtin[1].SuggestedActionsByGPUd
+
+	ds, err := c.Parse([]byte(`
+- code: 2
+  name: bar
+  event_type: Critical
+- code: 1
+  event_type: Fatal
+  critical_error_marked_by_gpud: true
+  suggested_actions_by_gpud:
+    repair_actions: [HARDWARE_INSPECTION]
+`))
+	require.NoError(t, err)
+	require.Len(t, ds, 2)
+
+	// sorted by the code, only the fields in the file are overridden
+	assert.Equal(t, 1, ds[0].Code)
+	assert.Equal(t, "foo", ds[0].Name)
+	assert.Equal(t, "foo error", ds[0].Description)
+	assert.Equal(t, apiv1.EventTypeFatal, ds[0].EventType)
+	// the suggested actions are replaced as a whole
+	assert.Equal(t, []apiv1.RepairActionType{apiv1.RepairActionTypeHardwareInspection}, ds[0].SuggestedActionsByGPUd.RepairActions)
+	assert.Equal(t, 2, ds[1].Code)
+	assert.Equal(t, "bar", ds[1].Name)
+
+	// the built-in detail is not modified
+	assert.Equal(t, orig, *builtin[1].SuggestedActionsByGPUd)
+	assert.Equal(t, apiv1.EventTypeWarning, builtin[1].EventType)
+
+	c.Set(ds)
+	d, ok := c.Get(2)
+	require.True(t, ok)
+	assert.Equal(t, "bar", d.Name)
+
+	c.Set(nil)
+	_, ok = c.Get(2)
+	assert.False(t, ok)
+}
+
+func TestParseInvalid(t *testing.T) {
+	c, _ := newTestCatalog()
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{name: "not a list", input: "code: 1"},
+		{name: "missing code", input: "- name: foo"},
+		{name: "invalid code", input: "- code: foo"},
+		{name: "new code without name", input: "- code: 2\n  event_type: Warning"},
+		{name: "unknown event type", input: "- code: 1\n  event_type: Bad"},
+		{name: "unknown repair action", input: "- code: 1\n  suggested_actions_by_gpud:\n    repair_actions: [BAD]"},
+		{name: "critical without repair action", input: "- code: 1\n  critical_error_marked_by_gpud: true\n  suggested_actions_by_gpud:\n    repair_actions: []"},
+		{name: "duplicate code", input: "- code: 1\n- code: 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := c.Parse([]byte(tt.input))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestParseUnknownField(t *testing.T) {
+	c, _ := newTestCatalog()
+
+	_, err := c.Parse([]byte("- code: 1\n  event_typ: Fatal"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `"event_typ"`)
+	assert.Contains(t, err.Error(), "code 1")
+
+	// nested fields are validated as well
+	_, err = c.Parse([]byte("- code: 1\n  suggested_actions_by_gpud:\n    repair_action: [REBOOT_SYSTEM]"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `"repair_action"`)
+}
+
+func TestLoad(t *testing.T) {
+	c, _ := newTestCatalog()
+
+	file := filepath.Join(t.TempDir(), "catalog.json")
+	require.NoError(t, os.WriteFile(file, []byte(`[{"code": 1, "event_type": "Critical"}]`), 0644))
+	ds, err := c.Load(file)
+	require.NoError(t, err)
+	require.Len(t, ds, 1)
+	assert.Equal(t, apiv1.EventTypeCritical, ds[0].EventType)
+
+	_, err = c.Load(filepath.Join(t.TempDir(), "does-not-exist.yaml"))
+	assert.Error(t, err)
+
+	require.NoError(t, os.WriteFile(file, []byte(`[{"code": 1, "event_type": "Bad"}]`), 0644))
+	_, err = c.Load(file)
+	assert.ErrorContains(t, err, "failed to parse code catalog file")
+}
@@ -0,0 +1,252 @@
+// Package dcgm runs and parses the NVIDIA Data Center GPU Manager (DCGM) diagnostics.
+package dcgm
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	// MinDiagLevel is the quick diagnostics level (e.g., deployment checks, seconds).
+	MinDiagLevel = 1
+	// MaxDiagLevel is the extended diagnostics level (e.g., hardware stress tests, hours).
+	MaxDiagLevel = 4
+)
+
+var ErrInvalidDiagLevel = fmt.Errorf("invalid dcgm diag level (expected %d-%d)", MinDiagLevel, MaxDiagLevel)
+
+// DiagCommand returns the command to run the DCGM diagnostics of the level
+// with the JSON output.
+// ref. https://docs.nvidia.com/datacenter/dcgm/latest/user-guide/dcgm-diagnostics.html
+func DiagCommand(level int) (string, error) {
+	if level < MinDiagLevel || level > MaxDiagLevel {
+		return "", ErrInvalidDiagLevel
+	}
+	return fmt.Sprintf("dcgmi diag -r %d -j", level), nil
+}
+
+const (
+	DiagStatusPass = "pass"
+	DiagStatusFail = "fail"
+	DiagStatusWarn = "warn"
+	DiagStatusSkip = "skip"
+)
+
+// DiagTestResult is the result of a diagnostics test on a GPU.
+type DiagTestResult struct {
+	// Category is the test category (e.g., "Deployment", "Hardware").
+	Category string `json:"category"`
+	// Test is the test name (e.g., "PCIe", "Memory").
+	Test string `json:"test"`
+	// GPUID is the DCGM GPU ID, -1 for the test that does not run per GPU
+	// (e.g., the "Denylist" deployment test).
+	GPUID int `json:"gpu_id"`
+	// Status is the lower-cased test status (e.g., "pass", "fail", "warn", "skip").
+	Status string `json:"status"`
+	// Warnings is the list of the warnings or the failure reasons.
+	Warnings []string `json:"warnings,omitempty"`
+	// Info is the list of the additional information.
+	Info []string `json:"info,omitempty"`
+}
+
+// Failed returns true if the test failed.
+func (r DiagTestResult) Failed() bool {
+	return r.Status == DiagStatusFail
+}
+
+// DiagResult is the parsed DCGM diagnostics result.
+type DiagResult struct {
+	// Version is the DCGM version (e.g., "3.3.5").
+	Version string `json:"version,omitempty"`
+	// DriverVersion is the NVIDIA driver version detected by DCGM.
+	DriverVersion string `json:"driver_version,omitempty"`
+	// Tests is the list of the per-test, per-GPU results in the output order.
+	Tests []DiagTestResult `json:"tests"`
+}
+
+// Failures returns the failed tests.
+func (r *DiagResult) Failures() []DiagTestResult {
+	if r == nil {
+		return nil
+	}
+	var failures []DiagTestResult
+	for _, t := range r.Tests {
+		if t.Failed() {
+			failures = append(failures, t)
+		}
+	}
+	return failures
+}
+
+// Passed returns true if no test failed.
+func (r *DiagResult) Passed() bool {
+	return len(r.Failures()) == 0
+}
+
+var ErrNoDiagResult = errors.New("no dcgm diag result found in the output")
+
+// ParseDiagOutput parses the "dcgmi diag -j" output,
+// ignoring the non-JSON messages around the result (e.g., stderr warnings).
+func ParseDiagOutput(b []byte) (*DiagResult, error) {
+	start := bytes.IndexByte(b, '{')
+	end := bytes.LastIndexByte(b, '}')
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("%w: %s", ErrNoDiagResult, truncate(b))
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(b[start:end+1], &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse dcgm diag output: %w", err)
+	}
+
+	// e.g., "DCGM GPU Diagnostic" (DCGM 3) or "DCGM Diagnostic" (DCGM 4)
+	for _, v := range raw {
+		var diag rawDiag
+		if err := json.Unmarshal(v, &diag); err != nil || len(diag.TestCategories) == 0 {
+			continue
+		}
+		return diag.toResult(), nil
+	}
+
+	// reports the error of the failed run (e.g., host engine not running)
+	var failed struct {
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(b[start:end+1], &failed); err == nil && failed.Error != "" {
+		return nil, fmt.Errorf("dcgm diag failed: %s", failed.Error)
+	}
+	return nil, fmt.Errorf("%w: %s", ErrNoDiagResult, truncate(b))
+}
+
+type rawDiag struct {
+	Version        string `json:"version"`
+	DriverVersion  string `json:"Driver Version Detected"`
+	TestCategories []struct {
+		Category string `json:"category"`
+		Tests    []struct {
+			Name    string          `json:"name"`
+			Results []rawTestResult `json:"results"`
+		} `json:"tests"`
+	} `json:"test_categories"`
+}
+
+type rawTestResult struct {
+	// e.g., "0" or "0,1" (DCGM 3)
+	GPUIDs json.RawMessage `json:"gpu_ids"`
+	// e.g., 0 (DCGM 4)
+	EntityID    *int            `json:"entity_id"`
+	EntityGroup string          `json:"entity_group"`
+	Status      string          `json:"status"`
+	Warnings    json.RawMessage `json:"warnings"`
+	Info        json.RawMessage `json:"info"`
+}
+
+func (d rawDiag) toResult() *DiagResult {
+	result := &DiagResult{
+		Version:       d.Version,
+		DriverVersion: d.DriverVersion,
+	}
+	for _, category := range d.TestCategories {
+		for _, test := range category.Tests {
+			for _, r := range test.Results {
+				if r.EntityGroup != "" && !strings.EqualFold(r.EntityGroup, "GPU") {
+					continue
+				}
+				for _, gpuID := range r.gpuIDs() {
+					result.Tests = append(result.Tests, DiagTestResult{
+						Category: category.Category,
+						Test:     test.Name,
+						GPUID:    gpuID,
+						Status:   strings.ToLower(strings.TrimSpace(r.Status)),
+						Warnings: parseMessages(r.Warnings),
+						Info:     parseMessages(r.Info),
+					})
+				}
+			}
+		}
+	}
+	return result
+}
+
+// gpuIDs returns the GPU IDs of the result, or [-1] if not per GPU.
+func (r rawTestResult) gpuIDs() []int {
+	if r.EntityID != nil {
+		return []int{*r.EntityID}
+	}
+
+	var s string
+	if err := json.Unmarshal(r.GPUIDs, &s); err != nil {
+		var n int
+		if err := json.Unmarshal(r.GPUIDs, &n); err == nil {
+			return []int{n}
+		}
+		return []int{-1}
+	}
+
+	var ids []int
+	for _, f := range strings.Split(s, ",") {
+		id, err := strconv.Atoi(strings.TrimSpace(f))
+		if err != nil {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 {
+		return []int{-1}
+	}
+	return ids
+}
+
+// parseMessages parses the warnings or the info, which is either a string,
+// a list of strings, or a list of objects with the message (e.g., {"warning": "..."}).
+func parseMessages(raw json.RawMessage) []string {
+	if len(raw) == 0 {
+		return nil
+	}
+
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		if s = strings.TrimSpace(s); s != "" {
+			return []string{s}
+		}
+		return nil
+	}
+
+	var items []json.RawMessage
+	if err := json.Unmarshal(raw, &items); err != nil {
+		return nil
+	}
+	var msgs []string
+	for _, item := range items {
+		var msg string
+		if err := json.Unmarshal(item, &msg); err != nil {
+			var obj struct {
+				Warning string `json:"warning"`
+				Message string `json:"message"`
+			}
+			if err := json.Unmarshal(item, &obj); err != nil {
+				continue
+			}
+			msg = obj.Warning
+			if msg == "" {
+				msg = obj.Message
+			}
+		}
+		if msg = strings.TrimSpace(msg); msg != "" {
+			msgs = append(msgs, msg)
+		}
+	}
+	return msgs
+}
+
+func truncate(b []byte) string {
+	s := strings.TrimSpace(string(b))
+	if len(s) > 256 {
+		s = s[:256] + "..."
+	}
+	return s
+}
@@ -0,0 +1,103 @@
+package dcgm
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiagCommand(t *testing.T) {
+	cmd, err := DiagCommand(2)
+	require.NoError(t, err)
+	assert.Equal(t, "dcgmi diag -r 2 -j", cmd)
+
+	_, err = DiagCommand(0)
+	assert.ErrorIs(t, err, ErrInvalidDiagLevel)
+	_, err = DiagCommand(5)
+	assert.ErrorIs(t, err, ErrInvalidDiagLevel)
+}
+
+func TestParseDiagOutput(t *testing.T) {
+	b, err := os.ReadFile(filepath.Join("testdata", "dcgmi-diag-r2.json"))
+	require.NoError(t, err)
+
+	result, err := ParseDiagOutput(b)
+	require.NoError(t, err)
+	assert.Equal(t, "3.3.5", result.Version)
+	assert.Equal(t, "535.161.08", result.DriverVersion)
+	require.Len(t, result.Tests, 13)
+
+	assert.Equal(t, DiagTestResult{Category: "Deployment", Test: "Denylist", GPUID: -1, Status: DiagStatusPass}, result.Tests[0])
+
+	// the results for the multiple GPUs are expanded per GPU
+	assert.Equal(t, "Persistence Mode", result.Tests[2].Test)
+	assert.Equal(t, 0, result.Tests[2].GPUID)
+	assert.Equal(t, 1, result.Tests[3].GPUID)
+
+	warn := result.Tests[6]
+	assert.Equal(t, "Page Retirement/Row Remap", warn.Test)
+	assert.Equal(t, DiagStatusWarn, warn.Status)
+	require.Len(t, warn.Warnings, 1)
+	assert.Contains(t, warn.Warnings[0], "row remapping is pending")
+
+	failures := result.Failures()
+	require.Len(t, failures, 1)
+	assert.Equal(t, "Integration", failures[0].Category)
+	assert.Equal(t, "PCIe", failures[0].Test)
+	assert.Equal(t, 1, failures[0].GPUID)
+	assert.Equal(t, []string{"GPU to Host bandwidth:\t\t3.12 GB/s", "Host to GPU bandwidth:\t\t3.08 GB/s"}, failures[0].Info)
+	assert.Contains(t, failures[0].Warnings[0], "observed 3.08 GB/s")
+	assert.False(t, result.Passed())
+
+	assert.Equal(t, DiagStatusSkip, result.Tests[len(result.Tests)-1].Status)
+}
+
+func TestParseDiagOutputDCGM4(t *testing.T) {
+	b, err := os.ReadFile(filepath.Join("testdata", "dcgmi-diag-r1-dcgm4.json"))
+	require.NoError(t, err)
+
+	result, err := ParseDiagOutput(b)
+	require.NoError(t, err)
+	assert.Equal(t, "4.1.1", result.Version)
+
+	// the non-GPU entities are skipped
+	require.Len(t, result.Tests, 2)
+	assert.Equal(t, DiagTestResult{Category: "Deployment", Test: "software", GPUID: 0, Status: DiagStatusPass}, result.Tests[0])
+	assert.True(t, result.Tests[1].Failed())
+	assert.Equal(t, 1, result.Tests[1].GPUID)
+	require.Len(t, result.Tests[1].Warnings, 1)
+	assert.Contains(t, result.Tests[1].Warnings[0], "Persistence mode for GPU 1 is disabled")
+}
+
+func TestParseDiagOutputError(t *testing.T) {
+	b, err := os.ReadFile(filepath.Join("testdata", "dcgmi-diag-hostengine-down.txt"))
+	require.NoError(t, err)
+
+	_, err = ParseDiagOutput(b)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrNoDiagResult))
+	assert.Contains(t, err.Error(), "Unable to connect to host engine")
+
+	_, err = ParseDiagOutput([]byte(`{"error": "Unable to run diagnostics: the GPUs are in use"}`))
+	require.Error(t, err)
+	assert.Equal(t, "dcgm diag failed: Unable to run diagnostics: the GPUs are in use", err.Error())
+
+	_, err = ParseDiagOutput([]byte(`{"DCGM GPU Diagnostic": `))
+	require.Error(t, err)
+}
+
+func TestDiagResultPassed(t *testing.T) {
+	var result *DiagResult
+	assert.Nil(t, result.Failures())
+	assert.True(t, result.Passed())
+
+	result = &DiagResult{Tests: []DiagTestResult{
+		{Test: "PCIe", GPUID: 0, Status: DiagStatusPass},
+		{Test: "PCIe", GPUID: 1, Status: DiagStatusWarn},
+	}}
+	assert.True(t, result.Passed())
+}
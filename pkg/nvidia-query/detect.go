@@ -3,16 +3,24 @@ package query
 import (
 	"context"
 	"fmt"
+	"os"
 	"strings"
 
 	"github.com/leptonai/gpud/pkg/file"
 	"github.com/leptonai/gpud/pkg/log"
 	"github.com/leptonai/gpud/pkg/nvidia-query/nvml"
+	nvmllib "github.com/leptonai/gpud/pkg/nvidia-query/nvml/lib"
 	"github.com/leptonai/gpud/pkg/process"
 )
 
 // Returns true if the local machine has NVIDIA GPUs installed.
 func GPUsInstalled(ctx context.Context) (bool, error) {
+	// the NVML fixture simulates the GPUs without the PCI devices
+	if file := os.Getenv(nvmllib.EnvNVMLFixture); file != "" {
+		log.Logger.Infow("nvml fixture set, assuming nvidia gpus installed", "file", file)
+		return true, nil
+	}
+
 	// now that nvidia-smi installed,
 	// check the NVIDIA GPU presence via PCI bus
 	pciDevices, err := ListNVIDIAPCIs(ctx)
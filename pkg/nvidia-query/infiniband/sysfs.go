@@ -0,0 +1,234 @@
+package infiniband
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultSysfsRoot is the sysfs directory of the infiniband devices.
+// e.g., "/sys/class/infiniband/mlx5_0/ports/1/state"
+const DefaultSysfsRoot = "/sys/class/infiniband"
+
+// Port error counters under "/sys/class/infiniband/<device>/ports/<port>/counters".
+// ref. https://enterprise-support.nvidia.com/s/article/understanding-mlx5-linux-counters-and-status-parameters
+const (
+	CounterSymbolError                  = "symbol_error"
+	CounterLinkDowned                   = "link_downed"
+	CounterLinkErrorRecovery            = "link_error_recovery"
+	CounterPortRcvErrors                = "port_rcv_errors"
+	CounterExcessiveBufferOverrunErrors = "excessive_buffer_overrun_errors"
+)
+
+var ErrNoSysfsDevice = errors.New("no infiniband device found in sysfs")
+
+// IBPort is the state of an infiniband port read from sysfs.
+type IBPort struct {
+	// Device is the device name (e.g., "mlx5_0").
+	Device string `json:"device"`
+	// Port is the port number (e.g., 1).
+	Port int `json:"port"`
+
+	// State is the logical port state (e.g., "Active", "Down").
+	State string `json:"state"`
+	// PhysicalState is the physical port state (e.g., "LinkUp", "Disabled", "Polling").
+	PhysicalState string `json:"physical_state"`
+	// Rate is the port rate in Gb/sec.
+	Rate int `json:"rate"`
+	// LinkLayer is the link layer (e.g., "InfiniBand", "Ethernet").
+	LinkLayer string `json:"link_layer,omitempty"`
+
+	// Counters is the port counter values, keyed by the counter file name.
+	Counters map[string]uint64 `json:"counters,omitempty"`
+}
+
+// Name returns the port name in the format of "<device>:<port>" (e.g., "mlx5_0:1").
+func (p IBPort) Name() string {
+	return fmt.Sprintf("%s:%d", p.Device, p.Port)
+}
+
+type IBPorts []IBPort
+
+// IBStatCards converts the ports to the ibstat cards,
+// using the first port of each device as "Port 1" (same as ibstat).
+func (ports IBPorts) IBStatCards() IBStatCards {
+	cards := make(IBStatCards, 0, len(ports))
+	for _, p := range ports {
+		if p.Port != 1 {
+			continue
+		}
+		cards = append(cards, IBStatCard{
+			Name: p.Device,
+			Port1: IBStatPort{
+				State:         p.State,
+				PhysicalState: p.PhysicalState,
+				Rate:          p.Rate,
+				LinkLayer:     p.LinkLayer,
+			},
+		})
+	}
+	return cards
+}
+
+// GetIBPorts reads the state, physical state, rate and counters of
+// all the infiniband ports under the sysfs root directory
+// (e.g., "/sys/class/infiniband/*/ports/*").
+// Returns ErrNoSysfsDevice if the root directory does not exist or has no port.
+// It does not require the "ibstat" command.
+func GetIBPorts(root string) (IBPorts, error) {
+	portDirs, err := filepath.Glob(filepath.Join(root, "*", "ports", "*"))
+	if err != nil {
+		return nil, err
+	}
+	if len(portDirs) == 0 {
+		return nil, ErrNoSysfsDevice
+	}
+
+	ports := make(IBPorts, 0, len(portDirs))
+	for _, dir := range portDirs {
+		p, err := readIBPort(dir)
+		if err != nil {
+			return nil, err
+		}
+		ports = append(ports, p)
+	}
+
+	sort.Slice(ports, func(i, j int) bool {
+		if ports[i].Device == ports[j].Device {
+			return ports[i].Port < ports[j].Port
+		}
+		return ports[i].Device < ports[j].Device
+	})
+	return ports, nil
+}
+
+// readIBPort reads the port directory (e.g., "/sys/class/infiniband/mlx5_0/ports/1").
+func readIBPort(dir string) (IBPort, error) {
+	portNum, err := strconv.Atoi(filepath.Base(dir))
+	if err != nil {
+		return IBPort{}, fmt.Errorf("invalid port directory %q: %w", dir, err)
+	}
+	p := IBPort{
+		Device: filepath.Base(filepath.Dir(filepath.Dir(dir))),
+		Port:   portNum,
+	}
+
+	// e.g., "4: ACTIVE"
+	state, err := readSysfsFile(filepath.Join(dir, "state"))
+	if err != nil {
+		return IBPort{}, err
+	}
+	p.State = parsePortState(state)
+
+	// e.g., "5: LinkUp"
+	physState, err := readSysfsFile(filepath.Join(dir, "phys_state"))
+	if err != nil {
+		return IBPort{}, err
+	}
+	p.PhysicalState = trimStateCode(physState)
+
+	// e.g., "400 Gb/sec (4X NDR)"
+	rate, err := readSysfsFile(filepath.Join(dir, "rate"))
+	if err != nil {
+		return IBPort{}, err
+	}
+	p.Rate, err = parsePortRate(rate)
+	if err != nil {
+		return IBPort{}, err
+	}
+
+	// optional, e.g., "InfiniBand"
+	if linkLayer, err := readSysfsFile(filepath.Join(dir, "link_layer")); err == nil {
+		p.LinkLayer = linkLayer
+	}
+
+	p.Counters, err = readPortCounters(filepath.Join(dir, "counters"))
+	if err != nil {
+		return IBPort{}, err
+	}
+
+	return p, nil
+}
+
+// readPortCounters reads all the counter files in the directory,
+// skipping the ones that are not readable (e.g., permission denied) or not numbers.
+func readPortCounters(dir string) (map[string]uint64, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	counters := make(map[string]uint64, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		s, err := readSysfsFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			continue
+		}
+		v, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			continue
+		}
+		counters[entry.Name()] = v
+	}
+	return counters, nil
+}
+
+func readSysfsFile(path string) (string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+// trimStateCode trims the numeric state code prefix
+// (e.g., "5: LinkUp" becomes "LinkUp").
+func trimStateCode(s string) string {
+	if idx := strings.Index(s, ":"); idx >= 0 {
+		return strings.TrimSpace(s[idx+1:])
+	}
+	return strings.TrimSpace(s)
+}
+
+// sysfsPortStates maps the sysfs port states to the ones displayed by ibstat.
+var sysfsPortStates = map[string]string{
+	"NOP":          "Nop",
+	"DOWN":         "Down",
+	"INIT":         "Initializing",
+	"ARMED":        "Armed",
+	"ACTIVE":       "Active",
+	"ACTIVE_DEFER": "Active Defer",
+}
+
+// parsePortState parses the sysfs port state (e.g., "4: ACTIVE" becomes "Active").
+func parsePortState(s string) string {
+	s = trimStateCode(s)
+	if state, ok := sysfsPortStates[s]; ok {
+		return state
+	}
+	return s
+}
+
+// parsePortRate parses the sysfs port rate in Gb/sec
+// (e.g., "400 Gb/sec (4X NDR)" becomes 400).
+func parsePortRate(s string) (int, error) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return 0, fmt.Errorf("invalid port rate %q", s)
+	}
+	rate, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid port rate %q: %w", s, err)
+	}
+	return int(rate), nil
+}
@@ -0,0 +1,115 @@
+package infiniband
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetIBPorts(t *testing.T) {
+	ports, err := GetIBPorts(filepath.Join("testdata", "sysfs.class.infiniband"))
+	require.NoError(t, err)
+	require.Len(t, ports, 3)
+
+	assert.Equal(t, "mlx5_0:1", ports[0].Name())
+	assert.Equal(t, "Active", ports[0].State)
+	assert.Equal(t, "LinkUp", ports[0].PhysicalState)
+	assert.Equal(t, 400, ports[0].Rate)
+	assert.Equal(t, "InfiniBand", ports[0].LinkLayer)
+	assert.Equal(t, uint64(3081419280), ports[0].Counters["port_xmit_data"])
+
+	assert.Equal(t, uint64(12), ports[1].Counters[CounterSymbolError])
+	assert.Equal(t, uint64(2), ports[1].Counters[CounterLinkDowned])
+
+	assert.Equal(t, "Down", ports[2].State)
+	assert.Equal(t, "Disabled", ports[2].PhysicalState)
+	assert.Equal(t, 10, ports[2].Rate)
+
+	cards := ports.IBStatCards()
+	require.Len(t, cards, 3)
+	assert.Equal(t, "mlx5_2", cards[2].Name)
+	assert.Equal(t, "Disabled", cards[2].Port1.PhysicalState)
+
+	err = cards.CheckPortsAndRate(3, 400)
+	require.Error(t, err)
+	assert.Equal(t, "only 2 ports (>= 400 Gb/s) are active, expect at least 3; 1 device(s) found Disabled (mlx5_2)", err.Error())
+}
+
+func TestGetIBPortsNoDevice(t *testing.T) {
+	_, err := GetIBPorts(filepath.Join(t.TempDir(), "does-not-exist"))
+	assert.ErrorIs(t, err, ErrNoSysfsDevice)
+
+	_, err = GetIBPorts(t.TempDir())
+	assert.ErrorIs(t, err, ErrNoSysfsDevice)
+}
+
+func TestGetIBPortsInvalid(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "mlx5_0", "ports", "1")
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "state"), []byte("4: ACTIVE\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "phys_state"), []byte("5: LinkUp\n"), 0644))
+
+	// missing rate
+	_, err := GetIBPorts(root)
+	require.Error(t, err)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "rate"), []byte("invalid\n"), 0644))
+	_, err = GetIBPorts(root)
+	require.Error(t, err)
+
+	// missing counters directory is allowed
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "rate"), []byte("200 Gb/sec (4X HDR)\n"), 0644))
+	ports, err := GetIBPorts(root)
+	require.NoError(t, err)
+	require.Len(t, ports, 1)
+	assert.Equal(t, 200, ports[0].Rate)
+	assert.Empty(t, ports[0].Counters)
+}
+
+func TestParsePortState(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"4: ACTIVE", "Active"},
+		{"1: DOWN", "Down"},
+		{"2: INIT", "Initializing"},
+		{"3: ARMED", "Armed"},
+		{"5: ACTIVE_DEFER", "Active Defer"},
+		{"UNKNOWN", "UNKNOWN"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			assert.Equal(t, tt.expected, parsePortState(tt.input))
+		})
+	}
+}
+
+func TestParsePortRate(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int
+		wantErr  bool
+	}{
+		{"400 Gb/sec (4X NDR)", 400, false},
+		{"100 Gb/sec (4X EDR)", 100, false},
+		{"2.5 Gb/sec (1X SDR)", 2, false},
+		{"", 0, true},
+		{"invalid", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			rate, err := parsePortRate(tt.input)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, rate)
+		})
+	}
+}
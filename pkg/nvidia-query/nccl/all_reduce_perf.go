@@ -0,0 +1,225 @@
+package nccl
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DefaultAllReducePerfCommand is the nccl-tests all-reduce benchmark binary.
+// ref. https://github.com/NVIDIA/nccl-tests
+const DefaultAllReducePerfCommand = "all_reduce_perf"
+
+// AllReducePerfArgs returns the default "all_reduce_perf" arguments
+// to sweep the message sizes from 1 MiB to 8 GiB on the local GPUs.
+func AllReducePerfArgs(numGPUs int) string {
+	return fmt.Sprintf("-b 1M -e 8G -f 2 -g %d", numGPUs)
+}
+
+// AllReducePerfStat is the measurement of the out-of-place or in-place all-reduce.
+type AllReducePerfStat struct {
+	// TimeUs is the average time in microseconds.
+	TimeUs float64 `json:"time_us"`
+	// AlgBandwidthGBps is the algorithm bandwidth in GB/s
+	// (the message size divided by the time).
+	AlgBandwidthGBps float64 `json:"alg_bandwidth_gbps"`
+	// BusBandwidthGBps is the bus bandwidth in GB/s, the algorithm bandwidth
+	// corrected for the number of ranks, to compare against the hardware peak.
+	// ref. https://github.com/NVIDIA/nccl-tests/blob/master/doc/PERFORMANCE.md
+	BusBandwidthGBps float64 `json:"bus_bandwidth_gbps"`
+	// Wrong is the number of the wrong elements, if the data validation is enabled.
+	Wrong int64 `json:"wrong"`
+}
+
+// AllReducePerfRow is the result of a message size.
+type AllReducePerfRow struct {
+	SizeBytes  int64             `json:"size_bytes"`
+	Count      int64             `json:"count"`
+	Type       string            `json:"type"`
+	OutOfPlace AllReducePerfStat `json:"out_of_place"`
+	InPlace    AllReducePerfStat `json:"in_place"`
+}
+
+// BusBandwidthGBps returns the higher bus bandwidth of the out-of-place and in-place results.
+func (r AllReducePerfRow) BusBandwidthGBps() float64 {
+	if r.InPlace.BusBandwidthGBps > r.OutOfPlace.BusBandwidthGBps {
+		return r.InPlace.BusBandwidthGBps
+	}
+	return r.OutOfPlace.BusBandwidthGBps
+}
+
+// AllReducePerfResult is the parsed "all_reduce_perf" output.
+type AllReducePerfResult struct {
+	// NumRanks is the number of the ranks (e.g., GPUs) in the test.
+	NumRanks int `json:"num_ranks"`
+	// Rows is the list of the results per message size, in the output order.
+	Rows []AllReducePerfRow `json:"rows"`
+	// OutOfBounds is the number of the out-of-bounds values, non-zero if the validation failed.
+	OutOfBounds int64 `json:"out_of_bounds"`
+	// AvgBusBandwidthGBps is the average bus bandwidth across all message sizes.
+	AvgBusBandwidthGBps float64 `json:"avg_bus_bandwidth_gbps"`
+}
+
+// PeakBusBandwidthGBps returns the highest bus bandwidth across all message sizes,
+// which is typically reached at the largest message sizes.
+func (r *AllReducePerfResult) PeakBusBandwidthGBps() float64 {
+	if r == nil {
+		return 0
+	}
+	peak := 0.0
+	for _, row := range r.Rows {
+		if bw := row.BusBandwidthGBps(); bw > peak {
+			peak = bw
+		}
+	}
+	return peak
+}
+
+// Wrong returns the total number of the wrong elements across all message sizes.
+func (r *AllReducePerfResult) Wrong() int64 {
+	if r == nil {
+		return 0
+	}
+	wrong := r.OutOfBounds
+	for _, row := range r.Rows {
+		wrong += row.OutOfPlace.Wrong + row.InPlace.Wrong
+	}
+	return wrong
+}
+
+var ErrNoAllReducePerfResult = errors.New("no all_reduce_perf result found in the output")
+
+// ParseAllReducePerfOutput parses the "all_reduce_perf" output table, e.g.,
+//
+//	#       size         count      type   redop    root     time   algbw   busbw #wrong     time   algbw   busbw #wrong
+//	#        (B)    (elements)                               (us)  (GB/s)  (GB/s)            (us)  (GB/s)  (GB/s)
+//	  8589934592    2147483648     float     sum      -1  36005.2  238.57  417.50      0  35812.1  239.86  419.76      0
+//
+// The older nccl-tests without the "root" column and with the "error" column
+// (the maximum error rather than the number of the wrong elements) are also supported.
+func ParseAllReducePerfOutput(b []byte) (*AllReducePerfResult, error) {
+	result := &AllReducePerfResult{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		// e.g., "node-1: Test NCCL failure common.cu:954 'unhandled cuda error'"
+		if strings.Contains(line, "Test NCCL failure") || strings.Contains(line, "Test CUDA failure") {
+			return nil, fmt.Errorf("all_reduce_perf failed: %s", line)
+		}
+
+		if strings.HasPrefix(line, "#") {
+			parseAllReducePerfComment(strings.TrimSpace(strings.TrimPrefix(line, "#")), result)
+			continue
+		}
+
+		row, ok := parseAllReducePerfRow(line)
+		if ok {
+			result.Rows = append(result.Rows, row)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(result.Rows) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrNoAllReducePerfResult, truncate(b))
+	}
+	return result, nil
+}
+
+func parseAllReducePerfComment(line string, result *AllReducePerfResult) {
+	switch {
+	// e.g., "Rank  0 Group  0 Pid  12345 on  node-1 device  0 [0x18] NVIDIA H100 80GB HBM3"
+	case strings.HasPrefix(line, "Rank "):
+		result.NumRanks++
+
+	// e.g., "Out of bounds values : 0 OK"
+	case strings.HasPrefix(line, "Out of bounds values"):
+		if fields := strings.Fields(afterColon(line)); len(fields) > 0 {
+			result.OutOfBounds, _ = strconv.ParseInt(fields[0], 10, 64)
+		}
+
+	// e.g., "Avg bus bandwidth    : 247.291"
+	case strings.HasPrefix(line, "Avg bus bandwidth"):
+		result.AvgBusBandwidthGBps, _ = strconv.ParseFloat(strings.TrimSpace(afterColon(line)), 64)
+	}
+}
+
+// parseAllReducePerfRow parses the row of the size, count, type, and the
+// out-of-place and in-place measurements (the last 8 fields).
+func parseAllReducePerfRow(line string) (AllReducePerfRow, bool) {
+	fields := strings.Fields(line)
+	if len(fields) < 11 {
+		return AllReducePerfRow{}, false
+	}
+
+	size, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return AllReducePerfRow{}, false
+	}
+	count, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return AllReducePerfRow{}, false
+	}
+
+	stats := fields[len(fields)-8:]
+	outOfPlace, ok := parseAllReducePerfStat(stats[:4])
+	if !ok {
+		return AllReducePerfRow{}, false
+	}
+	inPlace, ok := parseAllReducePerfStat(stats[4:])
+	if !ok {
+		return AllReducePerfRow{}, false
+	}
+
+	return AllReducePerfRow{
+		SizeBytes:  size,
+		Count:      count,
+		Type:       fields[2],
+		OutOfPlace: outOfPlace,
+		InPlace:    inPlace,
+	}, true
+}
+
+func parseAllReducePerfStat(fields []string) (AllReducePerfStat, bool) {
+	var stat AllReducePerfStat
+	var err error
+	if stat.TimeUs, err = strconv.ParseFloat(fields[0], 64); err != nil {
+		return stat, false
+	}
+	if stat.AlgBandwidthGBps, err = strconv.ParseFloat(fields[1], 64); err != nil {
+		return stat, false
+	}
+	if stat.BusBandwidthGBps, err = strconv.ParseFloat(fields[2], 64); err != nil {
+		return stat, false
+	}
+
+	// "#wrong" is the integer count, or "N/A" if the validation is disabled,
+	// the older "error" column is the maximum error (e.g., "5e-07"), not a count
+	stat.Wrong, _ = strconv.ParseInt(fields[3], 10, 64)
+	return stat, true
+}
+
+func afterColon(s string) string {
+	if idx := strings.Index(s, ":"); idx >= 0 {
+		return s[idx+1:]
+	}
+	return ""
+}
+
+func truncate(b []byte) string {
+	s := strings.TrimSpace(string(b))
+	if len(s) > 256 {
+		s = s[:256] + "..."
+	}
+	return s
+}
@@ -0,0 +1,130 @@
+package nccl
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAllReducePerfOutput(t *testing.T) {
+	b, err := os.ReadFile(filepath.Join("testdata", "all_reduce_perf.h100.txt"))
+	require.NoError(t, err)
+
+	result, err := ParseAllReducePerfOutput(b)
+	require.NoError(t, err)
+	assert.Equal(t, 8, result.NumRanks)
+	require.Len(t, result.Rows, 14)
+	assert.Equal(t, AllReducePerfRow{
+		SizeBytes:  1048576,
+		Count:      262144,
+		Type:       "float",
+		OutOfPlace: AllReducePerfStat{TimeUs: 38.50, AlgBandwidthGBps: 27.24, BusBandwidthGBps: 47.67},
+		InPlace:    AllReducePerfStat{TimeUs: 37.81, AlgBandwidthGBps: 27.73, BusBandwidthGBps: 48.53},
+	}, result.Rows[0])
+
+	last := result.Rows[len(result.Rows)-1]
+	assert.Equal(t, int64(8589934592), last.SizeBytes)
+	assert.Equal(t, 35736.0, last.OutOfPlace.TimeUs)
+	assert.Equal(t, 420.89, last.BusBandwidthGBps())
+
+	assert.Equal(t, 420.89, result.PeakBusBandwidthGBps())
+	assert.Equal(t, 308.412, result.AvgBusBandwidthGBps)
+	assert.Equal(t, int64(0), result.OutOfBounds)
+	assert.Equal(t, int64(0), result.Wrong())
+}
+
+func TestParseAllReducePerfOutputLegacy(t *testing.T) {
+	b, err := os.ReadFile(filepath.Join("testdata", "all_reduce_perf.legacy.txt"))
+	require.NoError(t, err)
+
+	result, err := ParseAllReducePerfOutput(b)
+	require.NoError(t, err)
+	assert.Equal(t, 4, result.NumRanks)
+	require.Len(t, result.Rows, 5)
+
+	// the "error" column is the maximum error, not the wrong element count
+	assert.Equal(t, int64(0), result.Wrong())
+	assert.Equal(t, 121.4, result.Rows[0].OutOfPlace.TimeUs)
+	assert.Equal(t, 155.49, result.PeakBusBandwidthGBps())
+	assert.Equal(t, 136.088, result.AvgBusBandwidthGBps)
+}
+
+func TestParseAllReducePerfOutputWrong(t *testing.T) {
+	result, err := ParseAllReducePerfOutput([]byte(`
+#       size         count      type   redop    root     time   algbw   busbw #wrong     time   algbw   busbw #wrong
+     1048576        262144     float     sum      -1    38.50   27.24   47.67      3    37.81   27.73   48.53    N/A
+# Out of bounds values : 3 FAILED
+# Avg bus bandwidth    : 48.1
+`))
+	require.NoError(t, err)
+	require.Len(t, result.Rows, 1)
+	assert.Equal(t, int64(3), result.Rows[0].OutOfPlace.Wrong)
+	assert.Equal(t, int64(0), result.Rows[0].InPlace.Wrong)
+	assert.Equal(t, int64(3), result.OutOfBounds)
+	assert.Equal(t, int64(6), result.Wrong())
+}
+
+func TestParseAllReducePerfOutputFailure(t *testing.T) {
+	b, err := os.ReadFile(filepath.Join("testdata", "all_reduce_perf.failure.txt"))
+	require.NoError(t, err)
+
+	_, err = ParseAllReducePerfOutput(b)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "all_reduce_perf failed: node-1: Test NCCL failure common.cu:954 'unhandled cuda error")
+
+	_, err = ParseAllReducePerfOutput([]byte("bash: all_reduce_perf: command not found\n"))
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrNoAllReducePerfResult))
+	assert.Contains(t, err.Error(), "command not found")
+
+	var result *AllReducePerfResult
+	assert.Equal(t, 0.0, result.PeakBusBandwidthGBps())
+	assert.Equal(t, int64(0), result.Wrong())
+}
+
+func TestAllReducePerfArgs(t *testing.T) {
+	assert.Equal(t, "-b 1M -e 8G -f 2 -g 8", AllReducePerfArgs(8))
+}
+
+func TestExpectedAllReduceBusBandwidth(t *testing.T) {
+	tests := []struct {
+		productName string
+		numGPUs     int
+		floors      map[string]BusBandwidthFloor
+		expected    float64
+		expectedErr error
+	}{
+		{productName: "NVIDIA H100 80GB HBM3", numGPUs: 8, expected: 370},
+		{productName: "NVIDIA A100-SXM4-80GB", numGPUs: 8, expected: 185},
+		{productName: "NVIDIA A100-SXM4-40GB", numGPUs: 8, expected: 185},
+		{productName: "NVIDIA H100 80GB HBM3", numGPUs: 4, expectedErr: ErrBusBandwidthNumGPUs},
+		{productName: "NVIDIA H100 PCIe", numGPUs: 8, expectedErr: ErrNoExpectedBusBandwidth},
+		{productName: "NVIDIA A10", numGPUs: 8, expectedErr: ErrNoExpectedBusBandwidth},
+		{productName: "", numGPUs: 8, expectedErr: ErrNoExpectedBusBandwidth},
+
+		// configured floors
+		{productName: "NVIDIA H100 80GB HBM3", numGPUs: 4, floors: map[string]BusBandwidthFloor{"H100 80GB HBM3": {GBps: 300, NumGPUs: 4}}, expected: 300},
+		{productName: "NVIDIA H100 PCIe", numGPUs: 2, floors: map[string]BusBandwidthFloor{"h100 pcie": {GBps: 20}}, expected: 20},
+		{productName: "NVIDIA H100 80GB HBM3", numGPUs: 8, floors: map[string]BusBandwidthFloor{"h100 pcie": {GBps: 20}}, expected: 370},
+	}
+	for _, tt := range tests {
+		t.Run(tt.productName, func(t *testing.T) {
+			got, err := ExpectedAllReduceBusBandwidth(tt.floors, tt.productName, tt.numGPUs)
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+
+	// the built-in floors are not modified
+	floors := DefaultBusBandwidthFloors()
+	floors["h100 80gb hbm3"] = BusBandwidthFloor{GBps: 1}
+	assert.Equal(t, 370.0, defaultBusBandwidthFloors["h100 80gb hbm3"].GBps)
+}
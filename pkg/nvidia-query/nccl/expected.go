@@ -0,0 +1,80 @@
+package nccl
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// BusBandwidthFloor is the expected minimum all-reduce bus bandwidth
+// at the large message sizes, measured on the specific number of GPUs.
+type BusBandwidthFloor struct {
+	// GBps is the expected minimum bus bandwidth in GB/s.
+	GBps float64 `json:"gbps"`
+	// NumGPUs is the number of GPUs that the floor is measured on,
+	// as the bus bandwidth varies by the number of GPUs (e.g., NVLink vs. PCIe peers).
+	// Zero to apply the floor to any number of GPUs.
+	NumGPUs int `json:"num_gpus"`
+}
+
+// defaultBusBandwidthFloors is the expected minimum all-reduce bus bandwidth
+// on all 8 GPUs of the NVSwitch baseboard,
+// about 80% of the typically measured values.
+// The products are matched by the lower-cased product name,
+// and the PCIe or NVL variants are not listed, as they vary by the server.
+// ref. https://github.com/NVIDIA/nccl-tests/blob/master/doc/PERFORMANCE.md
+var defaultBusBandwidthFloors = map[string]BusBandwidthFloor{
+	// e.g., "NVIDIA A100-SXM4-80GB" (NVLink 3, 600 GB/s per GPU)
+	"a100-sxm4": {GBps: 185, NumGPUs: 8},
+
+	// e.g., "NVIDIA H100 80GB HBM3" (NVLink 4, 900 GB/s per GPU)
+	"h100 80gb hbm3": {GBps: 370, NumGPUs: 8},
+}
+
+var (
+	ErrNoExpectedBusBandwidth = errors.New("no expected all-reduce bus bandwidth found (not supported)")
+	ErrBusBandwidthNumGPUs    = errors.New("expected all-reduce bus bandwidth is for a different number of gpus")
+)
+
+// DefaultBusBandwidthFloors returns a copy of the built-in expected minimum
+// all-reduce bus bandwidth, keyed by the lower-cased product name.
+func DefaultBusBandwidthFloors() map[string]BusBandwidthFloor {
+	floors := make(map[string]BusBandwidthFloor, len(defaultBusBandwidthFloors))
+	for k, v := range defaultBusBandwidthFloors {
+		floors[k] = v
+	}
+	return floors
+}
+
+// ExpectedAllReduceBusBandwidth returns the expected minimum all-reduce bus bandwidth
+// in GB/s for the GPU product on the number of GPUs.
+// The floors override or extend the built-in ones, keyed by the lower-cased product name
+// (e.g., "h100 80gb hbm3"), where the longest match wins.
+// Returns "ErrBusBandwidthNumGPUs" if the floor is measured on a different number of GPUs,
+// rather than comparing the bandwidth against the wrong floor.
+func ExpectedAllReduceBusBandwidth(floors map[string]BusBandwidthFloor, gpuProductName string, numGPUs int) (float64, error) {
+	merged := DefaultBusBandwidthFloors()
+	for k, v := range floors {
+		merged[strings.ToLower(k)] = v
+	}
+
+	p := strings.ToLower(gpuProductName)
+
+	longestMatch := ""
+	for gpuType := range merged {
+		if strings.Contains(p, gpuType) {
+			if len(gpuType) > len(longestMatch) {
+				longestMatch = gpuType
+			}
+		}
+	}
+	if longestMatch == "" {
+		return 0, ErrNoExpectedBusBandwidth
+	}
+
+	floor := merged[longestMatch]
+	if floor.NumGPUs > 0 && floor.NumGPUs != numGPUs {
+		return 0, fmt.Errorf("%w (expected %d gpu(s), running on %d gpu(s))", ErrBusBandwidthNumGPUs, floor.NumGPUs, numGPUs)
+	}
+	return floor.GBps, nil
+}
@@ -245,3 +245,48 @@ var clockEventReasonsToInclude = map[uint64]reasonType{
 		isHWSlowdown: false,
 	},
 }
+
+// Short names of the clock event reasons that reduce the clocks,
+// used to break down the time a GPU spends in each reason
+// (e.g., as a metric label).
+const (
+	ClockEventReasonSWPowerCap           = "sw_power_cap"
+	ClockEventReasonHWSlowdown           = "hw_slowdown"
+	ClockEventReasonHWThermalSlowdown    = "hw_thermal_slowdown"
+	ClockEventReasonHWPowerBrakeSlowdown = "hw_power_brake_slowdown"
+	ClockEventReasonSyncBoost            = "sync_boost"
+	ClockEventReasonSWThermalSlowdown    = "sw_thermal_slowdown"
+)
+
+// ClockEventReasons lists the short names of the clock event reasons
+// returned by ActiveClockEventReasons, in a deterministic order.
+var ClockEventReasons = []string{
+	ClockEventReasonSWPowerCap,
+	ClockEventReasonHWSlowdown,
+	ClockEventReasonHWThermalSlowdown,
+	ClockEventReasonHWPowerBrakeSlowdown,
+	ClockEventReasonSyncBoost,
+	ClockEventReasonSWThermalSlowdown,
+}
+
+var clockEventReasonFlags = map[string]uint64{
+	ClockEventReasonSWPowerCap:           reasonSWPowerCap,
+	ClockEventReasonHWSlowdown:           reasonHWSlowdown,
+	ClockEventReasonHWThermalSlowdown:    reasonHWSlowdownThermal,
+	ClockEventReasonHWPowerBrakeSlowdown: reasonHWSlowdownPowerBrake,
+	ClockEventReasonSyncBoost:            reasonSyncBoost,
+	ClockEventReasonSWThermalSlowdown:    reasonSwThermalSlowdown,
+}
+
+// ActiveClockEventReasons returns the short names of the active clock event reasons
+// in the bitmask (see ClockEventReasons), ignoring the idle, applications clocks,
+// and display clock settings.
+func ActiveClockEventReasons(bitmask uint64) []string {
+	var active []string
+	for _, name := range ClockEventReasons {
+		if bitmask&clockEventReasonFlags[name] != 0 {
+			active = append(active, name)
+		}
+	}
+	return active
+}
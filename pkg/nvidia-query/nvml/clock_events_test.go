@@ -487,3 +487,16 @@ func TestClockEventsWithNilPointer(t *testing.T) {
 		assert.Nil(t, result)
 	})
 }
+
+func TestActiveClockEventReasons(t *testing.T) {
+	assert.Empty(t, ActiveClockEventReasons(0))
+	assert.Empty(t, ActiveClockEventReasons(reasonGPUIdle|reasonApplicationsClocksSetting|reasonDisplayClockSetting))
+	assert.Equal(t,
+		[]string{ClockEventReasonSWPowerCap, ClockEventReasonSWThermalSlowdown},
+		ActiveClockEventReasons(reasonGPUIdle|reasonSWPowerCap|reasonSwThermalSlowdown),
+	)
+	assert.Equal(t,
+		[]string{ClockEventReasonHWSlowdown, ClockEventReasonHWThermalSlowdown, ClockEventReasonHWPowerBrakeSlowdown, ClockEventReasonSyncBoost},
+		ActiveClockEventReasons(reasonHWSlowdown|reasonHWSlowdownThermal|reasonHWSlowdownPowerBrake|reasonSyncBoost),
+	)
+}
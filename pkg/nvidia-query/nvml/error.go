@@ -50,6 +50,19 @@ func IsNotFoundError(ret nvml.Return) bool {
 	return strings.Contains(e, "not found") || strings.Contains(e, "not_found")
 }
 
+// IsGPULostError returns true if the error indicates that the GPU is lost,
+// meaning that the GPU has fallen off the bus or is otherwise inaccessible.
+// e.g.,
+// "GPU is lost"
+func IsGPULostError(ret nvml.Return) bool {
+	if ret == nvml.ERROR_GPU_IS_LOST {
+		return true
+	}
+
+	e := normalizeNVMLReturnString(ret)
+	return strings.Contains(e, "gpu is lost")
+}
+
 // normalizeNVMLReturnString normalizes an NVML return to a string.
 func normalizeNVMLReturnString(ret nvml.Return) string {
 	s := nvml.ErrorString(ret)
@@ -525,3 +525,79 @@ func TestIsNotFoundError(t *testing.T) {
 		})
 	}
 }
+
+func TestIsGPULostError(t *testing.T) {
+	tests := []struct {
+		name     string
+		ret      nvml.Return
+		expected bool
+	}{
+		{
+			name:     "Direct ERROR_GPU_IS_LOST match",
+			ret:      nvml.ERROR_GPU_IS_LOST,
+			expected: true,
+		},
+		{
+			name:     "Success is not a gpu-lost error",
+			ret:      nvml.SUCCESS,
+			expected: false,
+		},
+		{
+			name:     "Unknown error is not a gpu-lost error",
+			ret:      nvml.ERROR_UNKNOWN,
+			expected: false,
+		},
+		{
+			name:     "Not found error is not a gpu-lost error",
+			ret:      nvml.ERROR_NOT_FOUND,
+			expected: false,
+		},
+	}
+
+	originalErrorString := nvml.ErrorString
+	defer func() {
+		nvml.ErrorString = originalErrorString
+	}()
+
+	nvml.ErrorString = func(ret nvml.Return) string {
+		switch ret {
+		case nvml.Return(1000):
+			return "GPU is lost"
+		case nvml.Return(1001):
+			return "  the gpu is lost, reboot the system  "
+		case nvml.Return(1002):
+			return "GPU lost"
+		default:
+			return originalErrorString(ret)
+		}
+	}
+
+	tests = append(tests, []struct {
+		name     string
+		ret      nvml.Return
+		expected bool
+	}{
+		{
+			name:     "String contains 'GPU is lost'",
+			ret:      nvml.Return(1000),
+			expected: true,
+		},
+		{
+			name:     "String contains 'gpu is lost' within a longer message",
+			ret:      nvml.Return(1001),
+			expected: true,
+		},
+		{
+			name:     "String with similar but not exact match",
+			ret:      nvml.Return(1002),
+			expected: false,
+		},
+	}...)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := IsGPULostError(tt.ret)
+			assert.Equal(t, tt.expected, result, "IsGPULostError(%v) = %v, want %v", tt.ret, result, tt.expected)
+		})
+	}
+}
@@ -53,8 +53,9 @@ type GPMMetrics struct {
 // Don't call these in parallel for multiple devices.
 // It "SIGSEGV: segmentation violation" in cgo execution.
 // Returns nil if it's not supported.
+// The samples are allocated and evaluated with the NVML interface (e.g., "nvmllib.Library.NVML()").
 // ref. https://github.com/NVIDIA/go-nvml/blob/main/examples/gpm-metrics/main.go
-func GetGPMMetrics(ctx context.Context, dev device.Device, sampleDuration time.Duration, metricIDs ...nvml.GpmMetricId) (map[nvml.GpmMetricId]float64, error) {
+func GetGPMMetrics(ctx context.Context, nvmlLib nvml.Interface, dev device.Device, sampleDuration time.Duration, metricIDs ...nvml.GpmMetricId) (map[nvml.GpmMetricId]float64, error) {
 	if len(metricIDs) == 0 {
 		return nil, fmt.Errorf("no metric IDs provided")
 	}
@@ -62,7 +63,7 @@ func GetGPMMetrics(ctx context.Context, dev device.Device, sampleDuration time.D
 		return nil, fmt.Errorf("too many metric IDs provided (%d > 98)", len(metricIDs))
 	}
 
-	sample1, ret := nvml.GpmSampleAlloc()
+	sample1, ret := nvmlLib.GpmSampleAlloc()
 	if IsNotSupportError(ret) {
 		return nil, nil
 	}
@@ -80,7 +81,7 @@ func GetGPMMetrics(ctx context.Context, dev device.Device, sampleDuration time.D
 		_ = sample1.Free()
 	}()
 
-	sample2, ret := nvml.GpmSampleAlloc()
+	sample2, ret := nvmlLib.GpmSampleAlloc()
 	if ret != nvml.SUCCESS {
 		return nil, fmt.Errorf("could not allocate sample: %v", nvml.ErrorString(ret))
 	}
@@ -113,7 +114,7 @@ func GetGPMMetrics(ctx context.Context, dev device.Device, sampleDuration time.D
 	for i := range metricIDs {
 		gpmMetric.Metrics[i].MetricId = uint32(metricIDs[i])
 	}
-	if ret = nvml.GpmMetricsGet(&gpmMetric); ret != nvml.SUCCESS {
+	if ret = nvmlLib.GpmMetricsGet(&gpmMetric); ret != nvml.SUCCESS {
 		return nil, fmt.Errorf("failed to get gpm metric: %v", nvml.ErrorString(ret))
 	}
 	if len(gpmMetric.Metrics) == len(metricIDs) {
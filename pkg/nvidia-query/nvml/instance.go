@@ -49,7 +49,7 @@ func NewInstanceV2() (InstanceV2, error) {
 		return nil, err
 	}
 
-	cudaVersion, err := getCUDAVersion(nvmlLib.NVML())
+	cudaVersion, err := GetSystemCUDAVersion(nvmlLib.NVML())
 	if err != nil {
 		return nil, err
 	}
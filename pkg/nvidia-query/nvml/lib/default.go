@@ -13,6 +13,7 @@ import (
 
 const (
 	EnvMockAllSuccess              = "GPUD_NVML_MOCK_ALL_SUCCESS"
+	EnvNVMLFixture                 = "GPUD_NVML_FIXTURE"
 	EnvInjectRemapedRowsPending    = "GPUD_NVML_INJECT_REMAPPED_ROWS_PENDING"
 	EnvInjectClockEventsHwSlowdown = "GPUD_NVML_INJECT_CLOCK_EVENTS_HW_SLOWDOWN"
 )
@@ -23,7 +24,18 @@ var ErrNVMLNotFound = errors.New("NVML not found")
 // It returns nil and error, if NVML is not supported.
 // It also injects the mock data if the environment variables are set.
 func New(opts ...OpOption) (Library, error) {
-	if os.Getenv(EnvMockAllSuccess) == "true" {
+	if file := os.Getenv(EnvNVMLFixture); file != "" {
+		fixture, err := nvml_lib_mock.LoadFixture(file)
+		if err != nil {
+			return nil, err
+		}
+		log.Logger.Infow("using nvml fixture", "file", file, "gpus", len(fixture.GPUs), "changes", len(fixture.Changes))
+
+		opts = append(opts,
+			WithNVML(nvml_lib_mock.NewFixtureInterface(fixture)),
+			WithPropertyExtractor(nvml_lib_mock.HasNvmlPropertyExtractor),
+		)
+	} else if os.Getenv(EnvMockAllSuccess) == "true" {
 		opts = append(opts,
 			WithNVML(nvml_lib_mock.AllSuccessInterface),
 			WithPropertyExtractor(nvml_lib_mock.HasNvmlPropertyExtractor),
@@ -94,8 +94,40 @@ func TestNewDefaultMultipleEnvVars(t *testing.T) {
 	assert.Equal(t, nvml.SUCCESS, retClock)
 }
 
+// TestNewDefaultNVMLFixture tests the NewDefault function when EnvNVMLFixture is set
+func TestNewDefaultNVMLFixture(t *testing.T) {
+	cleanupEnvVars()
+	defer cleanupEnvVars()
+
+	os.Setenv(EnvNVMLFixture, "mock/testdata/fixture.yaml")
+
+	lib, err := New()
+	require.NoError(t, err)
+	assert.NotNil(t, lib)
+
+	devices, err := lib.Device().GetDevices()
+	require.NoError(t, err)
+	require.Len(t, devices, 2)
+
+	busID, err := devices[1].GetPCIBusID()
+	require.NoError(t, err)
+	assert.Equal(t, "0000:2a:00.0", busID)
+
+	temp, ret := devices[0].GetTemperature(nvml.TEMPERATURE_GPU)
+	assert.Equal(t, nvml.SUCCESS, ret)
+	assert.Equal(t, uint32(35), temp)
+
+	hasNvml, _ := lib.Info().HasNvml()
+	assert.True(t, hasNvml)
+
+	os.Setenv(EnvNVMLFixture, "mock/testdata/not-found.yaml")
+	_, err = New()
+	assert.Error(t, err)
+}
+
 // Utility function to clean up environment variables
 func cleanupEnvVars() {
+	os.Unsetenv(EnvNVMLFixture)
 	os.Unsetenv(EnvMockAllSuccess)
 	os.Unsetenv(EnvInjectRemapedRowsPending)
 	os.Unsetenv(EnvInjectClockEventsHwSlowdown)
@@ -0,0 +1,514 @@
+package mock
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// Fixture describes the fake GPUs served by the fixture-driven NVML mock,
+// so that "gpud run" and "gpud scan" exercise the accelerator components
+// end-to-end without the NVIDIA GPUs (e.g., on a laptop).
+// The scripted changes are applied over time to simulate failures.
+type Fixture struct {
+	// DriverVersion is the NVIDIA driver version (e.g., "535.161.08").
+	DriverVersion string `json:"driver_version"`
+	// CUDADriverVersion is the CUDA driver version in NVML format (e.g., 12020 for "12.2").
+	CUDADriverVersion int `json:"cuda_driver_version"`
+
+	// GPUs is the initial states of the GPUs.
+	GPUs []FixtureGPU `json:"gpus"`
+
+	// Changes is the list of the changes to apply over time,
+	// relative to the time the fixture is loaded.
+	Changes []FixtureChange `json:"changes,omitempty"`
+
+	mu sync.Mutex
+	// the time the fixture is loaded, where the changes are relative to
+	startTime time.Time
+	now       func() time.Time
+	// the indexes of the changes whose events have been delivered as NVML events
+	delivered map[int]struct{}
+}
+
+// FixtureGPU describes the state of a single fake GPU.
+type FixtureGPU struct {
+	Name string `json:"name"`
+	UUID string `json:"uuid"`
+	// PCIBusID is the PCI bus ID (e.g., "0000:9b:00.0").
+	PCIBusID    string `json:"pci_bus_id"`
+	MinorNumber int    `json:"minor_number"`
+	// Architecture is the GPU architecture (e.g., "hopper", "ampere").
+	Architecture string `json:"architecture,omitempty"`
+	// CUDAComputeCapability is the CUDA compute capability (e.g., "9.0").
+	CUDAComputeCapability string `json:"cuda_compute_capability,omitempty"`
+	NumCores              int    `json:"num_cores,omitempty"`
+
+	// Lost is true to simulate the GPU that has fallen off the bus,
+	// where the device queries fail with "GPU is lost".
+	Lost bool `json:"lost,omitempty"`
+
+	PersistenceMode    bool `json:"persistence_mode"`
+	GSPFirmwareEnabled bool `json:"gsp_firmware_enabled"`
+	// VBIOSVersion is the VBIOS version (e.g., "96.00.89.00.01"),
+	// empty to simulate the GPU without the VBIOS version query support.
+	VBIOSVersion string `json:"vbios_version,omitempty"`
+
+	TemperatureCelsius    uint32                       `json:"temperature_celsius"`
+	TemperatureThresholds FixtureTemperatureThresholds `json:"temperature_thresholds"`
+
+	Memory FixtureMemory `json:"memory"`
+
+	PowerUsageMilliWatts uint32 `json:"power_usage_milliwatts"`
+	PowerLimitMilliWatts uint32 `json:"power_limit_milliwatts"`
+
+	Utilization FixtureUtilization `json:"utilization"`
+	Clocks      FixtureClocks      `json:"clocks"`
+
+	// ClockEventsReasons is the bitmask of the current clock event reasons.
+	// ref. https://docs.nvidia.com/deploy/nvml-api/group__nvmlClocksEventReasons.html
+	ClockEventsReasons uint64 `json:"clock_events_reasons"`
+
+	// PCIe is the PCIe link status, zero values to simulate
+	// the GPU without PCIe link queries support.
+	PCIe FixturePCIe `json:"pcie"`
+
+	ECCEnabled   bool                `json:"ecc_enabled"`
+	ECCErrors    FixtureECCErrors    `json:"ecc_errors"`
+	RemappedRows FixtureRemappedRows `json:"remapped_rows"`
+
+	// NVLinks is the list of the NVLink states, indexed by the link number.
+	// Empty to simulate the GPU without NVLink support.
+	NVLinks []FixtureNVLink `json:"nvlinks,omitempty"`
+
+	Processes []FixtureProcess `json:"processes,omitempty"`
+
+	// MIG is the Multi-Instance GPU (MIG) mode and the MIG devices.
+	// Nil to simulate the GPU without MIG support.
+	MIG *FixtureMIG `json:"mig,omitempty"`
+
+	// Topology is the PCIe and NUMA placement of the GPU.
+	// Nil to simulate the GPU without topology queries support.
+	Topology *FixtureTopology `json:"topology,omitempty"`
+
+	GPMSupported bool `json:"gpm_supported"`
+	// GPMMetrics maps the GPM metric ID (e.g., 1 for "GPM_METRIC_GRAPHICS_UTIL")
+	// to the value returned for the metric.
+	GPMMetrics map[int]float64 `json:"gpm_metrics,omitempty"`
+}
+
+type FixtureTemperatureThresholds struct {
+	ShutdownCelsius uint32 `json:"shutdown_celsius"`
+	SlowdownCelsius uint32 `json:"slowdown_celsius"`
+	MemMaxCelsius   uint32 `json:"mem_max_celsius"`
+	GPUMaxCelsius   uint32 `json:"gpu_max_celsius"`
+}
+
+type FixtureMemory struct {
+	TotalBytes    uint64 `json:"total_bytes"`
+	ReservedBytes uint64 `json:"reserved_bytes"`
+	UsedBytes     uint64 `json:"used_bytes"`
+}
+
+type FixtureUtilization struct {
+	GPUPercent    uint32 `json:"gpu_percent"`
+	MemoryPercent uint32 `json:"memory_percent"`
+}
+
+type FixtureClocks struct {
+	GraphicsMHz uint32 `json:"graphics_mhz"`
+	MemoryMHz   uint32 `json:"memory_mhz"`
+}
+
+type FixturePCIe struct {
+	Generation    int `json:"generation"`
+	MaxGeneration int `json:"max_generation"`
+	Width         int `json:"width"`
+	MaxWidth      int `json:"max_width"`
+	ReplayCounter int `json:"replay_counter"`
+	// PerformanceState is the performance state (e.g., 0 for P0).
+	PerformanceState int `json:"performance_state"`
+}
+
+type FixtureECCErrors struct {
+	VolatileCorrected    uint64 `json:"volatile_corrected"`
+	VolatileUncorrected  uint64 `json:"volatile_uncorrected"`
+	AggregateCorrected   uint64 `json:"aggregate_corrected"`
+	AggregateUncorrected uint64 `json:"aggregate_uncorrected"`
+}
+
+type FixtureRemappedRows struct {
+	Corrected   int  `json:"corrected"`
+	Uncorrected int  `json:"uncorrected"`
+	Pending     bool `json:"pending"`
+	Failure     bool `json:"failure"`
+}
+
+type FixtureNVLink struct {
+	FeatureEnabled bool   `json:"feature_enabled"`
+	ReplayErrors   uint64 `json:"replay_errors"`
+	RecoveryErrors uint64 `json:"recovery_errors"`
+	CRCErrors      uint64 `json:"crc_errors"`
+	// ThroughputRawTxKiB and ThroughputRawRxKiB are the cumulative
+	// NVLink throughput counters in KiB.
+	ThroughputRawTxKiB uint64 `json:"throughput_raw_tx_kib"`
+	ThroughputRawRxKiB uint64 `json:"throughput_raw_rx_kib"`
+
+	// RemotePCIBusID is the PCI bus ID of the GPU at the other end of the link.
+	RemotePCIBusID string `json:"remote_pci_bus_id,omitempty"`
+	// RemoteSwitch is true if the link is connected to an NVSwitch.
+	RemoteSwitch bool `json:"remote_switch,omitempty"`
+}
+
+type FixtureTopology struct {
+	// NUMANode is the NUMA node that the GPU is attached to.
+	NUMANode int `json:"numa_node"`
+	// CPUs is the list of the CPUs local to the GPU (e.g., "0-47,96-143").
+	CPUs string `json:"cpus,omitempty"`
+	// PCISwitch is the name of the PCIe switch that the GPU is attached to,
+	// where the GPUs under the same switch are connected via a single bridge.
+	PCISwitch string `json:"pci_switch,omitempty"`
+}
+
+type FixtureProcess struct {
+	PID             uint32 `json:"pid"`
+	UsedMemoryBytes uint64 `json:"used_memory_bytes"`
+	SMUtilPercent   uint32 `json:"sm_util_percent"`
+	MemUtilPercent  uint32 `json:"mem_util_percent"`
+
+	// GPUInstanceID and ComputeInstanceID are the MIG device that runs the process,
+	// only used when the MIG mode is enabled.
+	GPUInstanceID     uint32 `json:"gpu_instance_id,omitempty"`
+	ComputeInstanceID uint32 `json:"compute_instance_id,omitempty"`
+}
+
+type FixtureMIG struct {
+	Enabled bool `json:"enabled"`
+	// Instances is the list of the MIG devices, indexed by the MIG device index.
+	Instances []FixtureMIGInstance `json:"instances,omitempty"`
+}
+
+type FixtureMIGInstance struct {
+	UUID              string `json:"uuid"`
+	GPUInstanceID     int    `json:"gpu_instance_id"`
+	ComputeInstanceID int    `json:"compute_instance_id"`
+	// GPUInstanceSlices and ComputeInstanceSlices are the number of the slices
+	// (e.g., 3 and 3 for "3g.40gb").
+	GPUInstanceSlices     uint32 `json:"gpu_instance_slices"`
+	ComputeInstanceSlices uint32 `json:"compute_instance_slices"`
+	MultiprocessorCount   uint32 `json:"multiprocessor_count"`
+	MemorySizeMB          uint64 `json:"memory_size_mb"`
+	MemoryUsedBytes       uint64 `json:"memory_used_bytes"`
+}
+
+// FixtureChange is a scripted change to the GPU states.
+type FixtureChange struct {
+	// After is the duration since the fixture is loaded, to apply the change.
+	After metav1.Duration `json:"after"`
+	// UUID is the UUID of the GPU to change.
+	// Empty to apply the change to all the GPUs.
+	UUID string `json:"uuid,omitempty"`
+	// Set is the partial GPU state in the same schema as "FixtureGPU",
+	// applied over the current GPU state (e.g., {"temperature_celsius": 95}).
+	// The lists (e.g., "nvlinks") are replaced as a whole.
+	Set json.RawMessage `json:"set,omitempty"`
+	// Xids is the list of the Xids to deliver as the NVML events,
+	// once the change is applied.
+	Xids []uint64 `json:"xids,omitempty"`
+	// SingleBitECCErrors and DoubleBitECCErrors are the number of the ECC error events
+	// to deliver as the NVML events, once the change is applied.
+	SingleBitECCErrors int `json:"single_bit_ecc_errors,omitempty"`
+	DoubleBitECCErrors int `json:"double_bit_ecc_errors,omitempty"`
+}
+
+// LoadFixture loads the fixture from the YAML or JSON file.
+func LoadFixture(file string) (*Fixture, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := parseFixture(b)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse nvml fixture file %q: %w", file, err)
+	}
+	return f, nil
+}
+
+func parseFixture(b []byte) (*Fixture, error) {
+	jb, err := yaml.YAMLToJSON(b)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &Fixture{}
+	if err := json.Unmarshal(jb, f); err != nil {
+		return nil, err
+	}
+	if err := f.Validate(); err != nil {
+		return nil, err
+	}
+
+	// applies the changes in the order of time
+	sort.SliceStable(f.Changes, func(i, j int) bool {
+		return f.Changes[i].After.Duration < f.Changes[j].After.Duration
+	})
+
+	f.now = time.Now
+	f.startTime = f.now()
+	f.delivered = make(map[int]struct{})
+	return f, nil
+}
+
+// Validate validates the fixture.
+func (f *Fixture) Validate() error {
+	if f.DriverVersion == "" {
+		return errors.New("driver_version is required")
+	}
+	if f.CUDADriverVersion <= 0 {
+		return errors.New("cuda_driver_version is required")
+	}
+	if len(f.GPUs) == 0 {
+		return errors.New("no gpu found")
+	}
+
+	uuids := make(map[string]struct{}, len(f.GPUs))
+	for i, gpu := range f.GPUs {
+		if gpu.UUID == "" {
+			return fmt.Errorf("gpu %d: uuid is required", i)
+		}
+		if _, ok := uuids[gpu.UUID]; ok {
+			return fmt.Errorf("duplicate gpu uuid %q", gpu.UUID)
+		}
+		uuids[gpu.UUID] = struct{}{}
+
+		if gpu.Name == "" {
+			return fmt.Errorf("gpu %q: name is required", gpu.UUID)
+		}
+		if _, err := parseArchitecture(gpu.Architecture); err != nil {
+			return fmt.Errorf("gpu %q: %w", gpu.UUID, err)
+		}
+		if _, _, err := parseCUDAComputeCapability(gpu.CUDAComputeCapability); err != nil {
+			return fmt.Errorf("gpu %q: %w", gpu.UUID, err)
+		}
+		if gpu.Topology != nil {
+			if _, err := parseCPUList(gpu.Topology.CPUs); err != nil {
+				return fmt.Errorf("gpu %q: %w", gpu.UUID, err)
+			}
+		}
+	}
+
+	for i, change := range f.Changes {
+		if change.After.Duration < 0 {
+			return fmt.Errorf("change %d: negative after %s", i, change.After.Duration)
+		}
+		if change.UUID != "" {
+			if _, ok := uuids[change.UUID]; !ok {
+				return fmt.Errorf("change %d: unknown gpu uuid %q", i, change.UUID)
+			}
+		}
+		if len(change.Set) > 0 {
+			var gpu FixtureGPU
+			if err := json.Unmarshal(change.Set, &gpu); err != nil {
+				return fmt.Errorf("change %d: %w", i, err)
+			}
+			if gpu.UUID != "" {
+				return fmt.Errorf("change %d: uuid cannot be changed", i)
+			}
+		}
+	}
+
+	return nil
+}
+
+// elapsed returns the duration since the fixture is loaded.
+func (f *Fixture) elapsed() time.Duration {
+	return f.now().Sub(f.startTime)
+}
+
+// snapshot returns the current state of the GPU at the index,
+// with all the changes applied up to now.
+func (f *Fixture) snapshot(idx int) (FixtureGPU, error) {
+	elapsed := f.elapsed()
+
+	gpu := f.GPUs[idx]
+	for _, change := range f.Changes {
+		if change.After.Duration > elapsed {
+			break
+		}
+		if change.UUID != "" && change.UUID != gpu.UUID {
+			continue
+		}
+		if len(change.Set) == 0 {
+			continue
+		}
+
+		// the lists, maps and pointers are shared with the base state,
+		// thus clear them before applying the change, if set by the change
+		var set map[string]json.RawMessage
+		if err := json.Unmarshal(change.Set, &set); err != nil {
+			return FixtureGPU{}, err
+		}
+		if _, ok := set["nvlinks"]; ok {
+			gpu.NVLinks = nil
+		}
+		if _, ok := set["processes"]; ok {
+			gpu.Processes = nil
+		}
+		if _, ok := set["gpm_metrics"]; ok {
+			gpu.GPMMetrics = nil
+		}
+		if _, ok := set["mig"]; ok {
+			gpu.MIG = nil
+		}
+		if _, ok := set["topology"]; ok {
+			gpu.Topology = nil
+		}
+
+		if err := json.Unmarshal(change.Set, &gpu); err != nil {
+			return FixtureGPU{}, err
+		}
+	}
+	return gpu, nil
+}
+
+// findGPU returns the current state of the GPU of the UUID.
+func (f *Fixture) findGPU(uuid string) (FixtureGPU, bool) {
+	for i, gpu := range f.GPUs {
+		if gpu.UUID != uuid {
+			continue
+		}
+		gpu, err := f.snapshot(i)
+		return gpu, err == nil
+	}
+	return FixtureGPU{}, false
+}
+
+// nextEvents returns the NVML events (e.g., Xids) of the applied changes
+// not delivered yet for each target GPU, and marks them as delivered.
+func (f *Fixture) nextEvents() []fixtureEvent {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	elapsed := f.elapsed()
+
+	var events []fixtureEvent
+	for i, change := range f.Changes {
+		if change.After.Duration > elapsed {
+			break
+		}
+		if _, ok := f.delivered[i]; ok {
+			continue
+		}
+		f.delivered[i] = struct{}{}
+
+		for _, gpu := range f.GPUs {
+			if change.UUID != "" && change.UUID != gpu.UUID {
+				continue
+			}
+			for _, xid := range change.Xids {
+				events = append(events, fixtureEvent{uuid: gpu.UUID, eventType: nvml.EventTypeXidCriticalError, data: xid})
+			}
+			for j := 0; j < change.SingleBitECCErrors; j++ {
+				events = append(events, fixtureEvent{uuid: gpu.UUID, eventType: nvml.EventTypeSingleBitEccError})
+			}
+			for j := 0; j < change.DoubleBitECCErrors; j++ {
+				events = append(events, fixtureEvent{uuid: gpu.UUID, eventType: nvml.EventTypeDoubleBitEccError})
+			}
+		}
+	}
+	return events
+}
+
+type fixtureEvent struct {
+	uuid      string
+	eventType uint64
+	// the Xid of the Xid events
+	data uint64
+}
+
+func parseArchitecture(s string) (nvml.DeviceArchitecture, error) {
+	switch strings.ToLower(s) {
+	case "kepler":
+		return nvml.DEVICE_ARCH_KEPLER, nil
+	case "maxwell":
+		return nvml.DEVICE_ARCH_MAXWELL, nil
+	case "pascal":
+		return nvml.DEVICE_ARCH_PASCAL, nil
+	case "volta":
+		return nvml.DEVICE_ARCH_VOLTA, nil
+	case "turing":
+		return nvml.DEVICE_ARCH_TURING, nil
+	case "ampere":
+		return nvml.DEVICE_ARCH_AMPERE, nil
+	case "ada":
+		return nvml.DEVICE_ARCH_ADA, nil
+	case "hopper":
+		return nvml.DEVICE_ARCH_HOPPER, nil
+	case "", "unknown":
+		return nvml.DEVICE_ARCH_UNKNOWN, nil
+	default:
+		return 0, fmt.Errorf("unknown architecture %q", s)
+	}
+}
+
+// parseCPUList parses the list of the CPUs in the kernel cpulist format
+// (e.g., "0-3,8" for the CPUs 0, 1, 2, 3 and 8).
+func parseCPUList(s string) ([]int, error) {
+	var cpus []int
+	if s == "" {
+		return cpus, nil
+	}
+	for _, r := range strings.Split(s, ",") {
+		start, end, isRange := strings.Cut(strings.TrimSpace(r), "-")
+		first, err := strconv.Atoi(start)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cpu list %q: %w", s, err)
+		}
+		last := first
+		if isRange {
+			last, err = strconv.Atoi(end)
+			if err != nil {
+				return nil, fmt.Errorf("invalid cpu list %q: %w", s, err)
+			}
+		}
+		if first < 0 || last < first {
+			return nil, fmt.Errorf("invalid cpu list %q", s)
+		}
+		for cpu := first; cpu <= last; cpu++ {
+			cpus = append(cpus, cpu)
+		}
+	}
+	return cpus, nil
+}
+
+// parseCUDAComputeCapability parses the compute capability (e.g., "9.0")
+// into the major and minor versions.
+func parseCUDAComputeCapability(s string) (int, int, error) {
+	if s == "" {
+		return 0, 0, nil
+	}
+	major, minor, ok := strings.Cut(s, ".")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid cuda compute capability %q", s)
+	}
+	mj, err := strconv.Atoi(major)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid cuda compute capability %q: %w", s, err)
+	}
+	mn, err := strconv.Atoi(minor)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid cuda compute capability %q: %w", s, err)
+	}
+	return mj, mn, nil
+}
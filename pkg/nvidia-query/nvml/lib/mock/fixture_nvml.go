@@ -0,0 +1,704 @@
+package mock
+
+import (
+	"encoding/binary"
+	"strings"
+	"time"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+	nvmlmock "github.com/NVIDIA/go-nvml/pkg/nvml/mock"
+)
+
+const (
+	// fixtureEventTypes are the NVML event types supported by the fixture devices.
+	fixtureEventTypes = nvml.EventTypeXidCriticalError | nvml.EventTypeDoubleBitEccError | nvml.EventTypeSingleBitEccError
+
+	// fixtureEventPollInterval is the interval to check the scripted events
+	// while waiting for the NVML events.
+	fixtureEventPollInterval = 100 * time.Millisecond
+
+	// fixtureInvalidInstanceID is the GPU and compute instance IDs of the events,
+	// same as NVML on the devices without the MIG mode.
+	fixtureInvalidInstanceID = uint32(0xFFFFFFFF)
+)
+
+// NewFixtureInterface returns the NVML interface that serves the GPUs in the fixture.
+// Each device query returns the GPU state with the scripted changes applied up to now.
+// The GPU identities (e.g., name, UUID, PCI bus ID) are always returned,
+// while the other queries fail with "GPU is lost" if the GPU is marked as lost.
+func NewFixtureInterface(f *Fixture) *nvmlmock.Interface {
+	devs := make([]*nvmlmock.Device, len(f.GPUs))
+	for i := range f.GPUs {
+		devs[i] = newFixtureDevice(f, i)
+	}
+
+	return &nvmlmock.Interface{
+		InitFunc: func() nvml.Return {
+			return nvml.SUCCESS
+		},
+		ShutdownFunc: func() nvml.Return {
+			return nvml.SUCCESS
+		},
+		SystemGetDriverVersionFunc: func() (string, nvml.Return) {
+			return f.DriverVersion, nvml.SUCCESS
+		},
+		SystemGetCudaDriverVersion_v2Func: func() (int, nvml.Return) {
+			return f.CUDADriverVersion, nvml.SUCCESS
+		},
+		DeviceGetCountFunc: func() (int, nvml.Return) {
+			return len(devs), nvml.SUCCESS
+		},
+		DeviceGetHandleByIndexFunc: func(n int) (nvml.Device, nvml.Return) {
+			if n < 0 || n >= len(devs) {
+				return nil, nvml.ERROR_INVALID_ARGUMENT
+			}
+			return devs[n], nvml.SUCCESS
+		},
+		EventSetCreateFunc: func() (nvml.EventSet, nvml.Return) {
+			return newFixtureEventSet(f, devs), nvml.SUCCESS
+		},
+		GpmSampleAllocFunc: func() (nvml.GpmSample, nvml.Return) {
+			return &fixtureGpmSample{idx: -1}, nvml.SUCCESS
+		},
+		GpmMetricsGetFunc: func(m *nvml.GpmMetricsGetType) nvml.Return {
+			sample, ok := m.Sample2.(*fixtureGpmSample)
+			if !ok || sample.idx < 0 {
+				return nvml.ERROR_INVALID_ARGUMENT
+			}
+			gpu, ret := getFixtureGPU(f, sample.idx)
+			if ret != nvml.SUCCESS {
+				return ret
+			}
+			for i := 0; i < int(m.NumMetrics) && i < len(m.Metrics); i++ {
+				m.Metrics[i].NvmlReturn = uint32(nvml.SUCCESS)
+				m.Metrics[i].Value = gpu.GPMMetrics[int(m.Metrics[i].MetricId)]
+			}
+			return nvml.SUCCESS
+		},
+	}
+}
+
+// getFixtureGPU returns the current state of the GPU at the index.
+func getFixtureGPU(f *Fixture, idx int) (FixtureGPU, nvml.Return) {
+	gpu, err := f.snapshot(idx)
+	if err != nil {
+		return FixtureGPU{}, nvml.ERROR_UNKNOWN
+	}
+	if gpu.Lost {
+		return FixtureGPU{}, nvml.ERROR_GPU_IS_LOST
+	}
+	return gpu, nvml.SUCCESS
+}
+
+func newFixtureDevice(f *Fixture, idx int) *nvmlmock.Device {
+	base := f.GPUs[idx]
+	arch, _ := parseArchitecture(base.Architecture)
+	ccMajor, ccMinor, _ := parseCUDAComputeCapability(base.CUDAComputeCapability)
+
+	get := func() (FixtureGPU, nvml.Return) {
+		return getFixtureGPU(f, idx)
+	}
+
+	return &nvmlmock.Device{
+		GetNameFunc: func() (string, nvml.Return) {
+			return base.Name, nvml.SUCCESS
+		},
+		GetUUIDFunc: func() (string, nvml.Return) {
+			return base.UUID, nvml.SUCCESS
+		},
+		GetMinorNumberFunc: func() (int, nvml.Return) {
+			return base.MinorNumber, nvml.SUCCESS
+		},
+		GetPciInfoFunc: func() (nvml.PciInfo, nvml.Return) {
+			return toPciInfo(base.PCIBusID), nvml.SUCCESS
+		},
+		GetArchitectureFunc: func() (nvml.DeviceArchitecture, nvml.Return) {
+			return arch, nvml.SUCCESS
+		},
+		GetBrandFunc: func() (nvml.BrandType, nvml.Return) {
+			return nvml.BRAND_NVIDIA, nvml.SUCCESS
+		},
+		GetCudaComputeCapabilityFunc: func() (int, int, nvml.Return) {
+			return ccMajor, ccMinor, nvml.SUCCESS
+		},
+		GetNumGpuCoresFunc: func() (int, nvml.Return) {
+			return base.NumCores, nvml.SUCCESS
+		},
+
+		GetSupportedEventTypesFunc: func() (uint64, nvml.Return) {
+			return fixtureEventTypes, nvml.SUCCESS
+		},
+		RegisterEventsFunc: func(v uint64, eventSet nvml.EventSet) nvml.Return {
+			if es, ok := eventSet.(*fixtureEventSet); ok {
+				es.register(idx, v)
+			}
+			return nvml.SUCCESS
+		},
+
+		GetPersistenceModeFunc: func() (nvml.EnableState, nvml.Return) {
+			gpu, ret := get()
+			return toEnableState(gpu.PersistenceMode), ret
+		},
+		GetGspFirmwareModeFunc: func() (bool, bool, nvml.Return) {
+			gpu, ret := get()
+			return gpu.GSPFirmwareEnabled, true, ret
+		},
+		GetVbiosVersionFunc: func() (string, nvml.Return) {
+			gpu, ret := get()
+			if ret != nvml.SUCCESS {
+				return "", ret
+			}
+			if gpu.VBIOSVersion == "" {
+				return "", nvml.ERROR_NOT_SUPPORTED
+			}
+			return gpu.VBIOSVersion, nvml.SUCCESS
+		},
+
+		GetTemperatureFunc: func(sensor nvml.TemperatureSensors) (uint32, nvml.Return) {
+			gpu, ret := get()
+			return gpu.TemperatureCelsius, ret
+		},
+		GetTemperatureThresholdFunc: func(threshold nvml.TemperatureThresholds) (uint32, nvml.Return) {
+			gpu, ret := get()
+			if ret != nvml.SUCCESS {
+				return 0, ret
+			}
+			switch threshold {
+			case nvml.TEMPERATURE_THRESHOLD_SHUTDOWN:
+				return gpu.TemperatureThresholds.ShutdownCelsius, nvml.SUCCESS
+			case nvml.TEMPERATURE_THRESHOLD_SLOWDOWN:
+				return gpu.TemperatureThresholds.SlowdownCelsius, nvml.SUCCESS
+			case nvml.TEMPERATURE_THRESHOLD_MEM_MAX:
+				return gpu.TemperatureThresholds.MemMaxCelsius, nvml.SUCCESS
+			case nvml.TEMPERATURE_THRESHOLD_GPU_MAX:
+				return gpu.TemperatureThresholds.GPUMaxCelsius, nvml.SUCCESS
+			default:
+				return 0, nvml.ERROR_NOT_SUPPORTED
+			}
+		},
+
+		GetMemoryInfo_v2Func: func() (nvml.Memory_v2, nvml.Return) {
+			gpu, ret := get()
+			return nvml.Memory_v2{
+				Total:    gpu.Memory.TotalBytes,
+				Reserved: gpu.Memory.ReservedBytes,
+				Used:     gpu.Memory.UsedBytes,
+				Free:     freeBytes(gpu.Memory),
+			}, ret
+		},
+		GetMemoryInfoFunc: func() (nvml.Memory, nvml.Return) {
+			gpu, ret := get()
+			return nvml.Memory{
+				Total: gpu.Memory.TotalBytes,
+				Used:  gpu.Memory.UsedBytes,
+				Free:  freeBytes(gpu.Memory),
+			}, ret
+		},
+
+		GetPowerUsageFunc: func() (uint32, nvml.Return) {
+			gpu, ret := get()
+			return gpu.PowerUsageMilliWatts, ret
+		},
+		GetEnforcedPowerLimitFunc: func() (uint32, nvml.Return) {
+			gpu, ret := get()
+			return gpu.PowerLimitMilliWatts, ret
+		},
+		GetPowerManagementLimitFunc: func() (uint32, nvml.Return) {
+			gpu, ret := get()
+			return gpu.PowerLimitMilliWatts, ret
+		},
+
+		GetUtilizationRatesFunc: func() (nvml.Utilization, nvml.Return) {
+			gpu, ret := get()
+			return nvml.Utilization{
+				Gpu:    gpu.Utilization.GPUPercent,
+				Memory: gpu.Utilization.MemoryPercent,
+			}, ret
+		},
+		GetClockInfoFunc: func(clockType nvml.ClockType) (uint32, nvml.Return) {
+			gpu, ret := get()
+			if ret != nvml.SUCCESS {
+				return 0, ret
+			}
+			switch clockType {
+			case nvml.CLOCK_GRAPHICS, nvml.CLOCK_SM:
+				return gpu.Clocks.GraphicsMHz, nvml.SUCCESS
+			case nvml.CLOCK_MEM:
+				return gpu.Clocks.MemoryMHz, nvml.SUCCESS
+			default:
+				return 0, nvml.ERROR_NOT_SUPPORTED
+			}
+		},
+		GetCurrentClocksEventReasonsFunc: func() (uint64, nvml.Return) {
+			gpu, ret := get()
+			return gpu.ClockEventsReasons, ret
+		},
+
+		GetCurrPcieLinkGenerationFunc: func() (int, nvml.Return) {
+			gpu, ret := get()
+			if ret == nvml.SUCCESS && gpu.PCIe.Generation == 0 {
+				return 0, nvml.ERROR_NOT_SUPPORTED
+			}
+			return gpu.PCIe.Generation, ret
+		},
+		GetMaxPcieLinkGenerationFunc: func() (int, nvml.Return) {
+			gpu, ret := get()
+			return gpu.PCIe.MaxGeneration, ret
+		},
+		GetCurrPcieLinkWidthFunc: func() (int, nvml.Return) {
+			gpu, ret := get()
+			return gpu.PCIe.Width, ret
+		},
+		GetMaxPcieLinkWidthFunc: func() (int, nvml.Return) {
+			gpu, ret := get()
+			return gpu.PCIe.MaxWidth, ret
+		},
+		GetPcieReplayCounterFunc: func() (int, nvml.Return) {
+			gpu, ret := get()
+			return gpu.PCIe.ReplayCounter, ret
+		},
+		GetPerformanceStateFunc: func() (nvml.Pstates, nvml.Return) {
+			gpu, ret := get()
+			return nvml.Pstates(gpu.PCIe.PerformanceState), ret
+		},
+
+		GetEccModeFunc: func() (nvml.EnableState, nvml.EnableState, nvml.Return) {
+			gpu, ret := get()
+			return toEnableState(gpu.ECCEnabled), toEnableState(gpu.ECCEnabled), ret
+		},
+		GetTotalEccErrorsFunc: func(errorType nvml.MemoryErrorType, counterType nvml.EccCounterType) (uint64, nvml.Return) {
+			gpu, ret := get()
+			if ret != nvml.SUCCESS {
+				return 0, ret
+			}
+			return getECCErrors(gpu.ECCErrors, errorType, counterType), nvml.SUCCESS
+		},
+		GetMemoryErrorCounterFunc: func(errorType nvml.MemoryErrorType, counterType nvml.EccCounterType, location nvml.MemoryLocation) (uint64, nvml.Return) {
+			gpu, ret := get()
+			if ret != nvml.SUCCESS {
+				return 0, ret
+			}
+			// all the errors are counted in the device memory
+			switch location {
+			case nvml.MEMORY_LOCATION_DEVICE_MEMORY:
+				return getECCErrors(gpu.ECCErrors, errorType, counterType), nvml.SUCCESS
+			default:
+				return 0, nvml.SUCCESS
+			}
+		},
+		GetRemappedRowsFunc: func() (int, int, bool, bool, nvml.Return) {
+			gpu, ret := get()
+			return gpu.RemappedRows.Corrected, gpu.RemappedRows.Uncorrected, gpu.RemappedRows.Pending, gpu.RemappedRows.Failure, ret
+		},
+
+		GetNvLinkStateFunc: func(link int) (nvml.EnableState, nvml.Return) {
+			gpu, ret := get()
+			if ret != nvml.SUCCESS {
+				return 0, ret
+			}
+			if len(gpu.NVLinks) == 0 {
+				return 0, nvml.ERROR_NOT_SUPPORTED
+			}
+			if link < 0 || link >= len(gpu.NVLinks) {
+				return 0, nvml.ERROR_INVALID_ARGUMENT
+			}
+			return toEnableState(gpu.NVLinks[link].FeatureEnabled), nvml.SUCCESS
+		},
+		GetNvLinkErrorCounterFunc: func(link int, counter nvml.NvLinkErrorCounter) (uint64, nvml.Return) {
+			gpu, ret := get()
+			if ret != nvml.SUCCESS {
+				return 0, ret
+			}
+			if link < 0 || link >= len(gpu.NVLinks) {
+				return 0, nvml.ERROR_INVALID_ARGUMENT
+			}
+			switch counter {
+			case nvml.NVLINK_ERROR_DL_REPLAY:
+				return gpu.NVLinks[link].ReplayErrors, nvml.SUCCESS
+			case nvml.NVLINK_ERROR_DL_RECOVERY:
+				return gpu.NVLinks[link].RecoveryErrors, nvml.SUCCESS
+			case nvml.NVLINK_ERROR_DL_CRC_FLIT:
+				return gpu.NVLinks[link].CRCErrors, nvml.SUCCESS
+			default:
+				return 0, nvml.SUCCESS
+			}
+		},
+		GetFieldValuesFunc: func(values []nvml.FieldValue) nvml.Return {
+			gpu, ret := get()
+			if ret != nvml.SUCCESS {
+				return ret
+			}
+			for i := range values {
+				link := int(values[i].ScopeId)
+				if link >= len(gpu.NVLinks) {
+					values[i].NvmlReturn = uint32(nvml.ERROR_NOT_SUPPORTED)
+					continue
+				}
+				switch values[i].FieldId {
+				case nvml.FI_DEV_NVLINK_THROUGHPUT_RAW_TX:
+					binary.LittleEndian.PutUint64(values[i].Value[:], gpu.NVLinks[link].ThroughputRawTxKiB)
+				case nvml.FI_DEV_NVLINK_THROUGHPUT_RAW_RX:
+					binary.LittleEndian.PutUint64(values[i].Value[:], gpu.NVLinks[link].ThroughputRawRxKiB)
+				default:
+					values[i].NvmlReturn = uint32(nvml.ERROR_NOT_SUPPORTED)
+				}
+			}
+			return nvml.SUCCESS
+		},
+
+		GetComputeRunningProcessesFunc: func() ([]nvml.ProcessInfo, nvml.Return) {
+			gpu, ret := get()
+			if ret != nvml.SUCCESS {
+				return nil, ret
+			}
+			migEnabled := gpu.MIG != nil && gpu.MIG.Enabled
+			procs := make([]nvml.ProcessInfo, 0, len(gpu.Processes))
+			for _, p := range gpu.Processes {
+				proc := nvml.ProcessInfo{
+					Pid:               p.PID,
+					UsedGpuMemory:     p.UsedMemoryBytes,
+					GpuInstanceId:     invalidInstanceID,
+					ComputeInstanceId: invalidInstanceID,
+				}
+				if migEnabled {
+					proc.GpuInstanceId = p.GPUInstanceID
+					proc.ComputeInstanceId = p.ComputeInstanceID
+				}
+				procs = append(procs, proc)
+			}
+			return procs, nvml.SUCCESS
+		},
+		GetProcessUtilizationFunc: func(lastSeenTimestamp uint64) ([]nvml.ProcessUtilizationSample, nvml.Return) {
+			gpu, ret := get()
+			if ret != nvml.SUCCESS {
+				return nil, ret
+			}
+			now := uint64(time.Now().UnixMicro())
+			samples := make([]nvml.ProcessUtilizationSample, 0, len(gpu.Processes))
+			for _, p := range gpu.Processes {
+				samples = append(samples, nvml.ProcessUtilizationSample{
+					Pid:       p.PID,
+					TimeStamp: now,
+					SmUtil:    p.SMUtilPercent,
+					MemUtil:   p.MemUtilPercent,
+				})
+			}
+			return samples, nvml.SUCCESS
+		},
+
+		GetMigModeFunc: func() (int, int, nvml.Return) {
+			gpu, ret := get()
+			if ret != nvml.SUCCESS {
+				return 0, 0, ret
+			}
+			if gpu.MIG == nil {
+				return 0, 0, nvml.ERROR_NOT_SUPPORTED
+			}
+			if gpu.MIG.Enabled {
+				return nvml.DEVICE_MIG_ENABLE, nvml.DEVICE_MIG_ENABLE, nvml.SUCCESS
+			}
+			return nvml.DEVICE_MIG_DISABLE, nvml.DEVICE_MIG_DISABLE, nvml.SUCCESS
+		},
+		GetMaxMigDeviceCountFunc: func() (int, nvml.Return) {
+			gpu, ret := get()
+			if ret != nvml.SUCCESS {
+				return 0, ret
+			}
+			if gpu.MIG == nil {
+				return 0, nvml.ERROR_NOT_SUPPORTED
+			}
+			return maxMIGDevices, nvml.SUCCESS
+		},
+		GetMigDeviceHandleByIndexFunc: func(i int) (nvml.Device, nvml.Return) {
+			gpu, ret := get()
+			if ret != nvml.SUCCESS {
+				return nil, ret
+			}
+			if gpu.MIG == nil || !gpu.MIG.Enabled || i < 0 || i >= len(gpu.MIG.Instances) {
+				return nil, nvml.ERROR_NOT_FOUND
+			}
+			return newFixtureMIGDevice(gpu.MIG.Instances[i]), nvml.SUCCESS
+		},
+
+		GetTopologyCommonAncestorFunc: func(other nvml.Device) (nvml.GpuTopologyLevel, nvml.Return) {
+			gpu, ret := get()
+			if ret != nvml.SUCCESS {
+				return 0, ret
+			}
+			otherUUID, ret := other.GetUUID()
+			if ret != nvml.SUCCESS {
+				return 0, ret
+			}
+			if otherUUID == base.UUID {
+				return nvml.TOPOLOGY_INTERNAL, nvml.SUCCESS
+			}
+			peer, ok := f.findGPU(otherUUID)
+			if !ok {
+				return 0, nvml.ERROR_INVALID_ARGUMENT
+			}
+			if gpu.Topology == nil || peer.Topology == nil {
+				return 0, nvml.ERROR_NOT_SUPPORTED
+			}
+			switch {
+			case gpu.Topology.PCISwitch != "" && gpu.Topology.PCISwitch == peer.Topology.PCISwitch:
+				return nvml.TOPOLOGY_SINGLE, nvml.SUCCESS
+			case gpu.Topology.NUMANode == peer.Topology.NUMANode:
+				return nvml.TOPOLOGY_NODE, nvml.SUCCESS
+			default:
+				return nvml.TOPOLOGY_SYSTEM, nvml.SUCCESS
+			}
+		},
+		GetCpuAffinityFunc: func(numCPUs int) ([]uint, nvml.Return) {
+			gpu, ret := get()
+			if ret != nvml.SUCCESS {
+				return nil, ret
+			}
+			if gpu.Topology == nil {
+				return nil, nvml.ERROR_NOT_SUPPORTED
+			}
+			cpus, _ := parseCPUList(gpu.Topology.CPUs)
+			cpuSet := make([]uint, (numCPUs+63)/64)
+			for _, cpu := range cpus {
+				if cpu < numCPUs {
+					cpuSet[cpu/64] |= 1 << uint(cpu%64)
+				}
+			}
+			return cpuSet, nvml.SUCCESS
+		},
+		GetNvLinkRemoteDeviceTypeFunc: func(link int) (nvml.IntNvLinkDeviceType, nvml.Return) {
+			gpu, ret := get()
+			if ret != nvml.SUCCESS {
+				return 0, ret
+			}
+			if link < 0 || link >= len(gpu.NVLinks) {
+				return 0, nvml.ERROR_INVALID_ARGUMENT
+			}
+			switch {
+			case gpu.NVLinks[link].RemoteSwitch:
+				return nvml.NVLINK_DEVICE_TYPE_SWITCH, nvml.SUCCESS
+			case gpu.NVLinks[link].RemotePCIBusID != "":
+				return nvml.NVLINK_DEVICE_TYPE_GPU, nvml.SUCCESS
+			default:
+				return 0, nvml.ERROR_NOT_SUPPORTED
+			}
+		},
+		GetNvLinkRemotePciInfoFunc: func(link int) (nvml.PciInfo, nvml.Return) {
+			gpu, ret := get()
+			if ret != nvml.SUCCESS {
+				return nvml.PciInfo{}, ret
+			}
+			if link < 0 || link >= len(gpu.NVLinks) {
+				return nvml.PciInfo{}, nvml.ERROR_INVALID_ARGUMENT
+			}
+			if gpu.NVLinks[link].RemotePCIBusID == "" {
+				return nvml.PciInfo{}, nvml.ERROR_NOT_SUPPORTED
+			}
+			return toPciInfo(gpu.NVLinks[link].RemotePCIBusID), nvml.SUCCESS
+		},
+
+		GpmQueryDeviceSupportFunc: func() (nvml.GpmSupport, nvml.Return) {
+			gpu, ret := get()
+			if ret != nvml.SUCCESS {
+				return nvml.GpmSupport{}, ret
+			}
+			support := nvml.GpmSupport{}
+			if gpu.GPMSupported {
+				support.IsSupportedDevice = 1
+			}
+			return support, nvml.SUCCESS
+		},
+		GpmSampleGetFunc: func(sample nvml.GpmSample) nvml.Return {
+			if _, ret := get(); ret != nvml.SUCCESS {
+				return ret
+			}
+			s, ok := sample.(*fixtureGpmSample)
+			if !ok {
+				return nvml.ERROR_INVALID_ARGUMENT
+			}
+			s.idx = idx
+			return nvml.SUCCESS
+		},
+	}
+}
+
+const (
+	// the maximum number of the MIG devices of a GPU (e.g., 7 x "1g.10gb" on H100)
+	maxMIGDevices = 7
+	// the GPU and compute instance IDs of the processes when the MIG mode is disabled
+	invalidInstanceID = 0xFFFFFFFF
+)
+
+func newFixtureMIGDevice(inst FixtureMIGInstance) *nvmlmock.Device {
+	return &nvmlmock.Device{
+		GetUUIDFunc: func() (string, nvml.Return) {
+			return inst.UUID, nvml.SUCCESS
+		},
+		GetGpuInstanceIdFunc: func() (int, nvml.Return) {
+			return inst.GPUInstanceID, nvml.SUCCESS
+		},
+		GetComputeInstanceIdFunc: func() (int, nvml.Return) {
+			return inst.ComputeInstanceID, nvml.SUCCESS
+		},
+		GetAttributesFunc: func() (nvml.DeviceAttributes, nvml.Return) {
+			return nvml.DeviceAttributes{
+				MultiprocessorCount:       inst.MultiprocessorCount,
+				GpuInstanceSliceCount:     inst.GPUInstanceSlices,
+				ComputeInstanceSliceCount: inst.ComputeInstanceSlices,
+				MemorySizeMB:              inst.MemorySizeMB,
+			}, nvml.SUCCESS
+		},
+		GetMemoryInfoFunc: func() (nvml.Memory, nvml.Return) {
+			total := inst.MemorySizeMB * 1024 * 1024
+			used := inst.MemoryUsedBytes
+			if used > total {
+				used = total
+			}
+			return nvml.Memory{Total: total, Used: used, Free: total - used}, nvml.SUCCESS
+		},
+	}
+}
+
+// toPciInfo converts the PCI bus ID (e.g., "0000:9b:00.0")
+// to the NVML PCI info with the 8-digit domain (e.g., "00000000:9B:00.0").
+func toPciInfo(busID string) nvml.PciInfo {
+	if parts := strings.Split(busID, ":"); len(parts) == 3 && len(parts[0]) == 4 {
+		busID = "0000" + busID
+	}
+	busID = strings.ToUpper(busID)
+
+	info := nvml.PciInfo{}
+	for i := 0; i < len(busID) && i < len(info.BusId)-1; i++ {
+		info.BusId[i] = int8(busID[i])
+	}
+	return info
+}
+
+func toEnableState(enabled bool) nvml.EnableState {
+	if enabled {
+		return nvml.FEATURE_ENABLED
+	}
+	return nvml.FEATURE_DISABLED
+}
+
+func freeBytes(m FixtureMemory) uint64 {
+	if m.UsedBytes+m.ReservedBytes >= m.TotalBytes {
+		return 0
+	}
+	return m.TotalBytes - m.UsedBytes - m.ReservedBytes
+}
+
+func getECCErrors(errs FixtureECCErrors, errorType nvml.MemoryErrorType, counterType nvml.EccCounterType) uint64 {
+	switch {
+	case errorType == nvml.MEMORY_ERROR_TYPE_CORRECTED && counterType == nvml.VOLATILE_ECC:
+		return errs.VolatileCorrected
+	case errorType == nvml.MEMORY_ERROR_TYPE_UNCORRECTED && counterType == nvml.VOLATILE_ECC:
+		return errs.VolatileUncorrected
+	case errorType == nvml.MEMORY_ERROR_TYPE_CORRECTED && counterType == nvml.AGGREGATE_ECC:
+		return errs.AggregateCorrected
+	case errorType == nvml.MEMORY_ERROR_TYPE_UNCORRECTED && counterType == nvml.AGGREGATE_ECC:
+		return errs.AggregateUncorrected
+	default:
+		return 0
+	}
+}
+
+var _ nvml.GpmSample = &fixtureGpmSample{}
+
+// fixtureGpmSample is the GPM sample of the fixture device,
+// which records the device the sample is taken from.
+type fixtureGpmSample struct {
+	idx int
+}
+
+func (s *fixtureGpmSample) Free() nvml.Return {
+	return nvml.SUCCESS
+}
+
+func (s *fixtureGpmSample) Get(dev nvml.Device) nvml.Return {
+	return dev.GpmSampleGet(s)
+}
+
+func (s *fixtureGpmSample) MigGet(dev nvml.Device, n int) nvml.Return {
+	return nvml.ERROR_NOT_SUPPORTED
+}
+
+var _ nvml.EventSet = &fixtureEventSet{}
+
+// fixtureEventSet delivers the scripted Xids and ECC errors of the registered devices.
+type fixtureEventSet struct {
+	f    *Fixture
+	devs []*nvmlmock.Device
+
+	// maps the device index to the registered event types
+	registered map[int]uint64
+	pending    []nvml.EventData
+}
+
+func newFixtureEventSet(f *Fixture, devs []*nvmlmock.Device) *fixtureEventSet {
+	return &fixtureEventSet{
+		f:          f,
+		devs:       devs,
+		registered: make(map[int]uint64),
+	}
+}
+
+func (es *fixtureEventSet) register(idx int, eventTypes uint64) {
+	es.f.mu.Lock()
+	defer es.f.mu.Unlock()
+	es.registered[idx] |= eventTypes
+}
+
+// Wait waits for the next scripted event up to the timeout in milliseconds.
+func (es *fixtureEventSet) Wait(timeoutMs uint32) (nvml.EventData, nvml.Return) {
+	deadline := time.Now().Add(time.Duration(timeoutMs) * time.Millisecond)
+	for {
+		if data, ok := es.next(); ok {
+			return data, nvml.SUCCESS
+		}
+		if !time.Now().Before(deadline) {
+			return nvml.EventData{}, nvml.ERROR_TIMEOUT
+		}
+
+		wait := fixtureEventPollInterval
+		if remaining := time.Until(deadline); remaining < wait {
+			wait = remaining
+		}
+		time.Sleep(wait)
+	}
+}
+
+func (es *fixtureEventSet) next() (nvml.EventData, bool) {
+	for _, ev := range es.f.nextEvents() {
+		for idx, gpu := range es.f.GPUs {
+			if gpu.UUID != ev.uuid {
+				continue
+			}
+
+			es.f.mu.Lock()
+			registered := es.registered[idx]
+			es.f.mu.Unlock()
+			if registered&ev.eventType == 0 {
+				continue
+			}
+
+			es.pending = append(es.pending, nvml.EventData{
+				Device:            es.devs[idx],
+				EventType:         ev.eventType,
+				EventData:         ev.data,
+				GpuInstanceId:     fixtureInvalidInstanceID,
+				ComputeInstanceId: fixtureInvalidInstanceID,
+			})
+		}
+	}
+
+	if len(es.pending) == 0 {
+		return nvml.EventData{}, false
+	}
+	data := es.pending[0]
+	es.pending = es.pending[1:]
+	return data, true
+}
+
+func (es *fixtureEventSet) Free() nvml.Return {
+	return nvml.SUCCESS
+}
@@ -0,0 +1,386 @@
+package mock
+
+import (
+	"testing"
+	"time"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testUUID0 = "GPU-00000000-0000-0000-0000-000000000000"
+	testUUID1 = "GPU-11111111-1111-1111-1111-111111111111"
+)
+
+// loadTestFixture loads the test fixture with the time elapsed since the load controlled by the test.
+func loadTestFixture(t *testing.T) (*Fixture, *time.Duration) {
+	f, err := LoadFixture("testdata/fixture.yaml")
+	require.NoError(t, err)
+
+	elapsed := new(time.Duration)
+	f.now = func() time.Time {
+		return f.startTime.Add(*elapsed)
+	}
+	return f, elapsed
+}
+
+func TestLoadFixture(t *testing.T) {
+	f, _ := loadTestFixture(t)
+	assert.Equal(t, "535.161.08", f.DriverVersion)
+	assert.Equal(t, 12020, f.CUDADriverVersion)
+	require.Len(t, f.GPUs, 2)
+	assert.Equal(t, testUUID0, f.GPUs[0].UUID)
+	assert.Len(t, f.GPUs[0].NVLinks, 2)
+	assert.Equal(t, map[int]float64{3: 12.5, 5: 30}, f.GPUs[0].GPMMetrics)
+	require.Len(t, f.Changes, 2)
+	assert.Equal(t, 10*time.Second, f.Changes[0].After.Duration)
+	assert.Equal(t, []uint64{79}, f.Changes[1].Xids)
+
+	_, err := LoadFixture("testdata/not-found.yaml")
+	assert.Error(t, err)
+}
+
+func TestParseFixtureInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{name: "no driver version", data: `{"cuda_driver_version": 12020, "gpus": [{"name": "a", "uuid": "GPU-0"}]}`},
+		{name: "no cuda version", data: `{"driver_version": "535.161.08", "gpus": [{"name": "a", "uuid": "GPU-0"}]}`},
+		{name: "no gpu", data: `{"driver_version": "535.161.08", "cuda_driver_version": 12020}`},
+		{name: "no uuid", data: `{"driver_version": "535.161.08", "cuda_driver_version": 12020, "gpus": [{"name": "a"}]}`},
+		{name: "duplicate uuid", data: `{"driver_version": "535.161.08", "cuda_driver_version": 12020, "gpus": [{"name": "a", "uuid": "GPU-0"}, {"name": "a", "uuid": "GPU-0"}]}`},
+		{name: "unknown architecture", data: `{"driver_version": "535.161.08", "cuda_driver_version": 12020, "gpus": [{"name": "a", "uuid": "GPU-0", "architecture": "unknown-arch"}]}`},
+		{name: "invalid compute capability", data: `{"driver_version": "535.161.08", "cuda_driver_version": 12020, "gpus": [{"name": "a", "uuid": "GPU-0", "cuda_compute_capability": "9"}]}`},
+		{name: "unknown change uuid", data: `{"driver_version": "535.161.08", "cuda_driver_version": 12020, "gpus": [{"name": "a", "uuid": "GPU-0"}], "changes": [{"after": "1s", "uuid": "GPU-1"}]}`},
+		{name: "change uuid", data: `{"driver_version": "535.161.08", "cuda_driver_version": 12020, "gpus": [{"name": "a", "uuid": "GPU-0"}], "changes": [{"after": "1s", "set": {"uuid": "GPU-1"}}]}`},
+		{name: "invalid change", data: `{"driver_version": "535.161.08", "cuda_driver_version": 12020, "gpus": [{"name": "a", "uuid": "GPU-0"}], "changes": [{"after": "1s", "set": {"temperature_celsius": "hot"}}]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseFixture([]byte(tt.data))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestFixtureSnapshot(t *testing.T) {
+	f, elapsed := loadTestFixture(t)
+
+	gpu, err := f.snapshot(1)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(36), gpu.TemperatureCelsius)
+	assert.False(t, gpu.RemappedRows.Pending)
+
+	*elapsed = 10 * time.Second
+	gpu, err = f.snapshot(1)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(90), gpu.TemperatureCelsius)
+	assert.Equal(t, uint64(72), gpu.ClockEventsReasons)
+	assert.Equal(t, uint64(1), gpu.ECCErrors.VolatileUncorrected)
+	assert.True(t, gpu.RemappedRows.Pending)
+	require.Len(t, gpu.NVLinks, 2)
+	assert.Equal(t, uint64(5), gpu.NVLinks[0].ReplayErrors)
+	assert.False(t, gpu.NVLinks[1].FeatureEnabled)
+	// unchanged fields are kept
+	assert.Equal(t, uint64(1048576), gpu.Memory.UsedBytes)
+	assert.Len(t, gpu.Processes, 1)
+	assert.False(t, gpu.Lost)
+
+	// the base state is not modified by the changes
+	assert.Equal(t, uint32(36), f.GPUs[1].TemperatureCelsius)
+	assert.Equal(t, uint64(0), f.GPUs[1].NVLinks[0].ReplayErrors)
+
+	// the other gpu is not changed
+	gpu, err = f.snapshot(0)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(35), gpu.TemperatureCelsius)
+
+	*elapsed = 30 * time.Second
+	gpu, err = f.snapshot(1)
+	require.NoError(t, err)
+	assert.True(t, gpu.Lost)
+	assert.Equal(t, uint32(90), gpu.TemperatureCelsius)
+}
+
+func TestFixtureInterface(t *testing.T) {
+	f, elapsed := loadTestFixture(t)
+	lib := NewFixtureInterface(f)
+
+	assert.Equal(t, nvml.SUCCESS, lib.Init())
+	driver, ret := lib.SystemGetDriverVersion()
+	assert.Equal(t, nvml.SUCCESS, ret)
+	assert.Equal(t, "535.161.08", driver)
+
+	count, ret := lib.DeviceGetCount()
+	assert.Equal(t, nvml.SUCCESS, ret)
+	assert.Equal(t, 2, count)
+	_, ret = lib.DeviceGetHandleByIndex(2)
+	assert.Equal(t, nvml.ERROR_INVALID_ARGUMENT, ret)
+
+	dev, ret := lib.DeviceGetHandleByIndex(1)
+	require.Equal(t, nvml.SUCCESS, ret)
+
+	uuid, ret := dev.GetUUID()
+	assert.Equal(t, nvml.SUCCESS, ret)
+	assert.Equal(t, testUUID1, uuid)
+
+	pciInfo, ret := dev.GetPciInfo()
+	assert.Equal(t, nvml.SUCCESS, ret)
+	busID := make([]byte, 0, len(pciInfo.BusId))
+	for _, b := range pciInfo.BusId {
+		if b == 0 {
+			break
+		}
+		busID = append(busID, byte(b))
+	}
+	assert.Equal(t, "00000000:2A:00.0", string(busID))
+
+	arch, ret := dev.GetArchitecture()
+	assert.Equal(t, nvml.SUCCESS, ret)
+	assert.Equal(t, nvml.DeviceArchitecture(nvml.DEVICE_ARCH_HOPPER), arch)
+
+	vbios, ret := dev.GetVbiosVersion()
+	assert.Equal(t, nvml.SUCCESS, ret)
+	assert.Equal(t, "96.00.89.00.01", vbios)
+
+	temp, ret := dev.GetTemperature(nvml.TEMPERATURE_GPU)
+	assert.Equal(t, nvml.SUCCESS, ret)
+	assert.Equal(t, uint32(36), temp)
+
+	slowdown, ret := dev.GetTemperatureThreshold(nvml.TEMPERATURE_THRESHOLD_SLOWDOWN)
+	assert.Equal(t, nvml.SUCCESS, ret)
+	assert.Equal(t, uint32(89), slowdown)
+
+	mem, ret := dev.GetMemoryInfo_v2()
+	assert.Equal(t, nvml.SUCCESS, ret)
+	assert.Equal(t, uint64(85520809984-553648128-1048576), mem.Free)
+
+	procs, ret := dev.GetComputeRunningProcesses()
+	assert.Equal(t, nvml.SUCCESS, ret)
+	require.Len(t, procs, 1)
+	assert.Equal(t, uint32(1), procs[0].Pid)
+
+	state, ret := nvml.DeviceGetNvLinkState(dev, 1)
+	assert.Equal(t, nvml.SUCCESS, ret)
+	assert.Equal(t, nvml.EnableState(nvml.FEATURE_ENABLED), state)
+	_, ret = nvml.DeviceGetNvLinkState(dev, 2)
+	assert.Equal(t, nvml.ERROR_INVALID_ARGUMENT, ret)
+
+	*elapsed = 10 * time.Second
+	temp, ret = dev.GetTemperature(nvml.TEMPERATURE_GPU)
+	assert.Equal(t, nvml.SUCCESS, ret)
+	assert.Equal(t, uint32(90), temp)
+
+	uncorrected, ret := dev.GetTotalEccErrors(nvml.MEMORY_ERROR_TYPE_UNCORRECTED, nvml.VOLATILE_ECC)
+	assert.Equal(t, nvml.SUCCESS, ret)
+	assert.Equal(t, uint64(1), uncorrected)
+
+	_, unc, pending, failure, ret := dev.GetRemappedRows()
+	assert.Equal(t, nvml.SUCCESS, ret)
+	assert.Equal(t, 1, unc)
+	assert.True(t, pending)
+	assert.False(t, failure)
+
+	replay, ret := nvml.DeviceGetNvLinkErrorCounter(dev, 0, nvml.NVLINK_ERROR_DL_REPLAY)
+	assert.Equal(t, nvml.SUCCESS, ret)
+	assert.Equal(t, uint64(5), replay)
+	state, ret = nvml.DeviceGetNvLinkState(dev, 1)
+	assert.Equal(t, nvml.SUCCESS, ret)
+	assert.Equal(t, nvml.EnableState(nvml.FEATURE_DISABLED), state)
+
+	width, ret := dev.GetCurrPcieLinkWidth()
+	assert.Equal(t, nvml.SUCCESS, ret)
+	assert.Equal(t, 8, width)
+	pcieReplays, ret := dev.GetPcieReplayCounter()
+	assert.Equal(t, nvml.SUCCESS, ret)
+	assert.Equal(t, 250, pcieReplays)
+
+	// the gpu has fallen off the bus
+	*elapsed = 20 * time.Second
+	_, ret = dev.GetTemperature(nvml.TEMPERATURE_GPU)
+	assert.Equal(t, nvml.ERROR_GPU_IS_LOST, ret)
+	uuid, ret = dev.GetUUID()
+	assert.Equal(t, nvml.SUCCESS, ret)
+	assert.Equal(t, testUUID1, uuid)
+
+	// the gpu without nvlink
+	f.GPUs[0].NVLinks = nil
+	dev0, ret := lib.DeviceGetHandleByIndex(0)
+	require.Equal(t, nvml.SUCCESS, ret)
+	_, ret = nvml.DeviceGetNvLinkState(dev0, 0)
+	assert.Equal(t, nvml.ERROR_NOT_SUPPORTED, ret)
+
+	// the gpu without vbios version
+	f.GPUs[0].VBIOSVersion = ""
+	_, ret = dev0.GetVbiosVersion()
+	assert.Equal(t, nvml.ERROR_NOT_SUPPORTED, ret)
+}
+
+func TestFixtureInterfaceGPM(t *testing.T) {
+	f, _ := loadTestFixture(t)
+	lib := NewFixtureInterface(f)
+
+	dev, ret := lib.DeviceGetHandleByIndex(0)
+	require.Equal(t, nvml.SUCCESS, ret)
+
+	support, ret := dev.GpmQueryDeviceSupport()
+	assert.Equal(t, nvml.SUCCESS, ret)
+	assert.Equal(t, uint32(1), support.IsSupportedDevice)
+
+	sample1, ret := lib.GpmSampleAlloc()
+	require.Equal(t, nvml.SUCCESS, ret)
+	sample2, ret := lib.GpmSampleAlloc()
+	require.Equal(t, nvml.SUCCESS, ret)
+
+	m := &nvml.GpmMetricsGetType{NumMetrics: 2, Sample1: sample1, Sample2: sample2}
+	m.Metrics[0].MetricId = uint32(nvml.GPM_METRIC_SM_OCCUPANCY)
+	m.Metrics[1].MetricId = uint32(nvml.GPM_METRIC_ANY_TENSOR_UTIL)
+
+	// no sample taken yet
+	assert.Equal(t, nvml.ERROR_INVALID_ARGUMENT, lib.GpmMetricsGet(m))
+
+	require.Equal(t, nvml.SUCCESS, dev.GpmSampleGet(sample1))
+	require.Equal(t, nvml.SUCCESS, dev.GpmSampleGet(sample2))
+	require.Equal(t, nvml.SUCCESS, lib.GpmMetricsGet(m))
+	assert.Equal(t, 12.5, m.Metrics[0].Value)
+	assert.Equal(t, float64(30), m.Metrics[1].Value)
+}
+
+func TestFixtureEventSet(t *testing.T) {
+	f, elapsed := loadTestFixture(t)
+	lib := NewFixtureInterface(f)
+
+	es, ret := lib.EventSetCreate()
+	require.Equal(t, nvml.SUCCESS, ret)
+	defer es.Free()
+
+	for i := 0; i < 2; i++ {
+		dev, ret := lib.DeviceGetHandleByIndex(i)
+		require.Equal(t, nvml.SUCCESS, ret)
+		require.Equal(t, nvml.SUCCESS, dev.RegisterEvents(nvml.EventTypeXidCriticalError|nvml.EventTypeDoubleBitEccError, es))
+	}
+
+	_, ret = es.Wait(10)
+	assert.Equal(t, nvml.ERROR_TIMEOUT, ret)
+
+	// the single-bit ECC errors are not registered
+	*elapsed = 10 * time.Second
+	data, ret := es.Wait(10)
+	require.Equal(t, nvml.SUCCESS, ret)
+	assert.Equal(t, uint64(nvml.EventTypeDoubleBitEccError), data.EventType)
+	assert.Equal(t, uint32(0xFFFFFFFF), data.GpuInstanceId)
+	_, ret = es.Wait(10)
+	assert.Equal(t, nvml.ERROR_TIMEOUT, ret)
+
+	*elapsed = 20 * time.Second
+	data, ret = es.Wait(10)
+	require.Equal(t, nvml.SUCCESS, ret)
+	assert.Equal(t, uint64(nvml.EventTypeXidCriticalError), data.EventType)
+	assert.Equal(t, uint64(79), data.EventData)
+	uuid, ret := data.Device.GetUUID()
+	require.Equal(t, nvml.SUCCESS, ret)
+	assert.Equal(t, testUUID1, uuid)
+
+	// delivered only once
+	_, ret = es.Wait(10)
+	assert.Equal(t, nvml.ERROR_TIMEOUT, ret)
+}
+
+func TestFixtureInterfaceMIG(t *testing.T) {
+	f, err := LoadFixture("testdata/fixture_mig.yaml")
+	require.NoError(t, err)
+	lib := NewFixtureInterface(f)
+
+	dev, ret := lib.DeviceGetHandleByIndex(0)
+	require.Equal(t, nvml.SUCCESS, ret)
+
+	current, pending, ret := dev.GetMigMode()
+	assert.Equal(t, nvml.SUCCESS, ret)
+	assert.Equal(t, nvml.DEVICE_MIG_ENABLE, current)
+	assert.Equal(t, nvml.DEVICE_MIG_ENABLE, pending)
+
+	count, ret := dev.GetMaxMigDeviceCount()
+	assert.Equal(t, nvml.SUCCESS, ret)
+	assert.Equal(t, 7, count)
+
+	migDev, ret := dev.GetMigDeviceHandleByIndex(2)
+	require.Equal(t, nvml.SUCCESS, ret)
+	uuid, ret := migDev.GetUUID()
+	assert.Equal(t, nvml.SUCCESS, ret)
+	assert.Equal(t, "MIG-33333333-3333-3333-3333-333333333333", uuid)
+	attrs, ret := migDev.GetAttributes()
+	assert.Equal(t, nvml.SUCCESS, ret)
+	assert.Equal(t, uint32(1), attrs.GpuInstanceSliceCount)
+	_, ret = dev.GetMigDeviceHandleByIndex(3)
+	assert.Equal(t, nvml.ERROR_NOT_FOUND, ret)
+
+	procs, ret := dev.GetComputeRunningProcesses()
+	assert.Equal(t, nvml.SUCCESS, ret)
+	require.Len(t, procs, 1)
+	assert.Equal(t, uint32(1), procs[0].GpuInstanceId)
+	assert.Equal(t, uint32(0), procs[0].ComputeInstanceId)
+
+	// the gpu without mig support
+	f2, _ := loadTestFixture(t)
+	dev, ret = NewFixtureInterface(f2).DeviceGetHandleByIndex(0)
+	require.Equal(t, nvml.SUCCESS, ret)
+	_, _, ret = dev.GetMigMode()
+	assert.Equal(t, nvml.ERROR_NOT_SUPPORTED, ret)
+	procs, ret = dev.GetComputeRunningProcesses()
+	assert.Equal(t, nvml.SUCCESS, ret)
+	for _, proc := range procs {
+		assert.Equal(t, uint32(0xFFFFFFFF), proc.GpuInstanceId)
+	}
+}
+
+func TestFixtureInterfaceTopology(t *testing.T) {
+	f, elapsed := loadTestFixture(t)
+	lib := NewFixtureInterface(f)
+
+	dev0, ret := lib.DeviceGetHandleByIndex(0)
+	require.Equal(t, nvml.SUCCESS, ret)
+	dev1, ret := lib.DeviceGetHandleByIndex(1)
+	require.Equal(t, nvml.SUCCESS, ret)
+
+	level, ret := dev0.GetTopologyCommonAncestor(dev0)
+	assert.Equal(t, nvml.SUCCESS, ret)
+	assert.Equal(t, nvml.TOPOLOGY_INTERNAL, level)
+	level, ret = dev0.GetTopologyCommonAncestor(dev1)
+	assert.Equal(t, nvml.SUCCESS, ret)
+	assert.Equal(t, nvml.TOPOLOGY_SYSTEM, level)
+
+	cpuSet, ret := dev1.GetCpuAffinity(128)
+	assert.Equal(t, nvml.SUCCESS, ret)
+	require.Len(t, cpuSet, 2)
+	assert.Equal(t, uint(0xFFFF000000000000), cpuSet[0])
+	assert.Equal(t, uint(0xFFFFFFFF), cpuSet[1])
+
+	remoteType, ret := dev0.GetNvLinkRemoteDeviceType(0)
+	assert.Equal(t, nvml.SUCCESS, ret)
+	assert.Equal(t, nvml.NVLINK_DEVICE_TYPE_SWITCH, remoteType)
+	_, ret = dev0.GetNvLinkRemotePciInfo(0)
+	assert.Equal(t, nvml.ERROR_NOT_SUPPORTED, ret)
+
+	// the topology queries fail once the gpu falls off the bus
+	*elapsed = 21 * time.Second
+	_, ret = dev1.GetCpuAffinity(128)
+	assert.Equal(t, nvml.ERROR_GPU_IS_LOST, ret)
+}
+
+func TestParseCPUList(t *testing.T) {
+	cpus, err := parseCPUList("0-3,8")
+	require.NoError(t, err)
+	assert.Equal(t, []int{0, 1, 2, 3, 8}, cpus)
+
+	cpus, err = parseCPUList("")
+	require.NoError(t, err)
+	assert.Empty(t, cpus)
+
+	_, err = parseCPUList("3-1")
+	require.Error(t, err)
+	_, err = parseCPUList("a")
+	require.Error(t, err)
+}
@@ -1,6 +1,8 @@
 package mock
 
 import (
+	"time"
+
 	nvinfo "github.com/NVIDIA/go-nvlib/pkg/nvlib/info"
 	"github.com/NVIDIA/go-nvml/pkg/nvml"
 	nvmlmock "github.com/NVIDIA/go-nvml/pkg/nvml/mock"
@@ -64,6 +66,9 @@ var AllSuccessInterface = &nvmlmock.Interface{
 			GetGspFirmwareModeFunc: func() (bool, bool, nvml.Return) {
 				return false, false, nvml.SUCCESS
 			},
+			GetVbiosVersionFunc: func() (string, nvml.Return) {
+				return "96.00.89.00.01", nvml.SUCCESS
+			},
 			GetPersistenceModeFunc: func() (nvml.EnableState, nvml.Return) {
 				return 1, nvml.SUCCESS
 			},
@@ -121,13 +126,48 @@ var AllSuccessInterface = &nvmlmock.Interface{
 			GetRemappedRowsFunc: func() (int, int, bool, bool, nvml.Return) {
 				return 0, 0, false, false, nvml.SUCCESS
 			},
+			GetCurrPcieLinkGenerationFunc: func() (int, nvml.Return) {
+				return 5, nvml.SUCCESS
+			},
+			GetMaxPcieLinkGenerationFunc: func() (int, nvml.Return) {
+				return 5, nvml.SUCCESS
+			},
+			GetCurrPcieLinkWidthFunc: func() (int, nvml.Return) {
+				return 16, nvml.SUCCESS
+			},
+			GetMaxPcieLinkWidthFunc: func() (int, nvml.Return) {
+				return 16, nvml.SUCCESS
+			},
+			GetPcieReplayCounterFunc: func() (int, nvml.Return) {
+				return 0, nvml.SUCCESS
+			},
+			GetPerformanceStateFunc: func() (nvml.Pstates, nvml.Return) {
+				return nvml.PSTATE_0, nvml.SUCCESS
+			},
+			GetMigModeFunc: func() (int, int, nvml.Return) {
+				return nvml.DEVICE_MIG_DISABLE, nvml.DEVICE_MIG_DISABLE, nvml.SUCCESS
+			},
+			GetTopologyCommonAncestorFunc: func(device nvml.Device) (nvml.GpuTopologyLevel, nvml.Return) {
+				return nvml.TOPOLOGY_INTERNAL, nvml.SUCCESS
+			},
+			GetCpuAffinityFunc: func(n int) ([]uint, nvml.Return) {
+				return nil, nvml.ERROR_NOT_SUPPORTED
+			},
+			GetNvLinkRemoteDeviceTypeFunc: func(n int) (nvml.IntNvLinkDeviceType, nvml.Return) {
+				return nvml.NVLINK_DEVICE_TYPE_UNKNOWN, nvml.SUCCESS
+			},
+			GetNvLinkRemotePciInfoFunc: func(n int) (nvml.PciInfo, nvml.Return) {
+				return nvml.PciInfo{}, nvml.ERROR_NOT_SUPPORTED
+			},
 		}, nvml.SUCCESS
 	},
 
 	EventSetCreateFunc: func() (nvml.EventSet, nvml.Return) {
 		return &nvmlmock.EventSet{
 			WaitFunc: func(v uint32) (nvml.EventData, nvml.Return) {
-				return nvml.EventData{}, nvml.SUCCESS
+				// no event ever happens, wait for the timeout in milliseconds
+				time.Sleep(time.Duration(v) * time.Millisecond)
+				return nvml.EventData{}, nvml.ERROR_TIMEOUT
 			},
 			FreeFunc: func() nvml.Return {
 				return nvml.SUCCESS
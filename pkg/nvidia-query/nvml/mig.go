@@ -0,0 +1,191 @@
+package nvml
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/NVIDIA/go-nvlib/pkg/nvlib/device"
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+)
+
+// MIG represents the Multi-Instance GPU (MIG) mode and the MIG devices of a GPU.
+// ref. https://docs.nvidia.com/datacenter/tesla/mig-user-guide/index.html
+type MIG struct {
+	// Represents the GPU UUID.
+	UUID string `json:"uuid"`
+
+	// Enabled is true if the MIG mode is currently enabled.
+	Enabled bool `json:"enabled"`
+	// PendingEnabled is true if the MIG mode is enabled after the next GPU reset.
+	PendingEnabled bool `json:"pending_enabled"`
+
+	// Instances is the list of the MIG devices, sorted by the GPU instance ID
+	// and the compute instance ID. Empty if the MIG mode is disabled.
+	Instances []MIGInstance `json:"instances,omitempty"`
+
+	// Supported is true if the MIG mode is supported by the device.
+	Supported bool `json:"supported"`
+}
+
+// MIGInstance is a MIG device, the pair of a GPU instance and a compute instance.
+type MIGInstance struct {
+	// UUID is the MIG device UUID (e.g., "MIG-b1028956-...").
+	UUID string `json:"uuid"`
+	// ParentUUID is the UUID of the GPU that the MIG device belongs to.
+	ParentUUID string `json:"parent_uuid"`
+
+	// GPUInstanceID is the GPU instance ID within the GPU.
+	GPUInstanceID int `json:"gpu_instance_id"`
+	// ComputeInstanceID is the compute instance ID within the GPU instance.
+	ComputeInstanceID int `json:"compute_instance_id"`
+
+	// Profile is the MIG profile name in the nvidia-smi format
+	// (e.g., "1g.10gb", or "1c.3g.40gb" for a compute instance
+	// that only uses a part of the GPU instance).
+	Profile string `json:"profile"`
+	// MultiprocessorCount is the number of the SMs of the MIG device.
+	MultiprocessorCount uint32 `json:"multiprocessor_count"`
+
+	MemoryTotalBytes uint64 `json:"memory_total_bytes"`
+	MemoryUsedBytes  uint64 `json:"memory_used_bytes"`
+}
+
+// FindInstance returns the MIG device of the GPU instance and compute instance IDs.
+// Returns false if not found.
+func (m MIG) FindInstance(gpuInstanceID int, computeInstanceID int) (MIGInstance, bool) {
+	for _, inst := range m.Instances {
+		if inst.GPUInstanceID == gpuInstanceID && inst.ComputeInstanceID == computeInstanceID {
+			return inst, true
+		}
+	}
+	return MIGInstance{}, false
+}
+
+// Profiles returns the sorted profile names of the MIG devices.
+func (m MIG) Profiles() []string {
+	profiles := make([]string, 0, len(m.Instances))
+	for _, inst := range m.Instances {
+		profiles = append(profiles, inst.Profile)
+	}
+	sort.Strings(profiles)
+	return profiles
+}
+
+// GetMIG returns the MIG mode and the MIG devices of the GPU.
+func GetMIG(uuid string, dev device.Device) (MIG, error) {
+	mig := MIG{
+		UUID:      uuid,
+		Supported: true,
+	}
+
+	// ref. https://docs.nvidia.com/deploy/nvml-api/group__nvmlMultiInstanceGPU.html
+	current, pending, ret := dev.GetMigMode()
+	if IsNotSupportError(ret) {
+		mig.Supported = false
+		return mig, nil
+	}
+	if ret != nvml.SUCCESS {
+		return mig, fmt.Errorf("failed to get device MIG mode: %v", nvml.ErrorString(ret))
+	}
+	mig.Enabled = current == nvml.DEVICE_MIG_ENABLE
+	mig.PendingEnabled = pending == nvml.DEVICE_MIG_ENABLE
+	if !mig.Enabled {
+		return mig, nil
+	}
+
+	// the parent memory is used to name the profiles in the same way as nvidia-smi
+	parentMemory, ret := dev.GetMemoryInfo()
+	if ret != nvml.SUCCESS {
+		return mig, fmt.Errorf("failed to get device memory info: %v", nvml.ErrorString(ret))
+	}
+
+	count, ret := dev.GetMaxMigDeviceCount()
+	if ret != nvml.SUCCESS {
+		return mig, fmt.Errorf("failed to get max MIG device count: %v", nvml.ErrorString(ret))
+	}
+
+	for i := 0; i < count; i++ {
+		migDev, ret := dev.GetMigDeviceHandleByIndex(i)
+		if IsNotFoundError(ret) || ret == nvml.ERROR_INVALID_ARGUMENT {
+			// no MIG device is created at the index
+			continue
+		}
+		if ret != nvml.SUCCESS {
+			return mig, fmt.Errorf("failed to get MIG device handle at index %d: %v", i, nvml.ErrorString(ret))
+		}
+
+		inst, err := getMIGInstance(uuid, migDev, parentMemory.Total)
+		if err != nil {
+			return mig, err
+		}
+		mig.Instances = append(mig.Instances, inst)
+	}
+
+	sort.Slice(mig.Instances, func(i, j int) bool {
+		if mig.Instances[i].GPUInstanceID != mig.Instances[j].GPUInstanceID {
+			return mig.Instances[i].GPUInstanceID < mig.Instances[j].GPUInstanceID
+		}
+		return mig.Instances[i].ComputeInstanceID < mig.Instances[j].ComputeInstanceID
+	})
+
+	return mig, nil
+}
+
+func getMIGInstance(parentUUID string, migDev nvml.Device, parentMemoryTotal uint64) (MIGInstance, error) {
+	inst := MIGInstance{ParentUUID: parentUUID}
+
+	var ret nvml.Return
+	inst.UUID, ret = migDev.GetUUID()
+	if ret != nvml.SUCCESS {
+		return inst, fmt.Errorf("failed to get MIG device UUID: %v", nvml.ErrorString(ret))
+	}
+	inst.GPUInstanceID, ret = migDev.GetGpuInstanceId()
+	if ret != nvml.SUCCESS {
+		return inst, fmt.Errorf("failed to get MIG device %s GPU instance ID: %v", inst.UUID, nvml.ErrorString(ret))
+	}
+	inst.ComputeInstanceID, ret = migDev.GetComputeInstanceId()
+	if ret != nvml.SUCCESS {
+		return inst, fmt.Errorf("failed to get MIG device %s compute instance ID: %v", inst.UUID, nvml.ErrorString(ret))
+	}
+
+	attrs, ret := migDev.GetAttributes()
+	if ret != nvml.SUCCESS {
+		return inst, fmt.Errorf("failed to get MIG device %s attributes: %v", inst.UUID, nvml.ErrorString(ret))
+	}
+	inst.Profile = migProfileName(attrs, parentMemoryTotal)
+	inst.MultiprocessorCount = attrs.MultiprocessorCount
+
+	mem, ret := migDev.GetMemoryInfo()
+	if ret != nvml.SUCCESS {
+		return inst, fmt.Errorf("failed to get MIG device %s memory info: %v", inst.UUID, nvml.ErrorString(ret))
+	}
+	inst.MemoryTotalBytes = mem.Total
+	inst.MemoryUsedBytes = mem.Used
+
+	return inst, nil
+}
+
+// migProfileName returns the MIG profile name in the nvidia-smi format
+// (e.g., "3g.40gb"), where the memory size is rounded to the fraction
+// of the parent GPU memory in the same way as go-nvlib.
+// ref. https://github.com/NVIDIA/go-nvlib/blob/main/pkg/nvlib/device/mig_profile.go
+func migProfileName(attrs nvml.DeviceAttributes, parentMemoryTotal uint64) string {
+	memGB := (attrs.MemorySizeMB + 1023) / 1024
+	if parentMemoryTotal > 0 {
+		const (
+			fracDenominator = 8
+			oneMB           = 1024 * 1024
+			oneGB           = 1024 * 1024 * 1024
+		)
+		frac := float64(attrs.MemorySizeMB*oneMB) / float64(parentMemoryTotal)
+		frac = math.Ceil(frac*fracDenominator) / fracDenominator
+		totalGB := float64((parentMemoryTotal + oneGB - 1) / oneGB)
+		memGB = uint64(math.Round(frac * totalGB))
+	}
+
+	if attrs.ComputeInstanceSliceCount > 0 && attrs.ComputeInstanceSliceCount < attrs.GpuInstanceSliceCount {
+		return fmt.Sprintf("%dc.%dg.%dgb", attrs.ComputeInstanceSliceCount, attrs.GpuInstanceSliceCount, memGB)
+	}
+	return fmt.Sprintf("%dg.%dgb", attrs.GpuInstanceSliceCount, memGB)
+}
@@ -0,0 +1,126 @@
+package nvml
+
+import (
+	"testing"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+	"github.com/NVIDIA/go-nvml/pkg/nvml/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/leptonai/gpud/pkg/nvidia-query/nvml/testutil"
+)
+
+// H100 80GB HBM3
+const testH100MemoryTotal = uint64(85520809984)
+
+func newTestMIGDevice(uuid string, gi, ci int, attrs nvml.DeviceAttributes) *mock.Device {
+	return &mock.Device{
+		GetUUIDFunc:              func() (string, nvml.Return) { return uuid, nvml.SUCCESS },
+		GetGpuInstanceIdFunc:     func() (int, nvml.Return) { return gi, nvml.SUCCESS },
+		GetComputeInstanceIdFunc: func() (int, nvml.Return) { return ci, nvml.SUCCESS },
+		GetAttributesFunc:        func() (nvml.DeviceAttributes, nvml.Return) { return attrs, nvml.SUCCESS },
+		GetMemoryInfoFunc: func() (nvml.Memory, nvml.Return) {
+			return nvml.Memory{Total: attrs.MemorySizeMB * 1024 * 1024, Used: 1024}, nvml.SUCCESS
+		},
+	}
+}
+
+func TestGetMIG(t *testing.T) {
+	migs := map[int]*mock.Device{
+		// index 1 is not created
+		0: newTestMIGDevice("MIG-2", 2, 0, nvml.DeviceAttributes{GpuInstanceSliceCount: 3, ComputeInstanceSliceCount: 3, MemorySizeMB: 40192, MultiprocessorCount: 60}),
+		2: newTestMIGDevice("MIG-1", 1, 0, nvml.DeviceAttributes{GpuInstanceSliceCount: 3, ComputeInstanceSliceCount: 1, MemorySizeMB: 40192, MultiprocessorCount: 16}),
+	}
+	dev := testutil.NewMockDevice(&mock.Device{
+		GetMigModeFunc: func() (int, int, nvml.Return) {
+			return nvml.DEVICE_MIG_ENABLE, nvml.DEVICE_MIG_ENABLE, nvml.SUCCESS
+		},
+		GetMemoryInfoFunc: func() (nvml.Memory, nvml.Return) {
+			return nvml.Memory{Total: testH100MemoryTotal}, nvml.SUCCESS
+		},
+		GetMaxMigDeviceCountFunc: func() (int, nvml.Return) {
+			return 7, nvml.SUCCESS
+		},
+		GetMigDeviceHandleByIndexFunc: func(i int) (nvml.Device, nvml.Return) {
+			if d, ok := migs[i]; ok {
+				return d, nvml.SUCCESS
+			}
+			return nil, nvml.ERROR_NOT_FOUND
+		},
+	}, "hopper", "Nvidia", "9.0", "0000:18:00.0")
+
+	mig, err := GetMIG("GPU-0", dev)
+	require.NoError(t, err)
+	assert.True(t, mig.Supported)
+	assert.True(t, mig.Enabled)
+	assert.True(t, mig.PendingEnabled)
+	require.Len(t, mig.Instances, 2)
+
+	assert.Equal(t, MIGInstance{
+		UUID:                "MIG-1",
+		ParentUUID:          "GPU-0",
+		GPUInstanceID:       1,
+		ComputeInstanceID:   0,
+		Profile:             "1c.3g.40gb",
+		MultiprocessorCount: 16,
+		MemoryTotalBytes:    40192 * 1024 * 1024,
+		MemoryUsedBytes:     1024,
+	}, mig.Instances[0])
+	assert.Equal(t, "MIG-2", mig.Instances[1].UUID)
+	assert.Equal(t, "3g.40gb", mig.Instances[1].Profile)
+	assert.Equal(t, []string{"1c.3g.40gb", "3g.40gb"}, mig.Profiles())
+
+	inst, ok := mig.FindInstance(2, 0)
+	assert.True(t, ok)
+	assert.Equal(t, "MIG-2", inst.UUID)
+	_, ok = mig.FindInstance(2, 1)
+	assert.False(t, ok)
+}
+
+func TestGetMIGDisabledOrNotSupported(t *testing.T) {
+	dev := testutil.NewMockDevice(&mock.Device{
+		GetMigModeFunc: func() (int, int, nvml.Return) {
+			return nvml.DEVICE_MIG_DISABLE, nvml.DEVICE_MIG_ENABLE, nvml.SUCCESS
+		},
+	}, "hopper", "Nvidia", "9.0", "0000:18:00.0")
+	mig, err := GetMIG("GPU-0", dev)
+	require.NoError(t, err)
+	assert.Equal(t, MIG{UUID: "GPU-0", Supported: true, PendingEnabled: true}, mig)
+
+	dev = testutil.NewMockDevice(&mock.Device{
+		GetMigModeFunc: func() (int, int, nvml.Return) {
+			return 0, 0, nvml.ERROR_NOT_SUPPORTED
+		},
+	}, "ampere", "Nvidia", "8.6", "0000:18:00.0")
+	mig, err = GetMIG("GPU-0", dev)
+	require.NoError(t, err)
+	assert.False(t, mig.Supported)
+
+	dev = testutil.NewMockDevice(&mock.Device{
+		GetMigModeFunc: func() (int, int, nvml.Return) {
+			return 0, 0, nvml.ERROR_UNKNOWN
+		},
+	}, "ampere", "Nvidia", "8.6", "0000:18:00.0")
+	_, err = GetMIG("GPU-0", dev)
+	assert.Error(t, err)
+}
+
+func TestMIGProfileName(t *testing.T) {
+	tests := []struct {
+		attrs    nvml.DeviceAttributes
+		total    uint64
+		expected string
+	}{
+		{nvml.DeviceAttributes{GpuInstanceSliceCount: 1, ComputeInstanceSliceCount: 1, MemorySizeMB: 9856}, testH100MemoryTotal, "1g.10gb"},
+		{nvml.DeviceAttributes{GpuInstanceSliceCount: 1, ComputeInstanceSliceCount: 1, MemorySizeMB: 19968}, testH100MemoryTotal, "1g.20gb"},
+		{nvml.DeviceAttributes{GpuInstanceSliceCount: 2, ComputeInstanceSliceCount: 2, MemorySizeMB: 20096}, testH100MemoryTotal, "2g.20gb"},
+		{nvml.DeviceAttributes{GpuInstanceSliceCount: 7, ComputeInstanceSliceCount: 7, MemorySizeMB: 80896}, testH100MemoryTotal, "7g.80gb"},
+		{nvml.DeviceAttributes{GpuInstanceSliceCount: 4, ComputeInstanceSliceCount: 2, MemorySizeMB: 40192}, testH100MemoryTotal, "2c.4g.40gb"},
+		// unknown parent memory
+		{nvml.DeviceAttributes{GpuInstanceSliceCount: 1, ComputeInstanceSliceCount: 1, MemorySizeMB: 4864}, 0, "1g.5gb"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, migProfileName(tt.attrs, tt.total))
+	}
+}
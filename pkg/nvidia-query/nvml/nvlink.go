@@ -1,6 +1,8 @@
 package nvml
 
 import (
+	"encoding/binary"
+
 	"github.com/leptonai/gpud/pkg/log"
 
 	"github.com/NVIDIA/go-nvlib/pkg/nvlib/device"
@@ -66,9 +68,9 @@ type NVLinkState struct {
 	// CRCErrors is the number of crc errors.
 	CRCErrors uint64 `json:"crc_errors"`
 
-	// ThroughputRawTxBytes is the NVLink TX Data throughput + protocol overhead in bytes.
+	// ThroughputRawTxBytes is the cumulative NVLink TX Data throughput + protocol overhead in bytes.
 	ThroughputRawTxBytes uint64 `json:"throughput_raw_tx_bytes"`
-	// ThroughputRawRxBytes is the NVLink RX Data throughput + protocol overhead in bytes.
+	// ThroughputRawRxBytes is the cumulative NVLink RX Data throughput + protocol overhead in bytes.
 	ThroughputRawRxBytes uint64 `json:"throughput_raw_rx_bytes"`
 }
 
@@ -118,6 +120,27 @@ func GetNVLink(uuid string, dev device.Device) (NVLink, error) {
 			nvlinkState.CRCErrors = crcErrors
 		}
 
+		// cumulative counters in KiB, scoped by the link number
+		// ref. https://docs.nvidia.com/deploy/nvml-api/group__nvmlFieldValueQueries.html
+		fieldValues := []nvml.FieldValue{
+			{FieldId: nvml.FI_DEV_NVLINK_THROUGHPUT_RAW_TX, ScopeId: uint32(link)},
+			{FieldId: nvml.FI_DEV_NVLINK_THROUGHPUT_RAW_RX, ScopeId: uint32(link)},
+		}
+		if ret := dev.GetFieldValues(fieldValues); ret == nvml.SUCCESS {
+			for _, v := range fieldValues {
+				if nvml.Return(v.NvmlReturn) != nvml.SUCCESS {
+					continue
+				}
+				bytes := binary.LittleEndian.Uint64(v.Value[:]) * 1024
+				switch v.FieldId {
+				case nvml.FI_DEV_NVLINK_THROUGHPUT_RAW_TX:
+					nvlinkState.ThroughputRawTxBytes = bytes
+				case nvml.FI_DEV_NVLINK_THROUGHPUT_RAW_RX:
+					nvlinkState.ThroughputRawRxBytes = bytes
+				}
+			}
+		}
+
 		// TODO
 		// nvmlDeviceGetNvLinkRemotePciInfo_v2
 		// ref. https://docs.nvidia.com/deploy/nvml-api/group__NvLink.html#group__NvLink_1gee01cb84cd8a08f08ddaec36cd9e62ff
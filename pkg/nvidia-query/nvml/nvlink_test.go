@@ -1,6 +1,7 @@
 package nvml
 
 import (
+	"encoding/binary"
 	"testing"
 
 	"github.com/NVIDIA/go-nvlib/pkg/nvlib/device"
@@ -20,6 +21,25 @@ type mockDevice struct {
 	crcErrors         uint64
 	crcErrorsErr      nvml.Return
 	fieldValuesErr    nvml.Return
+	rawTxKiB          uint64
+	rawRxKiB          uint64
+}
+
+func (m *mockDevice) GetFieldValues(values []nvml.FieldValue) nvml.Return {
+	if m.fieldValuesErr != nvml.SUCCESS {
+		return m.fieldValuesErr
+	}
+	for i := range values {
+		switch values[i].FieldId {
+		case nvml.FI_DEV_NVLINK_THROUGHPUT_RAW_TX:
+			binary.LittleEndian.PutUint64(values[i].Value[:], m.rawTxKiB)
+		case nvml.FI_DEV_NVLINK_THROUGHPUT_RAW_RX:
+			binary.LittleEndian.PutUint64(values[i].Value[:], m.rawRxKiB)
+		default:
+			values[i].NvmlReturn = uint32(nvml.ERROR_NOT_SUPPORTED)
+		}
+	}
+	return nvml.SUCCESS
 }
 
 func (m *mockDevice) GetNvLinkState(link int) (nvml.EnableState, nvml.Return) {
@@ -128,6 +148,8 @@ func TestGetNVLink(t *testing.T) {
 		expectedReplayErrors   uint64
 		expectedRecoveryErrors uint64
 		expectedCRCErrors      uint64
+		expectedRawTxBytes     uint64
+		expectedRawRxBytes     uint64
 	}{
 		{
 			name: "NVLink supported and working",
@@ -141,6 +163,8 @@ func TestGetNVLink(t *testing.T) {
 				crcErrors:         30,
 				crcErrorsErr:      nvml.SUCCESS,
 				fieldValuesErr:    nvml.SUCCESS,
+				rawTxKiB:          2,
+				rawRxKiB:          3,
 			},
 			expectedSupported:      true,
 			expectedStatesCount:    nvml.NVLINK_MAX_LINKS,
@@ -148,6 +172,22 @@ func TestGetNVLink(t *testing.T) {
 			expectedReplayErrors:   10,
 			expectedRecoveryErrors: 20,
 			expectedCRCErrors:      30,
+			expectedRawTxBytes:     2048,
+			expectedRawRxBytes:     3072,
+		},
+		{
+			name: "NVLink throughput not supported",
+			mockDev: &mockDevice{
+				nvLinkState:       nvml.FEATURE_DISABLED,
+				nvLinkStateErr:    nvml.SUCCESS,
+				replayErrorsErr:   nvml.SUCCESS,
+				recoveryErrorsErr: nvml.SUCCESS,
+				crcErrorsErr:      nvml.SUCCESS,
+				fieldValuesErr:    nvml.ERROR_NOT_SUPPORTED,
+			},
+			expectedSupported:      true,
+			expectedStatesCount:    nvml.NVLINK_MAX_LINKS,
+			expectedFeatureEnabled: false,
 		},
 		{
 			name: "NVLink not supported",
@@ -199,6 +239,8 @@ func TestGetNVLink(t *testing.T) {
 					assert.Equal(t, tc.expectedReplayErrors, state.ReplayErrors)
 					assert.Equal(t, tc.expectedRecoveryErrors, state.RecoveryErrors)
 					assert.Equal(t, tc.expectedCRCErrors, state.CRCErrors)
+					assert.Equal(t, tc.expectedRawTxBytes, state.ThroughputRawTxBytes)
+					assert.Equal(t, tc.expectedRawRxBytes, state.ThroughputRawRxBytes)
 				}
 			}
 		})
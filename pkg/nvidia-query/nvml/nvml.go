@@ -96,7 +96,7 @@ func NewInstance(ctx context.Context, opts ...OpOption) (Instance, error) {
 		log.Logger.Warnw("old nvidia driver -- skipping clock events, see https://github.com/NVIDIA/go-nvml/pull/123", "version", driverVersion)
 	}
 
-	cudaVersion, err := getCUDAVersion(nvmlLib.NVML())
+	cudaVersion, err := GetSystemCUDAVersion(nvmlLib.NVML())
 	if err != nil {
 		return nil, err
 	}
@@ -587,10 +587,10 @@ func GetCUDAVersion() (string, error) {
 		_ = nvmlLib.Shutdown()
 	}()
 
-	return getCUDAVersion(nvmlLib.NVML())
+	return GetSystemCUDAVersion(nvmlLib.NVML())
 }
 
-func getCUDAVersion(nvmlLib nvml.Interface) (string, error) {
+func GetSystemCUDAVersion(nvmlLib nvml.Interface) (string, error) {
 	// ref. https://docs.nvidia.com/deploy/nvml-api/group__nvmlSystemQueries.html#group__nvmlSystemQueries_1g1d12b603a42805ee7e4160557ffc2128
 	ver, ret := nvmlLib.SystemGetCudaDriverVersion_v2()
 	if ret != nvml.SUCCESS {
@@ -0,0 +1,117 @@
+package nvml
+
+import (
+	"fmt"
+
+	"github.com/NVIDIA/go-nvlib/pkg/nvlib/device"
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+)
+
+// PCIeLink is the PCIe link status of the GPU.
+type PCIeLink struct {
+	// Represents the GPU UUID.
+	UUID string `json:"uuid"`
+	// Represents the GPU PCI bus ID.
+	BusID string `json:"bus_id"`
+
+	// CurrentGeneration is the current PCIe link generation (e.g., 4 for Gen4).
+	CurrentGeneration int `json:"current_generation"`
+	// MaxGeneration is the maximum PCIe link generation possible
+	// with this GPU and system configuration.
+	MaxGeneration int `json:"max_generation"`
+	// CurrentWidth is the current PCIe link width (e.g., 16 for x16).
+	CurrentWidth int `json:"current_width"`
+	// MaxWidth is the maximum PCIe link width possible
+	// with this GPU and system configuration.
+	MaxWidth int `json:"max_width"`
+
+	// ReplayCounter is the cumulative PCIe replay counter,
+	// incremented when the link layer retransmits a packet (e.g., bad signal integrity).
+	ReplayCounter uint64 `json:"replay_counter"`
+
+	// PerformanceState is the current performance state (0 for P0, the maximum performance).
+	// The GPU may lower the PCIe link generation when idle to save power,
+	// so the generation is only expected at its maximum in P0.
+	PerformanceState int `json:"performance_state"`
+
+	Supported bool `json:"supported"`
+}
+
+// GenerationDegraded returns true if the PCIe link generation is below its maximum
+// while the GPU is at its maximum performance state.
+func (link PCIeLink) GenerationDegraded() bool {
+	if !link.Supported || link.MaxGeneration == 0 {
+		return false
+	}
+	return link.PerformanceState == int(nvml.PSTATE_0) && link.CurrentGeneration < link.MaxGeneration
+}
+
+// WidthDegraded returns true if the PCIe link width is below its maximum.
+// Unlike the generation, the width is not lowered for power saving.
+func (link PCIeLink) WidthDegraded() bool {
+	if !link.Supported || link.MaxWidth == 0 {
+		return false
+	}
+	return link.CurrentWidth < link.MaxWidth
+}
+
+// Degraded returns true if the PCIe link is trained below its maximum.
+func (link PCIeLink) Degraded() bool {
+	return link.GenerationDegraded() || link.WidthDegraded()
+}
+
+func GetPCIeLink(uuid string, dev device.Device) (PCIeLink, error) {
+	link := PCIeLink{
+		UUID:      uuid,
+		Supported: true,
+	}
+	if busID, err := dev.GetPCIBusID(); err == nil {
+		link.BusID = busID
+	}
+
+	currGen, ret := dev.GetCurrPcieLinkGeneration()
+	if IsNotSupportError(ret) {
+		link.Supported = false
+		return link, nil
+	}
+	if ret != nvml.SUCCESS {
+		return link, fmt.Errorf("failed to get current pcie link generation: %v", nvml.ErrorString(ret))
+	}
+	link.CurrentGeneration = currGen
+
+	maxGen, ret := dev.GetMaxPcieLinkGeneration()
+	if ret != nvml.SUCCESS && !IsNotSupportError(ret) {
+		return link, fmt.Errorf("failed to get max pcie link generation: %v", nvml.ErrorString(ret))
+	}
+	link.MaxGeneration = maxGen
+
+	currWidth, ret := dev.GetCurrPcieLinkWidth()
+	if ret != nvml.SUCCESS && !IsNotSupportError(ret) {
+		return link, fmt.Errorf("failed to get current pcie link width: %v", nvml.ErrorString(ret))
+	}
+	link.CurrentWidth = currWidth
+
+	maxWidth, ret := dev.GetMaxPcieLinkWidth()
+	if ret != nvml.SUCCESS && !IsNotSupportError(ret) {
+		return link, fmt.Errorf("failed to get max pcie link width: %v", nvml.ErrorString(ret))
+	}
+	link.MaxWidth = maxWidth
+
+	replays, ret := dev.GetPcieReplayCounter()
+	if ret != nvml.SUCCESS && !IsNotSupportError(ret) {
+		return link, fmt.Errorf("failed to get pcie replay counter: %v", nvml.ErrorString(ret))
+	}
+	link.ReplayCounter = uint64(replays)
+
+	pstate, ret := dev.GetPerformanceState()
+	if ret != nvml.SUCCESS && !IsNotSupportError(ret) {
+		return link, fmt.Errorf("failed to get performance state: %v", nvml.ErrorString(ret))
+	}
+	if IsNotSupportError(ret) {
+		// unknown performance state, do not evaluate the generation
+		pstate = nvml.PSTATE_UNKNOWN
+	}
+	link.PerformanceState = int(pstate)
+
+	return link, nil
+}
@@ -0,0 +1,83 @@
+package nvml
+
+import (
+	"testing"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+	"github.com/NVIDIA/go-nvml/pkg/nvml/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/leptonai/gpud/pkg/nvidia-query/nvml/testutil"
+)
+
+func newPCIeMockDevice(currGen, maxGen, currWidth, maxWidth, replays int, pstate nvml.Pstates) *testutil.MockDevice {
+	return testutil.NewMockDevice(&mock.Device{
+		GetCurrPcieLinkGenerationFunc: func() (int, nvml.Return) { return currGen, nvml.SUCCESS },
+		GetMaxPcieLinkGenerationFunc:  func() (int, nvml.Return) { return maxGen, nvml.SUCCESS },
+		GetCurrPcieLinkWidthFunc:      func() (int, nvml.Return) { return currWidth, nvml.SUCCESS },
+		GetMaxPcieLinkWidthFunc:       func() (int, nvml.Return) { return maxWidth, nvml.SUCCESS },
+		GetPcieReplayCounterFunc:      func() (int, nvml.Return) { return replays, nvml.SUCCESS },
+		GetPerformanceStateFunc:       func() (nvml.Pstates, nvml.Return) { return pstate, nvml.SUCCESS },
+	}, "hopper", "Nvidia", "9.0", "0000:19:00.0")
+}
+
+func TestGetPCIeLink(t *testing.T) {
+	tests := []struct {
+		name            string
+		dev             *testutil.MockDevice
+		wantGenDegraded bool
+		wantWidthDeg    bool
+	}{
+		{
+			name: "full link",
+			dev:  newPCIeMockDevice(5, 5, 16, 16, 0, nvml.PSTATE_0),
+		},
+		{
+			name:         "downtrained width",
+			dev:          newPCIeMockDevice(5, 5, 8, 16, 0, nvml.PSTATE_0),
+			wantWidthDeg: true,
+		},
+		{
+			name:            "downtrained generation at P0",
+			dev:             newPCIeMockDevice(3, 5, 16, 16, 0, nvml.PSTATE_0),
+			wantGenDegraded: true,
+		},
+		{
+			name: "lower generation when idle",
+			dev:  newPCIeMockDevice(1, 5, 16, 16, 0, nvml.PSTATE_8),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			link, err := GetPCIeLink("GPU-1", tt.dev)
+			require.NoError(t, err)
+			assert.True(t, link.Supported)
+			assert.Equal(t, "0000:19:00.0", link.BusID)
+			assert.Equal(t, tt.wantGenDegraded, link.GenerationDegraded())
+			assert.Equal(t, tt.wantWidthDeg, link.WidthDegraded())
+			assert.Equal(t, tt.wantGenDegraded || tt.wantWidthDeg, link.Degraded())
+		})
+	}
+}
+
+func TestGetPCIeLinkNotSupported(t *testing.T) {
+	dev := testutil.NewMockDevice(&mock.Device{
+		GetCurrPcieLinkGenerationFunc: func() (int, nvml.Return) { return 0, nvml.ERROR_NOT_SUPPORTED },
+	}, "hopper", "Nvidia", "9.0", "0000:19:00.0")
+
+	link, err := GetPCIeLink("GPU-1", dev)
+	require.NoError(t, err)
+	assert.False(t, link.Supported)
+	assert.False(t, link.Degraded())
+}
+
+func TestGetPCIeLinkError(t *testing.T) {
+	dev := testutil.NewMockDevice(&mock.Device{
+		GetCurrPcieLinkGenerationFunc: func() (int, nvml.Return) { return 0, nvml.ERROR_UNKNOWN },
+	}, "hopper", "Nvidia", "9.0", "0000:19:00.0")
+
+	_, err := GetPCIeLink("GPU-1", dev)
+	assert.Error(t, err)
+}
@@ -14,6 +14,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/yaml"
 
+	"github.com/leptonai/gpud/pkg/container"
 	"github.com/leptonai/gpud/pkg/log"
 )
 
@@ -49,6 +50,14 @@ type Process struct {
 	// This implements "DCGM_FR_BAD_CUDA_ENV" logic in DCGM.
 	BadEnvVarsForCUDA map[string]string `json:"bad_env_vars_for_cuda,omitempty"`
 
+	// MIG is the MIG device that runs the process,
+	// nil if the MIG mode is disabled.
+	MIG *ProcessMIG `json:"mig,omitempty"`
+
+	// Container is the container (and the Kubernetes pod) that runs the process,
+	// nil if the process does not run in a container.
+	Container *container.Identity `json:"container,omitempty"`
+
 	CmdArgs                     []string    `json:"cmd_args,omitempty"`
 	CreateTime                  metav1.Time `json:"create_time,omitempty"`
 	GPUUsedPercent              uint32      `json:"gpu_used_percent,omitempty"`
@@ -56,6 +65,19 @@ type Process struct {
 	GPUUsedMemoryBytesHumanized string      `json:"gpu_used_memory_bytes_humanized,omitempty"`
 }
 
+// ProcessMIG is the MIG device that runs the process.
+type ProcessMIG struct {
+	GPUInstanceID     int `json:"gpu_instance_id"`
+	ComputeInstanceID int `json:"compute_instance_id"`
+	// UUID is the MIG device UUID, empty if not resolved.
+	UUID string `json:"uuid,omitempty"`
+}
+
+// invalidInstanceID is the GPU or compute instance ID of the process
+// when the MIG mode is disabled.
+// ref. https://docs.nvidia.com/deploy/nvml-api/structnvmlProcessInfo__t.html
+const invalidInstanceID = 0xFFFFFFFF
+
 func (procs *Processes) JSON() ([]byte, error) {
 	return json.Marshal(procs)
 }
@@ -164,9 +186,19 @@ func GetProcesses(uuid string, dev device.Device) (Processes, error) {
 			badEnvVars = nil
 		}
 
+		var mig *ProcessMIG
+		if proc.GpuInstanceId != invalidInstanceID && proc.ComputeInstanceId != invalidInstanceID {
+			mig = &ProcessMIG{
+				GPUInstanceID:     int(proc.GpuInstanceId),
+				ComputeInstanceID: int(proc.ComputeInstanceId),
+			}
+		}
+
 		procs.RunningProcesses = append(procs.RunningProcesses, Process{
 			PID: proc.Pid,
 
+			MIG: mig,
+
 			Status:       status,
 			ZombieStatus: isZombie,
 
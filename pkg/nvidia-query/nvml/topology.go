@@ -0,0 +1,185 @@
+package nvml
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/NVIDIA/go-nvlib/pkg/nvlib/device"
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+
+	"github.com/leptonai/gpud/pkg/pci"
+)
+
+// The topology link types between the devices, in the same notation as "nvidia-smi topo -m".
+const (
+	// TopologyLinkSelf is the link to the device itself.
+	TopologyLinkSelf = "X"
+	// TopologyLinkPIX is the connection traversing at most a single PCIe bridge.
+	TopologyLinkPIX = "PIX"
+	// TopologyLinkPXB is the connection traversing multiple PCIe bridges,
+	// without traversing the PCIe host bridge.
+	TopologyLinkPXB = "PXB"
+	// TopologyLinkPHB is the connection traversing PCIe as well as a PCIe host bridge
+	// (typically the CPU).
+	TopologyLinkPHB = "PHB"
+	// TopologyLinkNode is the connection traversing PCIe as well as the interconnect
+	// between the PCIe host bridges within a NUMA node.
+	TopologyLinkNode = "NODE"
+	// TopologyLinkSYS is the connection traversing PCIe as well as the SMP interconnect
+	// between the NUMA nodes (e.g., QPI/UPI).
+	TopologyLinkSYS = "SYS"
+)
+
+// topologyLinkRanks ranks the link types from the closest to the farthest.
+var topologyLinkRanks = map[string]int{
+	TopologyLinkSelf: 0,
+	TopologyLinkPIX:  1,
+	TopologyLinkPXB:  2,
+	TopologyLinkPHB:  3,
+	TopologyLinkNode: 4,
+	TopologyLinkSYS:  5,
+}
+
+// TopologyLinkRank returns the rank of the link type, where the lower is the closer.
+// The NVLink connections (e.g., "NV18") are ranked the closest after the self.
+// Returns -1 if the link type is unknown.
+func TopologyLinkRank(link string) int {
+	if strings.HasPrefix(link, "NV") {
+		return 0
+	}
+	if rank, ok := topologyLinkRanks[link]; ok {
+		return rank
+	}
+	return -1
+}
+
+// TopologyLevelToLink converts the NVML topology level to the link type.
+// Returns an empty string if the level is unknown.
+// ref. https://docs.nvidia.com/deploy/nvml-api/group__nvmlDeviceEnumvs.html
+func TopologyLevelToLink(level nvml.GpuTopologyLevel) string {
+	switch level {
+	case nvml.TOPOLOGY_INTERNAL:
+		return TopologyLinkSelf
+	case nvml.TOPOLOGY_SINGLE:
+		return TopologyLinkPIX
+	case nvml.TOPOLOGY_MULTIPLE:
+		return TopologyLinkPXB
+	case nvml.TOPOLOGY_HOSTBRIDGE:
+		return TopologyLinkPHB
+	case nvml.TOPOLOGY_NODE:
+		return TopologyLinkNode
+	case nvml.TOPOLOGY_SYSTEM:
+		return TopologyLinkSYS
+	default:
+		return ""
+	}
+}
+
+// GetTopologyLink returns the PCIe topology link type between the two GPUs
+// based on their common ancestor (e.g., "PIX" for the GPUs under the same PCIe switch).
+// Returns an empty string and no error if not supported.
+func GetTopologyLink(dev1 device.Device, dev2 device.Device) (string, error) {
+	// ref. https://docs.nvidia.com/deploy/nvml-api/group__nvmlDeviceQueries.html
+	level, ret := dev1.GetTopologyCommonAncestor(dev2)
+	if IsNotSupportError(ret) {
+		return "", nil
+	}
+	if ret != nvml.SUCCESS {
+		return "", fmt.Errorf("failed to get topology common ancestor: %v", nvml.ErrorString(ret))
+	}
+	return TopologyLevelToLink(level), nil
+}
+
+// GetCPUAffinity returns the sorted IDs of the CPUs that are local to the GPU
+// (e.g., the CPUs of the socket that the GPU is attached to).
+// Returns nil and no error if not supported.
+func GetCPUAffinity(dev device.Device, numCPUs int) ([]int, error) {
+	if numCPUs <= 0 {
+		return nil, nil
+	}
+
+	// ref. https://docs.nvidia.com/deploy/nvml-api/group__nvmlAffinity.html
+	cpuSet, ret := dev.GetCpuAffinity(numCPUs)
+	if IsNotSupportError(ret) {
+		return nil, nil
+	}
+	if ret != nvml.SUCCESS {
+		return nil, fmt.Errorf("failed to get cpu affinity: %v", nvml.ErrorString(ret))
+	}
+
+	var cpus []int
+	for i, word := range cpuSet {
+		for bit := 0; bit < 64; bit++ {
+			if word&(1<<uint(bit)) == 0 {
+				continue
+			}
+			cpu := i*64 + bit
+			if cpu >= numCPUs {
+				return cpus, nil
+			}
+			cpus = append(cpus, cpu)
+		}
+	}
+	return cpus, nil
+}
+
+// NVLinkPeers is the NVLink connections of a GPU to the remote devices.
+type NVLinkPeers struct {
+	// GPULinks maps the PCI bus ID of the remote GPU (e.g., "0000:2a:00.0")
+	// to the number of the active links to the GPU.
+	GPULinks map[string]int `json:"gpu_links,omitempty"`
+	// SwitchLinks is the number of the active links to the NVSwitches,
+	// where all the GPUs connected to the NVSwitches can reach each other.
+	SwitchLinks int `json:"switch_links"`
+}
+
+// GetNVLinkPeers returns the active NVLink connections of the GPU.
+// Returns the zero value and no error if NVLink is not supported.
+func GetNVLinkPeers(dev device.Device) (NVLinkPeers, error) {
+	peers := NVLinkPeers{}
+
+	for link := 0; link < int(nvml.NVLINK_MAX_LINKS); link++ {
+		state, ret := dev.GetNvLinkState(link)
+		if IsNotSupportError(ret) || ret == nvml.ERROR_INVALID_ARGUMENT {
+			break
+		}
+		if ret != nvml.SUCCESS || state != nvml.FEATURE_ENABLED {
+			continue
+		}
+
+		// ref. https://docs.nvidia.com/deploy/nvml-api/group__NvLink.html
+		remoteType, ret := dev.GetNvLinkRemoteDeviceType(link)
+		if ret == nvml.SUCCESS && remoteType == nvml.NVLINK_DEVICE_TYPE_SWITCH {
+			peers.SwitchLinks++
+			continue
+		}
+
+		remote, ret := dev.GetNvLinkRemotePciInfo(link)
+		if IsNotSupportError(ret) {
+			continue
+		}
+		if ret != nvml.SUCCESS {
+			return peers, fmt.Errorf("failed to get nvlink %d remote pci info: %v", link, nvml.ErrorString(ret))
+		}
+		if peers.GPULinks == nil {
+			peers.GPULinks = make(map[string]int)
+		}
+		peers.GPULinks[PCIBusIDToString(remote.BusId)]++
+	}
+
+	return peers, nil
+}
+
+// PCIBusIDToString converts the NVML PCI bus ID (e.g., "00000000:2A:00.0")
+// to the lower-case PCI bus ID with the 4-digit domain (e.g., "0000:2a:00.0"),
+// in the same format as the sysfs and the go-nvlib device.
+func PCIBusIDToString(busID [32]int8) string {
+	var b []byte
+	for _, c := range busID {
+		if c == 0 {
+			break
+		}
+		b = append(b, byte(c))
+	}
+	return pci.NormalizeBusID(string(b))
+}
@@ -0,0 +1,135 @@
+package nvml
+
+import (
+	"testing"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+	"github.com/NVIDIA/go-nvml/pkg/nvml/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/leptonai/gpud/pkg/nvidia-query/nvml/testutil"
+)
+
+func toTestBusID(s string) [32]int8 {
+	var b [32]int8
+	for i := 0; i < len(s) && i < len(b); i++ {
+		b[i] = int8(s[i])
+	}
+	return b
+}
+
+func TestTopologyLevelToLink(t *testing.T) {
+	assert.Equal(t, TopologyLinkSelf, TopologyLevelToLink(nvml.TOPOLOGY_INTERNAL))
+	assert.Equal(t, TopologyLinkPIX, TopologyLevelToLink(nvml.TOPOLOGY_SINGLE))
+	assert.Equal(t, TopologyLinkPXB, TopologyLevelToLink(nvml.TOPOLOGY_MULTIPLE))
+	assert.Equal(t, TopologyLinkPHB, TopologyLevelToLink(nvml.TOPOLOGY_HOSTBRIDGE))
+	assert.Equal(t, TopologyLinkNode, TopologyLevelToLink(nvml.TOPOLOGY_NODE))
+	assert.Equal(t, TopologyLinkSYS, TopologyLevelToLink(nvml.TOPOLOGY_SYSTEM))
+	assert.Empty(t, TopologyLevelToLink(nvml.GpuTopologyLevel(99)))
+
+	assert.Equal(t, 0, TopologyLinkRank("NV18"))
+	assert.Less(t, TopologyLinkRank(TopologyLinkPIX), TopologyLinkRank(TopologyLinkSYS))
+	assert.Equal(t, -1, TopologyLinkRank("unknown"))
+}
+
+func TestGetTopologyLink(t *testing.T) {
+	dev2 := testutil.NewMockDevice(&mock.Device{}, "hopper", "Nvidia", "9.0", "0000:2a:00.0")
+	dev1 := testutil.NewMockDevice(&mock.Device{
+		GetTopologyCommonAncestorFunc: func(d nvml.Device) (nvml.GpuTopologyLevel, nvml.Return) {
+			return nvml.TOPOLOGY_NODE, nvml.SUCCESS
+		},
+	}, "hopper", "Nvidia", "9.0", "0000:18:00.0")
+
+	link, err := GetTopologyLink(dev1, dev2)
+	require.NoError(t, err)
+	assert.Equal(t, TopologyLinkNode, link)
+
+	dev1.GetTopologyCommonAncestorFunc = func(d nvml.Device) (nvml.GpuTopologyLevel, nvml.Return) {
+		return 0, nvml.ERROR_NOT_SUPPORTED
+	}
+	link, err = GetTopologyLink(dev1, dev2)
+	require.NoError(t, err)
+	assert.Empty(t, link)
+
+	dev1.GetTopologyCommonAncestorFunc = func(d nvml.Device) (nvml.GpuTopologyLevel, nvml.Return) {
+		return 0, nvml.ERROR_UNKNOWN
+	}
+	_, err = GetTopologyLink(dev1, dev2)
+	require.Error(t, err)
+}
+
+func TestGetCPUAffinity(t *testing.T) {
+	dev := testutil.NewMockDevice(&mock.Device{
+		GetCpuAffinityFunc: func(n int) ([]uint, nvml.Return) {
+			// CPUs 0-3 and 64-65
+			return []uint{0xF, 0x3}, nvml.SUCCESS
+		},
+	}, "hopper", "Nvidia", "9.0", "0000:18:00.0")
+
+	cpus, err := GetCPUAffinity(dev, 128)
+	require.NoError(t, err)
+	assert.Equal(t, []int{0, 1, 2, 3, 64, 65}, cpus)
+
+	// the CPUs beyond the number of CPUs are ignored
+	cpus, err = GetCPUAffinity(dev, 3)
+	require.NoError(t, err)
+	assert.Equal(t, []int{0, 1, 2}, cpus)
+
+	dev.GetCpuAffinityFunc = func(n int) ([]uint, nvml.Return) {
+		return nil, nvml.ERROR_NOT_SUPPORTED
+	}
+	cpus, err = GetCPUAffinity(dev, 128)
+	require.NoError(t, err)
+	assert.Nil(t, cpus)
+
+	dev.GetCpuAffinityFunc = func(n int) ([]uint, nvml.Return) {
+		return nil, nvml.ERROR_UNKNOWN
+	}
+	_, err = GetCPUAffinity(dev, 128)
+	require.Error(t, err)
+}
+
+func TestGetNVLinkPeers(t *testing.T) {
+	// links 0-1 to the GPU, link 2 disabled, links 3-4 to the NVSwitch
+	dev := testutil.NewMockDevice(&mock.Device{
+		GetNvLinkStateFunc: func(link int) (nvml.EnableState, nvml.Return) {
+			switch {
+			case link == 2:
+				return nvml.FEATURE_DISABLED, nvml.SUCCESS
+			case link < 5:
+				return nvml.FEATURE_ENABLED, nvml.SUCCESS
+			default:
+				return 0, nvml.ERROR_INVALID_ARGUMENT
+			}
+		},
+		GetNvLinkRemoteDeviceTypeFunc: func(link int) (nvml.IntNvLinkDeviceType, nvml.Return) {
+			if link >= 3 {
+				return nvml.NVLINK_DEVICE_TYPE_SWITCH, nvml.SUCCESS
+			}
+			return nvml.NVLINK_DEVICE_TYPE_GPU, nvml.SUCCESS
+		},
+		GetNvLinkRemotePciInfoFunc: func(link int) (nvml.PciInfo, nvml.Return) {
+			return nvml.PciInfo{BusId: toTestBusID("00000000:2A:00.0")}, nvml.SUCCESS
+		},
+	}, "hopper", "Nvidia", "9.0", "0000:18:00.0")
+
+	peers, err := GetNVLinkPeers(dev)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"0000:2a:00.0": 2}, peers.GPULinks)
+	assert.Equal(t, 2, peers.SwitchLinks)
+
+	dev.GetNvLinkStateFunc = func(link int) (nvml.EnableState, nvml.Return) {
+		return 0, nvml.ERROR_NOT_SUPPORTED
+	}
+	peers, err = GetNVLinkPeers(dev)
+	require.NoError(t, err)
+	assert.Equal(t, NVLinkPeers{}, peers)
+}
+
+func TestPCIBusIDToString(t *testing.T) {
+	assert.Equal(t, "0000:2a:00.0", PCIBusIDToString(toTestBusID("00000000:2A:00.0")))
+	assert.Equal(t, "0000:2a:00.0", PCIBusIDToString(toTestBusID("0000:2a:00.0")))
+	assert.Equal(t, "0001:2a:00.0", PCIBusIDToString(toTestBusID("00000001:2A:00.0")))
+	assert.Empty(t, PCIBusIDToString([32]int8{}))
+}
@@ -0,0 +1,23 @@
+package nvml
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/NVIDIA/go-nvlib/pkg/nvlib/device"
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+)
+
+// GetVBIOSVersion returns the VBIOS version of the device (e.g., "96.00.89.00.01").
+// Returns an empty string and no error if not supported.
+// ref. https://docs.nvidia.com/deploy/nvml-api/group__nvmlDeviceQueries.html
+func GetVBIOSVersion(dev device.Device) (string, error) {
+	ver, ret := dev.GetVbiosVersion()
+	if IsNotSupportError(ret) {
+		return "", nil
+	}
+	if ret != nvml.SUCCESS {
+		return "", fmt.Errorf("failed to get vbios version: %v", nvml.ErrorString(ret))
+	}
+	return strings.TrimSpace(ver), nil
+}
@@ -0,0 +1,37 @@
+package nvml
+
+import (
+	"testing"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+	"github.com/NVIDIA/go-nvml/pkg/nvml/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/leptonai/gpud/pkg/nvidia-query/nvml/testutil"
+)
+
+func TestGetVBIOSVersion(t *testing.T) {
+	dev := testutil.NewMockDevice(&mock.Device{
+		GetVbiosVersionFunc: func() (string, nvml.Return) {
+			return "96.00.89.00.01 ", nvml.SUCCESS
+		},
+	}, "hopper", "Nvidia", "9.0", "0000:18:00.0")
+
+	ver, err := GetVBIOSVersion(dev)
+	require.NoError(t, err)
+	assert.Equal(t, "96.00.89.00.01", ver)
+
+	dev.GetVbiosVersionFunc = func() (string, nvml.Return) {
+		return "", nvml.ERROR_NOT_SUPPORTED
+	}
+	ver, err = GetVBIOSVersion(dev)
+	require.NoError(t, err)
+	assert.Empty(t, ver)
+
+	dev.GetVbiosVersionFunc = func() (string, nvml.Return) {
+		return "", nvml.ERROR_UNKNOWN
+	}
+	_, err = GetVBIOSVersion(dev)
+	require.Error(t, err)
+}
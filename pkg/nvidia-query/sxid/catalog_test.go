@@ -0,0 +1,69 @@
+package sxid
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	apiv1 "github.com/leptonai/gpud/api/v1"
+)
+
+func TestLoadCatalog(t *testing.T) {
+	builtin := details[11004]
+
+	ds, err := LoadCatalog("testdata/catalog.yaml")
+	require.NoError(t, err)
+	require.Len(t, ds, 2)
+
+	// only the fields in the file are overridden
+	assert.Equal(t, 11004, ds[0].SXid)
+	assert.Equal(t, builtin.Name, ds[0].Name)
+	assert.Equal(t, builtin.SuggestedActionsByGPUd, ds[0].SuggestedActionsByGPUd)
+	assert.Equal(t, apiv1.EventTypeCritical, ds[0].EventType)
+
+	assert.Equal(t, 99999, ds[1].SXid)
+	assert.Equal(t, "Vendor specific NVSwitch error", ds[1].Name)
+
+	SetCatalog(ds)
+	defer SetCatalog(nil)
+
+	d, ok := GetDetail(11004)
+	require.True(t, ok)
+	assert.Equal(t, apiv1.EventTypeCritical, d.EventType)
+	_, ok = GetDetail(99999)
+	assert.True(t, ok)
+
+	SetCatalog(nil)
+	_, ok = GetDetail(99999)
+	assert.False(t, ok)
+
+	_, err = detailsCatalog.Parse([]byte("- sxid: 11004\n  event_type: Bad"))
+	assert.Error(t, err)
+	_, err = detailsCatalog.Parse([]byte("- sxid: 11004\n- sxid: 11004"))
+	assert.Error(t, err)
+}
+
+func TestValidateCatalogBuiltin(t *testing.T) {
+	ds := make([]Detail, 0, len(details))
+	for _, d := range details {
+		ds = append(ds, d)
+	}
+	assert.NoError(t, ValidateCatalog(ds))
+}
+
+func TestCatalogUnmarshalJSON(t *testing.T) {
+	builtin := details[11004]
+
+	var c Catalog
+	require.NoError(t, json.Unmarshal([]byte(`[{"sxid": 11004, "event_type": "Critical"}]`), &c))
+	require.Len(t, c, 1)
+
+	// merged over the built-in detail, same as the catalog file
+	assert.Equal(t, builtin.Name, c[0].Name)
+	assert.Equal(t, builtin.SuggestedActionsByGPUd, c[0].SuggestedActionsByGPUd)
+	assert.Equal(t, apiv1.EventTypeCritical, c[0].EventType)
+
+	assert.Error(t, json.Unmarshal([]byte(`[{"sxid": 99999, "event_type": "Warning"}]`), &c))
+}
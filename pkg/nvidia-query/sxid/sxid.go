@@ -38,7 +38,12 @@ func (d Detail) JSON() ([]byte, error) {
 
 // Returns the error if found.
 // Otherwise, returns false.
+// The details in the external catalog (see "SetCatalog")
+// take precedence over the built-in details.
 func GetDetail(id int) (*Detail, bool) {
+	if e, ok := getCatalogDetail(id); ok {
+		return &e, true
+	}
 	e, ok := details[id]
 	return &e, ok
 }
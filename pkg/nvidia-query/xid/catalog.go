@@ -0,0 +1,56 @@
+package xid
+
+import (
+	"github.com/leptonai/gpud/pkg/nvidia-query/catalog"
+)
+
+// detailsCatalog is the Xid details loaded from the external catalog file,
+// which take precedence over the built-in details.
+var detailsCatalog = catalog.New("xid", details, func(d Detail) int { return d.Xid }, Detail.Validate)
+
+// Catalog is the Xid details that override or extend the built-in details
+// (e.g., "xid_catalog" in the config).
+// Each entry decoded from the JSON (or YAML) is applied on top of the built-in detail
+// of the same Xid, same as "LoadCatalog", so only the fields to override need to be set.
+type Catalog []Detail
+
+// UnmarshalJSON decodes and validates the Xid details merged over the built-in details.
+func (c *Catalog) UnmarshalJSON(b []byte) error {
+	ds, err := detailsCatalog.Parse(b)
+	if err != nil {
+		return err
+	}
+	*c = ds
+	return nil
+}
+
+// SetCatalog sets the Xid details that override or extend the built-in details,
+// replacing the previously set ones (e.g., loaded with "LoadCatalog").
+// Set nil to only use the built-in details.
+func SetCatalog(ds []Detail) {
+	detailsCatalog.Set(ds)
+}
+
+// getCatalogDetail returns the Xid detail from the external catalog, if any.
+func getCatalogDetail(id int) (Detail, bool) {
+	return detailsCatalog.Get(id)
+}
+
+// Validate validates the Xid detail.
+func (d Detail) Validate() error {
+	return catalog.ValidateDetail("xid", d.Xid, d.Name, d.EventType, d.SuggestedActionsByGPUd, d.CriticalErrorMarkedByGPUd)
+}
+
+// ValidateCatalog validates the Xid details, and the Xids must be unique.
+func ValidateCatalog(ds []Detail) error {
+	return detailsCatalog.Validate(ds)
+}
+
+// LoadCatalog loads the Xid details from the YAML or JSON file,
+// which contains a list of Xid details in the same schema as "Detail".
+// Only the fields in the file override the built-in detail of the same Xid (e.g., "event_type"),
+// and the Xids not in the built-in details are added as new entries.
+// The returned details are validated, and can be set with "SetCatalog".
+func LoadCatalog(file string) ([]Detail, error) {
+	return detailsCatalog.Load(file)
+}
@@ -0,0 +1,108 @@
+package xid
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	apiv1 "github.com/leptonai/gpud/api/v1"
+)
+
+func TestLoadCatalog(t *testing.T) {
+	builtin := details[13]
+
+	ds, err := LoadCatalog("testdata/catalog.yaml")
+	require.NoError(t, err)
+	require.Len(t, ds, 2)
+
+	// only the fields in the file are overridden
+	assert.Equal(t, 13, ds[0].Xid)
+	assert.Equal(t, builtin.Name, ds[0].Name)
+	assert.Equal(t, builtin.Description, ds[0].Description)
+	assert.Equal(t, builtin.PotentialHWError, ds[0].PotentialHWError)
+	assert.Equal(t, apiv1.EventTypeFatal, ds[0].EventType)
+	assert.True(t, ds[0].CriticalErrorMarkedByGPUd)
+	assert.Equal(t, []apiv1.RepairActionType{apiv1.RepairActionTypeRebootSystem}, ds[0].SuggestedActionsByGPUd.RepairActions)
+
+	assert.Equal(t, 999, ds[1].Xid)
+	assert.Equal(t, "Vendor specific error", ds[1].Name)
+	assert.Equal(t, apiv1.EventTypeCritical, ds[1].EventType)
+
+	// the built-in detail is not modified
+	assert.Equal(t, builtin, details[13])
+
+	SetCatalog(ds)
+	defer SetCatalog(nil)
+
+	d, ok := GetDetail(13)
+	require.True(t, ok)
+	assert.Equal(t, apiv1.EventTypeFatal, d.EventType)
+	d, ok = GetDetail(999)
+	require.True(t, ok)
+	assert.Equal(t, "Vendor specific error", d.Name)
+
+	SetCatalog(nil)
+	d, ok = GetDetail(13)
+	require.True(t, ok)
+	assert.Equal(t, builtin.EventType, d.EventType)
+	_, ok = GetDetail(999)
+	assert.False(t, ok)
+
+	_, err = LoadCatalog("testdata/does-not-exist.yaml")
+	assert.Error(t, err)
+}
+
+func TestCatalogUnmarshalJSON(t *testing.T) {
+	builtin := details[13]
+
+	var c Catalog
+	require.NoError(t, json.Unmarshal([]byte(`[{"xid": 13, "event_type": "Fatal"}]`), &c))
+	require.Len(t, c, 1)
+
+	// merged over the built-in detail, same as the catalog file
+	assert.Equal(t, builtin.Name, c[0].Name)
+	assert.Equal(t, builtin.SuggestedActionsByGPUd, c[0].SuggestedActionsByGPUd)
+	assert.Equal(t, apiv1.EventTypeFatal, c[0].EventType)
+
+	// round trip
+	b, err := json.Marshal(c)
+	require.NoError(t, err)
+	var c2 Catalog
+	require.NoError(t, json.Unmarshal(b, &c2))
+	assert.Equal(t, c, c2)
+
+	assert.Error(t, json.Unmarshal([]byte(`[{"xid": 13, "event_typ": "Fatal"}]`), &c))
+	assert.Error(t, json.Unmarshal([]byte(`[{"xid": 999, "event_type": "Fatal"}]`), &c))
+}
+
+func TestParseCatalogInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{name: "not a list", input: "xid: 13"},
+		{name: "missing xid", input: "- name: foo"},
+		{name: "invalid xid", input: "- xid: foo"},
+		{name: "new xid without name", input: "- xid: 999\n  event_type: Warning"},
+		{name: "unknown event type", input: "- xid: 13\n  event_type: Bad"},
+		{name: "unknown repair action", input: "- xid: 13\n  suggested_actions_by_gpud:\n    repair_actions: [BAD]"},
+		{name: "critical without repair action", input: "- xid: 13\n  critical_error_marked_by_gpud: true\n  suggested_actions_by_gpud:\n    repair_actions: []"},
+		{name: "duplicate xid", input: "- xid: 13\n- xid: 13"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := detailsCatalog.Parse([]byte(tt.input))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestValidateCatalogBuiltin(t *testing.T) {
+	ds := make([]Detail, 0, len(details))
+	for _, d := range details {
+		ds = append(ds, d)
+	}
+	assert.NoError(t, ValidateCatalog(ds))
+}
@@ -0,0 +1,44 @@
+package pci
+
+import (
+	pkghost "github.com/leptonai/gpud/pkg/host"
+)
+
+// ACSCheckSkipReason returns the reason to skip the ACS check in the virtualization environment,
+// or an empty string if the ACS must be disabled on the PCI devices (e.g., bare metal).
+//
+// Baremetal systems
+// IO virtualization (also known as VT-d or IOMMU) can interfere with GPU Direct by redirecting all
+// PCI point-to-point traffic to the CPU root complex, causing a significant performance reduction or even a hang.
+// If PCI switches have ACS enabled, it needs to be disabled.
+//
+// Virtual machines
+// Virtual machines require ACS to function, hence disabling ACS is not an option.
+//
+// ref. https://docs.nvidia.com/deeplearning/nccl/user-guide/docs/troubleshooting.html#pci-access-control-services-acs
+func ACSCheckSkipReason(virtEnv pkghost.VirtualizationEnvironment) string {
+	if virtEnv.IsKVM {
+		return "host virt env is KVM (no need to check ACS)"
+	}
+	if virtEnv.Type == "" {
+		return "unknown virtualization environment (no need to check ACS)"
+	}
+	return ""
+}
+
+// FindACSEnabledDeviceUUIDs returns the IDs of the PCI devices (bridges) with the ACS enabled,
+// or nil if none.
+func FindACSEnabledDeviceUUIDs(devs []Device) []string {
+	uuids := make([]string, 0)
+	for _, dev := range devs {
+		// check whether ACS is enabled on PCI bridges
+		if dev.AccessControlService != nil && dev.AccessControlService.ACSCtl.SrcValid {
+			uuids = append(uuids, dev.ID)
+		}
+	}
+	if len(uuids) == 0 {
+		return nil
+	}
+
+	return uuids
+}
@@ -0,0 +1,24 @@
+package pci
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	pkghost "github.com/leptonai/gpud/pkg/host"
+)
+
+func TestACSCheckSkipReason(t *testing.T) {
+	assert.Equal(t, "host virt env is KVM (no need to check ACS)", ACSCheckSkipReason(pkghost.VirtualizationEnvironment{Type: "kvm", IsKVM: true}))
+	assert.Equal(t, "unknown virtualization environment (no need to check ACS)", ACSCheckSkipReason(pkghost.VirtualizationEnvironment{}))
+	assert.Empty(t, ACSCheckSkipReason(pkghost.VirtualizationEnvironment{Type: "none"}))
+}
+
+func TestFindACSEnabledDeviceUUIDs(t *testing.T) {
+	assert.Nil(t, FindACSEnabledDeviceUUIDs(nil))
+	assert.Nil(t, FindACSEnabledDeviceUUIDs([]Device{{ID: "0000:18:00.0"}, {ID: "0000:17:01.0", AccessControlService: &AccessControlService{}}}))
+	assert.Equal(t, []string{"0000:17:01.0"}, FindACSEnabledDeviceUUIDs([]Device{
+		{ID: "0000:17:01.0", AccessControlService: &AccessControlService{ACSCtl: ACS{SrcValid: true}}},
+		{ID: "0000:18:00.0"},
+	}))
+}
@@ -57,6 +57,64 @@ func List(ctx context.Context) (Devices, error) {
 	return devs, nil
 }
 
+// ListNVIDIAGPUs lists the NVIDIA GPUs (3D or VGA controllers) from "lspci",
+// excluding the other NVIDIA devices (e.g., NVSwitch bridges, audio devices).
+// Unlike "nvidia-smi" or NVML, the GPUs are listed as long as they are on the PCI bus,
+// even if the driver fails to initialize them.
+// Returns nil and no error, if "lspci" is not found.
+func ListNVIDIAGPUs(ctx context.Context) (Devices, error) {
+	lspciPath, err := file.LocateExecutable("lspci")
+	if err != nil {
+		return nil, nil
+	}
+
+	// "10de" is the NVIDIA PCI vendor ID
+	p, err := process.New(
+		process.WithCommand(lspciPath, "-d", nvidiaVendorID+":"),
+		process.WithRunAsBashScript(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.Start(ctx); err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := p.Close(ctx); err != nil {
+			log.Logger.Warnw("failed to abort command", "err", err)
+		}
+	}()
+
+	scanner := bufio.NewScanner(p.StdoutReader())
+	devs, err := parseLspciVVV(ctx, scanner, IsNVIDIAGPU)
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case err := <-p.Wait():
+		if err != nil {
+			return nil, err
+		}
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	return devs, nil
+}
+
+const nvidiaVendorID = "10de"
+
+// IsNVIDIAGPU returns true if the PCI device name is an NVIDIA GPU.
+// e.g., "3D controller: NVIDIA Corporation Device 2330 (rev a1)"
+func IsNVIDIAGPU(name string) bool {
+	if !strings.Contains(name, "NVIDIA") {
+		return false
+	}
+	return strings.HasPrefix(name, "3D controller") || strings.HasPrefix(name, "VGA compatible controller")
+}
+
 type Devices []Device
 
 func (devs Devices) JSON() ([]byte, error) {
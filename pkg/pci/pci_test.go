@@ -59,6 +59,48 @@ func TestParseAccessControlServices(t *testing.T) {
 	t.Logf("yaml: %s", string(yb))
 }
 
+func TestParseNVIDIAGPUs(t *testing.T) {
+	b, err := os.ReadFile("testdata/lspci-vvv")
+	if err != nil {
+		t.Fatalf("failed to read testdata: %v", err)
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	devs, err := parseLspciVVV(ctx, scanner, IsNVIDIAGPU)
+	if err != nil {
+		t.Fatalf("failed to parse nvidia gpus: %v", err)
+	}
+
+	// 8 GPUs, excluding the 4 NVSwitch bridges
+	if len(devs) != 8 {
+		t.Fatalf("expected 8 NVIDIA GPUs, got %d", len(devs))
+	}
+	if devs[0].ID != "19:00.0" {
+		t.Fatalf("expected first GPU 19:00.0, got %q", devs[0].ID)
+	}
+}
+
+func TestIsNVIDIAGPU(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{name: "3D controller: NVIDIA Corporation Device 2330 (rev a1)", want: true},
+		{name: "VGA compatible controller: NVIDIA Corporation Device 2684 (rev a1)", want: true},
+		{name: "Bridge: NVIDIA Corporation Device 22a3 (rev a1)", want: false},
+		{name: "Audio device: NVIDIA Corporation Device 22ba (rev a1)", want: false},
+		{name: "VGA compatible controller: ASPEED Technology, Inc. ASPEED Graphics Family (rev 52)", want: false},
+	}
+	for _, tt := range tests {
+		if got := IsNVIDIAGPU(tt.name); got != tt.want {
+			t.Errorf("IsNVIDIAGPU(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
 func TestParseAccessControlServicesNoFilter(t *testing.T) {
 	b, err := os.ReadFile("testdata/lspci-vvv")
 	if err != nil {
@@ -0,0 +1,157 @@
+package pci
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultSysfsRoot is the default root directory of the sysfs.
+const DefaultSysfsRoot = "/sys"
+
+// SysfsDevice is the PCI device information from the sysfs.
+type SysfsDevice struct {
+	// BusID is the PCI bus ID (e.g., "0000:18:00.0").
+	BusID string `json:"bus_id"`
+	// Path is the resolved sysfs path of the device, which is the PCI hierarchy
+	// from the root complex to the device
+	// (e.g., "/sys/devices/pci0000:17/0000:17:01.0/0000:18:00.0").
+	Path string `json:"path"`
+	// NUMANode is the NUMA node of the device,
+	// -1 if the platform does not report the NUMA node (e.g., single socket).
+	NUMANode int `json:"numa_node"`
+	// LocalCPUList is the list of the CPUs local to the device (e.g., "0-47"),
+	// empty if not reported.
+	LocalCPUList string `json:"local_cpulist,omitempty"`
+}
+
+// Ancestors returns the PCI devices (bridges) between the root complex and the device,
+// excluding the device itself (e.g., ["0000:17:01.0"]).
+func (d SysfsDevice) Ancestors() []string {
+	var ancestors []string
+	for _, elem := range strings.Split(filepath.ToSlash(d.Path), "/") {
+		if isPCIBusID(elem) && elem != d.BusID {
+			ancestors = append(ancestors, elem)
+		}
+	}
+	return ancestors
+}
+
+// RootComplex returns the PCI root complex of the device (e.g., "pci0000:17").
+// Returns an empty string if not found.
+func (d SysfsDevice) RootComplex() string {
+	for _, elem := range strings.Split(filepath.ToSlash(d.Path), "/") {
+		if strings.HasPrefix(elem, "pci") {
+			return elem
+		}
+	}
+	return ""
+}
+
+// NormalizeBusID converts the PCI bus ID to the lower-case sysfs format
+// with the 4-digit domain (e.g., "00000000:2A:00.0" to "0000:2a:00.0").
+func NormalizeBusID(busID string) string {
+	busID = strings.ToLower(busID)
+	if parts := strings.SplitN(busID, ":", 2); len(parts) == 2 && len(parts[0]) == 8 {
+		busID = parts[0][4:] + ":" + parts[1]
+	}
+	return busID
+}
+
+// ReadSysfsDevice reads the PCI device of the bus ID
+// from "<sysfsRoot>/bus/pci/devices/<bus ID>".
+func ReadSysfsDevice(sysfsRoot string, busID string) (SysfsDevice, error) {
+	busID = NormalizeBusID(busID)
+	dir := filepath.Join(sysfsRoot, "bus", "pci", "devices", busID)
+
+	path, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return SysfsDevice{}, fmt.Errorf("failed to resolve pci device %q: %w", busID, err)
+	}
+
+	dev := SysfsDevice{
+		BusID:    busID,
+		Path:     path,
+		NUMANode: -1,
+	}
+
+	b, err := os.ReadFile(filepath.Join(path, "local_cpulist"))
+	if err != nil && !os.IsNotExist(err) {
+		return dev, fmt.Errorf("failed to read local cpus of pci device %q: %w", busID, err)
+	}
+	dev.LocalCPUList = strings.TrimSpace(string(b))
+
+	b, err = os.ReadFile(filepath.Join(path, "numa_node"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return dev, nil
+		}
+		return dev, fmt.Errorf("failed to read numa node of pci device %q: %w", busID, err)
+	}
+	node, err := strconv.Atoi(strings.TrimSpace(string(b)))
+	if err != nil {
+		return dev, fmt.Errorf("failed to parse numa node of pci device %q: %w", busID, err)
+	}
+	dev.NUMANode = node
+
+	return dev, nil
+}
+
+// ClassDevice is a device of a sysfs class (e.g., "mlx5_0" of the "infiniband" class)
+// and its PCI bus ID.
+type ClassDevice struct {
+	// Name is the device name (e.g., "mlx5_0").
+	Name string `json:"name"`
+	// BusID is the PCI bus ID of the device (e.g., "0000:1a:00.0").
+	BusID string `json:"bus_id"`
+}
+
+// ListClassDevices lists the devices of the sysfs class (e.g., "infiniband")
+// from "<sysfsRoot>/class/<class>", sorted by the PCI bus ID and the name.
+// The devices that are not backed by a PCI device are skipped.
+// Returns nil and no error if the class does not exist.
+func ListClassDevices(sysfsRoot string, class string) ([]ClassDevice, error) {
+	dir := filepath.Join(sysfsRoot, "class", class)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var devs []ClassDevice
+	for _, entry := range entries {
+		path, err := filepath.EvalSymlinks(filepath.Join(dir, entry.Name(), "device"))
+		if err != nil {
+			continue
+		}
+		busID := filepath.Base(path)
+		if !isPCIBusID(busID) {
+			continue
+		}
+		devs = append(devs, ClassDevice{Name: entry.Name(), BusID: busID})
+	}
+
+	sort.Slice(devs, func(i, j int) bool {
+		if devs[i].BusID != devs[j].BusID {
+			return devs[i].BusID < devs[j].BusID
+		}
+		return devs[i].Name < devs[j].Name
+	})
+	return devs, nil
+}
+
+// isPCIBusID returns true if the string is a PCI bus ID
+// in the sysfs format (e.g., "0000:18:00.0").
+func isPCIBusID(s string) bool {
+	parts := strings.Split(s, ":")
+	if len(parts) != 3 || len(parts[0]) != 4 || len(parts[1]) != 2 {
+		return false
+	}
+	devFn := strings.Split(parts[2], ".")
+	return len(devFn) == 2 && len(devFn[0]) == 2 && len(devFn[1]) == 1
+}
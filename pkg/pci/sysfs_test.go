@@ -0,0 +1,134 @@
+package pci
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// createTestSysfsDevice creates the PCI device under the hierarchy
+// (e.g., "pci0000:17/0000:17:01.0/0000:18:00.0") in the fake sysfs,
+// and links it from "bus/pci/devices".
+func createTestSysfsDevice(t *testing.T, root string, hierarchy string, numaNode string) string {
+	t.Helper()
+
+	path := filepath.Join(root, "devices", hierarchy)
+	if err := os.MkdirAll(path, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if numaNode != "" {
+		if err := os.WriteFile(filepath.Join(path, "numa_node"), []byte(numaNode+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	busDir := filepath.Join(root, "bus", "pci", "devices")
+	if err := os.MkdirAll(busDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(path, filepath.Join(busDir, filepath.Base(path))); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReadSysfsDevice(t *testing.T) {
+	root := t.TempDir()
+	gpu := createTestSysfsDevice(t, root, "pci0000:17/0000:17:01.0/0000:18:00.0", "0")
+	if err := os.WriteFile(filepath.Join(gpu, "local_cpulist"), []byte("0-47\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	createTestSysfsDevice(t, root, "pci0000:97/0000:98:00.0", "")
+
+	dev, err := ReadSysfsDevice(root, "00000000:18:00.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dev.BusID != "0000:18:00.0" {
+		t.Errorf("unexpected bus id %q", dev.BusID)
+	}
+	if dev.NUMANode != 0 {
+		t.Errorf("expected numa node 0, got %d", dev.NUMANode)
+	}
+	if dev.LocalCPUList != "0-47" {
+		t.Errorf("unexpected local cpus %q", dev.LocalCPUList)
+	}
+	if dev.RootComplex() != "pci0000:17" {
+		t.Errorf("unexpected root complex %q", dev.RootComplex())
+	}
+	if !reflect.DeepEqual(dev.Ancestors(), []string{"0000:17:01.0"}) {
+		t.Errorf("unexpected ancestors %v", dev.Ancestors())
+	}
+
+	// no numa node reported
+	dev, err = ReadSysfsDevice(root, "0000:98:00.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dev.NUMANode != -1 {
+		t.Errorf("expected numa node -1, got %d", dev.NUMANode)
+	}
+	if dev.LocalCPUList != "" {
+		t.Errorf("expected no local cpus, got %q", dev.LocalCPUList)
+	}
+	if len(dev.Ancestors()) != 0 {
+		t.Errorf("expected no ancestors, got %v", dev.Ancestors())
+	}
+
+	if _, err := ReadSysfsDevice(root, "0000:ff:00.0"); err == nil {
+		t.Error("expected error for the missing device")
+	}
+}
+
+func TestListClassDevices(t *testing.T) {
+	root := t.TempDir()
+	nic1 := createTestSysfsDevice(t, root, "pci0000:97/0000:98:00.0", "1")
+	nic0 := createTestSysfsDevice(t, root, "pci0000:17/0000:17:01.0/0000:1a:00.0", "0")
+
+	classDir := filepath.Join(root, "class", "infiniband")
+	for name, path := range map[string]string{"mlx5_0": nic0, "mlx5_1": nic1} {
+		if err := os.MkdirAll(filepath.Join(classDir, name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Symlink(path, filepath.Join(classDir, name, "device")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// not backed by a PCI device
+	if err := os.MkdirAll(filepath.Join(classDir, "rxe0"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	devs, err := ListClassDevices(root, "infiniband")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []ClassDevice{
+		{Name: "mlx5_0", BusID: "0000:1a:00.0"},
+		{Name: "mlx5_1", BusID: "0000:98:00.0"},
+	}
+	if !reflect.DeepEqual(devs, expected) {
+		t.Errorf("expected %+v, got %+v", expected, devs)
+	}
+
+	devs, err = ListClassDevices(root, "net")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if devs != nil {
+		t.Errorf("expected nil for the missing class, got %+v", devs)
+	}
+}
+
+func TestNormalizeBusID(t *testing.T) {
+	for input, expected := range map[string]string{
+		"00000000:2A:00.0": "0000:2a:00.0",
+		"0000:2a:00.0":     "0000:2a:00.0",
+		"00000001:2A:00.0": "0001:2a:00.0",
+	} {
+		if got := NormalizeBusID(input); got != expected {
+			t.Errorf("NormalizeBusID(%q) = %q, want %q", input, got, expected)
+		}
+	}
+}
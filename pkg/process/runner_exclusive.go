@@ -2,8 +2,10 @@ package process
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"sync"
 
@@ -27,6 +29,8 @@ var defaultScriptsDir = filepath.Join(os.TempDir(), "gpud-scripts-runner")
 
 // RunUntilCompletion starts a bash script, blocks until it finishes,
 // and returns the output and the exit code.
+// If the script exits with non-zero status, the output is returned
+// along with the "*exec.ExitError".
 // If there is already a process running, it returns an error.
 func (er *exclusiveRunner) RunUntilCompletion(ctx context.Context, script string) ([]byte, int32, error) {
 	if er.alreadyRunning() {
@@ -76,9 +80,19 @@ func (er *exclusiveRunner) RunUntilCompletion(ctx context.Context, script string
 		return nil, p.ExitCode(), ctx.Err()
 
 	case err := <-p.Wait():
-		if err != nil {
+		var exitErr *exec.ExitError
+		if err != nil && !errors.As(err, &exitErr) {
 			return nil, p.ExitCode(), err
 		}
+		if err != nil {
+			// still returns the output of the process exited with non-zero status,
+			// since some commands report the results with the exit code (e.g., failed tests)
+			output, rerr := os.ReadFile(tmpFile.Name())
+			if rerr != nil {
+				return nil, p.ExitCode(), err
+			}
+			return output, p.ExitCode(), err
+		}
 		log.Logger.Infow("process exited", "pid", p.PID(), "exitCode", p.ExitCode())
 	}
 
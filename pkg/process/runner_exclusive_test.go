@@ -3,6 +3,7 @@ package process
 import (
 	"context"
 	"errors"
+	"os/exec"
 	"testing"
 	"time"
 
@@ -37,8 +38,15 @@ func TestExclusiveRunnerAbortByProcessExit(t *testing.T) {
 
 	out, exitCode, err := runner.RunUntilCompletion(ctx, "exit 1")
 	assert.Error(t, err)
-	assert.Nil(t, out)
+	assert.Empty(t, out)
 	assert.Equal(t, int32(1), exitCode)
+
+	// the output is returned with the non-zero exit
+	out, exitCode, err = runner.RunUntilCompletion(ctx, "echo failed; exit 2")
+	var exitErr *exec.ExitError
+	assert.ErrorAs(t, err, &exitErr)
+	assert.Equal(t, "failed\n", string(out))
+	assert.Equal(t, int32(2), exitCode)
 }
 
 func TestExclusiveRunnerAbortByContextCancellation(t *testing.T) {
@@ -0,0 +1,233 @@
+package scan
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+
+	apiv1 "github.com/leptonai/gpud/api/v1"
+	componentsacceleratornvidiagpucounts "github.com/leptonai/gpud/components/accelerator/nvidia/gpu-counts"
+	componentsacceleratornvidiainfiniband "github.com/leptonai/gpud/components/accelerator/nvidia/infiniband"
+	componentsacceleratornvidianccl "github.com/leptonai/gpud/components/accelerator/nvidia/nccl"
+	componentsacceleratornvidiapeermem "github.com/leptonai/gpud/components/accelerator/nvidia/peermem"
+	componentsacceleratornvidiasxid "github.com/leptonai/gpud/components/accelerator/nvidia/sxid"
+	componentsacceleratornvidiaxid "github.com/leptonai/gpud/components/accelerator/nvidia/xid"
+	componentscpu "github.com/leptonai/gpud/components/cpu"
+	componentsfd "github.com/leptonai/gpud/components/fd"
+	componentsmemory "github.com/leptonai/gpud/components/memory"
+	"github.com/leptonai/gpud/pkg/kmsg"
+)
+
+// TimelineEvent is a kernel message matched by the kmsg matchers
+// in the offline analysis.
+type TimelineEvent struct {
+	Time             time.Time               `json:"time"`
+	Component        string                  `json:"component"`
+	Name             string                  `json:"name"`
+	Type             apiv1.EventType         `json:"type"`
+	Message          string                  `json:"message"`
+	SuggestedActions *apiv1.SuggestedActions `json:"suggested_actions,omitempty"`
+	LogLine          string                  `json:"log_line"`
+}
+
+type kmsgMatcher struct {
+	component string
+	match     func(line string) *TimelineEvent
+}
+
+// fromMatchEventFunc converts the component matcher, carrying over
+// the event type and the suggested actions of each matched event.
+func fromMatchEventFunc(component string, matchEventFunc func(line string) *kmsg.MatchedEvent) kmsgMatcher {
+	return kmsgMatcher{
+		component: component,
+		match: func(line string) *TimelineEvent {
+			ev := matchEventFunc(line)
+			if ev == nil {
+				return nil
+			}
+			return &TimelineEvent{
+				Name:             ev.Name,
+				Type:             ev.Type,
+				Message:          ev.Message,
+				SuggestedActions: ev.SuggestedActions,
+			}
+		},
+	}
+}
+
+// kmsgMatchers are all the kmsg matchers used by the components.
+var kmsgMatchers = []kmsgMatcher{
+	{
+		component: componentsacceleratornvidiaxid.Name,
+		match: func(line string) *TimelineEvent {
+			xidErr := componentsacceleratornvidiaxid.Match(line)
+			if xidErr == nil {
+				return nil
+			}
+			ev := &TimelineEvent{
+				Name:    componentsacceleratornvidiaxid.EventNameErrorXid,
+				Type:    apiv1.EventTypeWarning,
+				Message: fmt.Sprintf("XID %d detected on %s", xidErr.Xid, xidErr.DeviceUUID),
+			}
+			if xidErr.Detail != nil {
+				ev.Message = fmt.Sprintf("XID %d(%s) detected on %s", xidErr.Xid, xidErr.Detail.Name, xidErr.DeviceUUID)
+				ev.Type = xidErr.Detail.EventType
+				ev.SuggestedActions = xidErr.Detail.SuggestedActionsByGPUd
+			}
+			return ev
+		},
+	},
+	{
+		component: componentsacceleratornvidiasxid.Name,
+		match: func(line string) *TimelineEvent {
+			sxidErr := componentsacceleratornvidiasxid.Match(line)
+			if sxidErr == nil {
+				return nil
+			}
+			ev := &TimelineEvent{
+				Name:    componentsacceleratornvidiasxid.EventNameErrorSXid,
+				Type:    apiv1.EventTypeWarning,
+				Message: fmt.Sprintf("SXID %d detected on %s", sxidErr.SXid, sxidErr.DeviceUUID),
+			}
+			if sxidErr.Detail != nil {
+				ev.Message = fmt.Sprintf("SXID %d(%s) detected on %s", sxidErr.SXid, sxidErr.Detail.Name, sxidErr.DeviceUUID)
+				ev.Type = sxidErr.Detail.EventType
+				ev.SuggestedActions = sxidErr.Detail.SuggestedActionsByGPUd
+			}
+			return ev
+		},
+	},
+	fromMatchEventFunc(componentsacceleratornvidiagpucounts.Name, componentsacceleratornvidiagpucounts.MatchEvent),
+	fromMatchEventFunc(componentsacceleratornvidianccl.Name, componentsacceleratornvidianccl.MatchEvent),
+	fromMatchEventFunc(componentsacceleratornvidiapeermem.Name, componentsacceleratornvidiapeermem.MatchEvent),
+	fromMatchEventFunc(componentsacceleratornvidiainfiniband.Name, componentsacceleratornvidiainfiniband.MatchEvent),
+	fromMatchEventFunc(componentsmemory.Name, componentsmemory.MatchEvent),
+	fromMatchEventFunc(componentsfd.Name, componentsfd.MatchEvent),
+	fromMatchEventFunc(componentscpu.Name, componentscpu.MatchEvent),
+}
+
+// analyzeKmsg runs all the kmsg matchers on the captured kernel messages,
+// and returns the matched events in the time order.
+func analyzeKmsg(msgs []kmsg.Message) []TimelineEvent {
+	var events []TimelineEvent
+	for _, msg := range msgs {
+		for _, m := range kmsgMatchers {
+			ev := m.match(msg.Message)
+			if ev == nil {
+				continue
+			}
+			ev.Time = msg.Timestamp.Time
+			ev.Component = m.component
+			ev.LogLine = msg.Message
+			events = append(events, *ev)
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Time.Before(events[j].Time)
+	})
+	return events
+}
+
+func describeTimelineTime(t time.Time) string {
+	if since, ok := kmsg.SinceDumpBoot(t); ok {
+		return fmt.Sprintf("boot+%.3fs", since.Seconds())
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func describeSuggestedActions(actions *apiv1.SuggestedActions) string {
+	if actions == nil || len(actions.RepairActions) == 0 {
+		return "-"
+	}
+	ss := make([]string, 0, len(actions.RepairActions))
+	for _, a := range actions.RepairActions {
+		ss = append(ss, string(a))
+	}
+	return strings.Join(ss, ", ")
+}
+
+// renderTimeline renders the matched events in a table,
+// followed by the suggested actions summary.
+func renderTimeline(events []TimelineEvent) string {
+	buf := bytes.NewBuffer(nil)
+
+	table := tablewriter.NewWriter(buf)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	table.SetAutoWrapText(false)
+	table.SetHeader([]string{"Time", "Component", "Event", "Type", "Message", "Suggested Actions"})
+	for _, ev := range events {
+		table.Append([]string{
+			describeTimelineTime(ev.Time),
+			ev.Component,
+			ev.Name,
+			string(ev.Type),
+			ev.Message,
+			describeSuggestedActions(ev.SuggestedActions),
+		})
+	}
+	table.Render()
+
+	// summarize the suggested actions across all the events
+	counts := make(map[apiv1.RepairActionType]int)
+	for _, ev := range events {
+		if ev.SuggestedActions == nil {
+			continue
+		}
+		for _, a := range ev.SuggestedActions.RepairActions {
+			counts[a]++
+		}
+	}
+	if len(counts) > 0 {
+		actions := make([]string, 0, len(counts))
+		for a, cnt := range counts {
+			actions = append(actions, string(a)+" ("+strconv.Itoa(cnt)+" event(s))")
+		}
+		sort.Strings(actions)
+		buf.WriteString("\nsuggested actions:\n")
+		for _, a := range actions {
+			buf.WriteString("- " + a + "\n")
+		}
+	}
+
+	return buf.String()
+}
+
+// scanKmsgFiles analyzes the captured kernel logs, rather than the live host.
+func scanKmsgFiles(op *Op) error {
+	var msgs []kmsg.Message
+	if op.kmsgFile != "" {
+		fmt.Printf("\n%s analyzing the kernel log file %q\n", inProgress, op.kmsgFile)
+		ms, err := kmsg.ReadDumpFile(op.kmsgFile)
+		if err != nil {
+			return fmt.Errorf("failed to read kernel log file %q: %w", op.kmsgFile, err)
+		}
+		msgs = append(msgs, ms...)
+	}
+	if op.journalExport != "" {
+		fmt.Printf("\n%s analyzing the journal export %q\n", inProgress, op.journalExport)
+		ms, err := kmsg.ReadJournalExportFile(op.journalExport)
+		if err != nil {
+			return fmt.Errorf("failed to read journal export %q: %w", op.journalExport, err)
+		}
+		msgs = append(msgs, ms...)
+	}
+
+	events := analyzeKmsg(msgs)
+
+	header := checkMark
+	if len(events) > 0 {
+		header = warningSign
+	}
+	fmt.Printf("%s matched %d event(s) from %d kernel message(s)\n", header, len(events), len(msgs))
+	if len(events) > 0 {
+		fmt.Println(renderTimeline(events))
+	}
+
+	fmt.Printf("\n\n%s scan complete\n\n", checkMark)
+	return nil
+}
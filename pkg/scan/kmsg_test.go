@@ -0,0 +1,103 @@
+package scan
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	apiv1 "github.com/leptonai/gpud/api/v1"
+	componentsacceleratornvidiagpucounts "github.com/leptonai/gpud/components/accelerator/nvidia/gpu-counts"
+	componentsacceleratornvidiainfiniband "github.com/leptonai/gpud/components/accelerator/nvidia/infiniband"
+	componentsacceleratornvidianccl "github.com/leptonai/gpud/components/accelerator/nvidia/nccl"
+	componentsacceleratornvidiapeermem "github.com/leptonai/gpud/components/accelerator/nvidia/peermem"
+	componentsacceleratornvidiasxid "github.com/leptonai/gpud/components/accelerator/nvidia/sxid"
+	componentsacceleratornvidiaxid "github.com/leptonai/gpud/components/accelerator/nvidia/xid"
+	componentscpu "github.com/leptonai/gpud/components/cpu"
+	componentsfd "github.com/leptonai/gpud/components/fd"
+	componentsmemory "github.com/leptonai/gpud/components/memory"
+	"github.com/leptonai/gpud/pkg/kmsg"
+)
+
+func TestAnalyzeKmsgFile(t *testing.T) {
+	msgs, err := kmsg.ReadDumpFile("testdata/dmesg.log")
+	require.NoError(t, err)
+	require.Len(t, msgs, 10)
+
+	events := analyzeKmsg(msgs)
+
+	var comps []string
+	for _, ev := range events {
+		comps = append(comps, ev.Component)
+	}
+	assert.Equal(t, []string{
+		componentsfd.Name,
+		componentsmemory.Name,
+		componentscpu.Name,
+		componentsacceleratornvidianccl.Name,
+		componentsacceleratornvidiapeermem.Name,
+		componentsacceleratornvidiainfiniband.Name,
+		componentsacceleratornvidiasxid.Name,
+		componentsacceleratornvidiaxid.Name,
+		componentsacceleratornvidiagpucounts.Name,
+	}, comps)
+
+	// the same line matched by the gpu counts component
+	assert.Equal(t, "NVRM: Xid (PCI:0000:05:00): 79, pid=1234, GPU has fallen off the bus.", events[len(events)-1].LogLine)
+	// the event type and suggested actions of the matcher are kept
+	gpuCountsEv := events[len(events)-1]
+	assert.Equal(t, apiv1.EventTypeFatal, gpuCountsEv.Type)
+	require.NotNil(t, gpuCountsEv.SuggestedActions)
+	assert.Equal(t, []apiv1.RepairActionType{apiv1.RepairActionTypeHardwareInspection}, gpuCountsEv.SuggestedActions.RepairActions)
+	assert.Equal(t, apiv1.EventTypeWarning, events[0].Type)
+	assert.Nil(t, events[0].SuggestedActions)
+
+	xidEv := events[len(events)-2]
+	assert.Equal(t, componentsacceleratornvidiaxid.EventNameErrorXid, xidEv.Name)
+	assert.Contains(t, xidEv.Message, "XID 79")
+	assert.Equal(t, "NVRM: Xid (PCI:0000:05:00): 79, pid=1234, GPU has fallen off the bus.", xidEv.LogLine)
+	require.NotNil(t, xidEv.SuggestedActions)
+	assert.NotEmpty(t, xidEv.SuggestedActions.RepairActions)
+	assert.Equal(t, "boot+180.000s", describeTimelineTime(xidEv.Time))
+
+	sxidEv := events[len(events)-3]
+	assert.Contains(t, sxidEv.Message, "SXID 20034")
+
+	out := renderTimeline(events)
+	assert.Contains(t, out, "boot+100.100s")
+	assert.Contains(t, out, "suggested actions:")
+	for _, a := range xidEv.SuggestedActions.RepairActions {
+		assert.Contains(t, out, string(a))
+	}
+}
+
+func TestAnalyzeJournalExport(t *testing.T) {
+	msgs, err := kmsg.ReadJournalExportFile("testdata/journal.json")
+	require.NoError(t, err)
+	require.Len(t, msgs, 3)
+
+	events := analyzeKmsg(msgs)
+	require.Len(t, events, 3)
+
+	// sorted by time, regardless of the input order
+	assert.Equal(t, componentsmemory.Name, events[0].Component)
+	assert.Equal(t, componentsacceleratornvidiaxid.Name, events[1].Component)
+	assert.Equal(t, componentsacceleratornvidiagpucounts.Name, events[2].Component)
+	assert.Equal(t, time.UnixMicro(1700000060000000).UTC().Format(time.RFC3339), describeTimelineTime(events[1].Time))
+}
+
+func TestAnalyzeKmsgNoMatch(t *testing.T) {
+	events := analyzeKmsg([]kmsg.Message{{Message: "hello"}})
+	assert.Empty(t, events)
+	assert.Equal(t, "-", describeSuggestedActions(nil))
+	assert.Equal(t, "REBOOT_SYSTEM, HARDWARE_INSPECTION", describeSuggestedActions(&apiv1.SuggestedActions{
+		RepairActions: []apiv1.RepairActionType{apiv1.RepairActionTypeRebootSystem, apiv1.RepairActionTypeHardwareInspection},
+	}))
+}
+
+func TestScanKmsgFiles(t *testing.T) {
+	require.NoError(t, scanKmsgFiles(&Op{kmsgFile: "testdata/dmesg.log", journalExport: "testdata/journal.json"}))
+	assert.Error(t, scanKmsgFiles(&Op{kmsgFile: "testdata/does-not-exist.log"}))
+	assert.Error(t, scanKmsgFiles(&Op{journalExport: "testdata/does-not-exist.json"}))
+}
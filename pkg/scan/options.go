@@ -1,8 +1,22 @@
 package scan
 
+import (
+	"fmt"
+
+	"github.com/leptonai/gpud/pkg/eventstore"
+)
+
 type Op struct {
-	ibstatCommand string
-	debug         bool
+	ibstatCommand     string
+	eventStoreBackend string
+	debug             bool
+
+	// expected number of GPUs, zero to use the GPUs found
+	expectedGPUCount int
+
+	// captured kernel logs to analyze, rather than the live host
+	kmsgFile      string
+	journalExport string
 }
 
 type OpOption func(*Op)
@@ -15,6 +29,12 @@ func (op *Op) applyOpts(opts []OpOption) error {
 	if op.ibstatCommand == "" {
 		op.ibstatCommand = "ibstat"
 	}
+	if op.eventStoreBackend == "" {
+		op.eventStoreBackend = eventstore.BackendMemory
+	}
+	if !eventstore.IsValidBackend(op.eventStoreBackend) {
+		return fmt.Errorf("unknown event store backend %q", op.eventStoreBackend)
+	}
 
 	return nil
 }
@@ -26,8 +46,38 @@ func WithIbstatCommand(p string) OpOption {
 	}
 }
 
+// Specifies the event store backend (defaults to "memory").
+func WithEventStoreBackend(backend string) OpOption {
+	return func(op *Op) {
+		op.eventStoreBackend = backend
+	}
+}
+
+// Specifies the expected number of GPUs.
+func WithExpectedGPUCount(n int) OpOption {
+	return func(op *Op) {
+		op.expectedGPUCount = n
+	}
+}
+
 func WithDebug(b bool) OpOption {
 	return func(op *Op) {
 		op.debug = b
 	}
 }
+
+// Specifies the captured kernel log file (e.g., "dmesg" output, "/dev/kmsg" dump)
+// to analyze offline, rather than scanning the live host.
+func WithKmsgFile(p string) OpOption {
+	return func(op *Op) {
+		op.kmsgFile = p
+	}
+}
+
+// Specifies the exported journal (e.g., "journalctl -k -o json" or "-o export" output)
+// to analyze offline, rather than scanning the live host.
+func WithJournalExport(p string) OpOption {
+	return func(op *Op) {
+		op.journalExport = p
+	}
+}
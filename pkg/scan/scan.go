@@ -3,27 +3,37 @@ package scan
 import (
 	"context"
 	"fmt"
+	"os"
 	"runtime"
+	"time"
 
 	apiv1 "github.com/leptonai/gpud/api/v1"
 	"github.com/leptonai/gpud/components"
 	nvidiacommon "github.com/leptonai/gpud/pkg/config/common"
+	"github.com/leptonai/gpud/pkg/eventstore"
+	pkghost "github.com/leptonai/gpud/pkg/host"
+	"github.com/leptonai/gpud/pkg/kmsg"
 	"github.com/leptonai/gpud/pkg/log"
 	nvidiaquery "github.com/leptonai/gpud/pkg/nvidia-query"
 	nvidianvml "github.com/leptonai/gpud/pkg/nvidia-query/nvml"
+	"github.com/leptonai/gpud/pkg/sqlite"
 
 	componentsacceleratornvidiabadenvs "github.com/leptonai/gpud/components/accelerator/nvidia/bad-envs"
 	componentsacceleratornvidiaclockspeed "github.com/leptonai/gpud/components/accelerator/nvidia/clock-speed"
 	componentsacceleratornvidiaecc "github.com/leptonai/gpud/components/accelerator/nvidia/ecc"
 	componentsacceleratornvidiafabricmanager "github.com/leptonai/gpud/components/accelerator/nvidia/fabric-manager"
 	componentsacceleratornvidiagpm "github.com/leptonai/gpud/components/accelerator/nvidia/gpm"
+	componentsacceleratornvidiagpucounts "github.com/leptonai/gpud/components/accelerator/nvidia/gpu-counts"
+	componentsacceleratornvidiagpudirectrdma "github.com/leptonai/gpud/components/accelerator/nvidia/gpudirect-rdma"
 	componentsacceleratornvidiagspfirmwaremode "github.com/leptonai/gpud/components/accelerator/nvidia/gsp-firmware-mode"
 	componentsacceleratornvidiahwslowdown "github.com/leptonai/gpud/components/accelerator/nvidia/hw-slowdown"
 	componentsacceleratornvidiainfiniband "github.com/leptonai/gpud/components/accelerator/nvidia/infiniband"
 	componentsacceleratornvidiainfo "github.com/leptonai/gpud/components/accelerator/nvidia/info"
 	componentsacceleratornvidiamemory "github.com/leptonai/gpud/components/accelerator/nvidia/memory"
+	componentsacceleratornvidiamig "github.com/leptonai/gpud/components/accelerator/nvidia/mig"
 	componentsacceleratornvidianccl "github.com/leptonai/gpud/components/accelerator/nvidia/nccl"
 	componentsacceleratornvidianvlink "github.com/leptonai/gpud/components/accelerator/nvidia/nvlink"
+	componentsacceleratornvidiapcie "github.com/leptonai/gpud/components/accelerator/nvidia/pcie"
 	componentsacceleratornvidiapeermem "github.com/leptonai/gpud/components/accelerator/nvidia/peermem"
 	componentsacceleratornvidiapersistencemode "github.com/leptonai/gpud/components/accelerator/nvidia/persistence-mode"
 	componentsacceleratornvidiapower "github.com/leptonai/gpud/components/accelerator/nvidia/power"
@@ -31,7 +41,9 @@ import (
 	componentsacceleratornvidiaremappedrows "github.com/leptonai/gpud/components/accelerator/nvidia/remapped-rows"
 	componentsacceleratornvidiasxid "github.com/leptonai/gpud/components/accelerator/nvidia/sxid"
 	componentsacceleratornvidiatemperature "github.com/leptonai/gpud/components/accelerator/nvidia/temperature"
+	componentsacceleratornvidiatopology "github.com/leptonai/gpud/components/accelerator/nvidia/topology"
 	componentsacceleratornvidiautilization "github.com/leptonai/gpud/components/accelerator/nvidia/utilization"
+	componentsacceleratornvidiaversioncompliance "github.com/leptonai/gpud/components/accelerator/nvidia/version-compliance"
 	componentsacceleratornvidiaxid "github.com/leptonai/gpud/components/accelerator/nvidia/xid"
 	componentscontainerdpod "github.com/leptonai/gpud/components/containerd/pod"
 	componentscpu "github.com/leptonai/gpud/components/cpu"
@@ -43,6 +55,7 @@ import (
 	componentskernelmodule "github.com/leptonai/gpud/components/kernel-module"
 	componentskubeletpod "github.com/leptonai/gpud/components/kubelet/pod"
 	componentslibrary "github.com/leptonai/gpud/components/library"
+	componentslogrules "github.com/leptonai/gpud/components/log-rules"
 	componentsmemory "github.com/leptonai/gpud/components/memory"
 	componentsnetworklatency "github.com/leptonai/gpud/components/network/latency"
 	componentsos "github.com/leptonai/gpud/components/os"
@@ -61,6 +74,7 @@ var componentInits = []components.InitFunc{
 	componentskernelmodule.New,
 	componentskubeletpod.New,
 	componentslibrary.New,
+	componentslogrules.New,
 	componentsmemory.New,
 	componentsnetworklatency.New,
 	componentsos.New,
@@ -71,13 +85,17 @@ var componentInits = []components.InitFunc{
 	componentsacceleratornvidiaecc.New,
 	componentsacceleratornvidiafabricmanager.New,
 	componentsacceleratornvidiagpm.New,
+	componentsacceleratornvidiagpucounts.New,
+	componentsacceleratornvidiagpudirectrdma.New,
 	componentsacceleratornvidiagspfirmwaremode.New,
 	componentsacceleratornvidiahwslowdown.New,
 	componentsacceleratornvidiainfiniband.New,
 	componentsacceleratornvidiainfo.New,
 	componentsacceleratornvidiamemory.New,
+	componentsacceleratornvidiamig.New,
 	componentsacceleratornvidianccl.New,
 	componentsacceleratornvidianvlink.New,
+	componentsacceleratornvidiapcie.New,
 	componentsacceleratornvidiapeermem.New,
 	componentsacceleratornvidiapersistencemode.New,
 	componentsacceleratornvidiapower.New,
@@ -85,7 +103,9 @@ var componentInits = []components.InitFunc{
 	componentsacceleratornvidiaremappedrows.New,
 	componentsacceleratornvidiasxid.New,
 	componentsacceleratornvidiatemperature.New,
+	componentsacceleratornvidiatopology.New,
 	componentsacceleratornvidiautilization.New,
+	componentsacceleratornvidiaversioncompliance.New,
 	componentsacceleratornvidiaxid.New,
 }
 
@@ -113,6 +133,10 @@ func Scan(ctx context.Context, opts ...OpOption) error {
 		return err
 	}
 
+	if op.kmsgFile != "" || op.journalExport != "" {
+		return scanKmsgFiles(op)
+	}
+
 	fmt.Printf("\n\n%s scanning the host (GOOS %s)\n\n", inProgress, runtime.GOOS)
 
 	nvidiaInstalled, err := nvidiaquery.GPUsInstalled(ctx)
@@ -130,6 +154,20 @@ func Scan(ctx context.Context, opts ...OpOption) error {
 		}
 	}
 
+	eventStore, cleanup, err := openEventStore(op.eventStoreBackend)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	rebootEventStore := pkghost.NewRebootEventStore(eventStore)
+	cctx, ccancel := context.WithTimeout(ctx, time.Minute)
+	err = rebootEventStore.RecordReboot(cctx)
+	ccancel()
+	if err != nil {
+		log.Logger.Warnw("failed to record reboot", "error", err)
+	}
+
 	gpudInstance := &components.GPUdInstance{
 		RootCtx: ctx,
 
@@ -138,18 +176,155 @@ func Scan(ctx context.Context, opts ...OpOption) error {
 			IbstatCommand: op.ibstatCommand,
 		},
 
-		EventStore:       nil,
-		RebootEventStore: nil,
+		EventStore:       eventStore,
+		RebootEventStore: rebootEventStore,
+	}
+	if op.expectedGPUCount > 0 {
+		gpudInstance.ExpectedGPUs = &nvidiacommon.ExpectedGPUs{Count: op.expectedGPUCount}
 	}
 
+	// read the kernel messages once and share among the components,
+	// so that the log based components (e.g., xid) check the messages in the ring buffer
+	kmsgHub := openKmsgHub(ctx)
+	gpudInstance.KmsgHub = kmsgHub
+
+	cs := make([]components.Component, 0, len(componentInits))
+	defer func() {
+		// close the components before the hub,
+		// so that the components stop consuming before the subscriber channels are closed
+		for _, c := range cs {
+			if err := c.Close(); err != nil {
+				log.Logger.Warnw("failed to close component", "component", c.Name(), "error", err)
+			}
+		}
+		if kmsgHub != nil {
+			if err := kmsgHub.Close(); err != nil {
+				log.Logger.Warnw("failed to close kmsg hub", "error", err)
+			}
+		}
+	}()
 	for _, initFunc := range componentInits {
 		c, err := initFunc(gpudInstance)
 		if err != nil {
 			return err
 		}
+		cs = append(cs, c)
+	}
+	for _, c := range cs {
+		if err := c.Start(); err != nil {
+			log.Logger.Warnw("failed to start component", "component", c.Name(), "error", err)
+		}
+	}
+
+	// start reading kmsg only after all the subscribers are registered and started
+	if kmsgHub != nil {
+		if err := kmsgHub.Start(); err != nil {
+			log.Logger.Warnw("failed to start kmsg hub", "error", err)
+		} else {
+			fmt.Printf("\n%s reading kernel messages (source %s)\n", inProgress, kmsgHub.Source())
+			waitKmsgProcessed(ctx, kmsgHub, time.Second, 30*time.Second)
+		}
+	}
+
+	for _, c := range cs {
 		printSummary(c.Check())
 	}
 
 	fmt.Printf("\n\n%s scan complete\n\n", checkMark)
 	return nil
 }
+
+// openKmsgHub opens the kernel log source available on the host for the scan.
+// Returns nil if no source is available, in which case
+// the log based components are skipped.
+func openKmsgHub(ctx context.Context) *kmsg.Hub {
+	source, filePath := kmsg.DetectSource("")
+	if source == "" {
+		log.Logger.Warnw("no kernel log source available, log based components (e.g., xid) are disabled")
+		return nil
+	}
+	h, err := kmsg.NewHub(ctx, kmsg.WithSource(source, filePath))
+	if err != nil {
+		log.Logger.Warnw("failed to create kmsg hub, log based components (e.g., xid) are disabled", "source", source, "error", err)
+		return nil
+	}
+	return h
+}
+
+// waitKmsgProcessed waits until the hub stops reading new messages
+// (e.g., read all the messages in the ring buffer), and all the subscribers
+// processed the messages read so far, or the timeout elapses.
+func waitKmsgProcessed(ctx context.Context, h *kmsg.Hub, pollInterval time.Duration, timeout time.Duration) {
+	cctx, ccancel := context.WithTimeout(ctx, timeout)
+	defer ccancel()
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	prevSeq := int64(-1)
+	for {
+		select {
+		case <-cctx.Done():
+			log.Logger.Warnw("timed out waiting for the kernel messages to be processed", "lastSequenceNumber", h.LastSequenceNumber())
+			return
+		case <-ticker.C:
+		}
+
+		seq := h.LastSequenceNumber()
+		if seq == prevSeq && kmsgProcessed(h.Stats()) {
+			return
+		}
+		prevSeq = seq
+	}
+}
+
+// kmsgProcessed returns true if all the subscribers
+// processed all the messages delivered to them.
+func kmsgProcessed(stats []kmsg.SubscriberStats) bool {
+	for _, st := range stats {
+		if st.Lag > 0 || st.AckedSequenceNumber < st.LastSequenceNumber {
+			return false
+		}
+	}
+	return true
+}
+
+// openEventStore opens the event store for the scan,
+// which is discarded once the scan completes.
+func openEventStore(backend string) (eventstore.Store, func(), error) {
+	if backend == eventstore.BackendMemory {
+		store, err := eventstore.NewMemory(0)
+		return store, func() {}, err
+	}
+
+	f, err := os.CreateTemp("", "gpud-scan-*.state")
+	if err != nil {
+		return nil, nil, err
+	}
+	stateFile := f.Name()
+	_ = f.Close()
+
+	dbRW, err := sqlite.Open(stateFile)
+	if err != nil {
+		_ = os.Remove(stateFile)
+		return nil, nil, err
+	}
+	dbRO, err := sqlite.Open(stateFile, sqlite.WithReadOnly(true))
+	if err != nil {
+		_ = dbRW.Close()
+		_ = os.Remove(stateFile)
+		return nil, nil, err
+	}
+	cleanup := func() {
+		_ = dbRO.Close()
+		_ = dbRW.Close()
+		_ = os.Remove(stateFile)
+	}
+
+	store, err := eventstore.New(dbRW, dbRO, 0)
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	return store, cleanup, nil
+}
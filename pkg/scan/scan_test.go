@@ -5,6 +5,10 @@ import (
 	"os"
 	"testing"
 	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/leptonai/gpud/pkg/kmsg"
 )
 
 func TestScan(t *testing.T) {
@@ -19,3 +23,17 @@ func TestScan(t *testing.T) {
 		t.Logf("error scanning: %+v", err)
 	}
 }
+
+func TestKmsgProcessed(t *testing.T) {
+	assert.True(t, kmsgProcessed(nil))
+	assert.True(t, kmsgProcessed([]kmsg.SubscriberStats{
+		{Name: "a", LastSequenceNumber: 3, AckedSequenceNumber: 3},
+		{Name: "b"},
+	}))
+	assert.False(t, kmsgProcessed([]kmsg.SubscriberStats{
+		{Name: "a", LastSequenceNumber: 3, AckedSequenceNumber: 2},
+	}))
+	assert.False(t, kmsgProcessed([]kmsg.SubscriberStats{
+		{Name: "a", Lag: 1, LastSequenceNumber: 3, AckedSequenceNumber: 3},
+	}))
+}
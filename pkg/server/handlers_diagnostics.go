@@ -0,0 +1,184 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	componentsnvidiadcgmdiag "github.com/leptonai/gpud/components/accelerator/nvidia/dcgm-diag"
+	componentsnvidianccl "github.com/leptonai/gpud/components/accelerator/nvidia/nccl"
+	"github.com/leptonai/gpud/pkg/errdefs"
+	"github.com/leptonai/gpud/pkg/log"
+	"github.com/leptonai/gpud/pkg/nvidia-query/dcgm"
+)
+
+func (g *globalHandler) registerDiagnosticsRoutes(r gin.IRoutes) {
+	r.POST(URLPathDiagnostics, g.runDiagnostics)
+	r.DELETE(URLPathDiagnostics, g.cancelDiagnostics)
+	r.POST(URLPathDiagnosticsNCCL, g.runNCCLSelfTest)
+	r.DELETE(URLPathDiagnosticsNCCL, g.cancelNCCLSelfTest)
+}
+
+const (
+	URLPathDiagnostics     = "/diagnostics"
+	URLPathDiagnosticsDesc = "Run or cancel the NVIDIA DCGM diagnostics"
+)
+
+func (g *globalHandler) getDiagnoser(c *gin.Context) (componentsnvidiadcgmdiag.Diagnoser, bool) {
+	comp := g.componentsRegistry.Get(componentsnvidiadcgmdiag.Name)
+	if comp == nil {
+		c.JSON(http.StatusNotFound, gin.H{"code": errdefs.ErrNotFound, "message": "component not found: " + componentsnvidiadcgmdiag.Name})
+		return nil, false
+	}
+	diagnoser, ok := comp.(componentsnvidiadcgmdiag.Diagnoser)
+	if !ok {
+		c.JSON(http.StatusNotImplemented, gin.H{"code": errdefs.ErrNotImplemented, "message": "component does not support diagnostics: " + componentsnvidiadcgmdiag.Name})
+		return nil, false
+	}
+	return diagnoser, true
+}
+
+// runDiagnostics godoc
+// @Summary Run the NVIDIA DCGM diagnostics
+// @Description runs "dcgmi diag" of the level and blocks until completion, refuses to start while the GPU processes are running unless forced
+// @ID runDiagnostics
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} dcgm.DiagResult
+// @Router /v1/diagnostics [post]
+func (g *globalHandler) runDiagnostics(c *gin.Context) {
+	var req componentsnvidiadcgmdiag.DiagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": errdefs.ErrInvalidArgument, "message": "failed to parse request: " + err.Error()})
+		return
+	}
+
+	diagnoser, ok := g.getDiagnoser(c)
+	if !ok {
+		return
+	}
+
+	result, err := diagnoser.RunDiag(c.Request.Context(), req)
+	if err != nil {
+		switch {
+		case errors.Is(err, dcgm.ErrInvalidDiagLevel), errors.Is(err, componentsnvidiadcgmdiag.ErrInvalidDiagTimeout):
+			c.JSON(http.StatusBadRequest, gin.H{"code": errdefs.ErrInvalidArgument, "message": err.Error()})
+		case errors.Is(err, componentsnvidiadcgmdiag.ErrDiagAlreadyRunning):
+			c.JSON(http.StatusConflict, gin.H{"code": errdefs.ErrAlreadyExists, "message": err.Error()})
+		case errors.Is(err, componentsnvidiadcgmdiag.ErrGPUProcessesRunning):
+			c.JSON(http.StatusPreconditionFailed, gin.H{"code": errdefs.ErrFailedPrecondition, "message": err.Error()})
+		case errors.Is(err, componentsnvidiadcgmdiag.ErrDcgmiNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"code": errdefs.ErrNotFound, "message": err.Error()})
+		case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+			c.JSON(http.StatusRequestTimeout, gin.H{"code": errdefs.ErrUnavailable, "message": "diagnostics aborted: " + err.Error()})
+		default:
+			log.Logger.Errorw("failed to run diagnostics", "level", req.Level, "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"code": http.StatusInternalServerError, "message": "failed to run diagnostics: " + err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// cancelDiagnostics godoc
+// @Summary Cancel the running NVIDIA DCGM diagnostics
+// @Description cancels the running "dcgmi diag"
+// @ID cancelDiagnostics
+// @Produce  json
+// @Success 200
+// @Router /v1/diagnostics [delete]
+func (g *globalHandler) cancelDiagnostics(c *gin.Context) {
+	diagnoser, ok := g.getDiagnoser(c)
+	if !ok {
+		return
+	}
+	if !diagnoser.CancelDiag() {
+		c.JSON(http.StatusNotFound, gin.H{"code": errdefs.ErrNotFound, "message": "no diagnostics running"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "diagnostics canceled"})
+}
+
+const (
+	URLPathDiagnosticsNCCL     = "/diagnostics/nccl"
+	URLPathDiagnosticsNCCLDesc = "Run or cancel the NCCL all-reduce bandwidth self-test"
+)
+
+func (g *globalHandler) getNCCLSelfTester(c *gin.Context) (componentsnvidianccl.SelfTester, bool) {
+	comp := g.componentsRegistry.Get(componentsnvidianccl.Name)
+	if comp == nil {
+		c.JSON(http.StatusNotFound, gin.H{"code": errdefs.ErrNotFound, "message": "component not found: " + componentsnvidianccl.Name})
+		return nil, false
+	}
+	selfTester, ok := comp.(componentsnvidianccl.SelfTester)
+	if !ok {
+		c.JSON(http.StatusNotImplemented, gin.H{"code": errdefs.ErrNotImplemented, "message": "component does not support self-test: " + componentsnvidianccl.Name})
+		return nil, false
+	}
+	return selfTester, true
+}
+
+// runNCCLSelfTest godoc
+// @Summary Run the NCCL all-reduce bandwidth self-test
+// @Description runs "all_reduce_perf" (or the configured equivalent) on the local GPUs and blocks until completion, refuses to start while the GPU processes are running unless forced
+// @ID runNCCLSelfTest
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} nccl.SelfTestResult
+// @Router /v1/diagnostics/nccl [post]
+func (g *globalHandler) runNCCLSelfTest(c *gin.Context) {
+	var req componentsnvidianccl.SelfTestRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"code": errdefs.ErrInvalidArgument, "message": "failed to parse request: " + err.Error()})
+			return
+		}
+	}
+
+	selfTester, ok := g.getNCCLSelfTester(c)
+	if !ok {
+		return
+	}
+
+	result, err := selfTester.RunSelfTest(c.Request.Context(), req)
+	if err != nil {
+		switch {
+		case errors.Is(err, componentsnvidianccl.ErrSelfTestAlreadyRunning):
+			c.JSON(http.StatusConflict, gin.H{"code": errdefs.ErrAlreadyExists, "message": err.Error()})
+		case errors.Is(err, componentsnvidianccl.ErrGPUProcessesRunning):
+			c.JSON(http.StatusPreconditionFailed, gin.H{"code": errdefs.ErrFailedPrecondition, "message": err.Error()})
+		case errors.Is(err, componentsnvidianccl.ErrNoGPU), errors.Is(err, componentsnvidianccl.ErrAllReducePerfNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"code": errdefs.ErrNotFound, "message": err.Error()})
+		case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+			c.JSON(http.StatusRequestTimeout, gin.H{"code": errdefs.ErrUnavailable, "message": "self-test aborted: " + err.Error()})
+		default:
+			log.Logger.Errorw("failed to run nccl self-test", "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"code": http.StatusInternalServerError, "message": "failed to run nccl self-test: " + err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// cancelNCCLSelfTest godoc
+// @Summary Cancel the running NCCL all-reduce bandwidth self-test
+// @Description cancels the running "all_reduce_perf"
+// @ID cancelNCCLSelfTest
+// @Produce  json
+// @Success 200
+// @Router /v1/diagnostics/nccl [delete]
+func (g *globalHandler) cancelNCCLSelfTest(c *gin.Context) {
+	selfTester, ok := g.getNCCLSelfTester(c)
+	if !ok {
+		return
+	}
+	if !selfTester.CancelSelfTest() {
+		c.JSON(http.StatusNotFound, gin.H{"code": errdefs.ErrNotFound, "message": "no self-test running"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "self-test canceled"})
+}
@@ -39,6 +39,7 @@ import (
 	metricstate "github.com/leptonai/gpud/pkg/gpud-metrics/state"
 	gpudstate "github.com/leptonai/gpud/pkg/gpud-state"
 	pkghost "github.com/leptonai/gpud/pkg/host"
+	"github.com/leptonai/gpud/pkg/kmsg"
 	"github.com/leptonai/gpud/pkg/log"
 	pkgmetrics "github.com/leptonai/gpud/pkg/metrics"
 	pkgmetricsscraper "github.com/leptonai/gpud/pkg/metrics/scraper"
@@ -46,22 +47,29 @@ import (
 	pkgmetricssyncer "github.com/leptonai/gpud/pkg/metrics/syncer"
 	nvidiaquery "github.com/leptonai/gpud/pkg/nvidia-query"
 	nvidianvml "github.com/leptonai/gpud/pkg/nvidia-query/nvml"
+	nvidiasxid "github.com/leptonai/gpud/pkg/nvidia-query/sxid"
+	nvidiaxid "github.com/leptonai/gpud/pkg/nvidia-query/xid"
 	"github.com/leptonai/gpud/pkg/session"
 	"github.com/leptonai/gpud/pkg/sqlite"
 	"github.com/leptonai/gpud/version"
 
 	componentsacceleratornvidiabadenvs "github.com/leptonai/gpud/components/accelerator/nvidia/bad-envs"
 	componentsacceleratornvidiaclockspeed "github.com/leptonai/gpud/components/accelerator/nvidia/clock-speed"
+	componentsacceleratornvidiadcgmdiag "github.com/leptonai/gpud/components/accelerator/nvidia/dcgm-diag"
 	componentsacceleratornvidiaecc "github.com/leptonai/gpud/components/accelerator/nvidia/ecc"
 	componentsacceleratornvidiafabricmanager "github.com/leptonai/gpud/components/accelerator/nvidia/fabric-manager"
 	componentsacceleratornvidiagpm "github.com/leptonai/gpud/components/accelerator/nvidia/gpm"
+	componentsacceleratornvidiagpucounts "github.com/leptonai/gpud/components/accelerator/nvidia/gpu-counts"
+	componentsacceleratornvidiagpudirectrdma "github.com/leptonai/gpud/components/accelerator/nvidia/gpudirect-rdma"
 	componentsacceleratornvidiagspfirmwaremode "github.com/leptonai/gpud/components/accelerator/nvidia/gsp-firmware-mode"
 	componentsacceleratornvidiahwslowdown "github.com/leptonai/gpud/components/accelerator/nvidia/hw-slowdown"
 	componentsacceleratornvidiainfiniband "github.com/leptonai/gpud/components/accelerator/nvidia/infiniband"
 	componentsacceleratornvidiainfo "github.com/leptonai/gpud/components/accelerator/nvidia/info"
 	componentsacceleratornvidiamemory "github.com/leptonai/gpud/components/accelerator/nvidia/memory"
+	componentsacceleratornvidiamig "github.com/leptonai/gpud/components/accelerator/nvidia/mig"
 	componentsacceleratornvidianccl "github.com/leptonai/gpud/components/accelerator/nvidia/nccl"
 	componentsacceleratornvidianvlink "github.com/leptonai/gpud/components/accelerator/nvidia/nvlink"
+	componentsacceleratornvidiapcie "github.com/leptonai/gpud/components/accelerator/nvidia/pcie"
 	componentsacceleratornvidiapeermem "github.com/leptonai/gpud/components/accelerator/nvidia/peermem"
 	componentsacceleratornvidiapersistencemode "github.com/leptonai/gpud/components/accelerator/nvidia/persistence-mode"
 	componentsacceleratornvidiapower "github.com/leptonai/gpud/components/accelerator/nvidia/power"
@@ -69,7 +77,9 @@ import (
 	componentsacceleratornvidiaremappedrows "github.com/leptonai/gpud/components/accelerator/nvidia/remapped-rows"
 	componentsacceleratornvidiasxid "github.com/leptonai/gpud/components/accelerator/nvidia/sxid"
 	componentsacceleratornvidiatemperature "github.com/leptonai/gpud/components/accelerator/nvidia/temperature"
+	componentsacceleratornvidiatopology "github.com/leptonai/gpud/components/accelerator/nvidia/topology"
 	componentsacceleratornvidiautilization "github.com/leptonai/gpud/components/accelerator/nvidia/utilization"
+	componentsacceleratornvidiaversioncompliance "github.com/leptonai/gpud/components/accelerator/nvidia/version-compliance"
 	componentsacceleratornvidiaxid "github.com/leptonai/gpud/components/accelerator/nvidia/xid"
 	componentscontainerdpod "github.com/leptonai/gpud/components/containerd/pod"
 	componentscpu "github.com/leptonai/gpud/components/cpu"
@@ -81,6 +91,7 @@ import (
 	componentskernelmodule "github.com/leptonai/gpud/components/kernel-module"
 	componentskubeletpod "github.com/leptonai/gpud/components/kubelet/pod"
 	componentslibrary "github.com/leptonai/gpud/components/library"
+	componentslogrules "github.com/leptonai/gpud/components/log-rules"
 	componentsmemory "github.com/leptonai/gpud/components/memory"
 	componentsnetworklatency "github.com/leptonai/gpud/components/network/latency"
 	componentsos "github.com/leptonai/gpud/components/os"
@@ -99,6 +110,7 @@ var componentInits = []components.InitFunc{
 	componentskernelmodule.New,
 	componentskubeletpod.New,
 	componentslibrary.New,
+	componentslogrules.New,
 	componentsmemory.New,
 	componentsnetworklatency.New,
 	componentsos.New,
@@ -106,16 +118,21 @@ var componentInits = []components.InitFunc{
 	componentstailscale.New,
 	componentsacceleratornvidiabadenvs.New,
 	componentsacceleratornvidiaclockspeed.New,
+	componentsacceleratornvidiadcgmdiag.New,
 	componentsacceleratornvidiaecc.New,
 	componentsacceleratornvidiafabricmanager.New,
 	componentsacceleratornvidiagpm.New,
+	componentsacceleratornvidiagpucounts.New,
+	componentsacceleratornvidiagpudirectrdma.New,
 	componentsacceleratornvidiagspfirmwaremode.New,
 	componentsacceleratornvidiahwslowdown.New,
 	componentsacceleratornvidiainfiniband.New,
 	componentsacceleratornvidiainfo.New,
 	componentsacceleratornvidiamemory.New,
+	componentsacceleratornvidiamig.New,
 	componentsacceleratornvidianccl.New,
 	componentsacceleratornvidianvlink.New,
+	componentsacceleratornvidiapcie.New,
 	componentsacceleratornvidiapeermem.New,
 	componentsacceleratornvidiapersistencemode.New,
 	componentsacceleratornvidiapower.New,
@@ -123,7 +140,9 @@ var componentInits = []components.InitFunc{
 	componentsacceleratornvidiaremappedrows.New,
 	componentsacceleratornvidiasxid.New,
 	componentsacceleratornvidiatemperature.New,
+	componentsacceleratornvidiatopology.New,
 	componentsacceleratornvidiautilization.New,
+	componentsacceleratornvidiaversioncompliance.New,
 	componentsacceleratornvidiaxid.New,
 }
 
@@ -133,6 +152,7 @@ type Server struct {
 	dbRO *sql.DB
 
 	componentsRegistry components.Registry
+	kmsgHub            *kmsg.Hub
 
 	uid                string
 	fifoPath           string
@@ -160,7 +180,14 @@ func New(ctx context.Context, config *lepconfig.Config, endpoint string, cliUID
 		return nil, fmt.Errorf("failed to open state file (for read-only): %w", err)
 	}
 
-	eventStore, err := eventstore.New(dbRW, dbRO, 0)
+	var eventStore eventstore.Store
+	switch config.EventStoreBackend {
+	case eventstore.BackendMemory:
+		log.Logger.Infow("using in-memory event store, events are not persisted")
+		eventStore, err = eventstore.NewMemory(0)
+	default:
+		eventStore, err = eventstore.New(dbRW, dbRO, 0)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to open events database: %w", err)
 	}
@@ -254,18 +281,66 @@ func New(ctx context.Context, config *lepconfig.Config, endpoint string, cliUID
 		}
 	}()
 
+	// apply the xid/sxid catalog overrides before the components resolve any event
+	// (already merged over the built-in details, either loaded from the catalog files
+	// or decoded from the config, see "xid.Catalog")
+	if len(config.XIDCatalog) > 0 {
+		log.Logger.Infow("using the xid catalog overrides", "count", len(config.XIDCatalog))
+		nvidiaxid.SetCatalog(config.XIDCatalog)
+	}
+	if len(config.SXIDCatalog) > 0 {
+		log.Logger.Infow("using the sxid catalog overrides", "count", len(config.SXIDCatalog))
+		nvidiasxid.SetCatalog(config.SXIDCatalog)
+	}
+
+	// open the kernel log source (e.g., "/dev/kmsg") once, and share among all the components
+	kmsgSource, kmsgFilePath := config.KmsgSource, config.KmsgFilePath
+	if kmsgSource == "" {
+		kmsgSource, kmsgFilePath = kmsg.DetectSource(config.KmsgFilePath)
+	}
+	if kmsgSource == "" {
+		log.Logger.Warnw("no kernel log source available, log based components (e.g., xid) are disabled")
+	} else {
+		log.Logger.Infow("reading kernel messages", "source", kmsgSource, "filePath", kmsgFilePath)
+
+		hubOpts := []kmsg.HubOpOption{kmsg.WithSource(kmsgSource, kmsgFilePath)}
+
+		// only persist the kmsg cursor when the events are persisted
+		// otherwise, the events would be lost after restart
+		if kmsgSource == kmsg.SourceKmsg && config.EventStoreBackend != eventstore.BackendMemory {
+			cursorStore, err := gpudstate.NewKmsgCursorStore(ctx, dbRW, dbRO)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create kmsg cursor store: %w", err)
+			}
+			hubOpts = append(hubOpts, kmsg.WithCursorStore(pkghost.BootID(), cursorStore))
+		}
+
+		// the log based components are disabled, rather than failing the server
+		// (e.g., the kernel log source is not readable in the container)
+		s.kmsgHub, err = kmsg.NewHub(ctx, hubOpts...)
+		if err != nil {
+			log.Logger.Errorw("failed to create kmsg hub, log based components (e.g., xid) are disabled", "source", kmsgSource, "error", err)
+			s.kmsgHub = nil
+		}
+	}
+
 	gpudInstance := &components.GPUdInstance{
 		RootCtx: ctx,
 
 		NVMLInstance:         nvmlInstanceV2,
 		NVIDIAToolOverwrites: config.NvidiaToolOverwrites,
+		ExpectedGPUs:         config.ExpectedGPUs,
 
 		Annotations: config.Annotations,
+		DBRW:        dbRW,
 		DBRO:        dbRO,
 
 		EventStore:       eventStore,
 		RebootEventStore: rebootEventStore,
 
+		KmsgHub:  s.kmsgHub,
+		LogRules: config.LogRules,
+
 		MountPoints:  []string{"/"},
 		MountTargets: []string{"/var/lib/kubelet"},
 	}
@@ -281,6 +356,14 @@ func New(ctx context.Context, config *lepconfig.Config, endpoint string, cliUID
 		componentNames = append(componentNames, c.Name())
 	}
 
+	// start reading kmsg only after all the subscribers are registered and started
+	// so that no subscriber misses the messages already in the ring buffer
+	if s.kmsgHub != nil {
+		if err = s.kmsgHub.Start(); err != nil {
+			log.Logger.Errorw("failed to start kmsg hub, log based components (e.g., xid) are disabled", "source", kmsgSource, "error", err)
+		}
+	}
+
 	go func() {
 		ticker := time.NewTicker(time.Minute) // only first run is 1-minute wait
 		defer ticker.Stop()
@@ -373,6 +456,7 @@ func New(ctx context.Context, config *lepconfig.Config, endpoint string, cliUID
 
 	ghler := newGlobalHandler(config, s.componentsRegistry, metricsSQLiteStore)
 	ghler.registerComponentRoutes(v1)
+	ghler.registerDiagnosticsRoutes(v1)
 	promHandler := promhttp.HandlerFor(pkgmetrics.DefaultGatherer(), promhttp.HandlerOpts{})
 	router.GET("/metrics", func(ctx *gin.Context) {
 		promHandler.ServeHTTP(ctx.Writer, ctx.Request)
@@ -467,6 +551,14 @@ func (s *Server) Stop() {
 		}
 	}
 
+	// close the hub after the components, so that the components
+	// stop consuming before the subscriber channels are closed
+	if s.kmsgHub != nil {
+		if err := s.kmsgHub.Close(); err != nil {
+			log.Logger.Errorw("failed to close kmsg hub", "error", err)
+		}
+	}
+
 	if cerr := s.dbRW.Close(); cerr != nil {
 		log.Logger.Debugw("failed to close read-write db", "error", cerr)
 	} else {
@@ -14,12 +14,20 @@ import (
 
 	apiv1 "github.com/leptonai/gpud/api/v1"
 	"github.com/leptonai/gpud/components"
+	componentsnvidiadcgmdiag "github.com/leptonai/gpud/components/accelerator/nvidia/dcgm-diag"
+	componentsnvidiaecc "github.com/leptonai/gpud/components/accelerator/nvidia/ecc"
 	componentsnvidiainfiniband "github.com/leptonai/gpud/components/accelerator/nvidia/infiniband"
+	componentsnvidiamig "github.com/leptonai/gpud/components/accelerator/nvidia/mig"
+	componentsnvidianccl "github.com/leptonai/gpud/components/accelerator/nvidia/nccl"
+	componentsnvidianvlink "github.com/leptonai/gpud/components/accelerator/nvidia/nvlink"
+	componentsnvidiapcie "github.com/leptonai/gpud/components/accelerator/nvidia/pcie"
+	componentsnvidiapower "github.com/leptonai/gpud/components/accelerator/nvidia/power"
+	componentsnvidiatopology "github.com/leptonai/gpud/components/accelerator/nvidia/topology"
+	componentsnvidiaversioncompliance "github.com/leptonai/gpud/components/accelerator/nvidia/version-compliance"
 	"github.com/leptonai/gpud/pkg/errdefs"
 	pkghost "github.com/leptonai/gpud/pkg/host"
 	"github.com/leptonai/gpud/pkg/log"
 	pkgmetrics "github.com/leptonai/gpud/pkg/metrics"
-	"github.com/leptonai/gpud/pkg/nvidia-query/infiniband"
 	"github.com/leptonai/gpud/pkg/systemd"
 	"github.com/leptonai/gpud/pkg/update"
 )
@@ -38,6 +46,14 @@ type Request struct {
 	UpdateVersion string            `json:"update_version,omitempty"`
 	UpdateConfig  map[string]string `json:"update_config,omitempty"`
 	Bootstrap     *BootstrapRequest `json:"bootstrap,omitempty"`
+
+	// Diagnostics is the request for the "diagnostics" method.
+	Diagnostics *DiagnosticsRequest `json:"diagnostics,omitempty"`
+
+	// DeviceUUID is the device to set healthy for the "sethealthy" method,
+	// for the components tracking the health state per device.
+	// If empty, all the devices are set healthy.
+	DeviceUUID string `json:"device_uuid,omitempty"`
 }
 
 type Response struct {
@@ -65,6 +81,13 @@ type BootstrapResponse struct {
 	ExitCode int32  `json:"exit_code,omitempty"`
 }
 
+type DiagnosticsRequest struct {
+	componentsnvidiadcgmdiag.DiagRequest
+
+	// Cancel cancels the running diagnostics, instead of starting one.
+	Cancel bool `json:"cancel,omitempty"`
+}
+
 func (s *Session) serve() {
 	for body := range s.reader {
 		var payload Request
@@ -114,14 +137,26 @@ func (s *Session) serve() {
 			go s.delete()
 
 		case "sethealthy":
-			log.Logger.Infow("sethealthy received", "components", payload.Components)
+			log.Logger.Infow("sethealthy received", "components", payload.Components, "deviceUUID", payload.DeviceUUID)
 			for _, componentName := range payload.Components {
 				comp := s.componentsRegistry.Get(componentName)
 				if comp == nil {
 					log.Logger.Errorw("failed to get component", "error", errdefs.ErrNotFound)
 					continue
 				}
-				if healthSettable, ok := comp.(components.HealthSettable); ok {
+				if payload.DeviceUUID != "" {
+					// never fall back to the whole component,
+					// which would clear the states of all the other devices
+					deviceHealthSettable, ok := comp.(components.DeviceHealthSettable)
+					if !ok {
+						log.Logger.Warnw("component does not implement DeviceHealthSettable, dropping sethealthy request", "component", componentName, "deviceUUID", payload.DeviceUUID)
+						response.Error = fmt.Sprintf("component %q does not support setting a device healthy", componentName)
+						continue
+					}
+					if err := deviceHealthSettable.SetHealthyDevice(payload.DeviceUUID); err != nil {
+						log.Logger.Errorw("failed to set healthy", "component", componentName, "deviceUUID", payload.DeviceUUID, "error", err)
+					}
+				} else if healthSettable, ok := comp.(components.HealthSettable); ok {
 					if err := healthSettable.SetHealthy(); err != nil {
 						log.Logger.Errorw("failed to set healthy", "component", componentName, "error", err)
 					}
@@ -181,11 +216,91 @@ func (s *Session) serve() {
 
 					switch componentName {
 					case componentsnvidiainfiniband.Name:
-						var updateCfg infiniband.ExpectedPortStates
+						// expected port states, and the optional port error rate and link flap thresholds in the same config
+						// e.g., {"at_least_ports":8,"at_least_rate":400,"error_rates":{"max_per_minute":{"symbol_error":10}},"link_flaps":{"window":"10m","max_flaps":3}}
+						// only the thresholds present in the config are updated, so that
+						// e.g., updating the error rates does not reset the expected port states
+						var updateCfg struct {
+							AtLeastPorts *int                                            `json:"at_least_ports"`
+							AtLeastRate  *int                                            `json:"at_least_rate"`
+							ErrorRates   *componentsnvidiainfiniband.ErrorRateThresholds `json:"error_rates"`
+							LinkFlaps    *componentsnvidiainfiniband.LinkFlapThresholds  `json:"link_flaps"`
+						}
+						if err := json.Unmarshal([]byte(value), &updateCfg); err != nil {
+							log.Logger.Warnw("failed to unmarshal update config", "error", err)
+							break
+						}
+						if updateCfg.AtLeastPorts != nil || updateCfg.AtLeastRate != nil {
+							states := componentsnvidiainfiniband.GetDefaultExpectedPortStates()
+							if updateCfg.AtLeastPorts != nil {
+								states.AtLeastPorts = *updateCfg.AtLeastPorts
+							}
+							if updateCfg.AtLeastRate != nil {
+								states.AtLeastRate = *updateCfg.AtLeastRate
+							}
+							componentsnvidiainfiniband.SetDefaultExpectedPortStates(states)
+						}
+						if updateCfg.ErrorRates != nil {
+							componentsnvidiainfiniband.SetDefaultErrorRateThresholds(*updateCfg.ErrorRates)
+						}
+						if updateCfg.LinkFlaps != nil {
+							componentsnvidiainfiniband.SetDefaultLinkFlapThresholds(*updateCfg.LinkFlaps)
+						}
+					case componentsnvidiaecc.Name:
+						var updateCfg componentsnvidiaecc.Thresholds
 						if err := json.Unmarshal([]byte(value), &updateCfg); err != nil {
 							log.Logger.Warnw("failed to unmarshal update config", "error", err)
 						} else {
-							componentsnvidiainfiniband.SetDefaultExpectedPortStates(updateCfg)
+							componentsnvidiaecc.SetDefaultThresholds(updateCfg)
+						}
+					case componentsnvidianvlink.Name:
+						var updateCfg componentsnvidianvlink.Thresholds
+						if err := json.Unmarshal([]byte(value), &updateCfg); err != nil {
+							log.Logger.Warnw("failed to unmarshal update config", "error", err)
+						} else {
+							componentsnvidianvlink.SetDefaultThresholds(updateCfg)
+						}
+					case componentsnvidiapcie.Name:
+						var updateCfg componentsnvidiapcie.Thresholds
+						if err := json.Unmarshal([]byte(value), &updateCfg); err != nil {
+							log.Logger.Warnw("failed to unmarshal update config", "error", err)
+						} else {
+							componentsnvidiapcie.SetDefaultThresholds(updateCfg)
+						}
+					case componentsnvidiapower.Name:
+						var updateCfg componentsnvidiapower.Thresholds
+						if err := json.Unmarshal([]byte(value), &updateCfg); err != nil {
+							log.Logger.Warnw("failed to unmarshal update config", "error", err)
+						} else {
+							componentsnvidiapower.SetDefaultThresholds(updateCfg)
+						}
+					case componentsnvidiamig.Name:
+						var updateCfg componentsnvidiamig.ExpectedLayout
+						if err := json.Unmarshal([]byte(value), &updateCfg); err != nil {
+							log.Logger.Warnw("failed to unmarshal update config", "error", err)
+						} else {
+							componentsnvidiamig.SetDefaultExpectedLayout(updateCfg)
+						}
+					case componentsnvidiatopology.Name:
+						var updateCfg componentsnvidiatopology.Reference
+						if err := json.Unmarshal([]byte(value), &updateCfg); err != nil {
+							log.Logger.Warnw("failed to unmarshal update config", "error", err)
+						} else {
+							componentsnvidiatopology.SetDefaultReference(updateCfg)
+						}
+					case componentsnvidianccl.Name:
+						var updateCfg componentsnvidianccl.SelfTestConfig
+						if err := json.Unmarshal([]byte(value), &updateCfg); err != nil {
+							log.Logger.Warnw("failed to unmarshal update config", "error", err)
+						} else {
+							componentsnvidianccl.SetDefaultSelfTestConfig(updateCfg)
+						}
+					case componentsnvidiaversioncompliance.Name:
+						var updateCfg componentsnvidiaversioncompliance.Policy
+						if err := json.Unmarshal([]byte(value), &updateCfg); err != nil {
+							log.Logger.Warnw("failed to unmarshal update config", "error", err)
+						} else {
+							componentsnvidiaversioncompliance.SetDefaultPolicy(updateCfg)
 						}
 					default:
 						log.Logger.Warnw("unsupported component for updateConfig", "component", componentName)
@@ -217,6 +332,13 @@ func (s *Session) serve() {
 					response.Error = err.Error()
 				}
 			}
+
+		case "diagnostics":
+			if payload.Diagnostics != nil {
+				if err := s.runDiagnostics(*payload.Diagnostics); err != nil {
+					response.Error = err.Error()
+				}
+			}
 		}
 
 		cancel()
@@ -234,6 +356,40 @@ func (s *Session) serve() {
 	}
 }
 
+// runDiagnostics starts the diagnostics in the background and returns immediately,
+// as the diagnostics may run for hours, and the results are reported as the
+// component events and health states.
+// The diagnostics are aborted when the session (or the component) is stopped.
+func (s *Session) runDiagnostics(req DiagnosticsRequest) error {
+	comp := s.componentsRegistry.Get(componentsnvidiadcgmdiag.Name)
+	if comp == nil {
+		return fmt.Errorf("component %s not found", componentsnvidiadcgmdiag.Name)
+	}
+	diagnoser, ok := comp.(componentsnvidiadcgmdiag.Diagnoser)
+	if !ok {
+		return fmt.Errorf("component %s does not support diagnostics", componentsnvidiadcgmdiag.Name)
+	}
+
+	if req.Cancel {
+		log.Logger.Infow("diagnostics cancel received")
+		if !diagnoser.CancelDiag() {
+			return errors.New("no diagnostics running")
+		}
+		return nil
+	}
+
+	log.Logger.Infow("diagnostics received", "level", req.Level, "force", req.Force)
+	if err := diagnoser.Preflight(req.DiagRequest); err != nil {
+		return err
+	}
+	go func() {
+		if _, err := diagnoser.RunDiag(s.ctx, req.DiagRequest); err != nil {
+			log.Logger.Errorw("failed to run diagnostics", "level", req.Level, "error", err)
+		}
+	}()
+	return nil
+}
+
 func (s *Session) delete() {
 	// cleanup packages
 	if err := createNeedDeleteFiles("/var/lib/gpud/packages"); err != nil {
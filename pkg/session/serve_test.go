@@ -12,7 +12,11 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/leptonai/gpud/components"
+	nvidia_dcgmdiag "github.com/leptonai/gpud/components/accelerator/nvidia/dcgm-diag"
 	nvidia_infiniband "github.com/leptonai/gpud/components/accelerator/nvidia/infiniband"
+	"github.com/leptonai/gpud/pkg/nvidia-query/dcgm"
+	"github.com/leptonai/gpud/pkg/nvidia-query/infiniband"
 )
 
 // TestCreateNeedDeleteFiles tests the createNeedDeleteFiles function
@@ -202,3 +206,77 @@ func TestInfinibandUpdateConfig(t *testing.T) {
 	// Verify the contents of the config
 	assert.Equal(t, expectedPortStates, unmarshaledConfig)
 }
+
+// TestInfinibandUpdateConfigErrorRatesOnly tests that updating only the error rate thresholds
+// keeps the expected port states
+func TestInfinibandUpdateConfigErrorRatesOnly(t *testing.T) {
+	prevStates := nvidia_infiniband.GetDefaultExpectedPortStates()
+	prevErrorRates := nvidia_infiniband.GetDefaultErrorRateThresholds()
+	defer func() {
+		nvidia_infiniband.SetDefaultExpectedPortStates(prevStates)
+		nvidia_infiniband.SetDefaultErrorRateThresholds(prevErrorRates)
+	}()
+
+	nvidia_infiniband.SetDefaultExpectedPortStates(infiniband.ExpectedPortStates{AtLeastPorts: 8, AtLeastRate: 400})
+
+	s := &Session{
+		reader: make(chan Body, 1),
+		writer: make(chan Body, 1),
+	}
+	go s.serve()
+	defer close(s.reader)
+
+	serveUpdateConfig := func(value string) {
+		reqJSON, err := json.Marshal(Request{
+			Method:       "updateConfig",
+			UpdateConfig: map[string]string{nvidia_infiniband.Name: value},
+		})
+		require.NoError(t, err)
+		s.reader <- Body{Data: reqJSON, ReqID: "1"}
+
+		select {
+		case body := <-s.writer:
+			var resp Response
+			require.NoError(t, json.Unmarshal(body.Data, &resp))
+			assert.Empty(t, resp.Error)
+		case <-time.After(10 * time.Second):
+			t.Fatal("timed out waiting for the response")
+		}
+	}
+
+	serveUpdateConfig(`{"error_rates":{"max_per_minute":{"symbol_error":10}}}`)
+	assert.Equal(t, infiniband.ExpectedPortStates{AtLeastPorts: 8, AtLeastRate: 400}, nvidia_infiniband.GetDefaultExpectedPortStates())
+	assert.Equal(t, map[string]float64{"symbol_error": 10}, nvidia_infiniband.GetDefaultErrorRateThresholds().MaxPerMinute)
+
+	// only the specified expected port state is updated
+	serveUpdateConfig(`{"at_least_rate":200}`)
+	assert.Equal(t, infiniband.ExpectedPortStates{AtLeastPorts: 8, AtLeastRate: 200}, nvidia_infiniband.GetDefaultExpectedPortStates())
+	assert.Equal(t, map[string]float64{"symbol_error": 10}, nvidia_infiniband.GetDefaultErrorRateThresholds().MaxPerMinute)
+}
+
+// TestDiagnosticsRequest tests the "diagnostics" method request handling
+func TestDiagnosticsRequest(t *testing.T) {
+	var req Request
+	err := json.Unmarshal([]byte(`{"method":"diagnostics","diagnostics":{"level":2,"force":true}}`), &req)
+	require.NoError(t, err)
+	require.NotNil(t, req.Diagnostics)
+	assert.Equal(t, 2, req.Diagnostics.Level)
+	assert.True(t, req.Diagnostics.Force)
+	assert.False(t, req.Diagnostics.Cancel)
+
+	s := &Session{componentsRegistry: components.NewRegistry(&components.GPUdInstance{RootCtx: context.Background()})}
+	err = s.runDiagnostics(*req.Diagnostics)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not found")
+
+	s.componentsRegistry.MustRegister(nvidia_dcgmdiag.New)
+	err = s.runDiagnostics(DiagnosticsRequest{DiagRequest: nvidia_dcgmdiag.DiagRequest{Level: 5}})
+	assert.ErrorIs(t, err, dcgm.ErrInvalidDiagLevel)
+
+	err = s.runDiagnostics(DiagnosticsRequest{DiagRequest: nvidia_dcgmdiag.DiagRequest{Level: 1, TimeoutInSeconds: -1}})
+	assert.ErrorIs(t, err, nvidia_dcgmdiag.ErrInvalidDiagTimeout)
+
+	err = s.runDiagnostics(DiagnosticsRequest{Cancel: true})
+	require.Error(t, err)
+	assert.Equal(t, "no diagnostics running", err.Error())
+}